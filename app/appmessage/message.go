@@ -73,6 +73,13 @@ const (
 	CmdRequestIBDChainBlockLocator
 	CmdIBDChainBlockLocator
 	CmdRequestAnticone
+	CmdRequestCompactBlock
+	CmdCompactBlock
+	CmdRequestBlockTransactions
+	CmdBlockTransactions
+	CmdRequestTransactionReconciliation
+	CmdTransactionReconciliationSketch
+	CmdTransactionReconciliationDifference
 
 	// rpc
 	CmdGetCurrentNetworkRequestMessage
@@ -163,6 +170,8 @@ const (
 	CmdGetMempoolEntriesByAddressesResponseMessage
 	CmdGetCoinSupplyRequestMessage
 	CmdGetCoinSupplyResponseMessage
+	CmdGetNetTotalsRequestMessage
+	CmdGetNetTotalsResponseMessage
 )
 
 // ProtocolMessageCommandToString maps all MessageCommands to their string representation
@@ -210,6 +219,13 @@ var ProtocolMessageCommandToString = map[MessageCommand]string{
 	CmdRequestIBDChainBlockLocator:                 "RequestIBDChainBlockLocator",
 	CmdIBDChainBlockLocator:                        "IBDChainBlockLocator",
 	CmdRequestAnticone:                             "RequestAnticone",
+	CmdRequestCompactBlock:                         "RequestCompactBlock",
+	CmdCompactBlock:                                "CompactBlock",
+	CmdRequestBlockTransactions:                    "RequestBlockTransactions",
+	CmdBlockTransactions:                           "BlockTransactions",
+	CmdRequestTransactionReconciliation:            "RequestTransactionReconciliation",
+	CmdTransactionReconciliationSketch:             "TransactionReconciliationSketch",
+	CmdTransactionReconciliationDifference:         "TransactionReconciliationDifference",
 }
 
 // RPCMessageCommandToString maps all MessageCommands to their string representation
@@ -300,6 +316,8 @@ var RPCMessageCommandToString = map[MessageCommand]string{
 	CmdGetMempoolEntriesByAddressesResponseMessage:                "GetMempoolEntriesByAddressesResponse",
 	CmdGetCoinSupplyRequestMessage:                                "GetCoinSupplyRequest",
 	CmdGetCoinSupplyResponseMessage:                               "GetCoinSupplyResponse",
+	CmdGetNetTotalsRequestMessage:                                 "GetNetTotalsRequest",
+	CmdGetNetTotalsResponseMessage:                                "GetNetTotalsResponse",
 }
 
 // Message is an interface that describes a kaspi message. A type that
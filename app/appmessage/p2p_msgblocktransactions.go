@@ -0,0 +1,29 @@
+package appmessage
+
+import (
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+)
+
+// MsgBlockTransactions implements the Message interface and represents a kaspi
+// BlockTransactions message. It is sent in response to a RequestBlockTransactions
+// message, and contains the requested transactions in the requested order.
+type MsgBlockTransactions struct {
+	baseMessage
+	Hash         *externalapi.DomainHash
+	Transactions []*MsgTx
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgBlockTransactions) Command() MessageCommand {
+	return CmdBlockTransactions
+}
+
+// NewMsgBlockTransactions returns a new kaspi BlockTransactions message that conforms to
+// the Message interface. See MsgBlockTransactions for details.
+func NewMsgBlockTransactions(hash *externalapi.DomainHash, transactions []*MsgTx) *MsgBlockTransactions {
+	return &MsgBlockTransactions{
+		Hash:         hash,
+		Transactions: transactions,
+	}
+}
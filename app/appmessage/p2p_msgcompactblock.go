@@ -0,0 +1,108 @@
+package appmessage
+
+import (
+	"encoding/binary"
+
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/hashes"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/transactionhelper"
+)
+
+// MaxShortIDsPerCompactBlock is the maximum number of short transaction IDs
+// that can be in a single CompactBlock message.
+const MaxShortIDsPerCompactBlock = MaxInvPerMsg
+
+// ShortIDSize is the amount of bytes of a transaction ID hash that are used
+// to build a compact block short ID.
+const ShortIDSize = 6
+
+// PrefilledTransaction is a transaction that is sent in full inside a compact block,
+// alongside its index within the block's transactions.
+type PrefilledTransaction struct {
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCompactBlock implements the Message interface and represents a kaspi
+// CompactBlock message. It carries a block header alongside short IDs of the
+// block's transactions, allowing the receiver to reconstruct the block out of
+// transactions that it already holds in its mempool.
+//
+// The block's transactions are obtained by placing every prefilled transaction
+// at its index, and filling the remaining indexes in order with the transactions
+// that match ShortIDs.
+type MsgCompactBlock struct {
+	baseMessage
+	Header                MsgBlockHeader
+	ShortIDNonce          uint64
+	ShortIDs              []uint64
+	PrefilledTransactions []*PrefilledTransaction
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgCompactBlock) Command() MessageCommand {
+	return CmdCompactBlock
+}
+
+// TransactionCount returns the amount of transactions in the block represented by
+// this compact block.
+func (msg *MsgCompactBlock) TransactionCount() int {
+	return len(msg.ShortIDs) + len(msg.PrefilledTransactions)
+}
+
+// NewMsgCompactBlock returns a new kaspi CompactBlock message that conforms to
+// the Message interface. See MsgCompactBlock for details.
+func NewMsgCompactBlock(header *MsgBlockHeader, shortIDNonce uint64, shortIDs []uint64,
+	prefilledTransactions []*PrefilledTransaction) *MsgCompactBlock {
+
+	return &MsgCompactBlock{
+		Header:                *header,
+		ShortIDNonce:          shortIDNonce,
+		ShortIDs:              shortIDs,
+		PrefilledTransactions: prefilledTransactions,
+	}
+}
+
+// DomainBlockToMsgCompactBlock converts an externalapi.DomainBlock to a MsgCompactBlock,
+// prefilling its coinbase transaction and using the given nonce to build the short IDs
+// of all other transactions
+func DomainBlockToMsgCompactBlock(domainBlock *externalapi.DomainBlock, shortIDNonce uint64) *MsgCompactBlock {
+	blockHash := consensushashing.BlockHash(domainBlock)
+
+	var prefilledTransactions []*PrefilledTransaction
+	shortIDs := make([]uint64, 0, len(domainBlock.Transactions))
+	for i, transaction := range domainBlock.Transactions {
+		if i == transactionhelper.CoinbaseTransactionIndex {
+			prefilledTransactions = append(prefilledTransactions, &PrefilledTransaction{
+				Index: uint32(i),
+				Tx:    DomainTransactionToMsgTx(transaction),
+			})
+			continue
+		}
+		shortIDs = append(shortIDs, CompactBlockShortID(blockHash, shortIDNonce, consensushashing.TransactionHash(transaction)))
+	}
+
+	return NewMsgCompactBlock(DomainBlockHeaderToBlockHeader(domainBlock.Header), shortIDNonce, shortIDs,
+		prefilledTransactions)
+}
+
+// CompactBlockShortID returns the short ID of the transaction with the given hash inside the
+// compact block with the given hash and nonce. The short ID is derived from the transaction
+// hash rather than from its ID, since the ID doesn't cover signature scripts, so a transaction
+// in the mempool that differs from the block's one only by its signature scripts doesn't match it.
+func CompactBlockShortID(blockHash *externalapi.DomainHash, shortIDNonce uint64,
+	transactionHash *externalapi.DomainHash) uint64 {
+
+	writer := hashes.NewCompactBlockShortIDWriter()
+	writer.InfallibleWrite(blockHash.ByteSlice())
+	var nonceBytes [8]byte
+	binary.LittleEndian.PutUint64(nonceBytes[:], shortIDNonce)
+	writer.InfallibleWrite(nonceBytes[:])
+	writer.InfallibleWrite(transactionHash.ByteSlice())
+
+	var shortIDBytes [8]byte
+	copy(shortIDBytes[:ShortIDSize], writer.Finalize().ByteSlice())
+	return binary.LittleEndian.Uint64(shortIDBytes[:])
+}
@@ -0,0 +1,29 @@
+package appmessage
+
+import (
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+)
+
+// MsgRequestBlockTransactions implements the Message interface and represents a kaspi
+// RequestBlockTransactions message. It is used to request the transactions at the given
+// indexes of a block, which could not be reconstructed out of a compact block.
+type MsgRequestBlockTransactions struct {
+	baseMessage
+	Hash    *externalapi.DomainHash
+	Indexes []uint32
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgRequestBlockTransactions) Command() MessageCommand {
+	return CmdRequestBlockTransactions
+}
+
+// NewMsgRequestBlockTransactions returns a new kaspi RequestBlockTransactions message that conforms to
+// the Message interface. See MsgRequestBlockTransactions for details.
+func NewMsgRequestBlockTransactions(hash *externalapi.DomainHash, indexes []uint32) *MsgRequestBlockTransactions {
+	return &MsgRequestBlockTransactions{
+		Hash:    hash,
+		Indexes: indexes,
+	}
+}
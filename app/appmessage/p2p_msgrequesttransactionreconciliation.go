@@ -0,0 +1,24 @@
+package appmessage
+
+// MsgRequestTransactionReconciliation implements the Message interface and represents a kaspi
+// RequestTransactionReconciliation message. It is periodically sent by the initiator of a
+// transaction reconciliation round, and carries the size of the initiator's reconciliation set
+// so that the responder could estimate the size of the sketch it should reply with.
+type MsgRequestTransactionReconciliation struct {
+	baseMessage
+	SetSize uint32
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgRequestTransactionReconciliation) Command() MessageCommand {
+	return CmdRequestTransactionReconciliation
+}
+
+// NewMsgRequestTransactionReconciliation returns a new kaspi RequestTransactionReconciliation message that
+// conforms to the Message interface. See MsgRequestTransactionReconciliation for details.
+func NewMsgRequestTransactionReconciliation(setSize uint32) *MsgRequestTransactionReconciliation {
+	return &MsgRequestTransactionReconciliation{
+		SetSize: setSize,
+	}
+}
@@ -0,0 +1,26 @@
+package appmessage
+
+// MsgTransactionReconciliationDifference implements the Message interface and represents a kaspi
+// TransactionReconciliationDifference message. It concludes a transaction reconciliation round:
+// if the initiator managed to decode the difference between the sets, ShortIDs holds the short IDs
+// of the transactions the initiator is missing. Otherwise, both sides announce their whole sets.
+type MsgTransactionReconciliationDifference struct {
+	baseMessage
+	Succeeded bool
+	ShortIDs  []uint64
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgTransactionReconciliationDifference) Command() MessageCommand {
+	return CmdTransactionReconciliationDifference
+}
+
+// NewMsgTransactionReconciliationDifference returns a new kaspi TransactionReconciliationDifference message that
+// conforms to the Message interface. See MsgTransactionReconciliationDifference for details.
+func NewMsgTransactionReconciliationDifference(succeeded bool, shortIDs []uint64) *MsgTransactionReconciliationDifference {
+	return &MsgTransactionReconciliationDifference{
+		Succeeded: succeeded,
+		ShortIDs:  shortIDs,
+	}
+}
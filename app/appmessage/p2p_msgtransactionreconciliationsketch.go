@@ -0,0 +1,36 @@
+package appmessage
+
+// MaxTransactionReconciliationSketchCells is the maximum number of cells
+// allowed in a single transaction reconciliation sketch.
+const MaxTransactionReconciliationSketchCells = MaxInvPerTxInvMsg
+
+// TransactionReconciliationSketchCell is a single cell of an invertible bloom
+// lookup table holding transaction short IDs.
+type TransactionReconciliationSketchCell struct {
+	Count    int32
+	KeySum   uint64
+	CheckSum uint32
+}
+
+// MsgTransactionReconciliationSketch implements the Message interface and represents a kaspi
+// TransactionReconciliationSketch message. It is sent by the responder of a transaction
+// reconciliation round, and holds a sketch of the short IDs of the transactions the responder
+// would have otherwise announced to the initiator.
+type MsgTransactionReconciliationSketch struct {
+	baseMessage
+	Cells []*TransactionReconciliationSketchCell
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgTransactionReconciliationSketch) Command() MessageCommand {
+	return CmdTransactionReconciliationSketch
+}
+
+// NewMsgTransactionReconciliationSketch returns a new kaspi TransactionReconciliationSketch message that
+// conforms to the Message interface. See MsgTransactionReconciliationSketch for details.
+func NewMsgTransactionReconciliationSketch(cells []*TransactionReconciliationSketchCell) *MsgTransactionReconciliationSketch {
+	return &MsgTransactionReconciliationSketch{
+		Cells: cells,
+	}
+}
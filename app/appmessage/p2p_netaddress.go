@@ -6,25 +6,35 @@ package appmessage
 
 import (
 	"net"
+	"strconv"
 
 	"github.com/kaspikr/kaspid/util/mstime"
 )
 
 // NetAddress defines information about a peer on the network including the time
-// it was last seen, the services it supports, its IP address, and port.
+// it was last seen, the services it supports, its IP or overlay address, and port.
 type NetAddress struct {
 	// Last time the address was seen.
 	Timestamp mstime.Time
 
-	// IP address of the peer.
+	// Network is the network the address belongs to.
+	Network AddressNetwork
+
+	// IP address of the peer. Only set if Network is AddressNetworkIP.
 	IP net.IP
 
+	// OverlayAddress is the address of the peer in an overlay network,
+	// such as the public key of a Tor onion service. Only set if Network
+	// is an overlay network.
+	OverlayAddress []byte
+
 	// Port the peer is using. This is encoded in big endian on the appmessage
 	// which differs from most everything else.
 	Port uint16
 }
 
-// TCPAddress converts the NetAddress to *net.TCPAddr
+// TCPAddress converts the NetAddress to *net.TCPAddr. It is only meaningful
+// for addresses in AddressNetworkIP.
 func (na *NetAddress) TCPAddress() *net.TCPAddr {
 	return &net.TCPAddr{
 		IP:   na.IP,
@@ -60,5 +70,8 @@ func NewNetAddress(addr *net.TCPAddr) *NetAddress {
 }
 
 func (na NetAddress) String() string {
+	if na.IsOverlay() {
+		return net.JoinHostPort(na.Host(), strconv.Itoa(int(na.Port)))
+	}
 	return na.TCPAddress().String()
 }
@@ -0,0 +1,157 @@
+package appmessage
+
+import (
+	"bytes"
+	"encoding/base32"
+	"fmt"
+	"strings"
+
+	"github.com/kaspikr/kaspid/util/mstime"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/sha3"
+)
+
+// AddressNetwork identifies the network a NetAddress belongs to
+type AddressNetwork uint8
+
+const (
+	// AddressNetworkIP is the network of IPv4 and IPv6 addresses
+	AddressNetworkIP AddressNetwork = iota
+
+	// AddressNetworkTorV3 is the network of Tor v3 onion services
+	AddressNetworkTorV3
+
+	// AddressNetworkI2P is the network of I2P destinations
+	AddressNetworkI2P
+)
+
+var addressNetworkStrings = map[AddressNetwork]string{
+	AddressNetworkIP:    "ip",
+	AddressNetworkTorV3: "onion",
+	AddressNetworkI2P:   "i2p",
+}
+
+func (network AddressNetwork) String() string {
+	if networkString, ok := addressNetworkStrings[network]; ok {
+		return networkString
+	}
+	return fmt.Sprintf("unknown network %d", uint8(network))
+}
+
+// OverlayAddressSize is the size of the overlay address of Tor v3 and I2P
+// addresses: the public key of the onion service and the hash of the I2P
+// destination respectively
+const OverlayAddressSize = 32
+
+const (
+	torV3HostSuffix    = ".onion"
+	torV3Version       = 3
+	torV3ChecksumSize  = 2
+	torV3ChecksumLabel = ".onion checksum"
+	i2pHostSuffix      = ".b32.i2p"
+)
+
+var overlayHostEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// IsOverlayHost returns whether the given host name belongs to an overlay
+// network. It does not validate the host name.
+func IsOverlayHost(host string) bool {
+	host = strings.ToLower(host)
+	return strings.HasSuffix(host, torV3HostSuffix) || strings.HasSuffix(host, ".i2p")
+}
+
+// NewNetAddressOverlay returns a new NetAddress in the given overlay network
+func NewNetAddressOverlay(network AddressNetwork, overlayAddress []byte, port uint16) *NetAddress {
+	return &NetAddress{
+		Timestamp:      mstime.Now(),
+		Network:        network,
+		OverlayAddress: overlayAddress,
+		Port:           port,
+	}
+}
+
+// NewNetAddressOverlayHost returns a new NetAddress for the given Tor v3 onion
+// or I2P host name
+func NewNetAddressOverlayHost(host string, port uint16) (*NetAddress, error) {
+	host = strings.ToLower(host)
+	switch {
+	case strings.HasSuffix(host, torV3HostSuffix):
+		publicKey, err := decodeTorV3Host(strings.TrimSuffix(host, torV3HostSuffix))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid onion address %s", host)
+		}
+		return NewNetAddressOverlay(AddressNetworkTorV3, publicKey, port), nil
+
+	case strings.HasSuffix(host, i2pHostSuffix):
+		destinationHash, err := overlayHostEncoding.DecodeString(strings.TrimSuffix(host, i2pHostSuffix))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid I2P address %s", host)
+		}
+		if len(destinationHash) != OverlayAddressSize {
+			return nil, errors.Errorf("invalid I2P address %s: the destination hash is %d bytes "+
+				"rather than %d", host, len(destinationHash), OverlayAddressSize)
+		}
+		return NewNetAddressOverlay(AddressNetworkI2P, destinationHash, port), nil
+	}
+
+	return nil, errors.Errorf("%s is neither an onion nor a .b32.i2p address", host)
+}
+
+// IsOverlay returns whether the address belongs to an overlay network rather
+// than to an IP network
+func (na *NetAddress) IsOverlay() bool {
+	return na.Network != AddressNetworkIP
+}
+
+// Host returns the host part of the address: an IP for IP addresses, and the
+// host name in its overlay network otherwise
+func (na *NetAddress) Host() string {
+	switch na.Network {
+	case AddressNetworkIP:
+		return na.IP.String()
+	case AddressNetworkTorV3:
+		return encodeTorV3Host(na.OverlayAddress) + torV3HostSuffix
+	case AddressNetworkI2P:
+		return overlayHostEncoding.EncodeToString(na.OverlayAddress) + i2pHostSuffix
+	}
+	return fmt.Sprintf("<%s: %x>", na.Network, na.OverlayAddress)
+}
+
+// encodeTorV3Host encodes an onion service public key as defined by the Tor rend-spec-v3:
+// base32(PUBKEY | CHECKSUM | VERSION)
+func encodeTorV3Host(publicKey []byte) string {
+	serialized := make([]byte, 0, len(publicKey)+torV3ChecksumSize+1)
+	serialized = append(serialized, publicKey...)
+	serialized = append(serialized, torV3Checksum(publicKey)...)
+	serialized = append(serialized, torV3Version)
+	return overlayHostEncoding.EncodeToString(serialized)
+}
+
+func decodeTorV3Host(encoded string) ([]byte, error) {
+	serialized, err := overlayHostEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(serialized) != OverlayAddressSize+torV3ChecksumSize+1 {
+		return nil, errors.Errorf("only version %d onion addresses are supported", torV3Version)
+	}
+	publicKey := serialized[:OverlayAddressSize]
+	checksum := serialized[OverlayAddressSize : OverlayAddressSize+torV3ChecksumSize]
+	version := serialized[OverlayAddressSize+torV3ChecksumSize]
+	if version != torV3Version {
+		return nil, errors.Errorf("only version %d onion addresses are supported", torV3Version)
+	}
+	if !bytes.Equal(checksum, torV3Checksum(publicKey)) {
+		return nil, errors.New("bad checksum")
+	}
+	return publicKey, nil
+}
+
+// torV3Checksum returns H(".onion checksum" | PUBKEY | VERSION)[:2]
+func torV3Checksum(publicKey []byte) []byte {
+	hasher := sha3.New256()
+	hasher.Write([]byte(torV3ChecksumLabel))
+	hasher.Write(publicKey)
+	hasher.Write([]byte{torV3Version})
+	return hasher.Sum(nil)[:torV3ChecksumSize]
+}
@@ -6,6 +6,7 @@ package appmessage
 
 import (
 	"net"
+	"strings"
 	"testing"
 )
 
@@ -26,3 +27,57 @@ func TestNetAddress(t *testing.T) {
 			port)
 	}
 }
+
+// TestNetAddressOverlayHost tests parsing and formatting overlay host names.
+func TestNetAddressOverlayHost(t *testing.T) {
+	tests := []struct {
+		host            string
+		expectedNetwork AddressNetwork
+		expectedValid   bool
+	}{
+		{host: "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion",
+			expectedNetwork: AddressNetworkTorV3, expectedValid: true},
+		{host: "DUCKDUCKGOGG42XJOC72X3SJASOWOARFBGCMVFIMAFTT6TWAGSWZCZAD.onion",
+			expectedNetwork: AddressNetworkTorV3, expectedValid: true},
+		{host: "ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnkdq.b32.i2p",
+			expectedNetwork: AddressNetworkI2P, expectedValid: true},
+		// Bad checksum
+		{host: "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczaa.onion", expectedValid: false},
+		// Version 2 onion addresses are no longer supported
+		{host: "expyuzz4wqqyqhjn.onion", expectedValid: false},
+		// Destination hash of the wrong length
+		{host: "ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnk.b32.i2p", expectedValid: false},
+		{host: "not base32!.onion", expectedValid: false},
+		{host: "example.com", expectedValid: false},
+	}
+
+	for _, test := range tests {
+		na, err := NewNetAddressOverlayHost(test.host, 16111)
+		if !test.expectedValid {
+			if err == nil {
+				t.Errorf("NewNetAddressOverlayHost(%s): expected an error", test.host)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewNetAddressOverlayHost(%s): unexpected error: %s", test.host, err)
+			continue
+		}
+		if na.Network != test.expectedNetwork {
+			t.Errorf("NewNetAddressOverlayHost(%s): wrong network - got %s, want %s",
+				test.host, na.Network, test.expectedNetwork)
+		}
+		if !na.IsOverlay() || !IsOverlayHost(test.host) {
+			t.Errorf("NewNetAddressOverlayHost(%s): expected an overlay address", test.host)
+		}
+		if len(na.OverlayAddress) != OverlayAddressSize {
+			t.Errorf("NewNetAddressOverlayHost(%s): wrong overlay address length - got %d, want %d",
+				test.host, len(na.OverlayAddress), OverlayAddressSize)
+		}
+		expectedString := strings.ToLower(test.host) + ":16111"
+		if na.String() != expectedString {
+			t.Errorf("NewNetAddressOverlayHost(%s): wrong string - got %s, want %s",
+				test.host, na.String(), expectedString)
+		}
+	}
+}
@@ -41,16 +41,21 @@ const (
 	// SFNodeCF is a flag used to indicate a peer supports committed
 	// filters (CFs).
 	SFNodeCF
+
+	// SFNodeTxReconciliation is a flag used to indicate a peer supports
+	// announcing transactions by set reconciliation.
+	SFNodeTxReconciliation
 )
 
 // Map of service flags back to their constant names for pretty printing.
 var sfStrings = map[ServiceFlag]string{
-	SFNodeNetwork: "SFNodeNetwork",
-	SFNodeGetUTXO: "SFNodeGetUTXO",
-	SFNodeBloom:   "SFNodeBloom",
-	SFNodeXthin:   "SFNodeXthin",
-	SFNodeBit5:    "SFNodeBit5",
-	SFNodeCF:      "SFNodeCF",
+	SFNodeNetwork:          "SFNodeNetwork",
+	SFNodeGetUTXO:          "SFNodeGetUTXO",
+	SFNodeBloom:            "SFNodeBloom",
+	SFNodeXthin:            "SFNodeXthin",
+	SFNodeBit5:             "SFNodeBit5",
+	SFNodeCF:               "SFNodeCF",
+	SFNodeTxReconciliation: "SFNodeTxReconciliation",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -62,6 +67,7 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeXthin,
 	SFNodeBit5,
 	SFNodeCF,
+	SFNodeTxReconciliation,
 }
 
 // String returns the ServiceFlag in human-readable form.
@@ -47,4 +47,30 @@ type GetConnectedPeerInfoMessage struct {
 	AdvertisedProtocolVersion uint32
 	TimeConnected             int64
 	IsIBDPeer                 bool
+	MinPingDuration           int64
+	AveragePingDuration       int64
+	MessagesSent              uint64
+	BytesSent                 uint64
+	MessagesReceived          uint64
+	BytesReceived             uint64
+	MessageTraffic            []*MessageTrafficInfo
+	RouteQueues               []*RouteQueueInfo
+}
+
+// MessageTrafficInfo holds the messages and bytes sent and
+// received of a single message type
+type MessageTrafficInfo struct {
+	Command          string
+	MessagesSent     uint64
+	BytesSent        uint64
+	MessagesReceived uint64
+	BytesReceived    uint64
+}
+
+// RouteQueueInfo holds the queue length of a message route of a peer
+type RouteQueueInfo struct {
+	Name          string
+	Length        uint32
+	Capacity      uint32
+	HighWaterMark uint32
 }
@@ -0,0 +1,48 @@
+package appmessage
+
+// GetNetTotalsRequestMessage is an appmessage corresponding to
+// its respective RPC message
+type GetNetTotalsRequestMessage struct {
+	baseMessage
+}
+
+// Command returns the protocol command string for the message
+func (msg *GetNetTotalsRequestMessage) Command() MessageCommand {
+	return CmdGetNetTotalsRequestMessage
+}
+
+// NewGetNetTotalsRequestMessage returns a instance of the message
+func NewGetNetTotalsRequestMessage() *GetNetTotalsRequestMessage {
+	return &GetNetTotalsRequestMessage{}
+}
+
+// GetNetTotalsResponseMessage is an appmessage corresponding to
+// its respective RPC message
+type GetNetTotalsResponseMessage struct {
+	baseMessage
+	MessagesSent     uint64
+	BytesSent        uint64
+	MessagesReceived uint64
+	BytesReceived    uint64
+	MessageTraffic   []*MessageTrafficInfo
+
+	Error *RPCError
+}
+
+// Command returns the protocol command string for the message
+func (msg *GetNetTotalsResponseMessage) Command() MessageCommand {
+	return CmdGetNetTotalsResponseMessage
+}
+
+// NewGetNetTotalsResponseMessage returns a instance of the message
+func NewGetNetTotalsResponseMessage(messagesSent uint64, bytesSent uint64, messagesReceived uint64,
+	bytesReceived uint64, messageTraffic []*MessageTrafficInfo) *GetNetTotalsResponseMessage {
+
+	return &GetNetTotalsResponseMessage{
+		MessagesSent:     messagesSent,
+		BytesSent:        bytesSent,
+		MessagesReceived: messagesReceived,
+		BytesReceived:    bytesReceived,
+		MessageTraffic:   messageTraffic,
+	}
+}
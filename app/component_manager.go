@@ -2,12 +2,16 @@ package app
 
 import (
 	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
 	"sync/atomic"
 
 	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
 
 	"github.com/kaspikr/kaspid/domain/miningmanager/mempool"
 
+	"github.com/kaspikr/kaspid/app/appmessage"
 	"github.com/kaspikr/kaspid/app/protocol"
 	"github.com/kaspikr/kaspid/app/rpc"
 	"github.com/kaspikr/kaspid/domain"
@@ -17,11 +21,16 @@ import (
 	infrastructuredatabase "github.com/kaspikr/kaspid/infrastructure/db/database"
 	"github.com/kaspikr/kaspid/infrastructure/network/addressmanager"
 	"github.com/kaspikr/kaspid/infrastructure/network/connmanager"
+	"github.com/kaspikr/kaspid/infrastructure/network/nat"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/id"
+	"github.com/kaspikr/kaspid/infrastructure/network/tor"
 	"github.com/kaspikr/kaspid/util/panics"
+	"github.com/pkg/errors"
 )
 
+const onionServicePrivateKeyFilename = "onion_v3_private_key"
+
 // ComponentManager is a wrapper for all the kaspid services
 type ComponentManager struct {
 	cfg               *config.Config
@@ -30,6 +39,8 @@ type ComponentManager struct {
 	rpcManager        *rpc.Manager
 	connectionManager *connmanager.ConnectionManager
 	netAdapter        *netadapter.NetAdapter
+	onionService      *tor.OnionService
+	portMapper        *nat.PortMapper
 
 	started, shutdown int32
 }
@@ -48,9 +59,90 @@ func (a *ComponentManager) Start() {
 		panics.Exit(log, fmt.Sprintf("Error starting the net adapter: %+v", err))
 	}
 
+	if a.cfg.TorControl != "" {
+		a.startOnionService()
+	}
+	if a.cfg.Upnp && !a.cfg.DisableListen {
+		a.startPortMapper()
+	}
+
 	a.connectionManager.Start()
 }
 
+// startOnionService creates an onion service for the P2P listener through
+// the Tor control port, and advertises its address to peers. Failing to
+// create it isn't fatal, since the node is still reachable by its other addresses.
+func (a *ComponentManager) startOnionService() {
+	host, port, err := a.p2pListener()
+	if err != nil {
+		log.Errorf("Error creating the onion service: %s", err)
+		return
+	}
+
+	privateKeyFile := filepath.Join(a.cfg.AppDir, onionServicePrivateKeyFilename)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	onionService, err := tor.NewOnionService(a.cfg.TorControl, a.cfg.TorPassword, privateKeyFile, port, target)
+	if err != nil {
+		log.Errorf("Error creating the onion service: %s", err)
+		return
+	}
+	a.onionService = onionService
+
+	err = a.addressManager.AddLocalAddress(onionService.NetAddress(), addressmanager.ManualPrio)
+	if err != nil {
+		log.Errorf("Error advertising the onion service address: %s", err)
+	}
+}
+
+// startPortMapper maps the port of the P2P listener on the NAT gateway of the
+// local network, and advertises the external address of the mapping to peers
+func (a *ComponentManager) startPortMapper() {
+	if len(a.cfg.ExternalIPs) > 0 {
+		log.Infof("Not mapping the P2P port with UPnP, since external IPs are specified")
+		return
+	}
+	_, port, err := a.p2pListener()
+	if err != nil {
+		log.Errorf("Error mapping the P2P port: %s", err)
+		return
+	}
+
+	a.portMapper = nat.NewPortMapper(port, func(previousAddress, externalAddress *net.TCPAddr) {
+		if previousAddress != nil {
+			a.addressManager.RemoveLocalAddress(
+				appmessage.NewNetAddressIPPort(previousAddress.IP, uint16(previousAddress.Port)))
+		}
+		if externalAddress == nil {
+			return
+		}
+		netAddress := appmessage.NewNetAddressIPPort(externalAddress.IP, uint16(externalAddress.Port))
+		err := a.addressManager.AddLocalAddress(netAddress, addressmanager.UpnpPrio)
+		if err != nil {
+			log.Warnf("Not advertising the external address of the port mapping: %s", err)
+		}
+	})
+	a.portMapper.Start()
+}
+
+// p2pListener returns the host and port of the first P2P listener
+func (a *ComponentManager) p2pListener() (host string, port uint16, err error) {
+	if len(a.cfg.Listeners) == 0 {
+		return "", 0, errors.New("listening for incoming connections is disabled")
+	}
+	host, portString, err := net.SplitHostPort(a.cfg.Listeners[0])
+	if err != nil {
+		return "", 0, errors.Wrap(err, "could not parse the P2P listener address")
+	}
+	parsedPort, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "could not parse the P2P listener port")
+	}
+	return host, uint16(parsedPort), nil
+}
+
 // Stop gracefully shuts down all the kaspid services.
 func (a *ComponentManager) Stop() {
 	// Make sure this only happens once.
@@ -63,6 +155,17 @@ func (a *ComponentManager) Stop() {
 
 	a.connectionManager.Stop()
 
+	if a.portMapper != nil {
+		a.portMapper.Stop()
+	}
+
+	if a.onionService != nil {
+		err := a.onionService.Close()
+		if err != nil {
+			log.Errorf("Error closing the onion service: %+v", err)
+		}
+	}
+
 	err := a.netAdapter.Stop()
 	if err != nil {
 		log.Errorf("Error stopping the net adapter: %+v", err)
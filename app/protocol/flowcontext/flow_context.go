@@ -62,6 +62,10 @@ type FlowContext struct {
 	lastTransactionIDPropagationTime time.Time
 	transactionIDPropagationLock     sync.Mutex
 
+	transactionReconciliationPeers       map[id.ID]*transactionReconciliationPeer
+	transactionFloodingOutboundPeerCount int
+	transactionReconciliationLock        sync.Mutex
+
 	shutdownChan chan struct{}
 }
 
@@ -82,6 +86,7 @@ func New(cfg *config.Config, domain domain.Domain, addressManager *addressmanage
 		timeStarted:                      mstime.Now().UnixMilliseconds(),
 		transactionIDsToPropagate:        []*externalapi.DomainTransactionID{},
 		lastTransactionIDPropagationTime: time.Now(),
+		transactionReconciliationPeers:   make(map[id.ID]*transactionReconciliationPeer),
 		shutdownChan:                     make(chan struct{}),
 	}
 }
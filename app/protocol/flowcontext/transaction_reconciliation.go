@@ -0,0 +1,174 @@
+package flowcontext
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	peerpkg "github.com/kaspikr/kaspid/app/protocol/peer"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter"
+)
+
+// MaxTransactionFloodingOutboundPeers is the maximum number of outbound reconciling peers
+// to which transaction IDs are still flooded, so that transactions keep propagating quickly
+// through the network between reconciliation rounds
+const MaxTransactionFloodingOutboundPeers = 8
+
+// TransactionReconciliationInterval is the interval between two reconciliation rounds with the same peer
+const TransactionReconciliationInterval = 2 * time.Second
+
+// MaxTransactionReconciliationSetSize is the maximum number of transaction IDs pending announcement to a
+// peer. Beyond it, a sketch couldn't reconcile the set anyway, so the IDs are flooded to the peer instead.
+const MaxTransactionReconciliationSetSize = appmessage.MaxInvPerTxInvMsg
+
+// maxTransactionReconciliationRoundDelay is how long transaction IDs may be pending announcement to a
+// peer without a reconciliation round before they're flooded to it, so that a peer that doesn't run
+// rounds still gets them, and doesn't make its set grow for as long as it's connected
+const maxTransactionReconciliationRoundDelay = 5 * TransactionReconciliationInterval
+
+// TransactionReconciliationSet is a set of IDs of transactions that are pending
+// announcement to a peer in the next reconciliation round
+type TransactionReconciliationSet struct {
+	transactionIDs   map[externalapi.DomainTransactionID]struct{}
+	lastSnapshotTime time.Time
+	lock             sync.Mutex
+}
+
+func newTransactionReconciliationSet() *TransactionReconciliationSet {
+	return &TransactionReconciliationSet{
+		transactionIDs:   make(map[externalapi.DomainTransactionID]struct{}),
+		lastSnapshotTime: time.Now(),
+	}
+}
+
+func (s *TransactionReconciliationSet) addMany(transactionIDs []*externalapi.DomainTransactionID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, transactionID := range transactionIDs {
+		s.transactionIDs[*transactionID] = struct{}{}
+	}
+}
+
+// Len returns the number of transaction IDs in the set
+func (s *TransactionReconciliationSet) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.transactionIDs)
+}
+
+// TakeSnapshot returns all the transaction IDs in the set and empties it.
+// Transaction IDs that are added afterwards are left for the next reconciliation round.
+func (s *TransactionReconciliationSet) TakeSnapshot() []*externalapi.DomainTransactionID {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	transactionIDs := make([]*externalapi.DomainTransactionID, 0, len(s.transactionIDs))
+	for transactionID := range s.transactionIDs {
+		transactionID := transactionID
+		transactionIDs = append(transactionIDs, &transactionID)
+	}
+	s.transactionIDs = make(map[externalapi.DomainTransactionID]struct{})
+	s.lastSnapshotTime = time.Now()
+	return transactionIDs
+}
+
+// isOverdue returns whether the set should be flooded rather than wait for a reconciliation round,
+// because it's full or because no round took a snapshot of it for too long
+func (s *TransactionReconciliationSet) isOverdue() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.transactionIDs) >= MaxTransactionReconciliationSetSize ||
+		(len(s.transactionIDs) > 0 && time.Since(s.lastSnapshotTime) > maxTransactionReconciliationRoundDelay)
+}
+
+type transactionReconciliationPeer struct {
+	isFlooding bool
+	set        *TransactionReconciliationSet
+}
+
+// RegisterTransactionReconciliationPeer marks the given peer as one with which transactions
+// are reconciled. Transaction IDs that would have been announced to the peer are added to the
+// returned set, and are flooded to the peer only if it's one of the few outbound peers to which
+// transactions are still flooded.
+func (f *FlowContext) RegisterTransactionReconciliationPeer(peer *peerpkg.Peer) *TransactionReconciliationSet {
+	f.transactionReconciliationLock.Lock()
+	defer f.transactionReconciliationLock.Unlock()
+
+	isFlooding := peer.IsOutbound() &&
+		f.transactionFloodingOutboundPeerCount < MaxTransactionFloodingOutboundPeers
+	if isFlooding {
+		f.transactionFloodingOutboundPeerCount++
+	}
+
+	set := newTransactionReconciliationSet()
+	f.transactionReconciliationPeers[*peer.ID()] = &transactionReconciliationPeer{
+		isFlooding: isFlooding,
+		set:        set,
+	}
+	log.Debugf("Reconciling transactions with peer %s (flooding: %t)", peer, isFlooding)
+	return set
+}
+
+// UnregisterTransactionReconciliationPeer reverts RegisterTransactionReconciliationPeer
+func (f *FlowContext) UnregisterTransactionReconciliationPeer(peer *peerpkg.Peer) {
+	f.transactionReconciliationLock.Lock()
+	defer f.transactionReconciliationLock.Unlock()
+
+	reconciliationPeer, ok := f.transactionReconciliationPeers[*peer.ID()]
+	if !ok {
+		return
+	}
+	if reconciliationPeer.isFlooding {
+		f.transactionFloodingOutboundPeerCount--
+	}
+	delete(f.transactionReconciliationPeers, *peer.ID())
+}
+
+// overdueTransactionAnnouncement is the transaction IDs pending announcement to a peer with which transactions
+// are reconciled, which are flooded to it since its reconciliation set is overdue
+type overdueTransactionAnnouncement struct {
+	connection     *netadapter.NetConnection
+	transactionIDs []*externalapi.DomainTransactionID
+}
+
+// transactionFloodingPeerConnections returns the NetConnections of all the ready peers to which
+// the given transaction IDs should be flooded, and adds them to the reconciliation sets of all
+// the peers with which transactions are reconciled. The IDs pending in overdue reconciliation sets
+// are taken out of them, to be flooded to their peers along with the given ones.
+func (f *FlowContext) transactionFloodingPeerConnections(transactionIDs []*externalapi.DomainTransactionID) (
+	[]*netadapter.NetConnection, []*overdueTransactionAnnouncement) {
+
+	f.transactionReconciliationLock.Lock()
+	defer f.transactionReconciliationLock.Unlock()
+
+	f.peersMutex.RLock()
+	defer f.peersMutex.RUnlock()
+
+	peerConnections := make([]*netadapter.NetConnection, 0, len(f.peers))
+	var overdueAnnouncements []*overdueTransactionAnnouncement
+	for peerID, peer := range f.peers {
+		reconciliationPeer, ok := f.transactionReconciliationPeers[peerID]
+		if ok {
+			isOverdue := !reconciliationPeer.isFlooding && reconciliationPeer.set.isOverdue()
+			if isOverdue {
+				log.Debugf("The transaction reconciliation set of %s is overdue, flooding it", peer)
+				overdueAnnouncements = append(overdueAnnouncements, &overdueTransactionAnnouncement{
+					connection:     peer.Connection(),
+					transactionIDs: reconciliationPeer.set.TakeSnapshot(),
+				})
+			}
+			// Transactions flooded to a peer are also reconciled with it, so that they cancel out
+			// with the same transactions in the peer's own set rather than being announced back
+			reconciliationPeer.set.addMany(transactionIDs)
+			if !reconciliationPeer.isFlooding && !isOverdue {
+				continue
+			}
+		}
+		peerConnections = append(peerConnections, peer.Connection())
+	}
+	return peerConnections, overdueAnnouncements
+}
@@ -0,0 +1,67 @@
+package flowcontext
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+)
+
+func transactionIDsForTest(start, count int) []*externalapi.DomainTransactionID {
+	transactionIDs := make([]*externalapi.DomainTransactionID, count)
+	for i := range transactionIDs {
+		var transactionIDBytes [externalapi.DomainHashSize]byte
+		binary.LittleEndian.PutUint64(transactionIDBytes[:], uint64(start+i))
+		transactionIDs[i] = externalapi.NewDomainTransactionIDFromByteArray(&transactionIDBytes)
+	}
+	return transactionIDs
+}
+
+func TestTransactionReconciliationSetCap(t *testing.T) {
+	set := newTransactionReconciliationSet()
+	if set.isOverdue() {
+		t.Fatalf("An empty set is overdue")
+	}
+
+	set.addMany(transactionIDsForTest(0, MaxTransactionReconciliationSetSize-1))
+	if set.isOverdue() {
+		t.Fatalf("A set below the cap is overdue")
+	}
+
+	set.addMany(transactionIDsForTest(MaxTransactionReconciliationSetSize-1, 10))
+	if !set.isOverdue() {
+		t.Fatalf("A set past the cap isn't overdue")
+	}
+
+	snapshot := set.TakeSnapshot()
+	if len(snapshot) != MaxTransactionReconciliationSetSize+9 {
+		t.Fatalf("Expected a snapshot of %d transaction IDs but got %d",
+			MaxTransactionReconciliationSetSize+9, len(snapshot))
+	}
+	if set.Len() != 0 {
+		t.Fatalf("The set wasn't reset by taking a snapshot of it")
+	}
+	if set.isOverdue() {
+		t.Fatalf("The set is still overdue after being reset")
+	}
+}
+
+func TestTransactionReconciliationSetRoundDelay(t *testing.T) {
+	set := newTransactionReconciliationSet()
+	set.lastSnapshotTime = time.Now().Add(-2 * maxTransactionReconciliationRoundDelay)
+	if set.isOverdue() {
+		t.Fatalf("An empty set is overdue")
+	}
+
+	set.addMany(transactionIDsForTest(0, 1))
+	if !set.isOverdue() {
+		t.Fatalf("A set with no round for too long isn't overdue")
+	}
+
+	set.TakeSnapshot()
+	set.addMany(transactionIDsForTest(0, 1))
+	if set.isOverdue() {
+		t.Fatalf("A set is overdue right after a round")
+	}
+}
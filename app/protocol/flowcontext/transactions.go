@@ -6,6 +6,7 @@ import (
 	"github.com/kaspikr/kaspid/app/appmessage"
 	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
 	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter"
 )
 
 // TransactionIDPropagationInterval is the interval between transaction IDs propagations
@@ -42,7 +43,9 @@ func (f *FlowContext) OnTransactionAddedToMempool() {
 }
 
 // EnqueueTransactionIDsForPropagation add the given transactions IDs to a set of IDs to
-// propagate. The IDs will be broadcast to all peers within a single transaction Inv message.
+// propagate. The IDs will be broadcast to all peers within a single transaction Inv message,
+// except for peers with which transactions are reconciled, to which they're announced in the next
+// reconciliation round.
 // The broadcast itself may happen only during a subsequent call to this method
 func (f *FlowContext) EnqueueTransactionIDsForPropagation(transactionIDs []*externalapi.DomainTransactionID) error {
 	f.transactionIDPropagationLock.Lock()
@@ -67,10 +70,17 @@ func (f *FlowContext) maybePropagateTransactions() error {
 		log.Debugf("Transaction propagation: broadcasting %d transactions", len(transactionIDsToBroadcast))
 
 		inv := appmessage.NewMsgInvTransaction(transactionIDsToBroadcast)
-		err := f.Broadcast(inv)
+		peerConnections, overdueAnnouncements := f.transactionFloodingPeerConnections(transactionIDsToBroadcast)
+		err := f.netAdapter.P2PBroadcast(peerConnections, inv)
 		if err != nil {
 			return err
 		}
+		for _, overdueAnnouncement := range overdueAnnouncements {
+			err := f.floodOverdueTransactionAnnouncement(overdueAnnouncement)
+			if err != nil {
+				return err
+			}
+		}
 
 		f.transactionIDsToPropagate = f.transactionIDsToPropagate[len(transactionIDsToBroadcast):]
 	}
@@ -79,3 +89,20 @@ func (f *FlowContext) maybePropagateTransactions() error {
 
 	return nil
 }
+
+func (f *FlowContext) floodOverdueTransactionAnnouncement(overdueAnnouncement *overdueTransactionAnnouncement) error {
+	transactionIDs := overdueAnnouncement.transactionIDs
+	for len(transactionIDs) > 0 {
+		transactionIDsToAnnounce := transactionIDs
+		if len(transactionIDsToAnnounce) > appmessage.MaxInvPerTxInvMsg {
+			transactionIDsToAnnounce = transactionIDsToAnnounce[:appmessage.MaxInvPerTxInvMsg]
+		}
+		err := f.netAdapter.P2PBroadcast([]*netadapter.NetConnection{overdueAnnouncement.connection},
+			appmessage.NewMsgInvTransaction(transactionIDsToAnnounce))
+		if err != nil {
+			return err
+		}
+		transactionIDs = transactionIDs[len(transactionIDsToAnnounce):]
+	}
+	return nil
+}
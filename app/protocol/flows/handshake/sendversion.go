@@ -66,6 +66,9 @@ func (flow *sendVersionFlow) start() error {
 
 	// Advertise the services flag
 	msg.Services = defaultServices
+	if flow.Config().TxReconciliation {
+		msg.AddService(appmessage.SFNodeTxReconciliation)
+	}
 
 	// Advertise our max supported protocol version.
 	msg.ProtocolVersion = flow.Config().ProtocolVersion
@@ -4,17 +4,24 @@ import (
 	"math/rand"
 
 	"github.com/kaspikr/kaspid/app/appmessage"
+	peerpkg "github.com/kaspikr/kaspid/app/protocol/peer"
 	"github.com/kaspikr/kaspid/infrastructure/network/addressmanager"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
 )
 
+// overlayAddressesProtocolVersion is the first protocol version in which peers can decode overlay
+// (Tor and I2P) addresses. Earlier versions decode them as addresses with an empty IP.
+const overlayAddressesProtocolVersion = 6
+
 // SendAddressesContext is the interface for the context needed for the SendAddresses flow.
 type SendAddressesContext interface {
 	AddressManager() *addressmanager.AddressManager
 }
 
 // SendAddresses sends addresses to a peer that requests it.
-func SendAddresses(context SendAddressesContext, incomingRoute *router.Route, outgoingRoute *router.Route) error {
+func SendAddresses(context SendAddressesContext, incomingRoute *router.Route, outgoingRoute *router.Route,
+	peer *peerpkg.Peer) error {
+
 	for {
 		_, err := incomingRoute.Dequeue()
 		if err != nil {
@@ -22,6 +29,9 @@ func SendAddresses(context SendAddressesContext, incomingRoute *router.Route, ou
 		}
 
 		addresses := context.AddressManager().Addresses()
+		if peer.ProtocolVersion() < overlayAddressesProtocolVersion {
+			addresses = withoutOverlayAddresses(addresses)
+		}
 		msgAddresses := appmessage.NewMsgAddresses(shuffleAddresses(addresses))
 
 		err = outgoingRoute.Enqueue(msgAddresses)
@@ -31,6 +41,16 @@ func SendAddresses(context SendAddressesContext, incomingRoute *router.Route, ou
 	}
 }
 
+func withoutOverlayAddresses(addresses []*appmessage.NetAddress) []*appmessage.NetAddress {
+	ipAddresses := make([]*appmessage.NetAddress, 0, len(addresses))
+	for _, address := range addresses {
+		if !address.IsOverlay() {
+			ipAddresses = append(ipAddresses, address)
+		}
+	}
+	return ipAddresses
+}
+
 // shuffleAddresses randomizes the given addresses sent if there are more than the maximum allowed in one message.
 func shuffleAddresses(addresses []*appmessage.NetAddress) []*appmessage.NetAddress {
 	addressCount := len(addresses)
@@ -0,0 +1,130 @@
+package blockrelay
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/app/protocol/protocolerrors"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/merkle"
+)
+
+// compactBlocksProtocolVersion is the lowest protocol version in which
+// blocks are relayed in their compact form
+const compactBlocksProtocolVersion = 6
+
+// requestCompactBlock requests the block with the given hash in its compact form and
+// attempts to reconstruct it out of the transactions in our mempool, requesting any
+// missing transactions from the peer. It returns nil if the reconstructed block does
+// not match its header, in which case the full block should be requested instead.
+func (flow *handleRelayInvsFlow) requestCompactBlock(requestHash *externalapi.DomainHash) (*externalapi.DomainBlock, error) {
+	err := flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestCompactBlock(requestHash))
+	if err != nil {
+		return nil, err
+	}
+
+	msgCompactBlock, err := flow.readMsgCompactBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	header := appmessage.BlockHeaderToDomainBlockHeader(&msgCompactBlock.Header)
+	blockHash := consensushashing.HeaderHash(header)
+	if !blockHash.Equal(requestHash) {
+		return nil, protocolerrors.Errorf(true, "got unrequested compact block %s", blockHash)
+	}
+
+	mempoolTransactions := flow.Domain().MiningManager().GetTransactionsByShortIDs(msgCompactBlock.ShortIDs,
+		func(transaction *externalapi.DomainTransaction) uint64 {
+			return appmessage.CompactBlockShortID(blockHash, msgCompactBlock.ShortIDNonce,
+				consensushashing.TransactionHash(transaction))
+		}, true, true)
+	transactions, missingIndexes, err := reconstructCompactBlockTransactions(msgCompactBlock, blockHash,
+		mempoolTransactions)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Reconstructed %d/%d transactions of compact block %s from the mempool",
+		len(transactions)-len(missingIndexes), len(transactions), blockHash)
+
+	if len(missingIndexes) > 0 {
+		err := flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestBlockTransactions(blockHash, missingIndexes))
+		if err != nil {
+			return nil, err
+		}
+
+		msgBlockTransactions, err := flow.readMsgBlockTransactions()
+		if err != nil {
+			return nil, err
+		}
+		if !msgBlockTransactions.Hash.Equal(blockHash) {
+			return nil, protocolerrors.Errorf(true, "got transactions of unrequested block %s",
+				msgBlockTransactions.Hash)
+		}
+		if len(msgBlockTransactions.Transactions) != len(missingIndexes) {
+			return nil, protocolerrors.Errorf(true, "requested %d transactions of block %s but got %d",
+				len(missingIndexes), blockHash, len(msgBlockTransactions.Transactions))
+		}
+		for i, index := range missingIndexes {
+			transactions[index] = appmessage.MsgTxToDomainTransaction(msgBlockTransactions.Transactions[i])
+		}
+	}
+
+	// A mismatching merkle root means that a short ID collided with a different
+	// transaction in our mempool, so we can't tell which transactions are wrong
+	if !merkle.CalculateHashMerkleRoot(transactions).Equal(header.HashMerkleRoot()) {
+		log.Debugf("Reconstructed compact block %s does not match its hash merkle root", blockHash)
+		return nil, nil
+	}
+
+	return &externalapi.DomainBlock{
+		Header:       header,
+		Transactions: transactions,
+	}, nil
+}
+
+// reconstructCompactBlockTransactions places the prefilled transactions of the given compact block
+// in their place, and fills the rest with the given mempool transactions that match the short IDs.
+// mempoolTransactions maps a short ID that matches more than one mempool transaction to nil.
+// It returns the indexes of the transactions that could not be found.
+func reconstructCompactBlockTransactions(msgCompactBlock *appmessage.MsgCompactBlock,
+	blockHash *externalapi.DomainHash, mempoolTransactions map[uint64]*externalapi.DomainTransaction) (
+	transactions []*externalapi.DomainTransaction, missingIndexes []uint32, err error) {
+
+	transactionCount := msgCompactBlock.TransactionCount()
+	if transactionCount == 0 {
+		return nil, nil, protocolerrors.Errorf(true, "got compact block %s with no transactions", blockHash)
+	}
+	transactions = make([]*externalapi.DomainTransaction, transactionCount)
+
+	isPrefilled := make([]bool, transactionCount)
+	for i, prefilledTransaction := range msgCompactBlock.PrefilledTransactions {
+		if int(prefilledTransaction.Index) >= transactionCount {
+			return nil, nil, protocolerrors.Errorf(true, "prefilled transaction index %d is out of range "+
+				"in compact block %s", prefilledTransaction.Index, blockHash)
+		}
+		if i > 0 && prefilledTransaction.Index <= msgCompactBlock.PrefilledTransactions[i-1].Index {
+			return nil, nil, protocolerrors.Errorf(true, "prefilled transactions of compact block %s "+
+				"are not sorted by their index", blockHash)
+		}
+		transactions[prefilledTransaction.Index] = appmessage.MsgTxToDomainTransaction(prefilledTransaction.Tx)
+		isPrefilled[prefilledTransaction.Index] = true
+	}
+
+	shortIDIndex := 0
+	for i := range transactions {
+		if isPrefilled[i] {
+			continue
+		}
+		transaction := mempoolTransactions[msgCompactBlock.ShortIDs[shortIDIndex]]
+		shortIDIndex++
+		if transaction == nil {
+			missingIndexes = append(missingIndexes, uint32(i))
+			continue
+		}
+		// Mempool transactions are converted to their wire form and back, so
+		// that the block gets its own copy without any populated UTXO entries
+		transactions[i] = appmessage.MsgTxToDomainTransaction(appmessage.DomainTransactionToMsgTx(transaction))
+	}
+
+	return transactions, missingIndexes, nil
+}
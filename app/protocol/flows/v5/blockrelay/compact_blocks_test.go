@@ -0,0 +1,82 @@
+package blockrelay
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/app/protocol/protocolerrors"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/blockheader"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/merkle"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/pkg/errors"
+)
+
+func TestReconstructCompactBlockTransactions(t *testing.T) {
+	transactions := make([]*externalapi.DomainTransaction, 5)
+	for i := range transactions {
+		transactions[i] = &externalapi.DomainTransaction{
+			Outputs: []*externalapi.DomainTransactionOutput{{
+				Value:           uint64(i + 1),
+				ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{byte(i)}},
+			}},
+			SubnetworkID: subnetworks.SubnetworkIDNative,
+			Payload:      []byte{},
+		}
+	}
+	transactions[0].SubnetworkID = subnetworks.SubnetworkIDCoinbase
+
+	unrelatedTransaction := &externalapi.DomainTransaction{
+		SubnetworkID: subnetworks.SubnetworkIDNative,
+		Payload:      []byte{1, 2, 3},
+	}
+	// A transaction with the same ID as one of the block's transactions but a different
+	// signature script must not be taken in its place
+	malleatedTransaction := transactions[2].Clone()
+	malleatedTransaction.Inputs = []*externalapi.DomainTransactionInput{{SignatureScript: []byte{1}}}
+	transactions[2].Inputs = []*externalapi.DomainTransactionInput{{SignatureScript: []byte{2}}}
+	if !consensushashing.TransactionID(malleatedTransaction).Equal(consensushashing.TransactionID(transactions[2])) {
+		t.Fatalf("The malleated transaction is expected to have the same ID as the original one")
+	}
+	header := blockheader.NewImmutableBlockHeader(0, nil, merkle.CalculateHashMerkleRoot(transactions),
+		&externalapi.DomainHash{}, &externalapi.DomainHash{}, 0, 0, 0, 0, 0, big.NewInt(0), &externalapi.DomainHash{})
+	block := &externalapi.DomainBlock{Header: header, Transactions: transactions}
+	blockHash := consensushashing.BlockHash(block)
+
+	msgCompactBlock := appmessage.DomainBlockToMsgCompactBlock(block, 42)
+	if msgCompactBlock.TransactionCount() != len(transactions) {
+		t.Fatalf("Unexpected transaction count. Want: %d, got: %d",
+			len(transactions), msgCompactBlock.TransactionCount())
+	}
+
+	mempoolTransactions := make(map[uint64]*externalapi.DomainTransaction)
+	for _, transaction := range []*externalapi.DomainTransaction{
+		transactions[3], unrelatedTransaction, transactions[1], malleatedTransaction} {
+
+		shortID := appmessage.CompactBlockShortID(blockHash, msgCompactBlock.ShortIDNonce,
+			consensushashing.TransactionHash(transaction))
+		mempoolTransactions[shortID] = transaction
+	}
+	reconstructed, missingIndexes, err := reconstructCompactBlockTransactions(msgCompactBlock, blockHash,
+		mempoolTransactions)
+	if err != nil {
+		t.Fatalf("reconstructCompactBlockTransactions: %+v", err)
+	}
+	if len(missingIndexes) != 2 || missingIndexes[0] != 2 || missingIndexes[1] != 4 {
+		t.Fatalf("Unexpected missing indexes. Want: [2 4], got: %v", missingIndexes)
+	}
+	for _, index := range missingIndexes {
+		reconstructed[index] = transactions[index]
+	}
+	if !merkle.CalculateHashMerkleRoot(reconstructed).Equal(header.HashMerkleRoot()) {
+		t.Fatalf("The reconstructed transactions do not match the block's hash merkle root")
+	}
+
+	msgCompactBlock.PrefilledTransactions[0].Index = uint32(len(transactions))
+	_, _, err = reconstructCompactBlockTransactions(msgCompactBlock, blockHash, mempoolTransactions)
+	if !errors.As(err, &protocolerrors.ProtocolError{}) {
+		t.Fatalf("Expected a protocol error for an out of range prefilled transaction, got: %+v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package blockrelay
 
 import (
+	"math/rand"
+
 	"github.com/kaspikr/kaspid/app/appmessage"
 	peerpkg "github.com/kaspikr/kaspid/app/protocol/peer"
 	"github.com/kaspikr/kaspid/app/protocol/protocolerrors"
 	"github.com/kaspikr/kaspid/domain"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
 	"github.com/pkg/errors"
 )
@@ -14,8 +17,9 @@ type RelayBlockRequestsContext interface {
 	Domain() domain.Domain
 }
 
-// HandleRelayBlockRequests listens to appmessage.MsgRequestRelayBlocks messages and sends
-// their corresponding blocks to the requesting peer.
+// HandleRelayBlockRequests listens to appmessage.MsgRequestRelayBlocks, appmessage.MsgRequestCompactBlock
+// and appmessage.MsgRequestBlockTransactions messages and sends their corresponding blocks or block
+// transactions to the requesting peer.
 func HandleRelayBlockRequests(context RelayBlockRequestsContext, incomingRoute *router.Route,
 	outgoingRoute *router.Route, peer *peerpkg.Peer) error {
 
@@ -24,26 +28,95 @@ func HandleRelayBlockRequests(context RelayBlockRequestsContext, incomingRoute *
 		if err != nil {
 			return err
 		}
-		getRelayBlocksMessage := message.(*appmessage.MsgRequestRelayBlocks)
-		log.Debugf("Got request for relay blocks with hashes %s", getRelayBlocksMessage.Hashes)
-		for _, hash := range getRelayBlocksMessage.Hashes {
-			// Fetch the block from the database.
-			block, found, err := context.Domain().Consensus().GetBlock(hash)
-			if err != nil {
-				return errors.Wrapf(err, "unable to fetch requested block hash %s", hash)
-			}
-
-			if !found {
-				return protocolerrors.Errorf(false, "Relay block %s not found", hash)
-			}
-
-			// TODO (Partial nodes): Convert block to partial block if needed
-
-			err = outgoingRoute.Enqueue(appmessage.DomainBlockToMsgBlock(block))
-			if err != nil {
-				return err
-			}
-			log.Debugf("Relayed block with hash %s", hash)
+
+		switch message := message.(type) {
+		case *appmessage.MsgRequestRelayBlocks:
+			err = sendRelayBlocks(context, outgoingRoute, message)
+		case *appmessage.MsgRequestCompactBlock:
+			err = sendCompactBlock(context, outgoingRoute, message)
+		case *appmessage.MsgRequestBlockTransactions:
+			err = sendBlockTransactions(context, outgoingRoute, message)
+		default:
+			err = protocolerrors.Errorf(true, "unexpected message %s in HandleRelayBlockRequests", message.Command())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func sendRelayBlocks(context RelayBlockRequestsContext, outgoingRoute *router.Route,
+	getRelayBlocksMessage *appmessage.MsgRequestRelayBlocks) error {
+
+	log.Debugf("Got request for relay blocks with hashes %s", getRelayBlocksMessage.Hashes)
+	for _, hash := range getRelayBlocksMessage.Hashes {
+		block, err := getRelayBlock(context, hash)
+		if err != nil {
+			return err
+		}
+
+		// TODO (Partial nodes): Convert block to partial block if needed
+
+		err = outgoingRoute.Enqueue(appmessage.DomainBlockToMsgBlock(block))
+		if err != nil {
+			return err
+		}
+		log.Debugf("Relayed block with hash %s", hash)
+	}
+	return nil
+}
+
+func sendCompactBlock(context RelayBlockRequestsContext, outgoingRoute *router.Route,
+	requestCompactBlockMessage *appmessage.MsgRequestCompactBlock) error {
+
+	hash := requestCompactBlockMessage.Hash
+	log.Debugf("Got request for compact block with hash %s", hash)
+	block, err := getRelayBlock(context, hash)
+	if err != nil {
+		return err
+	}
+
+	// The nonce makes short ID collisions differ between peers and blocks,
+	// so that no transaction could be crafted to collide with others everywhere
+	err = outgoingRoute.Enqueue(appmessage.DomainBlockToMsgCompactBlock(block, rand.Uint64()))
+	if err != nil {
+		return err
+	}
+	log.Debugf("Relayed compact block with hash %s", hash)
+	return nil
+}
+
+func sendBlockTransactions(context RelayBlockRequestsContext, outgoingRoute *router.Route,
+	requestBlockTransactionsMessage *appmessage.MsgRequestBlockTransactions) error {
+
+	hash := requestBlockTransactionsMessage.Hash
+	log.Debugf("Got request for %d transactions of block %s", len(requestBlockTransactionsMessage.Indexes), hash)
+	block, err := getRelayBlock(context, hash)
+	if err != nil {
+		return err
+	}
+
+	transactions := make([]*appmessage.MsgTx, len(requestBlockTransactionsMessage.Indexes))
+	for i, index := range requestBlockTransactionsMessage.Indexes {
+		if int(index) >= len(block.Transactions) {
+			return protocolerrors.Errorf(true, "requested transaction index %d is out of range in block %s",
+				index, hash)
 		}
+		transactions[i] = appmessage.DomainTransactionToMsgTx(block.Transactions[index])
+	}
+
+	return outgoingRoute.Enqueue(appmessage.NewMsgBlockTransactions(hash, transactions))
+}
+
+func getRelayBlock(context RelayBlockRequestsContext, hash *externalapi.DomainHash) (*externalapi.DomainBlock, error) {
+	// Fetch the block from the database.
+	block, found, err := context.Domain().Consensus().GetBlock(hash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to fetch requested block hash %s", hash)
+	}
+
+	if !found {
+		return nil, protocolerrors.Errorf(false, "Relay block %s not found", hash)
 	}
+	return block, nil
 }
@@ -282,6 +282,17 @@ func (flow *handleRelayInvsFlow) requestBlock(requestHash *externalapi.DomainHas
 	// clean from any pending blocks.
 	defer flow.SharedRequestedBlocks().Remove(requestHash)
 
+	if flow.peer.ProtocolVersion() >= compactBlocksProtocolVersion {
+		block, err := flow.requestCompactBlock(requestHash)
+		if err != nil {
+			return nil, false, err
+		}
+		if block != nil {
+			return block, false, nil
+		}
+		log.Debugf("Failed to reconstruct compact block %s. Requesting the full block", requestHash)
+	}
+
 	getRelayBlocksMsg := appmessage.NewMsgRequestRelayBlocks([]*externalapi.DomainHash{requestHash})
 	err := flow.outgoingRoute.Enqueue(getRelayBlocksMsg)
 	if err != nil {
@@ -303,9 +314,54 @@ func (flow *handleRelayInvsFlow) requestBlock(requestHash *externalapi.DomainHas
 }
 
 // readMsgBlock returns the next msgBlock in msgChan, and populates invsQueue with any inv messages that meanwhile arrive.
+func (flow *handleRelayInvsFlow) readMsgBlock() (*appmessage.MsgBlock, error) {
+	message, err := flow.readRelayMessage()
+	if err != nil {
+		return nil, err
+	}
+	msgBlock, ok := message.(*appmessage.MsgBlock)
+	if !ok {
+		return nil, protocolerrors.Errorf(true, "unexpected message %s while expecting a block", message.Command())
+	}
+	return msgBlock, nil
+}
+
+// readMsgCompactBlock returns the next msgCompactBlock in msgChan, and populates invsQueue with any inv
+// messages that meanwhile arrive.
+func (flow *handleRelayInvsFlow) readMsgCompactBlock() (*appmessage.MsgCompactBlock, error) {
+	message, err := flow.readRelayMessage()
+	if err != nil {
+		return nil, err
+	}
+	msgCompactBlock, ok := message.(*appmessage.MsgCompactBlock)
+	if !ok {
+		return nil, protocolerrors.Errorf(true, "unexpected message %s while expecting a compact block",
+			message.Command())
+	}
+	return msgCompactBlock, nil
+}
+
+// readMsgBlockTransactions returns the next msgBlockTransactions in msgChan, and populates invsQueue with
+// any inv messages that meanwhile arrive.
+func (flow *handleRelayInvsFlow) readMsgBlockTransactions() (*appmessage.MsgBlockTransactions, error) {
+	message, err := flow.readRelayMessage()
+	if err != nil {
+		return nil, err
+	}
+	msgBlockTransactions, ok := message.(*appmessage.MsgBlockTransactions)
+	if !ok {
+		return nil, protocolerrors.Errorf(true, "unexpected message %s while expecting block transactions",
+			message.Command())
+	}
+	return msgBlockTransactions, nil
+}
+
+// readRelayMessage returns the next message in msgChan that is not an inv, and populates invsQueue with
+// any inv messages that meanwhile arrive.
 //
-// Note: this function assumes msgChan can contain only appmessage.MsgInvRelayBlock and appmessage.MsgBlock messages.
-func (flow *handleRelayInvsFlow) readMsgBlock() (msgBlock *appmessage.MsgBlock, err error) {
+// Note: this function assumes msgChan can contain only appmessage.MsgInvRelayBlock, appmessage.MsgBlock,
+// appmessage.MsgCompactBlock and appmessage.MsgBlockTransactions messages.
+func (flow *handleRelayInvsFlow) readRelayMessage() (appmessage.Message, error) {
 	for {
 		message, err := flow.incomingRoute.DequeueWithTimeout(common.DefaultTimeout)
 		if err != nil {
@@ -315,7 +371,7 @@ func (flow *handleRelayInvsFlow) readMsgBlock() (msgBlock *appmessage.MsgBlock,
 		switch message := message.(type) {
 		case *appmessage.MsgInvRelayBlock:
 			flow.invsQueue = append(flow.invsQueue, invRelayBlock{Hash: message.Hash, IsOrphanRoot: false})
-		case *appmessage.MsgBlock:
+		case *appmessage.MsgBlock, *appmessage.MsgCompactBlock, *appmessage.MsgBlockTransactions:
 			return message, nil
 		default:
 			return nil, errors.Errorf("unexpected message %s", message.Command())
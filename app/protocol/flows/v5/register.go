@@ -24,10 +24,25 @@ type protocolManager interface {
 	Context() *flowcontext.FlowContext
 }
 
+// BlockRelayMessages are the messages that later protocol versions route to the block relay
+// flows, in addition to the ones of this version
+type BlockRelayMessages struct {
+	RelayInvs          []appmessage.MessageCommand
+	RelayBlockRequests []appmessage.MessageCommand
+}
+
 // Register is used in order to register all the protocol flows to the given router.
 func Register(m protocolManager, router *routerpkg.Router, errChan chan error, isStopping *uint32) (flows []*common.Flow) {
+	return RegisterWithBlockRelayMessages(m, router, errChan, isStopping, BlockRelayMessages{})
+}
+
+// RegisterWithBlockRelayMessages registers all the protocol flows to the given router, routing the given
+// additional messages to the block relay flows. Later protocol versions use it to run the flows of this version.
+func RegisterWithBlockRelayMessages(m protocolManager, router *routerpkg.Router, errChan chan error, isStopping *uint32,
+	blockRelayMessages BlockRelayMessages) (flows []*common.Flow) {
+
 	flows = registerAddressFlows(m, router, isStopping, errChan)
-	flows = append(flows, registerBlockRelayFlows(m, router, isStopping, errChan)...)
+	flows = append(flows, registerBlockRelayFlows(m, router, isStopping, errChan, blockRelayMessages)...)
 	flows = append(flows, registerPingFlows(m, router, isStopping, errChan)...)
 	flows = append(flows, registerTransactionRelayFlow(m, router, isStopping, errChan)...)
 	flows = append(flows, registerRejectsFlow(m, router, isStopping, errChan)...)
@@ -41,7 +56,7 @@ func registerAddressFlows(m protocolManager, router *routerpkg.Router, isStoppin
 	return []*common.Flow{
 		m.RegisterFlow("SendAddresses", router, []appmessage.MessageCommand{appmessage.CmdRequestAddresses}, isStopping, errChan,
 			func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
-				return addressexchange.SendAddresses(m.Context(), incomingRoute, outgoingRoute)
+				return addressexchange.SendAddresses(m.Context(), incomingRoute, outgoingRoute, peer)
 			},
 		),
 
@@ -53,7 +68,9 @@ func registerAddressFlows(m protocolManager, router *routerpkg.Router, isStoppin
 	}
 }
 
-func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStopping *uint32, errChan chan error) []*common.Flow {
+func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStopping *uint32, errChan chan error,
+	blockRelayMessages BlockRelayMessages) []*common.Flow {
+
 	outgoingRoute := router.OutgoingRoute()
 
 	return []*common.Flow{
@@ -62,9 +79,9 @@ func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStop
 				return blockrelay.SendVirtualSelectedParentInv(m.Context(), outgoingRoute, peer)
 			}),
 
-		m.RegisterFlow("HandleRelayInvs", router, []appmessage.MessageCommand{
+		m.RegisterFlow("HandleRelayInvs", router, append([]appmessage.MessageCommand{
 			appmessage.CmdInvRelayBlock, appmessage.CmdBlock, appmessage.CmdBlockLocator,
-		},
+		}, blockRelayMessages.RelayInvs...),
 			isStopping, errChan, func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
 				return blockrelay.HandleRelayInvs(m.Context(), incomingRoute,
 					outgoingRoute, peer)
@@ -86,7 +103,9 @@ func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStop
 			},
 		),
 
-		m.RegisterFlow("HandleRelayBlockRequests", router, []appmessage.MessageCommand{appmessage.CmdRequestRelayBlocks}, isStopping, errChan,
+		m.RegisterFlow("HandleRelayBlockRequests", router,
+			append([]appmessage.MessageCommand{appmessage.CmdRequestRelayBlocks}, blockRelayMessages.RelayBlockRequests...),
+			isStopping, errChan,
 			func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
 				return blockrelay.HandleRelayBlockRequests(m.Context(), incomingRoute, outgoingRoute, peer)
 			},
@@ -0,0 +1,55 @@
+package v6
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/app/protocol/common"
+	"github.com/kaspikr/kaspid/app/protocol/flowcontext"
+	"github.com/kaspikr/kaspid/app/protocol/flows/v5"
+	"github.com/kaspikr/kaspid/app/protocol/flows/v6/transactionreconciliation"
+	peerpkg "github.com/kaspikr/kaspid/app/protocol/peer"
+	routerpkg "github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
+)
+
+type protocolManager interface {
+	RegisterFlow(name string, router *routerpkg.Router, messageTypes []appmessage.MessageCommand, isStopping *uint32,
+		errChan chan error, initializeFunc common.FlowInitializeFunc) *common.Flow
+	RegisterOneTimeFlow(name string, router *routerpkg.Router, messageTypes []appmessage.MessageCommand,
+		isStopping *uint32, stopChan chan error, initializeFunc common.FlowInitializeFunc) *common.Flow
+	RegisterFlowWithCapacity(name string, capacity int, router *routerpkg.Router,
+		messageTypes []appmessage.MessageCommand, isStopping *uint32,
+		errChan chan error, initializeFunc common.FlowInitializeFunc) *common.Flow
+	Context() *flowcontext.FlowContext
+}
+
+// Register is used in order to register all the protocol flows to the given router.
+// Protocol version 6 runs the flows of version 5, and additionally relays blocks in
+// their compact form and optionally announces transactions by set reconciliation.
+func Register(m protocolManager, router *routerpkg.Router, errChan chan error, isStopping *uint32) (flows []*common.Flow) {
+	flows = v5.RegisterWithBlockRelayMessages(m, router, errChan, isStopping, v5.BlockRelayMessages{
+		RelayInvs: []appmessage.MessageCommand{appmessage.CmdCompactBlock, appmessage.CmdBlockTransactions},
+		RelayBlockRequests: []appmessage.MessageCommand{
+			appmessage.CmdRequestCompactBlock, appmessage.CmdRequestBlockTransactions,
+		},
+	})
+	flows = append(flows, registerTransactionReconciliationFlow(m, router, isStopping, errChan)...)
+
+	return flows
+}
+
+func registerTransactionReconciliationFlow(m protocolManager, router *routerpkg.Router, isStopping *uint32,
+	errChan chan error) []*common.Flow {
+
+	outgoingRoute := router.OutgoingRoute()
+
+	return []*common.Flow{
+		m.RegisterFlow("HandleTransactionReconciliation", router,
+			[]appmessage.MessageCommand{appmessage.CmdRequestTransactionReconciliation,
+				appmessage.CmdTransactionReconciliationSketch, appmessage.CmdTransactionReconciliationDifference},
+			isStopping, errChan,
+			func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
+				return transactionreconciliation.HandleTransactionReconciliation(m.Context(), incomingRoute,
+					outgoingRoute, peer)
+			},
+		),
+	}
+}
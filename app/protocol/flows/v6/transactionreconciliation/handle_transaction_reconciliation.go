@@ -0,0 +1,242 @@
+package transactionreconciliation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/app/protocol/common"
+	"github.com/kaspikr/kaspid/app/protocol/flowcontext"
+	peerpkg "github.com/kaspikr/kaspid/app/protocol/peer"
+	"github.com/kaspikr/kaspid/app/protocol/protocolerrors"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/hashes"
+	"github.com/kaspikr/kaspid/infrastructure/config"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
+)
+
+// TransactionReconciliationContext is the interface for the context needed for the
+// HandleTransactionReconciliation flow.
+type TransactionReconciliationContext interface {
+	Config() *config.Config
+	NetAdapter() *netadapter.NetAdapter
+	ShutdownChan() <-chan struct{}
+	RegisterTransactionReconciliationPeer(peer *peerpkg.Peer) *flowcontext.TransactionReconciliationSet
+	UnregisterTransactionReconciliationPeer(peer *peerpkg.Peer)
+}
+
+type handleTransactionReconciliationFlow struct {
+	TransactionReconciliationContext
+	incomingRoute, outgoingRoute *router.Route
+	peer                         *peerpkg.Peer
+	set                          *flowcontext.TransactionReconciliationSet
+	shortIDSalt                  []byte
+}
+
+// HandleTransactionReconciliation periodically reconciles the sets of transaction IDs pending
+// announcement between us and the peer, and announces to each side only the transactions it's
+// missing. Rounds are initiated by the side that made the outbound connection.
+// The flow exits immediately unless both sides have enabled transaction reconciliation.
+func HandleTransactionReconciliation(context TransactionReconciliationContext, incomingRoute *router.Route,
+	outgoingRoute *router.Route, peer *peerpkg.Peer) error {
+
+	if !context.Config().TxReconciliation || !peer.HasService(appmessage.SFNodeTxReconciliation) {
+		return nil
+	}
+
+	shortIDSalt, err := transactionShortIDSalt(context.NetAdapter(), peer)
+	if err != nil {
+		return err
+	}
+
+	set := context.RegisterTransactionReconciliationPeer(peer)
+	defer context.UnregisterTransactionReconciliationPeer(peer)
+
+	flow := &handleTransactionReconciliationFlow{
+		TransactionReconciliationContext: context,
+		incomingRoute:                    incomingRoute,
+		outgoingRoute:                    outgoingRoute,
+		peer:                             peer,
+		set:                              set,
+		shortIDSalt:                      shortIDSalt,
+	}
+	if peer.IsOutbound() {
+		return flow.initiateRounds()
+	}
+	return flow.respondToRounds()
+}
+
+func (flow *handleTransactionReconciliationFlow) initiateRounds() error {
+	ticker := time.NewTicker(flowcontext.TransactionReconciliationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-flow.ShutdownChan():
+			return nil
+		case <-ticker.C:
+		}
+
+		err := flow.initiateRound()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (flow *handleTransactionReconciliationFlow) initiateRound() error {
+	transactionIDs := flow.set.TakeSnapshot()
+	err := flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestTransactionReconciliation(uint32(len(transactionIDs))))
+	if err != nil {
+		return err
+	}
+
+	message, err := flow.incomingRoute.DequeueWithTimeout(common.DefaultTimeout)
+	if err != nil {
+		return err
+	}
+	msgSketch, ok := message.(*appmessage.MsgTransactionReconciliationSketch)
+	if !ok {
+		return protocolerrors.Errorf(true, "unexpected %s message in the transaction reconciliation flow "+
+			"while expecting a sketch", message.Command())
+	}
+
+	remoteSketch, err := sketchFromCells(msgSketch.Cells)
+	if err != nil {
+		return err
+	}
+	localSketch, transactionIDsByShortID := flow.buildSketch(len(remoteSketch.cells), transactionIDs)
+	remoteSketch.subtract(localSketch)
+	remoteOnlyShortIDs, localOnlyShortIDs, ok := remoteSketch.decode()
+	if !ok {
+		log.Debugf("Failed to decode the transaction reconciliation sketch of %s. "+
+			"Announcing all %d pending transactions instead", flow.peer, len(transactionIDs))
+		err := flow.outgoingRoute.Enqueue(appmessage.NewMsgTransactionReconciliationDifference(false, nil))
+		if err != nil {
+			return err
+		}
+		return flow.announce(transactionIDs)
+	}
+
+	log.Debugf("Reconciled transactions with %s: %d are missing locally and %d are missing remotely",
+		flow.peer, len(remoteOnlyShortIDs), len(localOnlyShortIDs))
+	err = flow.outgoingRoute.Enqueue(appmessage.NewMsgTransactionReconciliationDifference(true, remoteOnlyShortIDs))
+	if err != nil {
+		return err
+	}
+	return flow.announce(selectTransactionIDs(transactionIDsByShortID, localOnlyShortIDs))
+}
+
+func (flow *handleTransactionReconciliationFlow) respondToRounds() error {
+	for {
+		err := flow.respondToRound()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (flow *handleTransactionReconciliationFlow) respondToRound() error {
+	message, err := flow.incomingRoute.Dequeue()
+	if err != nil {
+		return err
+	}
+	msgRequest, ok := message.(*appmessage.MsgRequestTransactionReconciliation)
+	if !ok {
+		return protocolerrors.Errorf(true, "unexpected %s message in the transaction reconciliation flow "+
+			"while expecting a reconciliation request", message.Command())
+	}
+
+	transactionIDs := flow.set.TakeSnapshot()
+	cellCount := estimateSketchCellCount(len(transactionIDs), int(msgRequest.SetSize))
+	localSketch, transactionIDsByShortID := flow.buildSketch(cellCount, transactionIDs)
+	err = flow.outgoingRoute.Enqueue(appmessage.NewMsgTransactionReconciliationSketch(localSketch.cells))
+	if err != nil {
+		return err
+	}
+
+	message, err = flow.incomingRoute.DequeueWithTimeout(common.DefaultTimeout)
+	if err != nil {
+		return err
+	}
+	msgDifference, ok := message.(*appmessage.MsgTransactionReconciliationDifference)
+	if !ok {
+		return protocolerrors.Errorf(true, "unexpected %s message in the transaction reconciliation flow "+
+			"while expecting a reconciliation difference", message.Command())
+	}
+
+	if !msgDifference.Succeeded {
+		return flow.announce(transactionIDs)
+	}
+	return flow.announce(selectTransactionIDs(transactionIDsByShortID, msgDifference.ShortIDs))
+}
+
+func (flow *handleTransactionReconciliationFlow) buildSketch(cellCount int,
+	transactionIDs []*externalapi.DomainTransactionID) (*sketch, map[uint64]*externalapi.DomainTransactionID) {
+
+	localSketch := newSketch(cellCount)
+	transactionIDsByShortID := make(map[uint64]*externalapi.DomainTransactionID, len(transactionIDs))
+	for _, transactionID := range transactionIDs {
+		shortID := transactionShortID(flow.shortIDSalt, transactionID)
+		localSketch.add(shortID)
+		transactionIDsByShortID[shortID] = transactionID
+	}
+	return localSketch, transactionIDsByShortID
+}
+
+// selectTransactionIDs returns the transaction IDs matching the given short IDs.
+// Short IDs that don't match any transaction ID are ignored.
+func selectTransactionIDs(transactionIDsByShortID map[uint64]*externalapi.DomainTransactionID,
+	shortIDs []uint64) []*externalapi.DomainTransactionID {
+
+	transactionIDs := make([]*externalapi.DomainTransactionID, 0, len(shortIDs))
+	for _, shortID := range shortIDs {
+		transactionID, ok := transactionIDsByShortID[shortID]
+		if !ok {
+			continue
+		}
+		transactionIDs = append(transactionIDs, transactionID)
+	}
+	return transactionIDs
+}
+
+func (flow *handleTransactionReconciliationFlow) announce(transactionIDs []*externalapi.DomainTransactionID) error {
+	for len(transactionIDs) > 0 {
+		transactionIDsToAnnounce := transactionIDs
+		if len(transactionIDsToAnnounce) > appmessage.MaxInvPerTxInvMsg {
+			transactionIDsToAnnounce = transactionIDsToAnnounce[:appmessage.MaxInvPerTxInvMsg]
+		}
+		err := flow.outgoingRoute.Enqueue(appmessage.NewMsgInvTransaction(transactionIDsToAnnounce))
+		if err != nil {
+			return err
+		}
+		transactionIDs = transactionIDs[len(transactionIDsToAnnounce):]
+	}
+	return nil
+}
+
+// transactionShortIDSalt returns a salt that is shared by both sides of the connection and is
+// unique to it, so that short ID collisions are not repeated across connections
+func transactionShortIDSalt(netAdapter *netadapter.NetAdapter, peer *peerpkg.Peer) ([]byte, error) {
+	localID, err := netAdapter.ID().SerializeToBytes()
+	if err != nil {
+		return nil, err
+	}
+	remoteID, err := peer.ID().SerializeToBytes()
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Compare(localID, remoteID) > 0 {
+		localID, remoteID = remoteID, localID
+	}
+	return append(localID, remoteID...), nil
+}
+
+func transactionShortID(salt []byte, transactionID *externalapi.DomainTransactionID) uint64 {
+	writer := hashes.NewTransactionReconciliationShortIDWriter()
+	writer.InfallibleWrite(salt)
+	writer.InfallibleWrite(transactionID.ByteSlice())
+	return binary.LittleEndian.Uint64(writer.Finalize().ByteSlice()[:8])
+}
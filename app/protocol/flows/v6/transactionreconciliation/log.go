@@ -0,0 +1,7 @@
+package transactionreconciliation
+
+import (
+	"github.com/kaspikr/kaspid/infrastructure/logger"
+)
+
+var log = logger.RegisterSubSystem("PROT")
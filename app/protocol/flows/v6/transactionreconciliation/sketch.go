@@ -0,0 +1,160 @@
+package transactionreconciliation
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/app/protocol/protocolerrors"
+)
+
+// sketchHashCount is the number of cells every short ID is added to.
+// The cells of a sketch are split into sketchHashCount equal partitions,
+// and every short ID is added to exactly one cell in each of them.
+const sketchHashCount = 3
+
+const (
+	minSketchCellCount = 4 * sketchHashCount
+
+	// sketchDifferenceMultiplier is the number of cells that are allocated per
+	// expected difference between the sets. Invertible bloom lookup tables with
+	// three hash functions are decodable with high probability with about 1.3
+	// cells per difference, and the rest is a margin for estimation errors.
+	sketchDifferenceMultiplier = 2
+
+	// sketchOverlapDivisor approximates the portion of the smaller set
+	// that is not expected to be found in the other set.
+	sketchOverlapDivisor = 4
+)
+
+// sketch is an invertible bloom lookup table of transaction short IDs. Subtracting the sketch
+// of one set from the sketch of another allows to recover the symmetric difference between the
+// sets, as long as it is small enough relative to the number of cells.
+type sketch struct {
+	cells []*appmessage.TransactionReconciliationSketchCell
+}
+
+// estimateSketchCellCount returns the number of cells for a sketch that is expected to
+// be able to recover the difference between sets of the given sizes.
+func estimateSketchCellCount(localSetSize, remoteSetSize int) int {
+	largerSetSize, smallerSetSize := localSetSize, remoteSetSize
+	if largerSetSize < smallerSetSize {
+		largerSetSize, smallerSetSize = smallerSetSize, largerSetSize
+	}
+	expectedDifference := largerSetSize - smallerSetSize + smallerSetSize/sketchOverlapDivisor + 1
+
+	cellCount := minSketchCellCount + expectedDifference*sketchDifferenceMultiplier
+	if cellCount > appmessage.MaxTransactionReconciliationSketchCells {
+		cellCount = appmessage.MaxTransactionReconciliationSketchCells
+	}
+	return cellCount - cellCount%sketchHashCount
+}
+
+func newSketch(cellCount int) *sketch {
+	cells := make([]*appmessage.TransactionReconciliationSketchCell, cellCount)
+	for i := range cells {
+		cells[i] = &appmessage.TransactionReconciliationSketchCell{}
+	}
+	return &sketch{cells: cells}
+}
+
+// sketchFromCells returns the sketch made of the given cells, which were received from a peer
+func sketchFromCells(cells []*appmessage.TransactionReconciliationSketchCell) (*sketch, error) {
+	if len(cells) < minSketchCellCount || len(cells)%sketchHashCount != 0 {
+		return nil, protocolerrors.Errorf(true, "invalid transaction reconciliation sketch cell count %d",
+			len(cells))
+	}
+	return &sketch{cells: cells}, nil
+}
+
+func (s *sketch) add(shortID uint64) {
+	s.update(shortID, 1)
+}
+
+func (s *sketch) update(shortID uint64, countDelta int32) {
+	checkSum := shortIDCheckSum(shortID)
+	for _, index := range s.cellIndexes(shortID) {
+		cell := s.cells[index]
+		cell.Count += countDelta
+		cell.KeySum ^= shortID
+		cell.CheckSum ^= checkSum
+	}
+}
+
+func (s *sketch) cellIndexes(shortID uint64) [sketchHashCount]int {
+	partitionSize := uint64(len(s.cells) / sketchHashCount)
+	var indexes [sketchHashCount]int
+	for i := range indexes {
+		indexes[i] = int(uint64(i)*partitionSize + mix(shortID+uint64(i)+1)%partitionSize)
+	}
+	return indexes
+}
+
+// subtract removes all the short IDs that were added to other from s
+func (s *sketch) subtract(other *sketch) {
+	for i, cell := range s.cells {
+		otherCell := other.cells[i]
+		cell.Count -= otherCell.Count
+		cell.KeySum ^= otherCell.KeySum
+		cell.CheckSum ^= otherCell.CheckSum
+	}
+}
+
+// decode recovers the short IDs out of a sketch that is the result of subtraction.
+// It returns the short IDs that were found only in the subtracted-from sketch and the
+// short IDs that were found only in the subtracted sketch, or false if the difference
+// between the sketches is too large to be decoded. decode empties the sketch.
+func (s *sketch) decode() (addedShortIDs []uint64, removedShortIDs []uint64, ok bool) {
+	pendingIndexes := make([]int, len(s.cells))
+	for i := range pendingIndexes {
+		pendingIndexes[i] = i
+	}
+
+	// Every peeled short ID empties at least one cell, so a sketch that takes more
+	// peeling steps than it has cells is malformed
+	peelCount := 0
+	for len(pendingIndexes) > 0 && peelCount <= len(s.cells) {
+		var index int
+		index, pendingIndexes = pendingIndexes[len(pendingIndexes)-1], pendingIndexes[:len(pendingIndexes)-1]
+		cell := s.cells[index]
+		if !isPureCell(cell) {
+			continue
+		}
+
+		shortID := cell.KeySum
+		if cell.Count == 1 {
+			addedShortIDs = append(addedShortIDs, shortID)
+			s.update(shortID, -1)
+		} else {
+			removedShortIDs = append(removedShortIDs, shortID)
+			s.update(shortID, 1)
+		}
+		peelCount++
+
+		cellIndexes := s.cellIndexes(shortID)
+		pendingIndexes = append(pendingIndexes, cellIndexes[:]...)
+	}
+
+	for _, cell := range s.cells {
+		if cell.Count != 0 || cell.KeySum != 0 || cell.CheckSum != 0 {
+			return nil, nil, false
+		}
+	}
+	return addedShortIDs, removedShortIDs, true
+}
+
+func isPureCell(cell *appmessage.TransactionReconciliationSketchCell) bool {
+	return (cell.Count == 1 || cell.Count == -1) && cell.CheckSum == shortIDCheckSum(cell.KeySum)
+}
+
+func shortIDCheckSum(shortID uint64) uint32 {
+	return uint32(mix(^shortID) >> 32)
+}
+
+// mix is the finalizer of the SplitMix64 generator. Short IDs are already uniformly
+// distributed, so mix is only used to derive independent values out of them.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,94 @@
+package transactionreconciliation
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+)
+
+func TestSketchDecode(t *testing.T) {
+	random := rand.New(rand.NewSource(0))
+	const commonCount, localOnlyCount, remoteOnlyCount = 1000, 20, 30
+
+	cellCount := estimateSketchCellCount(commonCount+localOnlyCount, commonCount+remoteOnlyCount)
+	localSketch := newSketch(cellCount)
+	remoteSketch := newSketch(cellCount)
+	for i := 0; i < commonCount; i++ {
+		shortID := random.Uint64()
+		localSketch.add(shortID)
+		remoteSketch.add(shortID)
+	}
+	localOnlyShortIDs := make([]uint64, localOnlyCount)
+	for i := range localOnlyShortIDs {
+		localOnlyShortIDs[i] = random.Uint64()
+		localSketch.add(localOnlyShortIDs[i])
+	}
+	remoteOnlyShortIDs := make([]uint64, remoteOnlyCount)
+	for i := range remoteOnlyShortIDs {
+		remoteOnlyShortIDs[i] = random.Uint64()
+		remoteSketch.add(remoteOnlyShortIDs[i])
+	}
+
+	receivedSketch, err := sketchFromCells(remoteSketch.cells)
+	if err != nil {
+		t.Fatalf("sketchFromCells: %+v", err)
+	}
+	receivedSketch.subtract(localSketch)
+	decodedRemoteOnly, decodedLocalOnly, ok := receivedSketch.decode()
+	if !ok {
+		t.Fatalf("Failed to decode a sketch of %d cells with a difference of %d",
+			cellCount, localOnlyCount+remoteOnlyCount)
+	}
+	assertSameShortIDs(t, "remote only", remoteOnlyShortIDs, decodedRemoteOnly)
+	assertSameShortIDs(t, "local only", localOnlyShortIDs, decodedLocalOnly)
+}
+
+func TestSketchDecodeTooLargeDifference(t *testing.T) {
+	random := rand.New(rand.NewSource(0))
+	localSketch := newSketch(minSketchCellCount)
+	remoteSketch := newSketch(minSketchCellCount)
+	for i := 0; i < 10*minSketchCellCount; i++ {
+		remoteSketch.add(random.Uint64())
+	}
+
+	remoteSketch.subtract(localSketch)
+	_, _, ok := remoteSketch.decode()
+	if ok {
+		t.Fatalf("Unexpectedly decoded a sketch with a difference much larger than its size")
+	}
+}
+
+func TestSketchFromCells(t *testing.T) {
+	tests := []struct {
+		cellCount     int
+		expectedValid bool
+	}{
+		{cellCount: 0, expectedValid: false},
+		{cellCount: minSketchCellCount - sketchHashCount, expectedValid: false},
+		{cellCount: minSketchCellCount, expectedValid: true},
+		{cellCount: minSketchCellCount + 1, expectedValid: false},
+		{cellCount: estimateSketchCellCount(appmessage.MaxInvPerTxInvMsg, 0), expectedValid: true},
+	}
+	for _, test := range tests {
+		_, err := sketchFromCells(newSketch(test.cellCount).cells)
+		if (err == nil) != test.expectedValid {
+			t.Errorf("sketchFromCells with %d cells: expected valid: %t, got error: %v",
+				test.cellCount, test.expectedValid, err)
+		}
+	}
+}
+
+func assertSameShortIDs(t *testing.T, name string, expected []uint64, actual []uint64) {
+	sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+	sort.Slice(actual, func(i, j int) bool { return actual[i] < actual[j] })
+	if len(expected) != len(actual) {
+		t.Fatalf("Unexpected %s short IDs. Want: %v, got: %v", name, expected, actual)
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatalf("Unexpected %s short IDs. Want: %v, got: %v", name, expected, actual)
+		}
+	}
+}
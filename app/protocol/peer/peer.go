@@ -31,6 +31,8 @@ type Peer struct {
 	lastPingNonce    uint64        // The nonce of the last ping we sent
 	lastPingTime     time.Time     // Time we sent last ping
 	lastPingDuration time.Duration // Time for last ping to return
+	minPingDuration  time.Duration // Shortest time for a ping to return
+	avgPingDuration  time.Duration // Smoothed average time for a ping to return
 
 	ibdRequestChannel chan *externalapi.DomainBlock // A channel used to communicate IBD requests between flows
 }
@@ -80,6 +82,11 @@ func (p *Peer) ProtocolVersion() uint32 {
 	return p.protocolVersion
 }
 
+// HasService returns whether the peer advertised the given service during the handshake.
+func (p *Peer) HasService(service appmessage.ServiceFlag) bool {
+	return p.services&service == service
+}
+
 // TimeConnected returns the time since the connection to this been has been started.
 func (p *Peer) TimeConnected() time.Duration {
 	return time.Since(p.connectionStarted)
@@ -127,6 +134,15 @@ func (p *Peer) SetPingIdle() {
 
 	p.lastPingNonce = 0
 	p.lastPingDuration = time.Since(p.lastPingTime)
+	if p.minPingDuration == 0 || p.lastPingDuration < p.minPingDuration {
+		p.minPingDuration = p.lastPingDuration
+	}
+	if p.avgPingDuration == 0 {
+		p.avgPingDuration = p.lastPingDuration
+	} else {
+		// Smooth the average like TCP smooths its round-trip time (RFC 6298)
+		p.avgPingDuration += (p.lastPingDuration - p.avgPingDuration) / 8
+	}
 }
 
 func (p *Peer) String() string {
@@ -147,6 +163,24 @@ func (p *Peer) LastPingDuration() time.Duration {
 	return p.lastPingDuration
 }
 
+// MinPingDuration returns the duration of the
+// shortest ping to this peer
+func (p *Peer) MinPingDuration() time.Duration {
+	p.pingLock.Lock()
+	defer p.pingLock.Unlock()
+
+	return p.minPingDuration
+}
+
+// AveragePingDuration returns the smoothed average
+// duration of the pings to this peer
+func (p *Peer) AveragePingDuration() time.Duration {
+	p.pingLock.Lock()
+	defer p.pingLock.Unlock()
+
+	return p.avgPingDuration
+}
+
 // IBDRequestChannel returns the channel used in order to communicate an IBD request between peer flows
 func (p *Peer) IBDRequestChannel() chan *externalapi.DomainBlock {
 	return p.ibdRequestChannel
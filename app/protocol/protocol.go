@@ -4,6 +4,7 @@ import (
 	"github.com/kaspikr/kaspid/app/protocol/common"
 	"github.com/kaspikr/kaspid/app/protocol/flows/ready"
 	"github.com/kaspikr/kaspid/app/protocol/flows/v5"
+	"github.com/kaspikr/kaspid/app/protocol/flows/v6"
 	"sync"
 	"sync/atomic"
 
@@ -41,7 +42,7 @@ func (m *Manager) routerInitializer(router *routerpkg.Router, netConnection *net
 		if err != nil && !errors.Is(err, addressmanager.ErrAddressNotFound) {
 			panic(err)
 		}
-		if isBanned {
+		if isBanned && !netConnection.IsTrusted() {
 			log.Infof("Peer %s is banned. Disconnecting...", netConnection)
 			netConnection.Disconnect()
 			return
@@ -78,6 +79,8 @@ func (m *Manager) routerInitializer(router *routerpkg.Router, netConnection *net
 		switch peer.ProtocolVersion() {
 		case 5:
 			flows = v5.Register(m, router, errChan, &isStopping)
+		case 6:
+			flows = v6.Register(m, router, errChan, &isStopping)
 		default:
 			panic(errors.Errorf("no way to handle protocol version %d", peer.ProtocolVersion()))
 		}
@@ -106,7 +109,9 @@ func (m *Manager) routerInitializer(router *routerpkg.Router, netConnection *net
 
 func (m *Manager) handleError(err error, netConnection *netadapter.NetConnection, outgoingRoute *routerpkg.Route) {
 	if protocolErr := (protocolerrors.ProtocolError{}); errors.As(err, &protocolErr) {
-		if m.context.Config().EnableBanning && protocolErr.ShouldBan {
+		if m.context.Config().EnableBanning && protocolErr.ShouldBan && netConnection.IsTrusted() {
+			log.Warnf("Not banning trusted peer %s (reason: %s)", netConnection, protocolErr.Cause)
+		} else if m.context.Config().EnableBanning && protocolErr.ShouldBan {
 			log.Warnf("Banning %s (reason: %s)", netConnection, protocolErr.Cause)
 
 			err := m.context.ConnectionManager().Ban(netConnection)
@@ -12,6 +12,8 @@ func HandleGetConnectedPeerInfo(context *rpccontext.Context, _ *router.Router, _
 	ibdPeer := context.ProtocolManager.IBDPeer()
 	infos := make([]*appmessage.GetConnectedPeerInfoMessage, 0, len(peers))
 	for _, peer := range peers {
+		trafficStats := peer.Connection().TrafficStats()
+		traffic := trafficStats.Total()
 		info := &appmessage.GetConnectedPeerInfoMessage{
 			ID:                        peer.ID().String(),
 			Address:                   peer.Address(),
@@ -22,9 +24,30 @@ func HandleGetConnectedPeerInfo(context *rpccontext.Context, _ *router.Router, _
 			AdvertisedProtocolVersion: peer.AdvertisedProtocolVersion(),
 			TimeConnected:             peer.TimeConnected().Milliseconds(),
 			IsIBDPeer:                 peer == ibdPeer,
+			MinPingDuration:           peer.MinPingDuration().Milliseconds(),
+			AveragePingDuration:       peer.AveragePingDuration().Milliseconds(),
+			MessagesSent:              traffic.MessagesSent,
+			BytesSent:                 traffic.BytesSent,
+			MessagesReceived:          traffic.MessagesReceived,
+			BytesReceived:             traffic.BytesReceived,
+			MessageTraffic:            messageTrafficInfos(trafficStats),
+			RouteQueues:               routeQueueInfos(peer.Connection().RouteStats()),
 		}
 		infos = append(infos, info)
 	}
 	response := appmessage.NewGetConnectedPeerInfoResponseMessage(infos)
 	return response, nil
 }
+
+func routeQueueInfos(routeStats []router.RouteStats) []*appmessage.RouteQueueInfo {
+	infos := make([]*appmessage.RouteQueueInfo, len(routeStats))
+	for i, stats := range routeStats {
+		infos[i] = &appmessage.RouteQueueInfo{
+			Name:          stats.Name,
+			Length:        uint32(stats.Length),
+			Capacity:      uint32(stats.Capacity),
+			HighWaterMark: uint32(stats.HighWaterMark),
+		}
+	}
+	return infos
+}
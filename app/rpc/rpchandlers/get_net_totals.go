@@ -0,0 +1,32 @@
+package rpchandlers
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/app/rpc/rpccontext"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server"
+)
+
+// HandleGetNetTotals handles the respectively named RPC command
+func HandleGetNetTotals(context *rpccontext.Context, _ *router.Router, _ appmessage.Message) (appmessage.Message, error) {
+	trafficStats := context.NetAdapter.P2PTrafficStats()
+	total := trafficStats.Total()
+	response := appmessage.NewGetNetTotalsResponseMessage(total.MessagesSent, total.BytesSent,
+		total.MessagesReceived, total.BytesReceived, messageTrafficInfos(trafficStats))
+	return response, nil
+}
+
+func messageTrafficInfos(trafficStats *server.TrafficStats) []*appmessage.MessageTrafficInfo {
+	byMessageType := trafficStats.ByMessageType()
+	infos := make([]*appmessage.MessageTrafficInfo, len(byMessageType))
+	for i, traffic := range byMessageType {
+		infos[i] = &appmessage.MessageTrafficInfo{
+			Command:          traffic.Command.String(),
+			MessagesSent:     traffic.MessagesSent,
+			BytesSent:        traffic.BytesSent,
+			MessagesReceived: traffic.MessagesReceived,
+			BytesReceived:    traffic.BytesReceived,
+		}
+	}
+	return infos
+}
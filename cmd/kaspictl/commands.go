@@ -11,6 +11,7 @@ import (
 var commandTypes = []reflect.Type{
 	reflect.TypeOf(protowire.KaspidMessage_AddPeerRequest{}),
 	reflect.TypeOf(protowire.KaspidMessage_GetConnectedPeerInfoRequest{}),
+	reflect.TypeOf(protowire.KaspidMessage_GetNetTotalsRequest{}),
 	reflect.TypeOf(protowire.KaspidMessage_GetPeerAddressesRequest{}),
 	reflect.TypeOf(protowire.KaspidMessage_GetCurrentNetworkRequest{}),
 	reflect.TypeOf(protowire.KaspidMessage_GetInfoRequest{}),
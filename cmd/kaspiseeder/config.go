@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/kaspikr/kaspid/infrastructure/config"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/kaspikr/kaspid/version"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultLogFilename    = "kaspiseeder.log"
+	defaultErrLogFilename = "kaspiseeder_err.log"
+	defaultListen         = "127.0.0.1:5354"
+	defaultGRPCListen     = "0.0.0.0:3737"
+	defaultThreads        = 8
+	defaultMinUptime      = 50
+)
+
+var (
+	// Default configuration options
+	defaultAppDir = util.AppDir("kaspiseeder", false)
+)
+
+type configFlags struct {
+	ShowVersion        bool     `short:"V" long:"version" description:"Display version information and exit"`
+	AppDir             string   `short:"b" long:"appdir" description:"Directory to store data"`
+	KnownPeers         []string `short:"p" long:"peer" description:"Add a peer to start crawling from, in the form host[:port] -- Required on networks without DNS seeds"`
+	Host               string   `short:"H" long:"host" description:"The DNS zone this seeder is authoritative for, e.g. seed.example.com -- The DNS server is disabled if it's not set"`
+	Nameserver         string   `short:"n" long:"nameserver" description:"Hostname of the nameserver of the zone, e.g. ns.example.com"`
+	Listen             string   `short:"l" long:"listen" description:"Interface/port to serve DNS queries on"`
+	GRPCListen         string   `long:"grpclisten" description:"Interface/port to serve the gRPC peer service on -- The gRPC server is disabled if it's empty"`
+	Threads            int      `long:"threads" description:"Number of peers to poll concurrently"`
+	MinProtocolVersion uint32   `long:"minprotocolversion" description:"Minimum protocol version of the peers to serve"`
+	MinUptime          float64  `long:"minuptime" description:"Minimum uptime in percents, averaged over the last day, of the peers to serve"`
+	Subnetwork         string   `long:"subnetwork" description:"Crawl as a partial node of the given subnetwork ID, which lets partial nodes of that subnetwork be polled"`
+	Profile            string   `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
+	config.NetworkFlags
+
+	subnetworkID *externalapi.DomainSubnetworkID
+}
+
+func parseConfig() (*configFlags, error) {
+	cfg := &configFlags{
+		AppDir:     defaultAppDir,
+		Listen:     defaultListen,
+		GRPCListen: defaultGRPCListen,
+		Threads:    defaultThreads,
+		MinUptime:  defaultMinUptime,
+	}
+	parser := flags.NewParser(cfg, flags.PrintErrors|flags.HelpFlag)
+	_, err := parser.Parse()
+
+	// Show the version and exit if the version flag was specified.
+	if cfg.ShowVersion {
+		appName := filepath.Base(os.Args[0])
+		appName = strings.TrimSuffix(appName, filepath.Ext(appName))
+		fmt.Println(appName, "version", version.Version())
+		os.Exit(0)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.ResolveNetwork(parser)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Profile != "" {
+		profilePort, err := strconv.Atoi(cfg.Profile)
+		if err != nil || profilePort < 1024 || profilePort > 65535 {
+			return nil, errors.New("The profile port must be between 1024 and 65535")
+		}
+	}
+
+	if len(cfg.KnownPeers) == 0 && len(cfg.NetParams().DNSSeeds) == 0 {
+		return nil, errors.Errorf("--peer is required, since network %s has no DNS seeds", cfg.NetParams().Name)
+	}
+
+	if cfg.Host == "" && cfg.GRPCListen == "" {
+		return nil, errors.New("at least one of --host and --grpclisten is required")
+	}
+
+	if cfg.Host != "" {
+		if cfg.Nameserver == "" {
+			return nil, errors.New("--nameserver is required when --host is set")
+		}
+		_, _, err := net.SplitHostPort(cfg.Listen)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --listen %s", cfg.Listen)
+		}
+	}
+
+	if cfg.Subnetwork != "" {
+		if !cfg.NetParams().EnableNonNativeSubnetworks {
+			return nil, errors.Errorf("network %s doesn't allow partial nodes", cfg.NetParams().Name)
+		}
+		cfg.subnetworkID, err = subnetworks.FromString(cfg.Subnetwork)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --subnetwork %s", cfg.Subnetwork)
+		}
+	}
+
+	if cfg.Threads < 1 {
+		return nil, errors.New("--threads must be at least 1")
+	}
+
+	if cfg.MinUptime < 0 || cfg.MinUptime > 100 {
+		return nil, errors.New("--minuptime must be between 0 and 100")
+	}
+
+	// Append the network type to the app directory so it is "namespaced"
+	// per network.
+	cfg.AppDir = filepath.Join(filepath.Clean(os.ExpandEnv(cfg.AppDir)), cfg.NetParams().Name)
+
+	initLog(filepath.Join(cfg.AppDir, defaultLogFilename), filepath.Join(cfg.AppDir, defaultErrLogFilename))
+
+	return cfg, nil
+}
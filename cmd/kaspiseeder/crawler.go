@@ -0,0 +1,364 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/app/protocol/common"
+	"github.com/kaspikr/kaspid/app/protocol/flows/handshake"
+	"github.com/kaspikr/kaspid/app/protocol/flows/ready"
+	"github.com/kaspikr/kaspid/app/protocol/flows/v5/addressexchange"
+	peerpkg "github.com/kaspikr/kaspid/app/protocol/peer"
+	"github.com/kaspikr/kaspid/app/protocol/protocolerrors"
+	"github.com/kaspikr/kaspid/domain"
+	"github.com/kaspikr/kaspid/infrastructure/config"
+	"github.com/kaspikr/kaspid/infrastructure/network/addressmanager"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/id"
+	routerpkg "github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
+	"github.com/pkg/errors"
+)
+
+const (
+	// crawlInterval is how often the crawler looks for peers that are due to be polled
+	crawlInterval = 10 * time.Second
+
+	// pollTimeout bounds the time to wait for a poll, which consists of the
+	// handshake, the ready message and the address exchange
+	pollTimeout = 3 * common.DefaultTimeout
+)
+
+// pollResult is the outcome of polling a single peer
+type pollResult struct {
+	peer *peerpkg.Peer
+	err  error
+}
+
+// crawler discovers the peers of the network by polling them with the
+// handshake and address exchange flows of kaspid, and records which of
+// them replied in its peer store
+type crawler struct {
+	cfg            *config.Config
+	netAdapter     *netadapter.NetAdapter
+	addressManager *addressmanager.AddressManager
+	peers          *peerStore
+	threads        int
+
+	pendingPollsLock sync.Mutex
+	pendingPolls     map[string]chan<- *pollResult
+
+	connectedPeersLock sync.Mutex
+	connectedPeers     map[id.ID]struct{}
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+func newCrawler(cfg *config.Config, addressManager *addressmanager.AddressManager, peers *peerStore,
+	threads int) (*crawler, error) {
+
+	netAdapter, err := netadapter.NewNetAdapter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &crawler{
+		cfg:            cfg,
+		netAdapter:     netAdapter,
+		addressManager: addressManager,
+		peers:          peers,
+		threads:        threads,
+		pendingPolls:   make(map[string]chan<- *pollResult),
+		connectedPeers: make(map[id.ID]struct{}),
+		quit:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	netAdapter.SetP2PRouterInitializer(c.routerInitializer)
+	netAdapter.SetRPCRouterInitializer(func(router *routerpkg.Router, netConnection *netadapter.NetConnection) {
+		netConnection.Disconnect()
+	})
+	return c, nil
+}
+
+// Config returns the kaspid configuration the crawler polls peers with.
+// This is part of the handshake.HandleHandshakeContext interface
+func (c *crawler) Config() *config.Config {
+	return c.cfg
+}
+
+// NetAdapter returns the net adapter the crawler polls peers with.
+// This is part of the handshake.HandleHandshakeContext interface
+func (c *crawler) NetAdapter() *netadapter.NetAdapter {
+	return c.netAdapter
+}
+
+// Domain returns nil, since the crawler has no DAG.
+// This is part of the handshake.HandleHandshakeContext interface
+func (c *crawler) Domain() domain.Domain {
+	return nil
+}
+
+// AddressManager returns the address manager that stores the addresses the crawler found.
+// This is part of the handshake.HandleHandshakeContext and the addressexchange contexts interfaces
+func (c *crawler) AddressManager() *addressmanager.AddressManager {
+	return c.addressManager
+}
+
+// AddToPeers marks the given peer as connected.
+// This is part of the handshake.HandleHandshakeContext interface
+func (c *crawler) AddToPeers(peer *peerpkg.Peer) error {
+	c.connectedPeersLock.Lock()
+	defer c.connectedPeersLock.Unlock()
+
+	if _, ok := c.connectedPeers[*peer.ID()]; ok {
+		return errors.Wrapf(common.ErrPeerWithSameIDExists, "peer with ID %s already exists", peer.ID())
+	}
+	c.connectedPeers[*peer.ID()] = struct{}{}
+	return nil
+}
+
+func (c *crawler) removeFromPeers(peer *peerpkg.Peer) {
+	c.connectedPeersLock.Lock()
+	defer c.connectedPeersLock.Unlock()
+
+	delete(c.connectedPeers, *peer.ID())
+}
+
+// HandleError sends the given error to errChan if it's the first error of the connection,
+// and panics if it's neither a protocol error nor ErrRouteClosed.
+// This is part of the handshake.HandleHandshakeContext interface
+func (c *crawler) HandleError(err error, flowName string, isStopping *uint32, errChan chan<- error) {
+	if !errors.Is(err, routerpkg.ErrRouteClosed) {
+		if protocolErr := (protocolerrors.ProtocolError{}); !errors.As(err, &protocolErr) {
+			panic(err)
+		}
+		log.Debugf("Protocol error from %s: %s", flowName, err)
+	}
+
+	if atomic.AddUint32(isStopping, 1) == 1 {
+		errChan <- err
+	}
+}
+
+func (c *crawler) start() error {
+	err := c.netAdapter.Start()
+	if err != nil {
+		return err
+	}
+	spawn("crawler.crawlLoop", c.crawlLoop)
+	return nil
+}
+
+func (c *crawler) stop() error {
+	close(c.quit)
+	<-c.done
+	return c.netAdapter.Stop()
+}
+
+func (c *crawler) crawlLoop() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(crawlInterval)
+	defer ticker.Stop()
+	for {
+		c.crawl()
+		select {
+		case <-c.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// crawl polls every known address that is due to be polled, up to c.threads
+// at a time, and returns once all of them were polled
+func (c *crawler) crawl() {
+	addresses := c.addressManager.Addresses()
+	c.peers.retain(addresses)
+	dueAddresses := c.peers.dueAddresses(addresses, time.Now())
+	if len(dueAddresses) == 0 {
+		return
+	}
+	log.Debugf("Polling %d out of %d known addresses", len(dueAddresses), len(addresses))
+
+	semaphore := make(chan struct{}, c.threads)
+	waitGroup := sync.WaitGroup{}
+	for _, address := range dueAddresses {
+		select {
+		case <-c.quit:
+			waitGroup.Wait()
+			return
+		case semaphore <- struct{}{}:
+		}
+		waitGroup.Add(1)
+		address := address
+		spawn("crawler.poll", func() {
+			defer func() {
+				<-semaphore
+				waitGroup.Done()
+			}()
+			c.pollAndRecord(address)
+		})
+	}
+	waitGroup.Wait()
+}
+
+func (c *crawler) pollAndRecord(address *appmessage.NetAddress) {
+	peer, err := c.poll(address)
+	now := time.Now()
+	if err != nil {
+		log.Debugf("Failed to poll %s: %s", address, err)
+		c.peers.recordFailure(address, now)
+		err := c.addressManager.MarkConnectionFailure(address)
+		if err != nil {
+			log.Debugf("Could not mark the connection failure of %s: %s", address, err)
+		}
+		return
+	}
+
+	log.Debugf("Polled %s: protocol version %d, user agent %s", address, peer.AdvertisedProtocolVersion(),
+		peer.UserAgent())
+	c.peers.recordSuccess(address, peer.AdvertisedProtocolVersion(), peer.UserAgent(), peer.SubnetworkID(), now)
+	err = c.addressManager.MarkConnectionSuccess(address)
+	if err != nil {
+		log.Debugf("Could not mark the connection success of %s: %s", address, err)
+	}
+}
+
+// poll connects to the given address, and returns the peer once it completed
+// the handshake and the address exchange
+func (c *crawler) poll(address *appmessage.NetAddress) (*peerpkg.Peer, error) {
+	key := address.String()
+	resultChan := make(chan *pollResult, 1)
+
+	c.pendingPollsLock.Lock()
+	if _, ok := c.pendingPolls[key]; ok {
+		c.pendingPollsLock.Unlock()
+		return nil, errors.Errorf("%s is already being polled", key)
+	}
+	c.pendingPolls[key] = resultChan
+	c.pendingPollsLock.Unlock()
+
+	defer func() {
+		c.pendingPollsLock.Lock()
+		defer c.pendingPollsLock.Unlock()
+		delete(c.pendingPolls, key)
+	}()
+
+	err := c.netAdapter.P2PConnect(key)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case result := <-resultChan:
+		return result.peer, result.err
+	case <-time.After(pollTimeout):
+		return nil, errors.Errorf("timed out polling %s", key)
+	}
+}
+
+func (c *crawler) routerInitializer(router *routerpkg.Router, netConnection *netadapter.NetConnection) {
+	key := netConnection.NetAddress().String()
+	c.pendingPollsLock.Lock()
+	resultChan, ok := c.pendingPolls[key]
+	c.pendingPollsLock.Unlock()
+	if !ok {
+		log.Debugf("Disconnecting from %s, which is not being polled", netConnection)
+		netConnection.Disconnect()
+		return
+	}
+
+	routes := registerPollRoutes(router)
+	netConnection.SetOnInvalidMessageHandler(func(err error) {
+		log.Debugf("Invalid message from %s: %s", netConnection, err)
+		netConnection.Disconnect()
+	})
+
+	spawn("crawler.routerInitializer-runPoll", func() {
+		peer, err := c.runPoll(router, netConnection, routes)
+		netConnection.Disconnect()
+		resultChan <- &pollResult{peer: peer, err: err}
+	})
+}
+
+// pollRoutes are the incoming routes of a connection that is being polled
+type pollRoutes struct {
+	receiveVersion, sendVersion, receiveReady *routerpkg.Route
+	receiveAddresses, sendAddresses           *routerpkg.Route
+}
+
+func registerPollRoutes(router *routerpkg.Router) *pollRoutes {
+	addIncomingRoute := func(name string, messageTypes ...appmessage.MessageCommand) *routerpkg.Route {
+		route, err := router.AddIncomingRoute(name, messageTypes)
+		if err != nil {
+			panic(err)
+		}
+		return route
+	}
+
+	routes := &pollRoutes{
+		receiveVersion:   addIncomingRoute("receiveVersion - incoming", appmessage.CmdVersion),
+		sendVersion:      addIncomingRoute("sendVersion - incoming", appmessage.CmdVerAck),
+		receiveReady:     addIncomingRoute("receiveReady - incoming", appmessage.CmdReady),
+		receiveAddresses: addIncomingRoute("receiveAddresses", appmessage.CmdAddresses),
+		sendAddresses:    addIncomingRoute("sendAddresses", appmessage.CmdRequestAddresses),
+	}
+
+	// Peers start their flows once they're ready, so all other
+	// messages are discarded until the connection is closed
+	var ignoredMessageTypes []appmessage.MessageCommand
+	for messageType := range appmessage.ProtocolMessageCommandToString {
+		switch messageType {
+		case appmessage.CmdVersion, appmessage.CmdVerAck, appmessage.CmdReady,
+			appmessage.CmdAddresses, appmessage.CmdRequestAddresses:
+			continue
+		}
+		ignoredMessageTypes = append(ignoredMessageTypes, messageType)
+	}
+	ignoredRoute := addIncomingRoute("ignored", ignoredMessageTypes...)
+	spawn("crawler.registerPollRoutes-discardIgnored", func() {
+		for {
+			_, err := ignoredRoute.Dequeue()
+			if err != nil {
+				return
+			}
+		}
+	})
+
+	return routes
+}
+
+func (c *crawler) runPoll(router *routerpkg.Router, netConnection *netadapter.NetConnection,
+	routes *pollRoutes) (*peerpkg.Peer, error) {
+
+	peer, err := handshake.HandleHandshake(c, netConnection, routes.receiveVersion, routes.sendVersion,
+		router.OutgoingRoute())
+	if err != nil {
+		return nil, err
+	}
+	if peer == nil {
+		return nil, errors.Errorf("the connection to %s was closed during the handshake", netConnection)
+	}
+	defer c.removeFromPeers(peer)
+
+	err = ready.HandleReady(routes.receiveReady, router.OutgoingRoute(), peer)
+	if err != nil {
+		return nil, err
+	}
+
+	// The peer might ask for addresses too, so answer it while waiting for its own
+	spawn("crawler.runPoll-SendAddresses", func() {
+		err := addressexchange.SendAddresses(c, routes.sendAddresses, router.OutgoingRoute(), peer)
+		if err != nil && !errors.Is(err, routerpkg.ErrRouteClosed) {
+			log.Debugf("Error sending addresses to %s: %s", peer, err)
+		}
+	})
+
+	err = addressexchange.ReceiveAddresses(c, routes.receiveAddresses, router.OutgoingRoute(), peer)
+	if err != nil {
+		return nil, err
+	}
+	return peer, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"math/rand"
+	"net"
+	"strings"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/kaspikr/kaspid/infrastructure/network/dnsseed"
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// dnsTTL is the time to live of the records the seeder serves, in seconds
+	dnsTTL = 30
+
+	// maxIPv4Answers and maxIPv6Answers keep responses within
+	// the 512 bytes that DNS over UDP is limited to
+	maxIPv4Answers = 25
+	maxIPv6Answers = 15
+
+	maxDNSMessageSize = 512
+)
+
+// dnsServer is an authoritative DNS server for the zone of the seeder,
+// that answers A and AAAA queries with good peers. Like the queries of
+// kaspid, "n.<zone>" asks for full nodes, "n<subnetwork ID>.<zone>" for
+// partial nodes of a subnetwork, and "<zone>" for nodes of all subnetworks.
+type dnsServer struct {
+	zone        dnsmessage.Name
+	nameserver  dnsmessage.Name
+	peers       *peerStore
+	defaultPort uint16
+
+	conn net.PacketConn
+}
+
+func newDNSServer(host, nameserver string, peers *peerStore, defaultPort uint16) (*dnsServer, error) {
+	zone, err := dnsmessage.NewName(canonicalName(host))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid host %s", host)
+	}
+	nameserverName, err := dnsmessage.NewName(canonicalName(nameserver))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid nameserver %s", nameserver)
+	}
+	return &dnsServer{
+		zone:        zone,
+		nameserver:  nameserverName,
+		peers:       peers,
+		defaultPort: defaultPort,
+	}, nil
+}
+
+// canonicalName returns the given domain name in lower case, and fully qualified
+func canonicalName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+func (s *dnsServer) start(listen string) error {
+	conn, err := net.ListenPacket("udp", listen)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	spawn("dnsServer.serve", s.serve)
+	log.Infof("DNS server listening on %s for zone %s", conn.LocalAddr(), s.zone)
+	return nil
+}
+
+func (s *dnsServer) stop() error {
+	return s.conn.Close()
+}
+
+func (s *dnsServer) serve() {
+	buffer := make([]byte, maxDNSMessageSize)
+	for {
+		n, address, err := s.conn.ReadFrom(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Warnf("Error reading a DNS query: %s", err)
+			continue
+		}
+		response, err := s.handleQuery(buffer[:n])
+		if err != nil {
+			log.Debugf("Dropping a malformed DNS query from %s: %s", address, err)
+			continue
+		}
+		_, err = s.conn.WriteTo(response, address)
+		if err != nil {
+			log.Debugf("Error sending a DNS response to %s: %s", address, err)
+		}
+	}
+}
+
+// handleQuery returns the response to the given DNS query, or an error
+// if the query is too malformed to respond to
+func (s *dnsServer) handleQuery(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	if header.Response {
+		return nil, errors.New("the message is a response")
+	}
+
+	responseHeader := dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		RecursionDesired:   header.RecursionDesired,
+		RCode:              dnsmessage.RCodeSuccess,
+		Authoritative:      true,
+		RecursionAvailable: false,
+	}
+	question, err := parser.Question()
+	if err != nil {
+		responseHeader.RCode = dnsmessage.RCodeFormatError
+		return s.buildResponse(responseHeader, nil, nil)
+	}
+	if header.OpCode != 0 {
+		responseHeader.RCode = dnsmessage.RCodeNotImplemented
+		return s.buildResponse(responseHeader, &question, nil)
+	}
+
+	name := canonicalName(question.Name.String())
+	zone := s.zone.String()
+	if name != zone && !strings.HasSuffix(name, "."+zone) {
+		responseHeader.Authoritative = false
+		responseHeader.RCode = dnsmessage.RCodeRefused
+		return s.buildResponse(responseHeader, &question, nil)
+	}
+
+	subnetworkID, includeAllSubnetworks, ok := parseSeedName(strings.TrimSuffix(name, zone))
+	if !ok {
+		responseHeader.RCode = dnsmessage.RCodeNameError
+		return s.buildResponse(responseHeader, &question, nil)
+	}
+
+	var answers []dnsmessage.Resource
+	resourceHeader := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: dnsTTL}
+	switch question.Type {
+	case dnsmessage.TypeA:
+		for _, ip := range s.peerIPs(subnetworkID, includeAllSubnetworks, true, maxIPv4Answers) {
+			resource := dnsmessage.AResource{}
+			copy(resource.A[:], ip.To4())
+			answers = append(answers, dnsmessage.Resource{Header: resourceHeader, Body: &resource})
+		}
+	case dnsmessage.TypeAAAA:
+		for _, ip := range s.peerIPs(subnetworkID, includeAllSubnetworks, false, maxIPv6Answers) {
+			resource := dnsmessage.AAAAResource{}
+			copy(resource.AAAA[:], ip.To16())
+			answers = append(answers, dnsmessage.Resource{Header: resourceHeader, Body: &resource})
+		}
+	case dnsmessage.TypeNS:
+		if name == zone {
+			answers = append(answers, dnsmessage.Resource{Header: resourceHeader,
+				Body: &dnsmessage.NSResource{NS: s.nameserver}})
+		}
+	}
+	return s.buildResponse(responseHeader, &question, answers)
+}
+
+// parseSeedName parses the part of a queried name that precedes the zone
+// into the subnetwork the query asks for
+func parseSeedName(prefix string) (subnetworkID *externalapi.DomainSubnetworkID, includeAllSubnetworks bool, ok bool) {
+	if prefix == "" {
+		return nil, true, true
+	}
+	label := strings.TrimSuffix(prefix, ".")
+	if strings.Contains(label, ".") || label[0] != dnsseed.SubnetworkIDPrefixChar {
+		return nil, false, false
+	}
+	if len(label) == 1 {
+		return nil, false, true
+	}
+	subnetworkID, err := subnetworks.FromString(label[1:])
+	if err != nil {
+		return nil, false, false
+	}
+	return subnetworkID, false, true
+}
+
+// peerIPs returns up to maxCount random IPs of good peers that listen on the default port,
+// since that's the port clients connect to
+func (s *dnsServer) peerIPs(subnetworkID *externalapi.DomainSubnetworkID, includeAllSubnetworks bool,
+	isIPv4 bool, maxCount int) []net.IP {
+
+	var ips []net.IP
+	for _, address := range s.peers.goodPeers(subnetworkID, includeAllSubnetworks) {
+		if address.Network != appmessage.AddressNetworkIP || address.Port != s.defaultPort ||
+			(address.IP.To4() != nil) != isIPv4 {
+			continue
+		}
+		ips = append(ips, address.IP)
+	}
+	rand.Shuffle(len(ips), func(i, j int) {
+		ips[i], ips[j] = ips[j], ips[i]
+	})
+	if len(ips) > maxCount {
+		ips = ips[:maxCount]
+	}
+	return ips
+}
+
+func (s *dnsServer) buildResponse(header dnsmessage.Header, question *dnsmessage.Question,
+	answers []dnsmessage.Resource) ([]byte, error) {
+
+	builder := dnsmessage.NewBuilder(make([]byte, 0, maxDNSMessageSize), header)
+	builder.EnableCompression()
+	err := builder.StartQuestions()
+	if err != nil {
+		return nil, err
+	}
+	if question != nil {
+		err = builder.Question(*question)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = builder.StartAnswers()
+	if err != nil {
+		return nil, err
+	}
+	for _, answer := range answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			err = builder.AResource(answer.Header, *body)
+		case *dnsmessage.AAAAResource:
+			err = builder.AAAAResource(answer.Header, *body)
+		case *dnsmessage.NSResource:
+			err = builder.NSResource(answer.Header, *body)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
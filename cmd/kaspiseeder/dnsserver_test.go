@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/infrastructure/network/dnsseed/pb"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newTestPeerStore(t *testing.T) *peerStore {
+	store := newPeerStore(0, 0)
+	now := time.Now()
+	addPeer := func(host string, port uint16, subnetworkID *externalapi.DomainSubnetworkID) {
+		var address *appmessage.NetAddress
+		if appmessage.IsOverlayHost(host) {
+			var err error
+			address, err = appmessage.NewNetAddressOverlayHost(host, port)
+			if err != nil {
+				t.Fatalf("NewNetAddressOverlayHost: %s", err)
+			}
+		} else {
+			address = appmessage.NewNetAddressIPPort(net.ParseIP(host), port)
+		}
+		store.recordSuccess(address, 6, "/kaspid:0.1/", subnetworkID, now)
+	}
+	addPeer("203.0.113.1", 16111, nil)
+	addPeer("203.0.113.2", 16111, nil)
+	addPeer("203.0.113.3", 17000, nil)
+	addPeer("2001:db8::1", 16111, nil)
+	addPeer("203.0.113.4", 16111, &externalapi.DomainSubnetworkID{1})
+	addPeer("pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion", 16111, nil)
+	return store
+}
+
+func TestDNSServer(t *testing.T) {
+	server, err := newDNSServer("Seed.Example.com", "ns.example.com", newTestPeerStore(t), 16111)
+	if err != nil {
+		t.Fatalf("newDNSServer: %s", err)
+	}
+
+	query := func(name string, queryType dnsmessage.Type) (dnsmessage.Header, []dnsmessage.Resource) {
+		t.Helper()
+		builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 1234, RecursionDesired: true})
+		builder.StartQuestions()
+		builder.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: queryType,
+			Class: dnsmessage.ClassINET})
+		message, err := builder.Finish()
+		if err != nil {
+			t.Fatalf("Finish: %s", err)
+		}
+		response, err := server.handleQuery(message)
+		if err != nil {
+			t.Fatalf("handleQuery: %s", err)
+		}
+		if len(response) > maxDNSMessageSize {
+			t.Fatalf("the response is %d bytes long", len(response))
+		}
+		var parsed dnsmessage.Message
+		err = parsed.Unpack(response)
+		if err != nil {
+			t.Fatalf("Unpack: %s", err)
+		}
+		if parsed.ID != 1234 || !parsed.Response {
+			t.Fatalf("unexpected response header %+v", parsed.Header)
+		}
+		return parsed.Header, parsed.Answers
+	}
+	expectIPs := func(answers []dnsmessage.Resource, expected ...string) {
+		t.Helper()
+		ips := make(map[string]bool)
+		for _, answer := range answers {
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				ips[net.IP(body.A[:]).String()] = true
+			case *dnsmessage.AAAAResource:
+				ips[net.IP(body.AAAA[:]).String()] = true
+			}
+		}
+		if len(ips) != len(expected) {
+			t.Fatalf("expected IPs %v, got %v", expected, ips)
+		}
+		for _, ip := range expected {
+			if !ips[ip] {
+				t.Fatalf("expected IPs %v, got %v", expected, ips)
+			}
+		}
+	}
+
+	header, answers := query("n.seed.example.com.", dnsmessage.TypeA)
+	if header.RCode != dnsmessage.RCodeSuccess || !header.Authoritative {
+		t.Fatalf("unexpected response header %+v", header)
+	}
+	expectIPs(answers, "203.0.113.1", "203.0.113.2")
+
+	_, answers = query("N.SEED.example.com.", dnsmessage.TypeAAAA)
+	expectIPs(answers, "2001:db8::1")
+
+	_, answers = query("n0100000000000000000000000000000000000000.seed.example.com.", dnsmessage.TypeA)
+	expectIPs(answers, "203.0.113.4")
+
+	_, answers = query("seed.example.com.", dnsmessage.TypeA)
+	expectIPs(answers, "203.0.113.1", "203.0.113.2", "203.0.113.4")
+
+	_, answers = query("seed.example.com.", dnsmessage.TypeNS)
+	if len(answers) != 1 || answers[0].Body.(*dnsmessage.NSResource).NS.String() != "ns.example.com." {
+		t.Fatalf("unexpected NS answers %v", answers)
+	}
+
+	header, _ = query("x.seed.example.com.", dnsmessage.TypeA)
+	if header.RCode != dnsmessage.RCodeNameError {
+		t.Fatalf("expected NXDOMAIN for an unknown name in the zone, got %s", header.RCode)
+	}
+
+	header, answers = query("example.org.", dnsmessage.TypeA)
+	if header.RCode != dnsmessage.RCodeRefused || len(answers) != 0 {
+		t.Fatalf("expected REFUSED for a name outside the zone, got %s", header.RCode)
+	}
+}
+
+func TestDNSServerAnswerLimit(t *testing.T) {
+	store := newPeerStore(0, 0)
+	for i := 0; i < 100; i++ {
+		store.recordSuccess(appmessage.NewNetAddressIPPort(net.IPv4(203, 0, 113, byte(i)), 16111),
+			6, "", nil, time.Now())
+	}
+	server, err := newDNSServer("seed.example.com", "ns.example.com", store, 16111)
+	if err != nil {
+		t.Fatalf("newDNSServer: %s", err)
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 1})
+	builder.StartQuestions()
+	builder.Question(dnsmessage.Question{Name: dnsmessage.MustNewName("n.seed.example.com."),
+		Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET})
+	message, _ := builder.Finish()
+	response, err := server.handleQuery(message)
+	if err != nil {
+		t.Fatalf("handleQuery: %s", err)
+	}
+	var parsed dnsmessage.Message
+	err = parsed.Unpack(response)
+	if err != nil {
+		t.Fatalf("Unpack: %s", err)
+	}
+	if len(parsed.Answers) != maxIPv4Answers || len(response) > maxDNSMessageSize {
+		t.Fatalf("expected %d answers within %d bytes, got %d answers in %d bytes",
+			maxIPv4Answers, maxDNSMessageSize, len(parsed.Answers), len(response))
+	}
+}
+
+func TestGetPeersList(t *testing.T) {
+	server := newPeerServiceServer(newTestPeerStore(t), 16111)
+
+	response, err := server.GetPeersList(context.Background(), &pb.GetPeersListRequest{})
+	if err != nil {
+		t.Fatalf("GetPeersList: %s", err)
+	}
+	if len(response.Addresses) != 3 {
+		t.Fatalf("expected the 3 full nodes on the default port, got %v", response.Addresses)
+	}
+
+	subnetworkID := externalapi.DomainSubnetworkID{1}
+	response, err = server.GetPeersList(context.Background(), &pb.GetPeersListRequest{SubnetworkID: subnetworkID[:]})
+	if err != nil {
+		t.Fatalf("GetPeersList: %s", err)
+	}
+	if len(response.Addresses) != 1 || net.IP(response.Addresses[0].IP).String() != "203.0.113.4" {
+		t.Fatalf("expected only the partial node, got %v", response.Addresses)
+	}
+
+	response, err = server.GetPeersList(context.Background(), &pb.GetPeersListRequest{IncludeAllSubnetworks: true})
+	if err != nil {
+		t.Fatalf("GetPeersList: %s", err)
+	}
+	if len(response.Addresses) != 4 {
+		t.Fatalf("expected 4 addresses, got %v", response.Addresses)
+	}
+
+	_, err = server.GetPeersList(context.Background(), &pb.GetPeersListRequest{SubnetworkID: []byte{1, 2}})
+	if err == nil {
+		t.Fatalf("expected an error for an invalid subnetwork ID")
+	}
+}
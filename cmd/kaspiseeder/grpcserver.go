@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net"
+
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/kaspikr/kaspid/infrastructure/network/dnsseed/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// peerServiceServer serves the good peers of the peer store over the gRPC
+// PeerService that kaspid seeds from with --grpcseed
+type peerServiceServer struct {
+	pb.UnimplementedPeerServiceServer
+	peers       *peerStore
+	defaultPort uint16
+
+	server *grpc.Server
+}
+
+func newPeerServiceServer(peers *peerStore, defaultPort uint16) *peerServiceServer {
+	s := &peerServiceServer{
+		peers:       peers,
+		defaultPort: defaultPort,
+		server:      grpc.NewServer(),
+	}
+	pb.RegisterPeerServiceServer(s.server, s)
+	return s
+}
+
+func (s *peerServiceServer) start(listen string) error {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	spawn("peerServiceServer.serve", func() {
+		err := s.server.Serve(listener)
+		if err != nil {
+			log.Errorf("gRPC server stopped: %s", err)
+		}
+	})
+	log.Infof("gRPC server listening on %s", listener.Addr())
+	return nil
+}
+
+func (s *peerServiceServer) stop() {
+	s.server.Stop()
+}
+
+// GetPeersList returns the good peers of the requested subnetwork
+// This is part of the pb.PeerServiceServer interface
+func (s *peerServiceServer) GetPeersList(_ context.Context, request *pb.GetPeersListRequest) (
+	*pb.GetPeersListResponse, error) {
+
+	var subnetworkID *externalapi.DomainSubnetworkID
+	if len(request.SubnetworkID) > 0 {
+		var err error
+		subnetworkID, err = subnetworks.FromBytes(request.SubnetworkID)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid subnetwork ID: %s", err)
+		}
+	}
+
+	// Clients connect to the addresses they get on the default port,
+	// and are unable to connect to overlay addresses with just their IP
+	addresses := s.peers.goodPeers(subnetworkID, request.IncludeAllSubnetworks)
+	response := &pb.GetPeersListResponse{}
+	for _, address := range addresses {
+		if address.IsOverlay() || address.Port != s.defaultPort {
+			continue
+		}
+		response.Addresses = append(response.Addresses, &pb.NetAddress{
+			Timestamp: address.Timestamp.UnixSeconds(),
+			IP:        address.IP,
+			Port:      uint32(address.Port),
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	_ "net/http/pprof"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/infrastructure/config"
+	"github.com/kaspikr/kaspid/infrastructure/db/database/ldb"
+	"github.com/kaspikr/kaspid/infrastructure/network/addressmanager"
+	"github.com/kaspikr/kaspid/infrastructure/network/dnsseed"
+	"github.com/kaspikr/kaspid/infrastructure/os/signal"
+	"github.com/kaspikr/kaspid/util/panics"
+	"github.com/kaspikr/kaspid/util/profiling"
+	"github.com/kaspikr/kaspid/version"
+	"github.com/pkg/errors"
+)
+
+const (
+	addressesDBDirectoryName = "addresses"
+	leveldbCacheSizeMiB      = 16
+)
+
+func main() {
+	defer panics.HandlePanic(log, "MAIN", nil)
+	interrupt := signal.InterruptListener()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		printErrorAndExit(errors.Errorf("Error parsing command-line arguments: %s", err))
+	}
+	defer backendLog.Close()
+
+	// Show version at startup.
+	log.Infof("Version %s", version.Version())
+
+	// Enable http profiling server if requested.
+	if cfg.Profile != "" {
+		profiling.Start(cfg.Profile, log)
+	}
+
+	kaspidConfig := newKaspidConfig(cfg)
+	defaultPort, err := strconv.ParseUint(cfg.NetParams().DefaultPort, 10, 16)
+	if err != nil {
+		printErrorAndExit(errors.Wrapf(err, "invalid default port %s", cfg.NetParams().DefaultPort))
+	}
+
+	db, err := ldb.NewLevelDB(filepath.Join(cfg.AppDir, addressesDBDirectoryName), leveldbCacheSizeMiB)
+	if err != nil {
+		printErrorAndExit(errors.Wrap(err, "error opening the addresses database"))
+	}
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			log.Errorf("Error closing the addresses database: %s", err)
+		}
+	}()
+
+	addressManager, err := addressmanager.New(addressmanager.NewConfig(kaspidConfig), db)
+	if err != nil {
+		printErrorAndExit(errors.Wrap(err, "error creating the address manager"))
+	}
+	err = addKnownPeers(cfg, addressManager)
+	if err != nil {
+		printErrorAndExit(err)
+	}
+
+	peers := newPeerStore(cfg.MinProtocolVersion, cfg.MinUptime)
+	crawler, err := newCrawler(kaspidConfig, addressManager, peers, cfg.Threads)
+	if err != nil {
+		printErrorAndExit(errors.Wrap(err, "error creating the crawler"))
+	}
+	err = crawler.start()
+	if err != nil {
+		printErrorAndExit(errors.Wrap(err, "error starting the crawler"))
+	}
+	defer func() {
+		err := crawler.stop()
+		if err != nil {
+			log.Errorf("Error stopping the crawler: %s", err)
+		}
+	}()
+
+	if cfg.GRPCListen != "" {
+		peerServiceServer := newPeerServiceServer(peers, uint16(defaultPort))
+		err := peerServiceServer.start(cfg.GRPCListen)
+		if err != nil {
+			printErrorAndExit(errors.Wrap(err, "error starting the gRPC server"))
+		}
+		defer peerServiceServer.stop()
+	}
+
+	if cfg.Host != "" {
+		dnsServer, err := newDNSServer(cfg.Host, cfg.Nameserver, peers, uint16(defaultPort))
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		err = dnsServer.start(cfg.Listen)
+		if err != nil {
+			printErrorAndExit(errors.Wrap(err, "error starting the DNS server"))
+		}
+		defer func() {
+			err := dnsServer.stop()
+			if err != nil {
+				log.Errorf("Error stopping the DNS server: %s", err)
+			}
+		}()
+	}
+
+	<-interrupt
+}
+
+// newKaspidConfig returns the configuration the crawler polls peers with. It
+// doesn't listen to inbound connections, and its P2P identity changes on every start.
+// It belongs to the subnetwork given with --subnetwork, or is a full node if it's not set.
+func newKaspidConfig(cfg *configFlags) *config.Config {
+	kaspidConfig := config.DefaultConfig()
+	kaspidConfig.NetworkFlags = cfg.NetworkFlags
+	kaspidConfig.AppDir = cfg.AppDir
+	kaspidConfig.DisableListen = true
+	kaspidConfig.Dial = net.DialTimeout
+	kaspidConfig.Lookup = net.LookupIP
+	kaspidConfig.SubnetworkID = cfg.subnetworkID
+	return kaspidConfig
+}
+
+// addKnownPeers adds the peers given with --peer to the address manager, or if
+// there are none, seeds it from the DNS seeds of the network
+func addKnownPeers(cfg *configFlags, addressManager *addressmanager.AddressManager) error {
+	if len(cfg.KnownPeers) == 0 {
+		if len(addressManager.Addresses()) == 0 {
+			dnsseed.SeedFromDNS(cfg.NetParams(), "", true, nil, net.LookupIP,
+				func(addresses []*appmessage.NetAddress) {
+					err := addressManager.AddAddresses(addresses...)
+					if err != nil {
+						log.Errorf("Error adding addresses from the DNS seeds: %s", err)
+					}
+				})
+		}
+		return nil
+	}
+
+	for _, knownPeer := range cfg.KnownPeers {
+		host, portString, err := net.SplitHostPort(knownPeer)
+		if err != nil {
+			host, portString = knownPeer, cfg.NetParams().DefaultPort
+		}
+		port, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return errors.Wrapf(err, "invalid port in --peer %s", knownPeer)
+		}
+		var addresses []*appmessage.NetAddress
+		if appmessage.IsOverlayHost(host) {
+			address, err := appmessage.NewNetAddressOverlayHost(host, uint16(port))
+			if err != nil {
+				return errors.Wrapf(err, "invalid --peer %s", knownPeer)
+			}
+			addresses = append(addresses, address)
+		} else {
+			ips, err := net.LookupIP(host)
+			if err != nil {
+				return errors.Wrapf(err, "error resolving --peer %s", knownPeer)
+			}
+			for _, ip := range ips {
+				addresses = append(addresses, appmessage.NewNetAddressIPPort(ip, uint16(port)))
+			}
+		}
+		for _, address := range addresses {
+			if !addressmanager.IsRoutable(address, cfg.NetParams().AcceptUnroutable) {
+				log.Warnf("Ignoring --peer %s, since %s is not routable on %s",
+					knownPeer, address, cfg.NetParams().Name)
+				continue
+			}
+			err = addressManager.AddAddress(address)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func printErrorAndExit(err error) {
+	fmt.Fprintf(os.Stderr, "%+v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/util/mstime"
+)
+
+const (
+	// recrawlInterval is how often peers that replied to their last poll are polled again
+	recrawlInterval = 10 * time.Minute
+
+	// maxRetryInterval is the maximum time to wait before polling again a peer that
+	// failed its last polls. The wait doubles with every consecutive failure.
+	maxRetryInterval = 24 * time.Hour
+
+	// uptimeWindow is the period over which the uptime of peers is averaged
+	uptimeWindow = 24 * time.Hour
+)
+
+// peerInfo is what the seeder knows about a peer from polling it
+type peerInfo struct {
+	address         *appmessage.NetAddress
+	protocolVersion uint32
+	userAgent       string
+	subnetworkID    *externalapi.DomainSubnetworkID
+	lastAttempt     time.Time
+	lastSuccess     time.Time
+	failures        int
+
+	// uptime is the ratio of time the peer was reachable, as an exponentially
+	// weighted moving average over uptimeWindow
+	uptime float64
+}
+
+// isDue returns whether it's time to poll the peer again
+func (p *peerInfo) isDue(now time.Time) bool {
+	if p.lastAttempt.IsZero() {
+		return true
+	}
+	interval := recrawlInterval
+	for i := 1; i < p.failures && interval < maxRetryInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxRetryInterval {
+		interval = maxRetryInterval
+	}
+	return now.Sub(p.lastAttempt) >= interval
+}
+
+func (p *peerInfo) updateUptime(isUp bool, now time.Time) {
+	sample := 0.0
+	if isUp {
+		sample = 1.0
+	}
+	if p.lastAttempt.IsZero() {
+		p.uptime = sample
+		return
+	}
+	weight := 1 - math.Exp(-float64(now.Sub(p.lastAttempt))/float64(uptimeWindow))
+	p.uptime += weight * (sample - p.uptime)
+}
+
+// peerStore keeps track of the polled peers, and decides which of them are
+// good enough to be served to clients
+type peerStore struct {
+	mutex              sync.RWMutex
+	peers              map[string]*peerInfo
+	minProtocolVersion uint32
+	minUptime          float64
+}
+
+func newPeerStore(minProtocolVersion uint32, minUptime float64) *peerStore {
+	return &peerStore{
+		peers:              make(map[string]*peerInfo),
+		minProtocolVersion: minProtocolVersion,
+		minUptime:          minUptime,
+	}
+}
+
+// dueAddresses returns the addresses out of the given ones that should be polled now
+func (ps *peerStore) dueAddresses(addresses []*appmessage.NetAddress, now time.Time) []*appmessage.NetAddress {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	var due []*appmessage.NetAddress
+	for _, address := range addresses {
+		peer, ok := ps.peers[address.String()]
+		if !ok || peer.isDue(now) {
+			due = append(due, address)
+		}
+	}
+	return due
+}
+
+// retain forgets all peers except for the given addresses
+func (ps *peerStore) retain(addresses []*appmessage.NetAddress) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	retained := make(map[string]*peerInfo, len(addresses))
+	for _, address := range addresses {
+		key := address.String()
+		if peer, ok := ps.peers[key]; ok {
+			retained[key] = peer
+		}
+	}
+	ps.peers = retained
+}
+
+func (ps *peerStore) peer(address *appmessage.NetAddress) *peerInfo {
+	key := address.String()
+	peer, ok := ps.peers[key]
+	if !ok {
+		peer = &peerInfo{address: address}
+		ps.peers[key] = peer
+	}
+	return peer
+}
+
+// recordSuccess records that the peer at the given address replied to a poll
+func (ps *peerStore) recordSuccess(address *appmessage.NetAddress, protocolVersion uint32, userAgent string,
+	subnetworkID *externalapi.DomainSubnetworkID, now time.Time) {
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	peer := ps.peer(address)
+	peer.updateUptime(true, now)
+	peer.protocolVersion = protocolVersion
+	peer.userAgent = userAgent
+	peer.subnetworkID = subnetworkID
+	peer.lastAttempt = now
+	peer.lastSuccess = now
+	peer.failures = 0
+}
+
+// recordFailure records that the peer at the given address failed to reply to a poll
+func (ps *peerStore) recordFailure(address *appmessage.NetAddress, now time.Time) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	peer := ps.peer(address)
+	peer.updateUptime(false, now)
+	peer.lastAttempt = now
+	peer.failures++
+}
+
+func (ps *peerStore) isGood(peer *peerInfo) bool {
+	return peer.failures == 0 && !peer.lastSuccess.IsZero() &&
+		peer.protocolVersion >= ps.minProtocolVersion &&
+		peer.uptime*100 >= ps.minUptime
+}
+
+// goodPeers returns the addresses of the peers that replied to their last poll
+// and meet the protocol version and uptime requirements. Unless
+// includeAllSubnetworks is set, only peers of the given subnetwork are returned,
+// where a nil subnetworkID stands for full nodes.
+func (ps *peerStore) goodPeers(subnetworkID *externalapi.DomainSubnetworkID,
+	includeAllSubnetworks bool) []*appmessage.NetAddress {
+
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	var addresses []*appmessage.NetAddress
+	for _, peer := range ps.peers {
+		if !ps.isGood(peer) {
+			continue
+		}
+		if !includeAllSubnetworks && !peer.subnetworkID.Equal(subnetworkID) {
+			continue
+		}
+		address := *peer.address
+		address.Timestamp = mstime.ToMSTime(peer.lastSuccess)
+		addresses = append(addresses, &address)
+	}
+	return addresses
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+)
+
+func TestPeerStore(t *testing.T) {
+	now := time.Now()
+	goodAddress := appmessage.NewNetAddressIPPort(net.ParseIP("203.0.113.1"), 16111)
+	oldVersionAddress := appmessage.NewNetAddressIPPort(net.ParseIP("203.0.113.2"), 16111)
+	failingAddress := appmessage.NewNetAddressIPPort(net.ParseIP("203.0.113.3"), 16111)
+	partialAddress := appmessage.NewNetAddressIPPort(net.ParseIP("203.0.113.4"), 16111)
+	newAddress := appmessage.NewNetAddressIPPort(net.ParseIP("203.0.113.5"), 16111)
+	subnetworkID := &externalapi.DomainSubnetworkID{1}
+
+	store := newPeerStore(6, 50)
+	store.recordSuccess(goodAddress, 6, "/kaspid:0.1/", nil, now)
+	store.recordSuccess(oldVersionAddress, 5, "/kaspid:0.1/", nil, now)
+	store.recordSuccess(failingAddress, 6, "/kaspid:0.1/", nil, now.Add(-time.Hour))
+	store.recordFailure(failingAddress, now)
+	store.recordSuccess(partialAddress, 6, "/kaspid:0.1/", subnetworkID, now)
+
+	expectPeers := func(subnetworkID *externalapi.DomainSubnetworkID, includeAllSubnetworks bool,
+		expected ...*appmessage.NetAddress) {
+
+		t.Helper()
+		peers := store.goodPeers(subnetworkID, includeAllSubnetworks)
+		if len(peers) != len(expected) {
+			t.Fatalf("expected %d good peers, got %d: %v", len(expected), len(peers), peers)
+		}
+		for _, expectedAddress := range expected {
+			found := false
+			for _, peer := range peers {
+				if peer.String() == expectedAddress.String() {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("expected %s to be a good peer", expectedAddress)
+			}
+		}
+	}
+	expectPeers(nil, false, goodAddress)
+	expectPeers(subnetworkID, false, partialAddress)
+	expectPeers(nil, true, goodAddress, partialAddress)
+
+	addresses := []*appmessage.NetAddress{goodAddress, failingAddress, newAddress}
+	due := store.dueAddresses(addresses, now.Add(time.Minute))
+	if len(due) != 1 || due[0] != newAddress {
+		t.Fatalf("expected only the new address to be due, got %v", due)
+	}
+	due = store.dueAddresses(addresses, now.Add(recrawlInterval))
+	if len(due) != 3 {
+		t.Fatalf("expected all addresses to be due after the recrawl interval, got %v", due)
+	}
+
+	// Every consecutive failure doubles the time until the next poll
+	store.recordFailure(failingAddress, now)
+	due = store.dueAddresses([]*appmessage.NetAddress{failingAddress}, now.Add(recrawlInterval))
+	if len(due) != 0 {
+		t.Fatalf("expected the failing address to back off after two failures")
+	}
+	due = store.dueAddresses([]*appmessage.NetAddress{failingAddress}, now.Add(2*recrawlInterval))
+	if len(due) != 1 {
+		t.Fatalf("expected the failing address to be due after twice the recrawl interval")
+	}
+
+	store.retain([]*appmessage.NetAddress{goodAddress})
+	expectPeers(nil, true, goodAddress)
+}
+
+func TestPeerUptime(t *testing.T) {
+	now := time.Now()
+	peer := &peerInfo{}
+	peer.updateUptime(true, now)
+	peer.lastAttempt = now
+	if peer.uptime != 1 {
+		t.Fatalf("expected an uptime of 1 after the first successful poll, got %f", peer.uptime)
+	}
+
+	// A failure after a whole uptime window weighs much more than one after a minute
+	shortPeer := *peer
+	shortPeer.updateUptime(false, now.Add(time.Minute))
+	longPeer := *peer
+	longPeer.updateUptime(false, now.Add(uptimeWindow))
+	if shortPeer.uptime < 0.99 || shortPeer.uptime >= 1 {
+		t.Fatalf("unexpected uptime %f after a failure a minute later", shortPeer.uptime)
+	}
+	if longPeer.uptime < 0.3 || longPeer.uptime > 0.4 {
+		t.Fatalf("unexpected uptime %f after a failure a day later", longPeer.uptime)
+	}
+}
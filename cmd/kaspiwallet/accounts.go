@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+)
+
+func createAccount(conf *createAccountConfig) error {
+	keysFile, err := keys.ReadKeysFile(conf.NetParams(), conf.KeysFile)
+	if err != nil {
+		return err
+	}
+
+	// The keys of the account are derived from the private keys of the wallet, which are decrypted
+	// by the wallet daemon
+	password := conf.Password
+	if !keysFile.IsWatchOnly() && len(password) == 0 {
+		password = keys.GetPassword("Password:")
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.CreateAccount(ctx, &pb.CreateAccountRequest{
+		Name:               conf.Name,
+		Password:           password,
+		ExtendedPublicKeys: conf.XPubs,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created account %s with index %d\n", response.Account.Name, response.Account.Index)
+	if len(response.ExtendedPublicKeys) > 0 {
+		fmt.Println("Extended public key(s) of the account, to share with the other cosigners:")
+		for _, extendedPublicKey := range response.ExtendedPublicKeys {
+			fmt.Println(extendedPublicKey)
+		}
+	}
+	return nil
+}
+
+func accounts(conf *accountsConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.GetAccounts(ctx, &pb.GetAccountsRequest{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Accounts (%d):\n", len(response.Accounts))
+	for _, account := range response.Accounts {
+		fmt.Printf("%s (index %d)\n", account.Name, account.Index)
+		for _, extendedPublicKey := range account.ExtendedPublicKeys {
+			fmt.Printf("    %s\n", extendedPublicKey)
+		}
+	}
+	return nil
+}
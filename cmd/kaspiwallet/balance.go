@@ -3,14 +3,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
 )
 
 func balance(conf *balanceConfig) error {
-	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress)
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
 	if err != nil {
 		return err
 	}
@@ -18,7 +20,7 @@ func balance(conf *balanceConfig) error {
 
 	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
 	defer cancel()
-	response, err := daemonClient.GetBalance(ctx, &pb.GetBalanceRequest{})
+	response, err := daemonClient.GetBalance(ctx, &pb.GetBalanceRequest{Account: conf.Account})
 	if err != nil {
 		return err
 	}
@@ -32,12 +34,37 @@ func balance(conf *balanceConfig) error {
 		println("Address                                                                       Available             Pending")
 		println("-----------------------------------------------------------------------------------------------------------")
 		for _, addressBalance := range response.AddressBalances {
-			fmt.Printf("%s %s %s\n", addressBalance.Address, utils.FormatKas(addressBalance.Available), utils.FormatKas(addressBalance.Pending))
+			fmt.Printf("%s %s %s", addressBalance.Address, utils.FormatKas(addressBalance.Available), utils.FormatKas(addressBalance.Pending))
+			if addressBalance.Account != keys.DefaultAccountName {
+				fmt.Printf(" [%s]", addressBalance.Account)
+			}
+			if addressBalance.Label != "" {
+				fmt.Printf(" %s", addressBalance.Label)
+			}
+			if addressBalance.TimeLocked > 0 {
+				fmt.Printf(" (%s KAS time-locked until DAA score %d)",
+					strings.TrimSpace(utils.FormatKas(addressBalance.TimeLocked)), addressBalance.LockDaaScore)
+			}
+			fmt.Println()
 		}
 		println("-----------------------------------------------------------------------------------------------------------")
 		print("                                                 ")
 	}
+	if len(response.AccountBalances) > 1 {
+		for _, accountBalance := range response.AccountBalances {
+			fmt.Printf("Account %s balance, KAS %s %s", accountBalance.Account,
+				utils.FormatKas(accountBalance.Available), utils.FormatKas(accountBalance.Pending))
+			if accountBalance.TimeLocked > 0 {
+				fmt.Printf(" (%s KAS time-locked)", strings.TrimSpace(utils.FormatKas(accountBalance.TimeLocked)))
+			}
+			fmt.Println()
+		}
+	}
 	fmt.Printf("Total balance, KAS %s %s%s\n", utils.FormatKas(response.Available), utils.FormatKas(response.Pending), pendingSuffix)
+	if response.TimeLocked > 0 {
+		fmt.Printf("Time-locked, KAS %s, which becomes available once the lock DAA scores are reached\n",
+			utils.FormatKas(response.TimeLocked))
+	}
 
 	return nil
 }
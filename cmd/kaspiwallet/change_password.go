@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/pkg/errors"
+)
+
+func changePassword(conf *changePasswordConfig) error {
+	keysFile, err := keys.ReadKeysFile(conf.NetParams(), conf.KeysFile)
+	if err != nil {
+		return err
+	}
+
+	// The wallet daemon holds the lock of the keys file as long as it runs, and would
+	// otherwise overwrite the keys file with the mnemonics encrypted with the old password
+	err = keysFile.TryLock()
+	if err != nil {
+		return errors.Wrap(err, "Stop the wallet daemon before changing the password")
+	}
+	// The keys file is read again now that it's locked, so that whatever the daemon saved
+	// before it stopped isn't overwritten with what was read before
+	keysFile, err = keys.ReadKeysFile(conf.NetParams(), keysFile.Path())
+	if err != nil {
+		return err
+	}
+
+	if keysFile.IsWatchOnly() {
+		return errors.Errorf("The wallet is watch-only and has no private keys to encrypt")
+	}
+
+	if len(conf.Password) == 0 {
+		conf.Password = keys.GetPassword("Current password:")
+	}
+	if len(conf.NewPassword) == 0 {
+		newPassword := []byte(keys.GetPassword("New password:"))
+		confirmNewPassword := []byte(keys.GetPassword("Confirm new password:"))
+
+		if subtle.ConstantTimeCompare(newPassword, confirmNewPassword) != 1 {
+			return errors.New("Passwords are not identical")
+		}
+		conf.NewPassword = string(newPassword)
+	}
+	if len(conf.NewPassword) == 0 {
+		return errors.New("The new password must not be empty")
+	}
+
+	err = keysFile.ChangePassword(conf.Password, conf.NewPassword, conf.Argon2Time, conf.Argon2Memory)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Changed the password of %s\n", keysFile.Path())
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+func compound(conf *compoundConfig) error {
+	keysFile, err := keys.ReadKeysFile(conf.NetParams(), conf.KeysFile)
+	if err != nil {
+		return err
+	}
+
+	if !conf.DryRun && keysFile.IsWatchOnly() {
+		return errors.Errorf("Cannot use 'compound' command for a watch-only wallet, other than with '--dry-run'")
+	}
+	if !conf.DryRun && len(keysFile.ExtendedPublicKeys) > len(keysFile.EncryptedMnemonics) {
+		return errors.Errorf("Cannot use 'compound' command for multisig wallet without all of the keys")
+	}
+
+	var minUTXOAmountSompi uint64
+	if conf.MinUTXOAmount != "" {
+		minUTXOAmountSompi, err = util.KasToSompi(conf.MinUTXOAmount)
+		if err != nil {
+			return err
+		}
+	}
+	var maxFeeSompi uint64
+	if conf.MaxFee != "" {
+		maxFeeSompi, err = util.KasToSompi(conf.MaxFee)
+		if err != nil {
+			return err
+		}
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.CreateCompoundTransactions(ctx, &pb.CreateCompoundTransactionsRequest{
+		Account:       conf.Account,
+		MinUtxoAmount: minUTXOAmountSompi,
+		MaxInputs:     conf.MaxInputs,
+		ToAddress:     conf.ToAddress,
+		FeeRate:       conf.FeeRate,
+		MaxFee:        maxFeeSompi,
+		DryRun:        conf.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Compounding %d UTXO(s) in %d transaction(s) into %s KAS, with a fee of %s KAS for a mass of %d grams\n",
+		response.InputCount, len(response.UnsignedTransactions), strings.TrimSpace(utils.FormatKas(response.Amount)),
+		strings.TrimSpace(utils.FormatKas(response.Fee)), response.Mass)
+	if conf.DryRun {
+		return nil
+	}
+
+	return signAndBroadcast(conf.NetParams(), keysFile, conf.Password, daemonClient,
+		response.UnsignedTransactions, conf.Verbose)
+}
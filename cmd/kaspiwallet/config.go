@@ -1,9 +1,12 @@
 package main
 
 import (
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
 	"github.com/kaspikr/kaspid/infrastructure/config"
 	"github.com/pkg/errors"
 	"os"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 )
@@ -23,44 +26,105 @@ const (
 	startDaemonSubCmd               = "start-daemon"
 	versionSubCmd                   = "version"
 	getDaemonVersionSubCmd          = "get-daemon-version"
+	historySubCmd                   = "history"
+	utxosSubCmd                     = "utxos"
+	lockUTXOsSubCmd                 = "lock-utxos"
+	unlockUTXOsSubCmd               = "unlock-utxos"
+	compoundSubCmd                  = "compound"
+	createAccountSubCmd             = "create-account"
+	accountsSubCmd                  = "accounts"
+	setLabelSubCmd                  = "set-label"
+	labelsSubCmd                    = "labels"
+	watchSubCmd                     = "watch"
+	changePasswordSubCmd            = "change-password"
+	signMessageSubCmd               = "sign-message"
+	verifyMessageSubCmd             = "verify-message"
+	exportDescriptorSubCmd          = "export-descriptor"
+	verifyDescriptorSubCmd          = "verify-descriptor"
+	psktInspectSubCmd               = "pskt-inspect"
+	psktCombineSubCmd               = "pskt-combine"
+	psktFinalizeSubCmd              = "pskt-finalize"
+	htlcInitiateSubCmd              = "htlc-initiate"
+	htlcRedeemSubCmd                = "htlc-redeem"
+	htlcRefundSubCmd                = "htlc-refund"
+	htlcSecretSubCmd                = "htlc-secret"
+	htlcInspectSubCmd               = "htlc-inspect"
+	timeLockSubCmd                  = "time-lock"
+	unlockSubCmd                    = "unlock"
+	lockSubCmd                      = "lock"
 )
 
+// defaultHTLCLockDuration is the time after which an initiated hash time-locked contract can be refunded
+const defaultHTLCLockDuration = 48 * time.Hour
+
 const (
 	defaultListen    = "localhost:8082"
 	defaultRPCServer = "localhost"
 )
 
+// daemonConnectionFlags are the flags of the commands that connect to the wallet daemon,
+// matching the security flags of start-daemon
+type daemonConnectionFlags struct {
+	DaemonTLSCert       string `long:"daemon-tls-cert" description:"TLS certificate of the wallet daemon (or of its CA) to connect to it with TLS"`
+	DaemonClientCert    string `long:"daemon-client-cert" description:"Client certificate to authenticate to the wallet daemon with, when it requires client certificates"`
+	DaemonClientKey     string `long:"daemon-client-key" description:"Key of the client certificate given with --daemon-client-cert"`
+	DaemonAuthTokenFile string `long:"daemon-auth-token-file" description:"File of the auth token to authenticate to the wallet daemon with, when it requires one"`
+}
+
+func (flags *daemonConnectionFlags) connectOptions() *client.ConnectOptions {
+	return &client.ConnectOptions{
+		TLSCertFile:    flags.DaemonTLSCert,
+		ClientCertFile: flags.DaemonClientCert,
+		ClientKeyFile:  flags.DaemonClientKey,
+		AuthTokenFile:  flags.DaemonAuthTokenFile,
+	}
+}
+
 type configFlags struct {
 	ShowVersion bool `short:"V" long:"version" description:"Display version information and exit"`
 	config.NetworkFlags
 }
 
 type createConfig struct {
-	KeysFile          string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
-	Password          string `long:"password" short:"p" description:"Wallet password"`
-	Yes               bool   `long:"yes" short:"y" description:"Assume \"yes\" to all questions"`
-	MinimumSignatures uint32 `long:"min-signatures" short:"m" description:"Minimum required signatures" default:"1"`
-	NumPrivateKeys    uint32 `long:"num-private-keys" short:"k" description:"Number of private keys" default:"1"`
-	NumPublicKeys     uint32 `long:"num-public-keys" short:"n" description:"Total number of keys" default:"1"`
-	ECDSA             bool   `long:"ecdsa" description:"Create an ECDSA wallet"`
-	Import            bool   `long:"import" short:"i" description:"Import private keys (as opposed to generating them)"`
+	KeysFile          string   `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Password          string   `long:"password" short:"p" description:"Wallet password"`
+	Yes               bool     `long:"yes" short:"y" description:"Assume \"yes\" to all questions"`
+	MinimumSignatures uint32   `long:"min-signatures" short:"m" description:"Minimum required signatures" default:"1"`
+	NumPrivateKeys    uint32   `long:"num-private-keys" short:"k" description:"Number of private keys" default:"1"`
+	NumPublicKeys     uint32   `long:"num-public-keys" short:"n" description:"Total number of keys" default:"1"`
+	ECDSA             bool     `long:"ecdsa" description:"Create an ECDSA wallet"`
+	Import            bool     `long:"import" short:"i" description:"Import private keys (as opposed to generating them)"`
+	UsePassphrase     bool     `long:"use-passphrase" description:"Derive the keys from the mnemonics with a BIP39 passphrase, which is asked for every mnemonic and stored encrypted in the keys file"`
+	WatchOnly         bool     `long:"watch-only" description:"Create a watch-only wallet, holding no private keys, from the extended public keys given with --xpub"`
+	XPubs             []string `long:"xpub" description:"An extended public key of a watch-only wallet. Repeat multiple times (adding --xpub before each) to watch a multisig wallet"`
+	Descriptor        string   `long:"descriptor" description:"Create the wallet with the keys of the given wallet descriptor (exported with export-descriptor), importing its private keys or watching it with --watch-only"`
 	config.NetworkFlags
 }
 
 type balanceConfig struct {
 	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
-	Verbose       bool   `long:"verbose" short:"v" description:"Verbose: show addresses with balance"`
+	daemonConnectionFlags
+	Account string `long:"account" description:"Show only the balance of the account with this name (default: all accounts)"`
+	Verbose bool   `long:"verbose" short:"v" description:"Verbose: show addresses with balance"`
 	config.NetworkFlags
 }
 
 type sendConfig struct {
-	KeysFile                 string   `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
-	Password                 string   `long:"password" short:"p" description:"Wallet password"`
-	DaemonAddress            string   `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
-	ToAddress                string   `long:"to-address" short:"t" description:"The public address to send Kaspi to" required:"true"`
+	KeysFile      string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Password      string `long:"password" short:"p" description:"Wallet password"`
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account                  string   `long:"account" description:"The account to send from (default: the default account)"`
+	ToAddress                string   `long:"to-address" short:"t" description:"The public address to send Kaspi to, or a payment URI such as kaspi:<address>?amount=<amount in Kaspi>, which makes --send-amount optional"`
 	FromAddresses            []string `long:"from-address" short:"a" description:"Specific public address to send Kaspi from. Repeat multiple times (adding -a before each) to accept several addresses" required:"false"`
 	SendAmount               string   `long:"send-amount" short:"v" description:"An amount to send in Kaspi (e.g. 1234.12345678)"`
 	IsSendAll                bool     `long:"send-all" description:"Send all the Kaspi in the wallet (mutually exclusive with --send-amount). If --from-address was used, will send all only from the specified addresses."`
+	Outputs                  []string `long:"output" short:"o" description:"A payment in the form <address>,<amount in Kaspi>, or a payment URI with an amount. Repeat multiple times (adding -o before each) to pay several addresses at once (mutually exclusive with --to-address)"`
+	OutputsFile              string   `long:"outputs-file" description:"A CSV file with a payment in the form <address>,<amount in Kaspi> on each line, where the address may be a payment URI whose amount is paid if the amount is empty (mutually exclusive with --to-address)"`
+	FeeRate                  float64  `long:"fee-rate" description:"The fee to pay per gram of transaction mass, in sompi (default: 1, the minimum relay fee rate)"`
+	MaxFee                   string   `long:"max-fee" description:"Fail if the transaction(s) would pay a fee higher than this, in Kaspi (e.g. 0.01)"`
+	SubtractFeeFromAmount    bool     `long:"subtract-fee-from-amount" description:"Deduct the fee from the sent amount, splitting it evenly between the outputs, instead of paying it on top of it"`
+	UTXOs                    []string `long:"utxo" description:"A UTXO to spend, in the form <transaction ID>:<index>. Repeat multiple times to spend several UTXOs. All of them are spent instead of UTXOs selected by the wallet (mutually exclusive with --from-address)"`
 	UseExistingChangeAddress bool     `long:"use-existing-change-address" short:"u" description:"Will use an existing change address (in case no change address was ever used, it will use a new one)"`
 	Verbose                  bool     `long:"show-serialized" short:"s" description:"Show a list of hex encoded sent transactions"`
 	config.NetworkFlags
@@ -69,31 +133,111 @@ type sendConfig struct {
 type sweepConfig struct {
 	PrivateKey    string `long:"private-key" short:"k" description:"Private key in hex format"`
 	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
 	config.NetworkFlags
 }
 
 type createUnsignedTransactionConfig struct {
-	DaemonAddress            string   `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
-	ToAddress                string   `long:"to-address" short:"t" description:"The public address to send Kaspi to" required:"true"`
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account                  string   `long:"account" description:"The account to send from (default: the default account)"`
+	ToAddress                string   `long:"to-address" short:"t" description:"The public address to send Kaspi to, or a payment URI such as kaspi:<address>?amount=<amount in Kaspi>, which makes --send-amount optional"`
 	FromAddresses            []string `long:"from-address" short:"a" description:"Specific public address to send Kaspi from. Use multiple times to accept several addresses" required:"false"`
 	SendAmount               string   `long:"send-amount" short:"v" description:"An amount to send in Kaspi (e.g. 1234.12345678)"`
 	IsSendAll                bool     `long:"send-all" description:"Send all the Kaspi in the wallet (mutually exclusive with --send-amount)"`
+	Outputs                  []string `long:"output" short:"o" description:"A payment in the form <address>,<amount in Kaspi>, or a payment URI with an amount. Use multiple times to pay several addresses at once (mutually exclusive with --to-address)"`
+	OutputsFile              string   `long:"outputs-file" description:"A CSV file with a payment in the form <address>,<amount in Kaspi> on each line, where the address may be a payment URI whose amount is paid if the amount is empty (mutually exclusive with --to-address)"`
+	FeeRate                  float64  `long:"fee-rate" description:"The fee to pay per gram of transaction mass, in sompi (default: 1, the minimum relay fee rate)"`
+	MaxFee                   string   `long:"max-fee" description:"Fail if the transaction(s) would pay a fee higher than this, in Kaspi (e.g. 0.01)"`
+	SubtractFeeFromAmount    bool     `long:"subtract-fee-from-amount" description:"Deduct the fee from the sent amount, splitting it evenly between the outputs, instead of paying it on top of it"`
+	UTXOs                    []string `long:"utxo" description:"A UTXO to spend, in the form <transaction ID>:<index>. Repeat multiple times to spend several UTXOs. All of them are spent instead of UTXOs selected by the wallet (mutually exclusive with --from-address)"`
 	UseExistingChangeAddress bool     `long:"use-existing-change-address" short:"u" description:"Will use an existing change address (in case no change address was ever used, it will use a new one)"`
+	PSKT                     bool     `long:"pskt" description:"Print the transaction(s) as PSKTs (encoded in base64, one on each line) for external signers"`
 	config.NetworkFlags
 }
 
 type signConfig struct {
 	KeysFile        string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
 	Password        string `long:"password" short:"p" description:"Wallet password"`
-	Transaction     string `long:"transaction" short:"t" description:"The unsigned transaction(s) to sign on (encoded in hex)"`
-	TransactionFile string `long:"transaction-file" short:"F" description:"The file containing the unsigned transaction(s) to sign on (encoded in hex)"`
+	Transaction     string `long:"transaction" short:"t" description:"The unsigned transaction(s) to sign on (encoded in hex, or PSKTs)"`
+	TransactionFile string `long:"transaction-file" short:"F" description:"The file containing the unsigned transaction(s) to sign on (encoded in hex, or PSKTs)"`
 	config.NetworkFlags
 }
 
 type broadcastConfig struct {
-	DaemonAddress    string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
 	Transactions     string `long:"transaction" short:"t" description:"The signed transaction to broadcast (encoded in hex)"`
 	TransactionsFile string `long:"transaction-file" short:"F" description:"The file containing the unsigned transaction to sign on (encoded in hex)"`
+	IsFinalized      bool   `long:"finalized" description:"The transaction(s) were finalized from PSKTs with pskt-finalize"`
+	config.NetworkFlags
+}
+
+type psktInspectConfig struct {
+	Transaction     string `long:"transaction" short:"t" description:"The PSKT(s) to inspect (encoded in base64 or in JSON)"`
+	TransactionFile string `long:"transaction-file" short:"F" description:"The file containing the PSKT(s) to inspect (encoded in base64 or in JSON)"`
+	JSON            bool   `long:"json" description:"Print the PSKT(s) in JSON"`
+	config.NetworkFlags
+}
+
+type psktCombineConfig struct {
+	Transactions     []string `long:"transaction" short:"t" description:"A PSKT to combine (encoded in base64 or in JSON). Use multiple times to combine several PSKTs"`
+	TransactionFiles []string `long:"transaction-file" short:"F" description:"A file containing a PSKT to combine (encoded in base64 or in JSON). Use multiple times to combine several PSKTs"`
+	config.NetworkFlags
+}
+
+type psktFinalizeConfig struct {
+	Transaction     string `long:"transaction" short:"t" description:"The PSKT(s) to finalize (encoded in base64 or in JSON)"`
+	TransactionFile string `long:"transaction-file" short:"F" description:"The file containing the PSKT(s) to finalize (encoded in base64 or in JSON)"`
+	config.NetworkFlags
+}
+
+type htlcInitiateConfig struct {
+	KeysFile      string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Password      string `long:"password" short:"p" description:"Wallet password"`
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account       string        `long:"account" description:"The account to pay the contract from, and to refund it to (default: the default account)"`
+	ToAddress     string        `long:"to-address" short:"t" description:"The public key address of the recipient of the contract" required:"true"`
+	RefundAddress string        `long:"refund-address" description:"The public key address of the wallet the contract is refunded to (default: a new address of the account)"`
+	SendAmount    string        `long:"send-amount" short:"v" description:"An amount to lock in the contract in Kaspi (e.g. 1234.12345678)" required:"true"`
+	SecretHash    string        `long:"secret-hash" description:"The SHA256 hash of the secret of the counterparty's contract (encoded in hex). If it's not set, a new secret is generated, and the contract is locked with its hash"`
+	LockDuration  time.Duration `long:"lock-duration" description:"The time after which the contract can be refunded. The participant of a swap should lock their contract for about half as long as the initiator"`
+	FeeRate       float64       `long:"fee-rate" description:"The fee to pay per gram of transaction mass, in sompi (default: 1, the minimum relay fee rate)"`
+	config.NetworkFlags
+}
+
+type htlcRedeemConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Password string  `long:"password" short:"p" description:"Wallet password"`
+	Contract string  `long:"contract" short:"c" description:"The contract to redeem (encoded in hex)" required:"true"`
+	Secret   string  `long:"secret" short:"s" description:"The secret of the contract (encoded in hex)" required:"true"`
+	FeeRate  float64 `long:"fee-rate" description:"The fee to pay per gram of transaction mass, in sompi (default: 1, the minimum relay fee rate)"`
+	config.NetworkFlags
+}
+
+type htlcRefundConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Password string  `long:"password" short:"p" description:"Wallet password"`
+	Contract string  `long:"contract" short:"c" description:"The contract to refund (encoded in hex)" required:"true"`
+	FeeRate  float64 `long:"fee-rate" description:"The fee to pay per gram of transaction mass, in sompi (default: 1, the minimum relay fee rate)"`
+	config.NetworkFlags
+}
+
+type htlcSecretConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Contract       string `long:"contract" short:"c" description:"The contract whose secret to wait for (encoded in hex)" required:"true"`
+	StartBlockHash string `long:"start-block-hash" description:"A chain block to search the blocks after for the redeem transaction, for a contract that may have been redeemed already (default: the current selected tip)"`
+	config.NetworkFlags
+}
+
+type htlcInspectConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Contract string `long:"contract" short:"c" description:"The contract to inspect (encoded in hex)" required:"true"`
 	config.NetworkFlags
 }
 
@@ -106,21 +250,82 @@ type parseConfig struct {
 
 type showAddressesConfig struct {
 	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account string `long:"account" description:"The account to show the addresses of (default: the default account)"`
 	config.NetworkFlags
 }
 
 type newAddressConfig struct {
 	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account      string        `long:"account" description:"The account to generate the address for (default: the default account)"`
+	Amount       string        `long:"amount" description:"Also show a payment URI that requests this amount of Kaspi to the new address"`
+	Label        string        `long:"label" description:"Also show a payment URI with this label for the recipient"`
+	Message      string        `long:"message" description:"Also show a payment URI with this message describing the payment"`
+	ExpiresIn    time.Duration `long:"expires-in" description:"Also show a payment URI that expires after this duration (e.g. 1h)"`
+	LockDAAScore uint64        `long:"lock-daa-score" description:"Generate a time-locked address, whose funds can't be spent until the DAA score of the network passes this score"`
+	LockDuration time.Duration `long:"lock-duration" description:"Generate a time-locked address, whose funds can't be spent until about this duration passes (e.g. 720h). It's converted to a DAA score by the target time per block of the network"`
+	config.NetworkFlags
+}
+
+type timeLockConfig struct {
+	KeysFile      string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Password      string `long:"password" short:"p" description:"Wallet password"`
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account      string        `long:"account" description:"The account to lock the funds of (default: the default account)"`
+	SendAmount   string        `long:"send-amount" short:"v" description:"An amount to lock in Kaspi (e.g. 1234.12345678)" required:"true"`
+	LockDAAScore uint64        `long:"lock-daa-score" description:"Lock the funds until the DAA score of the network passes this score"`
+	LockDuration time.Duration `long:"lock-duration" description:"Lock the funds for about this duration (e.g. 720h). It's converted to a DAA score by the target time per block of the network"`
+	FeeRate      float64       `long:"fee-rate" description:"The fee to pay per gram of transaction mass, in sompi (default: 1, the minimum relay fee rate)"`
 	config.NetworkFlags
 }
 
+type unlockConfig struct {
+	Password      string `long:"password" short:"p" description:"Wallet password"`
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Timeout time.Duration `long:"timeout" short:"t" description:"How long the wallet stays unlocked (e.g. 15m)" default:"15m"`
+}
+
+type lockConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+}
+
 type startDaemonConfig struct {
-	KeysFile  string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
-	Password  string `long:"password" short:"p" description:"Wallet password"`
-	RPCServer string `long:"rpcserver" short:"s" description:"RPC server to connect to"`
-	Listen    string `long:"listen" short:"l" description:"Address to listen on (default: 0.0.0.0:8082)"`
-	Timeout   uint32 `long:"wait-timeout" short:"w" description:"Waiting timeout for RPC calls, seconds (default: 30 s)"`
-	Profile   string `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
+	KeysFile      string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Password      string `long:"password" short:"p" description:"Wallet password"`
+	RPCServer     string `long:"rpcserver" short:"s" description:"RPC server to connect to"`
+	Listen        string `long:"listen" short:"l" description:"Address to listen on (default: localhost:8082). Listening on other addresses should be secured with TLS and authentication"`
+	HTTPListen    string `long:"http-listen" description:"Also serve the HTTP/JSON API on this address (e.g. localhost:8083), secured like the gRPC service. Requires --auth-token-file"`
+	Timeout       uint32 `long:"wait-timeout" short:"w" description:"Waiting timeout for RPC calls, seconds (default: 30 s)"`
+	Profile       string `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
+	TLSCert       string `long:"tls-cert" description:"TLS certificate to serve clients with. A self-signed certificate is generated if neither it nor the key exists"`
+	TLSKey        string `long:"tls-key" description:"Key of the TLS certificate"`
+	TLSClientCA   string `long:"tls-client-ca" description:"Require clients to authenticate with a TLS certificate signed by the CA certificate in this file"`
+	AuthTokenFile string `long:"auth-token-file" description:"Require clients to authenticate with the auth token in this file. A random token is generated if the file doesn't exist"`
+	config.NetworkFlags
+}
+
+type changePasswordConfig struct {
+	KeysFile     string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Password     string `long:"password" short:"p" description:"Current wallet password"`
+	NewPassword  string `long:"new-password" description:"New wallet password"`
+	Argon2Time   uint32 `long:"argon2-time" description:"The number of argon2 passes the new password is hashed with (default: the current number, which is 1 for new wallets)"`
+	Argon2Memory uint32 `long:"argon2-memory" description:"The memory in KiB the new password is hashed with by argon2 (default: the current memory, which is 65536 for new wallets)"`
+	config.NetworkFlags
+}
+
+type exportDescriptorConfig struct {
+	KeysFile string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	config.NetworkFlags
+}
+
+type verifyDescriptorConfig struct {
+	KeysFile     string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Descriptor   string `long:"descriptor" description:"The wallet descriptor to verify the wallet against" required:"true"`
+	NumAddresses uint32 `long:"num-addresses" description:"The number of receive addresses of each cosigner to print" default:"3"`
 	config.NetworkFlags
 }
 
@@ -131,11 +336,114 @@ type dumpUnencryptedDataConfig struct {
 	config.NetworkFlags
 }
 
+type historyConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Limit         uint32 `long:"limit" short:"n" description:"Show only the given number of most recent transactions (default: all)"`
+	TransactionID string `long:"transaction-id" short:"t" description:"Show the details of the transaction with the given ID"`
+	Verbose       bool   `long:"verbose" short:"v" description:"Verbose: show the details of every transaction"`
+	config.NetworkFlags
+}
+
+type utxosConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account   string   `long:"account" description:"Show only the UTXOs of the account with this name (default: all accounts)"`
+	Addresses []string `long:"address" short:"a" description:"Show only the UTXOs of this address. Repeat multiple times (adding -a before each) to show the UTXOs of several addresses"`
+	config.NetworkFlags
+}
+
+type lockUTXOsConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	UTXOs []string `long:"utxo" short:"u" description:"The UTXO to lock, in the form <transaction ID>:<index>. Repeat multiple times (adding -u before each) to lock several UTXOs" required:"true"`
+	Label string   `long:"label" short:"l" description:"A label to show next to the locked UTXOs, e.g. the reason they are locked"`
+	config.NetworkFlags
+}
+
+type unlockUTXOsConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	UTXOs []string `long:"utxo" short:"u" description:"The UTXO to unlock, in the form <transaction ID>:<index>. Repeat multiple times (adding -u before each) to unlock several UTXOs" required:"true"`
+	config.NetworkFlags
+}
+
+type compoundConfig struct {
+	KeysFile      string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Password      string `long:"password" short:"p" description:"Wallet password"`
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account       string  `long:"account" description:"The account to compound the UTXOs of (default: the default account)"`
+	ToAddress     string  `long:"to-address" short:"t" description:"The public address to compound the UTXOs into (default: a change address of the account)"`
+	MinUTXOAmount string  `long:"min-utxo-amount" description:"Leave out UTXOs worth less than this, in Kaspi (e.g. 0.5)"`
+	MaxInputs     uint32  `long:"max-inputs" description:"Compound at most this number of UTXOs, the smallest first (default: all)"`
+	FeeRate       float64 `long:"fee-rate" description:"The fee to pay per gram of transaction mass, in sompi (default: 1, the minimum relay fee rate)"`
+	MaxFee        string  `long:"max-fee" description:"Fail if the transaction(s) would pay a fee higher than this, in Kaspi (e.g. 0.01)"`
+	DryRun        bool    `long:"dry-run" description:"Only show the transactions and fee the compound would take, without sending anything"`
+	Verbose       bool    `long:"show-serialized" short:"s" description:"Show a list of hex encoded sent transactions"`
+	config.NetworkFlags
+}
+
+type createAccountConfig struct {
+	KeysFile      string `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kaspiwallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Kaspiwallet\\key.json (Windows))"`
+	Password      string `long:"password" short:"p" description:"Wallet password"`
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Name  string   `long:"name" short:"n" description:"The name of the new account" required:"true"`
+	XPubs []string `long:"xpub" description:"The extended public key of the new account of a cosigner, or of a watch-only wallet. Repeat multiple times (adding --xpub before each) for several keys"`
+	config.NetworkFlags
+}
+
+type accountsConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	config.NetworkFlags
+}
+
+type setLabelConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Address string `long:"address" short:"a" description:"The address of the wallet to label (mutually exclusive with --utxo)"`
+	UTXO    string `long:"utxo" short:"u" description:"The UTXO of the wallet to label, in the form <transaction ID>:<index> (mutually exclusive with --address)"`
+	Label   string `long:"label" short:"l" description:"The label to set. An empty label removes the existing one"`
+	config.NetworkFlags
+}
+
+type signMessageConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Password string `long:"password" short:"p" description:"Wallet password"`
+	Address  string `long:"address" short:"a" description:"The address of the wallet to sign the message with" required:"true"`
+	Message  string `long:"message" short:"m" description:"The message to sign" required:"true"`
+	config.NetworkFlags
+}
+
+type verifyMessageConfig struct {
+	Address   string `long:"address" short:"a" description:"The address that signed the message" required:"true"`
+	Message   string `long:"message" short:"m" description:"The signed message" required:"true"`
+	Signature string `long:"signature" short:"s" description:"The signature to verify (encoded in hex)" required:"true"`
+	config.NetworkFlags
+}
+
+type labelsConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	config.NetworkFlags
+}
+
+type watchConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
+	Account string `long:"account" description:"Watch only the changes of the account with this name (default: all accounts)"`
+	config.NetworkFlags
+}
+
 type versionConfig struct {
 }
 
 type getDaemonVersionConfig struct {
 	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	daemonConnectionFlags
 }
 
 func parseCommandLine() (subCommand string, config interface{}) {
@@ -184,6 +492,129 @@ func parseCommandLine() (subCommand string, config interface{}) {
 	parser.AddCommand(newAddressSubCmd, "Generates new public address of the current wallet and shows it",
 		"Generates new public address of the current wallet and shows it", newAddressConf)
 
+	historyConf := &historyConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(historySubCmd, "Shows the transactions of the wallet",
+		"Shows the transactions the wallet received or sent funds in, as observed by the wallet daemon, the most recent first", historyConf)
+
+	utxosConf := &utxosConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(utxosSubCmd, "Shows the UTXOs of the wallet",
+		"Shows the UTXOs of the wallet, the largest first, along with whether they are locked and the labels they were locked with", utxosConf)
+
+	lockUTXOsConf := &lockUTXOsConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(lockUTXOsSubCmd, "Locks UTXOs of the wallet so they are not spent",
+		"Locks UTXOs of the wallet, so that they are not spent until they are unlocked, even across restarts of the wallet daemon", lockUTXOsConf)
+
+	unlockUTXOsConf := &unlockUTXOsConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(unlockUTXOsSubCmd, "Unlocks locked UTXOs of the wallet",
+		"Unlocks UTXOs of the wallet that were locked with lock-utxos, so that they can be spent again", unlockUTXOsConf)
+
+	compoundConf := &compoundConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(compoundSubCmd, "Consolidates the UTXOs of the wallet into fewer outputs",
+		"Consolidates the UTXOs of the wallet, the smallest first, into a single output per transaction, "+
+			"in transactions as large as the standard mass allows", compoundConf)
+
+	createAccountConf := &createAccountConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(createAccountSubCmd, "Creates a new account in the wallet",
+		"Creates a new named account in the wallet, with its own addresses and balance. The account keys are derived "+
+			"from the private keys of the wallet, while the keys of other cosigners and of watch-only wallets are given with --xpub. "+
+			"In a multisig wallet, running this without the keys of the other cosigners shows the keys to share with them", createAccountConf)
+
+	accountsConf := &accountsConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(accountsSubCmd, "Shows the accounts of the wallet",
+		"Shows the accounts of the wallet along with their extended public keys", accountsConf)
+
+	setLabelConf := &setLabelConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(setLabelSubCmd, "Sets a label for an address or a UTXO of the wallet",
+		"Sets a label for an address or a UTXO of the wallet, which is shown next to it by other commands", setLabelConf)
+
+	labelsConf := &labelsConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(labelsSubCmd, "Shows the labels of the wallet",
+		"Shows the labels set for addresses and UTXOs of the wallet", labelsConf)
+
+	watchConf := &watchConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(watchSubCmd, "Watches the changes of the wallet",
+		"Prints the UTXOs the wallet receives and spends, along with its balance, as they are observed by the wallet daemon, until interrupted", watchConf)
+
+	changePasswordConf := &changePasswordConfig{}
+	parser.AddCommand(changePasswordSubCmd, "Changes the wallet password",
+		"Re-encrypts the private keys of the wallet with a new password, optionally hashing it with higher argon2 "+
+			"cost parameters. The parameters can't be lowered, and raising them makes the wallet unreadable by "+
+			"older versions of kaspiwallet. The wallet daemon must be stopped while the password is changed.", changePasswordConf)
+
+	signMessageConf := &signMessageConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(signMessageSubCmd, "Signs a message with the key of an address of the wallet",
+		"Signs a message with the key of an address of the wallet, proving the ownership of the address. "+
+			"Only addresses of single signer wallets can sign messages", signMessageConf)
+
+	verifyMessageConf := &verifyMessageConfig{}
+	parser.AddCommand(verifyMessageSubCmd, "Verifies a message was signed by an address",
+		"Verifies a signature created with sign-message, given the address, the message and the signature", verifyMessageConf)
+
+	exportDescriptorConf := &exportDescriptorConfig{}
+	parser.AddCommand(exportDescriptorSubCmd, "Prints the descriptor of the wallet",
+		"Prints a descriptor of the keys of the wallet, with a checksum, from which the other cosigners can create "+
+			"their wallets with \"kaspiwallet create --descriptor\"", exportDescriptorConf)
+
+	verifyDescriptorConf := &verifyDescriptorConfig{}
+	parser.AddCommand(verifyDescriptorSubCmd, "Verifies the wallet matches the given descriptor",
+		"Verifies the keys of the wallet match the given descriptor, and prints the first addresses of every "+
+			"cosigner, for the cosigners to verify they all derive the same addresses", verifyDescriptorConf)
+
+	psktInspectConf := &psktInspectConfig{}
+	parser.AddCommand(psktInspectSubCmd, "Prints the contents of the given PSKT(s)",
+		"Prints the inputs, outputs, keys and signatures of the given partially signed transaction(s) (PSKTs)", psktInspectConf)
+
+	psktCombineConf := &psktCombineConfig{}
+	parser.AddCommand(psktCombineSubCmd, "Combines the signatures of the given PSKTs",
+		"Merges copies of the same partially signed transaction(s) (PSKTs) that were signed by different signers", psktCombineConf)
+
+	psktFinalizeConf := &psktFinalizeConfig{}
+	parser.AddCommand(psktFinalizeSubCmd, "Finalizes the given PSKT(s)",
+		"Builds the signature scripts of the given fully signed PSKT(s) and prints the transaction(s), ready to "+
+			"broadcast with --finalized", psktFinalizeConf)
+
+	htlcInitiateConf := &htlcInitiateConfig{DaemonAddress: defaultListen, LockDuration: defaultHTLCLockDuration}
+	parser.AddCommand(htlcInitiateSubCmd, "Pays to a new hash time-locked contract",
+		"Pays to a hash time-locked contract, which the recipient can redeem by revealing the secret behind its "+
+			"secret hash, or the wallet can refund after the lock duration. Without --secret-hash, a new secret is "+
+			"generated, which initiates an atomic swap. With the secret hash of the counterparty's contract, "+
+			"it participates in one", htlcInitiateConf)
+
+	htlcRedeemConf := &htlcRedeemConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(htlcRedeemSubCmd, "Redeems a hash time-locked contract with its secret",
+		"Redeems a hash time-locked contract whose recipient address belongs to the wallet, revealing its secret",
+		htlcRedeemConf)
+
+	htlcRefundConf := &htlcRefundConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(htlcRefundSubCmd, "Refunds a hash time-locked contract after its lock time",
+		"Refunds a hash time-locked contract whose refund address belongs to the wallet, once its lock time has passed",
+		htlcRefundConf)
+
+	htlcSecretConf := &htlcSecretConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(htlcSecretSubCmd, "Waits for the secret of a hash time-locked contract",
+		"Watches the DAG until a hash time-locked contract is redeemed, and shows the secret its redeem transaction "+
+			"revealed, with which the counterparty's contract can be redeemed", htlcSecretConf)
+
+	htlcInspectConf := &htlcInspectConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(htlcInspectSubCmd, "Shows the details of a hash time-locked contract",
+		"Shows the addresses, the secret hash and the lock time of a hash time-locked contract, along with the "+
+			"amount locked in it, for a participant of a swap to audit the contract of the counterparty", htlcInspectConf)
+
+	timeLockConf := &timeLockConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(timeLockSubCmd, "Locks funds of the wallet until a DAA score",
+		"Sends funds of the wallet to a new time-locked address of the wallet, from which they can't be spent "+
+			"until the DAA score of the network passes the lock DAA score. Once it does, the wallet spends them "+
+			"like any other funds. Only single signer wallets support time-locked addresses", timeLockConf)
+
+	unlockConf := &unlockConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(unlockSubCmd, "Unlocks the wallet in the wallet daemon for a while",
+		"Keeps the decrypted keys of the wallet in the wallet daemon until the timeout passes, so that clients of the "+
+			"daemon can sign with it without sending the password with every request", unlockConf)
+
+	lockConf := &lockConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(lockSubCmd, "Locks the wallet in the wallet daemon",
+		"Makes the wallet daemon forget the decrypted keys of the wallet before the unlock timeout passes", lockConf)
+
 	dumpUnencryptedDataConf := &dumpUnencryptedDataConfig{}
 	parser.AddCommand(dumpUnencryptedDataSubCmd, "Prints the unencrypted wallet data",
 		"Prints the unencrypted wallet data including its private keys. Anyone that sees it can access "+
@@ -216,6 +647,10 @@ func parseCommandLine() (subCommand string, config interface{}) {
 		if err != nil {
 			printErrorAndExit(err)
 		}
+		err = validateCreateConfig(createConf)
+		if err != nil {
+			printErrorAndExit(err)
+		}
 		config = createConf
 	case balanceSubCmd:
 		combineNetworkFlags(&balanceConf.NetworkFlags, &cfg.NetworkFlags)
@@ -287,7 +722,208 @@ func parseCommandLine() (subCommand string, config interface{}) {
 		if err != nil {
 			printErrorAndExit(err)
 		}
+		if newAddressConf.ExpiresIn < 0 {
+			printErrorAndExit(errors.New("--expires-in must not be negative"))
+		}
+		if newAddressConf.LockDAAScore != 0 && newAddressConf.LockDuration != 0 {
+			printErrorAndExit(errors.New("--lock-daa-score and --lock-duration can't be used together"))
+		}
+		if newAddressConf.LockDuration < 0 {
+			printErrorAndExit(errors.New("--lock-duration must not be negative"))
+		}
 		config = newAddressConf
+	case historySubCmd:
+		combineNetworkFlags(&historyConf.NetworkFlags, &cfg.NetworkFlags)
+		err := historyConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = historyConf
+	case utxosSubCmd:
+		combineNetworkFlags(&utxosConf.NetworkFlags, &cfg.NetworkFlags)
+		err := utxosConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = utxosConf
+	case lockUTXOsSubCmd:
+		combineNetworkFlags(&lockUTXOsConf.NetworkFlags, &cfg.NetworkFlags)
+		err := lockUTXOsConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = lockUTXOsConf
+	case unlockUTXOsSubCmd:
+		combineNetworkFlags(&unlockUTXOsConf.NetworkFlags, &cfg.NetworkFlags)
+		err := unlockUTXOsConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = unlockUTXOsConf
+	case compoundSubCmd:
+		combineNetworkFlags(&compoundConf.NetworkFlags, &cfg.NetworkFlags)
+		err := compoundConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = compoundConf
+	case createAccountSubCmd:
+		combineNetworkFlags(&createAccountConf.NetworkFlags, &cfg.NetworkFlags)
+		err := createAccountConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = createAccountConf
+	case accountsSubCmd:
+		combineNetworkFlags(&accountsConf.NetworkFlags, &cfg.NetworkFlags)
+		err := accountsConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = accountsConf
+	case setLabelSubCmd:
+		combineNetworkFlags(&setLabelConf.NetworkFlags, &cfg.NetworkFlags)
+		err := setLabelConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		err = validateSetLabelConfig(setLabelConf)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = setLabelConf
+	case labelsSubCmd:
+		combineNetworkFlags(&labelsConf.NetworkFlags, &cfg.NetworkFlags)
+		err := labelsConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = labelsConf
+	case watchSubCmd:
+		combineNetworkFlags(&watchConf.NetworkFlags, &cfg.NetworkFlags)
+		err := watchConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = watchConf
+	case changePasswordSubCmd:
+		combineNetworkFlags(&changePasswordConf.NetworkFlags, &cfg.NetworkFlags)
+		err := changePasswordConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		err = validateChangePasswordConfig(changePasswordConf)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = changePasswordConf
+	case signMessageSubCmd:
+		combineNetworkFlags(&signMessageConf.NetworkFlags, &cfg.NetworkFlags)
+		err := signMessageConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = signMessageConf
+	case verifyMessageSubCmd:
+		combineNetworkFlags(&verifyMessageConf.NetworkFlags, &cfg.NetworkFlags)
+		err := verifyMessageConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = verifyMessageConf
+	case exportDescriptorSubCmd:
+		combineNetworkFlags(&exportDescriptorConf.NetworkFlags, &cfg.NetworkFlags)
+		err := exportDescriptorConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = exportDescriptorConf
+	case verifyDescriptorSubCmd:
+		combineNetworkFlags(&verifyDescriptorConf.NetworkFlags, &cfg.NetworkFlags)
+		err := verifyDescriptorConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = verifyDescriptorConf
+	case psktInspectSubCmd:
+		combineNetworkFlags(&psktInspectConf.NetworkFlags, &cfg.NetworkFlags)
+		err := psktInspectConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = psktInspectConf
+	case psktCombineSubCmd:
+		combineNetworkFlags(&psktCombineConf.NetworkFlags, &cfg.NetworkFlags)
+		err := psktCombineConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = psktCombineConf
+	case psktFinalizeSubCmd:
+		combineNetworkFlags(&psktFinalizeConf.NetworkFlags, &cfg.NetworkFlags)
+		err := psktFinalizeConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = psktFinalizeConf
+	case htlcInitiateSubCmd:
+		combineNetworkFlags(&htlcInitiateConf.NetworkFlags, &cfg.NetworkFlags)
+		err := htlcInitiateConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		if htlcInitiateConf.LockDuration <= 0 {
+			printErrorAndExit(errors.New("--lock-duration must be positive"))
+		}
+		config = htlcInitiateConf
+	case htlcRedeemSubCmd:
+		combineNetworkFlags(&htlcRedeemConf.NetworkFlags, &cfg.NetworkFlags)
+		err := htlcRedeemConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = htlcRedeemConf
+	case htlcRefundSubCmd:
+		combineNetworkFlags(&htlcRefundConf.NetworkFlags, &cfg.NetworkFlags)
+		err := htlcRefundConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = htlcRefundConf
+	case htlcSecretSubCmd:
+		combineNetworkFlags(&htlcSecretConf.NetworkFlags, &cfg.NetworkFlags)
+		err := htlcSecretConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = htlcSecretConf
+	case htlcInspectSubCmd:
+		combineNetworkFlags(&htlcInspectConf.NetworkFlags, &cfg.NetworkFlags)
+		err := htlcInspectConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = htlcInspectConf
+	case timeLockSubCmd:
+		combineNetworkFlags(&timeLockConf.NetworkFlags, &cfg.NetworkFlags)
+		err := timeLockConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		if (timeLockConf.LockDAAScore == 0) == (timeLockConf.LockDuration == 0) {
+			printErrorAndExit(errors.New("exactly one of --lock-daa-score and --lock-duration is required"))
+		}
+		if timeLockConf.LockDuration < 0 {
+			printErrorAndExit(errors.New("--lock-duration must be positive"))
+		}
+		config = timeLockConf
+	case unlockSubCmd:
+		if unlockConf.Timeout < time.Second {
+			printErrorAndExit(errors.New("--timeout must be at least a second"))
+		}
+		config = unlockConf
+	case lockSubCmd:
+		config = lockConf
 	case dumpUnencryptedDataSubCmd:
 		combineNetworkFlags(&dumpUnencryptedDataConf.NetworkFlags, &cfg.NetworkFlags)
 		err := dumpUnencryptedDataConf.ResolveNetwork(parser)
@@ -301,6 +937,16 @@ func parseCommandLine() (subCommand string, config interface{}) {
 		if err != nil {
 			printErrorAndExit(err)
 		}
+		if (startDaemonConf.TLSCert == "") != (startDaemonConf.TLSKey == "") {
+			printErrorAndExit(errors.New("--tls-cert and --tls-key must be given together"))
+		}
+		if startDaemonConf.TLSClientCA != "" && startDaemonConf.TLSCert == "" {
+			printErrorAndExit(errors.New("--tls-client-ca requires TLS, with --tls-cert and --tls-key"))
+		}
+		if startDaemonConf.HTTPListen != "" && startDaemonConf.AuthTokenFile == "" {
+			printErrorAndExit(errors.New("--http-listen requires --auth-token-file, since any web page the user " +
+				"visits can send requests to a local HTTP server"))
+		}
 		config = startDaemonConf
 	case versionSubCmd:
 	case getDaemonVersionSubCmd:
@@ -310,20 +956,75 @@ func parseCommandLine() (subCommand string, config interface{}) {
 	return parser.Command.Active.Name, config
 }
 
-func validateCreateUnsignedTransactionConf(conf *createUnsignedTransactionConfig) error {
-	if (!conf.IsSendAll && conf.SendAmount == "") ||
-		(conf.IsSendAll && conf.SendAmount != "") {
-
-		return errors.New("exactly one of '--send-amount' or '--all' must be specified")
+func validateCreateConfig(conf *createConfig) error {
+	if conf.Descriptor != "" {
+		if len(conf.XPubs) > 0 {
+			return errors.New("'--descriptor' can't be used together with '--xpub'")
+		}
+		if conf.NumPublicKeys != 1 || conf.MinimumSignatures != 1 || conf.ECDSA {
+			return errors.New("the keys, the minimum signatures and the key type of the wallet are taken " +
+				"from '--descriptor', and can't be given with '-n', '-m' or '--ecdsa'")
+		}
+		if conf.WatchOnly {
+			if conf.Import || conf.UsePassphrase {
+				return errors.New("'--watch-only' can't be used together with '--import' or '--use-passphrase'")
+			}
+			return nil
+		}
+		if !conf.Import {
+			return errors.New("the private keys of a wallet created from '--descriptor' have to be imported with '--import'")
+		}
+		return nil
+	}
+	if !conf.WatchOnly {
+		if len(conf.XPubs) > 0 {
+			return errors.New("'--xpub' can only be used together with '--watch-only'")
+		}
+		return nil
+	}
+	if conf.Import {
+		return errors.New("'--watch-only' can't be used together with '--import'")
+	}
+	if conf.UsePassphrase {
+		return errors.New("'--watch-only' can't be used together with '--use-passphrase'")
+	}
+	if len(conf.XPubs) == 0 {
+		return errors.New("'--watch-only' requires the extended public keys of the wallet, given with '--xpub'")
+	}
+	if conf.MinimumSignatures == 0 || int(conf.MinimumSignatures) > len(conf.XPubs) {
+		return errors.Errorf("the minimum signatures must be between 1 and the number of extended public keys (%d)",
+			len(conf.XPubs))
 	}
 	return nil
 }
 
+func validateCreateUnsignedTransactionConf(conf *createUnsignedTransactionConfig) error {
+	if len(conf.FromAddresses) > 0 && len(conf.UTXOs) > 0 {
+		return errors.New("'--from-address' can't be used together with '--utxo'")
+	}
+	return validatePaymentFlags(conf.ToAddress, conf.SendAmount, conf.IsSendAll, conf.Outputs, conf.OutputsFile)
+}
+
 func validateSendConfig(conf *sendConfig) error {
-	if (!conf.IsSendAll && conf.SendAmount == "") ||
-		(conf.IsSendAll && conf.SendAmount != "") {
+	if len(conf.FromAddresses) > 0 && len(conf.UTXOs) > 0 {
+		return errors.New("'--from-address' can't be used together with '--utxo'")
+	}
+	return validatePaymentFlags(conf.ToAddress, conf.SendAmount, conf.IsSendAll, conf.Outputs, conf.OutputsFile)
+}
 
-		return errors.New("exactly one of '--send-amount' or '--all' must be specified")
+func validateSetLabelConfig(conf *setLabelConfig) error {
+	if (conf.Address == "") == (conf.UTXO == "") {
+		return errors.New("exactly one of '--address' or '--utxo' is required")
+	}
+	return nil
+}
+
+func validateChangePasswordConfig(conf *changePasswordConfig) error {
+	if conf.Argon2Time != 0 && conf.Argon2Time < keys.DefaultArgon2Time {
+		return errors.Errorf("'--argon2-time' must be at least %d", keys.DefaultArgon2Time)
+	}
+	if conf.Argon2Memory != 0 && conf.Argon2Memory < keys.DefaultArgon2Memory {
+		return errors.Errorf("'--argon2-memory' must be at least %d", keys.DefaultArgon2Memory)
 	}
 	return nil
 }
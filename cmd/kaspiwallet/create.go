@@ -14,14 +14,23 @@ import (
 )
 
 func create(conf *createConfig) error {
+	if conf.Descriptor != "" {
+		return createFromDescriptor(conf)
+	}
+	if conf.WatchOnly {
+		return createWatchOnly(conf)
+	}
+
 	var encryptedMnemonics []*keys.EncryptedMnemonic
 	var signerExtendedPublicKeys []string
 	var err error
 	isMultisig := conf.NumPublicKeys > 1
 	if !conf.Import {
-		encryptedMnemonics, signerExtendedPublicKeys, err = keys.CreateMnemonics(conf.NetParams(), conf.NumPrivateKeys, conf.Password, isMultisig)
+		encryptedMnemonics, signerExtendedPublicKeys, err = keys.CreateMnemonics(conf.NetParams(), conf.NumPrivateKeys, conf.Password, isMultisig,
+			conf.UsePassphrase)
 	} else {
-		encryptedMnemonics, signerExtendedPublicKeys, err = keys.ImportMnemonics(conf.NetParams(), conf.NumPrivateKeys, conf.Password, isMultisig)
+		encryptedMnemonics, signerExtendedPublicKeys, err = keys.ImportMnemonics(conf.NetParams(), conf.NumPrivateKeys, conf.Password, isMultisig,
+			conf.UsePassphrase)
 	}
 	if err != nil {
 		return err
@@ -65,7 +74,7 @@ func create(conf *createConfig) error {
 	}
 
 	file := keys.File{
-		Version:            keys.LastVersion,
+		Version:            keys.DefaultArgon2ParametersVersion,
 		EncryptedMnemonics: encryptedMnemonics,
 		ExtendedPublicKeys: extendedPublicKeys,
 		MinimumSignatures:  conf.MinimumSignatures,
@@ -73,7 +82,85 @@ func create(conf *createConfig) error {
 		ECDSA:              conf.ECDSA,
 	}
 
-	err = file.SetPath(conf.NetParams(), conf.KeysFile, conf.Yes)
+	return saveNewKeysFile(conf, &file)
+}
+
+// createWatchOnly creates a keys file with no private keys, which lets the wallet daemon
+// follow the balance of the wallet and create unsigned transactions, but not sign them
+func createWatchOnly(conf *createConfig) error {
+	seenExtendedPublicKeys := make(map[string]struct{}, len(conf.XPubs))
+	for _, extendedPublicKey := range conf.XPubs {
+		err := libkaspiwallet.ValidateExtendedPublicKey(conf.NetParams(), extendedPublicKey)
+		if err != nil {
+			return err
+		}
+		if _, ok := seenExtendedPublicKeys[extendedPublicKey]; ok {
+			return errors.Errorf("the extended public key %s is given more than once", extendedPublicKey)
+		}
+		seenExtendedPublicKeys[extendedPublicKey] = struct{}{}
+	}
+
+	file := keys.File{
+		Version:            keys.DefaultArgon2ParametersVersion,
+		ExtendedPublicKeys: conf.XPubs,
+		MinimumSignatures:  conf.MinimumSignatures,
+		ECDSA:              conf.ECDSA,
+	}
+	err := saveNewKeysFile(conf, &file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("The wallet is watch-only: transactions created from it have to be signed with the private keys elsewhere")
+	return nil
+}
+
+// createFromDescriptor creates a wallet with the keys of the given wallet descriptor. A signing wallet is
+// created from imported mnemonics, which have to belong to the keys of the descriptor.
+func createFromDescriptor(conf *createConfig) error {
+	descriptor, err := libkaspiwallet.ParseWalletDescriptor(conf.NetParams(), conf.Descriptor)
+	if err != nil {
+		return err
+	}
+
+	file := keys.File{
+		Version:            keys.DefaultArgon2ParametersVersion,
+		ExtendedPublicKeys: descriptor.ExtendedPublicKeys,
+		MinimumSignatures:  descriptor.MinimumSignatures,
+		ECDSA:              descriptor.ECDSA,
+	}
+	if !conf.WatchOnly {
+		if int(conf.NumPrivateKeys) > len(descriptor.ExtendedPublicKeys) {
+			return errors.Errorf("The descriptor has %d keys, while %d private keys were requested",
+				len(descriptor.ExtendedPublicKeys), conf.NumPrivateKeys)
+		}
+		encryptedMnemonics, signerExtendedPublicKeys, err := keys.ImportMnemonics(conf.NetParams(), conf.NumPrivateKeys,
+			conf.Password, descriptor.IsMultisig(), conf.UsePassphrase)
+		if err != nil {
+			return err
+		}
+		cosignerIndex, err := libkaspiwallet.MinimumCosignerIndex(signerExtendedPublicKeys, descriptor.ExtendedPublicKeys)
+		if err != nil {
+			return errors.Wrap(err, "The imported mnemonics don't belong to the wallet of the descriptor")
+		}
+		file.EncryptedMnemonics = encryptedMnemonics
+		file.CosignerIndex = cosignerIndex
+	}
+
+	err = saveNewKeysFile(conf, &file)
+	if err != nil {
+		return err
+	}
+
+	if conf.WatchOnly {
+		fmt.Println("The wallet is watch-only: transactions created from it have to be signed with the private keys elsewhere")
+	}
+	fmt.Println("Run \"kaspiwallet verify-descriptor\" and compare its addresses with the other cosigners")
+	return nil
+}
+
+func saveNewKeysFile(conf *createConfig, file *keys.File) error {
+	err := file.SetPath(conf.NetParams(), conf.KeysFile, conf.Yes)
 	if err != nil {
 		return err
 	}
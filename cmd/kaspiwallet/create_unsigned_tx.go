@@ -4,14 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
+	"github.com/kaspikr/kaspid/util"
 )
 
 func createUnsignedTransaction(conf *createUnsignedTransactionConfig) error {
-	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress)
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
 	if err != nil {
 		return err
 	}
@@ -20,24 +22,57 @@ func createUnsignedTransaction(conf *createUnsignedTransactionConfig) error {
 	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
 	defer cancel()
 
-	sendAmountSompi, err := utils.KasToSompi(conf.SendAmount)
+	var sendAmountSompi uint64
+	if conf.SendAmount != "" {
+		sendAmountSompi, err = util.KasToSompi(conf.SendAmount)
 
+		if err != nil {
+			return err
+		}
+	}
+	outputs, err := parsePaymentOutputs(conf.Outputs, conf.OutputsFile)
+	if err != nil {
+		return err
+	}
+	selectedUTXOs, err := parseOutpoints(conf.UTXOs)
 	if err != nil {
 		return err
 	}
+	var maxFeeSompi uint64
+	if conf.MaxFee != "" {
+		maxFeeSompi, err = util.KasToSompi(conf.MaxFee)
+		if err != nil {
+			return err
+		}
+	}
 
 	response, err := daemonClient.CreateUnsignedTransactions(ctx, &pb.CreateUnsignedTransactionsRequest{
+		Account:                  conf.Account,
 		From:                     conf.FromAddresses,
 		Address:                  conf.ToAddress,
 		Amount:                   sendAmountSompi,
+		Outputs:                  outputs,
 		IsSendAll:                conf.IsSendAll,
 		UseExistingChangeAddress: conf.UseExistingChangeAddress,
+		FeeRate:                  conf.FeeRate,
+		MaxFee:                   maxFeeSompi,
+		SubtractFeeFromAmount:    conf.SubtractFeeFromAmount,
+		Utxos:                    selectedUTXOs,
 	})
 	if err != nil {
 		return err
 	}
 
-	fmt.Fprintln(os.Stderr, "Created unsigned transaction")
+	fmt.Fprintf(os.Stderr, "Created unsigned transaction(s), paying a fee of %s KAS for a mass of %d grams\n",
+		strings.TrimSpace(utils.FormatKas(response.Fee)), response.Mass)
+	if conf.PSKT {
+		encoded, err := encodePSKTs(response.UnsignedTransactions)
+		if err != nil {
+			return err
+		}
+		fmt.Println(encoded)
+		return nil
+	}
 	fmt.Println(encodeTransactionsToHex(response.UnsignedTransactions))
 
 	return nil
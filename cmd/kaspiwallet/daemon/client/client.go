@@ -2,25 +2,60 @@ package client
 
 import (
 	"context"
-	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/server"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
 	"time"
 
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/server"
+
 	"github.com/pkg/errors"
 
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
-// Connect connects to the kaspiwalletd server, and returns the client instance
-func Connect(address string) (pb.KaspiwalletdClient, func(), error) {
+// ConnectOptions configures how the client secures its connection to the daemon.
+// It should match the SecurityConfig the daemon is started with.
+type ConnectOptions struct {
+	// TLSCertFile is the certificate of the daemon, or of the CA that signed it. TLS is off if it's empty.
+	TLSCertFile string
+	// ClientCertFile and ClientKeyFile are the certificate the client authenticates with, when
+	// the daemon requires client certificates
+	ClientCertFile string
+	ClientKeyFile  string
+	// AuthTokenFile is the file of the bearer token the client authenticates with, when the daemon requires one
+	AuthTokenFile string
+}
+
+// Connect connects to the kaspiwalletd server, and returns the client instance.
+// options may be nil, for a plaintext connection without authentication.
+func Connect(address string, options *ConnectOptions) (pb.KaspiwalletdClient, func(), error) {
+	if options == nil {
+		options = &ConnectOptions{}
+	}
+	dialOptions, err := options.dialOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Connection is local, so 1 second timeout is sufficient
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(server.MaxDaemonSendMsgSize)))
+	dialOptions = append(dialOptions, grpc.WithBlock(),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(server.MaxDaemonSendMsgSize)))
+	conn, err := grpc.DialContext(ctx, address, dialOptions...)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
-			return nil, nil, errors.New("kaspiwallet daemon is not running, start it with `kaspiwallet start-daemon`")
+			// A failed TLS handshake looks the same as a daemon that isn't running
+			if options.TLSCertFile != "" {
+				return nil, nil, errors.New("kaspiwallet daemon is not running, or the TLS handshake with it " +
+					"failed. Check the TLS certificate of the daemon and the client certificate")
+			}
+			return nil, nil, errors.New("kaspiwallet daemon is not running, start it with `kaspiwallet start-daemon`. " +
+				"If it serves TLS, connect to it with --daemon-tls-cert")
 		}
 		return nil, nil, err
 	}
@@ -29,3 +64,67 @@ func Connect(address string) (pb.KaspiwalletdClient, func(), error) {
 		conn.Close()
 	}, nil
 }
+
+func (co *ConnectOptions) dialOptions() ([]grpc.DialOption, error) {
+	var dialOptions []grpc.DialOption
+	if co.TLSCertFile != "" {
+		tlsConfig, err := co.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		if co.ClientCertFile != "" {
+			return nil, errors.New("a client certificate requires the TLS certificate of the daemon")
+		}
+		dialOptions = append(dialOptions, grpc.WithInsecure())
+	}
+
+	if co.AuthTokenFile != "" {
+		token, err := server.ReadAuthTokenFile(co.AuthTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(&tokenCredentials{token: token}))
+	}
+	return dialOptions, nil
+}
+
+func (co *ConnectOptions) tlsConfig() (*tls.Config, error) {
+	certificate, err := os.ReadFile(co.TLSCertFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the TLS certificate of the daemon")
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(certificate) {
+		return nil, errors.Errorf("no certificates were found in %s", co.TLSCertFile)
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    rootCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if co.ClientCertFile != "" || co.ClientKeyFile != "" {
+		clientCertificate, err := tls.LoadX509KeyPair(co.ClientCertFile, co.ClientKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error loading the client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCertificate}
+	}
+	return tlsConfig, nil
+}
+
+// tokenCredentials authenticates every request with a bearer token
+type tokenCredentials struct {
+	token string
+}
+
+func (tc *tokenCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + tc.token}, nil
+}
+
+// RequireTransportSecurity allows sending the token over a plaintext connection, since the daemon
+// may listen on localhost without TLS
+func (tc *tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
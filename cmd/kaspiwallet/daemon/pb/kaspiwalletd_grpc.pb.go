@@ -34,6 +34,36 @@ type KaspiwalletdClient interface {
 	// Since SignRequest contains a password - this command should only be used on a trusted or secure connection
 	Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error)
 	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error)
+	GetTransactions(ctx context.Context, in *GetTransactionsRequest, opts ...grpc.CallOption) (*GetTransactionsResponse, error)
+	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error)
+	GetUtxos(ctx context.Context, in *GetUtxosRequest, opts ...grpc.CallOption) (*GetUtxosResponse, error)
+	LockUtxos(ctx context.Context, in *LockUtxosRequest, opts ...grpc.CallOption) (*LockUtxosResponse, error)
+	UnlockUtxos(ctx context.Context, in *UnlockUtxosRequest, opts ...grpc.CallOption) (*UnlockUtxosResponse, error)
+	CreateCompoundTransactions(ctx context.Context, in *CreateCompoundTransactionsRequest, opts ...grpc.CallOption) (*CreateCompoundTransactionsResponse, error)
+	// Since CreateAccountRequest contains a password - this command should only be used on a trusted or secure connection
+	CreateAccount(ctx context.Context, in *CreateAccountRequest, opts ...grpc.CallOption) (*CreateAccountResponse, error)
+	GetAccounts(ctx context.Context, in *GetAccountsRequest, opts ...grpc.CallOption) (*GetAccountsResponse, error)
+	SetLabel(ctx context.Context, in *SetLabelRequest, opts ...grpc.CallOption) (*SetLabelResponse, error)
+	GetLabels(ctx context.Context, in *GetLabelsRequest, opts ...grpc.CallOption) (*GetLabelsResponse, error)
+	// SubscribeWalletChanges streams the balance of the wallet, and the changes in its UTXOs, as they happen
+	SubscribeWalletChanges(ctx context.Context, in *SubscribeWalletChangesRequest, opts ...grpc.CallOption) (Kaspiwalletd_SubscribeWalletChangesClient, error)
+	// Since SignMessageRequest contains a password - this command should only be used on a trusted or secure connection
+	SignMessage(ctx context.Context, in *SignMessageRequest, opts ...grpc.CallOption) (*SignMessageResponse, error)
+	VerifyMessage(ctx context.Context, in *VerifyMessageRequest, opts ...grpc.CallOption) (*VerifyMessageResponse, error)
+	// Since RedeemHTLCRequest contains a password - this command should only be used on a trusted or secure connection
+	RedeemHTLC(ctx context.Context, in *RedeemHTLCRequest, opts ...grpc.CallOption) (*RedeemHTLCResponse, error)
+	// Since RefundHTLCRequest contains a password - this command should only be used on a trusted or secure connection
+	RefundHTLC(ctx context.Context, in *RefundHTLCRequest, opts ...grpc.CallOption) (*RefundHTLCResponse, error)
+	// GetHTLCSecret looks for the secret of a hash time-locked contract in the transactions that redeem it
+	GetHTLCSecret(ctx context.Context, in *GetHTLCSecretRequest, opts ...grpc.CallOption) (*GetHTLCSecretResponse, error)
+	// NewTimeLockedAddress returns a new address of the wallet whose funds can't be spent until a DAA score
+	NewTimeLockedAddress(ctx context.Context, in *NewTimeLockedAddressRequest, opts ...grpc.CallOption) (*NewTimeLockedAddressResponse, error)
+	// UnlockWallet keeps the decrypted keys of the wallet in the daemon for a while, so that requests that need
+	// them can leave their password empty. Since UnlockWalletRequest contains a password - this command should
+	// only be used on a trusted or secure connection
+	UnlockWallet(ctx context.Context, in *UnlockWalletRequest, opts ...grpc.CallOption) (*UnlockWalletResponse, error)
+	// LockWallet forgets the decrypted keys of the wallet before the unlock timeout
+	LockWallet(ctx context.Context, in *LockWalletRequest, opts ...grpc.CallOption) (*LockWalletResponse, error)
 }
 
 type kaspiwalletdClient struct {
@@ -134,6 +164,200 @@ func (c *kaspiwalletdClient) GetVersion(ctx context.Context, in *GetVersionReque
 	return out, nil
 }
 
+func (c *kaspiwalletdClient) GetTransactions(ctx context.Context, in *GetTransactionsRequest, opts ...grpc.CallOption) (*GetTransactionsResponse, error) {
+	out := new(GetTransactionsResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/GetTransactions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error) {
+	out := new(GetTransactionResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/GetTransaction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) GetUtxos(ctx context.Context, in *GetUtxosRequest, opts ...grpc.CallOption) (*GetUtxosResponse, error) {
+	out := new(GetUtxosResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/GetUtxos", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) LockUtxos(ctx context.Context, in *LockUtxosRequest, opts ...grpc.CallOption) (*LockUtxosResponse, error) {
+	out := new(LockUtxosResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/LockUtxos", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) UnlockUtxos(ctx context.Context, in *UnlockUtxosRequest, opts ...grpc.CallOption) (*UnlockUtxosResponse, error) {
+	out := new(UnlockUtxosResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/UnlockUtxos", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) CreateCompoundTransactions(ctx context.Context, in *CreateCompoundTransactionsRequest, opts ...grpc.CallOption) (*CreateCompoundTransactionsResponse, error) {
+	out := new(CreateCompoundTransactionsResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/CreateCompoundTransactions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) CreateAccount(ctx context.Context, in *CreateAccountRequest, opts ...grpc.CallOption) (*CreateAccountResponse, error) {
+	out := new(CreateAccountResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/CreateAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) GetAccounts(ctx context.Context, in *GetAccountsRequest, opts ...grpc.CallOption) (*GetAccountsResponse, error) {
+	out := new(GetAccountsResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/GetAccounts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) SetLabel(ctx context.Context, in *SetLabelRequest, opts ...grpc.CallOption) (*SetLabelResponse, error) {
+	out := new(SetLabelResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/SetLabel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) GetLabels(ctx context.Context, in *GetLabelsRequest, opts ...grpc.CallOption) (*GetLabelsResponse, error) {
+	out := new(GetLabelsResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/GetLabels", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) SubscribeWalletChanges(ctx context.Context, in *SubscribeWalletChangesRequest, opts ...grpc.CallOption) (Kaspiwalletd_SubscribeWalletChangesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Kaspiwalletd_ServiceDesc.Streams[0], "/kaspiwalletd.kaspiwalletd/SubscribeWalletChanges", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kaspiwalletdSubscribeWalletChangesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Kaspiwalletd_SubscribeWalletChangesClient interface {
+	Recv() (*WalletChangedNotification, error)
+	grpc.ClientStream
+}
+
+type kaspiwalletdSubscribeWalletChangesClient struct {
+	grpc.ClientStream
+}
+
+func (x *kaspiwalletdSubscribeWalletChangesClient) Recv() (*WalletChangedNotification, error) {
+	m := new(WalletChangedNotification)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *kaspiwalletdClient) SignMessage(ctx context.Context, in *SignMessageRequest, opts ...grpc.CallOption) (*SignMessageResponse, error) {
+	out := new(SignMessageResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/SignMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) VerifyMessage(ctx context.Context, in *VerifyMessageRequest, opts ...grpc.CallOption) (*VerifyMessageResponse, error) {
+	out := new(VerifyMessageResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/VerifyMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) RedeemHTLC(ctx context.Context, in *RedeemHTLCRequest, opts ...grpc.CallOption) (*RedeemHTLCResponse, error) {
+	out := new(RedeemHTLCResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/RedeemHTLC", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) RefundHTLC(ctx context.Context, in *RefundHTLCRequest, opts ...grpc.CallOption) (*RefundHTLCResponse, error) {
+	out := new(RefundHTLCResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/RefundHTLC", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) GetHTLCSecret(ctx context.Context, in *GetHTLCSecretRequest, opts ...grpc.CallOption) (*GetHTLCSecretResponse, error) {
+	out := new(GetHTLCSecretResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/GetHTLCSecret", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) NewTimeLockedAddress(ctx context.Context, in *NewTimeLockedAddressRequest, opts ...grpc.CallOption) (*NewTimeLockedAddressResponse, error) {
+	out := new(NewTimeLockedAddressResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/NewTimeLockedAddress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) UnlockWallet(ctx context.Context, in *UnlockWalletRequest, opts ...grpc.CallOption) (*UnlockWalletResponse, error) {
+	out := new(UnlockWalletResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/UnlockWallet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kaspiwalletdClient) LockWallet(ctx context.Context, in *LockWalletRequest, opts ...grpc.CallOption) (*LockWalletResponse, error) {
+	out := new(LockWalletResponse)
+	err := c.cc.Invoke(ctx, "/kaspiwalletd.kaspiwalletd/LockWallet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KaspiwalletdServer is the server API for Kaspiwalletd service.
 // All implementations must embed UnimplementedKaspiwalletdServer
 // for forward compatibility
@@ -150,6 +374,36 @@ type KaspiwalletdServer interface {
 	// Since SignRequest contains a password - this command should only be used on a trusted or secure connection
 	Sign(context.Context, *SignRequest) (*SignResponse, error)
 	GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error)
+	GetTransactions(context.Context, *GetTransactionsRequest) (*GetTransactionsResponse, error)
+	GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error)
+	GetUtxos(context.Context, *GetUtxosRequest) (*GetUtxosResponse, error)
+	LockUtxos(context.Context, *LockUtxosRequest) (*LockUtxosResponse, error)
+	UnlockUtxos(context.Context, *UnlockUtxosRequest) (*UnlockUtxosResponse, error)
+	CreateCompoundTransactions(context.Context, *CreateCompoundTransactionsRequest) (*CreateCompoundTransactionsResponse, error)
+	// Since CreateAccountRequest contains a password - this command should only be used on a trusted or secure connection
+	CreateAccount(context.Context, *CreateAccountRequest) (*CreateAccountResponse, error)
+	GetAccounts(context.Context, *GetAccountsRequest) (*GetAccountsResponse, error)
+	SetLabel(context.Context, *SetLabelRequest) (*SetLabelResponse, error)
+	GetLabels(context.Context, *GetLabelsRequest) (*GetLabelsResponse, error)
+	// SubscribeWalletChanges streams the balance of the wallet, and the changes in its UTXOs, as they happen
+	SubscribeWalletChanges(*SubscribeWalletChangesRequest, Kaspiwalletd_SubscribeWalletChangesServer) error
+	// Since SignMessageRequest contains a password - this command should only be used on a trusted or secure connection
+	SignMessage(context.Context, *SignMessageRequest) (*SignMessageResponse, error)
+	VerifyMessage(context.Context, *VerifyMessageRequest) (*VerifyMessageResponse, error)
+	// Since RedeemHTLCRequest contains a password - this command should only be used on a trusted or secure connection
+	RedeemHTLC(context.Context, *RedeemHTLCRequest) (*RedeemHTLCResponse, error)
+	// Since RefundHTLCRequest contains a password - this command should only be used on a trusted or secure connection
+	RefundHTLC(context.Context, *RefundHTLCRequest) (*RefundHTLCResponse, error)
+	// GetHTLCSecret looks for the secret of a hash time-locked contract in the transactions that redeem it
+	GetHTLCSecret(context.Context, *GetHTLCSecretRequest) (*GetHTLCSecretResponse, error)
+	// NewTimeLockedAddress returns a new address of the wallet whose funds can't be spent until a DAA score
+	NewTimeLockedAddress(context.Context, *NewTimeLockedAddressRequest) (*NewTimeLockedAddressResponse, error)
+	// UnlockWallet keeps the decrypted keys of the wallet in the daemon for a while, so that requests that need
+	// them can leave their password empty. Since UnlockWalletRequest contains a password - this command should
+	// only be used on a trusted or secure connection
+	UnlockWallet(context.Context, *UnlockWalletRequest) (*UnlockWalletResponse, error)
+	// LockWallet forgets the decrypted keys of the wallet before the unlock timeout
+	LockWallet(context.Context, *LockWalletRequest) (*LockWalletResponse, error)
 	mustEmbedUnimplementedKaspiwalletdServer()
 }
 
@@ -187,6 +441,63 @@ func (UnimplementedKaspiwalletdServer) Sign(context.Context, *SignRequest) (*Sig
 func (UnimplementedKaspiwalletdServer) GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
 }
+func (UnimplementedKaspiwalletdServer) GetTransactions(context.Context, *GetTransactionsRequest) (*GetTransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransactions not implemented")
+}
+func (UnimplementedKaspiwalletdServer) GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
+}
+func (UnimplementedKaspiwalletdServer) GetUtxos(context.Context, *GetUtxosRequest) (*GetUtxosResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUtxos not implemented")
+}
+func (UnimplementedKaspiwalletdServer) LockUtxos(context.Context, *LockUtxosRequest) (*LockUtxosResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LockUtxos not implemented")
+}
+func (UnimplementedKaspiwalletdServer) UnlockUtxos(context.Context, *UnlockUtxosRequest) (*UnlockUtxosResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockUtxos not implemented")
+}
+func (UnimplementedKaspiwalletdServer) CreateCompoundTransactions(context.Context, *CreateCompoundTransactionsRequest) (*CreateCompoundTransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCompoundTransactions not implemented")
+}
+func (UnimplementedKaspiwalletdServer) CreateAccount(context.Context, *CreateAccountRequest) (*CreateAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAccount not implemented")
+}
+func (UnimplementedKaspiwalletdServer) GetAccounts(context.Context, *GetAccountsRequest) (*GetAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccounts not implemented")
+}
+func (UnimplementedKaspiwalletdServer) SetLabel(context.Context, *SetLabelRequest) (*SetLabelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLabel not implemented")
+}
+func (UnimplementedKaspiwalletdServer) GetLabels(context.Context, *GetLabelsRequest) (*GetLabelsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLabels not implemented")
+}
+func (UnimplementedKaspiwalletdServer) SubscribeWalletChanges(*SubscribeWalletChangesRequest, Kaspiwalletd_SubscribeWalletChangesServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeWalletChanges not implemented")
+}
+func (UnimplementedKaspiwalletdServer) SignMessage(context.Context, *SignMessageRequest) (*SignMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignMessage not implemented")
+}
+func (UnimplementedKaspiwalletdServer) VerifyMessage(context.Context, *VerifyMessageRequest) (*VerifyMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyMessage not implemented")
+}
+func (UnimplementedKaspiwalletdServer) RedeemHTLC(context.Context, *RedeemHTLCRequest) (*RedeemHTLCResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RedeemHTLC not implemented")
+}
+func (UnimplementedKaspiwalletdServer) RefundHTLC(context.Context, *RefundHTLCRequest) (*RefundHTLCResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefundHTLC not implemented")
+}
+func (UnimplementedKaspiwalletdServer) GetHTLCSecret(context.Context, *GetHTLCSecretRequest) (*GetHTLCSecretResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHTLCSecret not implemented")
+}
+func (UnimplementedKaspiwalletdServer) NewTimeLockedAddress(context.Context, *NewTimeLockedAddressRequest) (*NewTimeLockedAddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NewTimeLockedAddress not implemented")
+}
+func (UnimplementedKaspiwalletdServer) UnlockWallet(context.Context, *UnlockWalletRequest) (*UnlockWalletResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockWallet not implemented")
+}
+func (UnimplementedKaspiwalletdServer) LockWallet(context.Context, *LockWalletRequest) (*LockWalletResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LockWallet not implemented")
+}
 func (UnimplementedKaspiwalletdServer) mustEmbedUnimplementedKaspiwalletdServer() {}
 
 // UnsafeKaspiwalletdServer may be embedded to opt out of forward compatibility for this service.
@@ -380,6 +691,351 @@ func _Kaspiwalletd_GetVersion_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Kaspiwalletd_GetTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).GetTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/GetTransactions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).GetTransactions(ctx, req.(*GetTransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/GetTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).GetTransaction(ctx, req.(*GetTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_GetUtxos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUtxosRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).GetUtxos(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/GetUtxos",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).GetUtxos(ctx, req.(*GetUtxosRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_LockUtxos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockUtxosRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).LockUtxos(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/LockUtxos",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).LockUtxos(ctx, req.(*LockUtxosRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_UnlockUtxos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockUtxosRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).UnlockUtxos(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/UnlockUtxos",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).UnlockUtxos(ctx, req.(*UnlockUtxosRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_CreateCompoundTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCompoundTransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).CreateCompoundTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/CreateCompoundTransactions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).CreateCompoundTransactions(ctx, req.(*CreateCompoundTransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_CreateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).CreateAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/CreateAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).CreateAccount(ctx, req.(*CreateAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_GetAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).GetAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/GetAccounts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).GetAccounts(ctx, req.(*GetAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_SetLabel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLabelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).SetLabel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/SetLabel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).SetLabel(ctx, req.(*SetLabelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_GetLabels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLabelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).GetLabels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/GetLabels",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).GetLabels(ctx, req.(*GetLabelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_SubscribeWalletChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeWalletChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KaspiwalletdServer).SubscribeWalletChanges(m, &kaspiwalletdSubscribeWalletChangesServer{stream})
+}
+
+type Kaspiwalletd_SubscribeWalletChangesServer interface {
+	Send(*WalletChangedNotification) error
+	grpc.ServerStream
+}
+
+type kaspiwalletdSubscribeWalletChangesServer struct {
+	grpc.ServerStream
+}
+
+func (x *kaspiwalletdSubscribeWalletChangesServer) Send(m *WalletChangedNotification) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Kaspiwalletd_SignMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).SignMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/SignMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).SignMessage(ctx, req.(*SignMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_VerifyMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).VerifyMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/VerifyMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).VerifyMessage(ctx, req.(*VerifyMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_RedeemHTLC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeemHTLCRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).RedeemHTLC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/RedeemHTLC",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).RedeemHTLC(ctx, req.(*RedeemHTLCRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_RefundHTLC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefundHTLCRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).RefundHTLC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/RefundHTLC",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).RefundHTLC(ctx, req.(*RefundHTLCRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_GetHTLCSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHTLCSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).GetHTLCSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/GetHTLCSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).GetHTLCSecret(ctx, req.(*GetHTLCSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_NewTimeLockedAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NewTimeLockedAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).NewTimeLockedAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/NewTimeLockedAddress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).NewTimeLockedAddress(ctx, req.(*NewTimeLockedAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_UnlockWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).UnlockWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/UnlockWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).UnlockWallet(ctx, req.(*UnlockWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kaspiwalletd_LockWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KaspiwalletdServer).LockWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kaspiwalletd.kaspiwalletd/LockWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KaspiwalletdServer).LockWallet(ctx, req.(*LockWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Kaspiwalletd_ServiceDesc is the grpc.ServiceDesc for Kaspiwalletd service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -427,7 +1083,85 @@ var Kaspiwalletd_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetVersion",
 			Handler:    _Kaspiwalletd_GetVersion_Handler,
 		},
+		{
+			MethodName: "GetTransactions",
+			Handler:    _Kaspiwalletd_GetTransactions_Handler,
+		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _Kaspiwalletd_GetTransaction_Handler,
+		},
+		{
+			MethodName: "GetUtxos",
+			Handler:    _Kaspiwalletd_GetUtxos_Handler,
+		},
+		{
+			MethodName: "LockUtxos",
+			Handler:    _Kaspiwalletd_LockUtxos_Handler,
+		},
+		{
+			MethodName: "UnlockUtxos",
+			Handler:    _Kaspiwalletd_UnlockUtxos_Handler,
+		},
+		{
+			MethodName: "CreateCompoundTransactions",
+			Handler:    _Kaspiwalletd_CreateCompoundTransactions_Handler,
+		},
+		{
+			MethodName: "CreateAccount",
+			Handler:    _Kaspiwalletd_CreateAccount_Handler,
+		},
+		{
+			MethodName: "GetAccounts",
+			Handler:    _Kaspiwalletd_GetAccounts_Handler,
+		},
+		{
+			MethodName: "SetLabel",
+			Handler:    _Kaspiwalletd_SetLabel_Handler,
+		},
+		{
+			MethodName: "GetLabels",
+			Handler:    _Kaspiwalletd_GetLabels_Handler,
+		},
+		{
+			MethodName: "SignMessage",
+			Handler:    _Kaspiwalletd_SignMessage_Handler,
+		},
+		{
+			MethodName: "VerifyMessage",
+			Handler:    _Kaspiwalletd_VerifyMessage_Handler,
+		},
+		{
+			MethodName: "RedeemHTLC",
+			Handler:    _Kaspiwalletd_RedeemHTLC_Handler,
+		},
+		{
+			MethodName: "RefundHTLC",
+			Handler:    _Kaspiwalletd_RefundHTLC_Handler,
+		},
+		{
+			MethodName: "GetHTLCSecret",
+			Handler:    _Kaspiwalletd_GetHTLCSecret_Handler,
+		},
+		{
+			MethodName: "NewTimeLockedAddress",
+			Handler:    _Kaspiwalletd_NewTimeLockedAddress_Handler,
+		},
+		{
+			MethodName: "UnlockWallet",
+			Handler:    _Kaspiwalletd_UnlockWallet_Handler,
+		},
+		{
+			MethodName: "LockWallet",
+			Handler:    _Kaspiwalletd_LockWallet_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeWalletChanges",
+			Handler:       _Kaspiwalletd_SubscribeWalletChanges_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "kaspiwalletd.proto",
 }
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/pkg/errors"
+)
+
+// walletAccount is an account of the wallet, along with the keys its addresses are derived from.
+// The default account is the one whose keys and indexes are kept at the top level of the keys file.
+type walletAccount struct {
+	name               string
+	index              uint32
+	extendedPublicKeys []string
+	cosignerIndex      uint32
+	keysFileAccount    *keys.Account // nil for the default account
+}
+
+type accountKeychain struct {
+	accountIndex uint32
+	keyChain     uint8
+}
+
+func newWalletAccount(account *keys.Account) *walletAccount {
+	return &walletAccount{
+		name:               account.Name,
+		index:              account.Index,
+		extendedPublicKeys: account.ExtendedPublicKeys,
+		cosignerIndex:      account.CosignerIndex,
+		keysFileAccount:    account,
+	}
+}
+
+func (s *server) defaultAccount() *walletAccount {
+	return &walletAccount{
+		name:               keys.DefaultAccountName,
+		index:              0,
+		extendedPublicKeys: s.keysFile.ExtendedPublicKeys,
+		cosignerIndex:      s.keysFile.CosignerIndex,
+	}
+}
+
+// walletAccounts returns all the accounts of the wallet, the default account first
+func (s *server) walletAccounts() []*walletAccount {
+	accounts := make([]*walletAccount, 0, len(s.keysFile.Accounts)+1)
+	accounts = append(accounts, s.defaultAccount())
+	for _, account := range s.keysFile.Accounts {
+		accounts = append(accounts, newWalletAccount(account))
+	}
+	return accounts
+}
+
+func (s *server) walletAccount(index uint32) (*walletAccount, error) {
+	if index == 0 {
+		return s.defaultAccount(), nil
+	}
+	for _, account := range s.keysFile.Accounts {
+		if account.Index == index {
+			return newWalletAccount(account), nil
+		}
+	}
+	return nil, errors.Errorf("the wallet has no account with index %d", index)
+}
+
+// accountByName returns the account with the given name, or the default account if name is empty
+func (s *server) accountByName(name string) (*walletAccount, error) {
+	if name == "" {
+		return s.defaultAccount(), nil
+	}
+	for _, account := range s.walletAccounts() {
+		if account.name == name {
+			return account, nil
+		}
+	}
+	return nil, errors.Errorf("the wallet has no account named %s", name)
+}
+
+func (s *server) lastUsedIndex(account *walletAccount, keyChain uint8) uint32 {
+	if account.keysFileAccount == nil {
+		if keyChain == libkaspiwallet.ExternalKeychain {
+			return s.keysFile.LastUsedExternalIndex()
+		}
+		return s.keysFile.LastUsedInternalIndex()
+	}
+
+	if keyChain == libkaspiwallet.ExternalKeychain {
+		return account.keysFileAccount.LastUsedExternalIndex
+	}
+	return account.keysFileAccount.LastUsedInternalIndex
+}
+
+// setLastUsedIndex sets the last used index of the given key chain of the account, and saves
+// the keys file if it changed
+func (s *server) setLastUsedIndex(account *walletAccount, keyChain uint8, index uint32) error {
+	if account.keysFileAccount == nil {
+		if keyChain == libkaspiwallet.ExternalKeychain {
+			return s.keysFile.SetLastUsedExternalIndex(index)
+		}
+		return s.keysFile.SetLastUsedInternalIndex(index)
+	}
+
+	lastUsedIndex := &account.keysFileAccount.LastUsedInternalIndex
+	if keyChain == libkaspiwallet.ExternalKeychain {
+		lastUsedIndex = &account.keysFileAccount.LastUsedExternalIndex
+	}
+	if *lastUsedIndex == index {
+		return nil
+	}
+	*lastUsedIndex = index
+	return s.keysFile.Save()
+}
+
+// createUnsignedTransaction creates an unsigned transaction spending the given UTXOs, which all
+// have to belong to account
+func (s *server) createUnsignedTransaction(account *walletAccount, payments []*libkaspiwallet.Payment,
+	utxos []*libkaspiwallet.UTXO) ([]byte, error) {
+
+	return libkaspiwallet.CreateUnsignedTransaction(account.extendedPublicKeys, s.keysFile.MinimumSignatures,
+		payments, utxos)
+}
+
+func (s *server) CreateAccount(_ context.Context, request *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if request.Name == "" {
+		return nil, errors.Errorf("the account name can't be empty")
+	}
+	accounts := s.walletAccounts()
+	index := uint32(0)
+	for _, account := range accounts {
+		if account.name == request.Name {
+			return nil, errors.Errorf("the wallet already has an account named %s", request.Name)
+		}
+		if account.index > index {
+			index = account.index
+		}
+	}
+	index++
+
+	var derivedExtendedPublicKeys []string
+	if !s.keysFile.IsWatchOnly() {
+		mnemonics, passphrases, err := s.decryptMnemonics(request.Password)
+		if err != nil {
+			return nil, err
+		}
+		for i, mnemonic := range mnemonics {
+			extendedPublicKey, err := libkaspiwallet.AccountPublicKeyFromMnemonic(s.params, mnemonic,
+				passphrases[i], s.isMultisig(), index)
+			if err != nil {
+				return nil, err
+			}
+			derivedExtendedPublicKeys = append(derivedExtendedPublicKeys, extendedPublicKey)
+		}
+	}
+
+	extendedPublicKeys := append([]string{}, derivedExtendedPublicKeys...)
+	seenExtendedPublicKeys := make(map[string]struct{})
+	for _, extendedPublicKey := range derivedExtendedPublicKeys {
+		seenExtendedPublicKeys[extendedPublicKey] = struct{}{}
+	}
+	for _, extendedPublicKey := range request.ExtendedPublicKeys {
+		err := libkaspiwallet.ValidateExtendedPublicKey(s.params, extendedPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seenExtendedPublicKeys[extendedPublicKey]; ok {
+			return nil, errors.Errorf("the extended public key %s is given more than once", extendedPublicKey)
+		}
+		seenExtendedPublicKeys[extendedPublicKey] = struct{}{}
+		extendedPublicKeys = append(extendedPublicKeys, extendedPublicKey)
+	}
+	if len(extendedPublicKeys) != len(s.keysFile.ExtendedPublicKeys) {
+		// The derived keys are part of the error, so that cosigners can share them before any of them
+		// creates the account
+		return nil, errors.Errorf("the wallet has %d extended public keys, so its accounts need %d as well, "+
+			"while %d are derived from its private keys and %d were given. The derived keys of account %d are: %s",
+			len(s.keysFile.ExtendedPublicKeys), len(s.keysFile.ExtendedPublicKeys), len(derivedExtendedPublicKeys),
+			len(request.ExtendedPublicKeys), index, strings.Join(derivedExtendedPublicKeys, ", "))
+	}
+
+	// For a watch-only wallet the cosigner index is 0, as it is for the default account
+	cosignerIndex := uint32(0)
+	if len(derivedExtendedPublicKeys) > 0 {
+		var err error
+		cosignerIndex, err = libkaspiwallet.MinimumCosignerIndex(derivedExtendedPublicKeys, extendedPublicKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	account := &keys.Account{
+		Name:               request.Name,
+		Index:              index,
+		ExtendedPublicKeys: extendedPublicKeys,
+		CosignerIndex:      cosignerIndex,
+	}
+	s.keysFile.Accounts = append(s.keysFile.Accounts, account)
+	err := s.keysFile.Save()
+	if err != nil {
+		s.keysFile.Accounts = s.keysFile.Accounts[:len(s.keysFile.Accounts)-1]
+		return nil, err
+	}
+
+	// The account may have been used before, if the wallet was restored, so its addresses are
+	// collected with the next sync
+	s.forceSync()
+
+	return &pb.CreateAccountResponse{
+		Account: &pb.Account{
+			Name:               account.Name,
+			Index:              account.Index,
+			ExtendedPublicKeys: account.ExtendedPublicKeys,
+		},
+		ExtendedPublicKeys: derivedExtendedPublicKeys,
+	}, nil
+}
+
+func (s *server) GetAccounts(_ context.Context, _ *pb.GetAccountsRequest) (*pb.GetAccountsResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	accounts := s.walletAccounts()
+	pbAccounts := make([]*pb.Account, len(accounts))
+	for i, account := range accounts {
+		pbAccounts[i] = &pb.Account{
+			Name:               account.name,
+			Index:              account.index,
+			ExtendedPublicKeys: account.extendedPublicKeys,
+		}
+	}
+	return &pb.GetAccountsResponse{Accounts: pbAccounts}, nil
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+)
+
+func TestAccounts(t *testing.T) {
+	const password = "password"
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	keysFile, err := keys.NewFileFromMnemonic(params, mnemonic, password)
+	if err != nil {
+		t.Fatalf("NewFileFromMnemonic: %s", err)
+	}
+	keysFilePath := filepath.Join(t.TempDir(), "keys.json")
+	err = keysFile.SetPath(params, keysFilePath, false)
+	if err != nil {
+		t.Fatalf("SetPath: %s", err)
+	}
+	err = keysFile.Save()
+	if err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	serverInstance := &server{
+		params:        params,
+		keysFile:      keysFile,
+		addressSet:    make(walletAddressSet),
+		forceSyncChan: make(chan struct{}, 1),
+		// The wallet is new, so there are no used addresses to scan
+		nextSyncStartIndex: 1,
+	}
+	serverInstance.firstSyncDone.Store(true)
+
+	_, err = serverInstance.CreateAccount(context.Background(), &pb.CreateAccountRequest{Name: "savings", Password: "wrong"})
+	if err == nil {
+		t.Fatalf("creating an account with a wrong password is expected to fail")
+	}
+	response, err := serverInstance.CreateAccount(context.Background(),
+		&pb.CreateAccountRequest{Name: "savings", Password: password})
+	if err != nil {
+		t.Fatalf("CreateAccount: %s", err)
+	}
+	if response.Account.Index != 1 {
+		t.Fatalf("unexpected account index %d", response.Account.Index)
+	}
+	expectedExtendedPublicKey, err := libkaspiwallet.AccountPublicKeyFromMnemonic(params, mnemonic, "", false, 1)
+	if err != nil {
+		t.Fatalf("AccountPublicKeyFromMnemonic: %s", err)
+	}
+	if len(response.Account.ExtendedPublicKeys) != 1 ||
+		response.Account.ExtendedPublicKeys[0] != expectedExtendedPublicKey ||
+		expectedExtendedPublicKey == keysFile.ExtendedPublicKeys[0] {
+		t.Fatalf("unexpected account extended public keys %v", response.Account.ExtendedPublicKeys)
+	}
+	for _, name := range []string{"savings", keys.DefaultAccountName, ""} {
+		_, err = serverInstance.CreateAccount(context.Background(), &pb.CreateAccountRequest{Name: name, Password: password})
+		if err == nil {
+			t.Errorf("creating an account named '%s' is expected to fail", name)
+		}
+	}
+
+	defaultAddress, err := serverInstance.walletAddressString(&walletAddress{keyChain: libkaspiwallet.ExternalKeychain})
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	accountAddress, err := serverInstance.walletAddressString(
+		&walletAddress{keyChain: libkaspiwallet.ExternalKeychain, accountIndex: 1})
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	if defaultAddress == accountAddress {
+		t.Fatalf("the first addresses of different accounts are expected to differ")
+	}
+
+	newAddressResponse, err := serverInstance.NewAddress(context.Background(), &pb.NewAddressRequest{Account: "savings"})
+	if err != nil {
+		t.Fatalf("NewAddress: %s", err)
+	}
+	savedKeysFile, err := keys.ReadKeysFile(params, keysFilePath)
+	if err != nil {
+		t.Fatalf("ReadKeysFile: %s", err)
+	}
+	if len(savedKeysFile.Accounts) != 1 || savedKeysFile.Accounts[0].Name != "savings" ||
+		savedKeysFile.Accounts[0].LastUsedExternalIndex != 1 || savedKeysFile.LastUsedExternalIndex() != 0 {
+		t.Fatalf("unexpected saved accounts %+v", savedKeysFile.Accounts)
+	}
+	savedAddress, err := (&server{params: params, keysFile: savedKeysFile}).walletAddressString(
+		&walletAddress{index: 1, keyChain: libkaspiwallet.ExternalKeychain, accountIndex: 1})
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	if savedAddress != newAddressResponse.Address {
+		t.Fatalf("the new address %s is expected to be derived from the saved account, but got %s",
+			newAddressResponse.Address, savedAddress)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "keys.db")
+	db, err := openWalletDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("openWalletDatabase: %s", err)
+	}
+	labels, err := newLabelStore(db)
+	if err != nil {
+		t.Fatalf("newLabelStore: %s", err)
+	}
+
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %s", err)
+	}
+	serverInstance := &server{
+		params:     params,
+		keysFile:   &keys.File{ExtendedPublicKeys: []string{extendedPublicKey}, MinimumSignatures: 1},
+		addressSet: make(walletAddressSet),
+		labels:     labels,
+	}
+	address, err := serverInstance.walletAddressString(&walletAddress{keyChain: libkaspiwallet.ExternalKeychain})
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+
+	_, err = serverInstance.SetLabel(context.Background(), &pb.SetLabelRequest{Label: &pb.Label{Label: "no target"}})
+	if err == nil {
+		t.Fatalf("setting a label without an address or an outpoint is expected to fail")
+	}
+	_, err = serverInstance.SetLabel(context.Background(), &pb.SetLabelRequest{Label: &pb.Label{
+		Address: address,
+		Label:   "rent",
+	}})
+	if err != nil {
+		t.Fatalf("SetLabel: %s", err)
+	}
+
+	restoredLabels, err := newLabelStore(db)
+	if err != nil {
+		t.Fatalf("newLabelStore: %s", err)
+	}
+	if restoredLabels.addressLabel(address) != "rent" {
+		t.Fatalf("the label of address %s is expected to be restored, but got '%s'", address,
+			restoredLabels.addressLabel(address))
+	}
+
+	_, err = serverInstance.SetLabel(context.Background(), &pb.SetLabelRequest{Label: &pb.Label{Address: address}})
+	if err != nil {
+		t.Fatalf("SetLabel: %s", err)
+	}
+	response, err := serverInstance.GetLabels(context.Background(), &pb.GetLabelsRequest{})
+	if err != nil {
+		t.Fatalf("GetLabels: %s", err)
+	}
+	if len(response.Labels) != 0 {
+		t.Fatalf("the label is expected to be removed, but got %v", response.Labels)
+	}
+}
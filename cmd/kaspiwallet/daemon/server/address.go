@@ -10,35 +10,37 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (s *server) changeAddress(useExisting bool, fromAddresses []*walletAddress) (util.Address, *walletAddress, error) {
+func (s *server) changeAddress(account *walletAccount, useExisting bool, fromAddresses []*walletAddress) (
+	util.Address, *walletAddress, error) {
+
 	var walletAddr *walletAddress
 	if len(fromAddresses) != 0 && useExisting {
 		walletAddr = fromAddresses[0]
+		if walletAddr.lockTime != 0 {
+			// The change of a time-locked address goes to the regular address of the same key, rather than being locked again
+			regularAddr := *walletAddr
+			regularAddr.lockTime = 0
+			walletAddr = &regularAddr
+		}
 	} else {
 		internalIndex := uint32(0)
 		if !useExisting {
-			err := s.keysFile.SetLastUsedInternalIndex(s.keysFile.LastUsedInternalIndex() + 1)
+			internalIndex = s.lastUsedIndex(account, libkaspiwallet.InternalKeychain) + 1
+			err := s.setLastUsedIndex(account, libkaspiwallet.InternalKeychain, internalIndex)
 			if err != nil {
 				return nil, nil, err
 			}
-
-			err = s.keysFile.Save()
-			if err != nil {
-				return nil, nil, err
-			}
-
-			internalIndex = s.keysFile.LastUsedInternalIndex()
 		}
 
 		walletAddr = &walletAddress{
 			index:         internalIndex,
-			cosignerIndex: s.keysFile.CosignerIndex,
+			cosignerIndex: account.cosignerIndex,
 			keyChain:      libkaspiwallet.InternalKeychain,
+			accountIndex:  account.index,
 		}
 	}
 
-	path := s.walletAddressPath(walletAddr)
-	address, err := libkaspiwallet.Address(s.params, s.keysFile.ExtendedPublicKeys, s.keysFile.MinimumSignatures, path, s.keysFile.ECDSA)
+	address, err := s.walletAddressToAddress(walletAddr)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -50,25 +52,33 @@ func (s *server) ShowAddresses(_ context.Context, request *pb.ShowAddressesReque
 	defer s.lock.Unlock()
 
 	if !s.isSynced() {
-		return nil, errors.Errorf("wallet daemon is not synced yet, %s", s.formatSyncStateReport())
+		return nil, s.notSyncedError()
+	}
+
+	account, err := s.accountByName(request.Account)
+	if err != nil {
+		return nil, err
 	}
 
-	addresses := make([]string, s.keysFile.LastUsedExternalIndex())
-	for i := uint32(1); i <= s.keysFile.LastUsedExternalIndex(); i++ {
+	lastUsedExternalIndex := s.lastUsedIndex(account, libkaspiwallet.ExternalKeychain)
+	addresses := make([]string, lastUsedExternalIndex)
+	labels := make([]string, lastUsedExternalIndex)
+	for i := uint32(1); i <= lastUsedExternalIndex; i++ {
 		walletAddr := &walletAddress{
 			index:         i,
-			cosignerIndex: s.keysFile.CosignerIndex,
+			cosignerIndex: account.cosignerIndex,
 			keyChain:      libkaspiwallet.ExternalKeychain,
+			accountIndex:  account.index,
 		}
-		path := s.walletAddressPath(walletAddr)
-		address, err := libkaspiwallet.Address(s.params, s.keysFile.ExtendedPublicKeys, s.keysFile.MinimumSignatures, path, s.keysFile.ECDSA)
+		address, err := s.walletAddressString(walletAddr)
 		if err != nil {
 			return nil, err
 		}
-		addresses[i-1] = address.String()
+		addresses[i-1] = address
+		labels[i-1] = s.labels.addressLabel(address)
 	}
 
-	return &pb.ShowAddressesResponse{Address: addresses}, nil
+	return &pb.ShowAddressesResponse{Address: addresses, Labels: labels}, nil
 }
 
 func (s *server) NewAddress(_ context.Context, request *pb.NewAddressRequest) (*pb.NewAddressResponse, error) {
@@ -76,36 +86,126 @@ func (s *server) NewAddress(_ context.Context, request *pb.NewAddressRequest) (*
 	defer s.lock.Unlock()
 
 	if !s.isSynced() {
-		return nil, errors.Errorf("wallet daemon is not synced yet, %s", s.formatSyncStateReport())
+		return nil, s.notSyncedError()
+	}
+
+	account, err := s.accountByName(request.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	externalIndex := s.lastUsedIndex(account, libkaspiwallet.ExternalKeychain) + 1
+	err = s.setLastUsedIndex(account, libkaspiwallet.ExternalKeychain, externalIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	walletAddr := &walletAddress{
+		index:         externalIndex,
+		cosignerIndex: account.cosignerIndex,
+		keyChain:      libkaspiwallet.ExternalKeychain,
+		accountIndex:  account.index,
+	}
+	address, err := s.walletAddressString(walletAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.NewAddressResponse{Address: address}, nil
+}
+
+func (s *server) NewTimeLockedAddress(_ context.Context, request *pb.NewTimeLockedAddressRequest) (
+	*pb.NewTimeLockedAddressResponse, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.isSynced() {
+		return nil, s.notSyncedError()
+	}
+	if s.isMultisig() {
+		return nil, errors.Errorf("time-locked addresses are only supported by single signer wallets")
+	}
+	if (request.LockDaaScore == 0) == (request.LockDuration == 0) {
+		return nil, errors.Errorf("exactly one of the lock DAA score and the lock duration must be set")
 	}
 
-	err := s.keysFile.SetLastUsedExternalIndex(s.keysFile.LastUsedExternalIndex() + 1)
+	account, err := s.accountByName(request.Account)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.keysFile.Save()
+	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
 	if err != nil {
 		return nil, err
 	}
+	lockDAAScore := request.LockDaaScore
+	if request.LockDuration != 0 {
+		lockDAAScore = dagInfo.VirtualDAAScore + request.LockDuration/uint64(s.params.TargetTimePerBlock.Milliseconds())
+	}
+	if lockDAAScore <= dagInfo.VirtualDAAScore {
+		return nil, errors.Errorf("the lock DAA score %d was already reached, as the current DAA score is %d",
+			lockDAAScore, dagInfo.VirtualDAAScore)
+	}
 
+	externalIndex := s.lastUsedIndex(account, libkaspiwallet.ExternalKeychain) + 1
 	walletAddr := &walletAddress{
-		index:         s.keysFile.LastUsedExternalIndex(),
-		cosignerIndex: s.keysFile.CosignerIndex,
+		index:         externalIndex,
+		cosignerIndex: account.cosignerIndex,
 		keyChain:      libkaspiwallet.ExternalKeychain,
+		accountIndex:  account.index,
+		lockTime:      lockDAAScore,
+	}
+	address, err := s.walletAddressString(walletAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.setLastUsedIndex(account, libkaspiwallet.ExternalKeychain, externalIndex)
+	if err != nil {
+		return nil, err
 	}
-	path := s.walletAddressPath(walletAddr)
-	address, err := libkaspiwallet.Address(s.params, s.keysFile.ExtendedPublicKeys, s.keysFile.MinimumSignatures, path, s.keysFile.ECDSA)
+	err = s.timeLockedAddresses.add(address, walletAddr)
 	if err != nil {
 		return nil, err
 	}
+	// The sync loop starts tracking the address once it collects it
+	s.forceSync()
 
-	return &pb.NewAddressResponse{Address: address.String()}, nil
+	return &pb.NewTimeLockedAddressResponse{Address: address, LockDaaScore: lockDAAScore}, nil
+}
+
+// walletAddressByString returns the walletAddress of an address of the wallet. Unlike addressSet, it also
+// finds addresses that were given out by NewAddress or changeAddress but never received any funds.
+func (s *server) walletAddressByString(address string) (*walletAddress, bool, error) {
+	if wAddr, ok := s.addressSet[address]; ok {
+		return wAddr, true, nil
+	}
+
+	for _, account := range s.walletAccounts() {
+		for _, keychain := range keyChains {
+			for index := uint32(0); index <= s.lastUsedIndex(account, keychain); index++ {
+				wAddr := &walletAddress{
+					index:         index,
+					cosignerIndex: account.cosignerIndex,
+					keyChain:      keychain,
+					accountIndex:  account.index,
+				}
+				addressString, err := s.walletAddressString(wAddr)
+				if err != nil {
+					return nil, false, err
+				}
+				if addressString == address {
+					return wAddr, true, nil
+				}
+			}
+		}
+	}
+	return nil, false, nil
 }
 
 func (s *server) walletAddressString(wAddr *walletAddress) (string, error) {
-	path := s.walletAddressPath(wAddr)
-	addr, err := libkaspiwallet.Address(s.params, s.keysFile.ExtendedPublicKeys, s.keysFile.MinimumSignatures, path, s.keysFile.ECDSA)
+	addr, err := s.walletAddressToAddress(wAddr)
 	if err != nil {
 		return "", err
 	}
@@ -113,6 +213,31 @@ func (s *server) walletAddressString(wAddr *walletAddress) (string, error) {
 	return addr.String(), nil
 }
 
+func (s *server) walletAddressToAddress(wAddr *walletAddress) (util.Address, error) {
+	account, err := s.walletAccount(wAddr.accountIndex)
+	if err != nil {
+		return nil, err
+	}
+	path := s.walletAddressPath(wAddr)
+	if wAddr.lockTime != 0 {
+		return libkaspiwallet.TimeLockedAddress(s.params, account.extendedPublicKeys[0], path, wAddr.lockTime, s.keysFile.ECDSA)
+	}
+	return libkaspiwallet.Address(s.params, account.extendedPublicKeys, s.keysFile.MinimumSignatures, path, s.keysFile.ECDSA)
+}
+
+// walletAddressRedeemScript returns the redeem script of a time-locked address, or nil for a regular address
+func (s *server) walletAddressRedeemScript(wAddr *walletAddress) ([]byte, error) {
+	if wAddr.lockTime == 0 {
+		return nil, nil
+	}
+	account, err := s.walletAccount(wAddr.accountIndex)
+	if err != nil {
+		return nil, err
+	}
+	return libkaspiwallet.TimeLockRedeemScript(s.params, account.extendedPublicKeys[0], s.walletAddressPath(wAddr),
+		wAddr.lockTime, s.keysFile.ECDSA)
+}
+
 func (s *server) walletAddressPath(wAddr *walletAddress) string {
 	if s.isMultisig() {
 		return fmt.Sprintf("m/%d/%d/%d", wAddr.cosignerIndex, wAddr.keyChain, wAddr.index)
@@ -120,6 +245,12 @@ func (s *server) walletAddressPath(wAddr *walletAddress) string {
 	return fmt.Sprintf("m/%d/%d", wAddr.keyChain, wAddr.index)
 }
 
+// walletAddressDerivationPath returns the derivation path of a transaction input spending from wAddr,
+// which tells signers which account key to sign it with
+func (s *server) walletAddressDerivationPath(wAddr *walletAddress) string {
+	return libkaspiwallet.AccountDerivationPath(wAddr.accountIndex, s.walletAddressPath(wAddr))
+}
+
 func (s *server) isMultisig() bool {
 	return len(s.keysFile.ExtendedPublicKeys) > 1
 }
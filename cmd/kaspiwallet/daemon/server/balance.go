@@ -2,21 +2,33 @@ package server
 
 import (
 	"context"
-	"github.com/pkg/errors"
 
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
 )
 
-type balancesType struct{ available, pending uint64 }
+type balancesType struct{ available, pending, timeLocked uint64 }
 type balancesMapType map[*walletAddress]*balancesType
 
-func (s *server) GetBalance(_ context.Context, _ *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
+func (s *server) GetBalance(_ context.Context, request *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
 	if !s.isSynced() {
-		return nil, errors.Errorf("wallet daemon is not synced yet, %s", s.formatSyncStateReport())
+		return nil, s.notSyncedError()
+	}
+
+	accounts := s.walletAccounts()
+	if request.Account != "" {
+		account, err := s.accountByName(request.Account)
+		if err != nil {
+			return nil, err
+		}
+		accounts = []*walletAccount{account}
+	}
+	accountBalancesMap := make(map[uint32]*balancesType, len(accounts))
+	for _, account := range accounts {
+		accountBalancesMap[account.index] = new(balancesType)
 	}
 
 	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
@@ -28,34 +40,60 @@ func (s *server) GetBalance(_ context.Context, _ *pb.GetBalanceRequest) (*pb.Get
 	for _, entry := range s.utxosSortedByAmount {
 		amount := entry.UTXOEntry.Amount()
 		address := entry.address
+		accountBalances, ok := accountBalancesMap[address.accountIndex]
+		if !ok {
+			continue
+		}
 		balances, ok := balancesMap[address]
 		if !ok {
 			balances = new(balancesType)
 			balancesMap[address] = balances
 		}
-		if s.isUTXOSpendable(entry, daaScore) {
+		if s.isUTXOTimeLocked(entry, daaScore) {
+			balances.timeLocked += amount
+			accountBalances.timeLocked += amount
+		} else if s.isUTXOSpendable(entry, daaScore) {
 			balances.available += amount
+			accountBalances.available += amount
 		} else {
 			balances.pending += amount
+			accountBalances.pending += amount
+		}
+	}
+
+	accountNames := make(map[uint32]string, len(accounts))
+	accountBalances := make([]*pb.AccountBalances, len(accounts))
+	for i, account := range accounts {
+		accountNames[account.index] = account.name
+		accountBalances[i] = &pb.AccountBalances{
+			Account:    account.name,
+			Available:  accountBalancesMap[account.index].available,
+			Pending:    accountBalancesMap[account.index].pending,
+			TimeLocked: accountBalancesMap[account.index].timeLocked,
 		}
 	}
 
 	addressBalances := make([]*pb.AddressBalances, len(balancesMap))
 	i := 0
-	var available, pending uint64
+	var available, pending, timeLocked uint64
 	for walletAddress, balances := range balancesMap {
-		address, err := libkaspiwallet.Address(s.params, s.keysFile.ExtendedPublicKeys, s.keysFile.MinimumSignatures, s.walletAddressPath(walletAddress), s.keysFile.ECDSA)
+		address, err := s.walletAddressString(walletAddress)
 		if err != nil {
 			return nil, err
 		}
 		addressBalances[i] = &pb.AddressBalances{
-			Address:   address.String(),
-			Available: balances.available,
-			Pending:   balances.pending,
+			Address:      address,
+			Available:    balances.available,
+			Pending:      balances.pending,
+			Account:      accountNames[walletAddress.accountIndex],
+			Label:        s.labels.addressLabel(address),
+			TimeLocked:   balances.timeLocked,
+			LockDaaScore: walletAddress.lockTime,
 		}
 		i++
 		available += balances.available
 		pending += balances.pending
+		timeLocked += balances.timeLocked
 	}
 
 	log.Infof("GetBalance request scanned %d UTXOs overall over %d addresses", len(s.utxosSortedByAmount), len(balancesMap))
@@ -64,12 +102,22 @@ func (s *server) GetBalance(_ context.Context, _ *pb.GetBalanceRequest) (*pb.Get
 		Available:       available,
 		Pending:         pending,
 		AddressBalances: addressBalances,
+		AccountBalances: accountBalances,
+		TimeLocked:      timeLocked,
 	}, nil
 }
 
 func (s *server) isUTXOSpendable(entry *walletUTXO, virtualDAAScore uint64) bool {
+	if s.isUTXOTimeLocked(entry, virtualDAAScore) {
+		return false
+	}
 	if !entry.UTXOEntry.IsCoinbase() {
 		return true
 	}
 	return entry.UTXOEntry.BlockDAAScore()+s.coinbaseMaturity < virtualDAAScore
 }
+
+// isUTXOTimeLocked returns whether entry is of a time-locked address whose lock DAA score wasn't reached yet
+func (s *server) isUTXOTimeLocked(entry *walletUTXO, virtualDAAScore uint64) bool {
+	return entry.address.lockTime != 0 && !libkaspiwallet.IsLockTimeReached(entry.address.lockTime, virtualDAAScore, 0)
+}
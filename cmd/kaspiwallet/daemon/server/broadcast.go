@@ -51,6 +51,12 @@ func (s *server) broadcast(transactions [][]byte, isDomain bool) ([]string, erro
 			return nil, err
 		}
 
+		// The transaction was already sent, so failing to record it shouldn't fail the broadcast
+		err = s.recordBroadcastTransaction(tx)
+		if err != nil {
+			log.Errorf("Error recording transaction %s in the wallet database: %s", txIDs[i], err)
+		}
+
 		for _, input := range tx.Inputs {
 			s.usedOutpoints[input.PreviousOutpoint] = time.Now()
 		}
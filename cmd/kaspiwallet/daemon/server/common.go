@@ -12,4 +12,7 @@ type walletAddress struct {
 	index         uint32
 	cosignerIndex uint32
 	keyChain      uint8
+	accountIndex  uint32
+	// lockTime is the DAA score a time-locked address can't be spent from until, or 0 for a regular address
+	lockTime uint64
 }
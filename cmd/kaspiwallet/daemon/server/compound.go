@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/miningmanager/mempool"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+func (s *server) CreateCompoundTransactions(_ context.Context, request *pb.CreateCompoundTransactionsRequest) (
+	*pb.CreateCompoundTransactionsResponse, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.isSynced() {
+		return nil, s.notSyncedError()
+	}
+
+	account, err := s.accountByName(request.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	fees, err := newFeeOptions(request.FeeRate, request.MaxFee, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var toAddress util.Address
+	if request.ToAddress != "" {
+		toAddress, err = util.DecodeAddress(request.ToAddress, s.params.Prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := s.compoundCandidates(account, dagInfo.VirtualDAAScore, request.MinUtxoAmount, request.MaxInputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(utxos) < 2 {
+		return nil, errors.Errorf("found %d UTXOs to compound, while at least 2 are required", len(utxos))
+	}
+
+	if toAddress == nil {
+		// A dry run uses an existing change address, so that previewing doesn't use up addresses
+		toAddress, _, err = s.changeAddress(account, request.DryRun, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	unsignedTransactions, inputCount, amount, err := s.createCompoundTransactions(account, utxos, toAddress, fees)
+	if err != nil {
+		return nil, err
+	}
+	fee, mass, err := s.transactionsFeeAndMass(unsignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+	if fees.maxFee > 0 && fee > fees.maxFee {
+		return nil, errors.Errorf("the compound fee of %f is higher than the maximum fee of %f",
+			float64(fee)/constants.SompiPerKaspi, float64(fees.maxFee)/constants.SompiPerKaspi)
+	}
+
+	return &pb.CreateCompoundTransactionsResponse{
+		UnsignedTransactions: unsignedTransactions,
+		Fee:                  fee,
+		Mass:                 mass,
+		InputCount:           uint32(inputCount),
+		Amount:               amount,
+	}, nil
+}
+
+// compoundCandidates returns the UTXOs of account that can be compounded, the smallest first
+func (s *server) compoundCandidates(account *walletAccount, virtualDAAScore uint64, minUTXOAmount uint64, maxInputs uint32) (
+	[]*libkaspiwallet.UTXO, error) {
+
+	var utxos []*libkaspiwallet.UTXO
+	for i := len(s.utxosSortedByAmount) - 1; i >= 0; i-- {
+		if maxInputs > 0 && len(utxos) == int(maxInputs) {
+			break
+		}
+		utxo := s.utxosSortedByAmount[i]
+		if utxo.address.accountIndex != account.index {
+			continue
+		}
+		if utxo.UTXOEntry.Amount() < minUTXOAmount || !s.isUTXOSpendable(utxo, virtualDAAScore) ||
+			s.isOutpointPending(*utxo.Outpoint) {
+			continue
+		}
+		if _, ok := s.lockedOutpoints.get(*utxo.Outpoint); ok {
+			continue
+		}
+		spendableUTXO, err := s.spendableUTXO(utxo)
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, spendableUTXO)
+	}
+	return utxos, nil
+}
+
+// createCompoundTransactions compounds the given UTXOs into toAddress, in batches as large as the
+// standard mass allows. A last batch of a single UTXO is left out, since there's nothing to compound it with.
+func (s *server) createCompoundTransactions(account *walletAccount, utxos []*libkaspiwallet.UTXO, toAddress util.Address, fees *feeOptions) (
+	unsignedTransactions [][]byte, inputCount int, amount uint64, err error) {
+
+	massEstimate, err := s.estimateTransactionMass([]*libkaspiwallet.Payment{{Address: toAddress}}, utxos[0])
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	for start := 0; start < len(utxos); {
+		end := start
+		batchMass := massEstimate.massWithoutInputs
+		for end < len(utxos) && batchMass+massEstimate.inputMass(utxos[end]) < mempool.MaximumStandardTransactionMass {
+			batchMass += massEstimate.inputMass(utxos[end])
+			end++
+		}
+		batch := utxos[start:end]
+		start = end
+		if len(batch) < 2 {
+			break
+		}
+
+		batchValue := uint64(0)
+		for _, utxo := range batch {
+			batchValue += utxo.UTXOEntry.Amount()
+		}
+		fee := fees.fee(massEstimate.mass(batch))
+		if batchValue <= fee {
+			return nil, 0, 0, errors.Errorf("%d UTXOs worth %f can't pay the fee of %f for compounding them. "+
+				"Consider leaving small UTXOs out with a minimum UTXO amount", len(batch),
+				float64(batchValue)/constants.SompiPerKaspi, float64(fee)/constants.SompiPerKaspi)
+		}
+
+		unsignedTransaction, err := s.createUnsignedTransaction(account,
+			[]*libkaspiwallet.Payment{{
+				Address: toAddress,
+				Amount:  batchValue - fee,
+			}}, batch)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		unsignedTransactions = append(unsignedTransactions, unsignedTransaction)
+		inputCount += len(batch)
+		amount += batchValue - fee
+	}
+	return unsignedTransactions, inputCount, amount, nil
+}
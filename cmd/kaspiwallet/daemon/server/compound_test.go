@@ -0,0 +1,144 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/domain/miningmanager/mempool"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/kaspikr/kaspid/util/txmass"
+)
+
+func TestCompound(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %s", err)
+	}
+	serverInstance := &server{
+		params:           params,
+		keysFile:         &keys.File{ExtendedPublicKeys: []string{extendedPublicKey}, MinimumSignatures: 1},
+		txMassCalculator: txmass.NewCalculator(params.MassPerTxByte, params.MassPerScriptPubKeyByte, params.MassPerSigOp),
+		usedOutpoints:    map[externalapi.DomainOutpoint]time.Time{},
+		lockedOutpoints:  &lockedOutpointStore{lockedOutpoints: map[externalapi.DomainOutpoint]*lockedOutpoint{}},
+	}
+
+	receiveWalletAddress := &walletAddress{index: 0, keyChain: libkaspiwallet.ExternalKeychain}
+	receiveAddressString, err := serverInstance.walletAddressString(receiveWalletAddress)
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	receiveAddress, err := util.DecodeAddress(receiveAddressString, params.Prefix)
+	if err != nil {
+		t.Fatalf("DecodeAddress: %s", err)
+	}
+	receiveScriptPublicKey, err := txscript.PayToAddrScript(receiveAddress)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %s", err)
+	}
+
+	// UTXOs of 1 to 250 KAS, sorted by amount like the wallet's
+	const utxoCount = 250
+	for i := utxoCount; i > 0; i-- {
+		serverInstance.utxosSortedByAmount = append(serverInstance.utxosSortedByAmount, &walletUTXO{
+			Outpoint: externalapi.NewDomainOutpoint(
+				externalapi.NewDomainTransactionIDFromByteArray(&[32]byte{byte(i), byte(i >> 8)}), 0),
+			UTXOEntry: utxo.NewUTXOEntry(uint64(i)*constants.SompiPerKaspi, receiveScriptPublicKey, false, 0),
+			address:   receiveWalletAddress,
+		})
+	}
+	smallestUTXO := serverInstance.utxosSortedByAmount[utxoCount-1]
+	lockedUTXO := serverInstance.utxosSortedByAmount[utxoCount-2]
+	pendingUTXO := serverInstance.utxosSortedByAmount[utxoCount-3]
+	serverInstance.lockedOutpoints.lockedOutpoints[*lockedUTXO.Outpoint] = &lockedOutpoint{}
+	serverInstance.usedOutpoints[*pendingUTXO.Outpoint] = time.Now()
+
+	// Locked and pending UTXOs are left out, and the rest come smallest first
+	candidates, err := serverInstance.compoundCandidates(serverInstance.defaultAccount(), 1, 0, 0)
+	if err != nil {
+		t.Fatalf("compoundCandidates: %s", err)
+	}
+	if len(candidates) != utxoCount-2 {
+		t.Fatalf("expected %d candidates, got %d", utxoCount-2, len(candidates))
+	}
+	if *candidates[0].Outpoint != *smallestUTXO.Outpoint || candidates[1].UTXOEntry.Amount() != 4*constants.SompiPerKaspi {
+		t.Fatalf("expected the smallest UTXOs first")
+	}
+	candidates, err = serverInstance.compoundCandidates(serverInstance.defaultAccount(), 1, 10*constants.SompiPerKaspi, 5)
+	if err != nil {
+		t.Fatalf("compoundCandidates: %s", err)
+	}
+	if len(candidates) != 5 || candidates[0].UTXOEntry.Amount() != 10*constants.SompiPerKaspi {
+		t.Fatalf("expected the 5 smallest UTXOs of at least 10 KAS, got %d UTXOs", len(candidates))
+	}
+
+	fees, err := newFeeOptions(0, 0, false)
+	if err != nil {
+		t.Fatalf("newFeeOptions: %s", err)
+	}
+	candidates, err = serverInstance.compoundCandidates(serverInstance.defaultAccount(), 1, 0, 0)
+	if err != nil {
+		t.Fatalf("compoundCandidates: %s", err)
+	}
+	unsignedTransactions, inputCount, amount, err := serverInstance.createCompoundTransactions(serverInstance.defaultAccount(), candidates,
+		receiveAddress, fees)
+	if err != nil {
+		t.Fatalf("createCompoundTransactions: %s", err)
+	}
+	if len(unsignedTransactions) < 2 {
+		t.Fatalf("expected the UTXOs to be compounded in several batches, got %d", len(unsignedTransactions))
+	}
+
+	totalFee, _, err := serverInstance.transactionsFeeAndMass(unsignedTransactions)
+	if err != nil {
+		t.Fatalf("transactionsFeeAndMass: %s", err)
+	}
+	compoundedValue := uint64(0)
+	for _, candidate := range candidates[:inputCount] {
+		compoundedValue += candidate.UTXOEntry.Amount()
+	}
+	if amount+totalFee != compoundedValue {
+		t.Fatalf("the compound outputs %d and pays a fee of %d, while it spends %d", amount, totalFee, compoundedValue)
+	}
+
+	compoundedInputs := 0
+	for i, transactionBytes := range unsignedTransactions {
+		fee, mass, err := serverInstance.transactionsFeeAndMass([][]byte{transactionBytes})
+		if err != nil {
+			t.Fatalf("transactionsFeeAndMass: %s", err)
+		}
+		if mass >= mempool.MaximumStandardTransactionMass {
+			t.Fatalf("transaction #%d has mass %d, which is above the standard mass", i, mass)
+		}
+		if fee < fees.fee(mass) {
+			t.Fatalf("transaction #%d pays a fee of %d, while its mass requires %d", i, fee, fees.fee(mass))
+		}
+		transaction, err := serialization.DeserializePartiallySignedTransaction(transactionBytes)
+		if err != nil {
+			t.Fatalf("DeserializePartiallySignedTransaction: %s", err)
+		}
+		compoundedInputs += len(transaction.Tx.Inputs)
+		if outputs := transaction.Tx.Outputs; len(outputs) != 1 || !outputs[0].ScriptPublicKey.Equal(receiveScriptPublicKey) {
+			t.Fatalf("expected transaction #%d to have a single output to the compound address", i)
+		}
+	}
+	if compoundedInputs != inputCount {
+		t.Fatalf("the transactions spend %d inputs, while %d were reported", compoundedInputs, inputCount)
+	}
+	if inputCount != len(candidates) && inputCount != len(candidates)-1 {
+		t.Fatalf("expected all the candidates but a last single one to be compounded, got %d of %d",
+			inputCount, len(candidates))
+	}
+}
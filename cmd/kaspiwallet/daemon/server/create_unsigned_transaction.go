@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
@@ -11,7 +12,8 @@ import (
 	"github.com/pkg/errors"
 )
 
-// TODO: Implement a better fee estimation mechanism
+// feePerInput is a rough upper bound of the fee an input adds to a transaction, below which a UTXO isn't
+// worth spending
 const feePerInput = 10000
 
 // The minimal change amount to target in order to avoid large storage mass (see KIP9 for more details).
@@ -19,96 +21,233 @@ const feePerInput = 10000
 // should succeed (at most 50K storage mass for each output, thus overall lower than standard mass upper bound which is 100K gram)
 const minChangeTarget = constants.SompiPerKaspi / 5
 
+// maxPaymentsPerTransaction is the maximal number of outputs that can be paid in a single send. Every standard
+// P2PK output weighs a little over 400 grams, so this leaves most of the standard mass for inputs and change.
+const maxPaymentsPerTransaction = 100
+
 func (s *server) CreateUnsignedTransactions(_ context.Context, request *pb.CreateUnsignedTransactionsRequest) (
 	*pb.CreateUnsignedTransactionsResponse, error,
 ) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	unsignedTransactions, err := s.createUnsignedTransactions(request.Address, request.Amount, request.IsSendAll,
-		request.From, request.UseExistingChangeAddress)
+	fees, err := newFeeOptions(request.FeeRate, request.MaxFee, request.SubtractFeeFromAmount)
+	if err != nil {
+		return nil, err
+	}
+	unsignedTransactions, fee, mass, err := s.createUnsignedTransactions(request.Account, request.Address, request.Amount,
+		request.Outputs, request.IsSendAll, request.From, request.Utxos, request.UseExistingChangeAddress, fees)
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.CreateUnsignedTransactionsResponse{UnsignedTransactions: unsignedTransactions}, nil
+	return &pb.CreateUnsignedTransactionsResponse{
+		UnsignedTransactions: unsignedTransactions,
+		Fee:                  fee,
+		Mass:                 mass,
+	}, nil
 }
 
-func (s *server) createUnsignedTransactions(address string, amount uint64, isSendAll bool, fromAddressesString []string, useExistingChangeAddress bool) ([][]byte, error) {
+// createUnsignedTransactions returns the unsigned transactions of a send, along with the fee
+// they pay and their estimated mass after signing, both summed over all of them
+func (s *server) createUnsignedTransactions(accountName string, address string, amount uint64, outputs []*pb.PaymentOutput, isSendAll bool,
+	fromAddressesString []string, selectedOutpoints []*pb.Outpoint, useExistingChangeAddress bool, fees *feeOptions) (
+	unsignedTransactions [][]byte, fee uint64, mass uint64, err error) {
+
 	if !s.isSynced() {
-		return nil, errors.Errorf("wallet daemon is not synced yet, %s", s.formatSyncStateReport())
+		return nil, 0, 0, s.notSyncedError()
+	}
+	if isSendAll && fees.subtractFeeFromAmount {
+		return nil, 0, 0, errors.Errorf("send all always subtracts the fee from the amount, " +
+			"so subtract fee from amount can't be specified with it")
+	}
+	if len(fromAddressesString) > 0 && len(selectedOutpoints) > 0 {
+		return nil, 0, 0, errors.Errorf("from addresses can't be specified together with the UTXOs to spend")
+	}
+	account, err := s.accountByName(accountName)
+	if err != nil {
+		return nil, 0, 0, err
 	}
-	// make sure address string is correct before proceeding to a
+	// make sure the addresses are correct before proceeding to a
 	// potentially long UTXO refreshment operation
-	toAddress, err := util.DecodeAddress(address, s.params.Prefix)
+	payments, err := s.parsePayments(address, amount, outputs, isSendAll)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
 
 	var fromAddresses []*walletAddress
 	for _, from := range fromAddressesString {
 		fromAddress, exists := s.addressSet[from]
 		if !exists {
-			return nil, fmt.Errorf("specified from address %s does not exists", from)
+			return nil, 0, 0, fmt.Errorf("specified from address %s does not exists", from)
+		}
+		if fromAddress.accountIndex != account.index {
+			return nil, 0, 0, errors.Errorf("specified from address %s is not an address of account %s",
+				from, account.name)
 		}
 		fromAddresses = append(fromAddresses, fromAddress)
 	}
 
-	selectedUTXOs, spendValue, changeSompi, err := s.selectUTXOs(amount, isSendAll, feePerInput, fromAddresses)
+	selectedUTXOs, spendValue, changeSompi, selectionFee, err := s.selectUTXOs(account, payments, isSendAll, fees, fromAddresses,
+		selectedOutpoints)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
 
 	if len(selectedUTXOs) == 0 {
-		return nil, errors.Errorf("couldn't find funds to spend")
+		return nil, 0, 0, errors.Errorf("couldn't find funds to spend")
 	}
 
-	changeAddress, changeWalletAddress, err := s.changeAddress(useExistingChangeAddress, fromAddresses)
+	changeAddress, changeWalletAddress, err := s.changeAddress(account, useExistingChangeAddress, fromAddresses)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
 
-	payments := []*libkaspiwallet.Payment{{
-		Address: toAddress,
-		Amount:  spendValue,
-	}}
+	if isSendAll {
+		payments[0].Amount = spendValue
+		// Any further fees, of split transactions, must be paid by the payment as well
+		fees.subtractFeeFromAmount = true
+	} else if fees.subtractFeeFromAmount {
+		err = subtractFeeFromPayments(payments, selectionFee)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	transactionOutputs := append([]*libkaspiwallet.Payment{}, payments...)
 	if changeSompi > 0 {
-		payments = append(payments, &libkaspiwallet.Payment{
+		transactionOutputs = append(transactionOutputs, &libkaspiwallet.Payment{
 			Address: changeAddress,
 			Amount:  changeSompi,
 		})
 	}
-	unsignedTransaction, err := libkaspiwallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
-		s.keysFile.MinimumSignatures,
-		payments, selectedUTXOs)
+	unsignedTransaction, err := s.createUnsignedTransaction(account, transactionOutputs, selectedUTXOs)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
 
-	unsignedTransactions, err := s.maybeAutoCompoundTransaction(unsignedTransaction, toAddress, changeAddress, changeWalletAddress)
+	unsignedTransactions, err = s.maybeAutoCompoundTransaction(account, unsignedTransaction, payments, changeAddress,
+		changeWalletAddress, fees)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
+	}
+
+	fee, mass, err = s.transactionsFeeAndMass(unsignedTransactions)
+	if err != nil {
+		return nil, 0, 0, err
 	}
-	return unsignedTransactions, nil
+	if fees.maxFee > 0 && fee > fees.maxFee {
+		return nil, 0, 0, errors.Errorf("the transaction fee of %f is higher than the maximum fee of %f",
+			float64(fee)/constants.SompiPerKaspi, float64(fees.maxFee)/constants.SompiPerKaspi)
+	}
+	return unsignedTransactions, fee, mass, nil
 }
 
-func (s *server) selectUTXOs(spendAmount uint64, isSendAll bool, feePerInput uint64, fromAddresses []*walletAddress) (
-	selectedUTXOs []*libkaspiwallet.UTXO, totalReceived uint64, changeSompi uint64, err error) {
+// parsePayments returns the payments of a send request, which are either the
+// given outputs, or a single payment of amount to address. The addresses may be payment URIs.
+func (s *server) parsePayments(address string, amount uint64, outputs []*pb.PaymentOutput, isSendAll bool) (
+	[]*libkaspiwallet.Payment, error) {
+
+	if len(outputs) == 0 {
+		outputs = []*pb.PaymentOutput{{Address: address, Amount: amount}}
+	} else if address != "" || amount != 0 {
+		return nil, errors.Errorf("address and amount can't be specified together with outputs")
+	}
+	if isSendAll && len(outputs) > 1 {
+		return nil, errors.Errorf("send all can only be used with a single output")
+	}
+	if len(outputs) > maxPaymentsPerTransaction {
+		return nil, errors.Errorf("%d outputs were specified, while at most %d are allowed",
+			len(outputs), maxPaymentsPerTransaction)
+	}
+
+	payments := make([]*libkaspiwallet.Payment, len(outputs))
+	totalAmount := uint64(0)
+	for i, output := range outputs {
+		paymentAddress, paymentAmount, err := s.resolvePaymentURI(output.Address, output.Amount, isSendAll)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid payment in output #%d", i)
+		}
+		toAddress, err := util.DecodeAddress(paymentAddress, s.params.Prefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid address %s in output #%d", output.Address, i)
+		}
+		if !isSendAll && paymentAmount == 0 {
+			return nil, errors.Errorf("output #%d to %s has a zero amount", i, output.Address)
+		}
+		if totalAmount+paymentAmount < totalAmount {
+			return nil, errors.Errorf("the total amount of the outputs overflows")
+		}
+		totalAmount += paymentAmount
+		payments[i] = &libkaspiwallet.Payment{
+			Address: toAddress,
+			Amount:  paymentAmount,
+		}
+	}
+	return payments, nil
+}
+
+func totalPaymentsAmount(payments []*libkaspiwallet.Payment) uint64 {
+	total := uint64(0)
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	return total
+}
+
+// selectUTXOs selects the UTXOs of account to fund the given payments with, or uses all of selectedOutpoints if
+// any are given. It returns the amount the payments receive together, which for send all is everything
+// selected but the fee, the change, and the fee of a transaction spending the selected UTXOs to the
+// payments and the change.
+func (s *server) selectUTXOs(account *walletAccount, payments []*libkaspiwallet.Payment, isSendAll bool, fees *feeOptions,
+	fromAddresses []*walletAddress, selectedOutpoints []*pb.Outpoint) (
+	selectedUTXOs []*libkaspiwallet.UTXO, totalReceived uint64, changeSompi uint64, fee uint64, err error) {
 
 	selectedUTXOs = []*libkaspiwallet.UTXO{}
 	totalValue := uint64(0)
+	spendAmount := totalPaymentsAmount(payments)
 
 	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
 	if err != nil {
-		return nil, 0, 0, err
+		return nil, 0, 0, 0, err
 	}
 
-	for _, utxo := range s.utxosSortedByAmount {
+	// The mass is estimated with a change output, since whether there will be one is known only after the selection.
+	// The change address itself doesn't matter, as all of them have the same script type.
+	anyChangeAddress, _, err := s.changeAddress(account, true, nil)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	outputsWithChange := append([]*libkaspiwallet.Payment{}, payments...)
+	outputsWithChange = append(outputsWithChange, &libkaspiwallet.Payment{Address: anyChangeAddress})
+	var massEstimate *transactionMassEstimate
+
+	candidateUTXOs := s.utxosSortedByAmount
+	if len(selectedOutpoints) > 0 {
+		candidateUTXOs, err = s.selectedWalletUTXOs(selectedOutpoints, dagInfo.VirtualDAAScore)
+		if err != nil {
+			return nil, 0, 0, 0, err
+		}
+		for _, utxo := range candidateUTXOs {
+			if utxo.address.accountIndex != account.index {
+				return nil, 0, 0, 0, errors.Errorf("outpoint %s is not a UTXO of account %s", utxo.Outpoint,
+					account.name)
+			}
+		}
+	}
+
+	for _, utxo := range candidateUTXOs {
+		if utxo.address.accountIndex != account.index {
+			continue
+		}
 		if (fromAddresses != nil && !walletAddressesContain(fromAddresses, utxo.address)) ||
 			!s.isUTXOSpendable(utxo, dagInfo.VirtualDAAScore) {
 			continue
 		}
 
+		if _, ok := s.lockedOutpoints.get(*utxo.Outpoint); ok {
+			continue
+		}
+
 		if broadcastTime, ok := s.usedOutpoints[*utxo.Outpoint]; ok {
 			if s.usedOutpointHasExpired(broadcastTime) {
 				delete(s.usedOutpoints, *utxo.Outpoint)
@@ -117,41 +256,60 @@ func (s *server) selectUTXOs(spendAmount uint64, isSendAll bool, feePerInput uin
 			}
 		}
 
-		selectedUTXOs = append(selectedUTXOs, &libkaspiwallet.UTXO{
-			Outpoint:       utxo.Outpoint,
-			UTXOEntry:      utxo.UTXOEntry,
-			DerivationPath: s.walletAddressPath(utxo.address),
-		})
+		selectedUTXO, err := s.spendableUTXO(utxo)
+		if err != nil {
+			return nil, 0, 0, 0, err
+		}
+		selectedUTXOs = append(selectedUTXOs, selectedUTXO)
 
 		totalValue += utxo.UTXOEntry.Amount()
 
-		fee := feePerInput * uint64(len(selectedUTXOs))
+		if massEstimate == nil {
+			massEstimate, err = s.estimateTransactionMass(outputsWithChange, selectedUTXOs[0])
+			if err != nil {
+				return nil, 0, 0, 0, err
+			}
+		}
+		fee = fees.fee(massEstimate.mass(selectedUTXOs))
 		totalSpend := spendAmount + fee
+		if fees.subtractFeeFromAmount {
+			totalSpend = spendAmount
+		}
 		// Two break cases (if not send all):
 		// 		1. totalValue == totalSpend, so there's no change needed -> number of outputs = 1, so a single input is sufficient
 		// 		2. totalValue > totalSpend, so there will be change and 2 outputs, therefor in order to not struggle with --
 		//		   2.1 go-nodes dust patch we try and find at least 2 inputs (even though the next one is not necessary in terms of spend value)
 		// 		   2.2 KIP9 we try and make sure that the change amount is not too small
-		if !isSendAll && (totalValue == totalSpend || (totalValue >= totalSpend+minChangeTarget && len(selectedUTXOs) > 1)) {
+		// All of selectedOutpoints are always spent
+		if !isSendAll && len(selectedOutpoints) == 0 &&
+			(totalValue == totalSpend || (totalValue >= totalSpend+minChangeTarget && len(selectedUTXOs) > 1)) {
 			break
 		}
 	}
 
-	fee := feePerInput * uint64(len(selectedUTXOs))
 	var totalSpend uint64
 	if isSendAll {
 		totalSpend = totalValue
 		totalReceived = totalValue - fee
+	} else if fees.subtractFeeFromAmount {
+		totalSpend = spendAmount
+		totalReceived = spendAmount - fee
 	} else {
 		totalSpend = spendAmount + fee
 		totalReceived = spendAmount
 	}
 	if totalValue < totalSpend {
-		return nil, 0, 0, errors.Errorf("Insufficient funds for send: %f required, while only %f available",
+		return nil, 0, 0, 0, classifyErrorf(errInsufficientFunds,
+			"Insufficient funds for send: %f required, while only %f available",
 			float64(totalSpend)/constants.SompiPerKaspi, float64(totalValue)/constants.SompiPerKaspi)
 	}
+	if len(selectedUTXOs) > 0 && (isSendAll || fees.subtractFeeFromAmount) && totalSpend <= fee {
+		return nil, 0, 0, 0, classifyErrorf(errInsufficientFunds,
+			"Insufficient funds for send: %f available, which can't pay the fee of %f",
+			float64(totalSpend)/constants.SompiPerKaspi, float64(fee)/constants.SompiPerKaspi)
+	}
 
-	return selectedUTXOs, totalReceived, totalValue - totalSpend, nil
+	return selectedUTXOs, totalReceived, totalValue - totalSpend, fee, nil
 }
 
 func walletAddressesContain(addresses []*walletAddress, contain *walletAddress) bool {
@@ -163,3 +321,28 @@ func walletAddressesContain(addresses []*walletAddress, contain *walletAddress)
 
 	return false
 }
+
+// resolvePaymentURI resolves the address of a payment, which may be a payment URI, to a plain address
+// and the amount to pay. The amount of the URI is used when no amount is given, unless all the funds
+// are sent.
+func (s *server) resolvePaymentURI(address string, amount uint64, isSendAll bool) (string, uint64, error) {
+	paymentURI, err := util.ParsePaymentURI(address, s.params.Prefix)
+	if err != nil {
+		return "", 0, err
+	}
+	if paymentURI.IsExpired(time.Now()) {
+		return "", 0, errors.Errorf("the payment request %s expired at %s", address, paymentURI.Expires)
+	}
+	if paymentURI.Amount != 0 {
+		if isSendAll {
+			return "", 0, errors.Errorf("send all can't be used with a payment request that specifies an amount")
+		}
+		if amount == 0 {
+			amount = paymentURI.Amount
+		} else if amount != paymentURI.Amount {
+			return "", 0, errors.Errorf("the amount %d sompi is different from the amount %d sompi of the payment request",
+				amount, paymentURI.Amount)
+		}
+	}
+	return paymentURI.Address.String(), amount, nil
+}
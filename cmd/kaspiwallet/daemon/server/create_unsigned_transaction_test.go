@@ -0,0 +1,235 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/domain/miningmanager/mempool"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/kaspikr/kaspid/util/txmass"
+)
+
+func TestParsePayments(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	serverInstance := &server{params: params}
+	address := func(seed byte) string {
+		address, err := util.NewAddressPublicKey(append(make([]byte, 31), seed), params.Prefix)
+		if err != nil {
+			t.Fatalf("NewAddressPublicKey: %s", err)
+		}
+		return address.String()
+	}
+
+	payments, err := serverInstance.parsePayments(address(1), 5, nil, false)
+	if err != nil {
+		t.Fatalf("parsePayments: %s", err)
+	}
+	if len(payments) != 1 || payments[0].Address.String() != address(1) || payments[0].Amount != 5 {
+		t.Fatalf("unexpected payments %+v", payments)
+	}
+
+	outputs := []*pb.PaymentOutput{{Address: address(1), Amount: 5}, {Address: address(2), Amount: 7}}
+	payments, err = serverInstance.parsePayments("", 0, outputs, false)
+	if err != nil {
+		t.Fatalf("parsePayments: %s", err)
+	}
+	if len(payments) != 2 || totalPaymentsAmount(payments) != 12 || payments[1].Address.String() != address(2) {
+		t.Fatalf("unexpected payments %+v", payments)
+	}
+
+	payments, err = serverInstance.parsePayments(address(1)+"?amount=1.5&label=Shop", 0, nil, false)
+	if err != nil {
+		t.Fatalf("parsePayments: %s", err)
+	}
+	if len(payments) != 1 || payments[0].Address.String() != address(1) || payments[0].Amount != 150_000_000 {
+		t.Fatalf("unexpected payments %+v", payments)
+	}
+
+	outputs = []*pb.PaymentOutput{{Address: address(1) + "?amount=1.5"}, {Address: address(2), Amount: 7}}
+	payments, err = serverInstance.parsePayments("", 0, outputs, false)
+	if err != nil {
+		t.Fatalf("parsePayments: %s", err)
+	}
+	if len(payments) != 2 || payments[0].Address.String() != address(1) || payments[0].Amount != 150_000_000 {
+		t.Fatalf("unexpected payments %+v", payments)
+	}
+
+	tests := []struct {
+		name      string
+		address   string
+		amount    uint64
+		outputs   []*pb.PaymentOutput
+		isSendAll bool
+	}{
+		{name: "address together with outputs", address: address(1), outputs: outputs},
+		{name: "send all to several outputs", outputs: outputs, isSendAll: true},
+		{name: "invalid address", outputs: []*pb.PaymentOutput{{Address: "kaspi:invalid", Amount: 1}}},
+		{name: "zero amount", outputs: []*pb.PaymentOutput{{Address: address(1), Amount: 0}}},
+		{name: "overflow", outputs: []*pb.PaymentOutput{{Address: address(1), Amount: 1}, {Address: address(2), Amount: ^uint64(0)}}},
+		{name: "payment request with a different amount", address: address(1) + "?amount=1", amount: 5},
+		{name: "send all to a payment request with an amount", address: address(1) + "?amount=1", isSendAll: true},
+		{name: "expired payment request", address: address(1) + "?amount=1&expires=1000"},
+		{name: "output to a payment request with a different amount",
+			outputs: []*pb.PaymentOutput{{Address: address(1) + "?amount=1", Amount: 5}}},
+		{name: "output to a payment request without an amount", outputs: []*pb.PaymentOutput{{Address: address(1) + "?label=Shop"}}},
+		{name: "too many outputs", outputs: make([]*pb.PaymentOutput, maxPaymentsPerTransaction+1)},
+	}
+	for _, test := range tests {
+		_, err := serverInstance.parsePayments(test.address, test.amount, test.outputs, test.isSendAll)
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestAutoCompoundTransactionWithSeveralPayments(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %s", err)
+	}
+	serverInstance := &server{
+		params:           params,
+		keysFile:         &keys.File{ExtendedPublicKeys: []string{extendedPublicKey}, MinimumSignatures: 1},
+		addressSet:       make(walletAddressSet),
+		txMassCalculator: txmass.NewCalculator(params.MassPerTxByte, params.MassPerScriptPubKeyByte, params.MassPerSigOp),
+	}
+
+	receiveWalletAddress := &walletAddress{index: 0, keyChain: libkaspiwallet.ExternalKeychain}
+	receiveAddressString, err := serverInstance.walletAddressString(receiveWalletAddress)
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	receiveAddress, err := util.DecodeAddress(receiveAddressString, params.Prefix)
+	if err != nil {
+		t.Fatalf("DecodeAddress: %s", err)
+	}
+	receiveScriptPublicKey, err := txscript.PayToAddrScript(receiveAddress)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %s", err)
+	}
+	changeWalletAddress := &walletAddress{index: 0, keyChain: libkaspiwallet.InternalKeychain}
+	changeAddressString, err := serverInstance.walletAddressString(changeWalletAddress)
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	changeAddress, err := util.DecodeAddress(changeAddressString, params.Prefix)
+	if err != nil {
+		t.Fatalf("DecodeAddress: %s", err)
+	}
+
+	// Enough small UTXOs for the transaction to exceed the standard mass
+	const utxoCount = 200
+	utxos := make([]*libkaspiwallet.UTXO, utxoCount)
+	for i := range utxos {
+		utxos[i] = &libkaspiwallet.UTXO{
+			Outpoint: externalapi.NewDomainOutpoint(
+				externalapi.NewDomainTransactionIDFromByteArray(&[32]byte{byte(i), byte(i >> 8)}), 0),
+			UTXOEntry:      utxo.NewUTXOEntry(constants.SompiPerKaspi, receiveScriptPublicKey, false, 0),
+			DerivationPath: serverInstance.walletAddressPath(receiveWalletAddress),
+		}
+	}
+
+	testAutoCompound := func(subtractFeeFromAmount bool, paymentAmounts []uint64, changeAmount uint64) {
+		fees, err := newFeeOptions(0, 0, subtractFeeFromAmount)
+		if err != nil {
+			t.Fatalf("newFeeOptions: %s", err)
+		}
+		payments := make([]*libkaspiwallet.Payment, len(paymentAmounts))
+		for i, amount := range paymentAmounts {
+			address, err := util.NewAddressPublicKey(append(make([]byte, 31), byte(i+1)), params.Prefix)
+			if err != nil {
+				t.Fatalf("NewAddressPublicKey: %s", err)
+			}
+			payments[i] = &libkaspiwallet.Payment{Address: address, Amount: amount}
+		}
+		transactionOutputs := append([]*libkaspiwallet.Payment{}, payments...)
+		if changeAmount > 0 {
+			transactionOutputs = append(transactionOutputs, &libkaspiwallet.Payment{Address: changeAddress, Amount: changeAmount})
+		}
+		unsignedTransaction, err := libkaspiwallet.CreateUnsignedTransaction(serverInstance.keysFile.ExtendedPublicKeys,
+			serverInstance.keysFile.MinimumSignatures, transactionOutputs, utxos)
+		if err != nil {
+			t.Fatalf("CreateUnsignedTransaction: %s", err)
+		}
+
+		unsignedTransactions, err := serverInstance.maybeAutoCompoundTransaction(serverInstance.defaultAccount(), unsignedTransaction, payments,
+			changeAddress, changeWalletAddress, fees)
+		if err != nil {
+			t.Fatalf("maybeAutoCompoundTransaction: %s", err)
+		}
+		if len(unsignedTransactions) < 3 {
+			t.Fatalf("expected the transaction to be split, got %d transactions", len(unsignedTransactions))
+		}
+
+		var mergeTransaction *serialization.PartiallySignedTransaction
+		for i, transactionBytes := range unsignedTransactions {
+			transaction, err := serialization.DeserializePartiallySignedTransaction(transactionBytes)
+			if err != nil {
+				t.Fatalf("DeserializePartiallySignedTransaction: %s", err)
+			}
+			fee, mass, err := serverInstance.transactionsFeeAndMass([][]byte{transactionBytes})
+			if err != nil {
+				t.Fatalf("transactionsFeeAndMass: %s", err)
+			}
+			if mass >= mempool.MaximumStandardTransactionMass {
+				t.Fatalf("transaction #%d has mass %d, which is above the standard mass", i, mass)
+			}
+			if fee < fees.fee(mass) {
+				t.Fatalf("transaction #%d pays a fee of %d, while its mass requires %d", i, fee, fees.fee(mass))
+			}
+			mergeTransaction = transaction
+		}
+
+		// The merge transaction pays all the payments, followed by the change
+		if len(mergeTransaction.Tx.Outputs) < len(payments) {
+			t.Fatalf("expected the merge transaction to have at least %d outputs, got %d",
+				len(payments), len(mergeTransaction.Tx.Outputs))
+		}
+		totalPaid := uint64(0)
+		for i, payment := range payments {
+			scriptPublicKey, err := txscript.PayToAddrScript(payment.Address)
+			if err != nil {
+				t.Fatalf("PayToAddrScript: %s", err)
+			}
+			output := mergeTransaction.Tx.Outputs[i]
+			if !output.ScriptPublicKey.Equal(scriptPublicKey) {
+				t.Fatalf("output #%d of the merge transaction doesn't pay to payment #%d", i, i)
+			}
+			if !subtractFeeFromAmount && output.Value != payment.Amount {
+				t.Fatalf("output #%d of the merge transaction pays %d instead of %d", i, output.Value, payment.Amount)
+			}
+			totalPaid += output.Value
+		}
+
+		if subtractFeeFromAmount {
+			// The payments pay all of the fees
+			totalFee, _, err := serverInstance.transactionsFeeAndMass(unsignedTransactions)
+			if err != nil {
+				t.Fatalf("transactionsFeeAndMass: %s", err)
+			}
+			if totalPaid+totalFee != totalPaymentsAmount(payments) {
+				t.Fatalf("the payments received %d and paid a fee of %d, while they were of %d in total",
+					totalPaid, totalFee, totalPaymentsAmount(payments))
+			}
+		}
+	}
+
+	// The wallet adds the fees on top of the payments, and pays them from the change
+	testAutoCompound(false, []uint64{10 * constants.SompiPerKaspi, 11 * constants.SompiPerKaspi, 12 * constants.SompiPerKaspi},
+		(utxoCount-40)*constants.SompiPerKaspi)
+	// The payments spend everything, so they pay the fees
+	testAutoCompound(true, []uint64{60 * constants.SompiPerKaspi, 70 * constants.SompiPerKaspi, 70 * constants.SompiPerKaspi}, 0)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"math"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/pkg/errors"
+)
+
+// defaultFeeRate is the fee rate, in sompi per gram of mass, that is used when none is specified.
+// It matches kaspid's default minimum relay fee of 1000 sompi per kilogram.
+const defaultFeeRate = 1.0
+
+// feeOptions determine the fees that the transactions of a send pay
+type feeOptions struct {
+	// feeRate is the fee paid per gram of mass, in sompi
+	feeRate float64
+	// maxFee is the maximal fee all the transactions of a send may pay together, or 0 for no limit
+	maxFee uint64
+	// subtractFeeFromAmount makes the payments pay the fee instead of adding it on top of them
+	subtractFeeFromAmount bool
+}
+
+func newFeeOptions(feeRate float64, maxFee uint64, subtractFeeFromAmount bool) (*feeOptions, error) {
+	if feeRate == 0 {
+		feeRate = defaultFeeRate
+	}
+	if feeRate < 0 || math.IsNaN(feeRate) || math.IsInf(feeRate, 0) {
+		return nil, errors.Errorf("invalid fee rate %f", feeRate)
+	}
+	return &feeOptions{
+		feeRate:               feeRate,
+		maxFee:                maxFee,
+		subtractFeeFromAmount: subtractFeeFromAmount,
+	}, nil
+}
+
+// fee returns the fee of a transaction with the given mass
+func (fo *feeOptions) fee(mass uint64) uint64 {
+	return uint64(math.Ceil(float64(mass) * fo.feeRate))
+}
+
+// transactionMassEstimate estimates the mass of a wallet transaction with a given set of outputs by its inputs.
+// Since all of the wallet's inputs have the same script type, they all weigh the same, except for the inputs
+// of time-locked addresses, whose signature scripts also reveal their redeem scripts.
+type transactionMassEstimate struct {
+	massWithoutInputs uint64
+	massPerInput      uint64
+	massPerTxByte     uint64
+}
+
+func (tme *transactionMassEstimate) mass(inputs []*libkaspiwallet.UTXO) uint64 {
+	mass := tme.massWithoutInputs
+	for _, input := range inputs {
+		mass += tme.inputMass(input)
+	}
+	return mass
+}
+
+func (tme *transactionMassEstimate) inputMass(input *libkaspiwallet.UTXO) uint64 {
+	if input.RedeemScript == nil {
+		return tme.massPerInput
+	}
+	// The redeem script is pushed as a single data push, whose opcode takes a byte for any time lock script
+	return tme.massPerInput + uint64(len(input.RedeemScript)+1)*tme.massPerTxByte
+}
+
+// estimateTransactionMass estimates the mass of a transaction paying to the given outputs,
+// by signing a transaction spending sampleUTXO to them
+func (s *server) estimateTransactionMass(outputs []*libkaspiwallet.Payment, sampleUTXO *libkaspiwallet.UTXO) (
+	*transactionMassEstimate, error) {
+
+	// The redeem script of a time-locked sample is accounted for by inputMass, like those of the other inputs
+	regularSampleUTXO := *sampleUTXO
+	regularSampleUTXO.RedeemScript = nil
+	transactionBytes, err := libkaspiwallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
+		s.keysFile.MinimumSignatures, outputs, []*libkaspiwallet.UTXO{&regularSampleUTXO})
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := serialization.DeserializePartiallySignedTransaction(transactionBytes)
+	if err != nil {
+		return nil, err
+	}
+	massWithOneInput, err := s.estimateMassAfterSignatures(transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionWithoutInputs := transaction.Tx.Clone()
+	transactionWithoutInputs.Inputs = []*externalapi.DomainTransactionInput{}
+	massWithoutInputs := s.txMassCalculator.CalculateTransactionMass(transactionWithoutInputs)
+
+	return &transactionMassEstimate{
+		massWithoutInputs: massWithoutInputs,
+		massPerInput:      massWithOneInput - massWithoutInputs,
+		massPerTxByte:     s.params.MassPerTxByte,
+	}, nil
+}
+
+// payFeeFromOutput deducts the fee of transaction from the output at outputIndex
+func (s *server) payFeeFromOutput(transaction *serialization.PartiallySignedTransaction, outputIndex int,
+	fees *feeOptions) error {
+
+	mass, err := s.estimateMassAfterSignatures(transaction)
+	if err != nil {
+		return err
+	}
+	fee := fees.fee(mass)
+	output := transaction.Tx.Outputs[outputIndex]
+	if output.Value <= fee {
+		return errors.Errorf("output of %f can't pay the transaction fee of %f",
+			float64(output.Value)/constants.SompiPerKaspi, float64(fee)/constants.SompiPerKaspi)
+	}
+	output.Value -= fee
+	return nil
+}
+
+// subtractFeeFromPayments divides fee between the given payments and deducts each payment's share from its amount
+func subtractFeeFromPayments(payments []*libkaspiwallet.Payment, fee uint64) error {
+	share := fee / uint64(len(payments))
+	remainder := fee % uint64(len(payments))
+	for i, payment := range payments {
+		paymentFee := share
+		if uint64(i) < remainder {
+			paymentFee++
+		}
+		if payment.Amount <= paymentFee {
+			return errors.Errorf("payment of %f to %s can't pay its share of the fee, which is %f",
+				float64(payment.Amount)/constants.SompiPerKaspi, payment.Address,
+				float64(paymentFee)/constants.SompiPerKaspi)
+		}
+		payment.Amount -= paymentFee
+	}
+	return nil
+}
+
+// transactionsFeeAndMass returns the total fee and the total estimated mass after signing of the given transactions
+func (s *server) transactionsFeeAndMass(transactionsBytes [][]byte) (fee uint64, mass uint64, err error) {
+	for _, transactionBytes := range transactionsBytes {
+		transaction, err := serialization.DeserializePartiallySignedTransaction(transactionBytes)
+		if err != nil {
+			return 0, 0, err
+		}
+		transactionMass, err := s.estimateMassAfterSignatures(transaction)
+		if err != nil {
+			return 0, 0, err
+		}
+		mass += transactionMass
+
+		for _, input := range transaction.PartiallySignedInputs {
+			fee += input.PrevOutput.Value
+		}
+		for _, output := range transaction.Tx.Outputs {
+			fee -= output.Value
+		}
+	}
+	return fee, mass, nil
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/pkg/errors"
+)
+
+func (s *server) RedeemHTLC(_ context.Context, request *pb.RedeemHTLCRequest) (*pb.RedeemHTLCResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	htlc, err := libkaspiwallet.ParseHTLC(s.params, request.Contract)
+	if err != nil {
+		return nil, err
+	}
+	err = htlc.CheckSecret(request.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	txID, amount, fee, err := s.spendHTLC(htlc, request.Secret, request.Password, request.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RedeemHTLCResponse{TxID: txID, Amount: amount, Fee: fee}, nil
+}
+
+func (s *server) RefundHTLC(_ context.Context, request *pb.RefundHTLCRequest) (*pb.RefundHTLCResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	htlc, err := libkaspiwallet.ParseHTLC(s.params, request.Contract)
+	if err != nil {
+		return nil, err
+	}
+
+	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
+	if err != nil {
+		return nil, err
+	}
+	if !htlc.IsLockTimeReached(dagInfo.VirtualDAAScore, dagInfo.PastMedianTime) {
+		if htlc.LockTime < constants.LockTimeThreshold {
+			return nil, errors.Errorf("the contract can't be refunded before DAA score %d, "+
+				"and the virtual DAA score is %d", htlc.LockTime, dagInfo.VirtualDAAScore)
+		}
+		return nil, errors.Errorf("the contract can't be refunded before %s, and the past median time of the DAG "+
+			"is %s", time.UnixMilli(int64(htlc.LockTime)).Format(time.RFC3339),
+			time.UnixMilli(dagInfo.PastMedianTime).Format(time.RFC3339))
+	}
+
+	txID, amount, fee, err := s.spendHTLC(htlc, nil, request.Password, request.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RefundHTLCResponse{TxID: txID, Amount: amount, Fee: fee}, nil
+}
+
+// spendHTLC spends all the UTXOs of a hash time-locked contract to its recipient address if secret is
+// given, or to its refund address otherwise. The address must belong to the wallet.
+func (s *server) spendHTLC(htlc *libkaspiwallet.HTLC, secret []byte, password string, feeRate float64) (
+	txID string, amount uint64, fee uint64, err error) {
+
+	if !s.isSynced() {
+		return "", 0, 0, s.notSyncedError()
+	}
+	if s.isMultisig() {
+		return "", 0, 0, errors.Errorf("hash time-locked contracts can't be spent by a multisig wallet")
+	}
+	if s.keysFile.IsWatchOnly() {
+		return "", 0, 0, errors.Errorf("cannot sign with a watch-only wallet, since it has no private keys")
+	}
+
+	isRefund := secret == nil
+	address := htlc.RecipientAddress
+	if isRefund {
+		address = htlc.RefundAddress
+	}
+	wAddr, ok, err := s.walletAddressByString(address.String())
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if !ok {
+		return "", 0, 0, errors.Errorf("address %s of the contract is not an address of the wallet", address)
+	}
+
+	fees, err := newFeeOptions(feeRate, 0, false)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	utxos, err := s.htlcUTXOs(htlc)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	mnemonics, passphrases, err := s.decryptMnemonics(password)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	// The transaction is signed once without a fee to find its mass, and then again with the fee the mass requires
+	createSignedTransaction := func(fee uint64) (*externalapi.DomainTransaction, error) {
+		tx, err := libkaspiwallet.CreateHTLCSpendTransaction(htlc, utxos, fee, isRefund)
+		if err != nil {
+			return nil, err
+		}
+		err = libkaspiwallet.SignHTLCSpendTransaction(s.params, tx, htlc, mnemonics[0], passphrases[0],
+			s.walletAddressDerivationPath(wAddr), secret)
+		if err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+	transaction, err := createSignedTransaction(0)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	fee = fees.fee(s.txMassCalculator.CalculateTransactionMass(transaction))
+	transaction, err = createSignedTransaction(fee)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	serializedTransaction, err := serialization.SerializeDomainTransaction(transaction)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	txIDs, err := s.broadcast([][]byte{serializedTransaction}, true)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return txIDs[0], transaction.Outputs[0].Value, fee, nil
+}
+
+// htlcUTXOs returns the UTXOs of the P2SH address of a hash time-locked contract
+func (s *server) htlcUTXOs(htlc *libkaspiwallet.HTLC) ([]*libkaspiwallet.UTXO, error) {
+	contractAddress, err := htlc.Address(s.params)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.rpcClient.GetUTXOsByAddresses([]string{contractAddress.String()})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Entries) == 0 {
+		return nil, errors.Errorf("the contract address %s has no UTXOs. Either the contract wasn't accepted "+
+			"by the DAG yet, or it was already spent", contractAddress)
+	}
+
+	utxos := make([]*libkaspiwallet.UTXO, len(response.Entries))
+	for i, entry := range response.Entries {
+		outpoint, err := appmessage.RPCOutpointToDomainOutpoint(entry.Outpoint)
+		if err != nil {
+			return nil, err
+		}
+		utxoEntry, err := appmessage.RPCUTXOEntryToUTXOEntry(entry.UTXOEntry)
+		if err != nil {
+			return nil, err
+		}
+		utxos[i] = &libkaspiwallet.UTXO{Outpoint: outpoint, UTXOEntry: utxoEntry}
+	}
+	return utxos, nil
+}
+
+func (s *server) GetHTLCSecret(_ context.Context, request *pb.GetHTLCSecretRequest) (*pb.GetHTLCSecretResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	htlc, err := libkaspiwallet.ParseHTLC(s.params, request.Contract)
+	if err != nil {
+		return nil, err
+	}
+	contractAddress, err := htlc.Address(s.params)
+	if err != nil {
+		return nil, err
+	}
+
+	startBlockHash := request.StartBlockHash
+	if startBlockHash == "" {
+		selectedTip, err := s.rpcClient.GetSelectedTipHash()
+		if err != nil {
+			return nil, err
+		}
+		startBlockHash = selectedTip.SelectedTipHash
+	}
+
+	mempoolEntries, err := s.rpcClient.GetMempoolEntriesByAddresses([]string{contractAddress.String()}, false, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range mempoolEntries.Entries {
+		for _, mempoolEntry := range entry.Sending {
+			secret, err := htlcSecretFromTransaction(mempoolEntry.Transaction, htlc)
+			if err != nil {
+				return nil, err
+			}
+			if secret != nil {
+				return &pb.GetHTLCSecretResponse{Secret: secret, TxID: rpcTransactionID(mempoolEntry.Transaction)}, nil
+			}
+		}
+	}
+
+	// Every block is merged by exactly one chain block, so going over the merge sets of the chain
+	// blocks added since startBlockHash goes over all the blocks added since it
+	chain, err := s.rpcClient.GetVirtualSelectedParentChainFromBlock(startBlockHash, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, chainBlockHash := range chain.AddedChainBlockHashes {
+		chainBlock, err := s.rpcClient.GetBlock(chainBlockHash, true)
+		if err != nil {
+			return nil, err
+		}
+		blocks := []*appmessage.RPCBlock{chainBlock.Block}
+		verboseData := chainBlock.Block.VerboseData
+		for _, blockHash := range append(append([]string{}, verboseData.MergeSetBluesHashes...), verboseData.MergeSetRedsHashes...) {
+			// The selected parent is a chain block itself, which was already searched
+			if blockHash == verboseData.SelectedParentHash {
+				continue
+			}
+			block, err := s.rpcClient.GetBlock(blockHash, true)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block.Block)
+		}
+
+		for _, block := range blocks {
+			for _, transaction := range block.Transactions {
+				secret, err := htlcSecretFromTransaction(transaction, htlc)
+				if err != nil {
+					return nil, err
+				}
+				if secret != nil {
+					return &pb.GetHTLCSecretResponse{Secret: secret, TxID: rpcTransactionID(transaction)}, nil
+				}
+			}
+		}
+	}
+
+	nextStartBlockHash := startBlockHash
+	if len(chain.AddedChainBlockHashes) > 0 {
+		nextStartBlockHash = chain.AddedChainBlockHashes[len(chain.AddedChainBlockHashes)-1]
+	}
+	return &pb.GetHTLCSecretResponse{NextStartBlockHash: nextStartBlockHash}, nil
+}
+
+// htlcSecretFromTransaction returns the secret of the contract if the transaction redeems it, or nil otherwise
+func htlcSecretFromTransaction(transaction *appmessage.RPCTransaction, htlc *libkaspiwallet.HTLC) ([]byte, error) {
+	for _, input := range transaction.Inputs {
+		signatureScript, err := hex.DecodeString(input.SignatureScript)
+		if err != nil {
+			return nil, err
+		}
+		secret, contract, err := txscript.ExtractHTLCSecret(signatureScript)
+		if err != nil {
+			// Signature scripts of other transactions may be unparsable, and are just not redeems of the contract
+			continue
+		}
+		if secret != nil && bytes.Equal(contract, htlc.Contract) {
+			return secret, nil
+		}
+	}
+	return nil, nil
+}
+
+func rpcTransactionID(transaction *appmessage.RPCTransaction) string {
+	if transaction.VerboseData != nil {
+		return transaction.VerboseData.TransactionID
+	}
+	domainTransaction, err := appmessage.RPCTransactionToDomainTransaction(transaction)
+	if err != nil {
+		return ""
+	}
+	return consensushashing.TransactionID(domainTransaction).String()
+}
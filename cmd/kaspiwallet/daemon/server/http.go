@@ -0,0 +1,631 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/pkg/errors"
+)
+
+// The HTTP API serves the handlers of the gRPC service as JSON, for clients that can't use gRPC.
+// Its paths are versioned, and its schemas only change in backwards compatible ways within a version.
+//
+// Every request must carry the auth token of the daemon, POST requests must have a Content-Type of
+// application/json, and requests with an Origin header are rejected, so that web pages can't use the API.
+// Amounts are in sompi, and transactions are hex encoded in the kaspiwallet format.
+// Requests that fail are answered with an HTTP error status and a body of the form
+// {"error": {"code": "...", "message": "..."}}, where code is one of the httpErrorCode* constants.
+const (
+	httpPathBalance               = "/v1/balance"
+	httpPathAddresses             = "/v1/addresses"
+	httpPathUTXOs                 = "/v1/utxos"
+	httpPathCreateTransactions    = "/v1/transactions/create"
+	httpPathSignTransactions      = "/v1/transactions/sign"
+	httpPathBroadcastTransactions = "/v1/transactions/broadcast"
+	httpPathSend                  = "/v1/send"
+	httpPathUnlock                = "/v1/unlock"
+	httpPathLock                  = "/v1/lock"
+)
+
+// The error codes of the HTTP API
+const (
+	// httpErrorCodeInvalidRequest is returned for requests that can't be parsed
+	httpErrorCodeInvalidRequest = "invalid_request"
+	// httpErrorCodeUnauthenticated is returned for requests without the auth token of the daemon
+	httpErrorCodeUnauthenticated = "unauthenticated"
+	// httpErrorCodeCrossOriginRequest is returned for requests that browsers send on behalf of web pages
+	httpErrorCodeCrossOriginRequest = "cross_origin_request"
+	// httpErrorCodeUnsupportedMediaType is returned for POST requests whose Content-Type isn't application/json
+	httpErrorCodeUnsupportedMediaType = "unsupported_media_type"
+	httpErrorCodeNotFound             = "not_found"
+	httpErrorCodeMethodNotAllowed     = "method_not_allowed"
+	// httpErrorCodeNotSynced is returned until the daemon finishes syncing the wallet
+	httpErrorCodeNotSynced = "not_synced"
+	// httpErrorCodeWalletLocked is returned for requests without a password while the wallet is locked
+	httpErrorCodeWalletLocked      = "wallet_locked"
+	httpErrorCodeInvalidPassword   = "invalid_password"
+	httpErrorCodeInsufficientFunds = "insufficient_funds"
+	// httpErrorCodeRequestFailed is returned for any other error, which is described by the error message
+	httpErrorCodeRequestFailed = "request_failed"
+)
+
+const (
+	// httpStopTimeout is how long the HTTP server waits for the requests it serves to finish when it stops
+	httpStopTimeout = 2 * time.Second
+	// httpReadHeaderTimeout is how long the HTTP server waits for the headers of a request
+	httpReadHeaderTimeout = 10 * time.Second
+)
+
+// httpError is an error of the HTTP API, with the status and the error code it's answered with
+type httpError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func newInvalidRequestError(format string, args ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, code: httpErrorCodeInvalidRequest,
+		message: fmt.Sprintf(format, args...)}
+}
+
+// toHTTPError returns the status and the error code an error of a handler is answered with
+func toHTTPError(err error) *httpError {
+	var target *httpError
+	if errors.As(err, &target) {
+		return target
+	}
+
+	status, code := http.StatusBadRequest, httpErrorCodeRequestFailed
+	switch {
+	case errors.Is(err, errNotSynced):
+		status, code = http.StatusServiceUnavailable, httpErrorCodeNotSynced
+	case errors.Is(err, errWalletLocked):
+		status, code = http.StatusForbidden, httpErrorCodeWalletLocked
+	case errors.Is(err, errInvalidPassword):
+		status, code = http.StatusForbidden, httpErrorCodeInvalidPassword
+	case errors.Is(err, errInsufficientFunds):
+		status, code = http.StatusUnprocessableEntity, httpErrorCodeInsufficientFunds
+	}
+	return &httpError{status: status, code: code, message: err.Error()}
+}
+
+type httpErrorResponse struct {
+	Error httpErrorBody `json:"error"`
+}
+
+type httpErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeHTTPError(writer http.ResponseWriter, err error) {
+	httpErr := toHTTPError(err)
+	writeJSON(writer, httpErr.status, &httpErrorResponse{Error: httpErrorBody{Code: httpErr.code, Message: httpErr.message}})
+}
+
+func writeJSON(writer http.ResponseWriter, status int, response interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	err := json.NewEncoder(writer).Encode(response)
+	if err != nil {
+		log.Warnf("Error writing an HTTP response: %s", err)
+	}
+}
+
+// httpHandlerFunc serves a request of the HTTP API, and returns the response to encode as JSON
+type httpHandlerFunc func(ctx context.Context, request *http.Request) (interface{}, error)
+
+// httpHandler returns the handler of the HTTP API, which enforces the security of the daemon
+func (s *server) httpHandler(security *daemonSecurity) http.Handler {
+	mux := http.NewServeMux()
+	handle := func(path string, handlers map[string]httpHandlerFunc) {
+		mux.HandleFunc(path, func(writer http.ResponseWriter, request *http.Request) {
+			handler, ok := handlers[request.Method]
+			if !ok {
+				allowedMethods := make([]string, 0, len(handlers))
+				for method := range handlers {
+					allowedMethods = append(allowedMethods, method)
+				}
+				sort.Strings(allowedMethods)
+				writer.Header().Set("Allow", strings.Join(allowedMethods, ", "))
+				writeHTTPError(writer, &httpError{status: http.StatusMethodNotAllowed, code: httpErrorCodeMethodNotAllowed,
+					message: fmt.Sprintf("%s only supports %s", path, strings.Join(allowedMethods, " and "))})
+				return
+			}
+
+			response, err := handler(request.Context(), request)
+			if err != nil {
+				writeHTTPError(writer, err)
+				return
+			}
+			writeJSON(writer, http.StatusOK, response)
+		})
+	}
+	handle(httpPathBalance, map[string]httpHandlerFunc{http.MethodGet: s.httpGetBalance})
+	handle(httpPathAddresses, map[string]httpHandlerFunc{
+		http.MethodGet:  s.httpShowAddresses,
+		http.MethodPost: s.httpNewAddress,
+	})
+	handle(httpPathUTXOs, map[string]httpHandlerFunc{http.MethodGet: s.httpGetUTXOs})
+	handle(httpPathCreateTransactions, map[string]httpHandlerFunc{http.MethodPost: s.httpCreateTransactions})
+	handle(httpPathSignTransactions, map[string]httpHandlerFunc{http.MethodPost: s.httpSignTransactions})
+	handle(httpPathBroadcastTransactions, map[string]httpHandlerFunc{http.MethodPost: s.httpBroadcastTransactions})
+	handle(httpPathSend, map[string]httpHandlerFunc{http.MethodPost: s.httpSend})
+	handle(httpPathUnlock, map[string]httpHandlerFunc{http.MethodPost: s.httpUnlock})
+	handle(httpPathLock, map[string]httpHandlerFunc{http.MethodPost: s.httpLock})
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		writeHTTPError(writer, &httpError{status: http.StatusNotFound, code: httpErrorCodeNotFound,
+			message: fmt.Sprintf("%s is not a path of the API", request.URL.Path)})
+	})
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// The API is for backends, not for browsers, so requests that web pages make browsers send are
+		// rejected, along with requests that browsers send without a CORS preflight check
+		if request.Header.Get("Origin") != "" {
+			writeHTTPError(writer, &httpError{status: http.StatusForbidden, code: httpErrorCodeCrossOriginRequest,
+				message: "requests with an Origin header aren't accepted"})
+			return
+		}
+		// The HTTP API can't be served without a token, so a missing authenticator rejects every request
+		if security.authenticator == nil || !security.authenticator.isAuthorizedHTTPRequest(request) {
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			writeHTTPError(writer, &httpError{status: http.StatusUnauthorized, code: httpErrorCodeUnauthenticated,
+				message: "missing or invalid auth token"})
+			return
+		}
+		if request.Method == http.MethodPost && !isJSONContentType(request.Header.Get("Content-Type")) {
+			writeHTTPError(writer, &httpError{status: http.StatusUnsupportedMediaType,
+				code: httpErrorCodeUnsupportedMediaType, message: "the Content-Type of requests must be application/json"})
+			return
+		}
+		request.Body = http.MaxBytesReader(writer, request.Body, MaxDaemonSendMsgSize)
+		mux.ServeHTTP(writer, request)
+	})
+}
+
+func (ta *tokenAuthenticator) isAuthorizedHTTPRequest(request *http.Request) bool {
+	for _, authorization := range request.Header.Values("Authorization") {
+		if ta.isAuthorized(authorization) {
+			return true
+		}
+	}
+	return false
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// decodeHTTPRequest decodes the JSON body of a request into target. An empty body leaves target as it is,
+// and unknown fields are rejected, so that misspelled fields aren't ignored silently.
+func decodeHTTPRequest(request *http.Request, target interface{}) error {
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(target)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return newInvalidRequestError("invalid JSON request: %s", err)
+	}
+	if decoder.More() {
+		return newInvalidRequestError("invalid JSON request: unexpected data after the request object")
+	}
+	return nil
+}
+
+func decodeHexTransactions(transactionsHex []string) ([][]byte, error) {
+	transactions := make([][]byte, len(transactionsHex))
+	for i, transactionHex := range transactionsHex {
+		transaction, err := hex.DecodeString(transactionHex)
+		if err != nil {
+			return nil, newInvalidRequestError("transaction #%d isn't hex encoded: %s", i, err)
+		}
+		transactions[i] = transaction
+	}
+	return transactions, nil
+}
+
+func encodeHexTransactions(transactions [][]byte) []string {
+	transactionsHex := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		transactionsHex[i] = hex.EncodeToString(transaction)
+	}
+	return transactionsHex
+}
+
+type httpBalanceResponse struct {
+	Available  uint64                `json:"available"`
+	Pending    uint64                `json:"pending"`
+	TimeLocked uint64                `json:"time_locked"`
+	Accounts   []*httpAccountBalance `json:"accounts"`
+	Addresses  []*httpAddressBalance `json:"addresses"`
+}
+
+type httpAccountBalance struct {
+	Account    string `json:"account"`
+	Available  uint64 `json:"available"`
+	Pending    uint64 `json:"pending"`
+	TimeLocked uint64 `json:"time_locked"`
+}
+
+type httpAddressBalance struct {
+	Address    string `json:"address"`
+	Account    string `json:"account"`
+	Label      string `json:"label"`
+	Available  uint64 `json:"available"`
+	Pending    uint64 `json:"pending"`
+	TimeLocked uint64 `json:"time_locked"`
+	// LockDAAScore is the DAA score a time-locked address is locked until, or 0 for other addresses
+	LockDAAScore uint64 `json:"lock_daa_score"`
+}
+
+func (s *server) httpGetBalance(ctx context.Context, request *http.Request) (interface{}, error) {
+	balance, err := s.GetBalance(ctx, &pb.GetBalanceRequest{Account: request.URL.Query().Get("account")})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &httpBalanceResponse{
+		Available:  balance.Available,
+		Pending:    balance.Pending,
+		TimeLocked: balance.TimeLocked,
+		Accounts:   make([]*httpAccountBalance, len(balance.AccountBalances)),
+		Addresses:  make([]*httpAddressBalance, len(balance.AddressBalances)),
+	}
+	for i, accountBalance := range balance.AccountBalances {
+		response.Accounts[i] = &httpAccountBalance{
+			Account:    accountBalance.Account,
+			Available:  accountBalance.Available,
+			Pending:    accountBalance.Pending,
+			TimeLocked: accountBalance.TimeLocked,
+		}
+	}
+	for i, addressBalance := range balance.AddressBalances {
+		response.Addresses[i] = &httpAddressBalance{
+			Address:      addressBalance.Address,
+			Account:      addressBalance.Account,
+			Label:        addressBalance.Label,
+			Available:    addressBalance.Available,
+			Pending:      addressBalance.Pending,
+			TimeLocked:   addressBalance.TimeLocked,
+			LockDAAScore: addressBalance.LockDaaScore,
+		}
+	}
+	return response, nil
+}
+
+type httpAddressesResponse struct {
+	Addresses []*httpAddress `json:"addresses"`
+}
+
+type httpAddress struct {
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+func (s *server) httpShowAddresses(ctx context.Context, request *http.Request) (interface{}, error) {
+	addresses, err := s.ShowAddresses(ctx, &pb.ShowAddressesRequest{Account: request.URL.Query().Get("account")})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &httpAddressesResponse{Addresses: make([]*httpAddress, len(addresses.Address))}
+	for i, address := range addresses.Address {
+		response.Addresses[i] = &httpAddress{Address: address}
+		if i < len(addresses.Labels) {
+			response.Addresses[i].Label = addresses.Labels[i]
+		}
+	}
+	return response, nil
+}
+
+type httpNewAddressRequest struct {
+	Account string `json:"account"`
+}
+
+type httpNewAddressResponse struct {
+	Address string `json:"address"`
+}
+
+func (s *server) httpNewAddress(ctx context.Context, request *http.Request) (interface{}, error) {
+	newAddressRequest := &httpNewAddressRequest{}
+	err := decodeHTTPRequest(request, newAddressRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	newAddress, err := s.NewAddress(ctx, &pb.NewAddressRequest{Account: newAddressRequest.Account})
+	if err != nil {
+		return nil, err
+	}
+	return &httpNewAddressResponse{Address: newAddress.Address}, nil
+}
+
+type httpOutpoint struct {
+	TransactionID string `json:"transaction_id"`
+	Index         uint32 `json:"index"`
+}
+
+type httpUTXOsResponse struct {
+	UTXOs []*httpUTXO `json:"utxos"`
+}
+
+type httpUTXO struct {
+	Outpoint      httpOutpoint `json:"outpoint"`
+	Address       string       `json:"address"`
+	Account       string       `json:"account"`
+	Amount        uint64       `json:"amount"`
+	BlockDAAScore uint64       `json:"block_daa_score"`
+	IsCoinbase    bool         `json:"is_coinbase"`
+	IsSpendable   bool         `json:"is_spendable"`
+	IsPending     bool         `json:"is_pending"`
+	IsLocked      bool         `json:"is_locked"`
+	// Label is the label the UTXO was locked with
+	Label         string `json:"label"`
+	AddressLabel  string `json:"address_label"`
+	OutpointLabel string `json:"outpoint_label"`
+	LockDAAScore  uint64 `json:"lock_daa_score"`
+}
+
+// httpGetUTXOs serves the UTXOs of the wallet, limited to the account and the addresses given
+// with the account and address query parameters. address may be repeated.
+func (s *server) httpGetUTXOs(ctx context.Context, request *http.Request) (interface{}, error) {
+	query := request.URL.Query()
+	utxos, err := s.GetUtxos(ctx, &pb.GetUtxosRequest{Account: query.Get("account"), Addresses: query["address"]})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &httpUTXOsResponse{UTXOs: make([]*httpUTXO, len(utxos.Utxos))}
+	for i, utxo := range utxos.Utxos {
+		response.UTXOs[i] = &httpUTXO{
+			Outpoint:      httpOutpoint{TransactionID: utxo.Outpoint.TransactionId, Index: utxo.Outpoint.Index},
+			Address:       utxo.Address,
+			Account:       utxo.Account,
+			Amount:        utxo.Amount,
+			BlockDAAScore: utxo.BlockDaaScore,
+			IsCoinbase:    utxo.IsCoinbase,
+			IsSpendable:   utxo.IsSpendable,
+			IsPending:     utxo.IsPending,
+			IsLocked:      utxo.IsLocked,
+			Label:         utxo.Label,
+			AddressLabel:  utxo.AddressLabel,
+			OutpointLabel: utxo.OutpointLabel,
+			LockDAAScore:  utxo.LockDaaScore,
+		}
+	}
+	return response, nil
+}
+
+// httpCreateTransactionsRequest has the fields of CreateUnsignedTransactionsRequest
+type httpCreateTransactionsRequest struct {
+	Account   string `json:"account"`
+	ToAddress string `json:"to_address"`
+	Amount    uint64 `json:"amount"`
+	// Outputs, if set, replaces ToAddress and Amount in order to pay several recipients
+	Outputs                  []*httpPaymentOutput `json:"outputs"`
+	From                     []string             `json:"from"`
+	UTXOs                    []*httpOutpoint      `json:"utxos"`
+	SendAll                  bool                 `json:"send_all"`
+	UseExistingChangeAddress bool                 `json:"use_existing_change_address"`
+	FeeRate                  float64              `json:"fee_rate"`
+	MaxFee                   uint64               `json:"max_fee"`
+	SubtractFeeFromAmount    bool                 `json:"subtract_fee_from_amount"`
+}
+
+type httpPaymentOutput struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+func (r *httpCreateTransactionsRequest) outputs() []*pb.PaymentOutput {
+	if r.Outputs == nil {
+		return nil
+	}
+	outputs := make([]*pb.PaymentOutput, len(r.Outputs))
+	for i, output := range r.Outputs {
+		outputs[i] = &pb.PaymentOutput{Address: output.Address, Amount: output.Amount}
+	}
+	return outputs
+}
+
+func (r *httpCreateTransactionsRequest) utxos() []*pb.Outpoint {
+	if r.UTXOs == nil {
+		return nil
+	}
+	utxos := make([]*pb.Outpoint, len(r.UTXOs))
+	for i, utxo := range r.UTXOs {
+		utxos[i] = &pb.Outpoint{TransactionId: utxo.TransactionID, Index: utxo.Index}
+	}
+	return utxos
+}
+
+type httpCreateTransactionsResponse struct {
+	UnsignedTransactions []string `json:"unsigned_transactions"`
+	Fee                  uint64   `json:"fee"`
+	Mass                 uint64   `json:"mass"`
+}
+
+func (s *server) httpCreateTransactions(ctx context.Context, request *http.Request) (interface{}, error) {
+	createRequest := &httpCreateTransactionsRequest{}
+	err := decodeHTTPRequest(request, createRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.CreateUnsignedTransactions(ctx, &pb.CreateUnsignedTransactionsRequest{
+		Account:                  createRequest.Account,
+		Address:                  createRequest.ToAddress,
+		Amount:                   createRequest.Amount,
+		Outputs:                  createRequest.outputs(),
+		From:                     createRequest.From,
+		Utxos:                    createRequest.utxos(),
+		IsSendAll:                createRequest.SendAll,
+		UseExistingChangeAddress: createRequest.UseExistingChangeAddress,
+		FeeRate:                  createRequest.FeeRate,
+		MaxFee:                   createRequest.MaxFee,
+		SubtractFeeFromAmount:    createRequest.SubtractFeeFromAmount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &httpCreateTransactionsResponse{
+		UnsignedTransactions: encodeHexTransactions(created.UnsignedTransactions),
+		Fee:                  created.Fee,
+		Mass:                 created.Mass,
+	}, nil
+}
+
+type httpSignTransactionsRequest struct {
+	UnsignedTransactions []string `json:"unsigned_transactions"`
+	// Password can be left empty while the wallet is unlocked
+	Password string `json:"password"`
+}
+
+type httpSignTransactionsResponse struct {
+	SignedTransactions []string `json:"signed_transactions"`
+}
+
+func (s *server) httpSignTransactions(ctx context.Context, request *http.Request) (interface{}, error) {
+	signRequest := &httpSignTransactionsRequest{}
+	err := decodeHTTPRequest(request, signRequest)
+	if err != nil {
+		return nil, err
+	}
+	unsignedTransactions, err := decodeHexTransactions(signRequest.UnsignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := s.Sign(ctx, &pb.SignRequest{UnsignedTransactions: unsignedTransactions, Password: signRequest.Password})
+	if err != nil {
+		return nil, err
+	}
+	return &httpSignTransactionsResponse{SignedTransactions: encodeHexTransactions(signed.SignedTransactions)}, nil
+}
+
+type httpBroadcastTransactionsRequest struct {
+	SignedTransactions []string `json:"signed_transactions"`
+}
+
+type httpBroadcastTransactionsResponse struct {
+	TransactionIDs []string `json:"transaction_ids"`
+}
+
+func (s *server) httpBroadcastTransactions(ctx context.Context, request *http.Request) (interface{}, error) {
+	broadcastRequest := &httpBroadcastTransactionsRequest{}
+	err := decodeHTTPRequest(request, broadcastRequest)
+	if err != nil {
+		return nil, err
+	}
+	signedTransactions, err := decodeHexTransactions(broadcastRequest.SignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	broadcast, err := s.Broadcast(ctx, &pb.BroadcastRequest{Transactions: signedTransactions})
+	if err != nil {
+		return nil, err
+	}
+	return &httpBroadcastTransactionsResponse{TransactionIDs: broadcast.TxIDs}, nil
+}
+
+type httpSendRequest struct {
+	httpCreateTransactionsRequest
+	// Password can be left empty while the wallet is unlocked
+	Password string `json:"password"`
+}
+
+type httpSendResponse struct {
+	TransactionIDs     []string `json:"transaction_ids"`
+	SignedTransactions []string `json:"signed_transactions"`
+	Fee                uint64   `json:"fee"`
+	Mass               uint64   `json:"mass"`
+}
+
+func (s *server) httpSend(ctx context.Context, request *http.Request) (interface{}, error) {
+	sendRequest := &httpSendRequest{}
+	err := decodeHTTPRequest(request, sendRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	sent, err := s.Send(ctx, &pb.SendRequest{
+		Account:                  sendRequest.Account,
+		ToAddress:                sendRequest.ToAddress,
+		Amount:                   sendRequest.Amount,
+		Outputs:                  sendRequest.outputs(),
+		From:                     sendRequest.From,
+		Utxos:                    sendRequest.utxos(),
+		IsSendAll:                sendRequest.SendAll,
+		UseExistingChangeAddress: sendRequest.UseExistingChangeAddress,
+		FeeRate:                  sendRequest.FeeRate,
+		MaxFee:                   sendRequest.MaxFee,
+		SubtractFeeFromAmount:    sendRequest.SubtractFeeFromAmount,
+		Password:                 sendRequest.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &httpSendResponse{
+		TransactionIDs:     sent.TxIDs,
+		SignedTransactions: encodeHexTransactions(sent.SignedTransactions),
+		Fee:                sent.Fee,
+		Mass:               sent.Mass,
+	}, nil
+}
+
+type httpUnlockRequest struct {
+	Password string `json:"password"`
+	// TimeoutSeconds is how long the wallet stays unlocked
+	TimeoutSeconds uint32 `json:"timeout_seconds"`
+}
+
+type httpUnlockResponse struct {
+	// UnlockedUntil is when the wallet is locked again, in RFC 3339 format
+	UnlockedUntil string `json:"unlocked_until"`
+}
+
+func (s *server) httpUnlock(ctx context.Context, request *http.Request) (interface{}, error) {
+	unlockRequest := &httpUnlockRequest{}
+	err := decodeHTTPRequest(request, unlockRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	unlocked, err := s.UnlockWallet(ctx, &pb.UnlockWalletRequest{
+		Password: unlockRequest.Password,
+		Timeout:  unlockRequest.TimeoutSeconds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &httpUnlockResponse{
+		UnlockedUntil: time.UnixMilli(unlocked.UnlockedUntil).UTC().Format(time.RFC3339),
+	}, nil
+}
+
+type httpLockResponse struct{}
+
+func (s *server) httpLock(ctx context.Context, _ *http.Request) (interface{}, error) {
+	_, err := s.LockWallet(ctx, &pb.LockWalletRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &httpLockResponse{}, nil
+}
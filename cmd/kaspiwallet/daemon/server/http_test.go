@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/pkg/errors"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	keysFile, err := keys.NewFileFromMnemonic(params, mnemonic, "password")
+	if err != nil {
+		t.Fatalf("NewFileFromMnemonic: %s", err)
+	}
+	// The server didn't sync yet, so the requests that need the UTXOs of the wallet fail
+	serverInstance := &server{params: params, keysFile: keysFile, addressSet: make(walletAddressSet)}
+	const token = "token"
+	const authorization = "Bearer " + token
+	const jsonContentType = "application/json; charset=utf-8"
+	const sendBody = `{"to_address": "kaspidev:qqyx9c7a0lcqjxp6khz5ymq6cp6fmw8pd6fsh5ckfnkwc7cjcl8uvq2mv9x3p", "amount": 1}`
+	handler := serverInstance.httpHandler(&daemonSecurity{authenticator: &tokenAuthenticator{token: []byte(token)}})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		authorization  string
+		contentType    string
+		origin         string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"no token", http.MethodGet, httpPathBalance, "", "", jsonContentType, "",
+			http.StatusUnauthorized, httpErrorCodeUnauthenticated},
+		{"wrong token", http.MethodGet, httpPathBalance, "", "Bearer wrong", jsonContentType, "",
+			http.StatusUnauthorized, httpErrorCodeUnauthenticated},
+		{"not synced", http.MethodGet, httpPathBalance, "", authorization, jsonContentType, "",
+			http.StatusServiceUnavailable, httpErrorCodeNotSynced},
+		{"unknown path", http.MethodGet, "/v1/unknown", "", authorization, jsonContentType, "",
+			http.StatusNotFound, httpErrorCodeNotFound},
+		{"wrong method", http.MethodGet, httpPathSend, "", authorization, jsonContentType, "",
+			http.StatusMethodNotAllowed, httpErrorCodeMethodNotAllowed},
+		{"unknown field", http.MethodPost, httpPathSend, `{"to_adress": "kaspidev:qq"}`, authorization,
+			jsonContentType, "", http.StatusBadRequest, httpErrorCodeInvalidRequest},
+		{"invalid JSON", http.MethodPost, httpPathSend, `{"amount": "1"}`, authorization, jsonContentType, "",
+			http.StatusBadRequest, httpErrorCodeInvalidRequest},
+		{"invalid hex", http.MethodPost, httpPathSignTransactions, `{"unsigned_transactions": ["zz"]}`,
+			authorization, jsonContentType, "", http.StatusBadRequest, httpErrorCodeInvalidRequest},
+		{"locked wallet", http.MethodPost, httpPathSignTransactions, `{"unsigned_transactions": []}`,
+			authorization, jsonContentType, "", http.StatusForbidden, httpErrorCodeWalletLocked},
+		{"wrong password", http.MethodPost, httpPathUnlock, `{"password": "wrong", "timeout_seconds": 60}`,
+			authorization, jsonContentType, "", http.StatusForbidden, httpErrorCodeInvalidPassword},
+		// A web page can make a browser send a text/plain POST to the daemon without a CORS preflight check
+		{"cross-origin text/plain POST", http.MethodPost, httpPathSend, sendBody, "", "text/plain",
+			"https://example.com", http.StatusForbidden, httpErrorCodeCrossOriginRequest},
+		{"unauthenticated text/plain POST", http.MethodPost, httpPathSend, sendBody, "", "text/plain", "",
+			http.StatusUnauthorized, httpErrorCodeUnauthenticated},
+		{"cross-origin request with a token", http.MethodGet, httpPathBalance, "", authorization, "",
+			"https://example.com", http.StatusForbidden, httpErrorCodeCrossOriginRequest},
+		{"text/plain POST with a token", http.MethodPost, httpPathSend, sendBody, authorization, "text/plain", "",
+			http.StatusUnsupportedMediaType, httpErrorCodeUnsupportedMediaType},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.authorization != "" {
+			request.Header.Set("Authorization", test.authorization)
+		}
+		if test.contentType != "" {
+			request.Header.Set("Content-Type", test.contentType)
+		}
+		if test.origin != "" {
+			request.Header.Set("Origin", test.origin)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.expectedStatus {
+			t.Fatalf("%s: the status is %d, while %d is expected", test.name, recorder.Code, test.expectedStatus)
+		}
+		response := &httpErrorResponse{}
+		err := json.NewDecoder(recorder.Body).Decode(response)
+		if err != nil {
+			t.Fatalf("%s: the error response isn't JSON: %s", test.name, err)
+		}
+		if response.Error.Code != test.expectedCode || response.Error.Message == "" {
+			t.Fatalf("%s: the error is %+v, while the code %s is expected", test.name, response.Error,
+				test.expectedCode)
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodPost, httpPathUnlock, strings.NewReader(
+		`{"password": "password", "timeout_seconds": 60}`))
+	request.Header.Set("Authorization", authorization)
+	request.Header.Set("Content-Type", jsonContentType)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unlocking is expected to succeed, but got %d: %s", recorder.Code, recorder.Body)
+	}
+	unlockResponse := &httpUnlockResponse{}
+	err = json.NewDecoder(recorder.Body).Decode(unlockResponse)
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+	if unlockResponse.UnlockedUntil == "" || !serverInstance.isUnlocked() {
+		t.Fatalf("the wallet is expected to be unlocked")
+	}
+	serverInstance.lockKeys()
+
+	// Without an auth token the HTTP API serves nothing, since any web page could otherwise use it
+	unauthenticatedHandler := serverInstance.httpHandler(&daemonSecurity{})
+	request = httptest.NewRequest(http.MethodPost, httpPathSend, strings.NewReader(sendBody))
+	request.Header.Set("Content-Type", jsonContentType)
+	recorder = httptest.NewRecorder()
+	unauthenticatedHandler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("a request to an HTTP API without an auth token is expected to be rejected, but got %d",
+			recorder.Code)
+	}
+}
+
+func TestToHTTPError(t *testing.T) {
+	tests := []struct {
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{classifyErrorf(errNotSynced, "not synced"), http.StatusServiceUnavailable, httpErrorCodeNotSynced},
+		{classifyErrorf(errInsufficientFunds, "insufficient funds"), http.StatusUnprocessableEntity,
+			httpErrorCodeInsufficientFunds},
+		{errors.Wrap(classifyErrorf(errWalletLocked, "locked"), "wrapped"), http.StatusForbidden,
+			httpErrorCodeWalletLocked},
+		{errors.New("another error"), http.StatusBadRequest, httpErrorCodeRequestFailed},
+	}
+	for _, test := range tests {
+		httpErr := toHTTPError(test.err)
+		if httpErr.status != test.expectedStatus || httpErr.code != test.expectedCode {
+			t.Fatalf("%s is answered with %d %s, while %d %s is expected", test.err, httpErr.status, httpErr.code,
+				test.expectedStatus, test.expectedCode)
+		}
+		if httpErr.message != test.err.Error() {
+			t.Fatalf("the message of %s is expected to be kept, but got %s", test.err, httpErr.message)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/infrastructure/db/database"
+)
+
+var (
+	addressLabelsBucket  = database.MakeBucket([]byte("address-labels"))
+	outpointLabelsBucket = database.MakeBucket([]byte("outpoint-labels"))
+)
+
+// labelStore keeps the labels the user set for addresses and outpoints of the wallet in
+// memory, and writes every change to them through to the wallet database
+type labelStore struct {
+	database       database.Database
+	addressLabels  map[string]string
+	outpointLabels map[externalapi.DomainOutpoint]string
+}
+
+func newLabelStore(db database.Database) (*labelStore, error) {
+	store := &labelStore{
+		database:       db,
+		addressLabels:  make(map[string]string),
+		outpointLabels: make(map[externalapi.DomainOutpoint]string),
+	}
+	err := store.restoreLabels()
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (ls *labelStore) restoreLabels() error {
+	addressCursor, err := ls.database.Cursor(addressLabelsBucket)
+	if err != nil {
+		return err
+	}
+	defer addressCursor.Close()
+	for ok := addressCursor.First(); ok; ok = addressCursor.Next() {
+		key, err := addressCursor.Key()
+		if err != nil {
+			return err
+		}
+		label, err := addressCursor.Value()
+		if err != nil {
+			return err
+		}
+		ls.addressLabels[string(key.Suffix())] = string(label)
+	}
+
+	outpointCursor, err := ls.database.Cursor(outpointLabelsBucket)
+	if err != nil {
+		return err
+	}
+	defer outpointCursor.Close()
+	for ok := outpointCursor.First(); ok; ok = outpointCursor.Next() {
+		key, err := outpointCursor.Key()
+		if err != nil {
+			return err
+		}
+		outpoint, err := deserializeOutpointKey(key.Suffix())
+		if err != nil {
+			return err
+		}
+		label, err := outpointCursor.Value()
+		if err != nil {
+			return err
+		}
+		ls.outpointLabels[*outpoint] = string(label)
+	}
+	return nil
+}
+
+func (ls *labelStore) addressLabel(address string) string {
+	return ls.addressLabels[address]
+}
+
+func (ls *labelStore) outpointLabel(outpoint externalapi.DomainOutpoint) string {
+	return ls.outpointLabels[outpoint]
+}
+
+// setAddressLabel sets the label of the given address, or removes it if label is empty
+func (ls *labelStore) setAddressLabel(address string, label string) error {
+	key := addressLabelsBucket.Key([]byte(address))
+	if label == "" {
+		err := ls.database.Delete(key)
+		if err != nil {
+			return err
+		}
+		delete(ls.addressLabels, address)
+		return nil
+	}
+
+	err := ls.database.Put(key, []byte(label))
+	if err != nil {
+		return err
+	}
+	ls.addressLabels[address] = label
+	return nil
+}
+
+// setOutpointLabel sets the label of the given outpoint, or removes it if label is empty
+func (ls *labelStore) setOutpointLabel(outpoint externalapi.DomainOutpoint, label string) error {
+	key := outpointLabelsBucket.Key(serializeOutpointKey(&outpoint))
+	if label == "" {
+		err := ls.database.Delete(key)
+		if err != nil {
+			return err
+		}
+		delete(ls.outpointLabels, outpoint)
+		return nil
+	}
+
+	err := ls.database.Put(key, []byte(label))
+	if err != nil {
+		return err
+	}
+	ls.outpointLabels[outpoint] = label
+	return nil
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/pkg/errors"
+)
+
+func (s *server) SetLabel(_ context.Context, request *pb.SetLabelRequest) (*pb.SetLabelResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	label := request.Label
+	if label == nil || (label.Address == "") == (label.Outpoint == nil) {
+		return nil, errors.Errorf("a label is set for either an address or an outpoint")
+	}
+
+	if label.Address != "" {
+		isWalletAddress, err := s.isWalletAddress(label.Address)
+		if err != nil {
+			return nil, err
+		}
+		if !isWalletAddress {
+			return nil, errors.Errorf("address %s is not an address of the wallet", label.Address)
+		}
+		err = s.labels.setAddressLabel(label.Address, label.Label)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.SetLabelResponse{}, nil
+	}
+
+	outpoints, err := outpointsFromPB([]*pb.Outpoint{label.Outpoint})
+	if err != nil {
+		return nil, err
+	}
+	outpoint := outpoints[0]
+	if _, ok := s.walletUTXOsByOutpoint()[*outpoint]; !ok {
+		if _, ok := s.labels.outpointLabels[*outpoint]; !ok || label.Label != "" {
+			return nil, errors.Errorf("outpoint %s is not a UTXO of the wallet", outpoint)
+		}
+	}
+	err = s.labels.setOutpointLabel(*outpoint, label.Label)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SetLabelResponse{}, nil
+}
+
+func (s *server) GetLabels(_ context.Context, _ *pb.GetLabelsRequest) (*pb.GetLabelsResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	labels := make([]*pb.Label, 0, len(s.labels.addressLabels)+len(s.labels.outpointLabels))
+	addresses := make([]string, 0, len(s.labels.addressLabels))
+	for address := range s.labels.addressLabels {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		labels = append(labels, &pb.Label{Address: address, Label: s.labels.addressLabels[address]})
+	}
+
+	outpoints := make([]externalapi.DomainOutpoint, 0, len(s.labels.outpointLabels))
+	for outpoint := range s.labels.outpointLabels {
+		outpoints = append(outpoints, outpoint)
+	}
+	sort.Slice(outpoints, func(i, j int) bool { return outpoints[i].String() < outpoints[j].String() })
+	for _, outpoint := range outpoints {
+		labels = append(labels, &pb.Label{
+			Outpoint: &pb.Outpoint{TransactionId: outpoint.TransactionID.String(), Index: outpoint.Index},
+			Label:    s.labels.outpointLabels[outpoint],
+		})
+	}
+	return &pb.GetLabelsResponse{Labels: labels}, nil
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/infrastructure/db/database"
+	"github.com/pkg/errors"
+)
+
+var lockedOutpointsBucket = database.MakeBucket([]byte("locked-outpoints"))
+
+// lockedOutpoint is a wallet outpoint that the user froze, so that it's never
+// spent unless it's unlocked first
+type lockedOutpoint struct {
+	Label string `json:"label"`
+	// LockTime is the time the outpoint was locked at, in milliseconds since the epoch
+	LockTime int64 `json:"lockTime"`
+}
+
+// lockedOutpointStore keeps the locked outpoints of the wallet in memory, and
+// writes every change to them through to the wallet database
+type lockedOutpointStore struct {
+	database        database.Database
+	lockedOutpoints map[externalapi.DomainOutpoint]*lockedOutpoint
+}
+
+func newLockedOutpointStore(db database.Database) (*lockedOutpointStore, error) {
+	store := &lockedOutpointStore{
+		database:        db,
+		lockedOutpoints: make(map[externalapi.DomainOutpoint]*lockedOutpoint),
+	}
+	err := store.restoreLockedOutpoints()
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (los *lockedOutpointStore) restoreLockedOutpoints() error {
+	cursor, err := los.database.Cursor(lockedOutpointsBucket)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return err
+		}
+		outpoint, err := deserializeOutpointKey(key.Suffix())
+		if err != nil {
+			return err
+		}
+		serializedLockedOutpoint, err := cursor.Value()
+		if err != nil {
+			return err
+		}
+		locked := &lockedOutpoint{}
+		err = json.Unmarshal(serializedLockedOutpoint, locked)
+		if err != nil {
+			return errors.Wrap(err, "error deserializing a locked outpoint")
+		}
+		los.lockedOutpoints[*outpoint] = locked
+	}
+	return nil
+}
+
+func (los *lockedOutpointStore) get(outpoint externalapi.DomainOutpoint) (*lockedOutpoint, bool) {
+	locked, ok := los.lockedOutpoints[outpoint]
+	return locked, ok
+}
+
+func (los *lockedOutpointStore) lock(outpoint externalapi.DomainOutpoint, locked *lockedOutpoint) error {
+	serializedLockedOutpoint, err := json.Marshal(locked)
+	if err != nil {
+		return err
+	}
+	err = los.database.Put(lockedOutpointsBucket.Key(serializeOutpointKey(&outpoint)), serializedLockedOutpoint)
+	if err != nil {
+		return err
+	}
+	los.lockedOutpoints[outpoint] = locked
+	return nil
+}
+
+func (los *lockedOutpointStore) unlock(outpoint externalapi.DomainOutpoint) error {
+	err := los.database.Delete(lockedOutpointsBucket.Key(serializeOutpointKey(&outpoint)))
+	if err != nil {
+		return err
+	}
+	delete(los.lockedOutpoints, outpoint)
+	return nil
+}
+
+func serializeOutpointKey(outpoint *externalapi.DomainOutpoint) []byte {
+	key := make([]byte, externalapi.DomainHashSize+4)
+	copy(key, outpoint.TransactionID.ByteSlice())
+	binary.LittleEndian.PutUint32(key[externalapi.DomainHashSize:], outpoint.Index)
+	return key
+}
+
+func deserializeOutpointKey(key []byte) (*externalapi.DomainOutpoint, error) {
+	if len(key) != externalapi.DomainHashSize+4 {
+		return nil, errors.Errorf("invalid outpoint key length %d", len(key))
+	}
+	transactionID, err := externalapi.NewDomainTransactionIDFromByteSlice(key[:externalapi.DomainHashSize])
+	if err != nil {
+		return nil, err
+	}
+	return externalapi.NewDomainOutpoint(transactionID, binary.LittleEndian.Uint32(key[externalapi.DomainHashSize:])), nil
+}
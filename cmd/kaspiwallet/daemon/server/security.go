@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// SecurityConfig secures the connections of the clients of the daemon. Its zero value
+// serves plaintext connections without authentication, which is only safe on localhost.
+type SecurityConfig struct {
+	// TLSCertFile and TLSKeyFile are the certificate and the key the daemon serves TLS with. If neither
+	// of the files exists, a self-signed certificate is generated into them. TLS is off if they're empty.
+	TLSCertFile string
+	TLSKeyFile  string
+	// ClientCAFile, if set, makes the daemon require clients to present a TLS certificate signed by
+	// the CA certificate in it
+	ClientCAFile string
+	// AuthTokenFile, if set, makes the daemon require clients to authenticate with the bearer token
+	// in it. If the file doesn't exist, a random token is generated into it.
+	AuthTokenFile string
+}
+
+// authTokenSize is the size in bytes of a generated auth token, before it's hex encoded
+const authTokenSize = 32
+
+// selfSignedCertificateValidity is how long a generated self-signed certificate is valid for
+const selfSignedCertificateValidity = 10 * 365 * 24 * time.Hour
+
+// daemonSecurity is the loaded security configuration, which the gRPC and the HTTP servers share
+type daemonSecurity struct {
+	tlsConfig     *tls.Config         // nil if TLS is off
+	authenticator *tokenAuthenticator // nil if clients don't have to authenticate with a token
+}
+
+// load loads the security configuration for the servers listening on listenAddresses,
+// generating the certificate and the token it's missing
+func (sc *SecurityConfig) load(listenAddresses ...string) (*daemonSecurity, error) {
+	tlsConfig, err := sc.tlsConfig(listenAddresses)
+	if err != nil {
+		return nil, err
+	}
+	token, err := sc.authToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, listen := range listenAddresses {
+		if isLoopbackListenAddress(listen) {
+			continue
+		}
+		if tlsConfig == nil {
+			log.Warnf("Listening on %s without TLS: passwords and transactions are sent in the clear. "+
+				"Consider serving TLS with --tls-cert and --tls-key", listen)
+		}
+		if token == nil && (tlsConfig == nil || tlsConfig.ClientCAs == nil) {
+			log.Warnf("Listening on %s without authentication: anyone who can reach it can use the wallet. "+
+				"Consider requiring an auth token with --auth-token-file, or client certificates with --tls-client-ca",
+				listen)
+		}
+	}
+
+	security := &daemonSecurity{tlsConfig: tlsConfig}
+	if token != nil {
+		security.authenticator = &tokenAuthenticator{token: token}
+	}
+	return security, nil
+}
+
+// grpcServerOptions returns the gRPC server options that enforce the security configuration
+func (ds *daemonSecurity) grpcServerOptions() []grpc.ServerOption {
+	var options []grpc.ServerOption
+	if ds.tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(ds.tlsConfig)))
+	}
+	if ds.authenticator != nil {
+		options = append(options,
+			grpc.UnaryInterceptor(ds.authenticator.unaryInterceptor),
+			grpc.StreamInterceptor(ds.authenticator.streamInterceptor))
+	}
+	return options
+}
+
+// tlsConfig returns the TLS configuration the daemon serves with, or nil if TLS is off
+func (sc *SecurityConfig) tlsConfig(listenAddresses []string) (*tls.Config, error) {
+	if sc.TLSCertFile == "" {
+		if sc.ClientCAFile != "" {
+			return nil, errors.New("client certificates can't be required without TLS")
+		}
+		return nil, nil
+	}
+
+	if !fileExists(sc.TLSCertFile) && !fileExists(sc.TLSKeyFile) {
+		err := generateSelfSignedCertificate(sc.TLSCertFile, sc.TLSKeyFile, listenAddresses)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Generated a self-signed TLS certificate into %s", sc.TLSCertFile)
+	}
+	certificate, err := tls.LoadX509KeyPair(sc.TLSCertFile, sc.TLSKeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading the TLS certificate")
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if sc.ClientCAFile != "" {
+		clientCAs, err := loadCertificatePool(sc.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// authToken returns the bearer token clients have to authenticate with, or nil if it's not required
+func (sc *SecurityConfig) authToken() ([]byte, error) {
+	if sc.AuthTokenFile == "" {
+		return nil, nil
+	}
+
+	if !fileExists(sc.AuthTokenFile) {
+		token := make([]byte, authTokenSize)
+		_, err := rand.Read(token)
+		if err != nil {
+			return nil, err
+		}
+		err = os.WriteFile(sc.AuthTokenFile, []byte(hex.EncodeToString(token)+"\n"), 0600)
+		if err != nil {
+			return nil, errors.Wrap(err, "error writing the auth token file")
+		}
+		log.Infof("Generated an auth token into %s", sc.AuthTokenFile)
+	}
+	token, err := ReadAuthTokenFile(sc.AuthTokenFile)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(token), nil
+}
+
+// ReadAuthTokenFile reads a bearer token from a file, ignoring surrounding whitespace
+func ReadAuthTokenFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading the auth token file")
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", errors.Errorf("the auth token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// tokenAuthenticator rejects requests that don't carry the bearer token of the daemon
+type tokenAuthenticator struct {
+	token []byte
+}
+
+// isAuthorized returns whether the value of an authorization header, of gRPC metadata
+// or of an HTTP request, carries the token
+func (ta *tokenAuthenticator) isAuthorized(authorization string) bool {
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authorization, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(authorization, prefix)), ta.token) == 1
+}
+
+func (ta *tokenAuthenticator) authenticate(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if ta.isAuthorized(authorization) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid auth token")
+}
+
+func (ta *tokenAuthenticator) unaryInterceptor(ctx context.Context, request interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+
+	err := ta.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+func (ta *tokenAuthenticator) streamInterceptor(server interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+
+	err := ta.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(server, stream)
+}
+
+// isLoopbackListenAddress returns whether listen only accepts connections from the local machine
+func isLoopbackListenAddress(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// generateSelfSignedCertificate generates a self-signed certificate for the local machine and the hosts
+// of listenAddresses, and writes it and its key to the given files
+func generateSelfSignedCertificate(certFile string, keyFile string, listenAddresses []string) error {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"kaspiwallet autogenerated certificate"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		// The certificate is its own CA, so that clients can trust it directly
+		IsCA:        true,
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "localhost" {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	for _, listen := range listenAddresses {
+		host, _, err := net.SplitHostPort(listen)
+		if err != nil || host == "" || host == "localhost" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			if !ip.IsUnspecified() && !ip.IsLoopback() {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return err
+	}
+	serializedKey, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0644)
+	if err != nil {
+		return errors.Wrap(err, "error writing the TLS certificate")
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: serializedKey}), 0600)
+	if err != nil {
+		return errors.Wrap(err, "error writing the TLS key")
+	}
+	return nil
+}
+
+// loadCertificatePool loads the PEM encoded certificates in path into a certificate pool
+func loadCertificatePool(path string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the certificate file %s", path)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, errors.Errorf("no certificates were found in %s", path)
+	}
+	return pool, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestSecurityConfig(t *testing.T) {
+	directory := t.TempDir()
+	securityConfig := &SecurityConfig{
+		TLSCertFile:   filepath.Join(directory, "daemon.cert"),
+		TLSKeyFile:    filepath.Join(directory, "daemon.key"),
+		AuthTokenFile: filepath.Join(directory, "auth-token"),
+	}
+
+	// A self-signed certificate and a token are generated on the first run, and reused later on
+	tlsConfig, err := securityConfig.tlsConfig([]string{"localhost:8082"})
+	if err != nil {
+		t.Fatalf("tlsConfig: %s", err)
+	}
+	certificate, err := os.ReadFile(securityConfig.TLSCertFile)
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	_, err = securityConfig.tlsConfig([]string{"localhost:8082"})
+	if err != nil {
+		t.Fatalf("tlsConfig: %s", err)
+	}
+	reloadedCertificate, err := os.ReadFile(securityConfig.TLSCertFile)
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	if string(certificate) != string(reloadedCertificate) {
+		t.Fatalf("an existing certificate is expected to be reused")
+	}
+	servedCertificate := tlsConfig.Certificates[0]
+	if len(servedCertificate.Certificate) != 1 {
+		t.Fatalf("a single self-signed certificate is expected")
+	}
+	keyInfo, err := os.Stat(securityConfig.TLSKeyFile)
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if keyInfo.Mode().Perm()&0077 != 0 {
+		t.Fatalf("the TLS key is expected to be readable only by its owner, but its mode is %s", keyInfo.Mode())
+	}
+
+	token, err := securityConfig.authToken()
+	if err != nil {
+		t.Fatalf("authToken: %s", err)
+	}
+	reloadedToken, err := securityConfig.authToken()
+	if err != nil {
+		t.Fatalf("authToken: %s", err)
+	}
+	if len(token) != 2*authTokenSize || string(token) != string(reloadedToken) {
+		t.Fatalf("an existing auth token is expected to be reused")
+	}
+
+	authenticator := &tokenAuthenticator{token: token}
+	tests := []struct {
+		authorization string
+		isAuthorized  bool
+	}{
+		{"Bearer " + string(token), true},
+		{"Bearer " + string(token[1:]), false},
+		{string(token), false},
+		{"Basic " + string(token), false},
+		{"", false},
+	}
+	for _, test := range tests {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", test.authorization))
+		err := authenticator.authenticate(ctx)
+		if test.isAuthorized && err != nil {
+			t.Fatalf("%q is expected to be authorized, but got %s", test.authorization, err)
+		}
+		if !test.isAuthorized && status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("%q is expected to be unauthenticated, but got %v", test.authorization, err)
+		}
+	}
+	if status.Code(authenticator.authenticate(context.Background())) != codes.Unauthenticated {
+		t.Fatalf("a request without metadata is expected to be unauthenticated")
+	}
+
+	_, err = (&SecurityConfig{ClientCAFile: securityConfig.TLSCertFile}).tlsConfig([]string{"localhost:8082"})
+	if err == nil {
+		t.Fatalf("requiring client certificates without TLS is expected to fail")
+	}
+}
+
+func TestIsLoopbackListenAddress(t *testing.T) {
+	tests := []struct {
+		listen     string
+		isLoopback bool
+	}{
+		{"localhost:8082", true},
+		{"127.0.0.1:8082", true},
+		{"[::1]:8082", true},
+		{"0.0.0.0:8082", false},
+		{":8082", false},
+		{"192.168.1.2:8082", false},
+		{"example.com:8082", false},
+	}
+	for _, test := range tests {
+		if isLoopback := isLoopbackListenAddress(test.listen); isLoopback != test.isLoopback {
+			t.Fatalf("isLoopbackListenAddress(%s) is %t, while %t is expected", test.listen, isLoopback, test.isLoopback)
+		}
+	}
+}
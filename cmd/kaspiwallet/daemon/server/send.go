@@ -10,8 +10,12 @@ func (s *server) Send(_ context.Context, request *pb.SendRequest) (*pb.SendRespo
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	unsignedTransactions, err := s.createUnsignedTransactions(request.ToAddress, request.Amount, request.IsSendAll,
-		request.From, request.UseExistingChangeAddress)
+	fees, err := newFeeOptions(request.FeeRate, request.MaxFee, request.SubtractFeeFromAmount)
+	if err != nil {
+		return nil, err
+	}
+	unsignedTransactions, fee, mass, err := s.createUnsignedTransactions(request.Account, request.ToAddress, request.Amount,
+		request.Outputs, request.IsSendAll, request.From, request.Utxos, request.UseExistingChangeAddress, fees)
 
 	if err != nil {
 		return nil, err
@@ -27,5 +31,5 @@ func (s *server) Send(_ context.Context, request *pb.SendRequest) (*pb.SendRespo
 		return nil, err
 	}
 
-	return &pb.SendResponse{TxIDs: txIDs, SignedTransactions: signedTransactions}, nil
+	return &pb.SendResponse{TxIDs: txIDs, SignedTransactions: signedTransactions, Fee: fee, Mass: mass}, nil
 }
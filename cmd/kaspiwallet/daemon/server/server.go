@@ -1,13 +1,16 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/kaspikr/kaspid/app/appmessage"
 	"github.com/kaspikr/kaspid/version"
 
 	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
@@ -46,6 +49,28 @@ type server struct {
 	txMassCalculator                *txmass.Calculator
 	usedOutpoints                   map[externalapi.DomainOutpoint]time.Time
 	firstSyncDone                   atomic.Bool
+	transactionStore                *transactionStore
+	lockedOutpoints                 *lockedOutpointStore
+	labels                          *labelStore
+	timeLockedAddresses             *timeLockedAddressStore
+	ownAddresses                    map[string]struct{}
+	nextOwnAddressIndexes           map[accountKeychain]uint32
+	unlockedMnemonics               []string
+	unlockedPassphrases             []string
+	unlockedUntil                   time.Time
+	lockTimer                       *time.Timer
+
+	// The following fields are only accessed by the sync loop, unless noted otherwise
+	utxosChangedChan               chan *appmessage.UTXOsChangedNotificationMessage // Written by the notification handler
+	isSubscribedToUTXOsChanged     bool
+	isUTXOsChangedUnsupported      bool
+	isUTXOsChangedSubscriptionLost atomic.Bool // Set when the background RPC client reconnects
+	needsFullRefresh               atomic.Bool // Set when UTXO change notifications are dropped
+	subscribedAddresses            walletAddressSet
+	nextSubscriptionIndexes        map[accountKeychain]uint32
+
+	walletChangesSubscribersLock sync.Mutex
+	walletChangesSubscribers     map[*walletChangesSubscriber]struct{}
 
 	isLogFinalProgressLineShown bool
 	maxUsedAddressesForLog      uint32
@@ -56,8 +81,11 @@ type server struct {
 // Currently, set to 100MB
 const MaxDaemonSendMsgSize = 100_000_000
 
-// Start starts the kaspiwalletd server
-func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath string, profile string, timeout uint32) error {
+// Start starts the kaspiwalletd server. httpListen, if set, is the address the HTTP/JSON API is served on,
+// alongside the gRPC service.
+func Start(params *dagconfig.Params, listen, httpListen, rpcServer string, keysFilePath string, profile string,
+	timeout uint32, securityConfig *SecurityConfig) error {
+
 	initLog(defaultLogFile, defaultErrLogFile)
 
 	defer panics.HandlePanic(log, "MAIN", nil)
@@ -68,11 +96,31 @@ func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath stri
 	}
 
 	log.Infof("Version %s", version.Version())
+	listenAddresses := []string{listen}
+	if httpListen != "" {
+		listenAddresses = append(listenAddresses, httpListen)
+	}
+	security, err := securityConfig.load(listenAddresses...)
+	if err != nil {
+		return err
+	}
+	if httpListen != "" && security.authenticator == nil {
+		return errors.New("the HTTP API requires an auth token, since any web page the user visits can send " +
+			"requests to a local HTTP server")
+	}
 	listener, err := net.Listen("tcp", listen)
 	if err != nil {
 		return (errors.Wrapf(err, "Error listening to TCP on %s", listen))
 	}
 	log.Infof("Listening to TCP on %s", listen)
+	var httpListener net.Listener
+	if httpListen != "" {
+		httpListener, err = net.Listen("tcp", httpListen)
+		if err != nil {
+			return (errors.Wrapf(err, "Error listening to TCP on %s", httpListen))
+		}
+		log.Infof("Serving the HTTP API on %s", httpListen)
+	}
 
 	log.Infof("Connecting to a node at %s...", rpcServer)
 	rpcClient, err := connectToRPC(params, rpcServer, timeout)
@@ -94,6 +142,40 @@ func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath stri
 	if err != nil {
 		return err
 	}
+	err = keysFile.Migrate()
+	if err != nil {
+		return (errors.Wrapf(err, "Error upgrading keys file %s", keysFilePath))
+	}
+	if keysFile.IsWatchOnly() {
+		log.Infof("The wallet is watch-only: it can create unsigned transactions, but can't sign them")
+	}
+
+	walletDB, err := openWalletDatabase(walletDBPath(keysFile.Path()))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := walletDB.Close()
+		if err != nil {
+			log.Errorf("Error closing the wallet database: %s", err)
+		}
+	}()
+	transactionStore, err := newTransactionStore(walletDB)
+	if err != nil {
+		return err
+	}
+	lockedOutpoints, err := newLockedOutpointStore(walletDB)
+	if err != nil {
+		return err
+	}
+	labels, err := newLabelStore(walletDB)
+	if err != nil {
+		return err
+	}
+	timeLockedAddresses, err := newTimeLockedAddressStore(walletDB)
+	if err != nil {
+		return err
+	}
 
 	dagInfo, err := rpcClient.GetBlockDAGInfo()
 	if err != nil {
@@ -118,11 +200,21 @@ func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath stri
 		addressSet:                  make(walletAddressSet),
 		txMassCalculator:            txmass.NewCalculator(params.MassPerTxByte, params.MassPerScriptPubKeyByte, params.MassPerSigOp),
 		usedOutpoints:               map[externalapi.DomainOutpoint]time.Time{},
+		transactionStore:            transactionStore,
+		lockedOutpoints:             lockedOutpoints,
+		labels:                      labels,
+		timeLockedAddresses:         timeLockedAddresses,
+		utxosChangedChan:            make(chan *appmessage.UTXOsChangedNotificationMessage, utxosChangedChanSize),
+		subscribedAddresses:         make(walletAddressSet),
+		nextSubscriptionIndexes:     make(map[accountKeychain]uint32),
+		walletChangesSubscribers:    make(map[*walletChangesSubscriber]struct{}),
 		isLogFinalProgressLineShown: false,
 		maxUsedAddressesForLog:      0,
 		maxProcessedAddressesForLog: 0,
 	}
 
+	backgroundRPCClient.SetOnReconnectedHandler(serverInstance.onBackgroundRPCClientReconnected)
+
 	log.Infof("Read, syncing the wallet...")
 	spawn("serverInstance.syncLoop", func() {
 		err := serverInstance.syncLoop()
@@ -131,7 +223,7 @@ func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath stri
 		}
 	})
 
-	grpcServer := grpc.NewServer(grpc.MaxSendMsgSize(MaxDaemonSendMsgSize))
+	grpcServer := grpc.NewServer(append(security.grpcServerOptions(), grpc.MaxSendMsgSize(MaxDaemonSendMsgSize))...)
 	pb.RegisterKaspiwalletdServer(grpcServer, serverInstance)
 
 	spawn("grpcServer.Serve", func() {
@@ -141,9 +233,38 @@ func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath stri
 		}
 	})
 
+	var httpServer *http.Server
+	if httpListener != nil {
+		httpServer = &http.Server{
+			Handler:           serverInstance.httpHandler(security),
+			TLSConfig:         security.tlsConfig,
+			ReadHeaderTimeout: httpReadHeaderTimeout,
+		}
+		spawn("httpServer.Serve", func() {
+			var err error
+			if security.tlsConfig != nil {
+				err = httpServer.ServeTLS(httpListener, "", "")
+			} else {
+				err = httpServer.Serve(httpListener)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				printErrorAndExit(errors.Wrap(err, "Error serving HTTP"))
+			}
+		})
+	}
+
 	select {
 	case <-serverInstance.shutdown:
 	case <-interrupt:
+		if httpServer != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), httpStopTimeout)
+			err := httpServer.Shutdown(ctx)
+			cancel()
+			if err != nil {
+				log.Warnf("Could not gracefully stop the HTTP server: %s", err)
+			}
+		}
+
 		const stopTimeout = 2 * time.Second
 
 		stopChan := make(chan interface{})
@@ -20,13 +20,14 @@ func (s *server) Sign(_ context.Context, request *pb.SignRequest) (*pb.SignRespo
 }
 
 func (s *server) signTransactions(unsignedTransactions [][]byte, password string) ([][]byte, error) {
-	mnemonics, err := s.keysFile.DecryptMnemonics(password)
+	mnemonics, passphrases, err := s.decryptMnemonics(password)
 	if err != nil {
 		return nil, err
 	}
 	signedTransactions := make([][]byte, len(unsignedTransactions))
 	for i, unsignedTransaction := range unsignedTransactions {
-		signedTransaction, err := libkaspiwallet.Sign(s.params, mnemonics, unsignedTransaction, s.keysFile.ECDSA)
+		signedTransaction, err := libkaspiwallet.Sign(s.params, mnemonics, passphrases, unsignedTransaction,
+			s.keysFile.ECDSA)
 		if err != nil {
 			return nil, err
 		}
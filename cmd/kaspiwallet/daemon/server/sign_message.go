@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+func (s *server) SignMessage(_ context.Context, request *pb.SignMessageRequest) (*pb.SignMessageResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Signing only needs the key of the address, so unlike spending it doesn't wait for the wallet to sync
+	if s.isMultisig() {
+		return nil, errors.Errorf("messages can't be signed by the addresses of a multisig wallet")
+	}
+	if s.keysFile.IsWatchOnly() {
+		return nil, errors.Errorf("cannot sign with a watch-only wallet, since it has no private keys")
+	}
+
+	address, err := util.DecodeAddress(request.Address, s.params.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	wAddr, ok, err := s.walletAddressByString(address.String())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.Errorf("address %s is not an address of the wallet", request.Address)
+	}
+
+	mnemonics, passphrases, err := s.decryptMnemonics(request.Password)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := libkaspiwallet.SignMessage(s.params, mnemonics[0], passphrases[0],
+		s.walletAddressDerivationPath(wAddr), request.Message, s.keysFile.ECDSA)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.SignMessageResponse{Signature: signature}, nil
+}
+
+func (s *server) VerifyMessage(_ context.Context, request *pb.VerifyMessageRequest) (*pb.VerifyMessageResponse, error) {
+	address, err := util.DecodeAddress(request.Address, s.params.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	isValid, err := util.VerifyMessage(address, request.Message, request.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.VerifyMessageResponse{IsValid: isValid}, nil
+}
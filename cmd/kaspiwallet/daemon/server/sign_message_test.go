@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+)
+
+func TestSignMessageBeforeSync(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	keysFile, err := keys.NewFileFromMnemonic(params, mnemonic, "password")
+	if err != nil {
+		t.Fatalf("NewFileFromMnemonic: %s", err)
+	}
+	err = keysFile.SetPath(params, filepath.Join(t.TempDir(), "keys.json"), true)
+	if err != nil {
+		t.Fatalf("SetPath: %s", err)
+	}
+	err = keysFile.SetLastUsedExternalIndex(1)
+	if err != nil {
+		t.Fatalf("SetLastUsedExternalIndex: %s", err)
+	}
+	// The server never synced, as if its node is still syncing or is offline
+	serverInstance := &server{params: params, keysFile: keysFile, addressSet: make(walletAddressSet)}
+
+	address, err := serverInstance.walletAddressString(&walletAddress{
+		index:    1,
+		keyChain: libkaspiwallet.ExternalKeychain,
+	})
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	const message = "a message"
+	signResponse, err := serverInstance.SignMessage(context.Background(), &pb.SignMessageRequest{
+		Address:  address,
+		Message:  message,
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatalf("SignMessage: %s", err)
+	}
+	verifyResponse, err := serverInstance.VerifyMessage(context.Background(), &pb.VerifyMessageRequest{
+		Address:   address,
+		Message:   message,
+		Signature: signResponse.Signature,
+	})
+	if err != nil {
+		t.Fatalf("VerifyMessage: %s", err)
+	}
+	if !verifyResponse.IsValid {
+		t.Fatalf("the signature of the message is expected to be valid")
+	}
+
+	// An address that isn't one of the wallet's known addresses can't be signed with
+	unknownAddress, err := serverInstance.walletAddressString(&walletAddress{
+		index:    100,
+		keyChain: libkaspiwallet.ExternalKeychain,
+	})
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	_, err = serverInstance.SignMessage(context.Background(), &pb.SignMessageRequest{
+		Address:  unknownAddress,
+		Message:  message,
+		Password: "password",
+	})
+	if err == nil {
+		t.Fatalf("signing with an address the wallet didn't generate is expected to fail")
+	}
+}
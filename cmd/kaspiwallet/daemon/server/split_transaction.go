@@ -18,16 +18,17 @@ import (
 // transaction.
 // If it is - the transaction is split into multiple transactions, each with a portion of the inputs and a single output
 // into a change address.
-// An additional `mergeTransaction` is generated - which merges the outputs of the above splits into a single output
-// paying to the original transaction's payee.
-func (s *server) maybeAutoCompoundTransaction(transactionBytes []byte, toAddress util.Address,
-	changeAddress util.Address, changeWalletAddress *walletAddress) ([][]byte, error) {
+// An additional `mergeTransaction` is generated - which merges the outputs of the above splits into the
+// original transaction's payments.
+func (s *server) maybeAutoCompoundTransaction(account *walletAccount, transactionBytes []byte, payments []*libkaspiwallet.Payment,
+	changeAddress util.Address, changeWalletAddress *walletAddress, fees *feeOptions) ([][]byte, error) {
 	transaction, err := serialization.DeserializePartiallySignedTransaction(transactionBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	splitTransactions, err := s.maybeSplitAndMergeTransaction(transaction, toAddress, changeAddress, changeWalletAddress)
+	splitTransactions, err := s.maybeSplitAndMergeTransaction(account, transaction, payments, changeAddress, changeWalletAddress,
+		fees)
 	if err != nil {
 		return nil, err
 	}
@@ -41,24 +42,36 @@ func (s *server) maybeAutoCompoundTransaction(transactionBytes []byte, toAddress
 	return splitTransactionsBytes, nil
 }
 
+// mergeTransaction pays the payments of originalTransaction from the outputs of its split transactions.
+// The addresses of the payments are taken from payments, and their amounts from originalTransaction.
 func (s *server) mergeTransaction(
+	account *walletAccount,
 	splitTransactions []*serialization.PartiallySignedTransaction,
 	originalTransaction *serialization.PartiallySignedTransaction,
-	toAddress util.Address,
+	payments []*libkaspiwallet.Payment,
 	changeAddress util.Address,
 	changeWalletAddress *walletAddress,
+	fees *feeOptions,
 ) (*serialization.PartiallySignedTransaction, error) {
 	numOutputs := len(originalTransaction.Tx.Outputs)
-	if numOutputs > 2 || numOutputs == 0 {
-		// This is a sanity check to make sure originalTransaction has either 1 or 2 outputs:
-		// 1. For the payment itself
-		// 2. (optional) for change
-		return nil, errors.Errorf("original transaction has %d outputs, while 1 or 2 are expected",
-			len(originalTransaction.Tx.Outputs))
+	if numOutputs != len(payments) && numOutputs != len(payments)+1 {
+		// This is a sanity check to make sure originalTransaction has:
+		// 1. An output for each payment
+		// 2. (optional) An output for change
+		return nil, errors.Errorf("original transaction has %d outputs, while %d or %d are expected",
+			len(originalTransaction.Tx.Outputs), len(payments), len(payments)+1)
 	}
 
 	totalValue := uint64(0)
-	sentValue := originalTransaction.Tx.Outputs[0].Value
+	sentValue := uint64(0)
+	mergePayments := make([]*libkaspiwallet.Payment, len(payments))
+	for i, payment := range payments {
+		mergePayments[i] = &libkaspiwallet.Payment{
+			Address: payment.Address,
+			Amount:  originalTransaction.Tx.Outputs[i].Value,
+		}
+		sentValue += mergePayments[i].Amount
+	}
 	utxos := make([]*libkaspiwallet.UTXO, len(splitTransactions))
 	for i, splitTransaction := range splitTransactions {
 		output := splitTransaction.Tx.Outputs[0]
@@ -68,36 +81,50 @@ func (s *server) mergeTransaction(
 				Index:         0,
 			},
 			UTXOEntry:      utxo.NewUTXOEntry(output.Value, output.ScriptPublicKey, false, constants.UnacceptedDAAScore),
-			DerivationPath: s.walletAddressPath(changeWalletAddress),
+			DerivationPath: s.walletAddressDerivationPath(changeWalletAddress),
 		}
 		totalValue += output.Value
-		totalValue -= feePerInput
 	}
 
-	if totalValue < sentValue {
-		// sometimes the fees from compound transactions make the total output higher than what's available from selected
-		// utxos, in such cases - find one more UTXO and use it.
-		additionalUTXOs, totalValueAdded, err := s.moreUTXOsForMergeTransaction(utxos, sentValue-totalValue)
-		if err != nil {
-			return nil, err
+	outputsWithChange := append([]*libkaspiwallet.Payment{}, mergePayments...)
+	outputsWithChange = append(outputsWithChange, &libkaspiwallet.Payment{Address: changeAddress})
+	massEstimate, err := s.estimateTransactionMass(outputsWithChange, utxos[0])
+	if err != nil {
+		return nil, err
+	}
+	fee := fees.fee(massEstimate.mass(utxos))
+
+	if totalValue < sentValue+fee {
+		if fees.subtractFeeFromAmount {
+			// The fees of the split transactions come out of the payments as well
+			err := subtractFeeFromPayments(mergePayments, sentValue+fee-totalValue)
+			if err != nil {
+				return nil, err
+			}
+			sentValue = totalValue - fee
+		} else {
+			// sometimes the fees from compound transactions make the total output higher than what's available from selected
+			// utxos, in such cases - find one more UTXO and use it.
+			additionalUTXOs, totalValueAdded, err := s.moreUTXOsForMergeTransaction(account, utxos, sentValue+fee-totalValue,
+				massEstimate, fees)
+			if err != nil {
+				return nil, err
+			}
+			utxos = append(utxos, additionalUTXOs...)
+			totalValue += totalValueAdded
+			fee = fees.fee(massEstimate.mass(utxos))
 		}
-		utxos = append(utxos, additionalUTXOs...)
-		totalValue += totalValueAdded
 	}
 
-	payments := []*libkaspiwallet.Payment{{
-		Address: toAddress,
-		Amount:  sentValue,
-	}}
-	if totalValue > sentValue {
-		payments = append(payments, &libkaspiwallet.Payment{
+	outputs := mergePayments
+	if totalValue > sentValue+fee {
+		outputs = append(outputs, &libkaspiwallet.Payment{
 			Address: changeAddress,
-			Amount:  totalValue - sentValue,
+			Amount:  totalValue - sentValue - fee,
 		})
 	}
 
-	mergeTransactionBytes, err := libkaspiwallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
-		s.keysFile.MinimumSignatures, payments, utxos)
+	mergeTransactionBytes, err := s.createUnsignedTransaction(account, outputs, utxos)
 	if err != nil {
 		return nil, err
 	}
@@ -105,8 +132,9 @@ func (s *server) mergeTransaction(
 	return serialization.DeserializePartiallySignedTransaction(mergeTransactionBytes)
 }
 
-func (s *server) maybeSplitAndMergeTransaction(transaction *serialization.PartiallySignedTransaction, toAddress util.Address,
-	changeAddress util.Address, changeWalletAddress *walletAddress) ([]*serialization.PartiallySignedTransaction, error) {
+func (s *server) maybeSplitAndMergeTransaction(account *walletAccount, transaction *serialization.PartiallySignedTransaction,
+	payments []*libkaspiwallet.Payment, changeAddress util.Address, changeWalletAddress *walletAddress,
+	fees *feeOptions) ([]*serialization.PartiallySignedTransaction, error) {
 
 	transactionMass, err := s.estimateMassAfterSignatures(transaction)
 	if err != nil {
@@ -117,41 +145,56 @@ func (s *server) maybeSplitAndMergeTransaction(transaction *serialization.Partia
 		return []*serialization.PartiallySignedTransaction{transaction}, nil
 	}
 
-	splitCount, inputCountPerSplit, err := s.splitAndInputPerSplitCounts(transaction, transactionMass, changeAddress)
+	splitCount, inputCountPerSplit, err := s.splitAndInputPerSplitCounts(account, transaction, transactionMass, changeAddress)
 	if err != nil {
 		return nil, err
 	}
+	if splitCount == 1 {
+		// All the inputs fit in a single split, so it's the outputs that make the transaction too heavy.
+		// Split the inputs in two, so that the merge transaction only has two inputs.
+		inputCount := len(transaction.Tx.Inputs)
+		if inputCount <= 2 {
+			return nil, errors.Errorf("transaction mass %d is larger than the standard %d, even though its inputs "+
+				"can't be split further. Try sending to fewer outputs at once",
+				transactionMass, mempool.MaximumStandardTransactionMass)
+		}
+		splitCount = 2
+		inputCountPerSplit = (inputCount + 1) / 2
+	}
 
 	splitTransactions := make([]*serialization.PartiallySignedTransaction, splitCount)
 	for i := 0; i < splitCount; i++ {
 		startIndex := i * inputCountPerSplit
 		endIndex := startIndex + inputCountPerSplit
 		var err error
-		splitTransactions[i], err = s.createSplitTransaction(transaction, changeAddress, startIndex, endIndex)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	if len(splitTransactions) > 1 {
-		mergeTransaction, err := s.mergeTransaction(splitTransactions, transaction, toAddress, changeAddress, changeWalletAddress)
+		splitTransactions[i], err = s.createSplitTransaction(account, transaction, changeAddress, startIndex, endIndex)
 		if err != nil {
 			return nil, err
 		}
-		// Recursion will be 2-3 iterations deep even in the rarest` cases, so considered safe..
-		splitMergeTransaction, err := s.maybeSplitAndMergeTransaction(mergeTransaction, toAddress, changeAddress, changeWalletAddress)
+		err = s.payFeeFromOutput(splitTransactions[i], 0, fees)
 		if err != nil {
 			return nil, err
 		}
-		splitTransactions = append(splitTransactions, splitMergeTransaction...)
+	}
 
+	mergeTransaction, err := s.mergeTransaction(account, splitTransactions, transaction, payments, changeAddress, changeWalletAddress,
+		fees)
+	if err != nil {
+		return nil, err
 	}
+	// Recursion will be 2-3 iterations deep even in the rarest` cases, so considered safe..
+	splitMergeTransaction, err := s.maybeSplitAndMergeTransaction(account, mergeTransaction, payments, changeAddress,
+		changeWalletAddress, fees)
+	if err != nil {
+		return nil, err
+	}
+	splitTransactions = append(splitTransactions, splitMergeTransaction...)
 
 	return splitTransactions, nil
 }
 
 // splitAndInputPerSplitCounts calculates the number of splits to create, and the number of inputs to assign per split.
-func (s *server) splitAndInputPerSplitCounts(transaction *serialization.PartiallySignedTransaction, transactionMass uint64,
+func (s *server) splitAndInputPerSplitCounts(account *walletAccount, transaction *serialization.PartiallySignedTransaction, transactionMass uint64,
 	changeAddress util.Address) (splitCount, inputsPerSplitCount int, err error) {
 
 	// Create a dummy transaction which is a clone of the original transaction, but without inputs,
@@ -172,7 +215,7 @@ func (s *server) splitAndInputPerSplitCounts(transaction *serialization.Partiall
 
 	// Create another dummy transaction, this time one similar to the split transactions we wish to generate,
 	// but with 0 inputs, to calculate how much mass for inputs do we have available in the split transactions
-	splitTransactionWithoutInputs, err := s.createSplitTransaction(transaction, changeAddress, 0, 0)
+	splitTransactionWithoutInputs, err := s.createSplitTransaction(account, transaction, changeAddress, 0, 0)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -189,7 +232,7 @@ func (s *server) splitAndInputPerSplitCounts(transaction *serialization.Partiall
 	return splitCount, inputsPerSplitCount, nil
 }
 
-func (s *server) createSplitTransaction(transaction *serialization.PartiallySignedTransaction,
+func (s *server) createSplitTransaction(account *walletAccount, transaction *serialization.PartiallySignedTransaction,
 	changeAddress util.Address, startIndex int, endIndex int) (*serialization.PartiallySignedTransaction, error) {
 
 	selectedUTXOs := make([]*libkaspiwallet.UTXO, 0, endIndex-startIndex)
@@ -203,13 +246,12 @@ func (s *server) createSplitTransaction(transaction *serialization.PartiallySign
 				partiallySignedInput.PrevOutput.Value, partiallySignedInput.PrevOutput.ScriptPublicKey,
 				false, constants.UnacceptedDAAScore),
 			DerivationPath: partiallySignedInput.DerivationPath,
+			RedeemScript:   partiallySignedInput.RedeemScript,
 		})
 
 		totalSompi += selectedUTXOs[i-startIndex].UTXOEntry.Amount()
-		totalSompi -= feePerInput
 	}
-	unsignedTransactionBytes, err := libkaspiwallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
-		s.keysFile.MinimumSignatures,
+	unsignedTransactionBytes, err := s.createUnsignedTransaction(account,
 		[]*libkaspiwallet.Payment{{
 			Address: changeAddress,
 			Amount:  totalSompi,
@@ -248,7 +290,8 @@ func (s *server) estimateMassAfterSignatures(transaction *serialization.Partiall
 	return s.txMassCalculator.CalculateTransactionMass(transactionWithSignatures), nil
 }
 
-func (s *server) moreUTXOsForMergeTransaction(alreadySelectedUTXOs []*libkaspiwallet.UTXO, requiredAmount uint64) (
+func (s *server) moreUTXOsForMergeTransaction(account *walletAccount, alreadySelectedUTXOs []*libkaspiwallet.UTXO, requiredAmount uint64,
+	massEstimate *transactionMassEstimate, fees *feeOptions) (
 	additionalUTXOs []*libkaspiwallet.UTXO, totalValueAdded uint64, err error) {
 
 	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
@@ -264,20 +307,30 @@ func (s *server) moreUTXOsForMergeTransaction(alreadySelectedUTXOs []*libkaspiwa
 		if _, ok := alreadySelectedUTXOsMap[*utxo.Outpoint]; ok {
 			continue
 		}
+		if utxo.address.accountIndex != account.index {
+			continue
+		}
+		if _, ok := s.lockedOutpoints.get(*utxo.Outpoint); ok {
+			continue
+		}
+		if s.isOutpointPending(*utxo.Outpoint) {
+			continue
+		}
 		if !s.isUTXOSpendable(utxo, dagInfo.VirtualDAAScore) {
 			continue
 		}
-		additionalUTXOs = append(additionalUTXOs, &libkaspiwallet.UTXO{
-			Outpoint:       utxo.Outpoint,
-			UTXOEntry:      utxo.UTXOEntry,
-			DerivationPath: s.walletAddressPath(utxo.address)})
-		totalValueAdded += utxo.UTXOEntry.Amount() - feePerInput
+		additionalUTXO, err := s.spendableUTXO(utxo)
+		if err != nil {
+			return nil, 0, err
+		}
+		additionalUTXOs = append(additionalUTXOs, additionalUTXO)
+		totalValueAdded += utxo.UTXOEntry.Amount() - fees.fee(massEstimate.inputMass(additionalUTXO))
 		if totalValueAdded >= requiredAmount {
 			break
 		}
 	}
 	if totalValueAdded < requiredAmount {
-		return nil, 0, errors.Errorf("Insufficient funds for merge transaction")
+		return nil, 0, classifyErrorf(errInsufficientFunds, "Insufficient funds for merge transaction")
 	}
 
 	return additionalUTXOs, totalValueAdded, nil
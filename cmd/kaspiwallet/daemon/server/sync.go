@@ -31,6 +31,13 @@ func (s *server) syncLoop() error {
 	if err != nil {
 		return err
 	}
+	s.collectTimeLockedAddresses()
+
+	// Subscribing before the first refresh makes sure no change is missed between them
+	_, err = s.updateUTXOsChangedSubscription()
+	if err != nil {
+		return err
+	}
 
 	err = s.refreshUTXOs()
 	if err != nil {
@@ -44,6 +51,12 @@ func (s *server) syncLoop() error {
 		select {
 		case <-ticker.C:
 		case <-s.forceSyncChan:
+		case notification := <-s.utxosChangedChan:
+			err := s.applyUTXOsChanged(notification)
+			if err != nil {
+				return err
+			}
+			continue
 		}
 
 		err := s.sync()
@@ -63,8 +76,21 @@ func (s *server) sync() error {
 	if err != nil {
 		return err
 	}
+	s.collectTimeLockedAddresses()
+
+	needsFullRefresh, err := s.updateUTXOsChangedSubscription()
+	if err != nil {
+		return err
+	}
+
+	// Changes in the UTXOs of the wallet are applied as they are notified, so the full refresh
+	// is a fallback that reconciles the UTXO set with the node, and observes the mempool
+	if needsFullRefresh || s.needsFullRefresh.Swap(false) || !s.isSubscribedToUTXOsChanged ||
+		time.Since(s.startTimeOfLastCompletedRefresh) >= fullRefreshInterval {
 
-	return s.refreshUTXOs()
+		return s.refreshUTXOs()
+	}
+	return nil
 }
 
 const (
@@ -75,22 +101,25 @@ const (
 // addressesToQuery scans the addresses in the given range. Because
 // each cosigner in a multisig has its own unique path for generating
 // addresses it goes over all the cosigners and add their addresses
-// for each key chain.
+// for each key chain of every account.
 func (s *server) addressesToQuery(start, end uint32) (walletAddressSet, error) {
 	addresses := make(walletAddressSet)
-	for index := start; index < end; index++ {
-		for cosignerIndex := uint32(0); cosignerIndex < uint32(len(s.keysFile.ExtendedPublicKeys)); cosignerIndex++ {
-			for _, keychain := range keyChains {
-				address := &walletAddress{
-					index:         index,
-					cosignerIndex: cosignerIndex,
-					keyChain:      keychain,
-				}
-				addressString, err := s.walletAddressString(address)
-				if err != nil {
-					return nil, err
+	for _, account := range s.walletAccounts() {
+		for index := start; index < end; index++ {
+			for cosignerIndex := uint32(0); cosignerIndex < uint32(len(account.extendedPublicKeys)); cosignerIndex++ {
+				for _, keychain := range keyChains {
+					address := &walletAddress{
+						index:         index,
+						cosignerIndex: cosignerIndex,
+						keyChain:      keychain,
+						accountIndex:  account.index,
+					}
+					addressString, err := s.walletAddressString(address)
+					if err != nil {
+						return nil, err
+					}
+					addresses[addressString] = address
 				}
-				addresses[addressString] = address
 			}
 		}
 	}
@@ -120,9 +149,13 @@ func (s *server) maxUsedIndexWithLock() uint32 {
 }
 
 func (s *server) maxUsedIndex() uint32 {
-	maxUsedIndex := s.keysFile.LastUsedExternalIndex()
-	if s.keysFile.LastUsedInternalIndex() > maxUsedIndex {
-		maxUsedIndex = s.keysFile.LastUsedInternalIndex()
+	maxUsedIndex := uint32(0)
+	for _, account := range s.walletAccounts() {
+		for _, keychain := range keyChains {
+			if lastUsedIndex := s.lastUsedIndex(account, keychain); lastUsedIndex > maxUsedIndex {
+				maxUsedIndex = lastUsedIndex
+			}
+		}
 	}
 
 	return maxUsedIndex
@@ -155,6 +188,20 @@ func (s *server) collectRecentAddresses() error {
 	return nil
 }
 
+// collectTimeLockedAddresses adds the time-locked addresses of the wallet to addressSet, so that their
+// UTXOs are tracked whether they were funded yet or not. It runs on the sync loop, which is the only
+// writer of addressSet.
+func (s *server) collectTimeLockedAddresses() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for address, wAddr := range s.timeLockedAddresses.addresses {
+		if _, ok := s.addressSet[address]; !ok {
+			s.addressSet[address] = wAddr
+		}
+	}
+}
+
 func (s *server) collectAddressesWithLock(start, end uint32) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -183,8 +230,13 @@ func (s *server) collectAddresses(start, end uint32) error {
 
 func (s *server) updateAddressesAndLastUsedIndexes(requestedAddressSet walletAddressSet,
 	getBalancesByAddressesResponse *appmessage.GetBalancesByAddressesResponseMessage) error {
-	lastUsedExternalIndex := s.keysFile.LastUsedExternalIndex()
-	lastUsedInternalIndex := s.keysFile.LastUsedInternalIndex()
+	accounts := s.walletAccounts()
+	lastUsedIndexes := make(map[accountKeychain]uint32)
+	for _, account := range accounts {
+		for _, keychain := range keyChains {
+			lastUsedIndexes[accountKeychain{account.index, keychain}] = s.lastUsedIndex(account, keychain)
+		}
+	}
 
 	for _, entry := range getBalancesByAddressesResponse.Entries {
 		walletAddress, ok := requestedAddressSet[entry.Address]
@@ -196,26 +248,26 @@ func (s *server) updateAddressesAndLastUsedIndexes(requestedAddressSet walletAdd
 			continue
 		}
 
-		s.addressSet[entry.Address] = walletAddress
-
-		if walletAddress.keyChain == libkaspiwallet.ExternalKeychain {
-			if walletAddress.index > lastUsedExternalIndex {
-				lastUsedExternalIndex = walletAddress.index
-			}
-			continue
+		// Keeping the existing address lets the UTXOs of an address share the same walletAddress
+		if _, ok := s.addressSet[entry.Address]; !ok {
+			s.addressSet[entry.Address] = walletAddress
 		}
 
-		if walletAddress.index > lastUsedInternalIndex {
-			lastUsedInternalIndex = walletAddress.index
+		key := accountKeychain{walletAddress.accountIndex, walletAddress.keyChain}
+		if walletAddress.index > lastUsedIndexes[key] {
+			lastUsedIndexes[key] = walletAddress.index
 		}
 	}
 
-	err := s.keysFile.SetLastUsedExternalIndex(lastUsedExternalIndex)
-	if err != nil {
-		return err
+	for _, account := range accounts {
+		for _, keychain := range keyChains {
+			err := s.setLastUsedIndex(account, keychain, lastUsedIndexes[accountKeychain{account.index, keychain}])
+			if err != nil {
+				return err
+			}
+		}
 	}
-
-	return s.keysFile.SetLastUsedInternalIndex(lastUsedInternalIndex)
+	return nil
 }
 
 func (s *server) usedOutpointHasExpired(outpointBroadcastTime time.Time) bool {
@@ -270,7 +322,9 @@ func (s *server) updateUTXOSet(entries []*appmessage.UTXOsByAddressesEntry, memp
 	sort.Slice(utxos, func(i, j int) bool { return utxos[i].UTXOEntry.Amount() > utxos[j].UTXOEntry.Amount() })
 
 	s.lock.Lock()
+	defer s.lock.Unlock()
 	s.startTimeOfLastCompletedRefresh = refreshStart
+	previousUTXOs := s.utxosSortedByAmount
 	s.utxosSortedByAmount = utxos
 
 	// Cleanup expired used outpoints to avoid a memory leak
@@ -279,9 +333,8 @@ func (s *server) updateUTXOSet(entries []*appmessage.UTXOsByAddressesEntry, memp
 			delete(s.usedOutpoints, outpoint)
 		}
 	}
-	s.lock.Unlock()
 
-	return nil
+	return s.notifyWalletChanges(previousUTXOs, utxos)
 }
 
 func (s *server) refreshUTXOs() error {
@@ -304,7 +357,12 @@ func (s *server) refreshUTXOs() error {
 		return err
 	}
 
-	return s.updateUTXOSet(getUTXOsByAddressesResponse.Entries, mempoolEntriesByAddresses.Entries, refreshStart)
+	err = s.updateUTXOSet(getUTXOsByAddressesResponse.Entries, mempoolEntriesByAddresses.Entries, refreshStart)
+	if err != nil {
+		return err
+	}
+
+	return s.updateTransactions(getUTXOsByAddressesResponse.Entries, mempoolEntriesByAddresses.Entries, refreshStart)
 }
 
 func (s *server) forceSync() {
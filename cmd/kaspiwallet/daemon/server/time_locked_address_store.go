@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/kaspikr/kaspid/infrastructure/db/database"
+	"github.com/pkg/errors"
+)
+
+var timeLockedAddressesBucket = database.MakeBucket([]byte("time-locked-addresses"))
+
+// serializedTimeLockedAddress is the database representation of a time-locked walletAddress
+type serializedTimeLockedAddress struct {
+	AccountIndex  uint32 `json:"accountIndex"`
+	KeyChain      uint8  `json:"keyChain"`
+	Index         uint32 `json:"index"`
+	CosignerIndex uint32 `json:"cosignerIndex"`
+	LockDAAScore  uint64 `json:"lockDaaScore"`
+}
+
+// timeLockedAddressStore keeps the time-locked addresses the wallet gave out in memory, and writes
+// every new one through to the wallet database. Unlike regular addresses, they can't be found by
+// scanning the derivation indexes, since they depend on their lock DAA score as well.
+type timeLockedAddressStore struct {
+	database  database.Database
+	addresses walletAddressSet
+}
+
+func newTimeLockedAddressStore(db database.Database) (*timeLockedAddressStore, error) {
+	store := &timeLockedAddressStore{
+		database:  db,
+		addresses: make(walletAddressSet),
+	}
+	err := store.restoreAddresses()
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (tlas *timeLockedAddressStore) restoreAddresses() error {
+	cursor, err := tlas.database.Cursor(timeLockedAddressesBucket)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return err
+		}
+		value, err := cursor.Value()
+		if err != nil {
+			return err
+		}
+		serializedAddress := &serializedTimeLockedAddress{}
+		err = json.Unmarshal(value, serializedAddress)
+		if err != nil {
+			return errors.Wrap(err, "error deserializing a time-locked address")
+		}
+		tlas.addresses[string(key.Suffix())] = &walletAddress{
+			index:         serializedAddress.Index,
+			cosignerIndex: serializedAddress.CosignerIndex,
+			keyChain:      serializedAddress.KeyChain,
+			accountIndex:  serializedAddress.AccountIndex,
+			lockTime:      serializedAddress.LockDAAScore,
+		}
+	}
+	return nil
+}
+
+func (tlas *timeLockedAddressStore) add(address string, wAddr *walletAddress) error {
+	serializedAddress, err := json.Marshal(&serializedTimeLockedAddress{
+		AccountIndex:  wAddr.accountIndex,
+		KeyChain:      wAddr.keyChain,
+		Index:         wAddr.index,
+		CosignerIndex: wAddr.cosignerIndex,
+		LockDAAScore:  wAddr.lockTime,
+	})
+	if err != nil {
+		return err
+	}
+	err = tlas.database.Put(timeLockedAddressesBucket.Key([]byte(address)), serializedAddress)
+	if err != nil {
+		return err
+	}
+	tlas.addresses[address] = wAddr
+	return nil
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/kaspikr/kaspid/util/txmass"
+)
+
+func TestTimeLockedAddresses(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "keys.db")
+	db, err := openWalletDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("openWalletDatabase: %s", err)
+	}
+	timeLockedAddresses, err := newTimeLockedAddressStore(db)
+	if err != nil {
+		t.Fatalf("newTimeLockedAddressStore: %s", err)
+	}
+
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %s", err)
+	}
+	serverInstance := &server{
+		params:              params,
+		keysFile:            &keys.File{ExtendedPublicKeys: []string{extendedPublicKey}, MinimumSignatures: 1},
+		txMassCalculator:    txmass.NewCalculator(params.MassPerTxByte, params.MassPerScriptPubKeyByte, params.MassPerSigOp),
+		addressSet:          make(walletAddressSet),
+		timeLockedAddresses: timeLockedAddresses,
+	}
+
+	const lockDAAScore = 1000
+	lockedWalletAddress := &walletAddress{index: 1, keyChain: libkaspiwallet.ExternalKeychain, lockTime: lockDAAScore}
+	lockedAddressString, err := serverInstance.walletAddressString(lockedWalletAddress)
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	lockedAddress, err := util.DecodeAddress(lockedAddressString, params.Prefix)
+	if err != nil {
+		t.Fatalf("DecodeAddress: %s", err)
+	}
+	if _, ok := lockedAddress.(*util.AddressScriptHash); !ok {
+		t.Fatalf("a time-locked address is expected to be a P2SH address, but got %s", lockedAddress)
+	}
+
+	err = timeLockedAddresses.add(lockedAddressString, lockedWalletAddress)
+	if err != nil {
+		t.Fatalf("add: %s", err)
+	}
+	restoredTimeLockedAddresses, err := newTimeLockedAddressStore(db)
+	if err != nil {
+		t.Fatalf("newTimeLockedAddressStore: %s", err)
+	}
+	if restoredAddress, ok := restoredTimeLockedAddresses.addresses[lockedAddressString]; !ok ||
+		*restoredAddress != *lockedWalletAddress {
+		t.Fatalf("the time-locked address %s is expected to be restored, but got %+v", lockedAddressString,
+			restoredTimeLockedAddresses.addresses)
+	}
+	serverInstance.collectTimeLockedAddresses()
+	if _, ok := serverInstance.addressSet[lockedAddressString]; !ok {
+		t.Fatalf("the time-locked address is expected to be tracked")
+	}
+
+	// The change of a time-locked address isn't locked again
+	changeAddress, _, err := serverInstance.changeAddress(serverInstance.defaultAccount(), true,
+		[]*walletAddress{lockedWalletAddress})
+	if err != nil {
+		t.Fatalf("changeAddress: %s", err)
+	}
+	if _, ok := changeAddress.(*util.AddressPublicKey); !ok {
+		t.Fatalf("the change of a time-locked address is expected to be paid to a public key, but got %s", changeAddress)
+	}
+
+	scriptPublicKey, err := txscript.PayToAddrScript(lockedAddress)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %s", err)
+	}
+	lockedUTXO := &walletUTXO{
+		Outpoint:  externalapi.NewDomainOutpoint(externalapi.NewDomainTransactionIDFromByteArray(&[32]byte{1}), 0),
+		UTXOEntry: utxo.NewUTXOEntry(10*constants.SompiPerKaspi, scriptPublicKey, false, 10),
+		address:   lockedWalletAddress,
+	}
+	if serverInstance.isUTXOSpendable(lockedUTXO, lockDAAScore) || !serverInstance.isUTXOTimeLocked(lockedUTXO, lockDAAScore) {
+		t.Fatalf("the UTXO is expected to be locked until the virtual DAA score passes %d", lockDAAScore)
+	}
+	if !serverInstance.isUTXOSpendable(lockedUTXO, lockDAAScore+1) || serverInstance.isUTXOTimeLocked(lockedUTXO, lockDAAScore+1) {
+		t.Fatalf("the UTXO is expected to be spendable once the virtual DAA score passes %d", lockDAAScore)
+	}
+
+	// The mass estimate accounts for the redeem script the signature script of the input reveals
+	spendableUTXO, err := serverInstance.spendableUTXO(lockedUTXO)
+	if err != nil {
+		t.Fatalf("spendableUTXO: %s", err)
+	}
+	if spendableUTXO.RedeemScript == nil {
+		t.Fatalf("a UTXO of a time-locked address is expected to be spent with its redeem script")
+	}
+	payments := []*libkaspiwallet.Payment{{Address: changeAddress, Amount: 9 * constants.SompiPerKaspi}}
+	massEstimate, err := serverInstance.estimateTransactionMass(payments, spendableUTXO)
+	if err != nil {
+		t.Fatalf("estimateTransactionMass: %s", err)
+	}
+	unsignedTransaction, err := serverInstance.createUnsignedTransaction(serverInstance.defaultAccount(), payments,
+		[]*libkaspiwallet.UTXO{spendableUTXO})
+	if err != nil {
+		t.Fatalf("createUnsignedTransaction: %s", err)
+	}
+	transaction, err := serialization.DeserializePartiallySignedTransaction(unsignedTransaction)
+	if err != nil {
+		t.Fatalf("DeserializePartiallySignedTransaction: %s", err)
+	}
+	if transaction.Tx.LockTime != lockDAAScore {
+		t.Fatalf("the lock time of the transaction is %d, while %d is expected", transaction.Tx.LockTime, lockDAAScore)
+	}
+	mass, err := serverInstance.estimateMassAfterSignatures(transaction)
+	if err != nil {
+		t.Fatalf("estimateMassAfterSignatures: %s", err)
+	}
+	if estimatedMass := massEstimate.mass([]*libkaspiwallet.UTXO{spendableUTXO}); estimatedMass != mass {
+		t.Fatalf("the estimated mass is %d, while the mass after signatures is %d", estimatedMass, mass)
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/kaspikr/kaspid/infrastructure/db/database"
+	"github.com/pkg/errors"
+)
+
+var transactionsBucket = database.MakeBucket([]byte("transactions"))
+
+// transactionStore keeps the transactions of the wallet in memory, and
+// writes every change to them through to the wallet database
+type transactionStore struct {
+	database     database.Database
+	transactions map[string]*walletTransaction
+}
+
+func newTransactionStore(db database.Database) (*transactionStore, error) {
+	store := &transactionStore{
+		database:     db,
+		transactions: make(map[string]*walletTransaction),
+	}
+	err := store.restoreTransactions()
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (ts *transactionStore) restoreTransactions() error {
+	cursor, err := ts.database.Cursor(transactionsBucket)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		serializedTransaction, err := cursor.Value()
+		if err != nil {
+			return err
+		}
+		transaction := &walletTransaction{}
+		err = json.Unmarshal(serializedTransaction, transaction)
+		if err != nil {
+			return errors.Wrap(err, "error deserializing a wallet transaction")
+		}
+		ts.transactions[transaction.ID] = transaction
+	}
+	return nil
+}
+
+func (ts *transactionStore) get(transactionID string) (*walletTransaction, bool) {
+	transaction, ok := ts.transactions[transactionID]
+	return transaction, ok
+}
+
+func (ts *transactionStore) put(transaction *walletTransaction) error {
+	serializedTransaction, err := json.Marshal(transaction)
+	if err != nil {
+		return err
+	}
+	err = ts.database.Put(transactionsBucket.Key([]byte(transaction.ID)), serializedTransaction)
+	if err != nil {
+		return err
+	}
+	ts.transactions[transaction.ID] = transaction
+	return nil
+}
+
+// all returns all the transactions, the most recently seen first
+func (ts *transactionStore) all() []*walletTransaction {
+	transactions := make([]*walletTransaction, 0, len(ts.transactions))
+	for _, transaction := range ts.transactions {
+		transactions = append(transactions, transaction)
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].Timestamp != transactions[j].Timestamp {
+			return transactions[i].Timestamp > transactions[j].Timestamp
+		}
+		return transactions[i].ID < transactions[j].ID
+	})
+	return transactions
+}
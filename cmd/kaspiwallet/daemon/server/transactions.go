@@ -0,0 +1,459 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/pkg/errors"
+)
+
+// A pending transaction that wasn't seen in the mempool for droppedTransactionTimeout,
+// and wasn't accepted by the DAG, is considered dropped. Like with usedOutpointHasExpired,
+// the timeout lets the refreshes that started before the transaction was broadcast complete.
+const droppedTransactionTimeout = time.Minute
+
+type transactionStatus uint8
+
+const (
+	transactionStatusPending transactionStatus = iota
+	transactionStatusConfirmed
+	transactionStatusDropped
+)
+
+var transactionStatusToPB = map[transactionStatus]pb.TransactionStatus{
+	transactionStatusPending:   pb.TransactionStatus_PENDING,
+	transactionStatusConfirmed: pb.TransactionStatus_CONFIRMED,
+	transactionStatusDropped:   pb.TransactionStatus_DROPPED,
+}
+
+// walletTransaction is a transaction the wallet received or sent funds in,
+// as it's kept in the wallet database
+type walletTransaction struct {
+	ID                   string                     `json:"id"`
+	IsOutgoing           bool                       `json:"isOutgoing"`
+	Status               transactionStatus          `json:"status"`
+	Inputs               []*walletTransactionInput  `json:"inputs,omitempty"`
+	Outputs              []*walletTransactionOutput `json:"outputs"`
+	Fee                  uint64                     `json:"fee,omitempty"`
+	ConfirmationDAAScore uint64                     `json:"confirmationDaaScore,omitempty"`
+	IsCoinbase           bool                       `json:"isCoinbase,omitempty"`
+	Timestamp            int64                      `json:"timestamp"`
+
+	// lastSeenPending is when the transaction was last seen in the mempool or
+	// broadcast. It isn't persisted, so that transactions that were pending when
+	// the daemon stopped are resolved by the first refresh after it starts.
+	lastSeenPending time.Time
+}
+
+type walletTransactionInput struct {
+	TransactionID string `json:"transactionId"`
+	Index         uint32 `json:"index"`
+}
+
+type walletTransactionOutput struct {
+	Index           uint32 `json:"index"`
+	Address         string `json:"address,omitempty"`
+	Amount          uint64 `json:"amount"`
+	IsWalletAddress bool   `json:"isWalletAddress,omitempty"`
+}
+
+// amount returns the amount the wallet received in an incoming transaction, or the
+// amount it sent to addresses that don't belong to it in an outgoing transaction
+func (wt *walletTransaction) amount() uint64 {
+	amount := uint64(0)
+	for _, output := range wt.Outputs {
+		if output.IsWalletAddress != wt.IsOutgoing {
+			amount += output.Amount
+		}
+	}
+	return amount
+}
+
+func (wt *walletTransaction) hasOutput(index uint32) bool {
+	for _, output := range wt.Outputs {
+		if output.Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+func (wt *walletTransaction) toPB() *pb.TransactionInfo {
+	outputs := make([]*pb.TransactionOutputInfo, len(wt.Outputs))
+	for i, output := range wt.Outputs {
+		outputs[i] = &pb.TransactionOutputInfo{
+			Index:           output.Index,
+			Address:         output.Address,
+			Amount:          output.Amount,
+			IsWalletAddress: output.IsWalletAddress,
+		}
+	}
+	return &pb.TransactionInfo{
+		TransactionId:        wt.ID,
+		IsOutgoing:           wt.IsOutgoing,
+		Status:               transactionStatusToPB[wt.Status],
+		Amount:               wt.amount(),
+		Fee:                  wt.Fee,
+		Outputs:              outputs,
+		ConfirmationDaaScore: wt.ConfirmationDAAScore,
+		IsCoinbase:           wt.IsCoinbase,
+		Timestamp:            wt.Timestamp,
+	}
+}
+
+func (s *server) GetTransactions(_ context.Context, request *pb.GetTransactionsRequest) (*pb.GetTransactionsResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	transactions := s.transactionStore.all()
+	if request.Limit != 0 && uint32(len(transactions)) > request.Limit {
+		transactions = transactions[:request.Limit]
+	}
+
+	transactionInfos := make([]*pb.TransactionInfo, len(transactions))
+	for i, transaction := range transactions {
+		transactionInfos[i] = transaction.toPB()
+	}
+	return &pb.GetTransactionsResponse{Transactions: transactionInfos}, nil
+}
+
+func (s *server) GetTransaction(_ context.Context, request *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	transaction, ok := s.transactionStore.get(request.TransactionId)
+	if !ok {
+		return nil, errors.Errorf("transaction %s is not a transaction of this wallet", request.TransactionId)
+	}
+	return &pb.GetTransactionResponse{Transaction: transaction.toPB()}, nil
+}
+
+// newWalletTransaction creates a walletTransaction from a transaction that was seen in the mempool
+// or broadcast. utxoAmount returns the amounts of the UTXOs of the wallet, and is used to calculate
+// the fee of outgoing transactions.
+func (s *server) newWalletTransaction(tx *externalapi.DomainTransaction, isOutgoing bool,
+	utxoAmount func(outpoint externalapi.DomainOutpoint) (uint64, bool), now time.Time) (*walletTransaction, error) {
+
+	transaction := &walletTransaction{
+		ID:              consensushashing.TransactionID(tx).String(),
+		IsOutgoing:      isOutgoing,
+		Status:          transactionStatusPending,
+		Inputs:          make([]*walletTransactionInput, len(tx.Inputs)),
+		Outputs:         make([]*walletTransactionOutput, len(tx.Outputs)),
+		Timestamp:       now.UnixMilli(),
+		lastSeenPending: now,
+	}
+
+	inputsAmount := uint64(0)
+	areInputAmountsKnown := true
+	for i, input := range tx.Inputs {
+		transaction.Inputs[i] = &walletTransactionInput{
+			TransactionID: input.PreviousOutpoint.TransactionID.String(),
+			Index:         input.PreviousOutpoint.Index,
+		}
+		amount, ok := utxoAmount(input.PreviousOutpoint)
+		if !ok {
+			areInputAmountsKnown = false
+		}
+		inputsAmount += amount
+	}
+
+	outputsAmount := uint64(0)
+	for i, output := range tx.Outputs {
+		addressString := ""
+		isWalletAddress := false
+		_, address, err := txscript.ExtractScriptPubKeyAddress(output.ScriptPublicKey, s.params)
+		if err == nil && address != nil {
+			addressString = address.String()
+			isWalletAddress, err = s.isWalletAddress(addressString)
+			if err != nil {
+				return nil, err
+			}
+		}
+		transaction.Outputs[i] = &walletTransactionOutput{
+			Index:           uint32(i),
+			Address:         addressString,
+			Amount:          output.Value,
+			IsWalletAddress: isWalletAddress,
+		}
+		outputsAmount += output.Value
+	}
+
+	if isOutgoing && areInputAmountsKnown && inputsAmount >= outputsAmount {
+		transaction.Fee = inputsAmount - outputsAmount
+	}
+	return transaction, nil
+}
+
+// recordBroadcastTransaction records a transaction that was broadcast by the wallet
+// as pending. It's outgoing if it spends any of the UTXOs of the wallet.
+func (s *server) recordBroadcastTransaction(tx *externalapi.DomainTransaction) error {
+	now := time.Now()
+	transactionID := consensushashing.TransactionID(tx).String()
+	if transaction, ok := s.transactionStore.get(transactionID); ok {
+		if transaction.Status == transactionStatusConfirmed {
+			return nil
+		}
+		transaction.Status = transactionStatusPending
+		transaction.lastSeenPending = now
+		return s.transactionStore.put(transaction)
+	}
+
+	utxoAmounts := make(map[externalapi.DomainOutpoint]uint64, len(s.utxosSortedByAmount))
+	for _, utxo := range s.utxosSortedByAmount {
+		utxoAmounts[*utxo.Outpoint] = utxo.UTXOEntry.Amount()
+	}
+	utxoAmount := func(outpoint externalapi.DomainOutpoint) (uint64, bool) {
+		amount, ok := utxoAmounts[outpoint]
+		return amount, ok
+	}
+
+	isOutgoing := false
+	for _, input := range tx.Inputs {
+		if _, ok := utxoAmounts[input.PreviousOutpoint]; ok {
+			isOutgoing = true
+			break
+		}
+	}
+
+	transaction, err := s.newWalletTransaction(tx, isOutgoing, utxoAmount, now)
+	if err != nil {
+		return err
+	}
+	return s.transactionStore.put(transaction)
+}
+
+// updateTransactions records the transactions of the wallet that were observed in a refresh:
+// transactions in the mempool that spend from or pay to the wallet, and the transactions that
+// created the UTXOs of the wallet. It also resolves the status of pending transactions that
+// left the mempool.
+func (s *server) updateTransactions(entries []*appmessage.UTXOsByAddressesEntry,
+	mempoolEntries []*appmessage.MempoolEntryByAddress, refreshStart time.Time) error {
+
+	// entries include the UTXOs that are spent in the mempool, so they
+	// hold the amounts of the inputs of pending outgoing transactions
+	utxoAmounts := make(map[externalapi.DomainOutpoint]uint64, len(entries))
+	entriesByTransactionID := make(map[string][]*appmessage.UTXOsByAddressesEntry)
+	for _, entry := range entries {
+		outpoint, err := appmessage.RPCOutpointToDomainOutpoint(entry.Outpoint)
+		if err != nil {
+			return err
+		}
+		utxoAmounts[*outpoint] = entry.UTXOEntry.Amount
+		entriesByTransactionID[entry.Outpoint.TransactionID] =
+			append(entriesByTransactionID[entry.Outpoint.TransactionID], entry)
+	}
+	utxoAmount := func(outpoint externalapi.DomainOutpoint) (uint64, bool) {
+		amount, ok := utxoAmounts[outpoint]
+		return amount, ok
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// A transaction is listed under every address of the wallet it spends from or pays to,
+	// so the transactions that send from the wallet are observed first, to have them recorded
+	// as outgoing even if they pay change to the wallet.
+	inMempool := make(map[string]struct{})
+	for _, isOutgoing := range []bool{true, false} {
+		for _, entriesByAddress := range mempoolEntries {
+			addressMempoolEntries := entriesByAddress.Receiving
+			if isOutgoing {
+				addressMempoolEntries = entriesByAddress.Sending
+			}
+			for _, mempoolEntry := range addressMempoolEntries {
+				err := s.observeMempoolTransaction(mempoolEntry.Transaction, isOutgoing, utxoAmount, inMempool, refreshStart)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for transactionID, transactionEntries := range entriesByTransactionID {
+		err := s.observeConfirmedOutputs(transactionID, transactionEntries, refreshStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.resolvePendingTransactions(utxoAmounts, inMempool, refreshStart)
+}
+
+func (s *server) observeMempoolTransaction(rpcTransaction *appmessage.RPCTransaction, isOutgoing bool,
+	utxoAmount func(outpoint externalapi.DomainOutpoint) (uint64, bool), inMempool map[string]struct{},
+	refreshStart time.Time) error {
+
+	tx, err := appmessage.RPCTransactionToDomainTransaction(rpcTransaction)
+	if err != nil {
+		return err
+	}
+	transactionID := consensushashing.TransactionID(tx).String()
+	if _, ok := inMempool[transactionID]; ok {
+		return nil
+	}
+	inMempool[transactionID] = struct{}{}
+
+	transaction, ok := s.transactionStore.get(transactionID)
+	if !ok {
+		transaction, err = s.newWalletTransaction(tx, isOutgoing, utxoAmount, refreshStart)
+		if err != nil {
+			return err
+		}
+		return s.transactionStore.put(transaction)
+	}
+
+	transaction.lastSeenPending = refreshStart
+	if transaction.Status != transactionStatusDropped {
+		return nil
+	}
+	// The transaction was rebroadcast
+	transaction.Status = transactionStatusPending
+	return s.transactionStore.put(transaction)
+}
+
+// observeConfirmedOutputs records that the transaction with the given ID, which created
+// the given UTXOs of the wallet, was confirmed. Transactions that weren't seen before are
+// recorded as incoming, with only the outputs that belong to the wallet.
+func (s *server) observeConfirmedOutputs(transactionID string,
+	entries []*appmessage.UTXOsByAddressesEntry, refreshStart time.Time) error {
+
+	transaction, ok := s.transactionStore.get(transactionID)
+	isChanged := false
+	if !ok {
+		transaction = &walletTransaction{
+			ID:         transactionID,
+			IsCoinbase: entries[0].UTXOEntry.IsCoinbase,
+			Timestamp:  refreshStart.UnixMilli(),
+		}
+		isChanged = true
+	}
+
+	for _, entry := range entries {
+		if transaction.hasOutput(entry.Outpoint.Index) {
+			continue
+		}
+		transaction.Outputs = append(transaction.Outputs, &walletTransactionOutput{
+			Index:           entry.Outpoint.Index,
+			Address:         entry.Address,
+			Amount:          entry.UTXOEntry.Amount,
+			IsWalletAddress: true,
+		})
+		isChanged = true
+	}
+	sort.Slice(transaction.Outputs, func(i, j int) bool {
+		return transaction.Outputs[i].Index < transaction.Outputs[j].Index
+	})
+
+	if transaction.Status != transactionStatusConfirmed {
+		transaction.Status = transactionStatusConfirmed
+		transaction.ConfirmationDAAScore = entries[0].UTXOEntry.BlockDAAScore
+		isChanged = true
+	}
+
+	if !isChanged {
+		return nil
+	}
+	return s.transactionStore.put(transaction)
+}
+
+// resolvePendingTransactions resolves the status of the pending transactions that
+// are no longer in the mempool, and none of whose outputs is a UTXO of the wallet.
+// An outgoing transaction all of whose spent UTXOs are gone was accepted, and is
+// considered confirmed at the current virtual DAA score. Other transactions are
+// considered dropped after droppedTransactionTimeout.
+func (s *server) resolvePendingTransactions(utxoAmounts map[externalapi.DomainOutpoint]uint64,
+	inMempool map[string]struct{}, refreshStart time.Time) error {
+
+	virtualDAAScore := uint64(0)
+	for transactionID, transaction := range s.transactionStore.transactions {
+		if transaction.Status != transactionStatusPending {
+			continue
+		}
+		if _, ok := inMempool[transactionID]; ok {
+			continue
+		}
+
+		if transaction.IsOutgoing {
+			areInputsSpent := true
+			for _, input := range transaction.Inputs {
+				previousTransactionID, err := externalapi.NewDomainTransactionIDFromString(input.TransactionID)
+				if err != nil {
+					return err
+				}
+				outpoint := externalapi.DomainOutpoint{TransactionID: *previousTransactionID, Index: input.Index}
+				if _, ok := utxoAmounts[outpoint]; ok {
+					areInputsSpent = false
+					break
+				}
+			}
+			if areInputsSpent {
+				if virtualDAAScore == 0 {
+					dagInfo, err := s.backgroundRPCClient.GetBlockDAGInfo()
+					if err != nil {
+						return err
+					}
+					virtualDAAScore = dagInfo.VirtualDAAScore
+				}
+				transaction.Status = transactionStatusConfirmed
+				transaction.ConfirmationDAAScore = virtualDAAScore
+				err := s.transactionStore.put(transaction)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if refreshStart.After(transaction.lastSeenPending.Add(droppedTransactionTimeout)) {
+			transaction.Status = transactionStatusDropped
+			err := s.transactionStore.put(transaction)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isWalletAddress returns whether the given address belongs to the wallet: whether
+// it has a balance, or is an address of this cosigner up to the last used indexes.
+// The latter include new change addresses, which don't have a balance yet.
+func (s *server) isWalletAddress(address string) (bool, error) {
+	if _, ok := s.addressSet[address]; ok {
+		return true, nil
+	}
+
+	if s.ownAddresses == nil {
+		s.ownAddresses = make(map[string]struct{})
+		s.nextOwnAddressIndexes = make(map[accountKeychain]uint32)
+	}
+	for _, account := range s.walletAccounts() {
+		for _, keychain := range keyChains {
+			key := accountKeychain{account.index, keychain}
+			lastUsedIndex := s.lastUsedIndex(account, keychain)
+			for index := s.nextOwnAddressIndexes[key]; index <= lastUsedIndex; index++ {
+				ownAddress, err := s.walletAddressString(&walletAddress{
+					index:         index,
+					cosignerIndex: account.cosignerIndex,
+					keyChain:      keychain,
+					accountIndex:  account.index,
+				})
+				if err != nil {
+					return false, err
+				}
+				s.ownAddresses[ownAddress] = struct{}{}
+			}
+			s.nextOwnAddressIndexes[key] = lastUsedIndex + 1
+		}
+	}
+
+	_, ok := s.ownAddresses[address]
+	return ok, nil
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+)
+
+func TestTransactionHistory(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %s", err)
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "keys.db")
+	db, err := openWalletDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("openWalletDatabase: %s", err)
+	}
+	store, err := newTransactionStore(db)
+	if err != nil {
+		t.Fatalf("newTransactionStore: %s", err)
+	}
+	serverInstance := &server{
+		params:           params,
+		keysFile:         &keys.File{ExtendedPublicKeys: []string{extendedPublicKey}, MinimumSignatures: 1},
+		addressSet:       make(walletAddressSet),
+		transactionStore: store,
+	}
+
+	receiveWalletAddress := &walletAddress{index: 0, keyChain: libkaspiwallet.ExternalKeychain}
+	receiveAddress, err := serverInstance.walletAddressString(receiveWalletAddress)
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	serverInstance.addressSet[receiveAddress] = receiveWalletAddress
+	changeAddress, err := serverInstance.walletAddressString(&walletAddress{index: 0, keyChain: libkaspiwallet.InternalKeychain})
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	externalAddress, err := util.NewAddressPublicKey(make([]byte, 32), params.Prefix)
+	if err != nil {
+		t.Fatalf("NewAddressPublicKey: %s", err)
+	}
+
+	scriptPublicKey := func(address string) *externalapi.ScriptPublicKey {
+		decodedAddress, err := util.DecodeAddress(address, params.Prefix)
+		if err != nil {
+			t.Fatalf("DecodeAddress: %s", err)
+		}
+		scriptPublicKey, err := txscript.PayToAddrScript(decodedAddress)
+		if err != nil {
+			t.Fatalf("PayToAddrScript: %s", err)
+		}
+		return scriptPublicKey
+	}
+	utxoEntry := func(outpoint *externalapi.DomainOutpoint, address string, amount uint64,
+		daaScore uint64) *appmessage.UTXOsByAddressesEntry {
+
+		return &appmessage.UTXOsByAddressesEntry{
+			Address: address,
+			Outpoint: &appmessage.RPCOutpoint{
+				TransactionID: outpoint.TransactionID.String(),
+				Index:         outpoint.Index,
+			},
+			UTXOEntry: &appmessage.RPCUTXOEntry{
+				Amount: amount,
+				ScriptPublicKey: &appmessage.RPCScriptPublicKey{
+					Version: scriptPublicKey(address).Version,
+					Script:  hex.EncodeToString(scriptPublicKey(address).Script),
+				},
+				BlockDAAScore: daaScore,
+			},
+		}
+	}
+	setWalletUTXOs := func(entries ...*appmessage.UTXOsByAddressesEntry) {
+		err := serverInstance.updateUTXOSet(entries, nil, time.Now())
+		if err != nil {
+			t.Fatalf("updateUTXOSet: %s", err)
+		}
+	}
+	spend := func(outpoint *externalapi.DomainOutpoint, outputs ...*externalapi.DomainTransactionOutput) *externalapi.DomainTransaction {
+		return &externalapi.DomainTransaction{
+			Version:      constants.MaxTransactionVersion,
+			Inputs:       []*externalapi.DomainTransactionInput{{PreviousOutpoint: *outpoint, SigOpCount: 1}},
+			Outputs:      outputs,
+			SubnetworkID: subnetworks.SubnetworkIDNative,
+		}
+	}
+	expectTransaction := func(transactionID string, isOutgoing bool, status transactionStatus,
+		amount uint64, fee uint64, confirmationDAAScore uint64) *walletTransaction {
+
+		t.Helper()
+		transaction, ok := serverInstance.transactionStore.get(transactionID)
+		if !ok {
+			t.Fatalf("transaction %s wasn't recorded", transactionID)
+		}
+		if transaction.IsOutgoing != isOutgoing || transaction.Status != status || transaction.amount() != amount ||
+			transaction.Fee != fee || transaction.ConfirmationDAAScore != confirmationDAAScore {
+			t.Fatalf("unexpected transaction %+v with amount %d", transaction, transaction.amount())
+		}
+		return transaction
+	}
+
+	// A transaction that was first seen confirmed is recorded as incoming
+	incomingOutpoint := externalapi.NewDomainOutpoint(externalapi.NewDomainTransactionIDFromByteArray(&[32]byte{1}), 2)
+	incomingEntry := utxoEntry(incomingOutpoint, receiveAddress, 10*constants.SompiPerKaspi, 100)
+	setWalletUTXOs(incomingEntry)
+	err = serverInstance.updateTransactions([]*appmessage.UTXOsByAddressesEntry{incomingEntry}, nil, time.Now())
+	if err != nil {
+		t.Fatalf("updateTransactions: %s", err)
+	}
+	expectTransaction(incomingOutpoint.TransactionID.String(), false, transactionStatusConfirmed,
+		10*constants.SompiPerKaspi, 0, 100)
+
+	// A broadcast transaction that spends from the wallet is recorded as outgoing, with
+	// its change to a new change address counted as the wallet's
+	const fee = 10_000
+	sendTransaction := spend(incomingOutpoint,
+		&externalapi.DomainTransactionOutput{Value: 3 * constants.SompiPerKaspi, ScriptPublicKey: scriptPublicKey(externalAddress.String())},
+		&externalapi.DomainTransactionOutput{Value: 7*constants.SompiPerKaspi - fee, ScriptPublicKey: scriptPublicKey(changeAddress)})
+	sendTransactionID := consensushashing.TransactionID(sendTransaction).String()
+	err = serverInstance.recordBroadcastTransaction(sendTransaction)
+	if err != nil {
+		t.Fatalf("recordBroadcastTransaction: %s", err)
+	}
+	transaction := expectTransaction(sendTransactionID, true, transactionStatusPending, 3*constants.SompiPerKaspi, fee, 0)
+	if transaction.Outputs[0].Address != externalAddress.String() || transaction.Outputs[0].IsWalletAddress ||
+		!transaction.Outputs[1].IsWalletAddress {
+		t.Fatalf("unexpected outputs %+v %+v", transaction.Outputs[0], transaction.Outputs[1])
+	}
+
+	// It stays pending while it's in the mempool, even after the drop timeout
+	mempoolEntries := []*appmessage.MempoolEntryByAddress{{
+		Address: receiveAddress,
+		Sending: []*appmessage.MempoolEntry{{Transaction: appmessage.DomainTransactionToRPCTransaction(sendTransaction)}},
+	}}
+	err = serverInstance.updateTransactions([]*appmessage.UTXOsByAddressesEntry{incomingEntry}, mempoolEntries,
+		time.Now().Add(2*droppedTransactionTimeout))
+	if err != nil {
+		t.Fatalf("updateTransactions: %s", err)
+	}
+	expectTransaction(sendTransactionID, true, transactionStatusPending, 3*constants.SompiPerKaspi, fee, 0)
+
+	// It's confirmed once its change is a UTXO of the wallet
+	changeOutpoint := &externalapi.DomainOutpoint{TransactionID: *consensushashing.TransactionID(sendTransaction), Index: 1}
+	changeEntry := utxoEntry(changeOutpoint, changeAddress, 7*constants.SompiPerKaspi-fee, 105)
+	serverInstance.addressSet[changeAddress] = &walletAddress{index: 0, keyChain: libkaspiwallet.InternalKeychain}
+	setWalletUTXOs(changeEntry)
+	err = serverInstance.updateTransactions([]*appmessage.UTXOsByAddressesEntry{changeEntry}, nil, time.Now())
+	if err != nil {
+		t.Fatalf("updateTransactions: %s", err)
+	}
+	expectTransaction(sendTransactionID, true, transactionStatusConfirmed, 3*constants.SompiPerKaspi, fee, 105)
+
+	// A transaction that left the mempool while its input is still unspent is dropped after the timeout
+	droppedTransaction := spend(changeOutpoint,
+		&externalapi.DomainTransactionOutput{Value: 7*constants.SompiPerKaspi - 2*fee, ScriptPublicKey: scriptPublicKey(externalAddress.String())})
+	droppedTransactionID := consensushashing.TransactionID(droppedTransaction).String()
+	err = serverInstance.recordBroadcastTransaction(droppedTransaction)
+	if err != nil {
+		t.Fatalf("recordBroadcastTransaction: %s", err)
+	}
+	err = serverInstance.updateTransactions([]*appmessage.UTXOsByAddressesEntry{changeEntry}, nil, time.Now())
+	if err != nil {
+		t.Fatalf("updateTransactions: %s", err)
+	}
+	expectTransaction(droppedTransactionID, true, transactionStatusPending, 7*constants.SompiPerKaspi-2*fee, fee, 0)
+	err = serverInstance.updateTransactions([]*appmessage.UTXOsByAddressesEntry{changeEntry}, nil,
+		time.Now().Add(2*droppedTransactionTimeout))
+	if err != nil {
+		t.Fatalf("updateTransactions: %s", err)
+	}
+	expectTransaction(droppedTransactionID, true, transactionStatusDropped, 7*constants.SompiPerKaspi-2*fee, fee, 0)
+
+	// The history survives a restart
+	err = db.Close()
+	if err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	db, err = openWalletDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("openWalletDatabase: %s", err)
+	}
+	defer db.Close()
+	serverInstance.transactionStore, err = newTransactionStore(db)
+	if err != nil {
+		t.Fatalf("newTransactionStore: %s", err)
+	}
+	if len(serverInstance.transactionStore.all()) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(serverInstance.transactionStore.all()))
+	}
+	expectTransaction(sendTransactionID, true, transactionStatusConfirmed, 3*constants.SompiPerKaspi, fee, 105)
+	expectTransaction(droppedTransactionID, true, transactionStatusDropped, 7*constants.SompiPerKaspi-2*fee, fee, 0)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/pkg/errors"
+)
+
+func (s *server) UnlockWallet(_ context.Context, request *pb.UnlockWalletRequest) (*pb.UnlockWalletResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.keysFile.IsWatchOnly() {
+		return nil, errors.Errorf("a watch-only wallet can't be unlocked, since it has no private keys")
+	}
+	if request.Password == "" {
+		return nil, errors.Errorf("the password can't be empty")
+	}
+	if request.Timeout == 0 {
+		return nil, errors.Errorf("the unlock timeout must be positive")
+	}
+
+	mnemonics, passphrases, err := s.decryptMnemonics(request.Password)
+	if err != nil {
+		return nil, err
+	}
+	s.unlock(mnemonics, passphrases, time.Duration(request.Timeout)*time.Second)
+	log.Infof("The wallet is unlocked until %s", s.unlockedUntil.Format(time.RFC3339))
+
+	return &pb.UnlockWalletResponse{UnlockedUntil: s.unlockedUntil.UnixMilli()}, nil
+}
+
+func (s *server) LockWallet(_ context.Context, _ *pb.LockWalletRequest) (*pb.LockWalletResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isUnlocked() {
+		s.lockKeys()
+		log.Infof("The wallet is locked")
+	}
+	return &pb.LockWalletResponse{}, nil
+}
+
+// unlock keeps the decrypted keys of the wallet for timeout, replacing the keys of a previous unlock.
+// s.lock must be held for writing.
+func (s *server) unlock(mnemonics []string, passphrases []string, timeout time.Duration) {
+	s.lockKeys()
+
+	s.unlockedMnemonics = mnemonics
+	s.unlockedPassphrases = passphrases
+	s.unlockedUntil = time.Now().Add(timeout)
+
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		// The wallet may have been locked, or unlocked again, while this waited for the lock
+		if s.lockTimer != timer {
+			return
+		}
+		s.lockKeys()
+		log.Infof("The unlock timeout passed, the wallet is locked")
+	})
+	s.lockTimer = timer
+}
+
+// lockKeys forgets the decrypted keys of the wallet. s.lock must be held for writing.
+func (s *server) lockKeys() {
+	if s.lockTimer != nil {
+		s.lockTimer.Stop()
+		s.lockTimer = nil
+	}
+	s.unlockedMnemonics = nil
+	s.unlockedPassphrases = nil
+	s.unlockedUntil = time.Time{}
+}
+
+func (s *server) isUnlocked() bool {
+	return s.unlockedMnemonics != nil
+}
+
+// decryptMnemonics returns the decrypted keys of the wallet. An empty password returns
+// the keys of an unlocked wallet.
+func (s *server) decryptMnemonics(password string) (mnemonics []string, passphrases []string, err error) {
+	if password != "" {
+		mnemonics, passphrases, err = s.keysFile.DecryptMnemonics(password)
+		if err != nil && strings.Contains(err.Error(), "message authentication failed") {
+			return nil, nil, classifyErrorf(errInvalidPassword, "the password is wrong: %s", err)
+		}
+		return mnemonics, passphrases, err
+	}
+	if !s.isUnlocked() {
+		return nil, nil, classifyErrorf(errWalletLocked, "the wallet is locked: either give the password, "+
+			"or unlock the wallet with `kaspiwallet unlock`")
+	}
+	return s.unlockedMnemonics, s.unlockedPassphrases, nil
+}
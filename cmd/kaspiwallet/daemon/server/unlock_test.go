@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+)
+
+func TestUnlockWallet(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	keysFile, err := keys.NewFileFromMnemonic(params, mnemonic, "password")
+	if err != nil {
+		t.Fatalf("NewFileFromMnemonic: %s", err)
+	}
+	serverInstance := &server{params: params, keysFile: keysFile}
+
+	_, _, err = serverInstance.decryptMnemonics("")
+	if err == nil {
+		t.Fatalf("a locked wallet is expected to require the password")
+	}
+	_, err = serverInstance.UnlockWallet(context.Background(), &pb.UnlockWalletRequest{Password: "wrong", Timeout: 60})
+	if err == nil {
+		t.Fatalf("unlocking with a wrong password is expected to fail")
+	}
+
+	response, err := serverInstance.UnlockWallet(context.Background(),
+		&pb.UnlockWalletRequest{Password: "password", Timeout: 60})
+	if err != nil {
+		t.Fatalf("UnlockWallet: %s", err)
+	}
+	if unlockedUntil := time.UnixMilli(response.UnlockedUntil); time.Until(unlockedUntil) <= 0 {
+		t.Fatalf("the wallet is expected to be unlocked until a future time, but got %s", unlockedUntil)
+	}
+	mnemonics, _, err := serverInstance.decryptMnemonics("")
+	if err != nil {
+		t.Fatalf("decryptMnemonics: %s", err)
+	}
+	if len(mnemonics) != 1 || mnemonics[0] != mnemonic {
+		t.Fatalf("an unlocked wallet is expected to return its mnemonic")
+	}
+
+	_, err = serverInstance.LockWallet(context.Background(), &pb.LockWalletRequest{})
+	if err != nil {
+		t.Fatalf("LockWallet: %s", err)
+	}
+	_, _, err = serverInstance.decryptMnemonics("")
+	if err == nil {
+		t.Fatalf("a locked wallet is expected to require the password")
+	}
+
+	// The wallet is locked again once the timeout passes
+	serverInstance.lock.Lock()
+	serverInstance.unlock([]string{mnemonic}, []string{""}, time.Millisecond)
+	serverInstance.lock.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		serverInstance.lock.RLock()
+		isUnlocked := serverInstance.isUnlocked()
+		serverInstance.lock.RUnlock()
+		if !isUnlocked {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the wallet is expected to be locked once the unlock timeout passes")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/pkg/errors"
+)
+
+func (s *server) GetUtxos(_ context.Context, request *pb.GetUtxosRequest) (*pb.GetUtxosResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.isSynced() {
+		return nil, s.notSyncedError()
+	}
+
+	var requestedAccount *walletAccount
+	if request.Account != "" {
+		var err error
+		requestedAccount, err = s.accountByName(request.Account)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var requestedAddresses map[string]struct{}
+	if len(request.Addresses) > 0 {
+		requestedAddresses = make(map[string]struct{}, len(request.Addresses))
+		for _, address := range request.Addresses {
+			if _, ok := s.addressSet[address]; !ok {
+				return nil, errors.Errorf("address %s is not a used address of the wallet", address)
+			}
+			requestedAddresses[address] = struct{}{}
+		}
+	}
+
+	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	accountNames := make(map[uint32]string)
+	for _, account := range s.walletAccounts() {
+		accountNames[account.index] = account.name
+	}
+
+	addressStrings := make(map[*walletAddress]string)
+	utxos := make([]*pb.WalletUtxo, 0, len(s.utxosSortedByAmount))
+	for _, utxo := range s.utxosSortedByAmount {
+		if requestedAccount != nil && utxo.address.accountIndex != requestedAccount.index {
+			continue
+		}
+		address, ok := addressStrings[utxo.address]
+		if !ok {
+			address, err = s.walletAddressString(utxo.address)
+			if err != nil {
+				return nil, err
+			}
+			addressStrings[utxo.address] = address
+		}
+		if requestedAddresses != nil {
+			if _, ok := requestedAddresses[address]; !ok {
+				continue
+			}
+		}
+
+		utxos = append(utxos, s.walletUTXOToPB(utxo, address, accountNames[utxo.address.accountIndex],
+			dagInfo.VirtualDAAScore))
+	}
+
+	return &pb.GetUtxosResponse{Utxos: utxos}, nil
+}
+
+func (s *server) walletUTXOToPB(utxo *walletUTXO, address string, accountName string,
+	virtualDAAScore uint64) *pb.WalletUtxo {
+
+	walletUTXO := &pb.WalletUtxo{
+		Outpoint: &pb.Outpoint{
+			TransactionId: utxo.Outpoint.TransactionID.String(),
+			Index:         utxo.Outpoint.Index,
+		},
+		Address:       address,
+		Amount:        utxo.UTXOEntry.Amount(),
+		BlockDaaScore: utxo.UTXOEntry.BlockDAAScore(),
+		IsCoinbase:    utxo.UTXOEntry.IsCoinbase(),
+		IsSpendable:   s.isUTXOSpendable(utxo, virtualDAAScore),
+		IsPending:     s.isOutpointPending(*utxo.Outpoint),
+		Account:       accountName,
+		AddressLabel:  s.labels.addressLabel(address),
+		OutpointLabel: s.labels.outpointLabel(*utxo.Outpoint),
+		LockDaaScore:  utxo.address.lockTime,
+	}
+	if locked, ok := s.lockedOutpoints.get(*utxo.Outpoint); ok {
+		walletUTXO.IsLocked = true
+		walletUTXO.Label = locked.Label
+	}
+	return walletUTXO
+}
+
+// spendableUTXO returns utxo as an input for libkaspiwallet to create a transaction with
+func (s *server) spendableUTXO(utxo *walletUTXO) (*libkaspiwallet.UTXO, error) {
+	redeemScript, err := s.walletAddressRedeemScript(utxo.address)
+	if err != nil {
+		return nil, err
+	}
+	return &libkaspiwallet.UTXO{
+		Outpoint:       utxo.Outpoint,
+		UTXOEntry:      utxo.UTXOEntry,
+		DerivationPath: s.walletAddressDerivationPath(utxo.address),
+		RedeemScript:   redeemScript,
+	}, nil
+}
+
+func (s *server) LockUtxos(_ context.Context, request *pb.LockUtxosRequest) (*pb.LockUtxosResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.isSynced() {
+		return nil, s.notSyncedError()
+	}
+
+	outpoints, err := outpointsFromPB(request.Outpoints)
+	if err != nil {
+		return nil, err
+	}
+	walletUTXOs := s.walletUTXOsByOutpoint()
+	for _, outpoint := range outpoints {
+		if _, ok := walletUTXOs[*outpoint]; !ok {
+			return nil, errors.Errorf("outpoint %s is not a UTXO of the wallet", outpoint)
+		}
+	}
+
+	lockTime := time.Now().UnixMilli()
+	for _, outpoint := range outpoints {
+		err := s.lockedOutpoints.lock(*outpoint, &lockedOutpoint{Label: request.Label, LockTime: lockTime})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &pb.LockUtxosResponse{}, nil
+}
+
+func (s *server) UnlockUtxos(_ context.Context, request *pb.UnlockUtxosRequest) (*pb.UnlockUtxosResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	outpoints, err := outpointsFromPB(request.Outpoints)
+	if err != nil {
+		return nil, err
+	}
+	for _, outpoint := range outpoints {
+		if _, ok := s.lockedOutpoints.get(*outpoint); !ok {
+			return nil, errors.Errorf("outpoint %s is not locked", outpoint)
+		}
+	}
+
+	for _, outpoint := range outpoints {
+		err := s.lockedOutpoints.unlock(*outpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &pb.UnlockUtxosResponse{}, nil
+}
+
+// isOutpointPending returns whether the given outpoint is spent by a transaction
+// that was broadcast by the wallet, but wasn't accepted yet
+func (s *server) isOutpointPending(outpoint externalapi.DomainOutpoint) bool {
+	broadcastTime, ok := s.usedOutpoints[outpoint]
+	return ok && !s.usedOutpointHasExpired(broadcastTime)
+}
+
+func (s *server) walletUTXOsByOutpoint() map[externalapi.DomainOutpoint]*walletUTXO {
+	walletUTXOs := make(map[externalapi.DomainOutpoint]*walletUTXO, len(s.utxosSortedByAmount))
+	for _, utxo := range s.utxosSortedByAmount {
+		walletUTXOs[*utxo.Outpoint] = utxo
+	}
+	return walletUTXOs
+}
+
+// selectedWalletUTXOs returns the wallet UTXOs of the outpoints that were selected to be spent
+// by a send, making sure they can all be spent
+func (s *server) selectedWalletUTXOs(selectedOutpoints []*pb.Outpoint, virtualDAAScore uint64) ([]*walletUTXO, error) {
+	outpoints, err := outpointsFromPB(selectedOutpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	walletUTXOs := s.walletUTXOsByOutpoint()
+	selectedUTXOs := make([]*walletUTXO, len(outpoints))
+	for i, outpoint := range outpoints {
+		utxo, ok := walletUTXOs[*outpoint]
+		if !ok {
+			return nil, errors.Errorf("outpoint %s is not a UTXO of the wallet", outpoint)
+		}
+		if _, ok := s.lockedOutpoints.get(*outpoint); ok {
+			return nil, errors.Errorf("outpoint %s is locked, and has to be unlocked before it's spent", outpoint)
+		}
+		if s.isOutpointPending(*outpoint) {
+			return nil, errors.Errorf("outpoint %s is already spent by a pending transaction", outpoint)
+		}
+		if !s.isUTXOSpendable(utxo, virtualDAAScore) {
+			return nil, errors.Errorf("outpoint %s is an immature coinbase output", outpoint)
+		}
+		selectedUTXOs[i] = utxo
+	}
+	return selectedUTXOs, nil
+}
+
+// outpointsFromPB converts the given outpoints to domain outpoints, making sure there are no duplicates among them
+func outpointsFromPB(pbOutpoints []*pb.Outpoint) ([]*externalapi.DomainOutpoint, error) {
+	if len(pbOutpoints) == 0 {
+		return nil, errors.Errorf("no outpoints were specified")
+	}
+
+	outpoints := make([]*externalapi.DomainOutpoint, len(pbOutpoints))
+	seen := make(map[externalapi.DomainOutpoint]struct{}, len(pbOutpoints))
+	for i, pbOutpoint := range pbOutpoints {
+		transactionID, err := externalapi.NewDomainTransactionIDFromString(pbOutpoint.TransactionId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid transaction ID %s", pbOutpoint.TransactionId)
+		}
+		outpoint := externalapi.NewDomainOutpoint(transactionID, pbOutpoint.Index)
+		if _, ok := seen[*outpoint]; ok {
+			return nil, errors.Errorf("outpoint %s was specified more than once", outpoint)
+		}
+		seen[*outpoint] = struct{}{}
+		outpoints[i] = outpoint
+	}
+	return outpoints, nil
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/infrastructure/network/rpcclient"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fullRefreshInterval is how often the UTXO set of the wallet is refreshed in full, as
+	// a fallback to the UTXO change notifications
+	fullRefreshInterval = 30 * time.Second
+
+	utxosChangedChanSize = 1000
+)
+
+func (s *server) onUTXOsChanged(notification *appmessage.UTXOsChangedNotificationMessage) {
+	select {
+	case s.utxosChangedChan <- notification:
+	default:
+		// The sync loop doesn't keep up with the notifications, so the changes
+		// that are dropped are made up for with a full refresh
+		s.needsFullRefresh.Store(true)
+		s.forceSync()
+	}
+}
+
+func (s *server) onBackgroundRPCClientReconnected() {
+	// The subscription is gone along with the old connection. It's renewed by the next
+	// sync, which also makes up for the changes that happened while disconnected.
+	s.isUTXOsChangedSubscriptionLost.Store(true)
+	s.forceSync()
+}
+
+// updateUTXOsChangedSubscription subscribes to the UTXO changes of the addresses of the wallet that
+// aren't subscribed to yet. It returns whether a full refresh is required, because the UTXOs of some
+// of these addresses could have changed before they were subscribed to.
+func (s *server) updateUTXOsChangedSubscription() (needsFullRefresh bool, err error) {
+	if s.isUTXOsChangedUnsupported {
+		return false, nil
+	}
+	if s.isUTXOsChangedSubscriptionLost.Swap(false) {
+		s.isSubscribedToUTXOsChanged = false
+		s.subscribedAddresses = make(walletAddressSet)
+		s.nextSubscriptionIndexes = make(map[accountKeychain]uint32)
+	}
+
+	s.lock.RLock()
+	addresses, nextSubscriptionIndexes, hasUsedAddresses, err := s.addressesToSubscribe()
+	s.lock.RUnlock()
+	if err != nil {
+		return false, err
+	}
+	if len(addresses) == 0 {
+		return false, nil
+	}
+
+	if !s.isSubscribedToUTXOsChanged {
+		err := s.backgroundRPCClient.RegisterForUTXOsChangedNotifications(addresses.strings(), s.onUTXOsChanged)
+		if err != nil {
+			if errors.Is(err, rpcclient.ErrRPC) {
+				log.Warnf("The node doesn't notify UTXO changes, so the wallet falls back to polling them: %s", err)
+				s.isUTXOsChangedUnsupported = true
+				return true, nil
+			}
+			return false, err
+		}
+		s.isSubscribedToUTXOsChanged = true
+		hasUsedAddresses = true
+	} else {
+		err := s.backgroundRPCClient.AddUTXOsChangedNotificationAddresses(addresses.strings())
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for addressString, address := range addresses {
+		s.subscribedAddresses[addressString] = address
+	}
+	s.nextSubscriptionIndexes = nextSubscriptionIndexes
+	return hasUsedAddresses, nil
+}
+
+// addressesToSubscribe returns the addresses of the wallet that aren't subscribed to yet: the addresses
+// that were found used, and the addresses of all cosigners up to the last used indexes, which include
+// new addresses that weren't used yet. It also returns whether any of them was found used.
+func (s *server) addressesToSubscribe() (addresses walletAddressSet,
+	nextSubscriptionIndexes map[accountKeychain]uint32, hasUsedAddresses bool, err error) {
+
+	addresses = make(walletAddressSet)
+	for addressString, address := range s.addressSet {
+		if _, ok := s.subscribedAddresses[addressString]; !ok {
+			addresses[addressString] = address
+			hasUsedAddresses = true
+		}
+	}
+
+	nextSubscriptionIndexes = make(map[accountKeychain]uint32, len(s.nextSubscriptionIndexes))
+	for _, account := range s.walletAccounts() {
+		for _, keychain := range keyChains {
+			key := accountKeychain{account.index, keychain}
+			lastUsedIndex := s.lastUsedIndex(account, keychain)
+			for index := s.nextSubscriptionIndexes[key]; index <= lastUsedIndex; index++ {
+				for cosignerIndex := uint32(0); cosignerIndex < uint32(len(account.extendedPublicKeys)); cosignerIndex++ {
+					address := &walletAddress{
+						index:         index,
+						cosignerIndex: cosignerIndex,
+						keyChain:      keychain,
+						accountIndex:  account.index,
+					}
+					addressString, err := s.walletAddressString(address)
+					if err != nil {
+						return nil, nil, false, err
+					}
+					if _, ok := s.subscribedAddresses[addressString]; ok {
+						continue
+					}
+					if _, ok := addresses[addressString]; !ok {
+						addresses[addressString] = address
+					}
+				}
+			}
+			nextSubscriptionIndexes[key] = lastUsedIndex + 1
+		}
+	}
+	return addresses, nextSubscriptionIndexes, hasUsedAddresses, nil
+}
+
+// applyUTXOsChanged applies a notification of changes in the UTXOs of the subscribed addresses to
+// the UTXO set of the wallet, and records the transactions that created the added UTXOs
+func (s *server) applyUTXOsChanged(notification *appmessage.UTXOsChangedNotificationMessage) error {
+	removedOutpoints := make(map[externalapi.DomainOutpoint]struct{}, len(notification.Removed))
+	for _, entry := range notification.Removed {
+		outpoint, err := appmessage.RPCOutpointToDomainOutpoint(entry.Outpoint)
+		if err != nil {
+			return err
+		}
+		removedOutpoints[*outpoint] = struct{}{}
+	}
+
+	now := time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	existingUTXOs := s.walletUTXOsByOutpoint()
+	addedUTXOs := make([]*walletUTXO, 0, len(notification.Added))
+	addedEntriesByTransactionID := make(map[string][]*appmessage.UTXOsByAddressesEntry)
+	for _, entry := range notification.Added {
+		address, ok := s.addressSet[entry.Address]
+		if !ok {
+			address, ok = s.subscribedAddresses[entry.Address]
+			if !ok {
+				// A notification of a subscription that was lost may arrive after the subscription is renewed,
+				// in which case the full refresh that follows the renewal covers its changes
+				continue
+			}
+			s.addressSet[entry.Address] = address
+		}
+
+		outpoint, err := appmessage.RPCOutpointToDomainOutpoint(entry.Outpoint)
+		if err != nil {
+			return err
+		}
+		if _, ok := existingUTXOs[*outpoint]; ok {
+			continue
+		}
+		utxoEntry, err := appmessage.RPCUTXOEntryToUTXOEntry(entry.UTXOEntry)
+		if err != nil {
+			return err
+		}
+		addedUTXOs = append(addedUTXOs, &walletUTXO{
+			Outpoint:  outpoint,
+			UTXOEntry: utxoEntry,
+			address:   address,
+		})
+		addedEntriesByTransactionID[entry.Outpoint.TransactionID] =
+			append(addedEntriesByTransactionID[entry.Outpoint.TransactionID], entry)
+	}
+
+	utxos := make([]*walletUTXO, 0, len(s.utxosSortedByAmount)+len(addedUTXOs))
+	for _, utxo := range s.utxosSortedByAmount {
+		if _, ok := removedOutpoints[*utxo.Outpoint]; !ok {
+			utxos = append(utxos, utxo)
+		}
+	}
+	utxos = append(utxos, addedUTXOs...)
+	sort.Slice(utxos, func(i, j int) bool { return utxos[i].UTXOEntry.Amount() > utxos[j].UTXOEntry.Amount() })
+
+	for transactionID, entries := range addedEntriesByTransactionID {
+		err := s.observeConfirmedOutputs(transactionID, entries, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	previousUTXOs := s.utxosSortedByAmount
+	s.utxosSortedByAmount = utxos
+	return s.notifyWalletChanges(previousUTXOs, utxos)
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+)
+
+func TestApplyUTXOsChanged(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %s", err)
+	}
+
+	keysFile := &keys.File{ExtendedPublicKeys: []string{extendedPublicKey}, MinimumSignatures: 1}
+	err = keysFile.SetPath(params, filepath.Join(t.TempDir(), "keys.json"), false)
+	if err != nil {
+		t.Fatalf("SetPath: %s", err)
+	}
+
+	db, err := openWalletDatabase(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("openWalletDatabase: %s", err)
+	}
+	transactionStore, err := newTransactionStore(db)
+	if err != nil {
+		t.Fatalf("newTransactionStore: %s", err)
+	}
+	lockedOutpoints, err := newLockedOutpointStore(db)
+	if err != nil {
+		t.Fatalf("newLockedOutpointStore: %s", err)
+	}
+	labels, err := newLabelStore(db)
+	if err != nil {
+		t.Fatalf("newLabelStore: %s", err)
+	}
+	serverInstance := &server{
+		params:                  params,
+		coinbaseMaturity:        params.BlockCoinbaseMaturity,
+		keysFile:                keysFile,
+		addressSet:              make(walletAddressSet),
+		usedOutpoints:           map[externalapi.DomainOutpoint]time.Time{},
+		transactionStore:        transactionStore,
+		lockedOutpoints:         lockedOutpoints,
+		labels:                  labels,
+		subscribedAddresses:     make(walletAddressSet),
+		nextSubscriptionIndexes: make(map[accountKeychain]uint32),
+	}
+
+	// Before the wallet used any address, the first address of each key chain is subscribed to
+	addresses, nextSubscriptionIndexes, hasUsedAddresses, err := serverInstance.addressesToSubscribe()
+	if err != nil {
+		t.Fatalf("addressesToSubscribe: %s", err)
+	}
+	if len(addresses) != len(keyChains) || hasUsedAddresses {
+		t.Fatalf("unexpected addresses to subscribe %v", addresses.strings())
+	}
+	serverInstance.subscribedAddresses = addresses
+	serverInstance.nextSubscriptionIndexes = nextSubscriptionIndexes
+	receiveWalletAddress := &walletAddress{keyChain: libkaspiwallet.ExternalKeychain}
+	receiveAddress, err := serverInstance.walletAddressString(receiveWalletAddress)
+	if err != nil {
+		t.Fatalf("walletAddressString: %s", err)
+	}
+	if _, ok := addresses[receiveAddress]; !ok {
+		t.Fatalf("the first receive address %s is expected to be subscribed to", receiveAddress)
+	}
+
+	// A new address is subscribed to once it's derived
+	err = serverInstance.keysFile.SetLastUsedExternalIndex(1)
+	if err != nil {
+		t.Fatalf("SetLastUsedExternalIndex: %s", err)
+	}
+	addresses, _, hasUsedAddresses, err = serverInstance.addressesToSubscribe()
+	if err != nil {
+		t.Fatalf("addressesToSubscribe: %s", err)
+	}
+	if len(addresses) != 1 || hasUsedAddresses {
+		t.Fatalf("unexpected addresses to subscribe %v", addresses.strings())
+	}
+
+	utxoEntry := func(seed byte, address string, amount uint64) *appmessage.UTXOsByAddressesEntry {
+		decodedAddress, err := util.DecodeAddress(address, params.Prefix)
+		if err != nil {
+			t.Fatalf("DecodeAddress: %s", err)
+		}
+		scriptPublicKey, err := txscript.PayToAddrScript(decodedAddress)
+		if err != nil {
+			t.Fatalf("PayToAddrScript: %s", err)
+		}
+		return &appmessage.UTXOsByAddressesEntry{
+			Address: address,
+			Outpoint: &appmessage.RPCOutpoint{
+				TransactionID: externalapi.NewDomainTransactionIDFromByteArray(&[32]byte{seed}).String(),
+			},
+			UTXOEntry: &appmessage.RPCUTXOEntry{
+				Amount: amount,
+				ScriptPublicKey: &appmessage.RPCScriptPublicKey{
+					Version: scriptPublicKey.Version,
+					Script:  hex.EncodeToString(scriptPublicKey.Script),
+				},
+				BlockDAAScore: 10,
+			},
+		}
+	}
+	firstEntry := utxoEntry(1, receiveAddress, 100)
+	secondEntry := utxoEntry(2, receiveAddress, 200)
+
+	err = serverInstance.applyUTXOsChanged(&appmessage.UTXOsChangedNotificationMessage{
+		Added: []*appmessage.UTXOsByAddressesEntry{firstEntry, secondEntry},
+	})
+	if err != nil {
+		t.Fatalf("applyUTXOsChanged: %s", err)
+	}
+	if len(serverInstance.utxosSortedByAmount) != 2 || serverInstance.utxosSortedByAmount[0].UTXOEntry.Amount() != 200 {
+		t.Fatalf("unexpected UTXOs after the first notification")
+	}
+	if _, ok := serverInstance.addressSet[receiveAddress]; !ok {
+		t.Fatalf("the address of the added UTXOs is expected to be found used")
+	}
+	if transaction, ok := serverInstance.transactionStore.get(firstEntry.Outpoint.TransactionID); !ok ||
+		transaction.Status != transactionStatusConfirmed {
+		t.Fatalf("the transaction of the added UTXO is expected to be recorded as confirmed")
+	}
+
+	// Notifications of changes that were already applied are ignored
+	err = serverInstance.applyUTXOsChanged(&appmessage.UTXOsChangedNotificationMessage{
+		Added:   []*appmessage.UTXOsByAddressesEntry{secondEntry},
+		Removed: []*appmessage.UTXOsByAddressesEntry{firstEntry},
+	})
+	if err != nil {
+		t.Fatalf("applyUTXOsChanged: %s", err)
+	}
+	previousUTXOs := serverInstance.utxosSortedByAmount
+	if len(previousUTXOs) != 1 || previousUTXOs[0].UTXOEntry.Amount() != 200 {
+		t.Fatalf("unexpected UTXOs after the second notification")
+	}
+
+	thirdEntry := utxoEntry(3, receiveAddress, 300)
+	err = serverInstance.applyUTXOsChanged(&appmessage.UTXOsChangedNotificationMessage{
+		Added:   []*appmessage.UTXOsByAddressesEntry{thirdEntry},
+		Removed: []*appmessage.UTXOsByAddressesEntry{secondEntry},
+	})
+	if err != nil {
+		t.Fatalf("applyUTXOsChanged: %s", err)
+	}
+	notification, err := serverInstance.walletChangedNotification(nil, serverInstance.utxosSortedByAmount, previousUTXOs,
+		serverInstance.utxosSortedByAmount, 20)
+	if err != nil {
+		t.Fatalf("walletChangedNotification: %s", err)
+	}
+	if notification.Available != 300 || len(notification.AddedUtxos) != 1 || notification.AddedUtxos[0].Amount != 300 ||
+		notification.AddedUtxos[0].Address != receiveAddress || len(notification.RemovedUtxos) != 1 ||
+		notification.RemovedUtxos[0].TransactionId != secondEntry.Outpoint.TransactionID {
+		t.Fatalf("unexpected notification %+v", notification)
+	}
+
+	// Subscribers to other accounts aren't notified
+	notification, err = serverInstance.walletChangedNotification(&walletAccount{index: 1},
+		serverInstance.utxosSortedByAmount, previousUTXOs, serverInstance.utxosSortedByAmount, 20)
+	if err != nil {
+		t.Fatalf("walletChangedNotification: %s", err)
+	}
+	if notification != nil {
+		t.Fatalf("a notification of the changes of another account is unexpected")
+	}
+}
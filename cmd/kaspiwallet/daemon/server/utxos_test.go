@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+)
+
+func TestLockedOutpoints(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "keys.db")
+	db, err := openWalletDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("openWalletDatabase: %s", err)
+	}
+	lockedOutpoints, err := newLockedOutpointStore(db)
+	if err != nil {
+		t.Fatalf("newLockedOutpointStore: %s", err)
+	}
+
+	params := &dagconfig.DevnetParams
+	serverInstance := &server{
+		params:             params,
+		coinbaseMaturity:   params.BlockCoinbaseMaturity,
+		keysFile:           &keys.File{},
+		nextSyncStartIndex: 1,
+		usedOutpoints:      map[externalapi.DomainOutpoint]time.Time{},
+		lockedOutpoints:    lockedOutpoints,
+	}
+	serverInstance.firstSyncDone.Store(true)
+
+	newUTXO := func(seed byte, amount uint64, isCoinbase bool) *walletUTXO {
+		return &walletUTXO{
+			Outpoint: externalapi.NewDomainOutpoint(externalapi.NewDomainTransactionIDFromByteArray(&[32]byte{seed}), 1),
+			UTXOEntry: utxo.NewUTXOEntry(amount, &externalapi.ScriptPublicKey{}, isCoinbase,
+				params.BlockCoinbaseMaturity),
+			address: &walletAddress{},
+		}
+	}
+	spendableUTXO := newUTXO(1, 300, false)
+	pendingUTXO := newUTXO(2, 200, false)
+	immatureUTXO := newUTXO(3, 100, true)
+	serverInstance.utxosSortedByAmount = []*walletUTXO{spendableUTXO, pendingUTXO, immatureUTXO}
+	serverInstance.usedOutpoints[*pendingUTXO.Outpoint] = time.Now()
+	toPB := func(utxos ...*walletUTXO) []*pb.Outpoint {
+		outpoints := make([]*pb.Outpoint, len(utxos))
+		for i, utxo := range utxos {
+			outpoints[i] = &pb.Outpoint{TransactionId: utxo.Outpoint.TransactionID.String(), Index: utxo.Outpoint.Index}
+		}
+		return outpoints
+	}
+	const virtualDAAScore = 2
+
+	selectedUTXOs, err := serverInstance.selectedWalletUTXOs(toPB(spendableUTXO), virtualDAAScore)
+	if err != nil {
+		t.Fatalf("selectedWalletUTXOs: %s", err)
+	}
+	if len(selectedUTXOs) != 1 || selectedUTXOs[0] != spendableUTXO {
+		t.Fatalf("unexpected selected UTXOs %+v", selectedUTXOs)
+	}
+	unknownUTXO := newUTXO(4, 100, false)
+	for name, outpoints := range map[string][]*pb.Outpoint{
+		"pending":   toPB(pendingUTXO),
+		"immature":  toPB(immatureUTXO),
+		"unknown":   toPB(unknownUTXO),
+		"duplicate": toPB(spendableUTXO, spendableUTXO),
+		"invalid":   {{TransactionId: "invalid"}},
+	} {
+		_, err := serverInstance.selectedWalletUTXOs(outpoints, virtualDAAScore)
+		if err == nil {
+			t.Errorf("selecting a %s outpoint is expected to fail", name)
+		}
+	}
+
+	_, err = serverInstance.LockUtxos(context.Background(), &pb.LockUtxosRequest{Outpoints: toPB(unknownUTXO)})
+	if err == nil {
+		t.Fatalf("locking an outpoint that isn't a UTXO of the wallet is expected to fail")
+	}
+	_, err = serverInstance.LockUtxos(context.Background(),
+		&pb.LockUtxosRequest{Outpoints: toPB(spendableUTXO), Label: "cold storage"})
+	if err != nil {
+		t.Fatalf("LockUtxos: %s", err)
+	}
+	_, err = serverInstance.selectedWalletUTXOs(toPB(spendableUTXO), virtualDAAScore)
+	if err == nil {
+		t.Fatalf("selecting a locked outpoint is expected to fail")
+	}
+
+	// The locks survive a restart
+	err = db.Close()
+	if err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	db, err = openWalletDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("openWalletDatabase: %s", err)
+	}
+	defer db.Close()
+	serverInstance.lockedOutpoints, err = newLockedOutpointStore(db)
+	if err != nil {
+		t.Fatalf("newLockedOutpointStore: %s", err)
+	}
+	locked, ok := serverInstance.lockedOutpoints.get(*spendableUTXO.Outpoint)
+	if !ok || locked.Label != "cold storage" {
+		t.Fatalf("expected the outpoint to stay locked with its label, got %+v", locked)
+	}
+
+	_, err = serverInstance.UnlockUtxos(context.Background(), &pb.UnlockUtxosRequest{Outpoints: toPB(spendableUTXO)})
+	if err != nil {
+		t.Fatalf("UnlockUtxos: %s", err)
+	}
+	_, err = serverInstance.UnlockUtxos(context.Background(), &pb.UnlockUtxosRequest{Outpoints: toPB(spendableUTXO)})
+	if err == nil {
+		t.Fatalf("unlocking an outpoint that isn't locked is expected to fail")
+	}
+	_, err = serverInstance.selectedWalletUTXOs(toPB(spendableUTXO), virtualDAAScore)
+	if err != nil {
+		t.Fatalf("selectedWalletUTXOs: %s", err)
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/pkg/errors"
+)
+
+// walletChangesBufferSize is the number of notifications a subscriber to the wallet changes can
+// fall behind by before it's disconnected
+const walletChangesBufferSize = 100
+
+type walletChangesSubscriber struct {
+	account       *walletAccount // nil if subscribed to the changes of all the accounts
+	notifications chan *pb.WalletChangedNotification
+	overflow      chan struct{}
+}
+
+func (s *server) SubscribeWalletChanges(request *pb.SubscribeWalletChangesRequest,
+	stream pb.Kaspiwalletd_SubscribeWalletChangesServer) error {
+
+	subscriber, err := s.subscribeWalletChanges(request.Account)
+	if err != nil {
+		return err
+	}
+	defer s.unsubscribeWalletChanges(subscriber)
+
+	for {
+		select {
+		case notification := <-subscriber.notifications:
+			err := stream.Send(notification)
+			if err != nil {
+				return err
+			}
+		case <-subscriber.overflow:
+			return errors.Errorf("the client didn't keep up with the changes of the wallet")
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// subscribeWalletChanges subscribes to the changes of the given account, or of all the accounts if
+// accountName is empty. The first notification of the subscriber holds all the UTXOs of the account.
+func (s *server) subscribeWalletChanges(accountName string) (*walletChangesSubscriber, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.isSynced() {
+		return nil, s.notSyncedError()
+	}
+
+	var account *walletAccount
+	if accountName != "" {
+		var err error
+		account, err = s.accountByName(accountName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
+	if err != nil {
+		return nil, err
+	}
+	notification, err := s.walletChangedNotification(account, s.utxosSortedByAmount, nil, s.utxosSortedByAmount,
+		dagInfo.VirtualDAAScore)
+	if err != nil {
+		return nil, err
+	}
+	if notification == nil {
+		notification = &pb.WalletChangedNotification{}
+	}
+
+	subscriber := &walletChangesSubscriber{
+		account:       account,
+		notifications: make(chan *pb.WalletChangedNotification, walletChangesBufferSize),
+		overflow:      make(chan struct{}),
+	}
+	subscriber.notifications <- notification
+
+	// The subscriber is added while the UTXO set is locked, so that no change is missed
+	// between the first notification and the ones that follow it
+	s.walletChangesSubscribersLock.Lock()
+	defer s.walletChangesSubscribersLock.Unlock()
+	s.walletChangesSubscribers[subscriber] = struct{}{}
+	return subscriber, nil
+}
+
+func (s *server) unsubscribeWalletChanges(subscriber *walletChangesSubscriber) {
+	s.walletChangesSubscribersLock.Lock()
+	defer s.walletChangesSubscribersLock.Unlock()
+
+	delete(s.walletChangesSubscribers, subscriber)
+}
+
+// notifyWalletChanges notifies the subscribers to the wallet changes of the difference between
+// previousUTXOs and utxos. It must be called while the UTXO set is locked.
+func (s *server) notifyWalletChanges(previousUTXOs, utxos []*walletUTXO) error {
+	s.walletChangesSubscribersLock.Lock()
+	defer s.walletChangesSubscribersLock.Unlock()
+
+	if len(s.walletChangesSubscribers) == 0 {
+		return nil
+	}
+
+	previousOutpoints := make(map[externalapi.DomainOutpoint]struct{}, len(previousUTXOs))
+	for _, utxo := range previousUTXOs {
+		previousOutpoints[*utxo.Outpoint] = struct{}{}
+	}
+	outpoints := make(map[externalapi.DomainOutpoint]struct{}, len(utxos))
+	var addedUTXOs []*walletUTXO
+	for _, utxo := range utxos {
+		outpoints[*utxo.Outpoint] = struct{}{}
+		if _, ok := previousOutpoints[*utxo.Outpoint]; !ok {
+			addedUTXOs = append(addedUTXOs, utxo)
+		}
+	}
+	var removedUTXOs []*walletUTXO
+	for _, utxo := range previousUTXOs {
+		if _, ok := outpoints[*utxo.Outpoint]; !ok {
+			removedUTXOs = append(removedUTXOs, utxo)
+		}
+	}
+	if len(addedUTXOs) == 0 && len(removedUTXOs) == 0 {
+		return nil
+	}
+
+	dagInfo, err := s.backgroundRPCClient.GetBlockDAGInfo()
+	if err != nil {
+		return err
+	}
+	for subscriber := range s.walletChangesSubscribers {
+		notification, err := s.walletChangedNotification(subscriber.account, addedUTXOs, removedUTXOs, utxos,
+			dagInfo.VirtualDAAScore)
+		if err != nil {
+			return err
+		}
+		if notification == nil {
+			continue
+		}
+
+		select {
+		case subscriber.notifications <- notification:
+		default:
+			close(subscriber.overflow)
+			delete(s.walletChangesSubscribers, subscriber)
+		}
+	}
+	return nil
+}
+
+// walletChangedNotification returns a notification of the given changes in the UTXOs of account, or
+// of all the accounts if it's nil, along with the resulting balance. It returns nil if none of the
+// changes is of the account.
+func (s *server) walletChangedNotification(account *walletAccount, addedUTXOs, removedUTXOs []*walletUTXO,
+	utxos []*walletUTXO, virtualDAAScore uint64) (*pb.WalletChangedNotification, error) {
+
+	isOfAccount := func(utxo *walletUTXO) bool {
+		return account == nil || utxo.address.accountIndex == account.index
+	}
+
+	notification := &pb.WalletChangedNotification{}
+	accountNames := make(map[uint32]string)
+	for _, account := range s.walletAccounts() {
+		accountNames[account.index] = account.name
+	}
+	for _, utxo := range addedUTXOs {
+		if !isOfAccount(utxo) {
+			continue
+		}
+		address, err := s.walletAddressString(utxo.address)
+		if err != nil {
+			return nil, err
+		}
+		notification.AddedUtxos = append(notification.AddedUtxos,
+			s.walletUTXOToPB(utxo, address, accountNames[utxo.address.accountIndex], virtualDAAScore))
+	}
+	for _, utxo := range removedUTXOs {
+		if !isOfAccount(utxo) {
+			continue
+		}
+		notification.RemovedUtxos = append(notification.RemovedUtxos, &pb.Outpoint{
+			TransactionId: utxo.Outpoint.TransactionID.String(),
+			Index:         utxo.Outpoint.Index,
+		})
+	}
+	if len(notification.AddedUtxos) == 0 && len(notification.RemovedUtxos) == 0 {
+		return nil, nil
+	}
+
+	for _, utxo := range utxos {
+		if !isOfAccount(utxo) {
+			continue
+		}
+		if s.isUTXOTimeLocked(utxo, virtualDAAScore) {
+			notification.TimeLocked += utxo.UTXOEntry.Amount()
+		} else if s.isUTXOSpendable(utxo, virtualDAAScore) {
+			notification.Available += utxo.UTXOEntry.Amount()
+		} else {
+			notification.Pending += utxo.UTXOEntry.Amount()
+		}
+	}
+	return notification, nil
+}
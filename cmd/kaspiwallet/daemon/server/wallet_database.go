@@ -0,0 +1,28 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/kaspikr/kaspid/infrastructure/db/database"
+	"github.com/kaspikr/kaspid/infrastructure/db/database/ldb"
+	"github.com/pkg/errors"
+)
+
+const walletDBCacheSizeMiB = 8
+
+// walletDBPath returns the path of the wallet database of the given keys file,
+// which is kept next to it, e.g. keys.db for keys.json
+func walletDBPath(keysFilePath string) string {
+	return strings.TrimSuffix(keysFilePath, filepath.Ext(keysFilePath)) + ".db"
+}
+
+// openWalletDatabase opens the wallet database, which keeps the wallet's state that
+// isn't part of its keys file, such as its transaction history
+func openWalletDatabase(path string) (database.Database, error) {
+	db, err := ldb.NewLevelDB(path, walletDBCacheSizeMiB)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening the wallet database %s", path)
+	}
+	return db, nil
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// The following errors classify the errors of the daemon that its clients may want to tell apart,
+// such as the error codes of the HTTP API. They're matched with errors.Is.
+var (
+	errNotSynced         = errors.New("not synced")
+	errWalletLocked      = errors.New("wallet locked")
+	errInvalidPassword   = errors.New("invalid password")
+	errInsufficientFunds = errors.New("insufficient funds")
+)
+
+// classifiedError is an error with its own message, which is classified as one of the errors above
+type classifiedError struct {
+	message string
+	class   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.message
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.class
+}
+
+// classifyErrorf formats an error that errors.Is matches with class
+func classifyErrorf(class error, format string, args ...interface{}) error {
+	return &classifiedError{message: fmt.Sprintf(format, args...), class: class}
+}
+
+func (s *server) notSyncedError() error {
+	return classifyErrorf(errNotSynced, "wallet daemon is not synced yet, %s", s.formatSyncStateReport())
+}
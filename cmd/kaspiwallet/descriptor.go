@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/pkg/errors"
+)
+
+func exportDescriptor(conf *exportDescriptorConfig) error {
+	keysFile, err := keys.ReadKeysFile(conf.NetParams(), conf.KeysFile)
+	if err != nil {
+		return err
+	}
+
+	descriptor, err := libkaspiwallet.NewWalletDescriptor(conf.NetParams(), keysFile.ExtendedPublicKeys,
+		keysFile.MinimumSignatures, keysFile.ECDSA)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(descriptor)
+	return nil
+}
+
+func verifyDescriptor(conf *verifyDescriptorConfig) error {
+	descriptor, err := libkaspiwallet.ParseWalletDescriptor(conf.NetParams(), conf.Descriptor)
+	if err != nil {
+		return err
+	}
+
+	keysFile, err := keys.ReadKeysFile(conf.NetParams(), conf.KeysFile)
+	if err != nil {
+		return err
+	}
+	walletDescriptor, err := libkaspiwallet.NewWalletDescriptor(conf.NetParams(), keysFile.ExtendedPublicKeys,
+		keysFile.MinimumSignatures, keysFile.ECDSA)
+	if err != nil {
+		return err
+	}
+	if walletDescriptor.String() != descriptor.String() {
+		return errors.Errorf("The wallet in %s doesn't match the descriptor. The descriptor of the wallet is:\n%s",
+			keysFile.Path(), walletDescriptor)
+	}
+
+	fmt.Printf("The wallet in %s matches the descriptor\n", keysFile.Path())
+	if descriptor.IsMultisig() && !keysFile.IsWatchOnly() {
+		fmt.Printf("This wallet is cosigner #%d\n", keysFile.CosignerIndex)
+	}
+	fmt.Println()
+
+	// Every cosigner derives its own addresses, so the addresses of all of them are printed,
+	// for the cosigners to compare with what each of the others sees
+	numCosigners := uint32(1)
+	if descriptor.IsMultisig() {
+		numCosigners = uint32(len(descriptor.ExtendedPublicKeys))
+	}
+	for cosignerIndex := uint32(0); cosignerIndex < numCosigners; cosignerIndex++ {
+		if descriptor.IsMultisig() {
+			fmt.Printf("Receive addresses of cosigner #%d:\n", cosignerIndex)
+		} else {
+			fmt.Println("Receive addresses:")
+		}
+		for i := uint32(0); i < conf.NumAddresses; i++ {
+			address, err := descriptor.Address(conf.NetParams(), cosignerIndex, libkaspiwallet.ExternalKeychain, i)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\t%d: %s\n", i, address)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -25,10 +25,14 @@ func dumpUnencryptedData(conf *dumpUnencryptedDataConfig) error {
 		return err
 	}
 
+	if keysFile.IsWatchOnly() {
+		return errors.Errorf("The wallet is watch-only and has no private keys to dump")
+	}
+
 	if len(conf.Password) == 0 {
 		conf.Password = keys.GetPassword("Password:")
 	}
-	mnemonics, err := keysFile.DecryptMnemonics(conf.Password)
+	mnemonics, passphrases, err := keysFile.DecryptMnemonics(conf.Password)
 	if err != nil {
 		return err
 	}
@@ -36,7 +40,11 @@ func dumpUnencryptedData(conf *dumpUnencryptedDataConfig) error {
 	mnemonicPublicKeys := make(map[string]struct{})
 	for i, mnemonic := range mnemonics {
 		fmt.Printf("Mnemonic #%d:\n%s\n\n", i+1, mnemonic)
-		publicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(conf.NetParams(), mnemonic, len(keysFile.ExtendedPublicKeys) > 1)
+		if passphrases[i] != "" {
+			fmt.Printf("BIP39 passphrase of mnemonic #%d:\n%s\n\n", i+1, passphrases[i])
+		}
+		publicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(conf.NetParams(), mnemonic,
+			passphrases[i], len(keysFile.ExtendedPublicKeys) > 1)
 		if err != nil {
 			return err
 		}
@@ -8,7 +8,7 @@ import (
 )
 
 func getDaemonVersion(conf *getDaemonVersionConfig) error {
-	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress)
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
+)
+
+func history(conf *historyConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	if conf.TransactionID != "" {
+		response, err := daemonClient.GetTransaction(ctx, &pb.GetTransactionRequest{TransactionId: conf.TransactionID})
+		if err != nil {
+			return err
+		}
+		printTransactionDetails(response.Transaction)
+		return nil
+	}
+
+	response, err := daemonClient.GetTransactions(ctx, &pb.GetTransactionsRequest{Limit: conf.Limit})
+	if err != nil {
+		return err
+	}
+
+	if conf.Verbose {
+		for _, transaction := range response.Transactions {
+			printTransactionDetails(transaction)
+			fmt.Println()
+		}
+		return nil
+	}
+
+	println("Time                Transaction ID                                                    Status    Direction       Amount, KAS")
+	println("-------------------------------------------------------------------------------------------------------------------------")
+	for _, transaction := range response.Transactions {
+		fmt.Printf("%s %s %-9s %-8s %s\n", formatTimestamp(transaction.Timestamp), transaction.TransactionId,
+			formatStatus(transaction.Status), formatDirection(transaction), utils.FormatKas(transaction.Amount))
+	}
+	return nil
+}
+
+func printTransactionDetails(transaction *pb.TransactionInfo) {
+	fmt.Printf("Transaction ID: %s\n", transaction.TransactionId)
+	fmt.Printf("First seen:     %s\n", formatTimestamp(transaction.Timestamp))
+	fmt.Printf("Direction:      %s\n", formatDirection(transaction))
+	fmt.Printf("Status:         %s\n", formatStatus(transaction.Status))
+	if transaction.Status == pb.TransactionStatus_CONFIRMED {
+		fmt.Printf("Confirmed at:   DAA score %d\n", transaction.ConfirmationDaaScore)
+	}
+	fmt.Printf("Amount, KAS:    %s\n", strings.TrimSpace(utils.FormatKas(transaction.Amount)))
+	if transaction.Fee > 0 {
+		fmt.Printf("Fee, KAS:       %s\n", strings.TrimSpace(utils.FormatKas(transaction.Fee)))
+	}
+	fmt.Println("Outputs:")
+	for _, output := range transaction.Outputs {
+		address := output.Address
+		if address == "" {
+			address = "<non-standard script>"
+		}
+		walletSuffix := ""
+		if output.IsWalletAddress {
+			walletSuffix = " (wallet)"
+		}
+		fmt.Printf("  #%-3d %s %s%s\n", output.Index, address, utils.FormatKas(output.Amount), walletSuffix)
+	}
+}
+
+func formatTimestamp(timestamp int64) string {
+	return time.UnixMilli(timestamp).Format("2006-01-02 15:04:05")
+}
+
+func formatStatus(status pb.TransactionStatus) string {
+	return strings.ToLower(status.String())
+}
+
+func formatDirection(transaction *pb.TransactionInfo) string {
+	if transaction.IsCoinbase {
+		return "coinbase"
+	}
+	if transaction.IsOutgoing {
+		return "outgoing"
+	}
+	return "incoming"
+}
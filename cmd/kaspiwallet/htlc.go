@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/infrastructure/os/signal"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+// htlcSecretPollInterval is how often htlc-secret checks whether the contract was redeemed
+const htlcSecretPollInterval = 2 * time.Second
+
+func htlcInitiate(conf *htlcInitiateConfig) error {
+	keysFile, err := keys.ReadKeysFile(conf.NetParams(), conf.KeysFile)
+	if err != nil {
+		return err
+	}
+	if keysFile.IsWatchOnly() || len(keysFile.ExtendedPublicKeys) > 1 {
+		return errors.Errorf("Cannot initiate a hash time-locked contract with a watch-only or multisig wallet, " +
+			"since it couldn't refund it")
+	}
+
+	recipientAddress, err := util.DecodeAddress(conf.ToAddress, conf.NetParams().Prefix)
+	if err != nil {
+		return err
+	}
+	sendAmountSompi, err := util.KasToSompi(conf.SendAmount)
+	if err != nil {
+		return err
+	}
+
+	var secret, secretHash []byte
+	if conf.SecretHash != "" {
+		secretHash, err = hex.DecodeString(conf.SecretHash)
+		if err != nil {
+			return errors.Wrap(err, "the secret hash is not valid hex")
+		}
+	} else {
+		secret = make([]byte, txscript.HTLCSecretSize)
+		_, err = rand.Read(secret)
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(secret)
+		secretHash = hash[:]
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	refundAddressString := conf.RefundAddress
+	if refundAddressString == "" {
+		response, err := daemonClient.NewAddress(ctx, &pb.NewAddressRequest{Account: conf.Account})
+		if err != nil {
+			return err
+		}
+		refundAddressString = response.Address
+	}
+	refundAddress, err := util.DecodeAddress(refundAddressString, conf.NetParams().Prefix)
+	if err != nil {
+		return err
+	}
+
+	lockTime := uint64(time.Now().Add(conf.LockDuration).UnixMilli())
+	htlc, err := libkaspiwallet.NewHTLC(recipientAddress, refundAddress, secretHash, lockTime)
+	if err != nil {
+		return err
+	}
+	contractAddress, err := htlc.Address(conf.NetParams())
+	if err != nil {
+		return err
+	}
+
+	// The details of the contract are printed before it's paid, so that they're known even if the payment fails midway
+	if secret != nil {
+		fmt.Printf("Secret (keep it secret until you redeem the counterparty's contract):\n%x\n\n", secret)
+	}
+	err = printHTLC(conf.NetParams(), htlc)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+
+	createUnsignedTransactionsResponse, err :=
+		daemonClient.CreateUnsignedTransactions(ctx, &pb.CreateUnsignedTransactionsRequest{
+			Account: conf.Account,
+			Address: contractAddress.String(),
+			Amount:  sendAmountSompi,
+			FeeRate: conf.FeeRate,
+		})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Paying %s KAS to the contract with a fee of %s KAS\n",
+		strings.TrimSpace(utils.FormatKas(sendAmountSompi)),
+		strings.TrimSpace(utils.FormatKas(createUnsignedTransactionsResponse.Fee)))
+
+	return signAndBroadcast(conf.NetParams(), keysFile, conf.Password, daemonClient,
+		createUnsignedTransactionsResponse.UnsignedTransactions, false)
+}
+
+func htlcRedeem(conf *htlcRedeemConfig) error {
+	contract, err := hex.DecodeString(conf.Contract)
+	if err != nil {
+		return errors.Wrap(err, "the contract is not valid hex")
+	}
+	secret, err := hex.DecodeString(conf.Secret)
+	if err != nil {
+		return errors.Wrap(err, "the secret is not valid hex")
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	if len(conf.Password) == 0 {
+		conf.Password = keys.GetPassword("Password:")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.RedeemHTLC(ctx, &pb.RedeemHTLCRequest{
+		Contract: contract,
+		Secret:   secret,
+		Password: conf.Password,
+		FeeRate:  conf.FeeRate,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Redeemed %s KAS with a fee of %s KAS\n", strings.TrimSpace(utils.FormatKas(response.Amount)),
+		strings.TrimSpace(utils.FormatKas(response.Fee)))
+	fmt.Printf("Transaction ID: %s\n", response.TxID)
+	return nil
+}
+
+func htlcRefund(conf *htlcRefundConfig) error {
+	contract, err := hex.DecodeString(conf.Contract)
+	if err != nil {
+		return errors.Wrap(err, "the contract is not valid hex")
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	if len(conf.Password) == 0 {
+		conf.Password = keys.GetPassword("Password:")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.RefundHTLC(ctx, &pb.RefundHTLCRequest{
+		Contract: contract,
+		Password: conf.Password,
+		FeeRate:  conf.FeeRate,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Refunded %s KAS with a fee of %s KAS\n", strings.TrimSpace(utils.FormatKas(response.Amount)),
+		strings.TrimSpace(utils.FormatKas(response.Fee)))
+	fmt.Printf("Transaction ID: %s\n", response.TxID)
+	return nil
+}
+
+func htlcSecret(conf *htlcSecretConfig) error {
+	contract, err := hex.DecodeString(conf.Contract)
+	if err != nil {
+		return errors.Wrap(err, "the contract is not valid hex")
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	interrupt := signal.InterruptListener()
+
+	fmt.Println("Waiting for the contract to be redeemed...")
+	startBlockHash := conf.StartBlockHash
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+		response, err := daemonClient.GetHTLCSecret(ctx, &pb.GetHTLCSecretRequest{
+			Contract:       contract,
+			StartBlockHash: startBlockHash,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(response.Secret) > 0 {
+			fmt.Printf("The contract was redeemed by transaction %s, which revealed the secret:\n%x\n",
+				response.TxID, response.Secret)
+			return nil
+		}
+		startBlockHash = response.NextStartBlockHash
+
+		select {
+		case <-interrupt:
+			return nil
+		case <-time.After(htlcSecretPollInterval):
+		}
+	}
+}
+
+func htlcInspect(conf *htlcInspectConfig) error {
+	contract, err := hex.DecodeString(conf.Contract)
+	if err != nil {
+		return errors.Wrap(err, "the contract is not valid hex")
+	}
+	htlc, err := libkaspiwallet.ParseHTLC(conf.NetParams(), contract)
+	if err != nil {
+		return err
+	}
+	err = printHTLC(conf.NetParams(), htlc)
+	if err != nil {
+		return err
+	}
+
+	contractAddress, err := htlc.Address(conf.NetParams())
+	if err != nil {
+		return err
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.GetExternalSpendableUTXOs(ctx, &pb.GetExternalSpendableUTXOsRequest{
+		Address: contractAddress.String(),
+	})
+	if err != nil {
+		return err
+	}
+	if len(response.Entries) == 0 {
+		fmt.Println("Locked amount:     none. The contract is either unpaid or already spent")
+		return nil
+	}
+	lockedAmount := uint64(0)
+	for _, entry := range response.Entries {
+		lockedAmount += entry.UtxoEntry.Amount
+	}
+	fmt.Printf("Locked amount:     %s KAS in %d UTXO(s)\n", strings.TrimSpace(utils.FormatKas(lockedAmount)),
+		len(response.Entries))
+	return nil
+}
+
+func printHTLC(params *dagconfig.Params, htlc *libkaspiwallet.HTLC) error {
+	contractAddress, err := htlc.Address(params)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Contract:\n%x\n\n", htlc.Contract)
+	fmt.Printf("Contract address:  %s\n", contractAddress)
+	fmt.Printf("Recipient address: %s\n", htlc.RecipientAddress)
+	fmt.Printf("Refund address:    %s\n", htlc.RefundAddress)
+	fmt.Printf("Secret hash:       %x\n", htlc.SecretHash)
+	if htlc.LockTime < constants.LockTimeThreshold {
+		fmt.Printf("Lock time:         DAA score %d\n", htlc.LockTime)
+	} else {
+		fmt.Printf("Lock time:         %s\n", time.UnixMilli(int64(htlc.LockTime)).Format(time.RFC3339))
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package keys
 
 import (
 	"bufio"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/subtle"
 	"fmt"
@@ -14,8 +15,9 @@ import (
 	"github.com/tyler-smith/go-bip39"
 )
 
-// CreateMnemonics generates `numKeys` number of mnemonics.
-func CreateMnemonics(params *dagconfig.Params, numKeys uint32, cmdLinePassword string, isMultisig bool) (encryptedPrivateKeys []*EncryptedMnemonic, extendedPublicKeys []string, err error) {
+// CreateMnemonics generates `numKeys` number of mnemonics. If usePassphrases is set, the
+// user is asked for the BIP39 passphrase the seed of each mnemonic is derived with.
+func CreateMnemonics(params *dagconfig.Params, numKeys uint32, cmdLinePassword string, isMultisig bool, usePassphrases bool) (encryptedPrivateKeys []*EncryptedMnemonic, extendedPublicKeys []string, err error) {
 	mnemonics := make([]string, numKeys)
 	for i := uint32(0); i < numKeys; i++ {
 		var err error
@@ -25,11 +27,20 @@ func CreateMnemonics(params *dagconfig.Params, numKeys uint32, cmdLinePassword s
 		}
 	}
 
-	return encryptedMnemonicExtendedPublicKeyPairs(params, mnemonics, cmdLinePassword, isMultisig)
+	var passphrases []string
+	if usePassphrases {
+		passphrases, err = readPassphrases(numKeys)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return encryptedMnemonicExtendedPublicKeyPairs(params, mnemonics, passphrases, cmdLinePassword, isMultisig)
 }
 
-// ImportMnemonics imports a `numKeys` of mnemonics.
-func ImportMnemonics(params *dagconfig.Params, numKeys uint32, cmdLinePassword string, isMultisig bool) (encryptedPrivateKeys []*EncryptedMnemonic, extendedPublicKeys []string, err error) {
+// ImportMnemonics imports a `numKeys` of mnemonics. If usePassphrases is set, the user is
+// asked for the BIP39 passphrase the seed of each mnemonic is derived with.
+func ImportMnemonics(params *dagconfig.Params, numKeys uint32, cmdLinePassword string, isMultisig bool, usePassphrases bool) (encryptedPrivateKeys []*EncryptedMnemonic, extendedPublicKeys []string, err error) {
 	mnemonics := make([]string, numKeys)
 	for i := uint32(0); i < numKeys; i++ {
 		fmt.Printf("Enter mnemonic #%d here:\n", i+1)
@@ -45,10 +56,39 @@ func ImportMnemonics(params *dagconfig.Params, numKeys uint32, cmdLinePassword s
 
 		mnemonics[i] = string(mnemonic)
 	}
-	return encryptedMnemonicExtendedPublicKeyPairs(params, mnemonics, cmdLinePassword, isMultisig)
+
+	var passphrases []string
+	if usePassphrases {
+		passphrases, err = readPassphrases(numKeys)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return encryptedMnemonicExtendedPublicKeyPairs(params, mnemonics, passphrases, cmdLinePassword, isMultisig)
+}
+
+func readPassphrases(numKeys uint32) ([]string, error) {
+	passphrases := make([]string, numKeys)
+	for i := uint32(0); i < numKeys; i++ {
+		passphrase := []byte(GetPassword(fmt.Sprintf("Enter BIP39 passphrase of mnemonic #%d:", i+1)))
+		if len(passphrase) == 0 {
+			return nil, errors.New("The BIP39 passphrase must not be empty")
+		}
+		confirmPassphrase := []byte(GetPassword("Confirm BIP39 passphrase:"))
+
+		if subtle.ConstantTimeCompare(passphrase, confirmPassphrase) != 1 {
+			return nil, errors.New("Passphrases are not identical")
+		}
+		passphrases[i] = string(passphrase)
+	}
+
+	return passphrases, nil
 }
 
-func encryptedMnemonicExtendedPublicKeyPairs(params *dagconfig.Params, mnemonics []string, cmdLinePassword string, isMultisig bool) (
+// encryptedMnemonicExtendedPublicKeyPairs encrypts the given mnemonics along with their
+// passphrases, which may be nil if none of the mnemonics has one
+func encryptedMnemonicExtendedPublicKeyPairs(params *dagconfig.Params, mnemonics []string, passphrases []string, cmdLinePassword string, isMultisig bool) (
 	encryptedPrivateKeys []*EncryptedMnemonic, extendedPublicKeys []string, err error) {
 	password := []byte(cmdLinePassword)
 	if len(password) == 0 {
@@ -64,15 +104,21 @@ func encryptedMnemonicExtendedPublicKeyPairs(params *dagconfig.Params, mnemonics
 	encryptedPrivateKeys = make([]*EncryptedMnemonic, 0, len(mnemonics))
 	extendedPublicKeys = make([]string, 0, len(mnemonics))
 
-	for _, mnemonic := range mnemonics {
-		extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, isMultisig)
+	for i, mnemonic := range mnemonics {
+		passphrase := ""
+		if passphrases != nil {
+			passphrase = passphrases[i]
+		}
+
+		extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, passphrase, isMultisig)
 		if err != nil {
 			return nil, nil, err
 		}
 
 		extendedPublicKeys = append(extendedPublicKeys, extendedPublicKey)
 
-		encryptedPrivateKey, err := encryptMnemonic(mnemonic, password)
+		encryptedPrivateKey, err := encryptMnemonic(mnemonic, passphrase, password,
+			newArgon2Parameters(DefaultArgon2Time, DefaultArgon2Memory, defaultNumThreads))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -92,30 +138,49 @@ func generateSalt() ([]byte, error) {
 	return salt, nil
 }
 
-func encryptMnemonic(mnemonic string, password []byte) (*EncryptedMnemonic, error) {
-	mnemonicBytes := []byte(mnemonic)
+// encryptMnemonic encrypts the given mnemonic, and its passphrase if it's not empty
+func encryptMnemonic(mnemonic string, passphrase string, password []byte, parameters argon2Parameters) (
+	*EncryptedMnemonic, error) {
 
 	salt, err := generateSalt()
 	if err != nil {
 		return nil, err
 	}
 
-	aead, err := getAEAD(defaultNumThreads, password, salt)
+	aead, err := getAEAD(parameters, password, salt)
 	if err != nil {
 		return nil, err
 	}
 
-	// Select a random nonce, and leave capacity for the ciphertext.
-	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(mnemonicBytes)+aead.Overhead())
-	if _, err := rand.Read(nonce); err != nil {
+	mnemonicCipher, err := encrypt(aead, []byte(mnemonic))
+	if err != nil {
 		return nil, err
 	}
 
-	// Encrypt the message and append the ciphertext to the nonce.
-	cipher := aead.Seal(nonce, nonce, []byte(mnemonicBytes), nil)
+	// The passphrase is encrypted with the same key as the mnemonic, which is safe since every
+	// message is encrypted with its own random nonce
+	var passphraseCipher []byte
+	if passphrase != "" {
+		passphraseCipher, err = encrypt(aead, []byte(passphrase))
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &EncryptedMnemonic{
-		cipher: cipher,
-		salt:   salt,
+		cipher:           mnemonicCipher,
+		salt:             salt,
+		passphraseCipher: passphraseCipher,
 	}, nil
 }
+
+func encrypt(aead cipher.AEAD, message []byte) ([]byte, error) {
+	// Select a random nonce, and leave capacity for the ciphertext.
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(message)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// Encrypt the message and append the ciphertext to the nonce.
+	return aead.Seal(nonce, nonce, message, nil), nil
+}
@@ -25,16 +25,23 @@ var (
 	defaultAppDir = util.AppDir("kaspiwallet", false)
 )
 
-// LastVersion is the most up to date file format version
-const LastVersion = 1
+// LastVersion is the most up to date file format version. Version 1 fixed the number of threads
+// the password is hashed with, version 2 added the BIP39 passphrases of the mnemonics, and version 3
+// added the argon2 cost parameters the password is hashed with.
+const LastVersion = 3
+
+// DefaultArgon2ParametersVersion is the version of up to date files whose password is hashed with the
+// default argon2 cost parameters. They don't need version 3, so older wallets can still read them.
+const DefaultArgon2ParametersVersion = 2
 
 func defaultKeysFile(netParams *dagconfig.Params) string {
 	return filepath.Join(defaultAppDir, netParams.Name, "keys.json")
 }
 
 type encryptedPrivateKeyJSON struct {
-	Cipher string `json:"cipher"`
-	Salt   string `json:"salt"`
+	Cipher           string `json:"cipher"`
+	Salt             string `json:"salt"`
+	PassphraseCipher string `json:"passphraseCipher,omitempty"`
 }
 
 type keysFileJSON struct {
@@ -47,12 +54,26 @@ type keysFileJSON struct {
 	LastUsedExternalIndex uint32                     `json:"lastUsedExternalIndex"`
 	LastUsedInternalIndex uint32                     `json:"lastUsedInternalIndex"`
 	ECDSA                 bool                       `json:"ecdsa"`
+	Accounts              []*accountJSON             `json:"accounts,omitempty"`
+	Argon2Time            uint32                     `json:"argon2Time,omitempty"`
+	Argon2Memory          uint32                     `json:"argon2Memory,omitempty"`
+}
+
+type accountJSON struct {
+	Name                  string   `json:"name"`
+	Index                 uint32   `json:"index"`
+	ExtendedPublicKeys    []string `json:"publicKeys"`
+	CosignerIndex         uint32   `json:"cosignerIndex"`
+	LastUsedExternalIndex uint32   `json:"lastUsedExternalIndex"`
+	LastUsedInternalIndex uint32   `json:"lastUsedInternalIndex"`
 }
 
-// EncryptedMnemonic represents an encrypted mnemonic
+// EncryptedMnemonic represents an encrypted mnemonic, along with the BIP39 passphrase
+// its seed is derived with, if it has one
 type EncryptedMnemonic struct {
-	cipher []byte
-	salt   []byte
+	cipher           []byte
+	salt             []byte
+	passphraseCipher []byte
 }
 
 // File holds all the data related to the wallet keys
@@ -66,18 +87,53 @@ type File struct {
 	lastUsedExternalIndex uint32
 	lastUsedInternalIndex uint32
 	ECDSA                 bool
-	path                  string
+	// Accounts are the accounts of the wallet other than the default one, whose
+	// keys and indexes are the ones above
+	Accounts []*Account
+	// Argon2Time and Argon2Memory (in KiB) are the argon2 cost parameters the password
+	// is hashed with. DefaultArgon2Time and DefaultArgon2Memory are used if they're 0.
+	Argon2Time   uint32
+	Argon2Memory uint32
+	path         string
+}
+
+// DefaultAccountName is the name of the account every wallet is created with
+const DefaultAccountName = "default"
+
+// Account is a named account of the wallet. The keys of the account with index N
+// are derived from the hardened path m/<purpose>'/<coin type>'/N', so that every
+// account has its own extended public keys and addresses.
+type Account struct {
+	Name                  string
+	Index                 uint32
+	ExtendedPublicKeys    []string
+	CosignerIndex         uint32
+	LastUsedExternalIndex uint32
+	LastUsedInternalIndex uint32
 }
 
 func (d *File) toJSON() *keysFileJSON {
 	encryptedPrivateKeysJSON := make([]*encryptedPrivateKeyJSON, len(d.EncryptedMnemonics))
 	for i, encryptedPrivateKey := range d.EncryptedMnemonics {
 		encryptedPrivateKeysJSON[i] = &encryptedPrivateKeyJSON{
-			Cipher: hex.EncodeToString(encryptedPrivateKey.cipher),
-			Salt:   hex.EncodeToString(encryptedPrivateKey.salt),
+			Cipher:           hex.EncodeToString(encryptedPrivateKey.cipher),
+			Salt:             hex.EncodeToString(encryptedPrivateKey.salt),
+			PassphraseCipher: hex.EncodeToString(encryptedPrivateKey.passphraseCipher),
 		}
 	}
 
+	var accountsJSON []*accountJSON
+	for _, account := range d.Accounts {
+		accountsJSON = append(accountsJSON, &accountJSON{
+			Name:                  account.Name,
+			Index:                 account.Index,
+			ExtendedPublicKeys:    account.ExtendedPublicKeys,
+			CosignerIndex:         account.CosignerIndex,
+			LastUsedExternalIndex: account.LastUsedExternalIndex,
+			LastUsedInternalIndex: account.LastUsedInternalIndex,
+		})
+	}
+
 	return &keysFileJSON{
 		Version:               d.Version,
 		NumThreads:            d.NumThreads,
@@ -88,18 +144,21 @@ func (d *File) toJSON() *keysFileJSON {
 		CosignerIndex:         d.CosignerIndex,
 		LastUsedExternalIndex: d.lastUsedExternalIndex,
 		LastUsedInternalIndex: d.lastUsedInternalIndex,
+		Accounts:              accountsJSON,
+		Argon2Time:            d.Argon2Time,
+		Argon2Memory:          d.Argon2Memory,
 	}
 }
 
 // NewFileFromMnemonic generates a new File from the given mnemonic string
 func NewFileFromMnemonic(params *dagconfig.Params, mnemonic string, password string) (*File, error) {
 	encryptedMnemonics, extendedPublicKeys, err :=
-		encryptedMnemonicExtendedPublicKeyPairs(params, []string{mnemonic}, password, false)
+		encryptedMnemonicExtendedPublicKeyPairs(params, []string{mnemonic}, nil, password, false)
 	if err != nil {
 		return nil, err
 	}
 	return &File{
-		Version:            LastVersion,
+		Version:            DefaultArgon2ParametersVersion,
 		NumThreads:         defaultNumThreads,
 		EncryptedMnemonics: encryptedMnemonics,
 		ExtendedPublicKeys: extendedPublicKeys,
@@ -117,6 +176,20 @@ func (d *File) fromJSON(fileJSON *keysFileJSON) error {
 	d.CosignerIndex = fileJSON.CosignerIndex
 	d.lastUsedExternalIndex = fileJSON.LastUsedExternalIndex
 	d.lastUsedInternalIndex = fileJSON.LastUsedInternalIndex
+	d.Argon2Time = fileJSON.Argon2Time
+	d.Argon2Memory = fileJSON.Argon2Memory
+
+	d.Accounts = make([]*Account, len(fileJSON.Accounts))
+	for i, account := range fileJSON.Accounts {
+		d.Accounts[i] = &Account{
+			Name:                  account.Name,
+			Index:                 account.Index,
+			ExtendedPublicKeys:    account.ExtendedPublicKeys,
+			CosignerIndex:         account.CosignerIndex,
+			LastUsedExternalIndex: account.LastUsedExternalIndex,
+			LastUsedInternalIndex: account.LastUsedInternalIndex,
+		}
+	}
 
 	d.EncryptedMnemonics = make([]*EncryptedMnemonic, len(fileJSON.EncryptedPrivateKeys))
 	for i, encryptedPrivateKeyJSON := range fileJSON.EncryptedPrivateKeys {
@@ -130,9 +203,15 @@ func (d *File) fromJSON(fileJSON *keysFileJSON) error {
 			return err
 		}
 
+		passphraseCipher, err := hex.DecodeString(encryptedPrivateKeyJSON.PassphraseCipher)
+		if err != nil {
+			return err
+		}
+
 		d.EncryptedMnemonics[i] = &EncryptedMnemonic{
-			cipher: cipher,
-			salt:   salt,
+			cipher:           cipher,
+			salt:             salt,
+			passphraseCipher: passphraseCipher,
 		}
 	}
 
@@ -205,33 +284,40 @@ func (d *File) LastUsedInternalIndex() uint32 {
 	return d.lastUsedInternalIndex
 }
 
-// DecryptMnemonics asks the user to enter the password for the private keys and
-// returns the decrypted private keys.
-func (d *File) DecryptMnemonics(password string) ([]string, error) {
+// IsWatchOnly returns whether the wallet holds no private keys, and can only watch
+// the addresses of its extended public keys
+func (d *File) IsWatchOnly() bool {
+	return len(d.EncryptedMnemonics) == 0
+}
+
+// DecryptMnemonics decrypts the private keys with the given password, and returns
+// them along with their BIP39 passphrases, which are empty for mnemonics that have none.
+func (d *File) DecryptMnemonics(password string) (mnemonics []string, passphrases []string, err error) {
+	if d.IsWatchOnly() {
+		return nil, nil, errors.New("the wallet is watch-only and has no private keys to sign with")
+	}
+
 	passwordBytes := []byte(password)
 
-	var numThreads uint8
-	if len(d.EncryptedMnemonics) > 0 {
-		var err error
-		numThreads, err = d.numThreads(passwordBytes)
-		if err != nil {
-			return nil, err
-		}
+	parameters, err := d.argon2Parameters(passwordBytes)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	privateKeys := make([]string, len(d.EncryptedMnemonics))
+	mnemonics = make([]string, len(d.EncryptedMnemonics))
+	passphrases = make([]string, len(d.EncryptedMnemonics))
 	for i, encryptedPrivateKey := range d.EncryptedMnemonics {
-		var err error
-		privateKeys[i], err = decryptMnemonic(numThreads, encryptedPrivateKey, passwordBytes)
+		mnemonics[i], passphrases[i], err = decryptMnemonic(parameters, encryptedPrivateKey, passwordBytes)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
-	return privateKeys, nil
+	return mnemonics, passphrases, nil
 }
 
-// ReadKeysFile returns the data related to the keys file
+// ReadKeysFile returns the data related to the keys file. Files of older versions are read as they are,
+// see Migrate.
 func ReadKeysFile(netParams *dagconfig.Params, path string) (*File, error) {
 	if path == "" {
 		path = defaultKeysFile(netParams)
@@ -250,6 +336,11 @@ func ReadKeysFile(netParams *dagconfig.Params, path string) (*File, error) {
 		return nil, err
 	}
 
+	if decodedFile.Version > LastVersion {
+		return nil, errors.Errorf("the keys file %s is of version %d, which is newer than the latest version "+
+			"this wallet supports (%d). Please upgrade the wallet.", path, decodedFile.Version, LastVersion)
+	}
+
 	keysFile := &File{
 		path: path,
 	}
@@ -261,6 +352,31 @@ func ReadKeysFile(netParams *dagconfig.Params, path string) (*File, error) {
 	return keysFile, nil
 }
 
+// Migrate upgrades the file to the up to date version for its argon2 cost parameters, and saves
+// it if it was upgraded. Only commands that write the file should call it, while they hold its lock.
+func (d *File) Migrate() error {
+	// Version 0 files can't be upgraded without re-encrypting the mnemonics, since they
+	// are decrypted with the number of threads that is detected for them. See numThreads.
+	if d.Version == 0 || d.Version >= d.upToDateVersion() {
+		return nil
+	}
+
+	// Version 2 only added the optional BIP39 passphrases, so the mnemonics of version 1
+	// files are kept as is, without a passphrase
+	d.Version = d.upToDateVersion()
+	return d.Save()
+}
+
+// upToDateVersion returns the version the file is written with: LastVersion if its password
+// is hashed with non-default argon2 cost parameters, which older wallets would ignore and fail
+// to decrypt it with, or DefaultArgon2ParametersVersion otherwise
+func (d *File) upToDateVersion() uint32 {
+	if d.Argon2Time != 0 || d.Argon2Memory != 0 {
+		return LastVersion
+	}
+	return DefaultArgon2ParametersVersion
+}
+
 func createFileDirectoryIfDoesntExist(path string) error {
 	dir := filepath.Dir(path)
 	exists, err := pathExists(dir)
@@ -290,7 +406,8 @@ func pathExists(path string) (bool, error) {
 	return false, err
 }
 
-// Save writes the file contents to the disk.
+// Save writes the file contents to the disk. The contents are written to a temporary
+// file that then replaces the file, so that the file is never left partially written.
 func (d *File) Save() error {
 	if d.path == "" {
 		return errors.New("cannot save a file with uninitialized path")
@@ -301,23 +418,118 @@ func (d *File) Save() error {
 		return err
 	}
 
-	file, err := os.OpenFile(d.path, os.O_WRONLY|os.O_CREATE, 0600)
+	temporaryPath := d.path + ".tmp"
+	file, err := os.OpenFile(temporaryPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer os.Remove(temporaryPath)
 
 	encoder := json.NewEncoder(file)
 	err = encoder.Encode(d.toJSON())
 	if err != nil {
+		file.Close()
 		return err
 	}
 
-	return nil
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryPath, d.path)
+}
+
+// ChangePassword re-encrypts the mnemonics of the file, which are currently encrypted with
+// oldPassword, with newPassword, and saves the file. The password is hashed with the given
+// argon2 cost parameters from now on, or with the current ones if they're 0. The parameters
+// can't be lower than the current ones, so that changing the password never weakens the file.
+func (d *File) ChangePassword(oldPassword, newPassword string, argon2Time, argon2Memory uint32) error {
+	current := newArgon2Parameters(d.Argon2Time, d.Argon2Memory, defaultNumThreads)
+	if argon2Time == 0 {
+		argon2Time = current.time
+	}
+	if argon2Memory == 0 {
+		argon2Memory = current.memory
+	}
+	if argon2Time < current.time {
+		return errors.Errorf("the argon2 time %d is lower than the current one, %d", argon2Time, current.time)
+	}
+	if argon2Memory < current.memory {
+		return errors.Errorf("the argon2 memory %d KiB is lower than the current one, %d KiB",
+			argon2Memory, current.memory)
+	}
+	parameters := newArgon2Parameters(argon2Time, argon2Memory, defaultNumThreads)
+
+	mnemonics, passphrases, err := d.DecryptMnemonics(oldPassword)
+	if err != nil {
+		return err
+	}
+
+	encryptedMnemonics := make([]*EncryptedMnemonic, len(mnemonics))
+	for i, mnemonic := range mnemonics {
+		encryptedMnemonics[i], err = encryptMnemonic(mnemonic, passphrases[i], []byte(newPassword), parameters)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Re-encrypting the mnemonics also upgrades version 0 files, since the number
+	// of threads the new password is hashed with is constant
+	// The default parameters are kept as 0, so that the file doesn't need version 3 for them
+	d.EncryptedMnemonics = encryptedMnemonics
+	d.NumThreads = defaultNumThreads
+	d.Argon2Time = 0
+	if argon2Time != DefaultArgon2Time {
+		d.Argon2Time = argon2Time
+	}
+	d.Argon2Memory = 0
+	if argon2Memory != DefaultArgon2Memory {
+		d.Argon2Memory = argon2Memory
+	}
+	d.Version = d.upToDateVersion()
+	return d.Save()
 }
 
 const defaultNumThreads = 8
 
+// The default argon2 cost parameters the password is hashed with
+const (
+	DefaultArgon2Time   = 1
+	DefaultArgon2Memory = 64 * 1024 // In KiB
+)
+
+// argon2Parameters are the argon2 cost parameters the password is hashed with
+type argon2Parameters struct {
+	time       uint32
+	memory     uint32 // In KiB
+	numThreads uint8
+}
+
+func newArgon2Parameters(time, memory uint32, numThreads uint8) argon2Parameters {
+	if time == 0 {
+		time = DefaultArgon2Time
+	}
+	if memory == 0 {
+		memory = DefaultArgon2Memory
+	}
+	return argon2Parameters{time: time, memory: memory, numThreads: numThreads}
+}
+
+func (d *File) argon2Parameters(password []byte) (argon2Parameters, error) {
+	numThreads, err := d.numThreads(password)
+	if err != nil {
+		return argon2Parameters{}, err
+	}
+	return newArgon2Parameters(d.Argon2Time, d.Argon2Memory, numThreads), nil
+}
+
 func (d *File) numThreads(password []byte) (uint8, error) {
 	// There's a bug in v0 wallets where the number of threads
 	// was determined by the number of logical CPUs at the machine,
@@ -349,7 +561,8 @@ func (d *File) detectNumThreads(password []byte, encryptedMnemonic *EncryptedMne
 	if d.NumThreads == 0 {
 		firstGuessNumThreads = uint8(runtime.NumCPU())
 	}
-	_, err := decryptMnemonic(firstGuessNumThreads, encryptedMnemonic, password)
+	_, _, err := decryptMnemonic(newArgon2Parameters(d.Argon2Time, d.Argon2Memory, firstGuessNumThreads),
+		encryptedMnemonic, password)
 	if err != nil {
 		if !strings.Contains(err.Error(), "message authentication failed") {
 			return 0, err
@@ -363,7 +576,8 @@ func (d *File) detectNumThreads(password []byte, encryptedMnemonic *EncryptedMne
 			continue
 		}
 
-		_, err := decryptMnemonic(numThreadsGuess, encryptedMnemonic, password)
+		_, _, err := decryptMnemonic(newArgon2Parameters(d.Argon2Time, d.Argon2Memory, numThreadsGuess),
+			encryptedMnemonic, password)
 		if err != nil {
 			const maxTries = 255
 			if numThreadsGuess == maxTries || !strings.Contains(err.Error(), "message authentication failed") {
@@ -375,23 +589,42 @@ func (d *File) detectNumThreads(password []byte, encryptedMnemonic *EncryptedMne
 	}
 }
 
-func getAEAD(threads uint8, password, salt []byte) (cipher.AEAD, error) {
-	key := argon2.IDKey(password, salt, 1, 64*1024, threads, 32)
+func getAEAD(parameters argon2Parameters, password, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey(password, salt, parameters.time, parameters.memory, parameters.numThreads, 32)
 	return chacha20poly1305.NewX(key)
 }
 
-func decryptMnemonic(numThreads uint8, encryptedPrivateKey *EncryptedMnemonic, password []byte) (string, error) {
-	aead, err := getAEAD(numThreads, password, encryptedPrivateKey.salt)
+func decryptMnemonic(parameters argon2Parameters, encryptedPrivateKey *EncryptedMnemonic, password []byte) (
+	mnemonic string, passphrase string, err error) {
+
+	aead, err := getAEAD(parameters, password, encryptedPrivateKey.salt)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+
+	mnemonic, err = decrypt(aead, encryptedPrivateKey.cipher)
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(encryptedPrivateKey.passphraseCipher) == 0 {
+		return mnemonic, "", nil
+	}
+	passphrase, err = decrypt(aead, encryptedPrivateKey.passphraseCipher)
+	if err != nil {
+		return "", "", err
 	}
 
-	if len(encryptedPrivateKey.cipher) < aead.NonceSize() {
+	return mnemonic, passphrase, nil
+}
+
+func decrypt(aead cipher.AEAD, encrypted []byte) (string, error) {
+	if len(encrypted) < aead.NonceSize() {
 		return "", errors.New("ciphertext too short")
 	}
 
 	// Split nonce and ciphertext.
-	nonce, ciphertext := encryptedPrivateKey.cipher[:aead.NonceSize()], encryptedPrivateKey.cipher[aead.NonceSize():]
+	nonce, ciphertext := encrypted[:aead.NonceSize()], encrypted[aead.NonceSize():]
 
 	// Decrypt the message and check it wasn't tampered with.
 	decrypted, err := aead.Open(nil, nonce, ciphertext, nil)
@@ -0,0 +1,161 @@
+package keys
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+)
+
+func TestChangePassword(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	keysFile, err := NewFileFromMnemonic(params, mnemonic, "old")
+	if err != nil {
+		t.Fatalf("NewFileFromMnemonic: %s", err)
+	}
+	const passphrase = "a passphrase that is longer than the mnemonic of the wallet, to make the saved file shrink " +
+		"once it is re-encrypted without it, which would leave a partially overwritten file behind"
+	keysFile.EncryptedMnemonics[0], err = encryptMnemonic(mnemonic, passphrase, []byte("old"),
+		newArgon2Parameters(0, 0, defaultNumThreads))
+	if err != nil {
+		t.Fatalf("encryptMnemonic: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "keys.json")
+	err = keysFile.SetPath(params, path, true)
+	if err != nil {
+		t.Fatalf("SetPath: %s", err)
+	}
+	err = keysFile.Save()
+	if err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	err = keysFile.ChangePassword("wrong", "new", 0, 0)
+	if err == nil {
+		t.Fatalf("changing the password with a wrong password is expected to fail")
+	}
+	err = keysFile.ChangePassword("old", "old", DefaultArgon2Time, DefaultArgon2Memory)
+	if err != nil {
+		t.Fatalf("ChangePassword: %s", err)
+	}
+	if keysFile.Version != DefaultArgon2ParametersVersion || keysFile.Argon2Time != 0 || keysFile.Argon2Memory != 0 {
+		t.Fatalf("the default argon2 parameters are expected to keep the file at version %d",
+			DefaultArgon2ParametersVersion)
+	}
+	const argon2Time = 2
+	err = keysFile.ChangePassword("old", "new", argon2Time, 0)
+	if err != nil {
+		t.Fatalf("ChangePassword: %s", err)
+	}
+	err = keysFile.ChangePassword("new", "new", argon2Time-1, 0)
+	if err == nil {
+		t.Fatalf("lowering the argon2 time is expected to fail")
+	}
+	err = keysFile.ChangePassword("new", "new", 0, DefaultArgon2Memory-1)
+	if err == nil {
+		t.Fatalf("lowering the argon2 memory is expected to fail")
+	}
+
+	savedKeysFile, err := ReadKeysFile(params, path)
+	if err != nil {
+		t.Fatalf("ReadKeysFile: %s", err)
+	}
+	if savedKeysFile.Argon2Time != argon2Time || savedKeysFile.Argon2Memory != 0 {
+		t.Fatalf("unexpected argon2 parameters %d, %d", savedKeysFile.Argon2Time, savedKeysFile.Argon2Memory)
+	}
+	if savedKeysFile.Version != LastVersion {
+		t.Fatalf("non-default argon2 parameters are expected to need version %d, got %d",
+			LastVersion, savedKeysFile.Version)
+	}
+	_, _, err = savedKeysFile.DecryptMnemonics("old")
+	if err == nil {
+		t.Fatalf("decrypting with the old password is expected to fail")
+	}
+	mnemonics, passphrases, err := savedKeysFile.DecryptMnemonics("new")
+	if err != nil {
+		t.Fatalf("DecryptMnemonics: %s", err)
+	}
+	if len(mnemonics) != 1 || mnemonics[0] != mnemonic || passphrases[0] != passphrase {
+		t.Fatalf("the mnemonic and its passphrase are expected to be kept")
+	}
+
+	// Saving a file with less data must not leave the data of the previous file behind
+	savedKeysFile.EncryptedMnemonics[0], err = encryptMnemonic(mnemonic, "", []byte("new"),
+		newArgon2Parameters(argon2Time, 0, defaultNumThreads))
+	if err != nil {
+		t.Fatalf("encryptMnemonic: %s", err)
+	}
+	err = savedKeysFile.Save()
+	if err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	_, err = ReadKeysFile(params, path)
+	if err != nil {
+		t.Fatalf("ReadKeysFile: %s", err)
+	}
+	_, err = os.Stat(path + ".tmp")
+	if !os.IsNotExist(err) {
+		t.Fatalf("the temporary file is expected to be removed")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	keysFile, err := NewFileFromMnemonic(params, mnemonic, "password")
+	if err != nil {
+		t.Fatalf("NewFileFromMnemonic: %s", err)
+	}
+	keysFile.Version = 1
+	path := filepath.Join(t.TempDir(), "keys.json")
+	err = keysFile.SetPath(params, path, true)
+	if err != nil {
+		t.Fatalf("SetPath: %s", err)
+	}
+	err = keysFile.Save()
+	if err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	// Reading the file must not write it, since readers don't hold its lock
+	readKeysFile, err := ReadKeysFile(params, path)
+	if err != nil {
+		t.Fatalf("ReadKeysFile: %s", err)
+	}
+	if readKeysFile.Version != 1 {
+		t.Fatalf("expected the file to be read as version 1, got %d", readKeysFile.Version)
+	}
+	_, _, err = readKeysFile.DecryptMnemonics("password")
+	if err != nil {
+		t.Fatalf("DecryptMnemonics: %s", err)
+	}
+	savedKeysFile, err := ReadKeysFile(params, path)
+	if err != nil {
+		t.Fatalf("ReadKeysFile: %s", err)
+	}
+	if savedKeysFile.Version != 1 {
+		t.Fatalf("reading the file is expected to leave it at version 1, got %d", savedKeysFile.Version)
+	}
+
+	err = readKeysFile.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %s", err)
+	}
+	savedKeysFile, err = ReadKeysFile(params, path)
+	if err != nil {
+		t.Fatalf("ReadKeysFile: %s", err)
+	}
+	if savedKeysFile.Version != DefaultArgon2ParametersVersion {
+		t.Fatalf("expected the migrated file to be of version %d, got %d",
+			DefaultArgon2ParametersVersion, savedKeysFile.Version)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+)
+
+func setLabel(conf *setLabelConfig) error {
+	label := &pb.Label{Address: conf.Address, Label: conf.Label}
+	if conf.UTXO != "" {
+		outpoints, err := parseOutpoints([]string{conf.UTXO})
+		if err != nil {
+			return err
+		}
+		label.Outpoint = outpoints[0]
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	_, err = daemonClient.SetLabel(ctx, &pb.SetLabelRequest{Label: label})
+	if err != nil {
+		return err
+	}
+	if conf.Label == "" {
+		fmt.Println("Label removed")
+	} else {
+		fmt.Println("Label set")
+	}
+	return nil
+}
+
+func labels(conf *labelsConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.GetLabels(ctx, &pb.GetLabelsRequest{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Labels (%d):\n", len(response.Labels))
+	for _, label := range response.Labels {
+		target := label.Address
+		if label.Outpoint != nil {
+			target = formatOutpoint(label.Outpoint)
+		}
+		fmt.Printf("%s %s\n", target, label.Label)
+	}
+	return nil
+}
@@ -27,18 +27,31 @@ const (
 )
 
 func defaultPath(isMultisig bool) string {
+	return accountPath(isMultisig, 0)
+}
+
+func accountPath(isMultisig bool, accountIndex uint32) string {
 	purpose := SingleSignerPurpose
 	if isMultisig {
 		purpose = MultiSigPurpose
 	}
 
-	return fmt.Sprintf("m/%d'/%d'/0'", purpose, CoinType)
+	return fmt.Sprintf("m/%d'/%d'/%d'", purpose, CoinType, accountIndex)
 }
 
 // MasterPublicKeyFromMnemonic returns the master public key with the correct derivation for the given mnemonic.
-func MasterPublicKeyFromMnemonic(params *dagconfig.Params, mnemonic string, isMultisig bool) (string, error) {
-	path := defaultPath(isMultisig)
-	extendedKey, err := extendedKeyFromMnemonicAndPath(mnemonic, path, params)
+// passphrase is the BIP39 passphrase the seed of the mnemonic is derived with, or empty if there is none.
+func MasterPublicKeyFromMnemonic(params *dagconfig.Params, mnemonic string, passphrase string, isMultisig bool) (string, error) {
+	return AccountPublicKeyFromMnemonic(params, mnemonic, passphrase, isMultisig, 0)
+}
+
+// AccountPublicKeyFromMnemonic returns the extended public key of the account with the given
+// index for the given mnemonic. The account with index 0 is the one of MasterPublicKeyFromMnemonic.
+func AccountPublicKeyFromMnemonic(params *dagconfig.Params, mnemonic string, passphrase string, isMultisig bool,
+	accountIndex uint32) (string, error) {
+
+	path := accountPath(isMultisig, accountIndex)
+	extendedKey, err := extendedKeyFromMnemonicAndPath(mnemonic, passphrase, path, params)
 	if err != nil {
 		return "", err
 	}
@@ -51,8 +64,30 @@ func MasterPublicKeyFromMnemonic(params *dagconfig.Params, mnemonic string, isMu
 	return extendedPublicKey.String(), nil
 }
 
-func extendedKeyFromMnemonicAndPath(mnemonic string, path string, params *dagconfig.Params) (*bip32.ExtendedKey, error) {
-	seed := bip39.NewSeed(mnemonic, "")
+// ValidateExtendedPublicKey returns an error if the given string isn't an extended public key of the given network
+func ValidateExtendedPublicKey(params *dagconfig.Params, extendedPublicKey string) error {
+	extendedKey, err := bip32.DeserializeExtendedKey(extendedPublicKey)
+	if err != nil {
+		return errors.Wrapf(err, "%s is invalid extended public key", extendedPublicKey)
+	}
+	if extendedKey.IsPrivate() {
+		return errors.Errorf("%s is an extended private key, while an extended public key is expected", extendedPublicKey)
+	}
+
+	version, err := publicVersionFromParams(params)
+	if err != nil {
+		return err
+	}
+	if extendedKey.Version != version {
+		return errors.Errorf("%s is not an extended public key of %s", extendedPublicKey, params.Name)
+	}
+	return nil
+}
+
+func extendedKeyFromMnemonicAndPath(mnemonic string, passphrase string, path string, params *dagconfig.Params) (
+	*bip32.ExtendedKey, error) {
+
+	seed := bip39.NewSeed(mnemonic, passphrase)
 	version, err := versionFromParams(params)
 	if err != nil {
 		return nil, err
@@ -80,3 +115,18 @@ func versionFromParams(params *dagconfig.Params) ([4]byte, error) {
 
 	return [4]byte{}, errors.Errorf("unknown network %s", params.Name)
 }
+
+func publicVersionFromParams(params *dagconfig.Params) ([4]byte, error) {
+	switch params.Name {
+	case dagconfig.MainnetParams.Name:
+		return bip32.KaspiMainnetPublic, nil
+	case dagconfig.TestnetParams.Name:
+		return bip32.KaspiTestnetPublic, nil
+	case dagconfig.DevnetParams.Name:
+		return bip32.KaspiDevnetPublic, nil
+	case dagconfig.SimnetParams.Name:
+		return bip32.KaspiSimnetPublic, nil
+	}
+
+	return [4]byte{}, errors.Errorf("unknown network %s", params.Name)
+}
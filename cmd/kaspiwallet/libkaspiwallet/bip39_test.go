@@ -0,0 +1,44 @@
+package libkaspiwallet_test
+
+import (
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/bip32"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+)
+
+func TestValidateExtendedPublicKey(t *testing.T) {
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	for _, isMultisig := range []bool{false, true} {
+		extendedPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(&dagconfig.MainnetParams, mnemonic, "", isMultisig)
+		if err != nil {
+			t.Fatalf("MasterPublicKeyFromMnemonic: %s", err)
+		}
+		err = libkaspiwallet.ValidateExtendedPublicKey(&dagconfig.MainnetParams, extendedPublicKey)
+		if err != nil {
+			t.Fatalf("ValidateExtendedPublicKey: %s", err)
+		}
+		err = libkaspiwallet.ValidateExtendedPublicKey(&dagconfig.TestnetParams, extendedPublicKey)
+		if err == nil {
+			t.Fatalf("a mainnet extended public key is expected to be invalid on testnet")
+		}
+	}
+
+	extendedPrivateKey, err := bip32.NewMaster(make([]byte, 32), bip32.KaspiMainnetPrivate)
+	if err != nil {
+		t.Fatalf("NewMaster: %s", err)
+	}
+	err = libkaspiwallet.ValidateExtendedPublicKey(&dagconfig.MainnetParams, extendedPrivateKey.String())
+	if err == nil {
+		t.Fatalf("an extended private key is expected to be invalid")
+	}
+
+	err = libkaspiwallet.ValidateExtendedPublicKey(&dagconfig.MainnetParams, "kpub-invalid")
+	if err == nil {
+		t.Fatalf("a malformed extended public key is expected to be invalid")
+	}
+}
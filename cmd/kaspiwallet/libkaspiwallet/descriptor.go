@@ -0,0 +1,250 @@
+package libkaspiwallet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+// A wallet descriptor is a text describing the keys of a wallet, in the style of the output descriptors
+// of BIP 380, which cosigners can exchange instead of exchanging their extended public keys one by one:
+//
+//	pk([m/44'/111111'/0']kpub.../<0;1>/*)#checksum
+//	sortedmulti(2,[m/45'/111111'/0']kpub.../*/<0;1>/*,[m/45'/111111'/0']kpub.../*/<0;1>/*)#checksum
+//
+// The ECDSA variants are pk_ecdsa and sortedmulti_ecdsa. Every key is preceded by the derivation path
+// of the account it belongs to, and followed by the template of the paths of its addresses, where the
+// first wildcard of a multisig key is the cosigner index, <0;1> are the receive and change key chains,
+// and the last wildcard is the address index. The keys of a multisig wallet are sorted, so all cosigners
+// export the same descriptor, and the checksum is the checksum of BIP 380.
+const (
+	descriptorSingleKeyScript      = "pk"
+	descriptorSingleKeyScriptECDSA = "pk_ecdsa"
+	descriptorMultisigScript       = "sortedmulti"
+	descriptorMultisigScriptECDSA  = "sortedmulti_ecdsa"
+
+	descriptorSingleKeyPathTemplate = "/<0;1>/*"
+	descriptorMultisigPathTemplate  = "/*/<0;1>/*"
+)
+
+// WalletDescriptor describes the keys of a wallet
+type WalletDescriptor struct {
+	ECDSA              bool
+	MinimumSignatures  uint32
+	ExtendedPublicKeys []string
+}
+
+// NewWalletDescriptor returns the descriptor of a wallet with the given keys
+func NewWalletDescriptor(params *dagconfig.Params, extendedPublicKeys []string, minimumSignatures uint32,
+	ecdsa bool) (*WalletDescriptor, error) {
+
+	sortedExtendedPublicKeys := make([]string, len(extendedPublicKeys))
+	copy(sortedExtendedPublicKeys, extendedPublicKeys)
+	sortPublicKeys(sortedExtendedPublicKeys)
+
+	descriptor := &WalletDescriptor{
+		ECDSA:              ecdsa,
+		MinimumSignatures:  minimumSignatures,
+		ExtendedPublicKeys: sortedExtendedPublicKeys,
+	}
+	err := descriptor.validate(params)
+	if err != nil {
+		return nil, err
+	}
+	return descriptor, nil
+}
+
+func (d *WalletDescriptor) validate(params *dagconfig.Params) error {
+	if len(d.ExtendedPublicKeys) == 0 {
+		return errors.New("the descriptor has no keys")
+	}
+	if d.MinimumSignatures == 0 || int(d.MinimumSignatures) > len(d.ExtendedPublicKeys) {
+		return errors.Errorf("the minimum signatures must be between 1 and the number of keys (%d)",
+			len(d.ExtendedPublicKeys))
+	}
+	for i, extendedPublicKey := range d.ExtendedPublicKeys {
+		err := ValidateExtendedPublicKey(params, extendedPublicKey)
+		if err != nil {
+			return err
+		}
+		if i > 0 && d.ExtendedPublicKeys[i-1] == extendedPublicKey {
+			return errors.Errorf("the extended public key %s appears more than once", extendedPublicKey)
+		}
+	}
+	return nil
+}
+
+// IsMultisig returns whether the described wallet is a multisig wallet
+func (d *WalletDescriptor) IsMultisig() bool {
+	return len(d.ExtendedPublicKeys) > 1
+}
+
+// String returns the text of the descriptor, including its checksum
+func (d *WalletDescriptor) String() string {
+	pathTemplate := descriptorSingleKeyPathTemplate
+	if d.IsMultisig() {
+		pathTemplate = descriptorMultisigPathTemplate
+	}
+	keys := make([]string, len(d.ExtendedPublicKeys))
+	for i, extendedPublicKey := range d.ExtendedPublicKeys {
+		keys[i] = fmt.Sprintf("[%s]%s%s", defaultPath(d.IsMultisig()), extendedPublicKey, pathTemplate)
+	}
+
+	var descriptor string
+	if d.IsMultisig() {
+		descriptor = fmt.Sprintf("%s(%d,%s)", d.script(), d.MinimumSignatures, strings.Join(keys, ","))
+	} else {
+		descriptor = fmt.Sprintf("%s(%s)", d.script(), keys[0])
+	}
+	return descriptor + "#" + descriptorChecksum(descriptor)
+}
+
+func (d *WalletDescriptor) script() string {
+	switch {
+	case d.IsMultisig() && d.ECDSA:
+		return descriptorMultisigScriptECDSA
+	case d.IsMultisig():
+		return descriptorMultisigScript
+	case d.ECDSA:
+		return descriptorSingleKeyScriptECDSA
+	default:
+		return descriptorSingleKeyScript
+	}
+}
+
+// Address returns the address with the given index in the given key chain of the cosigner with the
+// given index, as the wallet of that cosigner derives it
+func (d *WalletDescriptor) Address(params *dagconfig.Params, cosignerIndex uint32, keyChain uint32,
+	index uint32) (util.Address, error) {
+
+	path := fmt.Sprintf("m/%d/%d", keyChain, index)
+	if d.IsMultisig() {
+		path = fmt.Sprintf("m/%d/%d/%d", cosignerIndex, keyChain, index)
+	}
+	extendedPublicKeys := make([]string, len(d.ExtendedPublicKeys))
+	copy(extendedPublicKeys, d.ExtendedPublicKeys)
+	return Address(params, extendedPublicKeys, d.MinimumSignatures, path, d.ECDSA)
+}
+
+// ParseWalletDescriptor parses the text of a wallet descriptor, and verifies its checksum
+func ParseWalletDescriptor(params *dagconfig.Params, text string) (*WalletDescriptor, error) {
+	text = strings.TrimSpace(text)
+	hashIndex := strings.LastIndex(text, "#")
+	if hashIndex == -1 {
+		return nil, errors.New("the descriptor has no checksum")
+	}
+	descriptor, checksum := text[:hashIndex], text[hashIndex+1:]
+	for _, char := range descriptor {
+		if !strings.ContainsRune(descriptorInputCharset, char) {
+			return nil, errors.Errorf("the descriptor contains the invalid character %q", char)
+		}
+	}
+	if checksum != descriptorChecksum(descriptor) {
+		return nil, errors.New("the checksum of the descriptor is wrong: it was probably mistyped")
+	}
+
+	openIndex := strings.Index(descriptor, "(")
+	if openIndex == -1 || !strings.HasSuffix(descriptor, ")") {
+		return nil, errors.Errorf("the descriptor %s is malformed", descriptor)
+	}
+	script := descriptor[:openIndex]
+	arguments := strings.Split(descriptor[openIndex+1:len(descriptor)-1], ",")
+
+	parsed := &WalletDescriptor{}
+	var isMultisig bool
+	switch script {
+	case descriptorSingleKeyScript, descriptorSingleKeyScriptECDSA:
+		if len(arguments) != 1 {
+			return nil, errors.Errorf("%s() expects a single key", script)
+		}
+		parsed.MinimumSignatures = 1
+	case descriptorMultisigScript, descriptorMultisigScriptECDSA:
+		if len(arguments) < 3 {
+			return nil, errors.Errorf("%s() expects the minimum signatures and at least two keys", script)
+		}
+		minimumSignatures, err := strconv.ParseUint(arguments[0], 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid minimum signatures %s", arguments[0])
+		}
+		parsed.MinimumSignatures = uint32(minimumSignatures)
+		arguments = arguments[1:]
+		isMultisig = true
+	default:
+		return nil, errors.Errorf("unknown descriptor script %s", script)
+	}
+	parsed.ECDSA = script == descriptorSingleKeyScriptECDSA || script == descriptorMultisigScriptECDSA
+
+	pathTemplate := descriptorSingleKeyPathTemplate
+	if isMultisig {
+		pathTemplate = descriptorMultisigPathTemplate
+	}
+	keyOrigin := fmt.Sprintf("[%s]", defaultPath(isMultisig))
+	parsed.ExtendedPublicKeys = make([]string, len(arguments))
+	for i, key := range arguments {
+		if !strings.HasPrefix(key, keyOrigin) {
+			return nil, errors.Errorf("the key %s is expected to be derived from %s", key, keyOrigin)
+		}
+		if !strings.HasSuffix(key, pathTemplate) {
+			return nil, errors.Errorf("the addresses of the key %s are expected to be derived with %s", key, pathTemplate)
+		}
+		parsed.ExtendedPublicKeys[i] = strings.TrimSuffix(strings.TrimPrefix(key, keyOrigin), pathTemplate)
+	}
+
+	return NewWalletDescriptor(params, parsed.ExtendedPublicKeys, parsed.MinimumSignatures, parsed.ECDSA)
+}
+
+// The checksum of BIP 380
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	descriptorChecksumLength  = 8
+)
+
+var descriptorChecksumGenerator = [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+func descriptorChecksumPolymod(checksum uint64, value uint64) uint64 {
+	top := checksum >> 35
+	checksum = (checksum&0x7ffffffff)<<5 ^ value
+	for i, generator := range descriptorChecksumGenerator {
+		if (top>>i)&1 == 1 {
+			checksum ^= generator
+		}
+	}
+	return checksum
+}
+
+// descriptorChecksum returns the checksum of the given descriptor, which is
+// expected to contain only characters of descriptorInputCharset
+func descriptorChecksum(descriptor string) string {
+	checksum := uint64(1)
+	var groups []uint64
+	for _, char := range descriptor {
+		position := uint64(strings.IndexRune(descriptorInputCharset, char))
+		checksum = descriptorChecksumPolymod(checksum, position&31)
+		groups = append(groups, position>>5)
+		if len(groups) == 3 {
+			checksum = descriptorChecksumPolymod(checksum, groups[0]*9+groups[1]*3+groups[2])
+			groups = groups[:0]
+		}
+	}
+	switch len(groups) {
+	case 1:
+		checksum = descriptorChecksumPolymod(checksum, groups[0])
+	case 2:
+		checksum = descriptorChecksumPolymod(checksum, groups[0]*3+groups[1])
+	}
+	for i := 0; i < descriptorChecksumLength; i++ {
+		checksum = descriptorChecksumPolymod(checksum, 0)
+	}
+	checksum ^= 1
+
+	result := make([]byte, descriptorChecksumLength)
+	for i := range result {
+		result[i] = descriptorChecksumCharset[(checksum>>(5*(descriptorChecksumLength-1-i)))&31]
+	}
+	return string(result)
+}
@@ -0,0 +1,92 @@
+package libkaspiwallet_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/consensus"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/testutils"
+)
+
+func TestWalletDescriptor(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		params := &consensusConfig.Params
+		forSchnorrAndECDSA(t, func(t *testing.T, ecdsa bool) {
+			for _, numKeys := range []int{1, 3} {
+				isMultisig := numKeys > 1
+				publicKeys := make([]string, numKeys)
+				for i := range publicKeys {
+					mnemonic, err := libkaspiwallet.CreateMnemonic()
+					if err != nil {
+						t.Fatalf("CreateMnemonic: %+v", err)
+					}
+					publicKeys[i], err = libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", isMultisig)
+					if err != nil {
+						t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
+					}
+				}
+				minimumSignatures := uint32(numKeys+1) / 2
+
+				descriptor, err := libkaspiwallet.NewWalletDescriptor(params, publicKeys, minimumSignatures, ecdsa)
+				if err != nil {
+					t.Fatalf("NewWalletDescriptor: %+v", err)
+				}
+
+				// Cosigners that list the keys in a different order export the same descriptor
+				reversedPublicKeys := make([]string, numKeys)
+				for i, publicKey := range publicKeys {
+					reversedPublicKeys[numKeys-1-i] = publicKey
+				}
+				otherDescriptor, err := libkaspiwallet.NewWalletDescriptor(params, reversedPublicKeys, minimumSignatures, ecdsa)
+				if err != nil {
+					t.Fatalf("NewWalletDescriptor: %+v", err)
+				}
+				if descriptor.String() != otherDescriptor.String() {
+					t.Fatalf("The descriptors of the same wallet are different: %s and %s", descriptor, otherDescriptor)
+				}
+
+				parsed, err := libkaspiwallet.ParseWalletDescriptor(params, descriptor.String())
+				if err != nil {
+					t.Fatalf("ParseWalletDescriptor: %+v", err)
+				}
+				if parsed.String() != descriptor.String() {
+					t.Fatalf("The parsed descriptor is %s, while %s was expected", parsed, descriptor)
+				}
+				if parsed.ECDSA != ecdsa || parsed.MinimumSignatures != minimumSignatures {
+					t.Fatalf("The parsed descriptor has unexpected parameters")
+				}
+
+				for cosignerIndex := uint32(0); cosignerIndex < uint32(numKeys); cosignerIndex++ {
+					address, err := parsed.Address(params, cosignerIndex, libkaspiwallet.ExternalKeychain, 7)
+					if err != nil {
+						t.Fatalf("Address: %+v", err)
+					}
+					path := "m/0/7"
+					if isMultisig {
+						path = fmt.Sprintf("m/%d/0/7", cosignerIndex)
+					}
+					expectedAddress, err := libkaspiwallet.Address(params, publicKeys, minimumSignatures, path, ecdsa)
+					if err != nil {
+						t.Fatalf("Address: %+v", err)
+					}
+					if address.String() != expectedAddress.String() {
+						t.Fatalf("The descriptor derived the address %s, while %s was expected", address, expectedAddress)
+					}
+				}
+
+				text := descriptor.String()
+				mistyped := strings.Replace(text, "(", "( ", 1)
+				_, err = libkaspiwallet.ParseWalletDescriptor(params, mistyped)
+				if err == nil || !strings.Contains(err.Error(), "checksum") {
+					t.Fatalf("ParseWalletDescriptor unexpectedly accepted a descriptor with a wrong checksum: %v", err)
+				}
+				_, err = libkaspiwallet.ParseWalletDescriptor(params, text[:strings.LastIndex(text, "#")])
+				if err == nil {
+					t.Fatalf("ParseWalletDescriptor unexpectedly accepted a descriptor without a checksum")
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,221 @@
+package libkaspiwallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+// HTLC is a hash time-locked contract, which pays to its recipient once they reveal the secret behind
+// its secret hash, or back to its refund address once its lock time has passed. Since contracts on
+// several chains can be locked with the same secret hash, HTLCs allow atomic swaps between the chains.
+type HTLC struct {
+	Contract         []byte
+	RecipientAddress util.Address
+	RefundAddress    util.Address
+	SecretHash       [32]byte
+	LockTime         uint64
+}
+
+// NewHTLC creates a hash time-locked contract between two public key addresses of the same type.
+// See txscript.HTLCScript for the meaning of lockTime.
+func NewHTLC(recipientAddress util.Address, refundAddress util.Address, secretHash []byte, lockTime uint64) (*HTLC, error) {
+	var ecdsa bool
+	switch recipientAddress.(type) {
+	case *util.AddressPublicKey:
+		if _, ok := refundAddress.(*util.AddressPublicKey); !ok {
+			return nil, errors.Errorf("the refund address %s must be a Schnorr public key address, "+
+				"like the recipient address", refundAddress)
+		}
+	case *util.AddressPublicKeyECDSA:
+		if _, ok := refundAddress.(*util.AddressPublicKeyECDSA); !ok {
+			return nil, errors.Errorf("the refund address %s must be an ECDSA public key address, "+
+				"like the recipient address", refundAddress)
+		}
+		ecdsa = true
+	default:
+		return nil, errors.Errorf("the recipient address %s of a hash time-locked contract must be "+
+			"a public key address", recipientAddress)
+	}
+
+	contract, err := txscript.HTLCScript(secretHash, recipientAddress.ScriptAddress(), refundAddress.ScriptAddress(),
+		lockTime, ecdsa)
+	if err != nil {
+		return nil, err
+	}
+
+	htlc := &HTLC{
+		Contract:         contract,
+		RecipientAddress: recipientAddress,
+		RefundAddress:    refundAddress,
+		LockTime:         lockTime,
+	}
+	copy(htlc.SecretHash[:], secretHash)
+	return htlc, nil
+}
+
+// ParseHTLC parses a hash time-locked contract as created by NewHTLC
+func ParseHTLC(params *dagconfig.Params, contract []byte) (*HTLC, error) {
+	pushes, err := txscript.ExtractHTLCDataPushes(contract)
+	if err != nil {
+		return nil, err
+	}
+	if pushes == nil {
+		return nil, errors.Errorf("the script is not a hash time-locked contract")
+	}
+
+	htlc := &HTLC{
+		Contract:   contract,
+		SecretHash: pushes.SecretHash,
+		LockTime:   pushes.LockTime,
+	}
+	if pushes.ECDSA {
+		htlc.RecipientAddress, err = util.NewAddressPublicKeyECDSA(pushes.RecipientPublicKey, params.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		htlc.RefundAddress, err = util.NewAddressPublicKeyECDSA(pushes.RefundPublicKey, params.Prefix)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		htlc.RecipientAddress, err = util.NewAddressPublicKey(pushes.RecipientPublicKey, params.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		htlc.RefundAddress, err = util.NewAddressPublicKey(pushes.RefundPublicKey, params.Prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return htlc, nil
+}
+
+// Address returns the P2SH address the contract is paid to
+func (h *HTLC) Address(params *dagconfig.Params) (util.Address, error) {
+	return util.NewAddressScriptHash(h.Contract, params.Prefix)
+}
+
+// IsECDSA returns whether the keys of the contract are ECDSA keys
+func (h *HTLC) IsECDSA() bool {
+	_, isECDSA := h.RecipientAddress.(*util.AddressPublicKeyECDSA)
+	return isECDSA
+}
+
+// IsLockTimeReached returns whether a refund of the contract is valid in a DAG with the given
+// virtual DAA score and past median time
+func (h *HTLC) IsLockTimeReached(virtualDAAScore uint64, pastMedianTime int64) bool {
+	return IsLockTimeReached(h.LockTime, virtualDAAScore, pastMedianTime)
+}
+
+// CheckSecret returns an error if secret isn't the secret behind the secret hash of the contract
+func (h *HTLC) CheckSecret(secret []byte) error {
+	if len(secret) != txscript.HTLCSecretSize {
+		return errors.Errorf("the secret must be %d bytes, but it's %d bytes", txscript.HTLCSecretSize, len(secret))
+	}
+	if sha256.Sum256(secret) != h.SecretHash {
+		return errors.Errorf("the secret doesn't match the secret hash of the contract")
+	}
+	return nil
+}
+
+// CreateHTLCSpendTransaction creates an unsigned transaction that spends the given UTXOs of the contract,
+// paying them minus the fee to the recipient address, or to the refund address if isRefund is set
+func CreateHTLCSpendTransaction(htlc *HTLC, utxos []*UTXO, fee uint64, isRefund bool) (*externalapi.DomainTransaction, error) {
+	if len(utxos) == 0 {
+		return nil, errors.Errorf("there are no UTXOs to spend")
+	}
+
+	inputs := make([]*externalapi.DomainTransactionInput, len(utxos))
+	totalAmount := uint64(0)
+	for i, utxo := range utxos {
+		inputs[i] = &externalapi.DomainTransactionInput{
+			PreviousOutpoint: *utxo.Outpoint,
+			UTXOEntry:        utxo.UTXOEntry,
+			SigOpCount:       1,
+		}
+		totalAmount += utxo.UTXOEntry.Amount()
+	}
+	if totalAmount <= fee {
+		return nil, errors.Errorf("the amount locked in the contract is %d sompi, which can't pay a fee of %d sompi",
+			totalAmount, fee)
+	}
+
+	toAddress, lockTime := htlc.RecipientAddress, uint64(0)
+	if isRefund {
+		// The lock time of the transaction must reach the lock time of the contract for
+		// OP_CHECKLOCKTIMEVERIFY to succeed. The sequence of the inputs is already less
+		// than the maximum, so the lock time is enforced.
+		toAddress, lockTime = htlc.RefundAddress, htlc.LockTime
+	}
+	scriptPublicKey, err := txscript.PayToAddrScript(toAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &externalapi.DomainTransaction{
+		Version: constants.MaxTransactionVersion,
+		Inputs:  inputs,
+		Outputs: []*externalapi.DomainTransactionOutput{{
+			Value:           totalAmount - fee,
+			ScriptPublicKey: scriptPublicKey,
+		}},
+		LockTime:     lockTime,
+		SubnetworkID: subnetworks.SubnetworkIDNative,
+		Gas:          0,
+		Payload:      nil,
+	}, nil
+}
+
+// SignHTLCSpendTransaction signs all the inputs of a transaction created by CreateHTLCSpendTransaction with the
+// key of a single signer wallet with the given derivation path. secret is the secret that redeems the contract,
+// or nil for a refund. passphrase is the BIP39 passphrase the seed of the mnemonic is derived with, if there is one.
+func SignHTLCSpendTransaction(params *dagconfig.Params, tx *externalapi.DomainTransaction, htlc *HTLC,
+	mnemonic string, passphrase string, derivationPath string, secret []byte) error {
+
+	expectedAddress := htlc.RefundAddress
+	if secret != nil {
+		err := htlc.CheckSecret(secret)
+		if err != nil {
+			return err
+		}
+		expectedAddress = htlc.RecipientAddress
+	}
+
+	key, err := singleSignerKey(params, mnemonic, passphrase, derivationPath)
+	if err != nil {
+		return err
+	}
+	address, err := keyAddress(params, key, htlc.IsECDSA())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(address.ScriptAddress(), expectedAddress.ScriptAddress()) {
+		return errors.Errorf("the key with derivation path %s is not the key of %s", derivationPath, expectedAddress)
+	}
+
+	sighashReusedValues := &consensushashing.SighashReusedValues{}
+	for i, input := range tx.Inputs {
+		signature, err := rawTxInSignature(key, tx, i, consensushashing.SigHashAll, sighashReusedValues, htlc.IsECDSA())
+		if err != nil {
+			return err
+		}
+		if secret != nil {
+			input.SignatureScript, err = txscript.HTLCRedeemSignatureScript(htlc.Contract, signature, secret)
+		} else {
+			input.SignatureScript, err = txscript.HTLCRefundSignatureScript(htlc.Contract, signature)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package libkaspiwallet_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+)
+
+func TestHTLC(t *testing.T) {
+	params := &dagconfig.SimnetParams
+	forSchnorrAndECDSA(t, func(t *testing.T, ecdsa bool) {
+		recipientMnemonic, err := libkaspiwallet.CreateMnemonic()
+		if err != nil {
+			t.Fatalf("CreateMnemonic: %+v", err)
+		}
+		refundMnemonic, err := libkaspiwallet.CreateMnemonic()
+		if err != nil {
+			t.Fatalf("CreateMnemonic: %+v", err)
+		}
+		const path = "m/0/1"
+		recipientPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, recipientMnemonic, "", false)
+		if err != nil {
+			t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
+		}
+		recipientAddress, err := libkaspiwallet.Address(params, []string{recipientPublicKey}, 1, path, ecdsa)
+		if err != nil {
+			t.Fatalf("Address: %+v", err)
+		}
+		refundPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, refundMnemonic, "", false)
+		if err != nil {
+			t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
+		}
+		refundAddress, err := libkaspiwallet.Address(params, []string{refundPublicKey}, 1, path, ecdsa)
+		if err != nil {
+			t.Fatalf("Address: %+v", err)
+		}
+
+		secret := bytes.Repeat([]byte{1}, txscript.HTLCSecretSize)
+		secretHash := sha256.Sum256(secret)
+		const lockTime = 1000
+		htlc, err := libkaspiwallet.NewHTLC(recipientAddress, refundAddress, secretHash[:], lockTime)
+		if err != nil {
+			t.Fatalf("NewHTLC: %+v", err)
+		}
+		parsedHTLC, err := libkaspiwallet.ParseHTLC(params, htlc.Contract)
+		if err != nil {
+			t.Fatalf("ParseHTLC: %+v", err)
+		}
+		if parsedHTLC.RecipientAddress.String() != recipientAddress.String() ||
+			parsedHTLC.RefundAddress.String() != refundAddress.String() ||
+			parsedHTLC.SecretHash != secretHash || parsedHTLC.LockTime != lockTime || parsedHTLC.IsECDSA() != ecdsa {
+
+			t.Fatalf("The parsed contract %+v is different from %+v", parsedHTLC, htlc)
+		}
+
+		contractAddress, err := htlc.Address(params)
+		if err != nil {
+			t.Fatalf("Address: %+v", err)
+		}
+		contractScriptPublicKey, err := txscript.PayToAddrScript(contractAddress)
+		if err != nil {
+			t.Fatalf("PayToAddrScript: %+v", err)
+		}
+		utxos := []*libkaspiwallet.UTXO{{
+			Outpoint:  &externalapi.DomainOutpoint{Index: 1},
+			UTXOEntry: utxo.NewUTXOEntry(100_000, contractScriptPublicKey, false, 10),
+		}}
+
+		checkSpend := func(isRefund bool, mnemonic string, secret []byte) {
+			tx, err := libkaspiwallet.CreateHTLCSpendTransaction(htlc, utxos, 1000, isRefund)
+			if err != nil {
+				t.Fatalf("CreateHTLCSpendTransaction: %+v", err)
+			}
+			err = libkaspiwallet.SignHTLCSpendTransaction(params, tx, htlc, mnemonic, "", path, secret)
+			if err != nil {
+				t.Fatalf("SignHTLCSpendTransaction: %+v", err)
+			}
+			if tx.Outputs[0].Value != 99_000 {
+				t.Fatalf("Unexpected output value %d", tx.Outputs[0].Value)
+			}
+
+			vm, err := txscript.NewEngine(contractScriptPublicKey, tx, 0, txscript.ScriptNoFlags, nil, nil,
+				&consensushashing.SighashReusedValues{})
+			if err != nil {
+				t.Fatalf("NewEngine: %+v", err)
+			}
+			err = vm.Execute()
+			if err != nil {
+				t.Fatalf("The spend of the contract is invalid: %+v", err)
+			}
+		}
+		checkSpend(false, recipientMnemonic, secret)
+		checkSpend(true, refundMnemonic, nil)
+
+		tx, err := libkaspiwallet.CreateHTLCSpendTransaction(htlc, utxos, 1000, false)
+		if err != nil {
+			t.Fatalf("CreateHTLCSpendTransaction: %+v", err)
+		}
+		err = libkaspiwallet.SignHTLCSpendTransaction(params, tx, htlc, refundMnemonic, "", path, secret)
+		if err == nil {
+			t.Fatalf("The contract was redeemed with the refund key")
+		}
+		err = libkaspiwallet.SignHTLCSpendTransaction(params, tx, htlc, recipientMnemonic, "", path,
+			bytes.Repeat([]byte{2}, txscript.HTLCSecretSize))
+		if err == nil {
+			t.Fatalf("The contract was redeemed with a wrong secret")
+		}
+	})
+}
@@ -1,8 +1,64 @@
 package libkaspiwallet
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/bip32"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/pkg/errors"
+)
+
 const (
 	// ExternalKeychain is the key chain that is used to create receive addresses
 	ExternalKeychain = 0
 	// InternalKeychain is used to create change addresses
 	InternalKeychain = 1
 )
+
+// AccountDerivationPath returns the derivation path of a transaction input that spends from the
+// given path of the account with the given index. The paths of the first account are kept as
+// they are, and the paths of other accounts are prefixed with their hardened account index,
+// so that a signer knows which account key to derive them from.
+func AccountDerivationPath(accountIndex uint32, path string) string {
+	if accountIndex == 0 {
+		return path
+	}
+	return fmt.Sprintf("m/%d'%s", accountIndex, strings.TrimPrefix(path, "m"))
+}
+
+// singleSignerKey derives the key of a single signer wallet with the given derivation path (as returned
+// by AccountDerivationPath) from its mnemonic
+func singleSignerKey(params *dagconfig.Params, mnemonic string, passphrase string, derivationPath string) (
+	*bip32.ExtendedKey, error) {
+
+	accountIndex, pathInAccount, err := splitAccountDerivationPath(derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	extendedKey, err := extendedKeyFromMnemonicAndPath(mnemonic, passphrase, accountPath(false, accountIndex), params)
+	if err != nil {
+		return nil, err
+	}
+	return extendedKey.DeriveFromPath(pathInAccount)
+}
+
+// splitAccountDerivationPath splits a derivation path returned by AccountDerivationPath back into
+// its account index and the path within the account
+func splitAccountDerivationPath(derivationPath string) (accountIndex uint32, path string, err error) {
+	parts := strings.SplitN(derivationPath, "/", 3)
+	if len(parts) < 2 || !strings.HasSuffix(parts[1], "'") {
+		return 0, derivationPath, nil
+	}
+
+	index, err := strconv.ParseUint(strings.TrimSuffix(parts[1], "'"), 10, 31)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "invalid account index in derivation path %s", derivationPath)
+	}
+	path = parts[0]
+	if len(parts) == 3 {
+		path += "/" + parts[2]
+	}
+	return uint32(index), path, nil
+}
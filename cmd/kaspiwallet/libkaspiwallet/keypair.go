@@ -106,7 +106,12 @@ func p2pkAddress(params *dagconfig.Params, extendedPublicKey string, path string
 		return nil, err
 	}
 
-	publicKey, err := derivedKey.PublicKey()
+	return keyAddress(params, derivedKey, ecdsa)
+}
+
+// keyAddress returns the public key address of the given extended key
+func keyAddress(params *dagconfig.Params, key *bip32.ExtendedKey, ecdsa bool) (util.Address, error) {
+	publicKey, err := key.PublicKey()
 	if err != nil {
 		return nil, err
 	}
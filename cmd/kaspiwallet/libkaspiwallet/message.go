@@ -0,0 +1,39 @@
+package libkaspiwallet
+
+import (
+	"github.com/kaspikr/go-secp256k1"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+)
+
+// SignMessage signs the given message with the key of a single signer wallet with the given derivation path
+// (as returned by AccountDerivationPath), so that util.VerifyMessage verifies it against the address of the
+// key. passphrase is the BIP39 passphrase the seed of the mnemonic is derived with, if there is one.
+func SignMessage(params *dagconfig.Params, mnemonic string, passphrase string, derivationPath string, message string,
+	ecdsa bool) ([]byte, error) {
+
+	derivedKey, err := singleSignerKey(params, mnemonic, passphrase, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := secp256k1.Hash(*util.MessageHash(message).ByteArray())
+	privateKey := derivedKey.PrivateKey()
+	if ecdsa {
+		signature, err := privateKey.ECDSASign(&hash)
+		if err != nil {
+			return nil, err
+		}
+		return signature.Serialize()[:], nil
+	}
+
+	schnorrKeyPair, err := privateKey.ToSchnorr()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := schnorrKeyPair.SchnorrSign(&hash)
+	if err != nil {
+		return nil, err
+	}
+	return signature.Serialize()[:], nil
+}
@@ -0,0 +1,46 @@
+package libkaspiwallet_test
+
+import (
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+)
+
+func TestSignMessage(t *testing.T) {
+	params := &dagconfig.MainnetParams
+	forSchnorrAndECDSA(t, func(t *testing.T, ecdsa bool) {
+		mnemonic, err := libkaspiwallet.CreateMnemonic()
+		if err != nil {
+			t.Fatalf("CreateMnemonic: %+v", err)
+		}
+
+		for _, accountIndex := range []uint32{0, 2} {
+			publicKey, err := libkaspiwallet.AccountPublicKeyFromMnemonic(params, mnemonic, "", false, accountIndex)
+			if err != nil {
+				t.Fatalf("AccountPublicKeyFromMnemonic: %+v", err)
+			}
+			const path = "m/0/3"
+			address, err := libkaspiwallet.Address(params, []string{publicKey}, 1, path, ecdsa)
+			if err != nil {
+				t.Fatalf("Address: %+v", err)
+			}
+
+			const message = "I own this address"
+			signature, err := libkaspiwallet.SignMessage(params, mnemonic, "",
+				libkaspiwallet.AccountDerivationPath(accountIndex, path), message, ecdsa)
+			if err != nil {
+				t.Fatalf("SignMessage: %+v", err)
+			}
+
+			isValid, err := util.VerifyMessage(address, message, signature)
+			if err != nil {
+				t.Fatalf("VerifyMessage: %+v", err)
+			}
+			if !isValid {
+				t.Fatalf("The signature of account %d is not valid", accountIndex)
+			}
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package pskt
+
+import (
+	"bytes"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/bip32"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/pkg/errors"
+)
+
+// FromWalletTransaction converts a partially signed transaction of the format of kaspiwallet to a PSKT.
+// The format of kaspiwallet doesn't carry the DAA scores of the spent UTXOs and whether they're coinbase
+// UTXOs, since they aren't needed in order to sign them, so they're left zero.
+func FromWalletTransaction(walletTransaction *serialization.PartiallySignedTransaction) (
+	*PartiallySignedTransaction, error) {
+
+	tx := walletTransaction.Tx.Clone()
+	inputs := make([]*Input, len(walletTransaction.PartiallySignedInputs))
+	for i, walletInput := range walletTransaction.PartiallySignedInputs {
+		schnorrPublicKeys, ecdsaPublicKeys, err := pairPublicKeys(walletInput.PubKeySignaturePairs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "input #%d", i)
+		}
+
+		// Whether the wallet is an ECDSA wallet isn't recorded in its transactions, so it's
+		// deduced from the script public key of the input
+		scriptPublicKey := walletInput.PrevOutput.ScriptPublicKey
+		var publicKeys [][]byte
+		var redeemScript []byte
+		if len(walletInput.PubKeySignaturePairs) == 1 && walletInput.RedeemScript != nil {
+			publicKeys, err = timeLockPublicKeys(walletInput, schnorrPublicKeys, ecdsaPublicKeys)
+			if err != nil {
+				return nil, errors.Wrapf(err, "input #%d", i)
+			}
+			redeemScript = walletInput.RedeemScript
+		} else if len(walletInput.PubKeySignaturePairs) == 1 {
+			switch txscript.GetScriptClass(scriptPublicKey.Script) {
+			case txscript.PubKeyTy:
+				publicKeys = schnorrPublicKeys
+			case txscript.PubKeyECDSATy:
+				publicKeys = ecdsaPublicKeys
+			default:
+				return nil, errors.Errorf("input #%d spends a UTXO of a single key that isn't paid to a public key", i)
+			}
+		} else {
+			for _, candidatePublicKeys := range [][][]byte{schnorrPublicKeys, ecdsaPublicKeys} {
+				candidateRedeemScript, err := multisigRedeemScript(candidatePublicKeys, walletInput.MinimumSignatures)
+				if err != nil {
+					return nil, err
+				}
+				scriptHashScript, err := txscript.PayToScriptHashScript(candidateRedeemScript)
+				if err != nil {
+					return nil, err
+				}
+				if bytes.Equal(scriptHashScript, scriptPublicKey.Script) {
+					publicKeys = candidatePublicKeys
+					redeemScript = candidateRedeemScript
+					break
+				}
+			}
+			if redeemScript == nil {
+				return nil, errors.Errorf("the script public key of input #%d doesn't match the keys of the input", i)
+			}
+		}
+
+		input := &Input{
+			UTXOEntry:         utxo.NewUTXOEntry(walletInput.PrevOutput.Value, scriptPublicKey, false, 0),
+			RedeemScript:      redeemScript,
+			MinimumSignatures: walletInput.MinimumSignatures,
+			SighashType:       consensushashing.SigHashAll,
+			BIP32Derivations:  make([]*BIP32Derivation, len(walletInput.PubKeySignaturePairs)),
+		}
+		for j, pair := range walletInput.PubKeySignaturePairs {
+			input.BIP32Derivations[j] = &BIP32Derivation{
+				PublicKey:         publicKeys[j],
+				DerivationPath:    walletInput.DerivationPath,
+				ExtendedPublicKey: pair.ExtendedPublicKey,
+			}
+			if pair.Signature != nil {
+				input.PartialSignatures = append(input.PartialSignatures, &PartialSignature{
+					PublicKey: publicKeys[j],
+					Signature: pair.Signature,
+				})
+			}
+		}
+		inputs[i] = input
+
+		// The signature operation count is committed to by the signature hash, so it's
+		// set as kaspiwallet sets it when it signs the input
+		tx.Inputs[i].SigOpCount = byte(len(walletInput.PubKeySignaturePairs))
+		tx.Inputs[i].SignatureScript = nil
+		tx.Inputs[i].UTXOEntry = nil
+	}
+
+	p := &PartiallySignedTransaction{
+		Tx:     tx,
+		Inputs: inputs,
+	}
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ToWalletTransaction converts a PSKT to a partially signed transaction of the format of kaspiwallet,
+// so that kaspiwallet can sign it. This requires the extended public keys of all the keys of the inputs.
+func ToWalletTransaction(p *PartiallySignedTransaction) (*serialization.PartiallySignedTransaction, error) {
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := p.Tx.Clone()
+	walletInputs := make([]*serialization.PartiallySignedInput, len(p.Inputs))
+	for i, input := range p.Inputs {
+		if input.FinalSignatureScript != nil {
+			return nil, errors.Errorf("input #%d is already finalized", i)
+		}
+		if input.SighashType != consensushashing.SigHashAll {
+			return nil, errors.Errorf("input #%d is expected to be signed with signature hash type %d, "+
+				"while kaspiwallet signs only with SigHashAll", i, input.SighashType)
+		}
+		if len(input.BIP32Derivations) == 0 {
+			return nil, errors.Errorf("input #%d has no keys", i)
+		}
+
+		derivationPath := input.BIP32Derivations[0].DerivationPath
+		pairs := make([]*serialization.PubKeySignaturePair, len(input.BIP32Derivations))
+		for j, derivation := range input.BIP32Derivations {
+			if derivation.ExtendedPublicKey == "" {
+				return nil, errors.Errorf("the extended public key of key #%d of input #%d is missing", j, i)
+			}
+			if derivation.DerivationPath != derivationPath {
+				return nil, errors.Errorf("the keys of input #%d are derived with different paths", i)
+			}
+			pairs[j] = &serialization.PubKeySignaturePair{ExtendedPublicKey: derivation.ExtendedPublicKey}
+			if signature, ok := input.partialSignature(derivation.PublicKey); ok {
+				pairs[j].Signature = signature.Signature
+			}
+		}
+
+		walletInputs[i] = &serialization.PartiallySignedInput{
+			PrevOutput: &externalapi.DomainTransactionOutput{
+				Value:           input.UTXOEntry.Amount(),
+				ScriptPublicKey: input.UTXOEntry.ScriptPublicKey(),
+			},
+			MinimumSignatures:    input.MinimumSignatures,
+			PubKeySignaturePairs: pairs,
+			DerivationPath:       derivationPath,
+		}
+		if len(pairs) == 1 {
+			// A redeem script of a single key input, which kaspiwallet creates for time-locked
+			// addresses, has to be revealed by its signature script
+			walletInputs[i].RedeemScript = input.RedeemScript
+		}
+	}
+
+	return &serialization.PartiallySignedTransaction{
+		Tx:                    tx,
+		PartiallySignedInputs: walletInputs,
+	}, nil
+}
+
+// timeLockPublicKeys returns the public key of a single key input of a time-locked address, out of
+// the Schnorr and ECDSA candidates, after checking that the input spends the address
+func timeLockPublicKeys(walletInput *serialization.PartiallySignedInput, schnorrPublicKeys, ecdsaPublicKeys [][]byte) (
+	[][]byte, error) {
+
+	pushes, err := txscript.ExtractTimeLockDataPushes(walletInput.RedeemScript)
+	if err != nil {
+		return nil, err
+	}
+	if pushes == nil {
+		return nil, errors.New("the redeem script of the input isn't a time lock script")
+	}
+	publicKeys := schnorrPublicKeys
+	if pushes.ECDSA {
+		publicKeys = ecdsaPublicKeys
+	}
+	if !bytes.Equal(publicKeys[0], pushes.PublicKey) {
+		return nil, errors.New("the redeem script of the input is locked with another key than the key of the input")
+	}
+	scriptHashScript, err := txscript.PayToScriptHashScript(walletInput.RedeemScript)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(scriptHashScript, walletInput.PrevOutput.ScriptPublicKey.Script) {
+		return nil, errors.New("the script public key of the input doesn't match its redeem script")
+	}
+	return publicKeys, nil
+}
+
+// pairPublicKeys returns the serialized Schnorr and ECDSA public keys of the given pairs
+func pairPublicKeys(pairs []*serialization.PubKeySignaturePair) (schnorrPublicKeys, ecdsaPublicKeys [][]byte, err error) {
+	schnorrPublicKeys = make([][]byte, len(pairs))
+	ecdsaPublicKeys = make([][]byte, len(pairs))
+	for i, pair := range pairs {
+		extendedKey, err := bip32.DeserializeExtendedKey(pair.ExtendedPublicKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		publicKey, err := extendedKey.PublicKey()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		serializedECDSAPublicKey, err := publicKey.Serialize()
+		if err != nil {
+			return nil, nil, err
+		}
+		ecdsaPublicKeys[i] = serializedECDSAPublicKey[:]
+
+		schnorrPublicKey, err := publicKey.ToSchnorr()
+		if err != nil {
+			return nil, nil, err
+		}
+		serializedSchnorrPublicKey, err := schnorrPublicKey.Serialize()
+		if err != nil {
+			return nil, nil, err
+		}
+		schnorrPublicKeys[i] = serializedSchnorrPublicKey[:]
+	}
+	return schnorrPublicKeys, ecdsaPublicKeys, nil
+}
+
+func multisigRedeemScript(publicKeys [][]byte, minimumSignatures uint32) ([]byte, error) {
+	isECDSA := len(publicKeys) > 0 && len(publicKeys[0]) == 33
+
+	scriptBuilder := txscript.NewScriptBuilder()
+	scriptBuilder.AddInt64(int64(minimumSignatures))
+	for _, publicKey := range publicKeys {
+		scriptBuilder.AddData(publicKey)
+	}
+	scriptBuilder.AddInt64(int64(len(publicKeys)))
+	if isECDSA {
+		scriptBuilder.AddOp(txscript.OpCheckMultiSigECDSA)
+	} else {
+		scriptBuilder.AddOp(txscript.OpCheckMultiSig)
+	}
+	return scriptBuilder.Script()
+}
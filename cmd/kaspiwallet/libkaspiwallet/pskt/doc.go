@@ -0,0 +1,39 @@
+/*
+Package pskt implements the partially signed Kaspi transaction (PSKT) format: a container that carries
+a transaction along with everything that a signer, which might not be a kaspiwallet, needs in order to
+sign its inputs, and that the parties of a multisig wallet pass between them until the transaction is
+fully signed.
+
+Every input of the transaction is accompanied by:
+  - The UTXO entry it spends, which is needed in order to compute the signature hash
+  - The redeem script of the UTXO, if it's paid to a script hash
+  - The number of signatures required to spend the UTXO
+  - The signature hash type the input is expected to be signed with
+  - The public keys that can sign the input, in the order of the redeem script, each with the BIP32
+    path it is derived with from the extended public key of its signer, and optionally that extended
+    public key
+  - The signatures that were collected for it so far, each with the public key it was signed with
+  - Its signature script, once it was finalized
+
+The signature scripts of the transaction are empty until it's finalized, while the signature operation
+counts of its inputs are already set, since they are committed to by the signature hash.
+
+# Encodings
+
+The binary encoding is the magic bytes "pskt" followed by a 0xff separator, followed by the protobuf
+encoding of the PartiallySignedTransaction message of protopskt/pskt.proto. The message holds the version
+of the format, which is currently 1, and readers must reject versions they don't know.
+
+The text encoding is the standard base64 encoding (RFC 4648, with padding) of the binary encoding.
+
+The JSON encoding holds the same fields as the protobuf message, with byte fields encoded in hex,
+transaction IDs in their usual byte order and amounts in sompi. It is meant for inspection and for
+tools that can't decode protobuf, and is accepted by Parse as well.
+
+# Flow
+
+A PSKT is created by the wallet that selected the UTXOs of the transaction. Every signer adds its
+signatures to a copy of it, the copies are merged with Combine, and once enough signatures were
+collected, Finalize builds the signature scripts and returns the transaction, ready to be broadcast.
+*/
+package pskt
@@ -0,0 +1,135 @@
+package pskt
+
+import (
+	"bytes"
+
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/pkg/errors"
+)
+
+// Combine merges the signatures and metadata of several PSKTs of the same transaction into one PSKT.
+// The metadata of an input is taken from the first PSKT that has it.
+func Combine(pskts ...*PartiallySignedTransaction) (*PartiallySignedTransaction, error) {
+	if len(pskts) == 0 {
+		return nil, errors.New("no PSKTs to combine")
+	}
+
+	combined := pskts[0].Clone()
+	transactionID := combined.TransactionID()
+	for _, p := range pskts[1:] {
+		if !p.TransactionID().Equal(transactionID) {
+			return nil, errors.Errorf("cannot combine PSKTs of different transactions: %s and %s",
+				transactionID, p.TransactionID())
+		}
+
+		for i, input := range p.Inputs {
+			combinedInput := combined.Inputs[i]
+			for _, derivation := range input.BIP32Derivations {
+				if !combinedInput.hasDerivation(derivation.PublicKey) {
+					combinedInput.BIP32Derivations = append(combinedInput.BIP32Derivations, derivation)
+				}
+			}
+			for _, signature := range input.PartialSignatures {
+				if _, ok := combinedInput.partialSignature(signature.PublicKey); !ok {
+					combinedInput.PartialSignatures = append(combinedInput.PartialSignatures, signature)
+				}
+			}
+			if combinedInput.FinalSignatureScript == nil {
+				combinedInput.FinalSignatureScript = input.FinalSignatureScript
+			}
+		}
+	}
+	return combined.Clone(), nil
+}
+
+func (input *Input) hasDerivation(publicKey []byte) bool {
+	for _, derivation := range input.BIP32Derivations {
+		if bytes.Equal(derivation.PublicKey, publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finalize builds the signature scripts of the inputs of the PSKT that weren't finalized yet from their
+// signatures, and returns the signed transaction, after verifying that all of its inputs are validly signed.
+func Finalize(p *PartiallySignedTransaction) (*externalapi.DomainTransaction, error) {
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := p.Tx.Clone()
+	for i, input := range p.Inputs {
+		signatureScript := input.FinalSignatureScript
+		if signatureScript == nil {
+			signatureScript, err = input.signatureScript()
+			if err != nil {
+				return nil, errors.Wrapf(err, "input #%d", i)
+			}
+		}
+		tx.Inputs[i].SignatureScript = signatureScript
+		tx.Inputs[i].UTXOEntry = input.UTXOEntry
+	}
+
+	sighashReusedValues := &consensushashing.SighashReusedValues{}
+	for i, input := range p.Inputs {
+		engine, err := txscript.NewEngine(input.UTXOEntry.ScriptPublicKey(), tx, i, txscript.ScriptNoFlags,
+			txscript.NewSigCache(0), txscript.NewSigCacheECDSA(0), sighashReusedValues)
+		if err != nil {
+			return nil, errors.Wrapf(err, "input #%d", i)
+		}
+		err = engine.Execute()
+		if err != nil {
+			return nil, errors.Wrapf(err, "input #%d isn't validly signed", i)
+		}
+	}
+
+	return tx, nil
+}
+
+// signatureScript builds the signature script of the input from its signatures
+func (input *Input) signatureScript() ([]byte, error) {
+	scriptBuilder := txscript.NewScriptBuilder()
+	if len(input.RedeemScript) == 0 {
+		pushedData, err := txscript.PushedData(input.UTXOEntry.ScriptPublicKey().Script)
+		if err != nil {
+			return nil, err
+		}
+		if len(pushedData) != 1 {
+			return nil, errors.New("the UTXO has no redeem script, but isn't paid to a public key")
+		}
+		signature, ok := input.partialSignature(pushedData[0])
+		if !ok {
+			return nil, errors.New("missing signature")
+		}
+		return scriptBuilder.AddData(signature.Signature).Script()
+	}
+
+	// The signatures are checked in the order of the keys of the redeem script, and exactly
+	// the required number of them has to be given
+	pushedData, err := txscript.PushedData(input.RedeemScript)
+	if err != nil {
+		return nil, err
+	}
+	signatureCount := uint32(0)
+	for _, data := range pushedData {
+		if signatureCount == input.MinimumSignatures {
+			break
+		}
+		signature, ok := input.partialSignature(data)
+		if !ok {
+			continue
+		}
+		scriptBuilder.AddData(signature.Signature)
+		signatureCount++
+	}
+	if signatureCount < input.MinimumSignatures {
+		return nil, errors.Errorf("missing %d signatures", input.MinimumSignatures-signatureCount)
+	}
+
+	scriptBuilder.AddData(input.RedeemScript)
+	return scriptBuilder.Script()
+}
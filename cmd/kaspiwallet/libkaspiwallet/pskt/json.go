@@ -0,0 +1,305 @@
+package pskt
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/pkg/errors"
+)
+
+type jsonPSKT struct {
+	Version uint32          `json:"version"`
+	Tx      jsonTransaction `json:"tx"`
+	Inputs  []*jsonInput    `json:"inputs"`
+}
+
+type jsonTransaction struct {
+	Version      uint16                   `json:"version"`
+	Inputs       []*jsonTransactionInput  `json:"inputs"`
+	Outputs      []*jsonTransactionOutput `json:"outputs"`
+	LockTime     uint64                   `json:"lockTime"`
+	SubnetworkID string                   `json:"subnetworkId"`
+	Gas          uint64                   `json:"gas"`
+	Payload      string                   `json:"payload"`
+}
+
+type jsonTransactionInput struct {
+	TransactionID string `json:"transactionId"`
+	Index         uint32 `json:"index"`
+	Sequence      uint64 `json:"sequence"`
+	SigOpCount    uint8  `json:"sigOpCount"`
+}
+
+type jsonTransactionOutput struct {
+	Value           uint64              `json:"value"`
+	ScriptPublicKey jsonScriptPublicKey `json:"scriptPublicKey"`
+}
+
+type jsonScriptPublicKey struct {
+	Script  string `json:"script"`
+	Version uint16 `json:"version"`
+}
+
+type jsonInput struct {
+	UTXOEntry            jsonUTXOEntry           `json:"utxoEntry"`
+	RedeemScript         string                  `json:"redeemScript,omitempty"`
+	MinimumSignatures    uint32                  `json:"minimumSignatures"`
+	SighashType          uint32                  `json:"sighashType"`
+	BIP32Derivations     []*jsonBIP32Derivation  `json:"bip32Derivations"`
+	PartialSignatures    []*jsonPartialSignature `json:"partialSignatures"`
+	FinalSignatureScript string                  `json:"finalSignatureScript,omitempty"`
+}
+
+type jsonUTXOEntry struct {
+	Amount          uint64              `json:"amount"`
+	ScriptPublicKey jsonScriptPublicKey `json:"scriptPublicKey"`
+	BlockDAAScore   uint64              `json:"blockDaaScore"`
+	IsCoinbase      bool                `json:"isCoinbase"`
+}
+
+type jsonBIP32Derivation struct {
+	PublicKey         string `json:"publicKey"`
+	DerivationPath    string `json:"derivationPath"`
+	ExtendedPublicKey string `json:"extendedPublicKey,omitempty"`
+}
+
+type jsonPartialSignature struct {
+	PublicKey string `json:"publicKey"`
+	Signature string `json:"signature"`
+}
+
+// MarshalJSON returns the JSON encoding of the PSKT
+func (p *PartiallySignedTransaction) MarshalJSON() ([]byte, error) {
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := jsonTransaction{
+		Version:      p.Tx.Version,
+		Inputs:       make([]*jsonTransactionInput, len(p.Tx.Inputs)),
+		Outputs:      make([]*jsonTransactionOutput, len(p.Tx.Outputs)),
+		LockTime:     p.Tx.LockTime,
+		SubnetworkID: hex.EncodeToString(p.Tx.SubnetworkID[:]),
+		Gas:          p.Tx.Gas,
+		Payload:      hex.EncodeToString(p.Tx.Payload),
+	}
+	for i, input := range p.Tx.Inputs {
+		tx.Inputs[i] = &jsonTransactionInput{
+			TransactionID: input.PreviousOutpoint.TransactionID.String(),
+			Index:         input.PreviousOutpoint.Index,
+			Sequence:      input.Sequence,
+			SigOpCount:    input.SigOpCount,
+		}
+	}
+	for i, output := range p.Tx.Outputs {
+		tx.Outputs[i] = &jsonTransactionOutput{
+			Value:           output.Value,
+			ScriptPublicKey: scriptPublicKeyToJSON(output.ScriptPublicKey),
+		}
+	}
+
+	inputs := make([]*jsonInput, len(p.Inputs))
+	for i, input := range p.Inputs {
+		inputs[i] = &jsonInput{
+			UTXOEntry: jsonUTXOEntry{
+				Amount:          input.UTXOEntry.Amount(),
+				ScriptPublicKey: scriptPublicKeyToJSON(input.UTXOEntry.ScriptPublicKey()),
+				BlockDAAScore:   input.UTXOEntry.BlockDAAScore(),
+				IsCoinbase:      input.UTXOEntry.IsCoinbase(),
+			},
+			RedeemScript:         hex.EncodeToString(input.RedeemScript),
+			MinimumSignatures:    input.MinimumSignatures,
+			SighashType:          uint32(input.SighashType),
+			BIP32Derivations:     make([]*jsonBIP32Derivation, len(input.BIP32Derivations)),
+			PartialSignatures:    make([]*jsonPartialSignature, len(input.PartialSignatures)),
+			FinalSignatureScript: hex.EncodeToString(input.FinalSignatureScript),
+		}
+		for j, derivation := range input.BIP32Derivations {
+			inputs[i].BIP32Derivations[j] = &jsonBIP32Derivation{
+				PublicKey:         hex.EncodeToString(derivation.PublicKey),
+				DerivationPath:    derivation.DerivationPath,
+				ExtendedPublicKey: derivation.ExtendedPublicKey,
+			}
+		}
+		for j, signature := range input.PartialSignatures {
+			inputs[i].PartialSignatures[j] = &jsonPartialSignature{
+				PublicKey: hex.EncodeToString(signature.PublicKey),
+				Signature: hex.EncodeToString(signature.Signature),
+			}
+		}
+	}
+
+	return json.Marshal(&jsonPSKT{
+		Version: Version,
+		Tx:      tx,
+		Inputs:  inputs,
+	})
+}
+
+// UnmarshalJSON decodes a PSKT from its JSON encoding
+func (p *PartiallySignedTransaction) UnmarshalJSON(data []byte) error {
+	decoded := &jsonPSKT{}
+	err := json.Unmarshal(data, decoded)
+	if err != nil {
+		return err
+	}
+	if decoded.Version != Version {
+		return errors.Errorf("the PSKT is of version %d, while only version %d is supported", decoded.Version, Version)
+	}
+
+	subnetworkIDBytes, err := decodeHex(decoded.Tx.SubnetworkID, "subnetwork ID")
+	if err != nil {
+		return err
+	}
+	subnetworkID, err := subnetworks.FromBytes(subnetworkIDBytes)
+	if err != nil {
+		return err
+	}
+	payload, err := decodeHex(decoded.Tx.Payload, "payload")
+	if err != nil {
+		return err
+	}
+	tx := &externalapi.DomainTransaction{
+		Version:      decoded.Tx.Version,
+		Inputs:       make([]*externalapi.DomainTransactionInput, len(decoded.Tx.Inputs)),
+		Outputs:      make([]*externalapi.DomainTransactionOutput, len(decoded.Tx.Outputs)),
+		LockTime:     decoded.Tx.LockTime,
+		SubnetworkID: *subnetworkID,
+		Gas:          decoded.Tx.Gas,
+		Payload:      payload,
+	}
+	for i, input := range decoded.Tx.Inputs {
+		if input == nil {
+			return errors.Errorf("transaction input #%d is missing", i)
+		}
+		transactionID, err := externalapi.NewDomainTransactionIDFromString(input.TransactionID)
+		if err != nil {
+			return err
+		}
+		tx.Inputs[i] = &externalapi.DomainTransactionInput{
+			PreviousOutpoint: externalapi.DomainOutpoint{TransactionID: *transactionID, Index: input.Index},
+			Sequence:         input.Sequence,
+			SigOpCount:       input.SigOpCount,
+		}
+	}
+	for i, output := range decoded.Tx.Outputs {
+		if output == nil {
+			return errors.Errorf("transaction output #%d is missing", i)
+		}
+		scriptPublicKey, err := scriptPublicKeyFromJSON(output.ScriptPublicKey)
+		if err != nil {
+			return err
+		}
+		tx.Outputs[i] = &externalapi.DomainTransactionOutput{Value: output.Value, ScriptPublicKey: scriptPublicKey}
+	}
+
+	inputs := make([]*Input, len(decoded.Inputs))
+	for i, decodedInput := range decoded.Inputs {
+		if decodedInput == nil {
+			return errors.Errorf("input #%d is missing", i)
+		}
+		inputs[i], err = inputFromJSON(decodedInput)
+		if err != nil {
+			return errors.Wrapf(err, "input #%d", i)
+		}
+	}
+
+	decodedPSKT := &PartiallySignedTransaction{Tx: tx, Inputs: inputs}
+	err = decodedPSKT.validate()
+	if err != nil {
+		return err
+	}
+	*p = *decodedPSKT
+	return nil
+}
+
+func inputFromJSON(decodedInput *jsonInput) (*Input, error) {
+	if decodedInput.SighashType > math.MaxUint8 {
+		return nil, errors.Errorf("the signature hash type %d is too big to be a uint8", decodedInput.SighashType)
+	}
+	scriptPublicKey, err := scriptPublicKeyFromJSON(decodedInput.UTXOEntry.ScriptPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	redeemScript, err := decodeHex(decodedInput.RedeemScript, "redeem script")
+	if err != nil {
+		return nil, err
+	}
+	finalSignatureScript, err := decodeHex(decodedInput.FinalSignatureScript, "final signature script")
+	if err != nil {
+		return nil, err
+	}
+
+	input := &Input{
+		UTXOEntry: utxo.NewUTXOEntry(decodedInput.UTXOEntry.Amount, scriptPublicKey,
+			decodedInput.UTXOEntry.IsCoinbase, decodedInput.UTXOEntry.BlockDAAScore),
+		RedeemScript:         redeemScript,
+		MinimumSignatures:    decodedInput.MinimumSignatures,
+		SighashType:          consensushashing.SigHashType(decodedInput.SighashType),
+		BIP32Derivations:     make([]*BIP32Derivation, len(decodedInput.BIP32Derivations)),
+		PartialSignatures:    make([]*PartialSignature, len(decodedInput.PartialSignatures)),
+		FinalSignatureScript: finalSignatureScript,
+	}
+	for i, derivation := range decodedInput.BIP32Derivations {
+		if derivation == nil {
+			return nil, errors.Errorf("BIP32 derivation #%d is missing", i)
+		}
+		publicKey, err := decodeHex(derivation.PublicKey, "public key")
+		if err != nil {
+			return nil, err
+		}
+		input.BIP32Derivations[i] = &BIP32Derivation{
+			PublicKey:         publicKey,
+			DerivationPath:    derivation.DerivationPath,
+			ExtendedPublicKey: derivation.ExtendedPublicKey,
+		}
+	}
+	for i, signature := range decodedInput.PartialSignatures {
+		if signature == nil {
+			return nil, errors.Errorf("partial signature #%d is missing", i)
+		}
+		publicKey, err := decodeHex(signature.PublicKey, "public key")
+		if err != nil {
+			return nil, err
+		}
+		signatureBytes, err := decodeHex(signature.Signature, "signature")
+		if err != nil {
+			return nil, err
+		}
+		input.PartialSignatures[i] = &PartialSignature{PublicKey: publicKey, Signature: signatureBytes}
+	}
+	return input, nil
+}
+
+func scriptPublicKeyToJSON(scriptPublicKey *externalapi.ScriptPublicKey) jsonScriptPublicKey {
+	return jsonScriptPublicKey{
+		Script:  hex.EncodeToString(scriptPublicKey.Script),
+		Version: scriptPublicKey.Version,
+	}
+}
+
+func scriptPublicKeyFromJSON(decoded jsonScriptPublicKey) (*externalapi.ScriptPublicKey, error) {
+	script, err := decodeHex(decoded.Script, "script public key")
+	if err != nil {
+		return nil, err
+	}
+	return &externalapi.ScriptPublicKey{Script: script, Version: decoded.Version}, nil
+}
+
+// decodeHex decodes a hex field of the JSON encoding, which is nil if it's empty
+func decodeHex(field string, name string) ([]byte, error) {
+	if field == "" {
+		return nil, nil
+	}
+	decoded, err := hex.DecodeString(field)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", name)
+	}
+	return decoded, nil
+}
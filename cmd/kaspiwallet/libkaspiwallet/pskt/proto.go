@@ -0,0 +1,269 @@
+package pskt
+
+import (
+	"math"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/pskt/protopskt"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/pkg/errors"
+)
+
+// validate returns an error if the PSKT is inconsistent
+func (p *PartiallySignedTransaction) validate() error {
+	if p.Tx == nil {
+		return errors.New("the PSKT has no transaction")
+	}
+	if len(p.Inputs) != len(p.Tx.Inputs) {
+		return errors.Errorf("the PSKT has metadata for %d inputs, while its transaction has %d inputs",
+			len(p.Inputs), len(p.Tx.Inputs))
+	}
+	for i, input := range p.Inputs {
+		if input.UTXOEntry == nil {
+			return errors.Errorf("input #%d has no UTXO entry", i)
+		}
+		if !input.SighashType.IsStandardSigHashType() {
+			return errors.Errorf("input #%d has an invalid signature hash type %d", i, input.SighashType)
+		}
+	}
+	return nil
+}
+
+func fromProto(protoPSKT *protopskt.PartiallySignedTransaction) (*PartiallySignedTransaction, error) {
+	if protoPSKT.Version != Version {
+		return nil, errors.Errorf("the PSKT is of version %d, while only version %d is supported",
+			protoPSKT.Version, Version)
+	}
+	if protoPSKT.Tx == nil {
+		return nil, errors.New("the PSKT has no transaction")
+	}
+
+	tx, err := transactionFromProto(protoPSKT.Tx)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]*Input, len(protoPSKT.Inputs))
+	for i, protoInput := range protoPSKT.Inputs {
+		inputs[i], err = inputFromProto(protoInput)
+		if err != nil {
+			return nil, errors.Wrapf(err, "input #%d", i)
+		}
+	}
+
+	p := &PartiallySignedTransaction{
+		Tx:     tx,
+		Inputs: inputs,
+	}
+	err = p.validate()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func toProto(p *PartiallySignedTransaction) (*protopskt.PartiallySignedTransaction, error) {
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	protoInputs := make([]*protopskt.Input, len(p.Inputs))
+	for i, input := range p.Inputs {
+		protoInputs[i] = inputToProto(input)
+	}
+
+	return &protopskt.PartiallySignedTransaction{
+		Version: Version,
+		Tx:      transactionToProto(p.Tx),
+		Inputs:  protoInputs,
+	}, nil
+}
+
+func inputFromProto(protoInput *protopskt.Input) (*Input, error) {
+	if protoInput.UtxoEntry == nil {
+		return nil, errors.New("missing UTXO entry")
+	}
+	if protoInput.SighashType > math.MaxUint8 {
+		return nil, errors.Errorf("the signature hash type %d is too big to be a uint8", protoInput.SighashType)
+	}
+	scriptPublicKey, err := scriptPublicKeyFromProto(protoInput.UtxoEntry.ScriptPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	derivations := make([]*BIP32Derivation, len(protoInput.Bip32Derivations))
+	for i, protoDerivation := range protoInput.Bip32Derivations {
+		derivations[i] = &BIP32Derivation{
+			PublicKey:         protoDerivation.PublicKey,
+			DerivationPath:    protoDerivation.DerivationPath,
+			ExtendedPublicKey: protoDerivation.ExtendedPublicKey,
+		}
+	}
+	signatures := make([]*PartialSignature, len(protoInput.PartialSignatures))
+	for i, protoSignature := range protoInput.PartialSignatures {
+		signatures[i] = &PartialSignature{
+			PublicKey: protoSignature.PublicKey,
+			Signature: protoSignature.Signature,
+		}
+	}
+
+	var finalSignatureScript []byte
+	if len(protoInput.FinalSignatureScript) > 0 {
+		finalSignatureScript = protoInput.FinalSignatureScript
+	}
+
+	return &Input{
+		UTXOEntry: utxo.NewUTXOEntry(protoInput.UtxoEntry.Amount, scriptPublicKey,
+			protoInput.UtxoEntry.IsCoinbase, protoInput.UtxoEntry.BlockDaaScore),
+		RedeemScript:         protoInput.RedeemScript,
+		MinimumSignatures:    protoInput.MinimumSignatures,
+		SighashType:          consensushashing.SigHashType(protoInput.SighashType),
+		BIP32Derivations:     derivations,
+		PartialSignatures:    signatures,
+		FinalSignatureScript: finalSignatureScript,
+	}, nil
+}
+
+func inputToProto(input *Input) *protopskt.Input {
+	protoDerivations := make([]*protopskt.Bip32Derivation, len(input.BIP32Derivations))
+	for i, derivation := range input.BIP32Derivations {
+		protoDerivations[i] = &protopskt.Bip32Derivation{
+			PublicKey:         derivation.PublicKey,
+			DerivationPath:    derivation.DerivationPath,
+			ExtendedPublicKey: derivation.ExtendedPublicKey,
+		}
+	}
+	protoSignatures := make([]*protopskt.PartialSignature, len(input.PartialSignatures))
+	for i, signature := range input.PartialSignatures {
+		protoSignatures[i] = &protopskt.PartialSignature{
+			PublicKey: signature.PublicKey,
+			Signature: signature.Signature,
+		}
+	}
+
+	return &protopskt.Input{
+		UtxoEntry: &protopskt.UtxoEntry{
+			Amount:          input.UTXOEntry.Amount(),
+			ScriptPublicKey: scriptPublicKeyToProto(input.UTXOEntry.ScriptPublicKey()),
+			BlockDaaScore:   input.UTXOEntry.BlockDAAScore(),
+			IsCoinbase:      input.UTXOEntry.IsCoinbase(),
+		},
+		RedeemScript:         input.RedeemScript,
+		MinimumSignatures:    input.MinimumSignatures,
+		SighashType:          uint32(input.SighashType),
+		Bip32Derivations:     protoDerivations,
+		PartialSignatures:    protoSignatures,
+		FinalSignatureScript: input.FinalSignatureScript,
+	}
+}
+
+func transactionFromProto(protoTransaction *protopskt.Transaction) (*externalapi.DomainTransaction, error) {
+	if protoTransaction.Version > math.MaxUint16 {
+		return nil, errors.Errorf("the transaction version %d is too big to be a uint16", protoTransaction.Version)
+	}
+
+	inputs := make([]*externalapi.DomainTransactionInput, len(protoTransaction.Inputs))
+	for i, protoInput := range protoTransaction.Inputs {
+		if protoInput.SigOpCount > math.MaxUint8 {
+			return nil, errors.Errorf("the signature operation count %d is too big to be a uint8", protoInput.SigOpCount)
+		}
+		if protoInput.PreviousOutpoint == nil {
+			return nil, errors.Errorf("transaction input #%d has no previous outpoint", i)
+		}
+		transactionID, err := externalapi.NewDomainTransactionIDFromByteSlice(protoInput.PreviousOutpoint.TransactionId)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = &externalapi.DomainTransactionInput{
+			PreviousOutpoint: externalapi.DomainOutpoint{
+				TransactionID: *transactionID,
+				Index:         protoInput.PreviousOutpoint.Index,
+			},
+			Sequence:   protoInput.Sequence,
+			SigOpCount: byte(protoInput.SigOpCount),
+		}
+	}
+
+	outputs := make([]*externalapi.DomainTransactionOutput, len(protoTransaction.Outputs))
+	for i, protoOutput := range protoTransaction.Outputs {
+		scriptPublicKey, err := scriptPublicKeyFromProto(protoOutput.ScriptPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = &externalapi.DomainTransactionOutput{
+			Value:           protoOutput.Value,
+			ScriptPublicKey: scriptPublicKey,
+		}
+	}
+
+	subnetworkID, err := subnetworks.FromBytes(protoTransaction.SubnetworkId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &externalapi.DomainTransaction{
+		Version:      uint16(protoTransaction.Version),
+		Inputs:       inputs,
+		Outputs:      outputs,
+		LockTime:     protoTransaction.LockTime,
+		SubnetworkID: *subnetworkID,
+		Gas:          protoTransaction.Gas,
+		Payload:      protoTransaction.Payload,
+	}, nil
+}
+
+func transactionToProto(tx *externalapi.DomainTransaction) *protopskt.Transaction {
+	protoInputs := make([]*protopskt.TransactionInput, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		protoInputs[i] = &protopskt.TransactionInput{
+			PreviousOutpoint: &protopskt.Outpoint{
+				TransactionId: input.PreviousOutpoint.TransactionID.ByteSlice(),
+				Index:         input.PreviousOutpoint.Index,
+			},
+			Sequence:   input.Sequence,
+			SigOpCount: uint32(input.SigOpCount),
+		}
+	}
+
+	protoOutputs := make([]*protopskt.TransactionOutput, len(tx.Outputs))
+	for i, output := range tx.Outputs {
+		protoOutputs[i] = &protopskt.TransactionOutput{
+			Value:           output.Value,
+			ScriptPublicKey: scriptPublicKeyToProto(output.ScriptPublicKey),
+		}
+	}
+
+	return &protopskt.Transaction{
+		Version:      uint32(tx.Version),
+		Inputs:       protoInputs,
+		Outputs:      protoOutputs,
+		LockTime:     tx.LockTime,
+		SubnetworkId: tx.SubnetworkID[:],
+		Gas:          tx.Gas,
+		Payload:      tx.Payload,
+	}
+}
+
+func scriptPublicKeyFromProto(protoScriptPublicKey *protopskt.ScriptPublicKey) (*externalapi.ScriptPublicKey, error) {
+	if protoScriptPublicKey == nil {
+		return nil, errors.New("missing script public key")
+	}
+	if protoScriptPublicKey.Version > math.MaxUint16 {
+		return nil, errors.Errorf("the script public key version %d is too big to be a uint16",
+			protoScriptPublicKey.Version)
+	}
+	return &externalapi.ScriptPublicKey{
+		Script:  protoScriptPublicKey.Script,
+		Version: uint16(protoScriptPublicKey.Version),
+	}, nil
+}
+
+func scriptPublicKeyToProto(scriptPublicKey *externalapi.ScriptPublicKey) *protopskt.ScriptPublicKey {
+	return &protopskt.ScriptPublicKey{
+		Script:  scriptPublicKey.Script,
+		Version: uint32(scriptPublicKey.Version),
+	}
+}
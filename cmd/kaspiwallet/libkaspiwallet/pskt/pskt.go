@@ -0,0 +1,179 @@
+package pskt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/pskt/protopskt"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// Version is the version of the PSKT format this package reads and writes
+const Version = 1
+
+// magic is the prefix of the binary encoding of a PSKT
+var magic = []byte{'p', 's', 'k', 't', 0xff}
+
+// PartiallySignedTransaction is a transaction along with the metadata its signers need in order to
+// sign it, and the signatures that were collected for it so far
+type PartiallySignedTransaction struct {
+	Tx     *externalapi.DomainTransaction
+	Inputs []*Input
+}
+
+// Input is the metadata of an input of a PartiallySignedTransaction
+type Input struct {
+	UTXOEntry            externalapi.UTXOEntry
+	RedeemScript         []byte
+	MinimumSignatures    uint32
+	SighashType          consensushashing.SigHashType
+	BIP32Derivations     []*BIP32Derivation
+	PartialSignatures    []*PartialSignature
+	FinalSignatureScript []byte
+}
+
+// BIP32Derivation is a public key that can sign an input, along with how it's derived
+type BIP32Derivation struct {
+	PublicKey         []byte
+	DerivationPath    string
+	ExtendedPublicKey string
+}
+
+// PartialSignature is a signature of an input, along with the public key it was signed with
+type PartialSignature struct {
+	PublicKey []byte
+	Signature []byte
+}
+
+// TransactionID returns the ID of the transaction, which doesn't depend on its signatures
+func (p *PartiallySignedTransaction) TransactionID() *externalapi.DomainTransactionID {
+	return consensushashing.TransactionID(p.Tx)
+}
+
+// IsFinalizable returns whether all the inputs are finalized or have enough signatures to be finalized
+func (p *PartiallySignedTransaction) IsFinalizable() bool {
+	for _, input := range p.Inputs {
+		if input.FinalSignatureScript == nil && uint32(len(input.PartialSignatures)) < input.MinimumSignatures {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone creates a deep-clone of this PartiallySignedTransaction
+func (p *PartiallySignedTransaction) Clone() *PartiallySignedTransaction {
+	clone := &PartiallySignedTransaction{
+		Tx:     p.Tx.Clone(),
+		Inputs: make([]*Input, len(p.Inputs)),
+	}
+	for i, input := range p.Inputs {
+		clone.Inputs[i] = input.Clone()
+	}
+	return clone
+}
+
+// Clone creates a deep-clone of this Input
+func (input *Input) Clone() *Input {
+	clone := &Input{
+		UTXOEntry:            input.UTXOEntry,
+		RedeemScript:         cloneBytes(input.RedeemScript),
+		MinimumSignatures:    input.MinimumSignatures,
+		SighashType:          input.SighashType,
+		BIP32Derivations:     make([]*BIP32Derivation, len(input.BIP32Derivations)),
+		PartialSignatures:    make([]*PartialSignature, len(input.PartialSignatures)),
+		FinalSignatureScript: cloneBytes(input.FinalSignatureScript),
+	}
+	for i, derivation := range input.BIP32Derivations {
+		clone.BIP32Derivations[i] = &BIP32Derivation{
+			PublicKey:         cloneBytes(derivation.PublicKey),
+			DerivationPath:    derivation.DerivationPath,
+			ExtendedPublicKey: derivation.ExtendedPublicKey,
+		}
+	}
+	for i, signature := range input.PartialSignatures {
+		clone.PartialSignatures[i] = &PartialSignature{
+			PublicKey: cloneBytes(signature.PublicKey),
+			Signature: cloneBytes(signature.Signature),
+		}
+	}
+	return clone
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	clone := make([]byte, len(b))
+	copy(clone, b)
+	return clone
+}
+
+// partialSignature returns the signature of the input that was signed with publicKey, if there is one
+func (input *Input) partialSignature(publicKey []byte) (*PartialSignature, bool) {
+	for _, signature := range input.PartialSignatures {
+		if bytes.Equal(signature.PublicKey, publicKey) {
+			return signature, true
+		}
+	}
+	return nil, false
+}
+
+// Serialize returns the binary encoding of the PSKT
+func Serialize(p *PartiallySignedTransaction) ([]byte, error) {
+	protoPSKT, err := toProto(p)
+	if err != nil {
+		return nil, err
+	}
+	serialized, err := proto.Marshal(protoPSKT)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, magic...), serialized...), nil
+}
+
+// Deserialize decodes a PSKT from its binary encoding
+func Deserialize(serialized []byte) (*PartiallySignedTransaction, error) {
+	if !bytes.HasPrefix(serialized, magic) {
+		return nil, errors.New("the data is not a PSKT: it doesn't start with the PSKT magic bytes")
+	}
+
+	protoPSKT := &protopskt.PartiallySignedTransaction{}
+	err := proto.Unmarshal(serialized[len(magic):], protoPSKT)
+	if err != nil {
+		return nil, err
+	}
+	return fromProto(protoPSKT)
+}
+
+// Encode returns the text (base64) encoding of the PSKT
+func Encode(p *PartiallySignedTransaction) (string, error) {
+	serialized, err := Serialize(p)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(serialized), nil
+}
+
+// Parse decodes a PSKT from either its text (base64) encoding or its JSON encoding
+func Parse(text string) (*PartiallySignedTransaction, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		p := &PartiallySignedTransaction{}
+		err := json.Unmarshal([]byte(text), p)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	serialized, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "a PSKT is expected to be encoded in base64 or in JSON")
+	}
+	return Deserialize(serialized)
+}
@@ -0,0 +1,260 @@
+package pskt_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/pskt"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+)
+
+func forSchnorrAndECDSA(t *testing.T, testFunc func(t *testing.T, ecdsa bool)) {
+	t.Run("schnorr", func(t *testing.T) {
+		testFunc(t, false)
+	})
+
+	t.Run("ecdsa", func(t *testing.T) {
+		testFunc(t, true)
+	})
+}
+
+// createMultisigPSKT creates a PSKT that spends a UTXO of a 2-of-3 multisig address, along with the mnemonics of its keys
+func createMultisigPSKT(t *testing.T, params *dagconfig.Params, ecdsa bool) (*pskt.PartiallySignedTransaction, []string) {
+	const numKeys = 3
+	const minimumSignatures = 2
+	mnemonics := make([]string, numKeys)
+	publicKeys := make([]string, numKeys)
+	for i := 0; i < numKeys; i++ {
+		var err error
+		mnemonics[i], err = libkaspiwallet.CreateMnemonic()
+		if err != nil {
+			t.Fatalf("CreateMnemonic: %+v", err)
+		}
+
+		publicKeys[i], err = libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonics[i], "", true)
+		if err != nil {
+			t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
+		}
+	}
+
+	path := "m/1/2/3"
+	address, err := libkaspiwallet.Address(params, publicKeys, minimumSignatures, path, ecdsa)
+	if err != nil {
+		t.Fatalf("Address: %+v", err)
+	}
+	scriptPublicKey, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %+v", err)
+	}
+
+	selectedUTXOs := []*libkaspiwallet.UTXO{
+		{
+			Outpoint: &externalapi.DomainOutpoint{
+				TransactionID: *externalapi.NewDomainTransactionIDFromByteArray(&[externalapi.DomainHashSize]byte{1}),
+				Index:         0,
+			},
+			UTXOEntry:      utxo.NewUTXOEntry(1000, scriptPublicKey, false, 0),
+			DerivationPath: path,
+		},
+	}
+	unsignedTransaction, err := libkaspiwallet.CreateUnsignedTransaction(publicKeys, minimumSignatures,
+		[]*libkaspiwallet.Payment{{
+			Address: address,
+			Amount:  10,
+		}}, selectedUTXOs)
+	if err != nil {
+		t.Fatalf("CreateUnsignedTransaction: %+v", err)
+	}
+
+	walletTransaction, err := serialization.DeserializePartiallySignedTransaction(unsignedTransaction)
+	if err != nil {
+		t.Fatalf("DeserializePartiallySignedTransaction: %+v", err)
+	}
+	p, err := pskt.FromWalletTransaction(walletTransaction)
+	if err != nil {
+		t.Fatalf("FromWalletTransaction: %+v", err)
+	}
+	return p, mnemonics
+}
+
+// signPSKT signs the PSKT with the given mnemonic the way kaspiwallet signs it
+func signPSKT(t *testing.T, params *dagconfig.Params, p *pskt.PartiallySignedTransaction, mnemonic string,
+	ecdsa bool) *pskt.PartiallySignedTransaction {
+
+	walletTransaction, err := pskt.ToWalletTransaction(p)
+	if err != nil {
+		t.Fatalf("ToWalletTransaction: %+v", err)
+	}
+	serialized, err := serialization.SerializePartiallySignedTransaction(walletTransaction)
+	if err != nil {
+		t.Fatalf("SerializePartiallySignedTransaction: %+v", err)
+	}
+	signed, err := libkaspiwallet.Sign(params, []string{mnemonic}, nil, serialized, ecdsa)
+	if err != nil {
+		t.Fatalf("Sign: %+v", err)
+	}
+	signedWalletTransaction, err := serialization.DeserializePartiallySignedTransaction(signed)
+	if err != nil {
+		t.Fatalf("DeserializePartiallySignedTransaction: %+v", err)
+	}
+	signedPSKT, err := pskt.FromWalletTransaction(signedWalletTransaction)
+	if err != nil {
+		t.Fatalf("FromWalletTransaction: %+v", err)
+	}
+	return signedPSKT
+}
+
+func TestEncodings(t *testing.T) {
+	params := &dagconfig.TestnetParams
+	forSchnorrAndECDSA(t, func(t *testing.T, ecdsa bool) {
+		p, mnemonics := createMultisigPSKT(t, params, ecdsa)
+		p = signPSKT(t, params, p, mnemonics[0], ecdsa)
+		expectedJSON, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("Marshal: %+v", err)
+		}
+
+		encoded, err := pskt.Encode(p)
+		if err != nil {
+			t.Fatalf("Encode: %+v", err)
+		}
+		fromBase64, err := pskt.Parse(encoded)
+		if err != nil {
+			t.Fatalf("Parse: %+v", err)
+		}
+		fromJSON, err := pskt.Parse(string(expectedJSON))
+		if err != nil {
+			t.Fatalf("Parse: %+v", err)
+		}
+
+		for _, decoded := range []*pskt.PartiallySignedTransaction{fromBase64, fromJSON} {
+			decodedJSON, err := json.Marshal(decoded)
+			if err != nil {
+				t.Fatalf("Marshal: %+v", err)
+			}
+			if string(decodedJSON) != string(expectedJSON) {
+				t.Fatalf("The decoded PSKT is different from the encoded one. Want: %s, got: %s",
+					expectedJSON, decodedJSON)
+			}
+		}
+
+		_, err = pskt.Deserialize([]byte("not a pskt"))
+		if err == nil {
+			t.Fatalf("Deserialize unexpectedly succeeded to decode data without the magic bytes")
+		}
+	})
+}
+
+func TestParseMissingElements(t *testing.T) {
+	params := &dagconfig.TestnetParams
+	p, mnemonics := createMultisigPSKT(t, params, false)
+	p = signPSKT(t, params, p, mnemonics[0], false)
+	validJSON, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal: %+v", err)
+	}
+
+	tests := []struct {
+		name        string
+		removeFirst func(decoded map[string]interface{})
+	}{
+		{
+			name: "transaction input",
+			removeFirst: func(decoded map[string]interface{}) {
+				decoded["tx"].(map[string]interface{})["inputs"].([]interface{})[0] = nil
+			},
+		},
+		{
+			name: "transaction output",
+			removeFirst: func(decoded map[string]interface{}) {
+				decoded["tx"].(map[string]interface{})["outputs"].([]interface{})[0] = nil
+			},
+		},
+		{
+			name: "input",
+			removeFirst: func(decoded map[string]interface{}) {
+				decoded["inputs"].([]interface{})[0] = nil
+			},
+		},
+		{
+			name: "BIP32 derivation",
+			removeFirst: func(decoded map[string]interface{}) {
+				input := decoded["inputs"].([]interface{})[0].(map[string]interface{})
+				input["bip32Derivations"].([]interface{})[0] = nil
+			},
+		},
+		{
+			name: "partial signature",
+			removeFirst: func(decoded map[string]interface{}) {
+				input := decoded["inputs"].([]interface{})[0].(map[string]interface{})
+				input["partialSignatures"].([]interface{})[0] = nil
+			},
+		},
+	}
+	for _, test := range tests {
+		decoded := make(map[string]interface{})
+		err := json.Unmarshal(validJSON, &decoded)
+		if err != nil {
+			t.Fatalf("Unmarshal: %+v", err)
+		}
+		test.removeFirst(decoded)
+		invalidJSON, err := json.Marshal(decoded)
+		if err != nil {
+			t.Fatalf("Marshal: %+v", err)
+		}
+		_, err = pskt.Parse(string(invalidJSON))
+		if err == nil {
+			t.Fatalf("Parse unexpectedly succeeded to decode a PSKT with a missing %s", test.name)
+		}
+	}
+
+	_, err = pskt.Parse(`{"version":1,"tx":{"subnetworkId":"0000000000000000000000000000000000000000",` +
+		`"inputs":[null]},"inputs":[null]}`)
+	if err == nil {
+		t.Fatalf("Parse unexpectedly succeeded to decode a PSKT with missing inputs")
+	}
+}
+
+func TestCombineAndFinalize(t *testing.T) {
+	params := &dagconfig.TestnetParams
+	forSchnorrAndECDSA(t, func(t *testing.T, ecdsa bool) {
+		p, mnemonics := createMultisigPSKT(t, params, ecdsa)
+
+		// Each signer signs their own copy of the PSKT, as external signers do
+		signedByFirst := signPSKT(t, params, p, mnemonics[0], ecdsa)
+		signedByThird := signPSKT(t, params, p, mnemonics[2], ecdsa)
+		if signedByFirst.IsFinalizable() {
+			t.Fatalf("A PSKT with a single signature is not expected to be finalizable")
+		}
+		_, err := pskt.Finalize(signedByFirst)
+		if err == nil {
+			t.Fatalf("Finalize unexpectedly succeeded with a single signature")
+		}
+
+		combined, err := pskt.Combine(signedByFirst, signedByThird)
+		if err != nil {
+			t.Fatalf("Combine: %+v", err)
+		}
+		if !combined.IsFinalizable() {
+			t.Fatalf("A PSKT with enough signatures is expected to be finalizable")
+		}
+		tx, err := pskt.Finalize(combined)
+		if err != nil {
+			t.Fatalf("Finalize: %+v", err)
+		}
+		if !tx.Inputs[0].UTXOEntry.ScriptPublicKey().Equal(combined.Inputs[0].UTXOEntry.ScriptPublicKey()) {
+			t.Fatalf("The finalized transaction doesn't carry the UTXO entries of its inputs")
+		}
+
+		other, _ := createMultisigPSKT(t, params, ecdsa)
+		_, err = pskt.Combine(combined, other)
+		if err == nil {
+			t.Fatalf("Combine unexpectedly succeeded to combine PSKTs of different transactions")
+		}
+	})
+}
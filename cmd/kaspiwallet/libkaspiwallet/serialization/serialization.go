@@ -26,6 +26,9 @@ type PartiallySignedInput struct {
 	MinimumSignatures    uint32
 	PubKeySignaturePairs []*PubKeySignaturePair
 	DerivationPath       string
+	// RedeemScript is the redeem script of a P2SH input of a single key, such as a time-locked
+	// one. It's nil for multisig inputs, whose redeem script is built from their keys.
+	RedeemScript []byte
 }
 
 // PubKeySignaturePair is a pair of public key and (potentially) its associated signature
@@ -54,6 +57,10 @@ func (psi PartiallySignedInput) Clone() *PartiallySignedInput {
 		PubKeySignaturePairs: make([]*PubKeySignaturePair, len(psi.PubKeySignaturePairs)),
 		DerivationPath:       psi.DerivationPath,
 	}
+	if psi.RedeemScript != nil {
+		clone.RedeemScript = make([]byte, len(psi.RedeemScript))
+		copy(clone.RedeemScript, psi.RedeemScript)
+	}
 	for i, pubKeySignaturePair := range psi.PubKeySignaturePairs {
 		clone.PubKeySignaturePairs[i] = pubKeySignaturePair.Clone()
 	}
@@ -152,6 +159,7 @@ func partiallySignedInputFromProto(protoPartiallySignedInput *protoserialization
 		MinimumSignatures:    protoPartiallySignedInput.MinimumSignatures,
 		PubKeySignaturePairs: pubKeySignaturePairs,
 		DerivationPath:       protoPartiallySignedInput.DerivationPath,
+		RedeemScript:         protoPartiallySignedInput.RedeemScript,
 	}, nil
 }
 
@@ -162,6 +170,7 @@ func partiallySignedInputToProto(partiallySignedInput *PartiallySignedInput) *pr
 	}
 
 	return &protoserialization.PartiallySignedInput{
+		RedeemScript:         partiallySignedInput.RedeemScript,
 		PrevOutput:           transactionOutputToProto(partiallySignedInput.PrevOutput),
 		MinimumSignatures:    partiallySignedInput.MinimumSignatures,
 		PubKeySignaturePairs: protoPairs,
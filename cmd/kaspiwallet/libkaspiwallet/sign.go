@@ -27,15 +27,25 @@ func rawTxInSignature(extendedKey *bip32.ExtendedKey, tx *externalapi.DomainTran
 	return txscript.RawTxInSignature(tx, idx, hashType, schnorrKeyPair, sighashReusedValues)
 }
 
-// Sign signs the transaction with the given private keys
-func Sign(params *dagconfig.Params, mnemonics []string, serializedPSTx []byte, ecdsa bool) ([]byte, error) {
+// Sign signs the transaction with the given private keys, where passphrases[i] is the BIP39
+// passphrase the seed of mnemonics[i] is derived with. passphrases may be nil if none of the
+// mnemonics has a passphrase.
+func Sign(params *dagconfig.Params, mnemonics []string, passphrases []string, serializedPSTx []byte, ecdsa bool) ([]byte, error) {
+	if passphrases != nil && len(passphrases) != len(mnemonics) {
+		return nil, errors.Errorf("got %d passphrases for %d mnemonics", len(passphrases), len(mnemonics))
+	}
+
 	partiallySignedTransaction, err := serialization.DeserializePartiallySignedTransaction(serializedPSTx)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, mnemonic := range mnemonics {
-		err = sign(params, mnemonic, partiallySignedTransaction, ecdsa)
+	for i, mnemonic := range mnemonics {
+		passphrase := ""
+		if passphrases != nil {
+			passphrase = passphrases[i]
+		}
+		err = sign(params, mnemonic, passphrase, partiallySignedTransaction, ecdsa)
 		if err != nil {
 			return nil, err
 		}
@@ -43,7 +53,7 @@ func Sign(params *dagconfig.Params, mnemonics []string, serializedPSTx []byte, e
 	return serialization.SerializePartiallySignedTransaction(partiallySignedTransaction)
 }
 
-func sign(params *dagconfig.Params, mnemonic string, partiallySignedTransaction *serialization.PartiallySignedTransaction, ecdsa bool) error {
+func sign(params *dagconfig.Params, mnemonic string, passphrase string, partiallySignedTransaction *serialization.PartiallySignedTransaction, ecdsa bool) error {
 	if isTransactionFullySigned(partiallySignedTransaction) {
 		return nil
 	}
@@ -63,13 +73,16 @@ func sign(params *dagconfig.Params, mnemonic string, partiallySignedTransaction
 	signed := false
 	for i, partiallySignedInput := range partiallySignedTransaction.PartiallySignedInputs {
 		isMultisig := len(partiallySignedInput.PubKeySignaturePairs) > 1
-		path := defaultPath(isMultisig)
-		extendedKey, err := extendedKeyFromMnemonicAndPath(mnemonic, path, params)
+		accountIndex, pathInAccount, err := splitAccountDerivationPath(partiallySignedInput.DerivationPath)
+		if err != nil {
+			return err
+		}
+		extendedKey, err := extendedKeyFromMnemonicAndPath(mnemonic, passphrase, accountPath(isMultisig, accountIndex), params)
 		if err != nil {
 			return err
 		}
 
-		derivedKey, err := extendedKey.DeriveFromPath(partiallySignedInput.DerivationPath)
+		derivedKey, err := extendedKey.DeriveFromPath(pathInAccount)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,71 @@
+package libkaspiwallet
+
+import (
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+// TimeLockRedeemScript returns the redeem script of the time-locked address of the key with the given path
+// of a single signer wallet, which can be spent only once the DAA score of the DAG passes lockDAAScore
+func TimeLockRedeemScript(params *dagconfig.Params, extendedPublicKey string, path string, lockDAAScore uint64,
+	ecdsa bool) ([]byte, error) {
+
+	if lockDAAScore >= constants.LockTimeThreshold {
+		return nil, errors.Errorf("the lock DAA score %d is too big, and would be interpreted as a timestamp",
+			lockDAAScore)
+	}
+	keyAddress, err := p2pkAddress(params, extendedPublicKey, path, ecdsa)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.TimeLockScript(keyAddress.ScriptAddress(), lockDAAScore, ecdsa)
+}
+
+// TimeLockedAddress returns the P2SH address of TimeLockRedeemScript
+func TimeLockedAddress(params *dagconfig.Params, extendedPublicKey string, path string, lockDAAScore uint64,
+	ecdsa bool) (util.Address, error) {
+
+	redeemScript, err := TimeLockRedeemScript(params, extendedPublicKey, path, lockDAAScore, ecdsa)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewAddressScriptHash(redeemScript, params.Prefix)
+}
+
+// IsLockTimeReached returns whether a transaction with the given lock time is final, and so can be
+// accepted, in a DAG with the given virtual DAA score and past median time
+func IsLockTimeReached(lockTime uint64, virtualDAAScore uint64, pastMedianTime int64) bool {
+	if lockTime < constants.LockTimeThreshold {
+		return lockTime < virtualDAAScore
+	}
+	return pastMedianTime >= 0 && lockTime < uint64(pastMedianTime)
+}
+
+// transactionLockTime returns the lock time a transaction spending the given UTXOs needs in order
+// to satisfy the time locks of their redeem scripts, or 0 if none of them is time-locked
+func transactionLockTime(utxos []*UTXO) (uint64, error) {
+	lockTime := uint64(0)
+	for _, utxo := range utxos {
+		if utxo.RedeemScript == nil {
+			continue
+		}
+		pushes, err := txscript.ExtractTimeLockDataPushes(utxo.RedeemScript)
+		if err != nil {
+			return 0, err
+		}
+		if pushes == nil {
+			return 0, errors.Errorf("the redeem script of UTXO %s is not a time lock script", utxo.Outpoint)
+		}
+		if lockTime != 0 && (lockTime < constants.LockTimeThreshold) != (pushes.LockTime < constants.LockTimeThreshold) {
+			return 0, errors.Errorf("UTXOs locked until a DAA score and UTXOs locked until a time " +
+				"can't be spent by the same transaction")
+		}
+		if pushes.LockTime > lockTime {
+			lockTime = pushes.LockTime
+		}
+	}
+	return lockTime, nil
+}
@@ -0,0 +1,126 @@
+package libkaspiwallet_test
+
+import (
+	"testing"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
+)
+
+func TestTimeLockedAddress(t *testing.T) {
+	params := &dagconfig.SimnetParams
+	forSchnorrAndECDSA(t, func(t *testing.T, ecdsa bool) {
+		mnemonic, err := libkaspiwallet.CreateMnemonic()
+		if err != nil {
+			t.Fatalf("CreateMnemonic: %+v", err)
+		}
+		publicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+		if err != nil {
+			t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
+		}
+
+		const path = "m/0/1"
+		const lockDAAScore = 1000
+		address, err := libkaspiwallet.TimeLockedAddress(params, publicKey, path, lockDAAScore, ecdsa)
+		if err != nil {
+			t.Fatalf("TimeLockedAddress: %+v", err)
+		}
+		if _, ok := address.(*util.AddressScriptHash); !ok {
+			t.Fatalf("The address is of unexpected type")
+		}
+		redeemScript, err := libkaspiwallet.TimeLockRedeemScript(params, publicKey, path, lockDAAScore, ecdsa)
+		if err != nil {
+			t.Fatalf("TimeLockRedeemScript: %+v", err)
+		}
+		_, err = libkaspiwallet.TimeLockRedeemScript(params, publicKey, path, constants.LockTimeThreshold, ecdsa)
+		if err == nil {
+			t.Fatalf("A time lock with a timestamp was created as a DAA score time lock")
+		}
+
+		scriptPublicKey, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			t.Fatalf("PayToAddrScript: %+v", err)
+		}
+		utxos := []*libkaspiwallet.UTXO{{
+			Outpoint:       &externalapi.DomainOutpoint{Index: 1},
+			UTXOEntry:      utxo.NewUTXOEntry(100_000, scriptPublicKey, false, 10),
+			DerivationPath: path,
+			RedeemScript:   redeemScript,
+		}}
+		unsignedTransaction, err := libkaspiwallet.CreateUnsignedTransaction([]string{publicKey}, 1,
+			[]*libkaspiwallet.Payment{{Address: address, Amount: 99_000}}, utxos)
+		if err != nil {
+			t.Fatalf("CreateUnsignedTransaction: %+v", err)
+		}
+		signedTransaction, err := libkaspiwallet.Sign(params, []string{mnemonic}, nil, unsignedTransaction, ecdsa)
+		if err != nil {
+			t.Fatalf("Sign: %+v", err)
+		}
+		tx, err := libkaspiwallet.ExtractTransaction(signedTransaction, ecdsa)
+		if err != nil {
+			t.Fatalf("ExtractTransaction: %+v", err)
+		}
+		if tx.LockTime != lockDAAScore {
+			t.Fatalf("The lock time of the transaction is %d, while %d is expected", tx.LockTime, lockDAAScore)
+		}
+
+		tx.Inputs[0].UTXOEntry = utxos[0].UTXOEntry
+		vm, err := txscript.NewEngine(scriptPublicKey, tx, 0, txscript.ScriptNoFlags, nil, nil,
+			&consensushashing.SighashReusedValues{})
+		if err != nil {
+			t.Fatalf("NewEngine: %+v", err)
+		}
+		err = vm.Execute()
+		if err != nil {
+			t.Fatalf("The spend of the time-locked UTXO is invalid: %+v", err)
+		}
+
+		pushes, err := txscript.ExtractTimeLockDataPushes(redeemScript)
+		if err != nil {
+			t.Fatalf("ExtractTimeLockDataPushes: %+v", err)
+		}
+		timestampRedeemScript, err := txscript.TimeLockScript(pushes.PublicKey, 1_700_000_000_000, ecdsa)
+		if err != nil {
+			t.Fatalf("TimeLockScript: %+v", err)
+		}
+		mixedUTXOs := append(utxos, &libkaspiwallet.UTXO{
+			Outpoint:       &externalapi.DomainOutpoint{Index: 2},
+			UTXOEntry:      utxo.NewUTXOEntry(100_000, scriptPublicKey, false, 10),
+			DerivationPath: path,
+			RedeemScript:   timestampRedeemScript,
+		})
+		_, err = libkaspiwallet.CreateUnsignedTransaction([]string{publicKey}, 1,
+			[]*libkaspiwallet.Payment{{Address: address, Amount: 99_000}}, mixedUTXOs)
+		if err == nil {
+			t.Fatalf("UTXOs locked until a DAA score and until a time were spent together")
+		}
+	})
+}
+
+func TestIsLockTimeReached(t *testing.T) {
+	tests := []struct {
+		lockTime        uint64
+		virtualDAAScore uint64
+		pastMedianTime  int64
+		expected        bool
+	}{
+		{lockTime: 1000, virtualDAAScore: 999, expected: false},
+		{lockTime: 1000, virtualDAAScore: 1000, expected: false},
+		{lockTime: 1000, virtualDAAScore: 1001, expected: true},
+		{lockTime: 1_700_000_000_000, virtualDAAScore: 2_000_000_000_000, pastMedianTime: 1_700_000_000_000, expected: false},
+		{lockTime: 1_700_000_000_000, pastMedianTime: 1_700_000_000_001, expected: true},
+	}
+	for _, test := range tests {
+		result := libkaspiwallet.IsLockTimeReached(test.lockTime, test.virtualDAAScore, test.pastMedianTime)
+		if result != test.expected {
+			t.Errorf("IsLockTimeReached(%d, %d, %d) is %t, while %t is expected", test.lockTime,
+				test.virtualDAAScore, test.pastMedianTime, result, test.expected)
+		}
+	}
+}
@@ -24,6 +24,9 @@ type UTXO struct {
 	Outpoint       *externalapi.DomainOutpoint
 	UTXOEntry      externalapi.UTXOEntry
 	DerivationPath string
+	// RedeemScript is the redeem script of a UTXO of a time-locked address, as returned by
+	// TimeLockRedeemScript, and nil for UTXOs of the regular addresses of the wallet
+	RedeemScript []byte
 }
 
 // CreateUnsignedTransaction creates an unsigned transaction
@@ -100,9 +103,24 @@ func createUnsignedTransaction(
 	payments []*Payment,
 	selectedUTXOs []*UTXO) (*serialization.PartiallySignedTransaction, error) {
 
+	lockTime, err := transactionLockTime(selectedUTXOs)
+	if err != nil {
+		return nil, err
+	}
+
 	inputs := make([]*externalapi.DomainTransactionInput, len(selectedUTXOs))
 	partiallySignedInputs := make([]*serialization.PartiallySignedInput, len(selectedUTXOs))
 	for i, utxo := range selectedUTXOs {
+		if utxo.RedeemScript != nil && len(extendedPublicKeys) > 1 {
+			return nil, errors.Errorf("UTXO %s of a multisig wallet can't have a redeem script of its own", utxo.Outpoint)
+		}
+
+		// The extended public keys are of the account of the UTXO, so only the path within the account is derived
+		_, pathInAccount, err := splitAccountDerivationPath(utxo.DerivationPath)
+		if err != nil {
+			return nil, err
+		}
+
 		emptyPubKeySignaturePairs := make([]*serialization.PubKeySignaturePair, len(extendedPublicKeys))
 		for i, extendedPublicKey := range extendedPublicKeys {
 			extendedKey, err := bip32.DeserializeExtendedKey(extendedPublicKey)
@@ -110,7 +128,7 @@ func createUnsignedTransaction(
 				return nil, err
 			}
 
-			derivedKey, err := extendedKey.DeriveFromPath(utxo.DerivationPath)
+			derivedKey, err := extendedKey.DeriveFromPath(pathInAccount)
 			if err != nil {
 				return nil, err
 			}
@@ -129,6 +147,7 @@ func createUnsignedTransaction(
 			MinimumSignatures:    minimumSignatures,
 			PubKeySignaturePairs: emptyPubKeySignaturePairs,
 			DerivationPath:       utxo.DerivationPath,
+			RedeemScript:         utxo.RedeemScript,
 		}
 	}
 
@@ -149,7 +168,7 @@ func createUnsignedTransaction(
 		Version:      constants.MaxTransactionVersion,
 		Inputs:       inputs,
 		Outputs:      outputs,
-		LockTime:     0,
+		LockTime:     lockTime,
 		SubnetworkID: subnetworks.SubnetworkIDNative,
 		Gas:          0,
 		Payload:      nil,
@@ -239,9 +258,11 @@ func ExtractTransactionDeserialized(partiallySignedTransaction *serialization.Pa
 				return nil, errors.Errorf("missing signature")
 			}
 
-			sigScript, err := txscript.NewScriptBuilder().
-				AddData(input.PubKeySignaturePairs[0].Signature).
-				Script()
+			scriptBuilder.AddData(input.PubKeySignaturePairs[0].Signature)
+			if input.RedeemScript != nil {
+				scriptBuilder.AddData(input.RedeemScript)
+			}
+			sigScript, err := scriptBuilder.Script()
 			if err != nil {
 				return nil, err
 			}
@@ -13,6 +13,7 @@ import (
 	"github.com/kaspikr/kaspid/domain/consensus/utils/testutils"
 	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
 	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
 	"github.com/kaspikr/kaspid/util"
 )
 
@@ -47,7 +48,7 @@ func TestMultisig(t *testing.T) {
 					t.Fatalf("CreateMnemonic: %+v", err)
 				}
 
-				publicKeys[i], err = libkaspiwallet.MasterPublicKeyFromMnemonic(&consensusConfig.Params, mnemonics[i], true)
+				publicKeys[i], err = libkaspiwallet.MasterPublicKeyFromMnemonic(&consensusConfig.Params, mnemonics[i], "", true)
 				if err != nil {
 					t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
 				}
@@ -125,7 +126,7 @@ func TestMultisig(t *testing.T) {
 				t.Fatal("Unexpectedly succeed to extract a valid transaction out of unsigned transaction")
 			}
 
-			signedTxStep1, err := libkaspiwallet.Sign(params, mnemonics[:1], unsignedTransaction, ecdsa)
+			signedTxStep1, err := libkaspiwallet.Sign(params, mnemonics[:1], nil, unsignedTransaction, ecdsa)
 			if err != nil {
 				t.Fatalf("Sign: %+v", err)
 			}
@@ -139,7 +140,7 @@ func TestMultisig(t *testing.T) {
 				t.Fatalf("Transaction is not expected to be fully signed")
 			}
 
-			signedTxStep2, err := libkaspiwallet.Sign(params, mnemonics[1:2], signedTxStep1, ecdsa)
+			signedTxStep2, err := libkaspiwallet.Sign(params, mnemonics[1:2], nil, signedTxStep1, ecdsa)
 			if err != nil {
 				t.Fatalf("Sign: %+v", err)
 			}
@@ -149,7 +150,7 @@ func TestMultisig(t *testing.T) {
 				t.Fatalf("ExtractTransaction: %+v", err)
 			}
 
-			signedTxOneStep, err := libkaspiwallet.Sign(params, mnemonics[:2], unsignedTransaction, ecdsa)
+			signedTxOneStep, err := libkaspiwallet.Sign(params, mnemonics[:2], nil, unsignedTransaction, ecdsa)
 			if err != nil {
 				t.Fatalf("Sign: %+v", err)
 			}
@@ -202,7 +203,7 @@ func TestP2PK(t *testing.T) {
 					t.Fatalf("CreateMnemonic: %+v", err)
 				}
 
-				publicKeys[i], err = libkaspiwallet.MasterPublicKeyFromMnemonic(&consensusConfig.Params, mnemonics[i], false)
+				publicKeys[i], err = libkaspiwallet.MasterPublicKeyFromMnemonic(&consensusConfig.Params, mnemonics[i], "", false)
 				if err != nil {
 					t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
 				}
@@ -286,7 +287,7 @@ func TestP2PK(t *testing.T) {
 				t.Fatal("Unexpectedly succeed to extract a valid transaction out of unsigned transaction")
 			}
 
-			signedTx, err := libkaspiwallet.Sign(params, mnemonics, unsignedTransaction, ecdsa)
+			signedTx, err := libkaspiwallet.Sign(params, mnemonics, nil, unsignedTransaction, ecdsa)
 			if err != nil {
 				t.Fatalf("Sign: %+v", err)
 			}
@@ -334,7 +335,7 @@ func TestMaxSompi(t *testing.T) {
 				t.Fatalf("CreateMnemonic: %+v", err)
 			}
 
-			publicKeys[i], err = libkaspiwallet.MasterPublicKeyFromMnemonic(&cfg.Params, mnemonics[i], false)
+			publicKeys[i], err = libkaspiwallet.MasterPublicKeyFromMnemonic(&cfg.Params, mnemonics[i], "", false)
 			if err != nil {
 				t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
 			}
@@ -434,7 +435,7 @@ func TestMaxSompi(t *testing.T) {
 			t.Fatalf("CreateUnsignedTransactions: %+v", err)
 		}
 
-		signedTxWithLargeInputAmount, err := libkaspiwallet.Sign(params, mnemonics, unsignedTxWithLargeInputAmount, false)
+		signedTxWithLargeInputAmount, err := libkaspiwallet.Sign(params, mnemonics, nil, unsignedTxWithLargeInputAmount, false)
 		if err != nil {
 			t.Fatalf("Sign: %+v", err)
 		}
@@ -485,7 +486,7 @@ func TestMaxSompi(t *testing.T) {
 			t.Fatalf("CreateUnsignedTransactions: %+v", err)
 		}
 
-		signedTxWithLargeInputAndOutputAmount, err := libkaspiwallet.Sign(params, mnemonics, unsignedTxWithLargeInputAndOutputAmount, false)
+		signedTxWithLargeInputAndOutputAmount, err := libkaspiwallet.Sign(params, mnemonics, nil, unsignedTxWithLargeInputAndOutputAmount, false)
 		if err != nil {
 			t.Fatalf("Sign: %+v", err)
 		}
@@ -516,3 +517,137 @@ func TestMaxSompi(t *testing.T) {
 		}
 	})
 }
+
+func TestSignWithAccount(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %+v", err)
+	}
+	const accountIndex = 2
+	accountPublicKey, err := libkaspiwallet.AccountPublicKeyFromMnemonic(params, mnemonic, "", false, accountIndex)
+	if err != nil {
+		t.Fatalf("AccountPublicKeyFromMnemonic: %+v", err)
+	}
+	masterPublicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
+	}
+	if accountPublicKey == masterPublicKey {
+		t.Fatalf("The account key is expected to differ from the key of the first account")
+	}
+
+	const path = "m/0/3"
+	address, err := libkaspiwallet.Address(params, []string{accountPublicKey}, 1, path, false)
+	if err != nil {
+		t.Fatalf("Address: %+v", err)
+	}
+	scriptPublicKey, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %+v", err)
+	}
+
+	derivationPath := libkaspiwallet.AccountDerivationPath(accountIndex, path)
+	if derivationPath != "m/2'/0/3" {
+		t.Fatalf("Unexpected derivation path %s", derivationPath)
+	}
+	selectedUTXOs := []*libkaspiwallet.UTXO{
+		{
+			Outpoint: &externalapi.DomainOutpoint{
+				TransactionID: *externalapi.NewDomainTransactionIDFromByteArray(&[32]byte{1}),
+				Index:         0,
+			},
+			UTXOEntry:      utxo.NewUTXOEntry(100, scriptPublicKey, false, 0),
+			DerivationPath: derivationPath,
+		},
+	}
+	unsignedTransaction, err := libkaspiwallet.CreateUnsignedTransaction([]string{accountPublicKey}, 1,
+		[]*libkaspiwallet.Payment{{
+			Address: address,
+			Amount:  10,
+		}}, selectedUTXOs)
+	if err != nil {
+		t.Fatalf("CreateUnsignedTransactions: %+v", err)
+	}
+
+	signedTransaction, err := libkaspiwallet.Sign(params, []string{mnemonic}, nil, unsignedTransaction, false)
+	if err != nil {
+		t.Fatalf("Sign: %+v", err)
+	}
+	isFullySigned, err := libkaspiwallet.IsTransactionFullySigned(signedTransaction)
+	if err != nil {
+		t.Fatalf("IsTransactionFullySigned: %+v", err)
+	}
+	if !isFullySigned {
+		t.Fatalf("The transaction is expected to be signed with the account key")
+	}
+}
+
+func TestSignWithPassphrase(t *testing.T) {
+	params := &dagconfig.DevnetParams
+	mnemonic, err := libkaspiwallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %+v", err)
+	}
+	const passphrase = "passphrase"
+	publicKey, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, passphrase, false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
+	}
+	publicKeyWithoutPassphrase, err := libkaspiwallet.MasterPublicKeyFromMnemonic(params, mnemonic, "", false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %+v", err)
+	}
+	if publicKey == publicKeyWithoutPassphrase {
+		t.Fatalf("The key derived with a passphrase is expected to differ from the one derived without it")
+	}
+
+	const path = "m/0/0"
+	address, err := libkaspiwallet.Address(params, []string{publicKey}, 1, path, false)
+	if err != nil {
+		t.Fatalf("Address: %+v", err)
+	}
+	scriptPublicKey, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %+v", err)
+	}
+	selectedUTXOs := []*libkaspiwallet.UTXO{
+		{
+			Outpoint: &externalapi.DomainOutpoint{
+				TransactionID: *externalapi.NewDomainTransactionIDFromByteArray(&[32]byte{1}),
+				Index:         0,
+			},
+			UTXOEntry:      utxo.NewUTXOEntry(100, scriptPublicKey, false, 0),
+			DerivationPath: path,
+		},
+	}
+	unsignedTransaction, err := libkaspiwallet.CreateUnsignedTransaction([]string{publicKey}, 1,
+		[]*libkaspiwallet.Payment{{
+			Address: address,
+			Amount:  10,
+		}}, selectedUTXOs)
+	if err != nil {
+		t.Fatalf("CreateUnsignedTransactions: %+v", err)
+	}
+
+	_, err = libkaspiwallet.Sign(params, []string{mnemonic}, nil, unsignedTransaction, false)
+	if err == nil {
+		t.Fatalf("Signing without the passphrase is expected to fail")
+	}
+	_, err = libkaspiwallet.Sign(params, []string{mnemonic}, []string{"wrong"}, unsignedTransaction, false)
+	if err == nil {
+		t.Fatalf("Signing with a wrong passphrase is expected to fail")
+	}
+	signedTransaction, err := libkaspiwallet.Sign(params, []string{mnemonic}, []string{passphrase},
+		unsignedTransaction, false)
+	if err != nil {
+		t.Fatalf("Sign: %+v", err)
+	}
+	isFullySigned, err := libkaspiwallet.IsTransactionFullySigned(signedTransaction)
+	if err != nil {
+		t.Fatalf("IsTransactionFullySigned: %+v", err)
+	}
+	if !isFullySigned {
+		t.Fatalf("The transaction is expected to be signed with the key derived with the passphrase")
+	}
+}
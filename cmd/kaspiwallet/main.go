@@ -26,6 +26,58 @@ func main() {
 		err = showAddresses(config.(*showAddressesConfig))
 	case newAddressSubCmd:
 		err = newAddress(config.(*newAddressConfig))
+	case historySubCmd:
+		err = history(config.(*historyConfig))
+	case utxosSubCmd:
+		err = utxos(config.(*utxosConfig))
+	case lockUTXOsSubCmd:
+		err = lockUTXOs(config.(*lockUTXOsConfig))
+	case unlockUTXOsSubCmd:
+		err = unlockUTXOs(config.(*unlockUTXOsConfig))
+	case compoundSubCmd:
+		err = compound(config.(*compoundConfig))
+	case createAccountSubCmd:
+		err = createAccount(config.(*createAccountConfig))
+	case accountsSubCmd:
+		err = accounts(config.(*accountsConfig))
+	case setLabelSubCmd:
+		err = setLabel(config.(*setLabelConfig))
+	case labelsSubCmd:
+		err = labels(config.(*labelsConfig))
+	case watchSubCmd:
+		err = watch(config.(*watchConfig))
+	case changePasswordSubCmd:
+		err = changePassword(config.(*changePasswordConfig))
+	case signMessageSubCmd:
+		err = signMessage(config.(*signMessageConfig))
+	case verifyMessageSubCmd:
+		err = verifyMessage(config.(*verifyMessageConfig))
+	case exportDescriptorSubCmd:
+		err = exportDescriptor(config.(*exportDescriptorConfig))
+	case verifyDescriptorSubCmd:
+		err = verifyDescriptor(config.(*verifyDescriptorConfig))
+	case psktInspectSubCmd:
+		err = psktInspect(config.(*psktInspectConfig))
+	case psktCombineSubCmd:
+		err = psktCombine(config.(*psktCombineConfig))
+	case psktFinalizeSubCmd:
+		err = psktFinalize(config.(*psktFinalizeConfig))
+	case htlcInitiateSubCmd:
+		err = htlcInitiate(config.(*htlcInitiateConfig))
+	case htlcRedeemSubCmd:
+		err = htlcRedeem(config.(*htlcRedeemConfig))
+	case htlcRefundSubCmd:
+		err = htlcRefund(config.(*htlcRefundConfig))
+	case htlcSecretSubCmd:
+		err = htlcSecret(config.(*htlcSecretConfig))
+	case htlcInspectSubCmd:
+		err = htlcInspect(config.(*htlcInspectConfig))
+	case timeLockSubCmd:
+		err = timeLock(config.(*timeLockConfig))
+	case unlockSubCmd:
+		err = unlock(config.(*unlockConfig))
+	case lockSubCmd:
+		err = lock(config.(*lockConfig))
 	case dumpUnencryptedDataSubCmd:
 		err = dumpUnencryptedData(config.(*dumpUnencryptedDataConfig))
 	case startDaemonSubCmd:
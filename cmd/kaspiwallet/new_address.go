@@ -3,12 +3,24 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
+
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/util"
 )
 
 func newAddress(conf *newAddressConfig) error {
-	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress)
+	var amountSompi uint64
+	if conf.Amount != "" {
+		var err error
+		amountSompi, err = util.KasToSompi(conf.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
 	if err != nil {
 		return err
 	}
@@ -17,11 +29,44 @@ func newAddress(conf *newAddressConfig) error {
 	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
 	defer cancel()
 
-	response, err := daemonClient.NewAddress(ctx, &pb.NewAddressRequest{})
+	var newAddress string
+	if conf.LockDAAScore != 0 || conf.LockDuration != 0 {
+		response, err := daemonClient.NewTimeLockedAddress(ctx, &pb.NewTimeLockedAddressRequest{
+			Account:      conf.Account,
+			LockDaaScore: conf.LockDAAScore,
+			LockDuration: uint64(conf.LockDuration.Milliseconds()),
+		})
+		if err != nil {
+			return err
+		}
+		newAddress = response.Address
+		fmt.Printf("New time-locked address, whose funds can't be spent until DAA score %d:\n%s\n",
+			response.LockDaaScore, newAddress)
+	} else {
+		response, err := daemonClient.NewAddress(ctx, &pb.NewAddressRequest{Account: conf.Account})
+		if err != nil {
+			return err
+		}
+		newAddress = response.Address
+		fmt.Printf("New address:\n%s\n", newAddress)
+	}
+
+	if conf.Amount == "" && conf.Label == "" && conf.Message == "" && conf.ExpiresIn == 0 {
+		return nil
+	}
+	address, err := util.DecodeAddress(newAddress, conf.NetParams().Prefix)
 	if err != nil {
 		return err
 	}
-
-	fmt.Printf("New address:\n%s\n", response.Address)
+	paymentURI := &util.PaymentURI{
+		Address: address,
+		Amount:  amountSompi,
+		Label:   conf.Label,
+		Message: conf.Message,
+	}
+	if conf.ExpiresIn != 0 {
+		paymentURI.Expires = time.Now().Add(conf.ExpiresIn)
+	}
+	fmt.Printf("\nPayment URI:\n%s\n", paymentURI)
 	return nil
 }
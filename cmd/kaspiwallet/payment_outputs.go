@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+// validatePaymentFlags makes sure the recipients of a transaction are specified either by
+// --to-address with --send-amount or --send-all, or by --output and/or --outputs-file
+func validatePaymentFlags(toAddress string, sendAmount string, isSendAll bool, outputs []string, outputsFile string) error {
+	hasOutputs := len(outputs) > 0 || outputsFile != ""
+	if toAddress == "" && !hasOutputs {
+		return errors.New("either '--to-address' or '--output'/'--outputs-file' must be specified")
+	}
+	if toAddress != "" && hasOutputs {
+		return errors.New("'--to-address' can't be used together with '--output' or '--outputs-file'")
+	}
+	if hasOutputs {
+		if sendAmount != "" || isSendAll {
+			return errors.New("'--send-amount' and '--send-all' can't be used together with " +
+				"'--output' or '--outputs-file', which specify the amount of each payment")
+		}
+		return nil
+	}
+	if isSendAll && sendAmount != "" {
+		return errors.New("'--send-amount' and '--send-all' can't be used together")
+	}
+	// A payment URI may carry the amount to send, so the amount isn't required for one
+	if !isSendAll && sendAmount == "" && !isPaymentURI(toAddress) {
+		return errors.New("exactly one of '--send-amount' or '--send-all' must be specified")
+	}
+	return nil
+}
+
+// parsePaymentOutputs parses the payments given in the form <address>,<amount in Kaspi> or as payment URIs
+// by --output, followed by the ones in the CSV file given by --outputs-file
+func parsePaymentOutputs(outputs []string, outputsFile string) ([]*pb.PaymentOutput, error) {
+	paymentOutputs := make([]*pb.PaymentOutput, 0, len(outputs))
+	for _, output := range outputs {
+		// A payment URI carries its own amount, and its label and message may contain commas
+		if isPaymentURI(output) {
+			paymentOutput, err := parsePaymentOutput(output, "")
+			if err != nil {
+				return nil, err
+			}
+			paymentOutputs = append(paymentOutputs, paymentOutput)
+			continue
+		}
+		parts := strings.Split(output, ",")
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid output %s, expected <address>,<amount>", output)
+		}
+		paymentOutput, err := parsePaymentOutput(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		paymentOutputs = append(paymentOutputs, paymentOutput)
+	}
+
+	if outputsFile != "" {
+		fileOutputs, err := readPaymentOutputsFile(outputsFile)
+		if err != nil {
+			return nil, err
+		}
+		paymentOutputs = append(paymentOutputs, fileOutputs...)
+	}
+	return paymentOutputs, nil
+}
+
+// readPaymentOutputsFile reads a CSV file with a payment per record in the form <address>,<amount in Kaspi>.
+// Lines starting with # are ignored, as is an optional "address,amount" header. The address may be a payment
+// URI, whose amount is paid if the amount is empty.
+func readPaymentOutputsFile(path string) ([]*pb.PaymentOutput, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not open outputs file %s", path)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var paymentOutputs []*pb.PaymentOutput
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not read outputs file %s", path)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(paymentOutputs) == 0 && strings.EqualFold(record[0], "address") && strings.EqualFold(record[1], "amount") {
+			continue
+		}
+		paymentOutput, err := parsePaymentOutput(record[0], record[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s:%d", path, line)
+		}
+		paymentOutputs = append(paymentOutputs, paymentOutput)
+	}
+	if len(paymentOutputs) == 0 {
+		return nil, errors.Errorf("outputs file %s has no outputs", path)
+	}
+	return paymentOutputs, nil
+}
+
+func parsePaymentOutput(address string, amount string) (*pb.PaymentOutput, error) {
+	address = strings.TrimSpace(address)
+	amount = strings.TrimSpace(amount)
+	if address == "" {
+		return nil, errors.Errorf("missing address for amount %s", amount)
+	}
+	// The wallet daemon pays the amount of a payment URI that is given without an amount
+	if amount == "" && isPaymentURI(address) {
+		return &pb.PaymentOutput{Address: address}, nil
+	}
+	amountSompi, err := util.KasToSompi(amount)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid amount %s for address %s", amount, address)
+	}
+	return &pb.PaymentOutput{Address: address, Amount: amountSompi}, nil
+}
+
+// isPaymentURI returns whether address is a payment URI with parameters, rather than a plain address
+func isPaymentURI(address string) bool {
+	return strings.Contains(address, "?")
+}
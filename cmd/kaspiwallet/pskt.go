@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/pskt"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/pkg/errors"
+)
+
+// encodePSKTs converts the given partially signed transactions of the format of kaspiwallet
+// to PSKTs, and returns their text encodings, one on each line
+func encodePSKTs(partiallySignedTransactions [][]byte) (string, error) {
+	pskts := make([]*pskt.PartiallySignedTransaction, len(partiallySignedTransactions))
+	for i, partiallySignedTransaction := range partiallySignedTransactions {
+		walletTransaction, err := serialization.DeserializePartiallySignedTransaction(partiallySignedTransaction)
+		if err != nil {
+			return "", err
+		}
+		pskts[i], err = pskt.FromWalletTransaction(walletTransaction)
+		if err != nil {
+			return "", err
+		}
+	}
+	return encodePSKTsToText(pskts)
+}
+
+func encodePSKTsToText(pskts []*pskt.PartiallySignedTransaction) (string, error) {
+	encodedPSKTs := make([]string, len(pskts))
+	for i, p := range pskts {
+		var err error
+		encodedPSKTs[i], err = pskt.Encode(p)
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(encodedPSKTs, "\n"), nil
+}
+
+// isPSKTText returns whether the given text holds PSKTs rather than hex encoded
+// partially signed transactions of the format of kaspiwallet
+func isPSKTText(text string) bool {
+	for _, transactionHex := range strings.Split(strings.TrimSpace(text), hexTransactionsSeparator) {
+		_, err := hex.DecodeString(transactionHex)
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// decodePSKTs decodes PSKTs from text that holds either a single PSKT in JSON, PSKTs in base64 with one
+// on each line, or hex encoded partially signed transactions of the format of kaspiwallet
+func decodePSKTs(text string) ([]*pskt.PartiallySignedTransaction, error) {
+	text = strings.TrimSpace(text)
+	if !isPSKTText(text) {
+		partiallySignedTransactions, err := decodeTransactionsFromHex(text)
+		if err != nil {
+			return nil, err
+		}
+		pskts := make([]*pskt.PartiallySignedTransaction, len(partiallySignedTransactions))
+		for i, partiallySignedTransaction := range partiallySignedTransactions {
+			walletTransaction, err := serialization.DeserializePartiallySignedTransaction(partiallySignedTransaction)
+			if err != nil {
+				return nil, err
+			}
+			pskts[i], err = pskt.FromWalletTransaction(walletTransaction)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return pskts, nil
+	}
+
+	if strings.HasPrefix(text, "{") {
+		p, err := pskt.Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return []*pskt.PartiallySignedTransaction{p}, nil
+	}
+
+	var pskts []*pskt.PartiallySignedTransaction
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		p, err := pskt.Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		pskts = append(pskts, p)
+	}
+	return pskts, nil
+}
+
+// readTransactionsText returns the given transactions, or the contents of the given file
+func readTransactionsText(transactions string, transactionsFile string) (string, error) {
+	if transactions == "" && transactionsFile == "" {
+		return "", errors.Errorf("Either --transaction or --transaction-file is required")
+	}
+	if transactions != "" && transactionsFile != "" {
+		return "", errors.Errorf("Both --transaction and --transaction-file cannot be passed at the same time")
+	}
+	if transactionsFile == "" {
+		return transactions, nil
+	}
+	transactionsBytes, err := ioutil.ReadFile(transactionsFile)
+	if err != nil {
+		return "", errors.Wrapf(err, "Could not read the transactions from %s", transactionsFile)
+	}
+	return strings.TrimSpace(string(transactionsBytes)), nil
+}
+
+func psktInspect(conf *psktInspectConfig) error {
+	text, err := readTransactionsText(conf.Transaction, conf.TransactionFile)
+	if err != nil {
+		return err
+	}
+	pskts, err := decodePSKTs(text)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range pskts {
+		if conf.JSON {
+			psktJSON, err := json.Marshal(p)
+			if err != nil {
+				return err
+			}
+			var indented bytes.Buffer
+			err = json.Indent(&indented, psktJSON, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(indented.String())
+			continue
+		}
+
+		err = printPSKT(conf.NetParams(), i, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printPSKT(params *dagconfig.Params, index int, p *pskt.PartiallySignedTransaction) error {
+	fmt.Printf("Transaction #%d ID: \t%s\n", index+1, p.TransactionID())
+	fmt.Println()
+
+	allInputSompi := uint64(0)
+	for i, input := range p.Inputs {
+		outpoint := p.Tx.Inputs[i].PreviousOutpoint
+		fmt.Printf("Input %d: \tOutpoint: %s:%d \tAmount: %.2f Kaspi\n", i, outpoint.TransactionID, outpoint.Index,
+			float64(input.UTXOEntry.Amount())/float64(constants.SompiPerKaspi))
+		if input.FinalSignatureScript != nil {
+			fmt.Println("\t\tFinalized")
+		} else {
+			fmt.Printf("\t\tSignatures: %d of %d \tSighash type: %d\n", len(input.PartialSignatures),
+				input.MinimumSignatures, input.SighashType)
+		}
+
+		signedPublicKeys := make(map[string]struct{}, len(input.PartialSignatures))
+		for _, signature := range input.PartialSignatures {
+			signedPublicKeys[string(signature.PublicKey)] = struct{}{}
+		}
+		for _, derivation := range input.BIP32Derivations {
+			_, isSigned := signedPublicKeys[string(derivation.PublicKey)]
+			fmt.Printf("\t\tKey: %x \tPath: %s \tSigned: %t\n", derivation.PublicKey, derivation.DerivationPath, isSigned)
+		}
+
+		allInputSompi += input.UTXOEntry.Amount()
+	}
+	fmt.Println()
+
+	allOutputSompi := uint64(0)
+	for i, output := range p.Tx.Outputs {
+		scriptPublicKeyType, scriptPublicKeyAddress, err := txscript.ExtractScriptPubKeyAddress(output.ScriptPublicKey, params)
+		if err != nil {
+			return err
+		}
+
+		addressString := scriptPublicKeyAddress.EncodeAddress()
+		if scriptPublicKeyType == txscript.NonStandardTy {
+			scriptPublicKeyHex := hex.EncodeToString(output.ScriptPublicKey.Script)
+			addressString = fmt.Sprintf("<Non-standard transaction script public key: %s>", scriptPublicKeyHex)
+		}
+
+		fmt.Printf("Output %d: \tRecipient: %s \tAmount: %.2f Kaspi\n",
+			i, addressString, float64(output.Value)/float64(constants.SompiPerKaspi))
+
+		allOutputSompi += output.Value
+	}
+	fmt.Println()
+
+	fmt.Printf("Fee:\t%d Sompi\n", allInputSompi-allOutputSompi)
+	fmt.Printf("Ready to finalize:\t%t\n\n", p.IsFinalizable())
+	return nil
+}
+
+func psktCombine(conf *psktCombineConfig) error {
+	if len(conf.Transactions)+len(conf.TransactionFiles) < 2 {
+		return errors.Errorf("At least two PSKTs are required, given with --transaction or --transaction-file")
+	}
+
+	texts := append([]string{}, conf.Transactions...)
+	for _, transactionFile := range conf.TransactionFiles {
+		text, err := readTransactionsText("", transactionFile)
+		if err != nil {
+			return err
+		}
+		texts = append(texts, text)
+	}
+
+	// Each of the texts may hold several transactions, which are combined
+	// with the transactions at the same positions in the other texts
+	var allPSKTs [][]*pskt.PartiallySignedTransaction
+	for _, text := range texts {
+		pskts, err := decodePSKTs(text)
+		if err != nil {
+			return err
+		}
+		if len(allPSKTs) > 0 && len(pskts) != len(allPSKTs[0]) {
+			return errors.Errorf("Cannot combine sets of %d and %d transactions", len(allPSKTs[0]), len(pskts))
+		}
+		allPSKTs = append(allPSKTs, pskts)
+	}
+
+	combinedPSKTs := make([]*pskt.PartiallySignedTransaction, len(allPSKTs[0]))
+	for i := range combinedPSKTs {
+		toCombine := make([]*pskt.PartiallySignedTransaction, len(allPSKTs))
+		for j, pskts := range allPSKTs {
+			toCombine[j] = pskts[i]
+		}
+		var err error
+		combinedPSKTs[i], err = pskt.Combine(toCombine...)
+		if err != nil {
+			return err
+		}
+	}
+
+	encoded, err := encodePSKTsToText(combinedPSKTs)
+	if err != nil {
+		return err
+	}
+	fmt.Println(encoded)
+	return nil
+}
+
+func psktFinalize(conf *psktFinalizeConfig) error {
+	text, err := readTransactionsText(conf.Transaction, conf.TransactionFile)
+	if err != nil {
+		return err
+	}
+	pskts, err := decodePSKTs(text)
+	if err != nil {
+		return err
+	}
+
+	transactions := make([][]byte, len(pskts))
+	for i, p := range pskts {
+		tx, err := pskt.Finalize(p)
+		if err != nil {
+			return errors.Wrapf(err, "Could not finalize transaction #%d", i+1)
+		}
+		transactions[i], err = serialization.SerializeDomainTransaction(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "The transaction is finalized and ready to broadcast with --finalized")
+	fmt.Println(encodeTransactionsToHex(transactions))
+	return nil
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
+	"github.com/kaspikr/kaspid/domain/dagconfig"
+	"github.com/kaspikr/kaspid/util"
 	"github.com/pkg/errors"
 )
 
@@ -20,11 +23,15 @@ func send(conf *sendConfig) error {
 		return err
 	}
 
+	if keysFile.IsWatchOnly() {
+		return errors.Errorf("Cannot use 'send' command for a watch-only wallet. " +
+			"Use 'create-unsigned-transaction' instead, and sign the transaction where the private keys are")
+	}
 	if len(keysFile.ExtendedPublicKeys) > len(keysFile.EncryptedMnemonics) {
 		return errors.Errorf("Cannot use 'send' command for multisig wallet without all of the keys")
 	}
 
-	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress)
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
 	if err != nil {
 		return err
 	}
@@ -34,30 +41,100 @@ func send(conf *sendConfig) error {
 	defer cancel()
 
 	var sendAmountSompi uint64
-	if !conf.IsSendAll {
-		sendAmountSompi, err = utils.KasToSompi(conf.SendAmount)
+	if conf.SendAmount != "" {
+		sendAmountSompi, err = util.KasToSompi(conf.SendAmount)
 
 		if err != nil {
 			return err
 		}
 	}
+	if isPaymentURI(conf.ToAddress) {
+		err := printPaymentRequest(conf.NetParams(), conf.ToAddress)
+		if err != nil {
+			return err
+		}
+	}
+	outputs, err := parsePaymentOutputs(conf.Outputs, conf.OutputsFile)
+	if err != nil {
+		return err
+	}
+	for _, output := range outputs {
+		if isPaymentURI(output.Address) {
+			err := printPaymentRequest(conf.NetParams(), output.Address)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	selectedUTXOs, err := parseOutpoints(conf.UTXOs)
+	if err != nil {
+		return err
+	}
+	var maxFeeSompi uint64
+	if conf.MaxFee != "" {
+		maxFeeSompi, err = util.KasToSompi(conf.MaxFee)
+		if err != nil {
+			return err
+		}
+	}
 
 	createUnsignedTransactionsResponse, err :=
 		daemonClient.CreateUnsignedTransactions(ctx, &pb.CreateUnsignedTransactionsRequest{
+			Account:                  conf.Account,
 			From:                     conf.FromAddresses,
 			Address:                  conf.ToAddress,
 			Amount:                   sendAmountSompi,
+			Outputs:                  outputs,
 			IsSendAll:                conf.IsSendAll,
 			UseExistingChangeAddress: conf.UseExistingChangeAddress,
+			FeeRate:                  conf.FeeRate,
+			MaxFee:                   maxFeeSompi,
+			SubtractFeeFromAmount:    conf.SubtractFeeFromAmount,
+			Utxos:                    selectedUTXOs,
 		})
 	if err != nil {
 		return err
 	}
 
-	if len(conf.Password) == 0 {
-		conf.Password = keys.GetPassword("Password:")
+	fmt.Printf("Sending with a fee of %s KAS for a mass of %d grams\n",
+		strings.TrimSpace(utils.FormatKas(createUnsignedTransactionsResponse.Fee)), createUnsignedTransactionsResponse.Mass)
+
+	return signAndBroadcast(conf.NetParams(), keysFile, conf.Password, daemonClient,
+		createUnsignedTransactionsResponse.UnsignedTransactions, conf.Verbose)
+}
+
+// printPaymentRequest shows what a payment URI requests, so the payer can tell who they pay and for what
+func printPaymentRequest(params *dagconfig.Params, uri string) error {
+	paymentURI, err := util.ParsePaymentURI(uri, params.Prefix)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Paying the payment request to %s\n", paymentURI.Address)
+	if paymentURI.Amount != 0 {
+		fmt.Printf("Requested amount: %s KAS\n", strings.TrimSpace(utils.FormatKas(paymentURI.Amount)))
+	}
+	if paymentURI.Label != "" {
+		fmt.Printf("Label: %s\n", paymentURI.Label)
+	}
+	if paymentURI.Message != "" {
+		fmt.Printf("Message: %s\n", paymentURI.Message)
+	}
+	if !paymentURI.Expires.IsZero() {
+		fmt.Printf("Expires: %s\n", paymentURI.Expires.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// signAndBroadcast signs the given unsigned transactions with the keys of keysFile, asking for
+// the password if it's not given, and broadcasts them through the wallet daemon
+func signAndBroadcast(params *dagconfig.Params, keysFile *keys.File, password string,
+	daemonClient pb.KaspiwalletdClient, unsignedTransactions [][]byte, showSerialized bool) error {
+
+	if len(password) == 0 {
+		password = keys.GetPassword("Password:")
 	}
-	mnemonics, err := keysFile.DecryptMnemonics(conf.Password)
+	mnemonics, passphrases, err := keysFile.DecryptMnemonics(password)
 	if err != nil {
 		if strings.Contains(err.Error(), "message authentication failed") {
 			fmt.Fprintf(os.Stderr, "Password decryption failed. Sometimes this is a result of not "+
@@ -66,9 +143,10 @@ func send(conf *sendConfig) error {
 		return err
 	}
 
-	signedTransactions := make([][]byte, len(createUnsignedTransactionsResponse.UnsignedTransactions))
-	for i, unsignedTransaction := range createUnsignedTransactionsResponse.UnsignedTransactions {
-		signedTransaction, err := libkaspiwallet.Sign(conf.NetParams(), mnemonics, unsignedTransaction, keysFile.ECDSA)
+	signedTransactions := make([][]byte, len(unsignedTransactions))
+	for i, unsignedTransaction := range unsignedTransactions {
+		signedTransaction, err := libkaspiwallet.Sign(params, mnemonics, passphrases, unsignedTransaction,
+			keysFile.ECDSA)
 		if err != nil {
 			return err
 		}
@@ -100,7 +178,7 @@ func send(conf *sendConfig) error {
 		}
 	}
 
-	if conf.Verbose {
+	if showSerialized {
 		fmt.Println("Serialized Transaction(s) (can be parsed via the `parse` command or resent via `broadcast`): ")
 		for _, signedTx := range signedTransactions {
 			fmt.Printf("\t%x\n\n", signedTx)
@@ -8,7 +8,7 @@ import (
 )
 
 func showAddresses(conf *showAddressesConfig) error {
-	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress)
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
 	if err != nil {
 		return err
 	}
@@ -17,13 +17,17 @@ func showAddresses(conf *showAddressesConfig) error {
 	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
 	defer cancel()
 
-	response, err := daemonClient.ShowAddresses(ctx, &pb.ShowAddressesRequest{})
+	response, err := daemonClient.ShowAddresses(ctx, &pb.ShowAddressesRequest{Account: conf.Account})
 	if err != nil {
 		return err
 	}
 
 	fmt.Printf("Addresses (%d):\n", len(response.Address))
-	for _, address := range response.Address {
+	for i, address := range response.Address {
+		if i < len(response.Labels) && response.Labels[i] != "" {
+			fmt.Printf("%s %s\n", address, response.Labels[i])
+			continue
+		}
 		fmt.Println(address)
 	}
 
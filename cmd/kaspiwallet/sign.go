@@ -8,6 +8,8 @@ import (
 
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
 	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/pskt"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/libkaspiwallet/serialization"
 	"github.com/pkg/errors"
 )
 
@@ -24,10 +26,14 @@ func sign(conf *signConfig) error {
 		return err
 	}
 
+	if keysFile.IsWatchOnly() {
+		return errors.Errorf("Cannot sign with a watch-only wallet, since it has no private keys")
+	}
+
 	if len(conf.Password) == 0 {
 		conf.Password = keys.GetPassword("Password:")
 	}
-	privateKeys, err := keysFile.DecryptMnemonics(conf.Password)
+	privateKeys, passphrases, err := keysFile.DecryptMnemonics(conf.Password)
 	if err != nil {
 		return err
 	}
@@ -40,6 +46,10 @@ func sign(conf *signConfig) error {
 		}
 		transactionsHex = strings.TrimSpace(string(transactionHexBytes))
 	}
+	if isPSKTText(transactionsHex) {
+		return signPSKTs(conf, keysFile, privateKeys, passphrases, transactionsHex)
+	}
+
 	partiallySignedTransactions, err := decodeTransactionsFromHex(transactionsHex)
 	if err != nil {
 		return err
@@ -48,7 +58,8 @@ func sign(conf *signConfig) error {
 	updatedPartiallySignedTransactions := make([][]byte, len(partiallySignedTransactions))
 	for i, partiallySignedTransaction := range partiallySignedTransactions {
 		updatedPartiallySignedTransactions[i], err =
-			libkaspiwallet.Sign(conf.NetParams(), privateKeys, partiallySignedTransaction, keysFile.ECDSA)
+			libkaspiwallet.Sign(conf.NetParams(), privateKeys, passphrases, partiallySignedTransaction,
+				keysFile.ECDSA)
 		if err != nil {
 			return err
 		}
@@ -75,3 +86,57 @@ func sign(conf *signConfig) error {
 	fmt.Println(encodeTransactionsToHex(updatedPartiallySignedTransactions))
 	return nil
 }
+
+// signPSKTs signs the given PSKTs, and prints them back as PSKTs, so the metadata kaspiwallet
+// doesn't use itself is kept for the other signers
+func signPSKTs(conf *signConfig, keysFile *keys.File, privateKeys []string, passphrases []string, text string) error {
+	pskts, err := decodePSKTs(text)
+	if err != nil {
+		return err
+	}
+
+	areAllTransactionsFinalizable := true
+	for i, p := range pskts {
+		walletTransaction, err := pskt.ToWalletTransaction(p)
+		if err != nil {
+			return err
+		}
+		partiallySignedTransaction, err := serialization.SerializePartiallySignedTransaction(walletTransaction)
+		if err != nil {
+			return err
+		}
+		signedPartiallySignedTransaction, err := libkaspiwallet.Sign(conf.NetParams(), privateKeys,
+			passphrases, partiallySignedTransaction, keysFile.ECDSA)
+		if err != nil {
+			return err
+		}
+		signedWalletTransaction, err := serialization.DeserializePartiallySignedTransaction(signedPartiallySignedTransaction)
+		if err != nil {
+			return err
+		}
+		signed, err := pskt.FromWalletTransaction(signedWalletTransaction)
+		if err != nil {
+			return err
+		}
+		pskts[i], err = pskt.Combine(p, signed)
+		if err != nil {
+			return err
+		}
+		if !pskts[i].IsFinalizable() {
+			areAllTransactionsFinalizable = false
+		}
+	}
+
+	if areAllTransactionsFinalizable {
+		fmt.Fprintln(os.Stderr, "The transaction is signed and ready to be finalized")
+	} else {
+		fmt.Fprintln(os.Stderr, "Successfully signed transaction")
+	}
+
+	encoded, err := encodePSKTsToText(pskts)
+	if err != nil {
+		return err
+	}
+	fmt.Println(encoded)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+)
+
+func signMessage(conf *signMessageConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	if len(conf.Password) == 0 {
+		conf.Password = keys.GetPassword("Password:")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.SignMessage(ctx, &pb.SignMessageRequest{
+		Address:  conf.Address,
+		Message:  conf.Message,
+		Password: conf.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(hex.EncodeToString(response.Signature))
+	return nil
+}
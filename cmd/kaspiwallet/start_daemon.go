@@ -3,5 +3,11 @@ package main
 import "github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/server"
 
 func startDaemon(conf *startDaemonConfig) error {
-	return server.Start(conf.NetParams(), conf.Listen, conf.RPCServer, conf.KeysFile, conf.Profile, conf.Timeout)
+	return server.Start(conf.NetParams(), conf.Listen, conf.HTTPListen, conf.RPCServer, conf.KeysFile, conf.Profile,
+		conf.Timeout, &server.SecurityConfig{
+			TLSCertFile:   conf.TLSCert,
+			TLSKeyFile:    conf.TLSKey,
+			ClientCAFile:  conf.TLSClientCA,
+			AuthTokenFile: conf.AuthTokenFile,
+		})
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+func timeLock(conf *timeLockConfig) error {
+	keysFile, err := keys.ReadKeysFile(conf.NetParams(), conf.KeysFile)
+	if err != nil {
+		return err
+	}
+	if keysFile.IsWatchOnly() || len(keysFile.ExtendedPublicKeys) > 1 {
+		return errors.Errorf("Cannot lock funds with a watch-only or multisig wallet, since only single signer " +
+			"wallets support time-locked addresses")
+	}
+
+	sendAmountSompi, err := util.KasToSompi(conf.SendAmount)
+	if err != nil {
+		return err
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	newTimeLockedAddressResponse, err := daemonClient.NewTimeLockedAddress(ctx, &pb.NewTimeLockedAddressRequest{
+		Account:      conf.Account,
+		LockDaaScore: conf.LockDAAScore,
+		LockDuration: uint64(conf.LockDuration.Milliseconds()),
+	})
+	if err != nil {
+		return err
+	}
+
+	createUnsignedTransactionsResponse, err :=
+		daemonClient.CreateUnsignedTransactions(ctx, &pb.CreateUnsignedTransactionsRequest{
+			Account: conf.Account,
+			Address: newTimeLockedAddressResponse.Address,
+			Amount:  sendAmountSompi,
+			FeeRate: conf.FeeRate,
+		})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Locking %s KAS in %s until DAA score %d, with a fee of %s KAS\n",
+		strings.TrimSpace(utils.FormatKas(sendAmountSompi)), newTimeLockedAddressResponse.Address,
+		newTimeLockedAddressResponse.LockDaaScore,
+		strings.TrimSpace(utils.FormatKas(createUnsignedTransactionsResponse.Fee)))
+
+	return signAndBroadcast(conf.NetParams(), keysFile, conf.Password, daemonClient,
+		createUnsignedTransactionsResponse.UnsignedTransactions, false)
+}
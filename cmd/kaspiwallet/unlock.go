@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+)
+
+func unlock(conf *unlockConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	if len(conf.Password) == 0 {
+		conf.Password = keys.GetPassword("Password:")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.UnlockWallet(ctx, &pb.UnlockWalletRequest{
+		Password: conf.Password,
+		Timeout:  uint32(conf.Timeout / time.Second),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("The wallet is unlocked until %s\n", time.UnixMilli(response.UnlockedUntil).Format(time.RFC3339))
+	return nil
+}
+
+func lock(conf *lockConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	_, err = daemonClient.LockWallet(ctx, &pb.LockWalletRequest{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("The wallet is locked")
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/keys"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
+	"github.com/pkg/errors"
+)
+
+func utxos(conf *utxosConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	response, err := daemonClient.GetUtxos(ctx, &pb.GetUtxosRequest{Addresses: conf.Addresses, Account: conf.Account})
+	if err != nil {
+		return err
+	}
+
+	println("UTXO                                                                   Amount, KAS     Status    Address")
+	println("---------------------------------------------------------------------------------------------------------")
+	for _, utxo := range response.Utxos {
+		fmt.Printf("%-70s %s %-9s %s\n", formatOutpoint(utxo.Outpoint), utils.FormatKas(utxo.Amount),
+			formatUTXOStatus(utxo), utxo.Address)
+		if utxo.Label != "" {
+			fmt.Printf("    Lock label: %s\n", utxo.Label)
+		}
+		if utxo.OutpointLabel != "" {
+			fmt.Printf("    Label: %s\n", utxo.OutpointLabel)
+		}
+		if utxo.AddressLabel != "" {
+			fmt.Printf("    Address label: %s\n", utxo.AddressLabel)
+		}
+		if utxo.Account != keys.DefaultAccountName {
+			fmt.Printf("    Account: %s\n", utxo.Account)
+		}
+	}
+	return nil
+}
+
+func lockUTXOs(conf *lockUTXOsConfig) error {
+	outpoints, err := parseOutpoints(conf.UTXOs)
+	if err != nil {
+		return err
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	_, err = daemonClient.LockUtxos(ctx, &pb.LockUtxosRequest{Outpoints: outpoints, Label: conf.Label})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Locked %d UTXO(s)\n", len(outpoints))
+	return nil
+}
+
+func unlockUTXOs(conf *unlockUTXOsConfig) error {
+	outpoints, err := parseOutpoints(conf.UTXOs)
+	if err != nil {
+		return err
+	}
+
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	_, err = daemonClient.UnlockUtxos(ctx, &pb.UnlockUtxosRequest{Outpoints: outpoints})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Unlocked %d UTXO(s)\n", len(outpoints))
+	return nil
+}
+
+// parseOutpoints parses outpoints in the form <transaction ID>:<index>
+func parseOutpoints(outpointStrings []string) ([]*pb.Outpoint, error) {
+	outpoints := make([]*pb.Outpoint, len(outpointStrings))
+	for i, outpointString := range outpointStrings {
+		parts := strings.Split(outpointString, ":")
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid UTXO %s, expected <transaction ID>:<index>", outpointString)
+		}
+		index, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid index in UTXO %s", outpointString)
+		}
+		outpoints[i] = &pb.Outpoint{TransactionId: parts[0], Index: uint32(index)}
+	}
+	return outpoints, nil
+}
+
+func formatOutpoint(outpoint *pb.Outpoint) string {
+	return fmt.Sprintf("%s:%d", outpoint.TransactionId, outpoint.Index)
+}
+
+func formatUTXOStatus(utxo *pb.WalletUtxo) string {
+	switch {
+	case utxo.IsLocked:
+		return "locked"
+	case utxo.IsPending:
+		return "pending"
+	case !utxo.IsSpendable:
+		return "immature"
+	default:
+		return "available"
+	}
+}
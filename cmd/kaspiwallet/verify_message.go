@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+)
+
+// verifyMessage verifies the signature locally, since it requires nothing but the address
+func verifyMessage(conf *verifyMessageConfig) error {
+	address, err := util.DecodeAddress(conf.Address, conf.ActiveNetParams.Prefix)
+	if err != nil {
+		return err
+	}
+	signature, err := hex.DecodeString(conf.Signature)
+	if err != nil {
+		return errors.Wrap(err, "The signature is expected to be encoded in hex")
+	}
+
+	isValid, err := util.VerifyMessage(address, conf.Message, signature)
+	if err != nil {
+		return err
+	}
+	if !isValid {
+		return errors.Errorf("The signature is not a valid signature of the message by %s", conf.Address)
+	}
+
+	fmt.Printf("The signature is a valid signature of the message by %s\n", conf.Address)
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/client"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/daemon/pb"
+	"github.com/kaspikr/kaspid/cmd/kaspiwallet/utils"
+	"github.com/kaspikr/kaspid/infrastructure/os/signal"
+)
+
+func watch(conf *watchConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress, conf.connectOptions())
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupt := signal.InterruptListener()
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	stream, err := daemonClient.SubscribeWalletChanges(ctx, &pb.SubscribeWalletChangesRequest{Account: conf.Account})
+	if err != nil {
+		return err
+	}
+
+	isFirstNotification := true
+	for {
+		notification, err := stream.Recv()
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if isFirstNotification {
+			fmt.Printf("The wallet has %d UTXO(s)\n", len(notification.AddedUtxos))
+			isFirstNotification = false
+		} else {
+			for _, utxo := range notification.AddedUtxos {
+				fmt.Printf("+ %-70s %s %s\n", formatOutpoint(utxo.Outpoint), utils.FormatKas(utxo.Amount), utxo.Address)
+			}
+			for _, outpoint := range notification.RemovedUtxos {
+				fmt.Printf("- %s\n", formatOutpoint(outpoint))
+			}
+		}
+		fmt.Printf("Balance: %s KAS available, %s KAS pending\n",
+			utils.FormatKas(notification.Available), utils.FormatKas(notification.Pending))
+	}
+}
@@ -16,6 +16,9 @@ const (
 	proofOfWorkDomain             = "ProofOfWorkHash"
 	heavyHashDomain               = "HeavyHash"
 	merkleBranchDomain            = "MerkleBranchHash"
+	compactBlockShortIDDomain     = "CompactBlockShortID"
+	txReconciliationShortIDDomain = "TransactionReconciliationShortID"
+	personalMessageSigningDomain  = "PersonalMessageSigningHash"
 )
 
 // transactionSigningECDSADomainHash is a hashed version of transcationSigningECDSADomain that is used
@@ -86,3 +89,32 @@ func NewMerkleBranchHashWriter() HashWriter {
 	}
 	return HashWriter{blake}
 }
+
+// NewCompactBlockShortIDWriter Returns a new HashWriter used for the short transaction IDs of compact blocks
+func NewCompactBlockShortIDWriter() HashWriter {
+	blake, err := blake2b.New256([]byte(compactBlockShortIDDomain))
+	if err != nil {
+		panic(errors.Wrapf(err, "this should never happen. %s is less than 64 bytes", compactBlockShortIDDomain))
+	}
+	return HashWriter{blake}
+}
+
+// NewTransactionReconciliationShortIDWriter Returns a new HashWriter used for the short transaction IDs
+// of transaction reconciliation sketches
+func NewTransactionReconciliationShortIDWriter() HashWriter {
+	blake, err := blake2b.New256([]byte(txReconciliationShortIDDomain))
+	if err != nil {
+		panic(errors.Wrapf(err, "this should never happen. %s is less than 64 bytes", txReconciliationShortIDDomain))
+	}
+	return HashWriter{blake}
+}
+
+// NewPersonalMessageSigningHashWriter Returns a new HashWriter used for signing on a message, which
+// can't be mistaken for a transaction, since it's hashed with a different domain
+func NewPersonalMessageSigningHashWriter() HashWriter {
+	blake, err := blake2b.New256([]byte(personalMessageSigningDomain))
+	if err != nil {
+		panic(errors.Wrapf(err, "this should never happen. %s is less than 64 bytes", personalMessageSigningDomain))
+	}
+	return HashWriter{blake}
+}
@@ -5,6 +5,8 @@
 package txscript
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
@@ -441,3 +443,250 @@ func ExtractAtomicSwapDataPushes(version uint16, scriptPubKey []byte) (*AtomicSw
 	}
 	return pushes, nil
 }
+
+// HTLCSecretSize is the size of the secret of a hash time-locked contract. The contract locks
+// the SHA256 hash of the secret, so that contracts on other chains can be locked with the same hash.
+const HTLCSecretSize = 32
+
+// HTLCDataPushes houses the data pushes found in a hash time-locked contract.
+type HTLCDataPushes struct {
+	SecretHash         [32]byte
+	RecipientPublicKey []byte
+	RefundPublicKey    []byte
+	LockTime           uint64
+	ECDSA              bool
+}
+
+// HTLCScript returns a hash time-locked contract, which can be redeemed by the owner of the
+// recipient public key by revealing a secret whose SHA256 hash is secretHash, or refunded by the
+// owner of the refund public key once the lock time of the spending transaction reaches lockTime.
+// Like the lock time of a transaction, lockTime is a DAA score if it's below constants.LockTimeThreshold,
+// and a timestamp in milliseconds otherwise. The contract is of the form:
+//
+//	OP_IF
+//		OP_SIZE <secret size> OP_EQUALVERIFY OP_SHA256 <secret hash> OP_EQUALVERIFY <recipient public key>
+//	OP_ELSE
+//		<lock time> OP_CHECKLOCKTIMEVERIFY <refund public key>
+//	OP_ENDIF
+//	OP_CHECKSIG
+//
+// where the final opcode is OP_CHECKSIGECDSA for ECDSA public keys.
+//
+// NOTE: Hash time-locked contracts aren't standard script public keys, and should be paid to
+// with P2SH.
+func HTLCScript(secretHash []byte, recipientPublicKey []byte, refundPublicKey []byte, lockTime uint64,
+	ecdsa bool) ([]byte, error) {
+
+	if len(secretHash) != 32 {
+		return nil, errors.Errorf("the secret hash must be 32 bytes, but it's %d bytes", len(secretHash))
+	}
+	publicKeySize, checkSigOpcode := util.PublicKeySize, byte(OpCheckSig)
+	if ecdsa {
+		publicKeySize, checkSigOpcode = util.PublicKeySizeECDSA, OpCheckSigECDSA
+	}
+	if len(recipientPublicKey) != publicKeySize || len(refundPublicKey) != publicKeySize {
+		return nil, errors.Errorf("the public keys of the contract must be %d bytes", publicKeySize)
+	}
+	if lockTime == 0 {
+		return nil, errors.New("the lock time of the contract must not be 0")
+	}
+
+	return NewScriptBuilder().
+		AddOp(OpIf).
+		AddOp(OpSize).AddInt64(HTLCSecretSize).AddOp(OpEqualVerify).
+		AddOp(OpSHA256).AddData(secretHash).AddOp(OpEqualVerify).
+		AddData(recipientPublicKey).
+		AddOp(OpElse).
+		AddLockTimeNumber(lockTime).AddOp(OpCheckLockTimeVerify).
+		AddData(refundPublicKey).
+		AddOp(OpEndIf).
+		AddOp(checkSigOpcode).
+		Script()
+}
+
+// HTLCRedeemSignatureScript returns the signature script that redeems the P2SH output of the
+// given hash time-locked contract with the signature of the recipient and the secret.
+func HTLCRedeemSignatureScript(contract []byte, signature []byte, secret []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddData(signature).
+		AddData(secret).
+		AddInt64(1).
+		AddData(contract).
+		Script()
+}
+
+// HTLCRefundSignatureScript returns the signature script that refunds the P2SH output of the
+// given hash time-locked contract with the signature of the refund key.
+func HTLCRefundSignatureScript(contract []byte, signature []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddData(signature).
+		AddInt64(0).
+		AddData(contract).
+		Script()
+}
+
+// ExtractHTLCDataPushes returns the data pushes from a hash time-locked contract as built by
+// HTLCScript. If the script is not such a contract, ExtractHTLCDataPushes returns (nil, nil).
+// Non-nil errors are returned for unparsable scripts.
+func ExtractHTLCDataPushes(script []byte) (*HTLCDataPushes, error) {
+	pops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pops) != 14 {
+		return nil, nil
+	}
+	isHTLC := pops[0].opcode.value == OpIf &&
+		pops[1].opcode.value == OpSize &&
+		pops[2].opcode.value == OpData1 && len(pops[2].data) == 1 && pops[2].data[0] == HTLCSecretSize &&
+		pops[3].opcode.value == OpEqualVerify &&
+		pops[4].opcode.value == OpSHA256 &&
+		pops[5].opcode.value == OpData32 &&
+		pops[6].opcode.value == OpEqualVerify &&
+		pops[8].opcode.value == OpElse &&
+		canonicalPush(pops[9]) &&
+		pops[10].opcode.value == OpCheckLockTimeVerify &&
+		pops[12].opcode.value == OpEndIf
+	if !isHTLC {
+		return nil, nil
+	}
+
+	pushes := &HTLCDataPushes{
+		RecipientPublicKey: pops[7].data,
+		RefundPublicKey:    pops[11].data,
+	}
+	copy(pushes.SecretHash[:], pops[5].data)
+	switch {
+	case pops[7].opcode.value == OpData32 && pops[11].opcode.value == OpData32 &&
+		pops[13].opcode.value == OpCheckSig:
+
+		pushes.ECDSA = false
+	case pops[7].opcode.value == OpData33 && pops[11].opcode.value == OpData33 &&
+		pops[13].opcode.value == OpCheckSigECDSA:
+
+		pushes.ECDSA = true
+	default:
+		return nil, nil
+	}
+
+	lockTime, ok := lockTimeFromPush(pops[9])
+	if !ok {
+		return nil, nil
+	}
+	pushes.LockTime = lockTime
+
+	return pushes, nil
+}
+
+// lockTimeFromPush returns the lock time pushed by a canonical push, as pushed by
+// ScriptBuilder.AddLockTimeNumber, and whether it's a valid non-zero lock time.
+func lockTimeFromPush(pop parsedOpcode) (uint64, bool) {
+	lockTimeBytes := pop.data
+	if op := pop.opcode; lockTimeBytes == nil {
+		switch {
+		case op.value >= Op1 && op.value <= Op16:
+			lockTimeBytes = []byte{byte(asSmallInt(op))}
+		case op.value == Op1Negate:
+			// AddData pushes the single byte 0x81 as OP_1NEGATE
+			lockTimeBytes = []byte{0x81}
+		}
+	}
+	if len(lockTimeBytes) == 0 || len(lockTimeBytes) > 8 {
+		return 0, false
+	}
+	paddedLockTimeBytes := make([]byte, 8)
+	copy(paddedLockTimeBytes, lockTimeBytes)
+	return binary.LittleEndian.Uint64(paddedLockTimeBytes), true
+}
+
+// ExtractHTLCSecret returns the secret revealed by a signature script that redeems a hash
+// time-locked contract, along with the contract. If the signature script doesn't redeem
+// such a contract, ExtractHTLCSecret returns (nil, nil, nil).
+func ExtractHTLCSecret(signatureScript []byte) (secret []byte, contract []byte, err error) {
+	pops, err := parseScript(signatureScript)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(pops) != 4 || !isPushOnly(pops) || pops[1].opcode.value != OpData32 || pops[2].opcode.value != Op1 {
+		return nil, nil, nil
+	}
+	secret, contract = pops[1].data, pops[3].data
+	pushes, err := ExtractHTLCDataPushes(contract)
+	if err != nil || pushes == nil {
+		return nil, nil, nil
+	}
+	if sha256.Sum256(secret) != pushes.SecretHash {
+		return nil, nil, nil
+	}
+	return secret, contract, nil
+}
+
+// TimeLockDataPushes houses the data pushes found in a time lock script.
+type TimeLockDataPushes struct {
+	PublicKey []byte
+	LockTime  uint64
+	ECDSA     bool
+}
+
+// TimeLockScript returns a script that can be spent by the owner of the public key once the lock
+// time of the spending transaction reaches lockTime. Like the lock time of a transaction, lockTime
+// is a DAA score if it's below constants.LockTimeThreshold, and a timestamp in milliseconds otherwise.
+// The script is of the form:
+//
+//	<lock time> OP_CHECKLOCKTIMEVERIFY <public key> OP_CHECKSIG
+//
+// where the final opcode is OP_CHECKSIGECDSA for an ECDSA public key.
+//
+// NOTE: Time lock scripts aren't standard script public keys, and should be paid to with P2SH.
+func TimeLockScript(publicKey []byte, lockTime uint64, ecdsa bool) ([]byte, error) {
+	publicKeySize, checkSigOpcode := util.PublicKeySize, byte(OpCheckSig)
+	if ecdsa {
+		publicKeySize, checkSigOpcode = util.PublicKeySizeECDSA, OpCheckSigECDSA
+	}
+	if len(publicKey) != publicKeySize {
+		return nil, errors.Errorf("the public key must be %d bytes, but it's %d bytes", publicKeySize, len(publicKey))
+	}
+	if lockTime == 0 {
+		return nil, errors.New("the lock time must not be 0")
+	}
+
+	return NewScriptBuilder().
+		AddLockTimeNumber(lockTime).AddOp(OpCheckLockTimeVerify).
+		AddData(publicKey).
+		AddOp(checkSigOpcode).
+		Script()
+}
+
+// ExtractTimeLockDataPushes returns the data pushes from a time lock script as built by
+// TimeLockScript. If the script is not such a script, ExtractTimeLockDataPushes returns (nil, nil).
+// Non-nil errors are returned for unparsable scripts.
+func ExtractTimeLockDataPushes(script []byte) (*TimeLockDataPushes, error) {
+	pops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pops) != 4 || !canonicalPush(pops[0]) || pops[1].opcode.value != OpCheckLockTimeVerify {
+		return nil, nil
+	}
+	lockTime, ok := lockTimeFromPush(pops[0])
+	if !ok {
+		return nil, nil
+	}
+
+	pushes := &TimeLockDataPushes{
+		PublicKey: pops[2].data,
+		LockTime:  lockTime,
+	}
+	switch {
+	case pops[2].opcode.value == OpData32 && pops[3].opcode.value == OpCheckSig:
+		pushes.ECDSA = false
+	case pops[2].opcode.value == OpData33 && pops[3].opcode.value == OpCheckSigECDSA:
+		pushes.ECDSA = true
+	default:
+		return nil, nil
+	}
+	return pushes, nil
+}
@@ -6,10 +6,16 @@ package txscript
 
 import (
 	"bytes"
-	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"crypto/sha256"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/kaspikr/go-secp256k1"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
 	"github.com/kaspikr/kaspid/domain/dagconfig"
 	"github.com/kaspikr/kaspid/util"
 )
@@ -566,3 +572,276 @@ func TestStringifyClass(t *testing.T) {
 		}
 	}
 }
+
+func TestHTLC(t *testing.T) {
+	recipientKey, err := secp256k1.GenerateSchnorrKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateSchnorrKeyPair: %s", err)
+	}
+	refundKey, err := secp256k1.GenerateSchnorrKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateSchnorrKeyPair: %s", err)
+	}
+	serializePublicKey := func(key *secp256k1.SchnorrKeyPair) []byte {
+		publicKey, err := key.SchnorrPublicKey()
+		if err != nil {
+			t.Fatalf("SchnorrPublicKey: %s", err)
+		}
+		serializedPublicKey, err := publicKey.Serialize()
+		if err != nil {
+			t.Fatalf("Serialize: %s", err)
+		}
+		return serializedPublicKey[:]
+	}
+
+	secret := bytes.Repeat([]byte{7}, HTLCSecretSize)
+	secretHash := sha256.Sum256(secret)
+	const lockTime = 1_700_000_000_000
+	contract, err := HTLCScript(secretHash[:], serializePublicKey(recipientKey), serializePublicKey(refundKey), lockTime, false)
+	if err != nil {
+		t.Fatalf("HTLCScript: %s", err)
+	}
+
+	pushes, err := ExtractHTLCDataPushes(contract)
+	if err != nil {
+		t.Fatalf("ExtractHTLCDataPushes: %s", err)
+	}
+	if pushes == nil || pushes.SecretHash != secretHash || pushes.LockTime != lockTime || pushes.ECDSA ||
+		!bytes.Equal(pushes.RecipientPublicKey, serializePublicKey(recipientKey)) ||
+		!bytes.Equal(pushes.RefundPublicKey, serializePublicKey(refundKey)) {
+
+		t.Fatalf("unexpected data pushes %+v", pushes)
+	}
+	// Lock times that fit in a single byte are pushed by small integer opcodes rather than as data
+	for _, lockTime := range []uint64{1, 16, 17, 128, 129, 255, 256} {
+		contract, err := HTLCScript(secretHash[:], serializePublicKey(recipientKey), serializePublicKey(refundKey),
+			lockTime, false)
+		if err != nil {
+			t.Fatalf("HTLCScript: %s", err)
+		}
+		pushes, err := ExtractHTLCDataPushes(contract)
+		if err != nil {
+			t.Fatalf("ExtractHTLCDataPushes: %s", err)
+		}
+		if pushes == nil || pushes.LockTime != lockTime {
+			t.Fatalf("the lock time %d of a hash time-locked contract was extracted as %+v", lockTime, pushes)
+		}
+	}
+	pushes, err = ExtractHTLCDataPushes(mustParseShortForm("DATA_32 0x"+strings.Repeat("00", 32)+" CHECKSIG", 0))
+	if err != nil || pushes != nil {
+		t.Fatalf("a pay-to-pubkey script was recognized as a hash time-locked contract")
+	}
+
+	contractScriptPubKey, err := PayToScriptHashScript(contract)
+	if err != nil {
+		t.Fatalf("PayToScriptHashScript: %s", err)
+	}
+	newTransaction := func(txLockTime uint64) *externalapi.DomainTransaction {
+		return &externalapi.DomainTransaction{
+			Inputs: []*externalapi.DomainTransactionInput{{
+				UTXOEntry: utxo.NewUTXOEntry(500, &externalapi.ScriptPublicKey{Script: contractScriptPubKey}, false, 100),
+			}},
+			Outputs:  []*externalapi.DomainTransactionOutput{{Value: 400, ScriptPublicKey: &externalapi.ScriptPublicKey{}}},
+			LockTime: txLockTime,
+		}
+	}
+	redeemSignatureScript := func(tx *externalapi.DomainTransaction, key *secp256k1.SchnorrKeyPair, secret []byte) []byte {
+		signature, err := RawTxInSignature(tx, 0, consensushashing.SigHashAll, key, &consensushashing.SighashReusedValues{})
+		if err != nil {
+			t.Fatalf("RawTxInSignature: %s", err)
+		}
+		signatureScript, err := HTLCRedeemSignatureScript(contract, signature, secret)
+		if err != nil {
+			t.Fatalf("HTLCRedeemSignatureScript: %s", err)
+		}
+		return signatureScript
+	}
+	refundSignatureScript := func(tx *externalapi.DomainTransaction, key *secp256k1.SchnorrKeyPair) []byte {
+		signature, err := RawTxInSignature(tx, 0, consensushashing.SigHashAll, key, &consensushashing.SighashReusedValues{})
+		if err != nil {
+			t.Fatalf("RawTxInSignature: %s", err)
+		}
+		signatureScript, err := HTLCRefundSignatureScript(contract, signature)
+		if err != nil {
+			t.Fatalf("HTLCRefundSignatureScript: %s", err)
+		}
+		return signatureScript
+	}
+
+	tests := []struct {
+		name            string
+		tx              *externalapi.DomainTransaction
+		signatureScript func(tx *externalapi.DomainTransaction) []byte
+		isValid         bool
+	}{
+		{
+			name: "redeem",
+			tx:   newTransaction(0),
+			signatureScript: func(tx *externalapi.DomainTransaction) []byte {
+				return redeemSignatureScript(tx, recipientKey, secret)
+			},
+			isValid: true,
+		},
+		{
+			name: "redeem with a wrong secret",
+			tx:   newTransaction(0),
+			signatureScript: func(tx *externalapi.DomainTransaction) []byte {
+				return redeemSignatureScript(tx, recipientKey, bytes.Repeat([]byte{8}, HTLCSecretSize))
+			},
+		},
+		{
+			name: "redeem with the refund key",
+			tx:   newTransaction(0),
+			signatureScript: func(tx *externalapi.DomainTransaction) []byte {
+				return redeemSignatureScript(tx, refundKey, secret)
+			},
+		},
+		{
+			name: "refund",
+			tx:   newTransaction(lockTime),
+			signatureScript: func(tx *externalapi.DomainTransaction) []byte {
+				return refundSignatureScript(tx, refundKey)
+			},
+			isValid: true,
+		},
+		{
+			name: "refund before the lock time",
+			tx:   newTransaction(lockTime - 1),
+			signatureScript: func(tx *externalapi.DomainTransaction) []byte {
+				return refundSignatureScript(tx, refundKey)
+			},
+		},
+		{
+			name: "refund with the recipient key",
+			tx:   newTransaction(lockTime),
+			signatureScript: func(tx *externalapi.DomainTransaction) []byte {
+				return refundSignatureScript(tx, recipientKey)
+			},
+		},
+	}
+	for _, test := range tests {
+		signatureScript := test.signatureScript(test.tx)
+		test.tx.Inputs[0].SignatureScript = signatureScript
+		vm, err := NewEngine(test.tx.Inputs[0].UTXOEntry.ScriptPublicKey(), test.tx, 0, ScriptNoFlags, nil, nil,
+			&consensushashing.SighashReusedValues{})
+		if err != nil {
+			t.Fatalf("%s: NewEngine: %s", test.name, err)
+		}
+		err = vm.Execute()
+		if test.isValid && err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.name, err)
+		}
+		if !test.isValid && err == nil {
+			t.Fatalf("%s: the contract was unexpectedly spent", test.name)
+		}
+
+		extractedSecret, extractedContract, err := ExtractHTLCSecret(signatureScript)
+		if err != nil {
+			t.Fatalf("%s: ExtractHTLCSecret: %s", test.name, err)
+		}
+		// The secret is extracted from any signature script that reveals it, regardless of its signature
+		if test.name == "redeem" || test.name == "redeem with the refund key" {
+			if !bytes.Equal(extractedSecret, secret) || !bytes.Equal(extractedContract, contract) {
+				t.Fatalf("%s: the secret wasn't extracted from the signature script", test.name)
+			}
+		} else if extractedSecret != nil {
+			t.Fatalf("%s: a secret was unexpectedly extracted from the signature script", test.name)
+		}
+	}
+}
+
+func TestTimeLockScript(t *testing.T) {
+	key, err := secp256k1.GenerateSchnorrKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateSchnorrKeyPair: %s", err)
+	}
+	publicKey, err := key.SchnorrPublicKey()
+	if err != nil {
+		t.Fatalf("SchnorrPublicKey: %s", err)
+	}
+	serializedPublicKey, err := publicKey.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %s", err)
+	}
+
+	const lockTime = 1000
+	script, err := TimeLockScript(serializedPublicKey[:], lockTime, false)
+	if err != nil {
+		t.Fatalf("TimeLockScript: %s", err)
+	}
+	pushes, err := ExtractTimeLockDataPushes(script)
+	if err != nil {
+		t.Fatalf("ExtractTimeLockDataPushes: %s", err)
+	}
+	if pushes == nil || pushes.LockTime != lockTime || pushes.ECDSA || !bytes.Equal(pushes.PublicKey, serializedPublicKey[:]) {
+		t.Fatalf("unexpected data pushes %+v", pushes)
+	}
+	for _, lockTime := range []uint64{1, 16, 17, 128, 129, 255, 256} {
+		script, err := TimeLockScript(serializedPublicKey[:], lockTime, false)
+		if err != nil {
+			t.Fatalf("TimeLockScript: %s", err)
+		}
+		pushes, err := ExtractTimeLockDataPushes(script)
+		if err != nil {
+			t.Fatalf("ExtractTimeLockDataPushes: %s", err)
+		}
+		if pushes == nil || pushes.LockTime != lockTime {
+			t.Fatalf("the lock time %d of a time lock script was extracted as %+v", lockTime, pushes)
+		}
+	}
+	pushes, err = ExtractTimeLockDataPushes(mustParseShortForm("DATA_32 0x"+strings.Repeat("00", 32)+" CHECKSIG", 0))
+	if err != nil || pushes != nil {
+		t.Fatalf("a pay-to-pubkey script was recognized as a time lock script")
+	}
+	_, err = TimeLockScript(serializedPublicKey[:], 0, false)
+	if err == nil {
+		t.Fatalf("a time lock script was created with a zero lock time")
+	}
+
+	scriptPubKey, err := PayToScriptHashScript(script)
+	if err != nil {
+		t.Fatalf("PayToScriptHashScript: %s", err)
+	}
+	tests := []struct {
+		name       string
+		txLockTime uint64
+		sequence   uint64
+		isValid    bool
+	}{
+		{name: "at the lock time", txLockTime: lockTime, isValid: true},
+		{name: "after the lock time", txLockTime: lockTime + 1, isValid: true},
+		{name: "before the lock time", txLockTime: lockTime - 1},
+		{name: "with a timestamp lock time", txLockTime: 1_700_000_000_000},
+		{name: "with a finalized input", txLockTime: lockTime, sequence: constants.MaxTxInSequenceNum},
+	}
+	for _, test := range tests {
+		tx := &externalapi.DomainTransaction{
+			Inputs: []*externalapi.DomainTransactionInput{{
+				UTXOEntry: utxo.NewUTXOEntry(500, &externalapi.ScriptPublicKey{Script: scriptPubKey}, false, 100),
+				Sequence:  test.sequence,
+			}},
+			Outputs:  []*externalapi.DomainTransactionOutput{{Value: 400, ScriptPublicKey: &externalapi.ScriptPublicKey{}}},
+			LockTime: test.txLockTime,
+		}
+		signature, err := RawTxInSignature(tx, 0, consensushashing.SigHashAll, key, &consensushashing.SighashReusedValues{})
+		if err != nil {
+			t.Fatalf("%s: RawTxInSignature: %s", test.name, err)
+		}
+		tx.Inputs[0].SignatureScript, err = NewScriptBuilder().AddData(signature).AddData(script).Script()
+		if err != nil {
+			t.Fatalf("%s: Script: %s", test.name, err)
+		}
+
+		vm, err := NewEngine(tx.Inputs[0].UTXOEntry.ScriptPublicKey(), tx, 0, ScriptNoFlags, nil, nil, &consensushashing.SighashReusedValues{})
+		if err != nil {
+			t.Fatalf("%s: NewEngine: %s", test.name, err)
+		}
+		err = vm.Execute()
+		if test.isValid && err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.name, err)
+		}
+		if !test.isValid && err == nil {
+			t.Fatalf("%s: the time-locked output was unexpectedly spent", test.name)
+		}
+	}
+}
@@ -117,6 +117,44 @@ func (mp *mempool) AllTransactions(includeTransactionPool bool, includeOrphanPoo
 	return transactionPoolTransactions, orphanPoolTransactions
 }
 
+func (mp *mempool) GetTransactionsByShortIDs(shortIDs []uint64,
+	shortID func(transaction *externalapi.DomainTransaction) uint64,
+	includeTransactionPool bool, includeOrphanPool bool) map[uint64]*externalapi.DomainTransaction {
+
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	requestedShortIDs := make(map[uint64]struct{}, len(shortIDs))
+	for _, requestedShortID := range shortIDs {
+		requestedShortIDs[requestedShortID] = struct{}{}
+	}
+	transactionsByShortID := make(map[uint64]*externalapi.DomainTransaction)
+	addIfRequested := func(transaction *externalapi.DomainTransaction) {
+		transactionShortID := shortID(transaction)
+		if _, ok := requestedShortIDs[transactionShortID]; !ok {
+			return
+		}
+		if _, ok := transactionsByShortID[transactionShortID]; ok {
+			transactionsByShortID[transactionShortID] = nil
+			return
+		}
+		transactionsByShortID[transactionShortID] = transaction.Clone() //this pointer leaves the mempool, hence we clone.
+	}
+
+	if includeTransactionPool {
+		for _, mempoolTransaction := range mp.transactionsPool.allTransactions {
+			addIfRequested(mempoolTransaction.Transaction())
+		}
+	}
+	if includeOrphanPool {
+		for _, orphanTransaction := range mp.orphansPool.allOrphans {
+			addIfRequested(orphanTransaction.Transaction())
+		}
+	}
+
+	return transactionsByShortID
+}
+
 func (mp *mempool) TransactionCount(includeTransactionPool bool, includeOrphanPool bool) int {
 	mp.mtx.RLock()
 	defer mp.mtx.RUnlock()
@@ -28,6 +28,8 @@ type MiningManager interface {
 	AllTransactions(includeTransactionPool bool, includeOrphanPool bool) (
 		transactionPoolTransactions []*externalapi.DomainTransaction,
 		orphanPoolTransactions []*externalapi.DomainTransaction)
+	GetTransactionsByShortIDs(shortIDs []uint64, shortID func(transaction *externalapi.DomainTransaction) uint64,
+		includeTransactionPool bool, includeOrphanPool bool) map[uint64]*externalapi.DomainTransaction
 	TransactionCount(includeTransactionPool bool, includeOrphanPool bool) int
 	HandleNewBlockTransactions(txs []*externalapi.DomainTransaction) ([]*externalapi.DomainTransaction, error)
 	ValidateAndInsertTransaction(transaction *externalapi.DomainTransaction, isHighPriority bool, allowOrphan bool) (
@@ -145,6 +147,15 @@ func (mm *miningManager) GetTransactionsByAddresses(includeTransactionPool bool,
 	return mm.mempool.GetTransactionsByAddresses(includeTransactionPool, includeOrphanPool)
 }
 
+// GetTransactionsByShortIDs returns the transactions whose short ID, as calculated by the given shortID
+// function, is one of the given short IDs. A short ID that matches more than one transaction is mapped to nil.
+func (mm *miningManager) GetTransactionsByShortIDs(shortIDs []uint64,
+	shortID func(transaction *externalapi.DomainTransaction) uint64,
+	includeTransactionPool bool, includeOrphanPool bool) map[uint64]*externalapi.DomainTransaction {
+
+	return mm.mempool.GetTransactionsByShortIDs(shortIDs, shortID, includeTransactionPool, includeOrphanPool)
+}
+
 func (mm *miningManager) TransactionCount(includeTransactionPool bool, includeOrphanPool bool) int {
 	return mm.mempool.TransactionCount(includeTransactionPool, includeOrphanPool)
 }
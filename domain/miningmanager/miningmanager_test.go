@@ -233,6 +233,53 @@ func TestHandleNewBlockTransactions(t *testing.T) {
 	})
 }
 
+// TestGetTransactionsByShortIDs verifies that only the mempool transactions that match the requested short IDs are
+// returned, and that a short ID that matches more than one transaction is mapped to nil.
+func TestGetTransactionsByShortIDs(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		consensusConfig.BlockCoinbaseMaturity = 0
+		factory := consensus.NewFactory()
+		tc, teardown, err := factory.NewTestConsensus(consensusConfig, "TestGetTransactionsByShortIDs")
+		if err != nil {
+			t.Fatalf("Error setting up TestConsensus: %+v", err)
+		}
+		defer teardown(false)
+
+		miningFactory := miningmanager.NewFactory()
+		tcAsConsensus := tc.(externalapi.Consensus)
+		tcAsConsensusPointer := &tcAsConsensus
+		consensusReference := consensusreference.NewConsensusReference(&tcAsConsensusPointer)
+		miningManager := miningFactory.NewMiningManager(consensusReference, &consensusConfig.Params, mempool.DefaultConfig(&consensusConfig.Params))
+
+		// The second and third transactions share a short ID, and the fourth one isn't requested
+		transactionShortIDs := []uint64{1, 2, 2, 3}
+		shortIDsByTransactionID := make(map[externalapi.DomainTransactionID]uint64)
+		transactionsToInsert := make([]*externalapi.DomainTransaction, len(transactionShortIDs))
+		for i := range transactionsToInsert {
+			transactionsToInsert[i] = createTransactionWithUTXOEntry(t, i, 0)
+			shortIDsByTransactionID[*consensushashing.TransactionID(transactionsToInsert[i])] = transactionShortIDs[i]
+			_, err = miningManager.ValidateAndInsertTransaction(transactionsToInsert[i], false, true)
+			if err != nil {
+				t.Fatalf("ValidateAndInsertTransaction: %v", err)
+			}
+		}
+
+		transactionsByShortID := miningManager.GetTransactionsByShortIDs([]uint64{1, 2, 4},
+			func(transaction *externalapi.DomainTransaction) uint64 {
+				return shortIDsByTransactionID[*consensushashing.TransactionID(transaction)]
+			}, true, true)
+		if len(transactionsByShortID) != 2 {
+			t.Fatalf("Expected 2 short IDs to match mempool transactions, but got %d", len(transactionsByShortID))
+		}
+		if !transactionsByShortID[1].Equal(transactionsToInsert[0]) {
+			t.Fatalf("Unexpected transaction for short ID 1: %s", consensushashing.TransactionID(transactionsByShortID[1]))
+		}
+		if transaction, ok := transactionsByShortID[2]; !ok || transaction != nil {
+			t.Fatalf("Expected the short ID that matches two transactions to be mapped to nil")
+		}
+	})
+}
+
 func domainBlocksToBlockIds(blocks []*externalapi.DomainTransaction) []*externalapi.DomainTransactionID {
 	blockIDs := make([]*externalapi.DomainTransactionID, len(blocks))
 	for i := range blockIDs {
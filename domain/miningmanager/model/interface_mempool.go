@@ -35,6 +35,12 @@ type Mempool interface {
 	) (
 		transactionPoolTransactions []*externalapi.DomainTransaction,
 		orphanPoolTransactions []*externalapi.DomainTransaction)
+	GetTransactionsByShortIDs(
+		shortIDs []uint64,
+		shortID func(transaction *externalapi.DomainTransaction) uint64,
+		includeTransactionPool bool,
+		includeOrphanPool bool,
+	) map[uint64]*externalapi.DomainTransaction
 	TransactionCount(
 		includeTransactionPool bool,
 		includeOrphanPool bool) int
@@ -53,7 +53,15 @@ const (
 	defaultSigCacheMaxSize  = 100_000
 	sampleConfigFilename    = "sample-kaspid.conf"
 	defaultMaxUTXOCacheSize = 5_000_000_000
-	defaultProtocolVersion  = 5
+	defaultProtocolVersion  = 6
+)
+
+// The networks that can be given to --onlynet
+const (
+	OnlyNetIPv4  = "ipv4"
+	OnlyNetIPv6  = "ipv6"
+	OnlyNetOnion = "onion"
+	OnlyNetI2P   = "i2p"
 )
 
 var (
@@ -91,6 +99,9 @@ type Flags struct {
 	BanDuration                     time.Duration `long:"banduration" description:"How long to ban misbehaving peers. Valid time units are {s, m, h}. Minimum 1 second"`
 	BanThreshold                    uint32        `long:"banthreshold" description:"Maximum allowed ban score before disconnecting and banning misbehaving peers."`
 	Whitelists                      []string      `long:"whitelist" description:"Add an IP network or IP that will not be banned. (eg. 192.168.1.0/24 or ::1)"`
+	RequireP2PEncryption            bool          `long:"requirep2pencryption" description:"Refuse P2P connections with peers that do not support encryption"`
+	P2PIdentityKeyFile              string        `long:"p2pidentitykey" description:"File containing the static identity key this node authenticates to its P2P peers with -- It is created if it doesn't exist (default: a new key on every start)"`
+	TrustedPeers                    []string      `long:"trustedpeer" description:"Add a trusted peer in the form <identity public key>@<host:port> -- Connections to it must be encrypted and authenticated with its identity key, and peers that authenticate with the key of a trusted peer are never banned"`
 	RPCListeners                    []string      `long:"rpclisten" description:"Add an interface/port to listen for RPC connections (default port: 16110, testnet: 16210)"`
 	RPCCert                         string        `long:"rpccert" description:"File containing the certificate file"`
 	RPCKey                          string        `long:"rpckey" description:"File containing the certificate key"`
@@ -106,10 +117,18 @@ type Flags struct {
 	Proxy                           string        `long:"proxy" description:"Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
 	ProxyUser                       string        `long:"proxyuser" description:"Username for proxy server"`
 	ProxyPass                       string        `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
+	OnionProxy                      string        `long:"onion" description:"Connect to Tor onion services via SOCKS5 proxy (eg. 127.0.0.1:9050) -- Defaults to the --proxy value"`
+	OnionProxyUser                  string        `long:"onionuser" description:"Username for onion proxy server"`
+	OnionProxyPass                  string        `long:"onionpass" default-mask:"-" description:"Password for onion proxy server"`
+	NoOnion                         bool          `long:"noonion" description:"Disable connecting to Tor onion services"`
+	I2PProxy                        string        `long:"i2pproxy" description:"Connect to I2P destinations via the SOCKS5 proxy of an I2P router (eg. 127.0.0.1:4447)"`
+	OnlyNets                        []string      `long:"onlynet" description:"Only make automatic outbound connections to peers in the given network {ipv4, ipv6, onion, i2p} -- May be specified multiple times"`
+	TorControl                      string        `long:"torcontrol" description:"Create an onion service for the P2P listener through the given Tor control port (eg. 127.0.0.1:9051)"`
+	TorPassword                     string        `long:"torpassword" default-mask:"-" description:"Password for the Tor control port -- Cookie authentication is used if not set"`
 	DbType                          string        `long:"dbtype" description:"Database backend to use for the Block DAG"`
 	Profile                         string        `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
 	LogLevel                        string        `short:"d" long:"loglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
-	Upnp                            bool          `long:"upnp" description:"Use UPnP to map our listening port outside of NAT"`
+	Upnp                            bool          `long:"upnp" description:"Use UPnP, PCP or NAT-PMP to map our listening port outside of NAT"`
 	MinRelayTxFee                   float64       `long:"minrelaytxfee" description:"The minimum transaction fee in KAS/kB to be considered a non-zero fee."`
 	MaxOrphanTxs                    uint64        `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
 	BlockMaxMass                    uint64        `long:"blockmaxmass" description:"Maximum transaction mass to be used when creating a block"`
@@ -117,6 +136,7 @@ type Flags struct {
 	NoPeerBloomFilters              bool          `long:"nopeerbloomfilters" description:"Disable bloom filtering support"`
 	SigCacheMaxSize                 uint          `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
 	BlocksOnly                      bool          `long:"blocksonly" description:"Do not accept transactions from remote peers."`
+	TxReconciliation                bool          `long:"txreconciliation" description:"Announce transactions to peers that support it by periodic set reconciliation rather than by flooding"`
 	RelayNonStd                     bool          `long:"relaynonstd" description:"Relay non-standard transactions regardless of the default settings for the active network."`
 	RejectNonStd                    bool          `long:"rejectnonstd" description:"Reject non-standard transactions regardless of the default settings for the active network."`
 	ResetDatabase                   bool          `long:"reset-db" description:"Reset database before starting node. It's needed when switching between subnetworks."`
@@ -137,6 +157,8 @@ type Config struct {
 	*Flags
 	Lookup        func(string) ([]net.IP, error)
 	Dial          func(string, string, time.Duration) (net.Conn, error)
+	OnionDial     func(string, string, time.Duration) (net.Conn, error) // nil if onion services are unreachable
+	I2PDial       func(string, string, time.Duration) (net.Conn, error) // nil if I2P destinations are unreachable
 	MiningAddrs   []util.Address
 	MinRelayTxFee util.Amount
 	Whitelists    []*net.IPNet
@@ -334,6 +356,10 @@ func LoadConfig() (*Config, error) {
 	}
 	cfg.LogDir = cleanAndExpandPath(cfg.LogDir)
 
+	if cfg.P2PIdentityKeyFile != "" {
+		cfg.P2PIdentityKeyFile = cleanAndExpandPath(cfg.P2PIdentityKeyFile)
+	}
+
 	// Special show command to list supported subsystems and exit.
 	if cfg.LogLevel == "show" {
 		fmt.Println("Supported subsystems", logger.SupportedSubsystems())
@@ -566,6 +592,91 @@ func LoadConfig() (*Config, error) {
 		cfg.Dial = proxy.DialTimeout
 	}
 
+	// Setup the dial functions of overlay networks. Onion services are
+	// reached through --onion, or through --proxy when it isn't set, since
+	// that is typically the SOCKS5 port of Tor.
+	if !cfg.NoOnion {
+		if cfg.OnionProxy != "" {
+			_, _, err := net.SplitHostPort(cfg.OnionProxy)
+			if err != nil {
+				str := "%s: Onion proxy address '%s' is invalid: %s"
+				err := errors.Errorf(str, funcName, cfg.OnionProxy, err)
+				fmt.Fprintln(os.Stderr, err)
+				fmt.Fprintln(os.Stderr, usageMessage)
+				return nil, err
+			}
+
+			proxy := &socks.Proxy{
+				Addr:     cfg.OnionProxy,
+				Username: cfg.OnionProxyUser,
+				Password: cfg.OnionProxyPass,
+			}
+			cfg.OnionDial = proxy.DialTimeout
+		} else if cfg.Proxy != "" {
+			cfg.OnionDial = cfg.Dial
+		}
+	}
+	if cfg.I2PProxy != "" {
+		_, _, err := net.SplitHostPort(cfg.I2PProxy)
+		if err != nil {
+			str := "%s: I2P proxy address '%s' is invalid: %s"
+			err := errors.Errorf(str, funcName, cfg.I2PProxy, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, err
+		}
+
+		proxy := &socks.Proxy{Addr: cfg.I2PProxy}
+		cfg.I2PDial = proxy.DialTimeout
+	}
+
+	// Validate --onlynet, and make sure that every network
+	// it names is reachable.
+	for _, onlyNet := range cfg.OnlyNets {
+		var err error
+		switch onlyNet {
+		case OnlyNetIPv4, OnlyNetIPv6:
+		case OnlyNetOnion:
+			if cfg.OnionDial == nil {
+				err = errors.Errorf("%s: --onlynet=%s requires either --onion or --proxy, "+
+					"and not --noonion", funcName, onlyNet)
+			}
+		case OnlyNetI2P:
+			if cfg.I2PDial == nil {
+				err = errors.Errorf("%s: --onlynet=%s requires --i2pproxy", funcName, onlyNet)
+			}
+		default:
+			err = errors.Errorf("%s: unknown network '%s' in --onlynet -- supported networks are "+
+				"{%s, %s, %s, %s}", funcName, onlyNet, OnlyNetIPv4, OnlyNetIPv6, OnlyNetOnion, OnlyNetI2P)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, err
+		}
+	}
+
+	// --torcontrol creates an onion service for the P2P listener, so it
+	// requires listening.
+	if cfg.TorControl != "" {
+		_, _, err := net.SplitHostPort(cfg.TorControl)
+		if err != nil {
+			str := "%s: Tor control address '%s' is invalid: %s"
+			err := errors.Errorf(str, funcName, cfg.TorControl, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, err
+		}
+		if cfg.DisableListen {
+			str := "%s: --torcontrol requires listening for incoming connections -- " +
+				"Use --listen to specify the interface the onion service forwards to (eg. 127.0.0.1:%s)"
+			err := errors.Errorf(str, funcName, cfg.NetParams().DefaultPort)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, err
+		}
+	}
+
 	// Warn about missing config file only after all other configuration is
 	// done. This prevents the warning on help messages and invalid
 	// options. Note this should go directly before the return.
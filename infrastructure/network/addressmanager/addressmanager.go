@@ -25,33 +25,38 @@ type addressRandomizer interface {
 	RandomAddresses(addresses []*address, count int) []*appmessage.NetAddress
 }
 
-// addressKey represents a pair of IP and port, the IP is always in V6 representation
+// addressKey represents a pair of host and port
 type addressKey struct {
 	port    uint16
-	address ipv6
+	address hostKey
 }
 
+// hostKey represents the host of an address. IPs are always in their
+// V6 representation, and overlay addresses are prefixed by their network.
+type hostKey string
+
 type address struct {
 	netAddress            *appmessage.NetAddress
 	connectionFailedCount uint64
 }
 
-type ipv6 [net.IPv6len]byte
-
-func (i ipv6) equal(other ipv6) bool {
-	return i == other
-}
-
 // ErrAddressNotFound is an error returned from some functions when a
 // given address is not found in the address manager
 var ErrAddressNotFound = errors.New("address not found")
 
-// NetAddressKey returns a key of the ip address to use it in maps.
+// NetAddressKey returns a key of the address to use it in maps.
 func netAddressKey(netAddress *appmessage.NetAddress) addressKey {
-	key := addressKey{port: netAddress.Port}
+	return addressKey{port: netAddress.Port, address: netAddressHostKey(netAddress)}
+}
+
+func netAddressHostKey(netAddress *appmessage.NetAddress) hostKey {
+	if netAddress.IsOverlay() {
+		return hostKey(append([]byte{byte(netAddress.Network)}, netAddress.OverlayAddress...))
+	}
 	// all IPv4 can be represented as IPv6.
-	copy(key.address[:], netAddress.IP.To16())
-	return key
+	ip := make([]byte, net.IPv6len)
+	copy(ip, netAddress.IP.To16())
+	return hostKey(ip)
 }
 
 // AddressManager provides a concurrency safe address manager for caching potential
@@ -160,7 +165,7 @@ func (am *AddressManager) MarkConnectionFailure(address *appmessage.NetAddress)
 	key := netAddressKey(address)
 	entry, ok := am.store.getNotBanned(key)
 	if !ok {
-		return errors.Errorf("address %s is not registered with the address manager", address.TCPAddress())
+		return errors.Errorf("address %s is not registered with the address manager", address)
 	}
 	entry.connectionFailedCount = entry.connectionFailedCount + 1
 
@@ -181,7 +186,7 @@ func (am *AddressManager) MarkConnectionSuccess(address *appmessage.NetAddress)
 	key := netAddressKey(address)
 	entry, ok := am.store.getNotBanned(key)
 	if !ok {
-		return errors.Errorf("address %s is not registered with the address manager", address.TCPAddress())
+		return errors.Errorf("address %s is not registered with the address manager", address)
 	}
 	entry.connectionFailedCount = 0
 	return am.store.updateNotBanned(key, entry)
@@ -211,10 +216,17 @@ func (am *AddressManager) notBannedAddressesWithException(exceptions []*appmessa
 	return am.store.getAllNotBannedNetAddressesWithout(exceptions)
 }
 
-// RandomAddresses returns count addresses at random that aren't banned and aren't in exceptions
+// RandomAddresses returns count addresses at random that aren't banned, aren't in exceptions,
+// and belong to a reachable network
 func (am *AddressManager) RandomAddresses(count int, exceptions []*appmessage.NetAddress) []*appmessage.NetAddress {
 	validAddresses := am.notBannedAddressesWithException(exceptions)
-	return am.random.RandomAddresses(validAddresses, count)
+	reachableAddresses := make([]*address, 0, len(validAddresses))
+	for _, address := range validAddresses {
+		if am.cfg.IsReachable(address.netAddress) {
+			reachableAddresses = append(reachableAddresses, address)
+		}
+	}
+	return am.random.RandomAddresses(reachableAddresses, count)
 }
 
 // BestLocalAddress returns the most appropriate local address to use
@@ -223,6 +235,19 @@ func (am *AddressManager) BestLocalAddress(remoteAddress *appmessage.NetAddress)
 	return am.localAddresses.bestLocalAddress(remoteAddress)
 }
 
+// AddLocalAddress adds an address that this node is reachable at, such as the
+// address of an onion service that forwards to it, to the addresses it
+// advertises to peers
+func (am *AddressManager) AddLocalAddress(netAddress *appmessage.NetAddress, priority AddressPriority) error {
+	return am.localAddresses.addLocalNetAddress(netAddress, priority)
+}
+
+// RemoveLocalAddress stops advertising an address that this node is no longer
+// reachable at, such as the former external address of a port mapping
+func (am *AddressManager) RemoveLocalAddress(netAddress *appmessage.NetAddress) {
+	am.localAddresses.removeLocalNetAddress(netAddress)
+}
+
 // Ban marks the given address as banned
 func (am *AddressManager) Ban(addressToBan *appmessage.NetAddress) error {
 	am.mutex.Lock()
@@ -232,7 +257,7 @@ func (am *AddressManager) Ban(addressToBan *appmessage.NetAddress) error {
 	keysToDelete := make([]addressKey, 0)
 	for _, address := range am.store.getAllNotBannedNetAddresses() {
 		key := netAddressKey(address)
-		if key.address.equal(keyToBan.address) {
+		if key.address == keyToBan.address {
 			keysToDelete = append(keysToDelete, key)
 		}
 	}
@@ -255,7 +280,7 @@ func (am *AddressManager) Unban(address *appmessage.NetAddress) error {
 	key := netAddressKey(address)
 	if !am.store.isBanned(key) {
 		return errors.Wrapf(ErrAddressNotFound, "address %s "+
-			"is not registered with the address manager as banned", address.TCPAddress())
+			"is not registered with the address manager as banned", address)
 	}
 
 	return am.store.removeBanned(key)
@@ -274,7 +299,7 @@ func (am *AddressManager) IsBanned(address *appmessage.NetAddress) (bool, error)
 	if !am.store.isBanned(key) {
 		if !am.store.isNotBanned(key) {
 			return false, errors.Wrapf(ErrAddressNotFound, "address %s "+
-				"is not registered with the address manager", address.TCPAddress())
+				"is not registered with the address manager", address)
 		}
 		return false, nil
 	}
@@ -372,3 +372,90 @@ func TestOverfillAddressManager(t *testing.T) {
 		}
 	}
 }
+
+func TestBestLocalAddressOverlay(t *testing.T) {
+	amgr, teardown := newAddressManagerForTest(t, "TestBestLocalAddressOverlay")
+	defer teardown()
+
+	onionAddress, err := appmessage.NewNetAddressOverlayHost(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion", 16111)
+	if err != nil {
+		t.Fatalf("NewNetAddressOverlayHost: %s", err)
+	}
+	ipv4Address := appmessage.NewNetAddressIPPort(net.ParseIP("204.124.8.100"), 16111)
+
+	err = amgr.AddLocalAddress(onionAddress, ManualPrio)
+	if err != nil {
+		t.Fatalf("AddLocalAddress: %s", err)
+	}
+	err = amgr.AddLocalAddress(ipv4Address, InterfacePrio)
+	if err != nil {
+		t.Fatalf("AddLocalAddress: %s", err)
+	}
+
+	remoteOnionAddress := appmessage.NewNetAddressOverlay(appmessage.AddressNetworkTorV3,
+		make([]byte, appmessage.OverlayAddressSize), 16111)
+	if got := amgr.BestLocalAddress(remoteOnionAddress); got != onionAddress {
+		t.Errorf("unexpected best local address for an onion peer: want %s got %s", onionAddress, got)
+	}
+
+	remoteIPv4Address := appmessage.NewNetAddressIPPort(net.ParseIP("12.1.2.3"), 16111)
+	if got := amgr.BestLocalAddress(remoteIPv4Address); got != ipv4Address {
+		t.Errorf("unexpected best local address for an IPv4 peer: want %s got %s", ipv4Address, got)
+	}
+}
+
+func TestRemoveLocalAddress(t *testing.T) {
+	amgr, teardown := newAddressManagerForTest(t, "TestRemoveLocalAddress")
+	defer teardown()
+
+	previousAddress := appmessage.NewNetAddressIPPort(net.ParseIP("204.124.8.100"), 16111)
+	externalAddress := appmessage.NewNetAddressIPPort(net.ParseIP("204.124.8.101"), 16111)
+	err := amgr.AddLocalAddress(previousAddress, UpnpPrio)
+	if err != nil {
+		t.Fatalf("AddLocalAddress: %s", err)
+	}
+	amgr.RemoveLocalAddress(previousAddress)
+	err = amgr.AddLocalAddress(externalAddress, UpnpPrio)
+	if err != nil {
+		t.Fatalf("AddLocalAddress: %s", err)
+	}
+
+	remoteAddress := appmessage.NewNetAddressIPPort(net.ParseIP("12.1.2.3"), 16111)
+	if got := amgr.BestLocalAddress(remoteAddress); got != externalAddress {
+		t.Errorf("unexpected best local address: want %s got %s", externalAddress, got)
+	}
+
+	amgr.RemoveLocalAddress(externalAddress)
+	if got := amgr.BestLocalAddress(remoteAddress); !got.IP.IsUnspecified() {
+		t.Errorf("a removed local address %s is still advertised", got)
+	}
+}
+
+func TestRandomAddressesReachableNetworks(t *testing.T) {
+	amgr, teardown := newAddressManagerForTest(t, "TestRandomAddressesReachableNetworks")
+	defer teardown()
+
+	onionAddress, err := appmessage.NewNetAddressOverlayHost(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion", 16111)
+	if err != nil {
+		t.Fatalf("NewNetAddressOverlayHost: %s", err)
+	}
+	ipv4Address := appmessage.NewNetAddressIPPort(net.ParseIP("12.1.2.3"), 16111)
+	err = amgr.AddAddresses(onionAddress, ipv4Address)
+	if err != nil {
+		t.Fatalf("AddAddresses: %s", err)
+	}
+
+	// Without a Tor proxy only the IPv4 address is reachable
+	addresses := amgr.RandomAddresses(10, nil)
+	if len(addresses) != 1 || addresses[0] != ipv4Address {
+		t.Fatalf("unexpected random addresses without a Tor proxy: %v", addresses)
+	}
+
+	amgr.cfg.ReachableNetworks = map[string]bool{config.OnlyNetOnion: true}
+	addresses = amgr.RandomAddresses(10, nil)
+	if len(addresses) != 1 || addresses[0] != onionAddress {
+		t.Fatalf("unexpected random addresses with --onlynet=onion: %v", addresses)
+	}
+}
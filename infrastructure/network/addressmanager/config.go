@@ -3,6 +3,7 @@ package addressmanager
 import (
 	"net"
 
+	"github.com/kaspikr/kaspid/app/appmessage"
 	"github.com/kaspikr/kaspid/infrastructure/config"
 )
 
@@ -13,15 +14,61 @@ type Config struct {
 	ExternalIPs      []string
 	Listeners        []string
 	Lookup           func(string) ([]net.IP, error)
+
+	// ReachableNetworks are the networks, named as in --onlynet,
+	// that automatic outbound connections are made to
+	ReachableNetworks map[string]bool
 }
 
 // NewConfig returns a new address manager Config.
 func NewConfig(cfg *config.Config) *Config {
 	return &Config{
-		AcceptUnroutable: cfg.NetParams().AcceptUnroutable,
-		DefaultPort:      cfg.NetParams().DefaultPort,
-		ExternalIPs:      cfg.ExternalIPs,
-		Listeners:        cfg.Listeners,
-		Lookup:           cfg.Lookup,
+		AcceptUnroutable:  cfg.NetParams().AcceptUnroutable,
+		DefaultPort:       cfg.NetParams().DefaultPort,
+		ExternalIPs:       cfg.ExternalIPs,
+		Listeners:         cfg.Listeners,
+		Lookup:            cfg.Lookup,
+		ReachableNetworks: reachableNetworks(cfg),
+	}
+}
+
+// reachableNetworks returns the networks given by --onlynet, or if
+// it isn't set, the IP networks and every overlay network that has
+// a proxy configured
+func reachableNetworks(cfg *config.Config) map[string]bool {
+	reachable := make(map[string]bool)
+	if len(cfg.OnlyNets) > 0 {
+		for _, onlyNet := range cfg.OnlyNets {
+			reachable[onlyNet] = true
+		}
+		return reachable
+	}
+
+	reachable[config.OnlyNetIPv4] = true
+	reachable[config.OnlyNetIPv6] = true
+	reachable[config.OnlyNetOnion] = cfg.OnionDial != nil
+	reachable[config.OnlyNetI2P] = cfg.I2PDial != nil
+	return reachable
+}
+
+// IsReachable returns whether automatic outbound connections are made
+// to the network of the given address
+func (cfg *Config) IsReachable(netAddress *appmessage.NetAddress) bool {
+	return cfg.ReachableNetworks[networkName(netAddress)]
+}
+
+// networkName returns the name of the network of the given address as in --onlynet
+func networkName(netAddress *appmessage.NetAddress) string {
+	switch netAddress.Network {
+	case appmessage.AddressNetworkIP:
+		if IsIPv4(netAddress) {
+			return config.OnlyNetIPv4
+		}
+		return config.OnlyNetIPv6
+	case appmessage.AddressNetworkTorV3:
+		return config.OnlyNetOnion
+	case appmessage.AddressNetworkI2P:
+		return config.OnlyNetI2P
 	}
+	return ""
 }
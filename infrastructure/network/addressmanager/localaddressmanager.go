@@ -63,7 +63,7 @@ func newLocalAddressManager(cfg *Config) (*localAddressManager, error) {
 // with the given priority.
 func (lam *localAddressManager) addLocalNetAddress(netAddress *appmessage.NetAddress, priority AddressPriority) error {
 	if !IsRoutable(netAddress, lam.cfg.AcceptUnroutable) {
-		return errors.Errorf("address %s is not routable", netAddress.IP)
+		return errors.Errorf("address %s is not routable", netAddress.Host())
 	}
 
 	lam.mutex.Lock()
@@ -84,6 +84,14 @@ func (lam *localAddressManager) addLocalNetAddress(netAddress *appmessage.NetAdd
 	return nil
 }
 
+// removeLocalNetAddress removes netAddress from the list of known local addresses to advertise
+func (lam *localAddressManager) removeLocalNetAddress(netAddress *appmessage.NetAddress) {
+	lam.mutex.Lock()
+	defer lam.mutex.Unlock()
+
+	delete(lam.localAddresses, netAddressKey(netAddress))
+}
+
 // bestLocalAddress returns the most appropriate local address to use
 // for the given remote address.
 func (lam *localAddressManager) bestLocalAddress(remoteAddress *appmessage.NetAddress) *appmessage.NetAddress {
@@ -230,8 +238,12 @@ func (lam *localAddressManager) initListeners() error {
 }
 
 // hostToNetAddress returns a netaddress given a host address. If
-// the host is not an IP address it will be resolved.
+// the host is neither an IP nor an overlay address it will be resolved.
 func (lam *localAddressManager) hostToNetAddress(host string, port uint16) (*appmessage.NetAddress, error) {
+	if appmessage.IsOverlayHost(host) {
+		return appmessage.NewNetAddressOverlayHost(host, port)
+	}
+
 	ip := net.ParseIP(host)
 	if ip == nil {
 		ips, err := lam.lookupFunc(host)
@@ -312,6 +324,9 @@ func reachabilityFrom(localAddress, remoteAddress *appmessage.NetAddress, accept
 	)
 
 	IsRoutable := func(na *appmessage.NetAddress) bool {
+		if na.IsOverlay() {
+			return IsValid(na)
+		}
 		if acceptUnroutable {
 			return !IsLocal(na)
 		}
@@ -326,6 +341,23 @@ func reachabilityFrom(localAddress, remoteAddress *appmessage.NetAddress, accept
 		return Unreachable
 	}
 
+	// Peers in an overlay network can reach local addresses in the same
+	// overlay network, and typically also IPv4 addresses through the exits
+	// of the overlay network. Local overlay addresses are only advertised
+	// within their own overlay network.
+	if remoteAddress.IsOverlay() {
+		if localAddress.Network == remoteAddress.Network {
+			return Private
+		}
+		if IsRoutable(localAddress) && IsIPv4(localAddress) {
+			return Ipv4
+		}
+		return Default
+	}
+	if localAddress.IsOverlay() {
+		return Unreachable
+	}
+
 	if IsRFC4380(remoteAddress) {
 		if !IsRoutable(localAddress) {
 			return Default
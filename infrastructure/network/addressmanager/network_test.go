@@ -204,3 +204,31 @@ func TestGroupKey(t *testing.T) {
 		}
 	}
 }
+
+// TestOverlayAddresses tests that overlay addresses are routable and grouped
+// by their network
+func TestOverlayAddresses(t *testing.T) {
+	amgr, teardown := newAddressManagerForTest(t, "TestOverlayAddresses")
+	defer teardown()
+
+	onionAddress, err := appmessage.NewNetAddressOverlayHost(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion", 16111)
+	if err != nil {
+		t.Fatalf("NewNetAddressOverlayHost: %s", err)
+	}
+	if !IsRoutable(onionAddress, false) {
+		t.Errorf("onion address %s is not routable", onionAddress)
+	}
+	if key := amgr.GroupKey(onionAddress); key != "onion:1" {
+		t.Errorf("unexpected group key for %s - got '%s', want 'onion:1'", onionAddress, key)
+	}
+
+	truncatedAddress := appmessage.NewNetAddressOverlay(appmessage.AddressNetworkTorV3, make([]byte, 16), 16111)
+	if IsRoutable(truncatedAddress, true) {
+		t.Errorf("onion address with a truncated public key is routable")
+	}
+	unknownNetworkAddress := appmessage.NewNetAddressOverlay(100, make([]byte, appmessage.OverlayAddressSize), 16111)
+	if IsRoutable(unknownNetworkAddress, true) {
+		t.Errorf("overlay address of an unknown network is routable")
+	}
+}
@@ -15,14 +15,14 @@ var bannedAddressBucket = database.MakeBucket([]byte("banned-addresses"))
 type addressStore struct {
 	database           database.Database
 	notBannedAddresses map[addressKey]*address
-	bannedAddresses    map[ipv6]*address
+	bannedAddresses    map[hostKey]*address
 }
 
 func newAddressStore(database database.Database) (*addressStore, error) {
 	addressStore := &addressStore{
 		database:           database,
 		notBannedAddresses: map[addressKey]*address{},
-		bannedAddresses:    map[ipv6]*address{},
+		bannedAddresses:    map[hostKey]*address{},
 	}
 	err := addressStore.restoreNotBannedAddresses()
 	if err != nil {
@@ -74,15 +74,14 @@ func (as *addressStore) restoreBannedAddresses() error {
 		if err != nil {
 			return err
 		}
-		var ipv6 ipv6
-		copy(ipv6[:], databaseKey.Suffix())
+		host := hostKey(databaseKey.Suffix())
 
 		serializedNetAddress, err := cursor.Value()
 		if err != nil {
 			return err
 		}
 		netAddress := as.deserializeAddress(serializedNetAddress)
-		as.bannedAddresses[ipv6] = netAddress
+		as.bannedAddresses[host] = netAddress
 	}
 	return nil
 }
@@ -106,7 +105,7 @@ func (as *addressStore) add(key addressKey, address *address) error {
 // updateNotBanned updates the not-banned address collection
 func (as *addressStore) updateNotBanned(key addressKey, address *address) error {
 	if _, ok := as.notBannedAddresses[key]; !ok {
-		return errors.Errorf("address %s is not in the store", address.netAddress.TCPAddress())
+		return errors.Errorf("address %s is not in the store", address.netAddress)
 	}
 
 	as.notBannedAddresses[key] = address
@@ -214,57 +213,65 @@ func (as *addressStore) notBannedDatabaseKey(key addressKey) *database.Key {
 }
 
 func (as *addressStore) bannedDatabaseKey(key addressKey) *database.Key {
-	return bannedAddressBucket.Key(key.address[:])
+	return bannedAddressBucket.Key([]byte(key.address))
 }
 
+// Addresses are serialized as their host key followed by their port. IP host keys are
+// always 16 bytes long, which tells them apart from the longer overlay host keys.
+const ipHostKeySize = net.IPv6len
+
 func (as *addressStore) serializeAddressKey(key addressKey) []byte {
-	serializedSize := 16 + 2 // ipv6 + port
-	serializedKey := make([]byte, serializedSize)
+	serializedKey := make([]byte, len(key.address)+2) // host + port
 
-	copy(serializedKey[:], key.address[:])
-	binary.LittleEndian.PutUint16(serializedKey[16:], key.port)
+	copy(serializedKey[:], key.address)
+	binary.LittleEndian.PutUint16(serializedKey[len(key.address):], key.port)
 
 	return serializedKey
 }
 
 func (as *addressStore) deserializeAddressKey(serializedKey []byte) addressKey {
-	var ip ipv6
-	copy(ip[:], serializedKey[:])
+	hostSize := len(serializedKey) - 2
+	host := hostKey(serializedKey[:hostSize])
 
-	port := binary.LittleEndian.Uint16(serializedKey[16:])
+	port := binary.LittleEndian.Uint16(serializedKey[hostSize:])
 
 	return addressKey{
 		port:    port,
-		address: ip,
+		address: host,
 	}
 }
 
 func (as *addressStore) serializeAddress(address *address) []byte {
-	serializedSize := 16 + 2 + 8 + 8 // ipv6 + port + timestamp + connectionFailedCount
+	host := netAddressHostKey(address.netAddress)
+	serializedSize := len(host) + 2 + 8 + 8 // host + port + timestamp + connectionFailedCount
 	serializedNetAddress := make([]byte, serializedSize)
 
-	copy(serializedNetAddress[:], address.netAddress.IP.To16()[:])
-	binary.LittleEndian.PutUint16(serializedNetAddress[16:], address.netAddress.Port)
-	binary.LittleEndian.PutUint64(serializedNetAddress[18:], uint64(address.netAddress.Timestamp.UnixMilliseconds()))
-	binary.LittleEndian.PutUint64(serializedNetAddress[26:], uint64(address.connectionFailedCount))
+	copy(serializedNetAddress[:], host)
+	binary.LittleEndian.PutUint16(serializedNetAddress[len(host):], address.netAddress.Port)
+	binary.LittleEndian.PutUint64(serializedNetAddress[len(host)+2:], uint64(address.netAddress.Timestamp.UnixMilliseconds()))
+	binary.LittleEndian.PutUint64(serializedNetAddress[len(host)+10:], uint64(address.connectionFailedCount))
 
 	return serializedNetAddress
 }
 
 func (as *addressStore) deserializeAddress(serializedAddress []byte) *address {
-	ip := make(net.IP, 16)
-	copy(ip[:], serializedAddress[:])
+	hostSize := len(serializedAddress) - 2 - 8 - 8
+	netAddress := &appmessage.NetAddress{}
+	if hostSize == ipHostKeySize {
+		netAddress.IP = make(net.IP, ipHostKeySize)
+		copy(netAddress.IP, serializedAddress[:hostSize])
+	} else {
+		netAddress.Network = appmessage.AddressNetwork(serializedAddress[0])
+		netAddress.OverlayAddress = make([]byte, hostSize-1)
+		copy(netAddress.OverlayAddress, serializedAddress[1:hostSize])
+	}
 
-	port := binary.LittleEndian.Uint16(serializedAddress[16:])
-	timestamp := mstime.UnixMilliseconds(int64(binary.LittleEndian.Uint64(serializedAddress[18:])))
-	connectionFailedCount := binary.LittleEndian.Uint64(serializedAddress[26:])
+	netAddress.Port = binary.LittleEndian.Uint16(serializedAddress[hostSize:])
+	netAddress.Timestamp = mstime.UnixMilliseconds(int64(binary.LittleEndian.Uint64(serializedAddress[hostSize+2:])))
+	connectionFailedCount := binary.LittleEndian.Uint64(serializedAddress[hostSize+10:])
 
 	return &address{
-		netAddress: &appmessage.NetAddress{
-			IP:        ip,
-			Port:      port,
-			Timestamp: timestamp,
-		},
+		netAddress:            netAddress,
 		connectionFailedCount: connectionFailedCount,
 	}
 }
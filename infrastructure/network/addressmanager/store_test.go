@@ -45,3 +45,33 @@ func TestAddressSerialization(t *testing.T) {
 			"testAddress:%+v\ndeserializedTestAddress:%+v", testAddress, deserializedTestAddress)
 	}
 }
+
+func TestOverlayAddressSerialization(t *testing.T) {
+	addressManager, teardown := newAddressManagerForTest(t, "TestOverlayAddressSerialization")
+	defer teardown()
+	addressStore := addressManager.store
+
+	onionAddress, err := appmessage.NewNetAddressOverlayHost(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion", 16111)
+	if err != nil {
+		t.Fatalf("NewNetAddressOverlayHost: %s", err)
+	}
+	onionAddress.Timestamp = mstime.Now()
+	testAddress := &address{
+		netAddress:            onionAddress,
+		connectionFailedCount: 3,
+	}
+
+	testAddressKey := netAddressKey(testAddress.netAddress)
+	deserializedTestAddressKey := addressStore.deserializeAddressKey(addressStore.serializeAddressKey(testAddressKey))
+	if !reflect.DeepEqual(testAddressKey, deserializedTestAddressKey) {
+		t.Fatalf("testAddressKey and deserializedTestAddressKey are not equal\n"+
+			"testAddressKey:%+v\ndeserializedTestAddressKey:%+v", testAddressKey, deserializedTestAddressKey)
+	}
+
+	deserializedTestAddress := addressStore.deserializeAddress(addressStore.serializeAddress(testAddress))
+	if !reflect.DeepEqual(testAddress, deserializedTestAddress) {
+		t.Fatalf("testAddress and deserializedTestAddress are not equal\n"+
+			"testAddress:%+v\ndeserializedTestAddress:%+v", testAddress, deserializedTestAddress)
+	}
+}
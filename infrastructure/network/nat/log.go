@@ -0,0 +1,9 @@
+package nat
+
+import (
+	"github.com/kaspikr/kaspid/infrastructure/logger"
+	"github.com/kaspikr/kaspid/util/panics"
+)
+
+var log = logger.RegisterSubSystem("NATM")
+var spawn = panics.GoroutineWrapperFunc(log)
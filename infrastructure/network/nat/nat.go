@@ -0,0 +1,44 @@
+package nat
+
+import (
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Gateway is a NAT gateway that can map TCP ports of its external address to
+// ports of this host
+type Gateway interface {
+	// AddPortMapping maps externalPort of the gateway to internalPort of this host for
+	// the given lifetime, and returns the external address of the mapping. Gateways may
+	// map a different external port than the requested one.
+	AddPortMapping(internalPort, externalPort uint16, lifetime time.Duration) (*net.TCPAddr, error)
+
+	// DeletePortMapping deletes the mapping of externalPort to internalPort
+	DeletePortMapping(internalPort, externalPort uint16) error
+
+	// String returns a description of the gateway
+	String() string
+}
+
+const discoveryTimeout = 3 * time.Second
+
+// Discover returns the NAT gateway of the local network. UPnP IGD gateways
+// are searched for first, and then PCP and NAT-PMP gateways.
+func Discover() (Gateway, error) {
+	upnpGateway, upnpErr := discoverUPnP(discoveryTimeout)
+	if upnpErr == nil {
+		return upnpGateway, nil
+	}
+	log.Debugf("UPnP discovery failed: %s", upnpErr)
+
+	pmpGateway, pmpErr := discoverPMP()
+	if pmpErr == nil {
+		return pmpGateway, nil
+	}
+	log.Debugf("PCP and NAT-PMP discovery failed: %s", pmpErr)
+
+	return nil, errors.Errorf("no UPnP, PCP or NAT-PMP gateway found (UPnP: %s; PCP/NAT-PMP: %s)",
+		upnpErr, pmpErr)
+}
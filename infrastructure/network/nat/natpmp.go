@@ -0,0 +1,337 @@
+package nat
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pmpPort is the port PCP and NAT-PMP gateways listen on. It's a variable so
+// that tests can use fake gateways.
+var pmpPort = 5351
+
+// gatewayCandidates returns the addresses that might belong to the NAT gateway
+// of the local network. It's a variable so that tests can use fake gateways.
+var gatewayCandidates = defaultGatewayCandidates
+
+const (
+	pcpVersion    = 2
+	natPMPVersion = 0
+
+	pcpOpcodeAnnounce = 0
+	pcpOpcodeMap      = 1
+
+	natPMPOpcodeExternalAddress = 0
+	natPMPOpcodeMapTCP          = 2
+
+	pmpReplyFlag                = 0x80
+	pmpResultSuccess            = 0
+	pmpResultUnsupportedVersion = 1
+
+	pcpHeaderSize     = 24
+	pcpMapPayloadSize = 36
+	pcpNonceSize      = 12
+	tcpProtocolNumber = 6
+
+	// pmpInitialRetransmission is the time to wait for a reply before retransmitting
+	// a request. It doubles with every retransmission, as specified in RFC 6886 and RFC 6887.
+	pmpInitialRetransmission = 250 * time.Millisecond
+	pmpRequestAttempts       = 4
+)
+
+// pmpGateway is a gateway that supports either the Port Control Protocol
+// (RFC 6887), or its predecessor NAT-PMP (RFC 6886)
+type pmpGateway struct {
+	address  *net.UDPAddr
+	isPCP    bool
+	clientIP net.IP
+
+	// nonce identifies the mappings of this host to PCP gateways
+	nonce [pcpNonceSize]byte
+}
+
+// discoverPMP returns the first gateway candidate that replies to either PCP or NAT-PMP
+func discoverPMP() (*pmpGateway, error) {
+	candidates := gatewayCandidates()
+	if len(candidates) == 0 {
+		return nil, errors.New("could not find the address of the default gateway")
+	}
+	for _, candidate := range candidates {
+		gateway, err := newPMPGateway(candidate)
+		if err != nil {
+			log.Debugf("%s is not a PCP or NAT-PMP gateway: %s", candidate, err)
+			continue
+		}
+		return gateway, nil
+	}
+	return nil, errors.New("no gateway replied to PCP or NAT-PMP")
+}
+
+// newPMPGateway probes the given address for PCP, and falls back
+// to NAT-PMP if it replies that it doesn't support PCP
+func newPMPGateway(ip net.IP) (*pmpGateway, error) {
+	gateway := &pmpGateway{address: &net.UDPAddr{IP: ip, Port: pmpPort}}
+	_, err := rand.Read(gateway.nonce[:])
+	if err != nil {
+		return nil, err
+	}
+	gateway.clientIP, err = localIPTowards(ip.String())
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := gateway.request(gateway.pcpHeader(pcpOpcodeAnnounce, 0))
+	if err != nil {
+		return nil, err
+	}
+	switch reply[0] {
+	case pcpVersion:
+		if reply[3] != pmpResultSuccess {
+			return nil, errors.Errorf("PCP announce failed with result code %d", reply[3])
+		}
+		gateway.isPCP = true
+		return gateway, nil
+	case natPMPVersion:
+		_, err := gateway.natPMPExternalIP()
+		if err != nil {
+			return nil, err
+		}
+		return gateway, nil
+	}
+	return nil, errors.Errorf("unknown protocol version %d", reply[0])
+}
+
+func (g *pmpGateway) String() string {
+	if g.isPCP {
+		return fmt.Sprintf("PCP gateway %s", g.address.IP)
+	}
+	return fmt.Sprintf("NAT-PMP gateway %s", g.address.IP)
+}
+
+// AddPortMapping maps externalPort of the gateway to internalPort of this host
+// This is part of the Gateway interface
+func (g *pmpGateway) AddPortMapping(internalPort, externalPort uint16, lifetime time.Duration) (
+	*net.TCPAddr, error) {
+
+	if g.isPCP {
+		return g.pcpMap(internalPort, externalPort, lifetime)
+	}
+
+	mappedPort, err := g.natPMPMap(internalPort, externalPort, lifetime)
+	if err != nil {
+		return nil, err
+	}
+	externalIP, err := g.natPMPExternalIP()
+	if err != nil {
+		return nil, err
+	}
+	return &net.TCPAddr{IP: externalIP, Port: int(mappedPort)}, nil
+}
+
+// DeletePortMapping deletes the mapping of internalPort
+// This is part of the Gateway interface
+func (g *pmpGateway) DeletePortMapping(internalPort, _ uint16) error {
+	var err error
+	if g.isPCP {
+		_, err = g.pcpMap(internalPort, 0, 0)
+	} else {
+		_, err = g.natPMPMap(internalPort, 0, 0)
+	}
+	return err
+}
+
+func (g *pmpGateway) pcpHeader(opcode byte, lifetime time.Duration) []byte {
+	header := make([]byte, pcpHeaderSize)
+	header[0] = pcpVersion
+	header[1] = opcode
+	binary.BigEndian.PutUint32(header[4:8], uint32(lifetime/time.Second))
+	copy(header[8:24], g.clientIP.To16())
+	return header
+}
+
+func (g *pmpGateway) pcpMap(internalPort, externalPort uint16, lifetime time.Duration) (*net.TCPAddr, error) {
+	request := append(g.pcpHeader(pcpOpcodeMap, lifetime), make([]byte, pcpMapPayloadSize)...)
+	payload := request[pcpHeaderSize:]
+	copy(payload[0:12], g.nonce[:])
+	payload[12] = tcpProtocolNumber
+	binary.BigEndian.PutUint16(payload[16:18], internalPort)
+	binary.BigEndian.PutUint16(payload[18:20], externalPort)
+	copy(payload[20:36], net.IPv4zero.To16())
+
+	reply, err := g.request(request)
+	if err != nil {
+		return nil, err
+	}
+	if reply[3] != pmpResultSuccess {
+		return nil, errors.Errorf("PCP MAP failed with result code %d", reply[3])
+	}
+	if len(reply) < pcpHeaderSize+pcpMapPayloadSize {
+		return nil, errors.Errorf("PCP MAP reply is too short (%d bytes)", len(reply))
+	}
+	replyPayload := reply[pcpHeaderSize:]
+	if string(replyPayload[0:12]) != string(g.nonce[:]) {
+		return nil, errors.New("PCP MAP reply has an unexpected nonce")
+	}
+	mappedPort := binary.BigEndian.Uint16(replyPayload[18:20])
+	mappedIP := net.IP(append([]byte(nil), replyPayload[20:36]...))
+	return &net.TCPAddr{IP: mappedIP, Port: int(mappedPort)}, nil
+}
+
+func (g *pmpGateway) natPMPExternalIP() (net.IP, error) {
+	reply, err := g.request([]byte{natPMPVersion, natPMPOpcodeExternalAddress})
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) < 12 {
+		return nil, errors.Errorf("NAT-PMP external address reply is too short (%d bytes)", len(reply))
+	}
+	if result := binary.BigEndian.Uint16(reply[2:4]); result != pmpResultSuccess {
+		return nil, errors.Errorf("NAT-PMP external address request failed with result code %d", result)
+	}
+	return net.IPv4(reply[8], reply[9], reply[10], reply[11]), nil
+}
+
+func (g *pmpGateway) natPMPMap(internalPort, externalPort uint16, lifetime time.Duration) (uint16, error) {
+	request := make([]byte, 12)
+	request[0] = natPMPVersion
+	request[1] = natPMPOpcodeMapTCP
+	binary.BigEndian.PutUint16(request[4:6], internalPort)
+	binary.BigEndian.PutUint16(request[6:8], externalPort)
+	binary.BigEndian.PutUint32(request[8:12], uint32(lifetime/time.Second))
+
+	reply, err := g.request(request)
+	if err != nil {
+		return 0, err
+	}
+	if len(reply) < 16 {
+		return 0, errors.Errorf("NAT-PMP mapping reply is too short (%d bytes)", len(reply))
+	}
+	if result := binary.BigEndian.Uint16(reply[2:4]); result != pmpResultSuccess {
+		return 0, errors.Errorf("NAT-PMP mapping request failed with result code %d", result)
+	}
+	return binary.BigEndian.Uint16(reply[10:12]), nil
+}
+
+// request sends the given request to the gateway, retransmitting it until
+// a reply arrives, and returns the reply. Replies with a different protocol
+// version than the request's are returned too, since they tell that the
+// gateway doesn't support the version of the request.
+func (g *pmpGateway) request(request []byte) ([]byte, error) {
+	conn, err := net.DialUDP("udp4", nil, g.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	opcode := request[1]
+	buffer := make([]byte, 1100)
+	timeout := pmpInitialRetransmission
+	for attempt := 0; attempt < pmpRequestAttempts; attempt++ {
+		_, err := conn.Write(request)
+		if err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(timeout)
+		timeout *= 2
+		for {
+			err := conn.SetReadDeadline(deadline)
+			if err != nil {
+				return nil, err
+			}
+			n, err := conn.Read(buffer)
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			if n < 4 || buffer[1] != opcode|pmpReplyFlag {
+				continue
+			}
+			reply := buffer[:n]
+			if reply[0] != request[0] && !isUnsupportedVersionReply(reply) {
+				continue
+			}
+			return reply, nil
+		}
+	}
+	return nil, errors.Errorf("%s did not reply", g.address)
+}
+
+func isUnsupportedVersionReply(reply []byte) bool {
+	if reply[0] == pcpVersion {
+		return reply[3] == pmpResultUnsupportedVersion
+	}
+	return binary.BigEndian.Uint16(reply[2:4]) == pmpResultUnsupportedVersion
+}
+
+// defaultGatewayCandidates returns the default gateway if it's known, and the
+// first address in the subnet of every private IPv4 interface address, which
+// is where gateways usually are
+func defaultGatewayCandidates() []net.IP {
+	var candidates []net.IP
+	seen := make(map[string]bool)
+	addCandidate := func(ip net.IP) {
+		if !seen[ip.String()] {
+			seen[ip.String()] = true
+			candidates = append(candidates, ip)
+		}
+	}
+
+	if ip, ok := linuxDefaultGateway(); ok {
+		addCandidate(ip)
+	}
+
+	interfaceAddresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return candidates
+	}
+	for _, interfaceAddress := range interfaceAddresses {
+		ipNet, ok := interfaceAddress.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP.To4()
+		if ip == nil || !ip.IsPrivate() {
+			continue
+		}
+		gateway := ip.Mask(ipNet.Mask)
+		gateway[3] |= 1
+		addCandidate(gateway)
+	}
+	return candidates
+}
+
+// linuxDefaultGateway returns the default gateway from the routing table of Linux
+func linuxDefaultGateway() (net.IP, bool) {
+	routes, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, false
+	}
+	defer routes.Close()
+
+	scanner := bufio.NewScanner(routes)
+	for scanner.Scan() {
+		// The fields are Iface, Destination, Gateway, ..., where addresses are
+		// hexadecimal in the byte order of the host, which is little endian
+		// on all architectures this is relevant for
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		gateway, err := hex.DecodeString(fields[2])
+		if err != nil || len(gateway) != net.IPv4len {
+			continue
+		}
+		return net.IPv4(gateway[3], gateway[2], gateway[1], gateway[0]), true
+	}
+	return nil, false
+}
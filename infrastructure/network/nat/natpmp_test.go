@@ -0,0 +1,179 @@
+package nat
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePMPGateway is a stand-in for a gateway that supports NAT-PMP,
+// and PCP if supportsPCP is set
+type fakePMPGateway struct {
+	conn        net.PacketConn
+	supportsPCP bool
+	externalIP  net.IP
+
+	mutex    sync.Mutex
+	mappings map[uint16]uint32 // internal port -> lifetime
+}
+
+func newFakePMPGateway(t *testing.T, supportsPCP bool) *fakePMPGateway {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %s", err)
+	}
+	gateway := &fakePMPGateway{
+		conn:        conn,
+		supportsPCP: supportsPCP,
+		externalIP:  net.ParseIP("203.0.113.9").To4(),
+		mappings:    make(map[uint16]uint32),
+	}
+	t.Cleanup(func() { conn.Close() })
+	go gateway.serve()
+
+	originalPMPPort, originalGatewayCandidates := pmpPort, gatewayCandidates
+	pmpPort = conn.LocalAddr().(*net.UDPAddr).Port
+	gatewayCandidates = func() []net.IP { return []net.IP{net.ParseIP("127.0.0.1")} }
+	t.Cleanup(func() { pmpPort, gatewayCandidates = originalPMPPort, originalGatewayCandidates })
+
+	return gateway
+}
+
+func (g *fakePMPGateway) serve() {
+	buffer := make([]byte, 1100)
+	for {
+		n, address, err := g.conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		request := buffer[:n]
+		var reply []byte
+		switch {
+		case request[0] == pcpVersion && g.supportsPCP:
+			reply = g.pcpReply(request)
+		case request[0] == pcpVersion:
+			reply = []byte{natPMPVersion, request[1] | pmpReplyFlag, 0, pmpResultUnsupportedVersion,
+				0, 0, 0, 0}
+		default:
+			reply = g.natPMPReply(request)
+		}
+		g.conn.WriteTo(reply, address)
+	}
+}
+
+func (g *fakePMPGateway) pcpReply(request []byte) []byte {
+	reply := make([]byte, len(request))
+	copy(reply, request)
+	reply[1] |= pmpReplyFlag
+	reply[2], reply[3] = 0, pmpResultSuccess
+	if request[1] == pcpOpcodeMap {
+		lifetime := binary.BigEndian.Uint32(request[4:8])
+		payload := reply[pcpHeaderSize:]
+		internalPort := binary.BigEndian.Uint16(payload[16:18])
+		g.setMapping(internalPort, lifetime)
+		// The gateway assigns external ports with an offset of 1000
+		binary.BigEndian.PutUint16(payload[18:20], internalPort+1000)
+		copy(payload[20:36], g.externalIP.To16())
+	}
+	return reply
+}
+
+func (g *fakePMPGateway) natPMPReply(request []byte) []byte {
+	switch request[1] {
+	case natPMPOpcodeExternalAddress:
+		reply := make([]byte, 12)
+		reply[1] = natPMPOpcodeExternalAddress | pmpReplyFlag
+		copy(reply[8:12], g.externalIP)
+		return reply
+	case natPMPOpcodeMapTCP:
+		internalPort := binary.BigEndian.Uint16(request[4:6])
+		lifetime := binary.BigEndian.Uint32(request[8:12])
+		g.setMapping(internalPort, lifetime)
+		reply := make([]byte, 16)
+		reply[1] = natPMPOpcodeMapTCP | pmpReplyFlag
+		binary.BigEndian.PutUint16(reply[8:10], internalPort)
+		binary.BigEndian.PutUint16(reply[10:12], internalPort+1000)
+		binary.BigEndian.PutUint32(reply[12:16], lifetime)
+		return reply
+	}
+	return []byte{natPMPVersion, request[1] | pmpReplyFlag, 0, 5} // Unsupported opcode
+}
+
+func (g *fakePMPGateway) setMapping(internalPort uint16, lifetime uint32) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if lifetime == 0 {
+		delete(g.mappings, internalPort)
+		return
+	}
+	g.mappings[internalPort] = lifetime
+}
+
+func (g *fakePMPGateway) mapping(internalPort uint16) (lifetime uint32, ok bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	lifetime, ok = g.mappings[internalPort]
+	return lifetime, ok
+}
+
+func TestPMP(t *testing.T) {
+	tests := []struct {
+		name        string
+		supportsPCP bool
+	}{
+		{name: "PCP", supportsPCP: true},
+		{name: "NAT-PMP", supportsPCP: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeGateway := newFakePMPGateway(t, test.supportsPCP)
+
+			gateway, err := discoverPMP()
+			if err != nil {
+				t.Fatalf("discoverPMP: %s", err)
+			}
+			if gateway.isPCP != test.supportsPCP {
+				t.Fatalf("expected isPCP to be %t", test.supportsPCP)
+			}
+
+			externalAddress, err := gateway.AddPortMapping(16111, 16111, 20*time.Minute)
+			if err != nil {
+				t.Fatalf("AddPortMapping: %s", err)
+			}
+			if !externalAddress.IP.Equal(fakeGateway.externalIP) || externalAddress.Port != 17111 {
+				t.Fatalf("unexpected external address %s", externalAddress)
+			}
+			if lifetime, ok := fakeGateway.mapping(16111); !ok || lifetime != 1200 {
+				t.Fatalf("expected a mapping with a lifetime of 1200 seconds, got %d (exists: %t)", lifetime, ok)
+			}
+
+			err = gateway.DeletePortMapping(16111, uint16(externalAddress.Port))
+			if err != nil {
+				t.Fatalf("DeletePortMapping: %s", err)
+			}
+			if _, ok := fakeGateway.mapping(16111); ok {
+				t.Fatalf("the mapping was not deleted")
+			}
+		})
+	}
+}
+
+func TestPMPNoGateway(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %s", err)
+	}
+	defer conn.Close()
+
+	originalPMPPort, originalGatewayCandidates := pmpPort, gatewayCandidates
+	pmpPort = conn.LocalAddr().(*net.UDPAddr).Port
+	gatewayCandidates = func() []net.IP { return []net.IP{net.ParseIP("127.0.0.1")} }
+	defer func() { pmpPort, gatewayCandidates = originalPMPPort, originalGatewayCandidates }()
+
+	_, err = discoverPMP()
+	if err == nil {
+		t.Fatalf("discoverPMP unexpectedly found a gateway")
+	}
+}
@@ -0,0 +1,103 @@
+package nat
+
+import (
+	"net"
+	"time"
+)
+
+const (
+	// mappingLifetime is the lease duration requested for port mappings.
+	// Mappings are renewed when half of their lease passes.
+	mappingLifetime = 20 * time.Minute
+
+	// mappingRetryDivisor divides mappingLifetime to get the interval
+	// between attempts to create or renew a mapping after a failure
+	mappingRetryDivisor = 8
+)
+
+// PortMapper keeps a TCP port of this host mapped on the NAT gateway of the local
+// network, and deletes the mapping once it's stopped
+type PortMapper struct {
+	port              uint16
+	onExternalAddress func(previousAddress, externalAddress *net.TCPAddr)
+	discover          func() (Gateway, error)
+	lifetime          time.Duration
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewPortMapper returns a PortMapper that maps port to the same external port if the
+// gateway allows it. onExternalAddress is called whenever the external address of the
+// mapping changes, with its previous and its new external address. The previous address
+// is nil when the mapping is created, and the new one is nil once the mapping is deleted.
+func NewPortMapper(port uint16, onExternalAddress func(previousAddress, externalAddress *net.TCPAddr)) *PortMapper {
+	return &PortMapper{
+		port:              port,
+		onExternalAddress: onExternalAddress,
+		discover:          Discover,
+		lifetime:          mappingLifetime,
+		quit:              make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+}
+
+// Start discovers the NAT gateway and maps the port on it in the background
+func (pm *PortMapper) Start() {
+	spawn("PortMapper.mapPortLoop", pm.mapPortLoop)
+}
+
+// Stop stops renewing the port mapping and deletes it from the gateway.
+// It must only be called after Start.
+func (pm *PortMapper) Stop() {
+	close(pm.quit)
+	<-pm.done
+}
+
+func (pm *PortMapper) mapPortLoop() {
+	defer close(pm.done)
+
+	gateway, err := pm.discover()
+	if err != nil {
+		log.Warnf("Could not map port %d: %s", pm.port, err)
+		return
+	}
+	log.Infof("Discovered NAT gateway %s", gateway)
+
+	var externalAddress *net.TCPAddr
+	externalPort := pm.port
+	for {
+		nextAttempt := pm.lifetime / 2
+		mappedAddress, err := gateway.AddPortMapping(pm.port, externalPort, pm.lifetime)
+		if err != nil {
+			log.Warnf("Could not map port %d on %s: %s", pm.port, gateway, err)
+			nextAttempt = pm.lifetime / mappingRetryDivisor
+		} else if externalAddress == nil || !mappedAddress.IP.Equal(externalAddress.IP) ||
+			mappedAddress.Port != externalAddress.Port {
+
+			log.Infof("Mapped port %d to external address %s on %s", pm.port, mappedAddress, gateway)
+			previousAddress := externalAddress
+			externalAddress = mappedAddress
+			externalPort = uint16(mappedAddress.Port)
+			pm.onExternalAddress(previousAddress, mappedAddress)
+		}
+
+		select {
+		case <-pm.quit:
+			if externalAddress == nil {
+				return
+			}
+			// The node stops, so the external address is withdrawn even if the gateway
+			// doesn't delete the mapping
+			pm.onExternalAddress(externalAddress, nil)
+			err := gateway.DeletePortMapping(pm.port, externalPort)
+			if err != nil {
+				log.Warnf("Could not delete the mapping of port %d on %s: %s", pm.port, gateway, err)
+				return
+			}
+			log.Infof("Deleted the mapping of port %d on %s", pm.port, gateway)
+			return
+		case <-time.After(nextAttempt):
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package nat
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type fakeGateway struct {
+	mutex       sync.Mutex
+	externalIP  net.IP
+	mappings    map[uint16]uint16
+	addCount    int
+	deleteCount int
+	failAdds    bool
+}
+
+func newFakeGateway(externalIP string) *fakeGateway {
+	return &fakeGateway{
+		externalIP: net.ParseIP(externalIP),
+		mappings:   make(map[uint16]uint16),
+	}
+}
+
+func (g *fakeGateway) AddPortMapping(internalPort, externalPort uint16, _ time.Duration) (*net.TCPAddr, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.addCount++
+	if g.failAdds {
+		return nil, errors.New("mapping failed")
+	}
+	g.mappings[externalPort] = internalPort
+	return &net.TCPAddr{IP: g.externalIP, Port: int(externalPort)}, nil
+}
+
+func (g *fakeGateway) DeletePortMapping(_, externalPort uint16) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.deleteCount++
+	delete(g.mappings, externalPort)
+	return nil
+}
+
+func (g *fakeGateway) String() string {
+	return "fake gateway"
+}
+
+func (g *fakeGateway) setExternalIP(externalIP string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.externalIP = net.ParseIP(externalIP)
+}
+
+func (g *fakeGateway) state() (addCount, deleteCount, mappingCount int) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.addCount, g.deleteCount, len(g.mappings)
+}
+
+type externalAddressChange struct {
+	previousAddress *net.TCPAddr
+	externalAddress *net.TCPAddr
+}
+
+func TestPortMapper(t *testing.T) {
+	gateway := newFakeGateway("203.0.113.1")
+	externalAddressChanges := make(chan externalAddressChange, 10)
+
+	portMapper := NewPortMapper(16111, func(previousAddress, externalAddress *net.TCPAddr) {
+		externalAddressChanges <- externalAddressChange{previousAddress, externalAddress}
+	})
+	portMapper.discover = func() (Gateway, error) { return gateway, nil }
+	portMapper.lifetime = 100 * time.Millisecond
+	portMapper.Start()
+
+	addressString := func(address *net.TCPAddr) string {
+		if address == nil {
+			return "none"
+		}
+		return address.String()
+	}
+	expectExternalAddressChange := func(expectedPrevious, expected string) {
+		select {
+		case change := <-externalAddressChanges:
+			previous, current := addressString(change.previousAddress), addressString(change.externalAddress)
+			if previous != expectedPrevious || current != expected {
+				t.Fatalf("expected the external address to change from %s to %s, but it changed from %s to %s",
+					expectedPrevious, expected, previous, current)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the external address to change from %s to %s",
+				expectedPrevious, expected)
+		}
+	}
+	expectExternalAddressChange("none", "203.0.113.1:16111")
+
+	// The mapping is renewed before its lease expires, and the external
+	// address is only reported again once it changes
+	time.Sleep(200 * time.Millisecond)
+	if addCount, _, _ := gateway.state(); addCount < 3 {
+		t.Fatalf("expected the mapping to be renewed, but it was only added %d times", addCount)
+	}
+	select {
+	case change := <-externalAddressChanges:
+		t.Fatalf("unexpected external address %s reported while it didn't change", change.externalAddress)
+	default:
+	}
+	gateway.setExternalIP("203.0.113.2")
+	expectExternalAddressChange("203.0.113.1:16111", "203.0.113.2:16111")
+
+	// The external address is withdrawn once the mapping is deleted
+	portMapper.Stop()
+	expectExternalAddressChange("203.0.113.2:16111", "none")
+	if _, deleteCount, mappingCount := gateway.state(); deleteCount != 1 || mappingCount != 0 {
+		t.Fatalf("expected the mapping to be deleted once on Stop, but it was deleted %d times "+
+			"and %d mappings remain", deleteCount, mappingCount)
+	}
+}
+
+func TestPortMapperRetry(t *testing.T) {
+	gateway := newFakeGateway("203.0.113.1")
+	gateway.failAdds = true
+	externalAddresses := make(chan *net.TCPAddr, 10)
+
+	portMapper := NewPortMapper(16111, func(_, externalAddress *net.TCPAddr) {
+		if externalAddress != nil {
+			externalAddresses <- externalAddress
+		}
+	})
+	portMapper.discover = func() (Gateway, error) { return gateway, nil }
+	portMapper.lifetime = 80 * time.Millisecond
+	portMapper.Start()
+
+	time.Sleep(100 * time.Millisecond)
+	gateway.mutex.Lock()
+	gateway.failAdds = false
+	gateway.mutex.Unlock()
+
+	select {
+	case externalAddress := <-externalAddresses:
+		if externalAddress.String() != "203.0.113.1:16111" {
+			t.Fatalf("unexpected external address %s", externalAddress)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the mapping was not retried after it failed")
+	}
+	portMapper.Stop()
+}
+
+func TestPortMapperNoGateway(t *testing.T) {
+	portMapper := NewPortMapper(16111, func(_, externalAddress *net.TCPAddr) {
+		t.Errorf("unexpected external address %s", externalAddress)
+	})
+	portMapper.discover = func() (Gateway, error) { return nil, errors.New("no gateway") }
+	portMapper.Start()
+	portMapper.Stop()
+}
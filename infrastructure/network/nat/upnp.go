@@ -0,0 +1,339 @@
+package nat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ssdpAddress is the address UPnP devices are searched for at. It's a variable so
+// that tests can search for fake gateways.
+var ssdpAddress = "239.255.255.250:1900"
+
+var igdDeviceTypes = []string{
+	"urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+	"urn:schemas-upnp-org:device:InternetGatewayDevice:2",
+}
+
+var wanConnectionServiceTypePrefixes = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:",
+	"urn:schemas-upnp-org:service:WANPPPConnection:",
+}
+
+const (
+	upnpRequestTimeout         = 5 * time.Second
+	upnpPortMappingDescription = "kaspid"
+
+	// upnpErrorOnlyPermanentLeasesSupported is returned by gateways that
+	// don't support port mappings with a lease duration
+	upnpErrorOnlyPermanentLeasesSupported = "725"
+)
+
+// upnpGateway is an Internet Gateway Device, as specified in
+// https://openconnectivity.org/developer/specifications/upnp-resources/upnp/internet-gateway-device-igd-v-2-0/
+type upnpGateway struct {
+	controlURL     string
+	serviceType    string
+	internalClient net.IP
+	client         *http.Client
+}
+
+type upnpDeviceDescription struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	DeviceType string        `xml:"deviceType"`
+	Services   []upnpService `xml:"serviceList>service"`
+	Devices    []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// discoverUPnP searches for an Internet Gateway Device with SSDP, and returns
+// the first one found that has a WAN connection service
+func discoverUPnP(timeout time.Duration) (*upnpGateway, error) {
+	searchAddress, err := net.ResolveUDPAddr("udp4", ssdpAddress)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open an SSDP socket")
+	}
+	defer conn.Close()
+
+	for _, deviceType := range igdDeviceTypes {
+		search := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\n"+
+			"HOST: %s\r\n"+
+			"ST: %s\r\n"+
+			"MAN: \"ssdp:discover\"\r\n"+
+			"MX: 2\r\n\r\n", ssdpAddress, deviceType)
+		_, err := conn.WriteTo([]byte(search), searchAddress)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not send an SSDP search")
+		}
+	}
+
+	err = conn.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return nil, errors.New("no UPnP Internet Gateway Device responded")
+			}
+			return nil, err
+		}
+		response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buffer[:n])), nil)
+		if err != nil {
+			continue
+		}
+		location := response.Header.Get("Location")
+		if location == "" {
+			continue
+		}
+		gateway, err := newUPnPGateway(location)
+		if err != nil {
+			log.Debugf("Skipping UPnP device at %s: %s", location, err)
+			continue
+		}
+		return gateway, nil
+	}
+}
+
+// newUPnPGateway returns the gateway described by the device description at location
+func newUPnPGateway(location string) (*upnpGateway, error) {
+	client := &http.Client{Timeout: upnpRequestTimeout}
+	response, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %s", response.Status)
+	}
+	var description upnpDeviceDescription
+	err = xml.NewDecoder(response.Body).Decode(&description)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed device description")
+	}
+
+	service, ok := findWANConnectionService(&description.Device)
+	if !ok {
+		return nil, errors.New("the device has no WAN connection service")
+	}
+	baseURL, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if description.URLBase != "" {
+		baseURL, err = url.Parse(description.URLBase)
+		if err != nil {
+			return nil, errors.Wrap(err, "malformed URLBase")
+		}
+	}
+	controlURL, err := baseURL.Parse(service.ControlURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed controlURL")
+	}
+
+	internalClient, err := localIPTowards(controlURL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	return &upnpGateway{
+		controlURL:     controlURL.String(),
+		serviceType:    service.ServiceType,
+		internalClient: internalClient,
+		client:         client,
+	}, nil
+}
+
+func findWANConnectionService(device *upnpDevice) (*upnpService, bool) {
+	for i := range device.Services {
+		for _, prefix := range wanConnectionServiceTypePrefixes {
+			if strings.HasPrefix(device.Services[i].ServiceType, prefix) {
+				return &device.Services[i], true
+			}
+		}
+	}
+	for i := range device.Devices {
+		if service, ok := findWANConnectionService(&device.Devices[i]); ok {
+			return service, true
+		}
+	}
+	return nil, false
+}
+
+// localIPTowards returns the IP address of the local interface that
+// packets to the given host are sent from
+func localIPTowards(host string) (net.IP, error) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(host, "1"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not find the local address towards %s", host)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+func (g *upnpGateway) String() string {
+	return fmt.Sprintf("UPnP gateway %s", g.controlURL)
+}
+
+// AddPortMapping maps externalPort of the gateway to internalPort of this host
+// This is part of the Gateway interface
+func (g *upnpGateway) AddPortMapping(internalPort, externalPort uint16, lifetime time.Duration) (
+	*net.TCPAddr, error) {
+
+	err := g.addPortMapping(internalPort, externalPort, lifetime)
+	if err != nil {
+		var upnpErr *upnpError
+		if !errors.As(err, &upnpErr) || upnpErr.code != upnpErrorOnlyPermanentLeasesSupported {
+			return nil, err
+		}
+		err = g.addPortMapping(internalPort, externalPort, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := g.soapRequest("GetExternalIPAddress", nil)
+	if err != nil {
+		return nil, err
+	}
+	externalIP := net.ParseIP(reply["NewExternalIPAddress"])
+	if externalIP == nil {
+		return nil, errors.Errorf("%s replied with an invalid external address %q",
+			g, reply["NewExternalIPAddress"])
+	}
+	return &net.TCPAddr{IP: externalIP, Port: int(externalPort)}, nil
+}
+
+func (g *upnpGateway) addPortMapping(internalPort, externalPort uint16, lifetime time.Duration) error {
+	_, err := g.soapRequest("AddPortMapping", []soapArgument{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(int(externalPort))},
+		{"NewProtocol", "TCP"},
+		{"NewInternalPort", strconv.Itoa(int(internalPort))},
+		{"NewInternalClient", g.internalClient.String()},
+		{"NewEnabled", "1"},
+		{"NewPortMappingDescription", upnpPortMappingDescription},
+		{"NewLeaseDuration", strconv.Itoa(int(lifetime / time.Second))},
+	})
+	return err
+}
+
+// DeletePortMapping deletes the mapping of externalPort
+// This is part of the Gateway interface
+func (g *upnpGateway) DeletePortMapping(_, externalPort uint16) error {
+	_, err := g.soapRequest("DeletePortMapping", []soapArgument{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(int(externalPort))},
+		{"NewProtocol", "TCP"},
+	})
+	return err
+}
+
+type soapArgument struct {
+	name  string
+	value string
+}
+
+// upnpError is an error reported by a UPnP device in a SOAP fault
+type upnpError struct {
+	action      string
+	code        string
+	description string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("%s failed with UPnP error %s: %s", e.action, e.code, e.description)
+}
+
+// soapRequest invokes the given action of the WAN connection service, and
+// returns the elements of the reply by their names
+func (g *upnpGateway) soapRequest(action string, arguments []soapArgument) (map[string]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, g.serviceType)
+	for _, argument := range arguments {
+		fmt.Fprintf(&body, "<%s>", argument.name)
+		err := xml.EscapeText(&body, []byte(argument.value))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&body, "</%s>", argument.name)
+	}
+	fmt.Fprintf(&body, "</u:%s></s:Body></s:Envelope>", action)
+
+	request, err := http.NewRequest(http.MethodPost, g.controlURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	request.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, g.serviceType, action))
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s request to %s failed", action, g)
+	}
+	defer response.Body.Close()
+	reply, err := parseSOAPReply(response.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "malformed reply to %s from %s", action, g)
+	}
+	if response.StatusCode != http.StatusOK {
+		if code, ok := reply["errorCode"]; ok {
+			return nil, &upnpError{action: action, code: code, description: reply["errorDescription"]}
+		}
+		return nil, errors.Errorf("%s request to %s failed with status %s", action, g, response.Status)
+	}
+	return reply, nil
+}
+
+// parseSOAPReply returns the text of the leaf elements of a SOAP reply by their names
+func parseSOAPReply(reader io.Reader) (map[string]string, error) {
+	reply := make(map[string]string)
+	decoder := xml.NewDecoder(reader)
+	var elementName string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return reply, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			elementName = token.Name.Local
+		case xml.CharData:
+			if elementName != "" {
+				reply[elementName] = strings.TrimSpace(string(token))
+			}
+		case xml.EndElement:
+			elementName = ""
+		}
+	}
+}
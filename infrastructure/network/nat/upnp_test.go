@@ -0,0 +1,235 @@
+package nat
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fakeIGDDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/control/wanip</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+// fakeIGD is a stand-in for a UPnP Internet Gateway Device
+type fakeIGD struct {
+	t                   *testing.T
+	server              *httptest.Server
+	ssdpConn            net.PacketConn
+	onlyPermanentLeases bool
+	mutex               sync.Mutex
+	mappings            map[string]string
+	leaseDurations      []string
+	soapActionHeaders   []string
+}
+
+func newFakeIGD(t *testing.T, onlyPermanentLeases bool) *fakeIGD {
+	igd := &fakeIGD{
+		t:                   t,
+		onlyPermanentLeases: onlyPermanentLeases,
+		mappings:            make(map[string]string),
+	}
+	igd.server = httptest.NewServer(http.HandlerFunc(igd.serveHTTP))
+	t.Cleanup(igd.server.Close)
+
+	ssdpConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %s", err)
+	}
+	igd.ssdpConn = ssdpConn
+	t.Cleanup(func() { ssdpConn.Close() })
+	go igd.serveSSDP()
+
+	originalSSDPAddress := ssdpAddress
+	ssdpAddress = ssdpConn.LocalAddr().String()
+	t.Cleanup(func() { ssdpAddress = originalSSDPAddress })
+
+	return igd
+}
+
+func (igd *fakeIGD) serveSSDP() {
+	buffer := make([]byte, 2048)
+	for {
+		n, address, err := igd.ssdpConn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		search := string(buffer[:n])
+		if !strings.HasPrefix(search, "M-SEARCH") {
+			continue
+		}
+		response := "HTTP/1.1 200 OK\r\n" +
+			"CACHE-CONTROL: max-age=120\r\n" +
+			"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+			"LOCATION: " + igd.server.URL + "/rootDesc.xml\r\n\r\n"
+		igd.ssdpConn.WriteTo([]byte(response), address)
+	}
+}
+
+func (igd *fakeIGD) serveHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.URL.Path {
+	case "/rootDesc.xml":
+		io.WriteString(writer, fakeIGDDescription)
+		return
+	case "/control/wanip":
+	default:
+		http.NotFound(writer, request)
+		return
+	}
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		igd.t.Errorf("ReadAll: %s", err)
+		return
+	}
+	arguments, err := parseSOAPReply(strings.NewReader(string(body)))
+	if err != nil {
+		igd.t.Errorf("malformed SOAP request: %s", err)
+		return
+	}
+
+	igd.mutex.Lock()
+	defer igd.mutex.Unlock()
+	igd.soapActionHeaders = append(igd.soapActionHeaders, request.Header.Get("SOAPAction"))
+
+	serviceType := "urn:schemas-upnp-org:service:WANIPConnection:1"
+	action := strings.TrimSuffix(strings.TrimPrefix(request.Header.Get("SOAPAction"), `"`+serviceType+"#"), `"`)
+	var reply string
+	switch action {
+	case "GetExternalIPAddress":
+		reply = "<NewExternalIPAddress>203.0.113.7</NewExternalIPAddress>"
+	case "AddPortMapping":
+		igd.leaseDurations = append(igd.leaseDurations, arguments["NewLeaseDuration"])
+		if igd.onlyPermanentLeases && arguments["NewLeaseDuration"] != "0" {
+			writeSOAPFault(writer, "725", "OnlyPermanentLeasesSupported")
+			return
+		}
+		igd.mappings[arguments["NewExternalPort"]] = arguments["NewInternalClient"] + ":" +
+			arguments["NewInternalPort"]
+	case "DeletePortMapping":
+		if _, ok := igd.mappings[arguments["NewExternalPort"]]; !ok {
+			writeSOAPFault(writer, "714", "NoSuchEntryInArray")
+			return
+		}
+		delete(igd.mappings, arguments["NewExternalPort"])
+	default:
+		writeSOAPFault(writer, "401", "Invalid Action")
+		return
+	}
+	fmt.Fprintf(writer, `<?xml version="1.0"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+		`<u:%sResponse xmlns:u="%s">%s</u:%sResponse></s:Body></s:Envelope>`,
+		action, serviceType, reply, action)
+}
+
+func writeSOAPFault(writer http.ResponseWriter, code, description string) {
+	writer.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(writer, `<?xml version="1.0"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>`+
+		`<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+		`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0">`+
+		`<errorCode>%s</errorCode><errorDescription>%s</errorDescription>`+
+		`</UPnPError></detail></s:Fault></s:Body></s:Envelope>`, code, description)
+}
+
+func TestUPnP(t *testing.T) {
+	igd := newFakeIGD(t, false)
+
+	gateway, err := discoverUPnP(time.Second)
+	if err != nil {
+		t.Fatalf("discoverUPnP: %s", err)
+	}
+	if gateway.controlURL != igd.server.URL+"/control/wanip" {
+		t.Fatalf("unexpected control URL %s", gateway.controlURL)
+	}
+
+	externalAddress, err := gateway.AddPortMapping(16111, 16111, 20*time.Minute)
+	if err != nil {
+		t.Fatalf("AddPortMapping: %s", err)
+	}
+	if externalAddress.String() != "203.0.113.7:16111" {
+		t.Fatalf("unexpected external address %s", externalAddress)
+	}
+	if mapping := igd.mappings["16111"]; mapping != "127.0.0.1:16111" {
+		t.Fatalf("unexpected mapping %q", mapping)
+	}
+	if igd.leaseDurations[0] != "1200" {
+		t.Fatalf("unexpected lease duration %s", igd.leaseDurations[0])
+	}
+	expectedSOAPAction := `"urn:schemas-upnp-org:service:WANIPConnection:1#AddPortMapping"`
+	if igd.soapActionHeaders[0] != expectedSOAPAction {
+		t.Fatalf("expected SOAPAction %s, got %s", expectedSOAPAction, igd.soapActionHeaders[0])
+	}
+
+	err = gateway.DeletePortMapping(16111, 16111)
+	if err != nil {
+		t.Fatalf("DeletePortMapping: %s", err)
+	}
+	if len(igd.mappings) != 0 {
+		t.Fatalf("the mapping was not deleted")
+	}
+
+	err = gateway.DeletePortMapping(16111, 16111)
+	if err == nil || !strings.Contains(err.Error(), "714") {
+		t.Fatalf("expected UPnP error 714 when deleting a missing mapping, got %v", err)
+	}
+}
+
+func TestUPnPOnlyPermanentLeases(t *testing.T) {
+	igd := newFakeIGD(t, true)
+
+	gateway, err := discoverUPnP(time.Second)
+	if err != nil {
+		t.Fatalf("discoverUPnP: %s", err)
+	}
+	_, err = gateway.AddPortMapping(16111, 16111, 20*time.Minute)
+	if err != nil {
+		t.Fatalf("AddPortMapping: %s", err)
+	}
+	if len(igd.leaseDurations) != 2 || igd.leaseDurations[1] != "0" {
+		t.Fatalf("expected a retry with a permanent lease, got lease durations %v", igd.leaseDurations)
+	}
+	if _, ok := igd.mappings["16111"]; !ok {
+		t.Fatalf("the mapping was not added")
+	}
+}
+
+func TestUPnPNoGateway(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %s", err)
+	}
+	defer conn.Close()
+	originalSSDPAddress := ssdpAddress
+	ssdpAddress = conn.LocalAddr().String()
+	defer func() { ssdpAddress = originalSSDPAddress }()
+
+	_, err = discoverUPnP(100 * time.Millisecond)
+	if err == nil {
+		t.Fatalf("discoverUPnP unexpectedly found a gateway")
+	}
+}
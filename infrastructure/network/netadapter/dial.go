@@ -0,0 +1,64 @@
+package netadapter
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/infrastructure/config"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server/grpcserver"
+)
+
+// unreachableNetworkError is returned when dialing an address in an overlay
+// network that has no proxy configured. It isn't temporary, so that gRPC
+// doesn't keep retrying to dial the address until the dial timeout.
+type unreachableNetworkError struct {
+	address string
+}
+
+func (e unreachableNetworkError) Error() string {
+	return "cannot connect to " + e.address + ": no proxy is configured for its network"
+}
+
+func (e unreachableNetworkError) Temporary() bool {
+	return false
+}
+
+// p2pDialer returns the dialer of outbound P2P connections. Addresses in overlay
+// networks are dialed through the proxy of their network, and all other
+// addresses through cfg.Dial.
+func p2pDialer(cfg *config.Config) grpcserver.Dialer {
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		dial := cfg.Dial
+		if appmessage.IsOverlayHost(host) {
+			netAddress, err := appmessage.NewNetAddressOverlayHost(host, 0)
+			if err != nil {
+				return nil, err
+			}
+			switch netAddress.Network {
+			case appmessage.AddressNetworkTorV3:
+				dial = cfg.OnionDial
+			case appmessage.AddressNetworkI2P:
+				dial = cfg.I2PDial
+			}
+			if dial == nil {
+				return nil, unreachableNetworkError{address: address}
+			}
+		}
+		if dial == nil {
+			dial = net.DialTimeout
+		}
+
+		timeout := config.DefaultConnectTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = time.Until(deadline)
+		}
+		return dial("tcp", address, timeout)
+	}
+}
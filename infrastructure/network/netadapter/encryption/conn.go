@@ -0,0 +1,153 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// maxFramePayloadSize is the maximum amount of plaintext that is sealed in a single frame.
+// Larger writes are split into several frames.
+const maxFramePayloadSize = 1 << 16
+
+const frameLengthSize = 4
+
+// encryptedConn is a net.Conn that seals everything written to it into length-prefixed
+// AEAD frames, and opens frames read from the underlying connection. Every direction has
+// its own key, and frames are numbered by a nonce counter, so that frames that are
+// reordered, replayed or dropped fail to open.
+type encryptedConn struct {
+	net.Conn
+	reader io.Reader
+
+	readLock   sync.Mutex
+	readAEAD   cipher.AEAD
+	readNonce  uint64
+	readBuffer []byte
+
+	writeLock  sync.Mutex
+	writeAEAD  cipher.AEAD
+	writeNonce uint64
+}
+
+func newEncryptedConn(conn net.Conn, reader io.Reader, readKey []byte, writeKey []byte) (*encryptedConn, error) {
+	readAEAD, err := chacha20poly1305.New(readKey)
+	if err != nil {
+		return nil, err
+	}
+	writeAEAD, err := chacha20poly1305.New(writeKey)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedConn{
+		Conn:      conn,
+		reader:    reader,
+		readAEAD:  readAEAD,
+		writeAEAD: writeAEAD,
+	}, nil
+}
+
+func (c *encryptedConn) Read(b []byte) (int, error) {
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	for len(c.readBuffer) == 0 {
+		frame, err := c.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		c.readBuffer = frame
+	}
+
+	n := copy(b, c.readBuffer)
+	c.readBuffer = c.readBuffer[n:]
+	return n, nil
+}
+
+func (c *encryptedConn) readFrame() ([]byte, error) {
+	var frameLength [frameLengthSize]byte
+	_, err := io.ReadFull(c.reader, frameLength[:])
+	if err != nil {
+		return nil, err
+	}
+	ciphertextLength := binary.BigEndian.Uint32(frameLength[:])
+	if ciphertextLength < uint32(c.readAEAD.Overhead()) ||
+		ciphertextLength > uint32(maxFramePayloadSize+c.readAEAD.Overhead()) {
+		return nil, errors.Errorf("invalid encrypted frame length %d", ciphertextLength)
+	}
+
+	ciphertext := make([]byte, ciphertextLength)
+	_, err = io.ReadFull(c.reader, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := frameNonce(&c.readNonce)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := c.readAEAD.Open(ciphertext[:0], nonce, ciphertext, frameLength[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open encrypted frame")
+	}
+	return plaintext, nil
+}
+
+func (c *encryptedConn) Write(b []byte) (int, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	written := 0
+	for len(b) > 0 {
+		payload := b
+		if len(payload) > maxFramePayloadSize {
+			payload = payload[:maxFramePayloadSize]
+		}
+		err := c.writeFrame(payload)
+		if err != nil {
+			return written, err
+		}
+		written += len(payload)
+		b = b[len(payload):]
+	}
+	return written, nil
+}
+
+func (c *encryptedConn) writeFrame(payload []byte) error {
+	nonce, err := frameNonce(&c.writeNonce)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, frameLengthSize, frameLengthSize+len(payload)+c.writeAEAD.Overhead())
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)+c.writeAEAD.Overhead()))
+	frame = c.writeAEAD.Seal(frame, nonce, payload, frame[:frameLengthSize])
+	_, err = c.Conn.Write(frame)
+	return err
+}
+
+// frameNonce returns the nonce of the next frame and advances the given counter
+func frameNonce(counter *uint64) ([]byte, error) {
+	if *counter == ^uint64(0) {
+		return nil, errors.New("encrypted frame nonce counter exhausted")
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], *counter)
+	*counter++
+	return nonce, nil
+}
+
+// prefixedConn is a net.Conn whose first bytes were already consumed into a buffered reader
+type prefixedConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c *prefixedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
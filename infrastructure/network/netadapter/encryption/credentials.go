@@ -0,0 +1,172 @@
+package encryption
+
+import (
+	"context"
+	"crypto/ed25519"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	authType = "kaspid-p2p-encryption"
+
+	// handshakeTimeout is the time the remote peer has to complete the handshake
+	handshakeTimeout = 10 * time.Second
+)
+
+// AuthInfo describes the security of a P2P connection. It implements credentials.AuthInfo.
+type AuthInfo struct {
+	credentials.CommonAuthInfo
+
+	// RemoteIdentityKey is the static identity key the remote peer authenticated with.
+	// It is nil if the connection is not encrypted.
+	RemoteIdentityKey ed25519.PublicKey
+
+	// IsTrusted is whether the remote peer authenticated with the identity key of a trusted peer
+	IsTrusted bool
+}
+
+// AuthType returns the type of the AuthInfo. This is part of the credentials.AuthInfo interface.
+func (a *AuthInfo) AuthType() string {
+	return authType
+}
+
+// IsEncrypted returns whether the connection is encrypted
+func (a *AuthInfo) IsEncrypted() bool {
+	return a.RemoteIdentityKey != nil
+}
+
+// Credentials are gRPC transport credentials that opportunistically encrypt P2P connections.
+// Servers accept plaintext connections from clients that do not open with an encryption
+// handshake, unless encryption is required.
+type Credentials struct {
+	identityKey  ed25519.PrivateKey
+	isRequired   bool
+	trustedPeers *TrustedPeers
+}
+
+// NewCredentials returns new Credentials that authenticate this node with the given identity key
+func NewCredentials(identityKey ed25519.PrivateKey, isRequired bool, trustedPeers *TrustedPeers) *Credentials {
+	return &Credentials{
+		identityKey:  identityKey,
+		isRequired:   isRequired,
+		trustedPeers: trustedPeers,
+	}
+}
+
+// IdentityPublicKey returns the public identity key this node authenticates with
+func (c *Credentials) IdentityPublicKey() ed25519.PublicKey {
+	return c.identityKey.Public().(ed25519.PublicKey)
+}
+
+// IsRequired returns whether plaintext connections are refused
+func (c *Credentials) IsRequired() bool {
+	return c.isRequired
+}
+
+// AllowsPlaintextTo returns whether a plaintext connection to the given address may be
+// made once it turns out not to support encryption
+func (c *Credentials) AllowsPlaintextTo(address string) bool {
+	return !c.isRequired && !c.trustedPeers.IsTrustedAddress(address)
+}
+
+// ClientHandshake does the authentication handshake for outbound connections.
+// This is part of the credentials.TransportCredentials interface.
+func (c *Credentials) ClientHandshake(ctx context.Context, authority string,
+	rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(handshakeTimeout)
+	}
+	err := rawConn.SetDeadline(deadline)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rawConn.SetDeadline(time.Time{})
+
+	result, err := clientHandshake(rawConn, c.identityKey)
+	if err != nil {
+		return nil, nil, handshakeError{err}
+	}
+
+	expectedIdentityKey := c.trustedPeers.keyByAddress(authority)
+	if expectedIdentityKey != nil && !expectedIdentityKey.Equal(result.remoteIdentityKey) {
+		return nil, nil, handshakeError{errors.Errorf("trusted peer %s authenticated with identity key %x "+
+			"rather than %x", authority, []byte(result.remoteIdentityKey), []byte(expectedIdentityKey))}
+	}
+
+	return result.conn, c.authInfo(result.remoteIdentityKey), nil
+}
+
+// ServerHandshake does the authentication handshake for inbound connections.
+// This is part of the credentials.TransportCredentials interface.
+func (c *Credentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	err := rawConn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rawConn.SetDeadline(time.Time{})
+
+	result, err := serverHandshake(rawConn, c.identityKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.remoteIdentityKey == nil && c.isRequired {
+		return nil, nil, errors.Errorf("refusing plaintext connection from %s", rawConn.RemoteAddr())
+	}
+
+	return result.conn, c.authInfo(result.remoteIdentityKey), nil
+}
+
+func (c *Credentials) authInfo(remoteIdentityKey ed25519.PublicKey) *AuthInfo {
+	securityLevel := credentials.NoSecurity
+	if remoteIdentityKey != nil {
+		securityLevel = credentials.PrivacyAndIntegrity
+	}
+	return &AuthInfo{
+		CommonAuthInfo:    credentials.CommonAuthInfo{SecurityLevel: securityLevel},
+		RemoteIdentityKey: remoteIdentityKey,
+		IsTrusted:         remoteIdentityKey != nil && c.trustedPeers.isTrustedKey(remoteIdentityKey),
+	}
+}
+
+// Info provides the ProtocolInfo of these Credentials.
+// This is part of the credentials.TransportCredentials interface.
+func (c *Credentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{
+		SecurityProtocol: authType,
+		SecurityVersion:  "1",
+	}
+}
+
+// Clone makes a copy of these Credentials.
+// This is part of the credentials.TransportCredentials interface.
+func (c *Credentials) Clone() credentials.TransportCredentials {
+	clone := *c
+	return &clone
+}
+
+// OverrideServerName is a no-op, since P2P connections are authenticated by
+// identity keys rather than by server names.
+// This is part of the credentials.TransportCredentials interface.
+func (c *Credentials) OverrideServerName(string) error {
+	return nil
+}
+
+// handshakeError marks handshake errors as permanent, so that gRPC fails
+// the dial immediately rather than retrying the handshake
+type handshakeError struct {
+	error
+}
+
+func (e handshakeError) Temporary() bool {
+	return false
+}
+
+func (e handshakeError) Unwrap() error {
+	return e.error
+}
@@ -0,0 +1,300 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type handshakeOutcome struct {
+	conn     net.Conn
+	authInfo *AuthInfo
+	err      error
+}
+
+// connectedPair returns the two ends of a fresh loopback TCP connection
+func connectedPair(t *testing.T) (clientConn net.Conn, serverConn net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	defer listener.Close()
+
+	acceptedChan := make(chan net.Conn)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Errorf("Accept: %s", err)
+		}
+		acceptedChan <- conn
+	}()
+
+	clientConn, err = net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %s", err)
+	}
+	return clientConn, <-acceptedChan
+}
+
+func newTestCredentials(t *testing.T, isRequired bool, trustedPeers ...string) *Credentials {
+	identityKey, err := LoadOrCreateIdentityKey("")
+	if err != nil {
+		t.Fatalf("LoadOrCreateIdentityKey: %s", err)
+	}
+	parsedTrustedPeers, err := ParseTrustedPeers(trustedPeers)
+	if err != nil {
+		t.Fatalf("ParseTrustedPeers: %s", err)
+	}
+	return NewCredentials(identityKey, isRequired, parsedTrustedPeers)
+}
+
+func handshake(t *testing.T, clientCredentials, serverCredentials *Credentials,
+	authority string) (client handshakeOutcome, server handshakeOutcome) {
+
+	clientRawConn, serverRawConn := connectedPair(t)
+	serverOutcomeChan := make(chan handshakeOutcome)
+	go func() {
+		conn, authInfo, err := serverCredentials.ServerHandshake(serverRawConn)
+		outcome := handshakeOutcome{conn: conn, err: err}
+		if authInfo != nil {
+			outcome.authInfo = authInfo.(*AuthInfo)
+		}
+		serverOutcomeChan <- outcome
+	}()
+
+	conn, authInfo, err := clientCredentials.ClientHandshake(context.Background(), authority, clientRawConn)
+	client = handshakeOutcome{conn: conn, err: err}
+	if authInfo != nil {
+		client.authInfo = authInfo.(*AuthInfo)
+	}
+	if err != nil {
+		clientRawConn.Close()
+	}
+	return client, <-serverOutcomeChan
+}
+
+func TestEncryptedConnection(t *testing.T) {
+	clientCredentials := newTestCredentials(t, false)
+	serverCredentials := newTestCredentials(t, false)
+	client, server := handshake(t, clientCredentials, serverCredentials, "127.0.0.1:16111")
+	if client.err != nil || server.err != nil {
+		t.Fatalf("Handshake failed. Client error: %+v, server error: %+v", client.err, server.err)
+	}
+	defer client.conn.Close()
+	defer server.conn.Close()
+
+	if !client.authInfo.IsEncrypted() || !server.authInfo.IsEncrypted() {
+		t.Fatalf("Expected both sides of the connection to be encrypted")
+	}
+	if !client.authInfo.RemoteIdentityKey.Equal(serverCredentials.IdentityPublicKey()) {
+		t.Fatalf("The client got an unexpected server identity key")
+	}
+	if !server.authInfo.RemoteIdentityKey.Equal(clientCredentials.IdentityPublicKey()) {
+		t.Fatalf("The server got an unexpected client identity key")
+	}
+	if client.authInfo.IsTrusted || server.authInfo.IsTrusted {
+		t.Fatalf("Expected neither side to be trusted")
+	}
+
+	// Send more than a single frame in each direction
+	for _, direction := range []struct{ writer, reader net.Conn }{
+		{client.conn, server.conn},
+		{server.conn, client.conn},
+	} {
+		payload := make([]byte, 3*maxFramePayloadSize+1)
+		_, err := rand.Read(payload)
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		writeErrChan := make(chan error)
+		go func(writer net.Conn) {
+			_, err := writer.Write(payload)
+			writeErrChan <- err
+		}(direction.writer)
+
+		received := make([]byte, len(payload))
+		_, err = io.ReadFull(direction.reader, received)
+		if err != nil {
+			t.Fatalf("ReadFull: %s", err)
+		}
+		if err := <-writeErrChan; err != nil {
+			t.Fatalf("Write: %s", err)
+		}
+		if !bytes.Equal(payload, received) {
+			t.Fatalf("Received payload differs from the one sent")
+		}
+	}
+}
+
+func TestTamperedFrame(t *testing.T) {
+	key := make([]byte, handshakeKeySize)
+	writerRawConn, readerRawConn := connectedPair(t)
+	defer writerRawConn.Close()
+	defer readerRawConn.Close()
+
+	tamperingWriter := &tamperingConn{Conn: writerRawConn}
+	writer, err := newEncryptedConn(tamperingWriter, tamperingWriter, key, key)
+	if err != nil {
+		t.Fatalf("newEncryptedConn: %s", err)
+	}
+	reader, err := newEncryptedConn(readerRawConn, readerRawConn, key, key)
+	if err != nil {
+		t.Fatalf("newEncryptedConn: %s", err)
+	}
+
+	_, err = writer.Write([]byte("intact"))
+	if err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	received := make([]byte, len("intact"))
+	_, err = io.ReadFull(reader, received)
+	if err != nil {
+		t.Fatalf("ReadFull: %s", err)
+	}
+
+	tamperingWriter.shouldTamper = true
+	_, err = writer.Write([]byte("tampered"))
+	if err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	_, err = reader.Read(received)
+	if err == nil {
+		t.Fatalf("Expected a tampered frame to fail to open")
+	}
+}
+
+// tamperingConn flips the last bit of every write once shouldTamper is set
+type tamperingConn struct {
+	net.Conn
+	shouldTamper bool
+}
+
+func (c *tamperingConn) Write(b []byte) (int, error) {
+	if c.shouldTamper {
+		b = append([]byte(nil), b...)
+		b[len(b)-1] ^= 1
+	}
+	return c.Conn.Write(b)
+}
+
+func TestPlaintextClient(t *testing.T) {
+	const http2ClientPreface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+	for _, isRequired := range []bool{false, true} {
+		clientRawConn, serverRawConn := connectedPair(t)
+		_, err := clientRawConn.Write([]byte(http2ClientPreface))
+		if err != nil {
+			t.Fatalf("Write: %s", err)
+		}
+
+		conn, authInfo, err := newTestCredentials(t, isRequired).ServerHandshake(serverRawConn)
+		if isRequired {
+			if err == nil {
+				t.Fatalf("Expected a plaintext connection to be refused when encryption is required")
+			}
+			clientRawConn.Close()
+			serverRawConn.Close()
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ServerHandshake: %+v", err)
+		}
+		if authInfo.(*AuthInfo).IsEncrypted() {
+			t.Fatalf("Expected a plaintext connection")
+		}
+
+		// The bytes consumed while detecting the handshake must still be readable
+		received := make([]byte, len(http2ClientPreface))
+		_, err = io.ReadFull(conn, received)
+		if err != nil {
+			t.Fatalf("ReadFull: %s", err)
+		}
+		if string(received) != http2ClientPreface {
+			t.Fatalf("Unexpected plaintext. Want: %q, got: %q", http2ClientPreface, received)
+		}
+		clientRawConn.Close()
+		conn.Close()
+	}
+}
+
+func TestPlaintextServer(t *testing.T) {
+	clientRawConn, serverRawConn := connectedPair(t)
+	go func() {
+		// Behave like a server that expects the HTTP/2 client preface
+		preface := make([]byte, 24)
+		_, _ = io.ReadFull(serverRawConn, preface)
+		serverRawConn.Close()
+	}()
+
+	_, _, err := newTestCredentials(t, false).ClientHandshake(context.Background(), "127.0.0.1:16111", clientRawConn)
+	if !errors.Is(err, ErrEncryptionNotSupported) {
+		t.Fatalf("Expected ErrEncryptionNotSupported, got: %+v", err)
+	}
+	temporaryErr, ok := err.(interface{ Temporary() bool })
+	if !ok || temporaryErr.Temporary() {
+		t.Fatalf("Expected handshake errors to be permanent")
+	}
+}
+
+func TestTrustedPeers(t *testing.T) {
+	const trustedAddress = "127.0.0.1:16111"
+	serverCredentials := newTestCredentials(t, false)
+	serverIdentityKey := hex.EncodeToString(serverCredentials.IdentityPublicKey())
+
+	clientCredentials := newTestCredentials(t, false, serverIdentityKey+"@"+trustedAddress)
+	if clientCredentials.AllowsPlaintextTo(trustedAddress) {
+		t.Fatalf("Expected plaintext connections to trusted peers to be disallowed")
+	}
+	client, server := handshake(t, clientCredentials, serverCredentials, trustedAddress)
+	if client.err != nil || server.err != nil {
+		t.Fatalf("Handshake failed. Client error: %+v, server error: %+v", client.err, server.err)
+	}
+	if !client.authInfo.IsTrusted {
+		t.Fatalf("Expected the server to be trusted")
+	}
+	client.conn.Close()
+	server.conn.Close()
+
+	_, impostorIdentityKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %s", err)
+	}
+	impostorCredentials := NewCredentials(impostorIdentityKey, false, serverCredentials.trustedPeers)
+	client, server = handshake(t, clientCredentials, impostorCredentials, trustedAddress)
+	if client.err == nil {
+		t.Fatalf("Expected the handshake with an impostor of a trusted peer to fail")
+	}
+	if server.conn != nil {
+		server.conn.Close()
+	}
+}
+
+func TestParseTrustedPeers(t *testing.T) {
+	validKey := hex.EncodeToString(make([]byte, ed25519.PublicKeySize))
+	tests := []struct {
+		trustedPeer   string
+		expectedValid bool
+	}{
+		{trustedPeer: validKey + "@127.0.0.1:16111", expectedValid: true},
+		{trustedPeer: validKey + "@[::1]:16111", expectedValid: true},
+		{trustedPeer: validKey + "@example.com:16111", expectedValid: true},
+		{trustedPeer: validKey + "@127.0.0.1", expectedValid: false},
+		{trustedPeer: validKey, expectedValid: false},
+		{trustedPeer: validKey[2:] + "@127.0.0.1:16111", expectedValid: false},
+		{trustedPeer: "zz" + validKey[2:] + "@127.0.0.1:16111", expectedValid: false},
+	}
+	for _, test := range tests {
+		_, err := ParseTrustedPeers([]string{test.trustedPeer})
+		if (err == nil) != test.expectedValid {
+			t.Errorf("ParseTrustedPeers(%s): expected valid: %t, got error: %v",
+				test.trustedPeer, test.expectedValid, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"net"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// handshakeMagic opens every encrypted connection. Its first bytes differ from the HTTP/2
+// client preface, so that a server can tell encrypting clients from plaintext ones.
+var handshakeMagic = []byte("kaspienc")
+
+// handshakeVersion is the version of the handshake and framing protocol
+const handshakeVersion = 1
+
+const helloSize = 8 + 1 + curve25519.PointSize
+
+const (
+	keyDerivationInfo   = "kaspid p2p encryption keys"
+	clientIdentityLabel = "kaspid p2p client identity"
+	serverIdentityLabel = "kaspid p2p server identity"
+	identityMessageSize = ed25519.PublicKeySize + ed25519.SignatureSize
+	handshakeKeySize    = 32
+)
+
+// ErrEncryptionNotSupported indicates that the remote peer does not support encrypted connections
+var ErrEncryptionNotSupported = errors.New("the remote peer does not support encrypted connections")
+
+type handshakeResult struct {
+	conn              net.Conn
+	remoteIdentityKey ed25519.PublicKey
+}
+
+type hello struct {
+	ephemeralPrivateKey []byte
+	serialized          []byte
+}
+
+func newHello() (*hello, error) {
+	ephemeralPrivateKey := make([]byte, curve25519.ScalarSize)
+	_, err := rand.Read(ephemeralPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPublicKey, err := curve25519.X25519(ephemeralPrivateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	serialized := make([]byte, 0, helloSize)
+	serialized = append(serialized, handshakeMagic...)
+	serialized = append(serialized, handshakeVersion)
+	serialized = append(serialized, ephemeralPublicKey...)
+	return &hello{ephemeralPrivateKey: ephemeralPrivateKey, serialized: serialized}, nil
+}
+
+// clientHandshake performs the client side of the handshake over conn. It returns
+// ErrEncryptionNotSupported if the server does not respond with a handshake of its own.
+func clientHandshake(conn net.Conn, identityKey ed25519.PrivateKey) (*handshakeResult, error) {
+	clientHello, err := newHello()
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Write(clientHello.serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	serverHello := make([]byte, helloSize)
+	_, err = io.ReadFull(conn, serverHello)
+	if err != nil {
+		// Servers that don't support encryption close the connection
+		// once they fail to parse the client hello
+		return nil, errors.Wrapf(ErrEncryptionNotSupported, "could not read the server hello: %s", err)
+	}
+	if !bytes.Equal(serverHello[:len(handshakeMagic)], handshakeMagic) {
+		return nil, ErrEncryptionNotSupported
+	}
+
+	return completeHandshake(conn, conn, identityKey, clientHello, clientHello.serialized, serverHello, true)
+}
+
+// serverHandshake performs the server side of the handshake over conn. If the client
+// doesn't open with a handshake, the returned result holds a plaintext connection and
+// no remote identity key.
+func serverHandshake(conn net.Conn, identityKey ed25519.PrivateKey) (*handshakeResult, error) {
+	reader := bufio.NewReaderSize(conn, helloSize)
+	magic, err := reader.Peek(len(handshakeMagic))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, handshakeMagic) {
+		return &handshakeResult{conn: &prefixedConn{Conn: conn, reader: reader}}, nil
+	}
+
+	clientHello := make([]byte, helloSize)
+	_, err = io.ReadFull(reader, clientHello)
+	if err != nil {
+		return nil, err
+	}
+	serverHello, err := newHello()
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Write(serverHello.serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	return completeHandshake(conn, reader, identityKey, serverHello, clientHello, serverHello.serialized, false)
+}
+
+// completeHandshake derives the session keys out of the exchanged hellos, and
+// then authenticates both sides by their static identity keys over the encrypted
+// connection.
+func completeHandshake(conn net.Conn, reader io.Reader, identityKey ed25519.PrivateKey, localHello *hello,
+	clientHello []byte, serverHello []byte, isClient bool) (*handshakeResult, error) {
+
+	remoteHello := serverHello
+	if !isClient {
+		remoteHello = clientHello
+	}
+	if remoteHello[len(handshakeMagic)] != handshakeVersion {
+		return nil, errors.Errorf("unsupported encryption handshake version %d", remoteHello[len(handshakeMagic)])
+	}
+	sharedSecret, err := curve25519.X25519(localHello.ephemeralPrivateKey, remoteHello[len(handshakeMagic)+1:])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ephemeral public key")
+	}
+
+	transcript := sha256.New()
+	transcript.Write(clientHello)
+	transcript.Write(serverHello)
+	transcriptHash := transcript.Sum(nil)
+
+	keyMaterial := make([]byte, 2*handshakeKeySize)
+	_, err = io.ReadFull(hkdf.New(sha256.New, sharedSecret, transcriptHash, []byte(keyDerivationInfo)), keyMaterial)
+	if err != nil {
+		return nil, err
+	}
+	clientKey, serverKey := keyMaterial[:handshakeKeySize], keyMaterial[handshakeKeySize:]
+
+	readKey, writeKey := serverKey, clientKey
+	localLabel, remoteLabel := clientIdentityLabel, serverIdentityLabel
+	if !isClient {
+		readKey, writeKey = clientKey, serverKey
+		localLabel, remoteLabel = serverIdentityLabel, clientIdentityLabel
+	}
+	encrypted, err := newEncryptedConn(conn, reader, readKey, writeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	identityMessage := make([]byte, 0, identityMessageSize)
+	identityMessage = append(identityMessage, identityKey.Public().(ed25519.PublicKey)...)
+	identityMessage = append(identityMessage, ed25519.Sign(identityKey, append([]byte(localLabel), transcriptHash...))...)
+	_, err = encrypted.Write(identityMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteIdentityMessage := make([]byte, identityMessageSize)
+	_, err = io.ReadFull(encrypted, remoteIdentityMessage)
+	if err != nil {
+		return nil, err
+	}
+	remoteIdentityKey := ed25519.PublicKey(remoteIdentityMessage[:ed25519.PublicKeySize])
+	signature := remoteIdentityMessage[ed25519.PublicKeySize:]
+	if !ed25519.Verify(remoteIdentityKey, append([]byte(remoteLabel), transcriptHash...), signature) {
+		return nil, errors.New("invalid identity signature")
+	}
+
+	return &handshakeResult{conn: encrypted, remoteIdentityKey: remoteIdentityKey}, nil
+}
@@ -0,0 +1,113 @@
+package encryption
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LoadOrCreateIdentityKey loads the static identity key stored in the given file, creating
+// the file with a new random key if it does not exist. If path is empty, a new random key
+// that is not persisted anywhere is returned.
+func LoadOrCreateIdentityKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		_, identityKey, err := ed25519.GenerateKey(rand.Reader)
+		return identityKey, err
+	}
+
+	seedHex, err := ioutil.ReadFile(path)
+	if err == nil {
+		seed, err := hex.DecodeString(strings.TrimSpace(string(seedHex)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, errors.Errorf("%s does not hold a valid P2P identity key", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "could not read the P2P identity key from %s", path)
+	}
+
+	_, identityKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(path, []byte(hex.EncodeToString(identityKey.Seed())), 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not write the P2P identity key to %s", path)
+	}
+	return identityKey, nil
+}
+
+// TrustedPeers maps the addresses of trusted peers to the static identity keys
+// they're expected to authenticate with
+type TrustedPeers struct {
+	keysByAddress map[string]ed25519.PublicKey
+}
+
+// ParseTrustedPeers parses trusted peers given in the form <hex identity public key>@<host:port>
+func ParseTrustedPeers(trustedPeers []string) (*TrustedPeers, error) {
+	keysByAddress := make(map[string]ed25519.PublicKey, len(trustedPeers))
+	for _, trustedPeer := range trustedPeers {
+		parts := strings.SplitN(trustedPeer, "@", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("trusted peer %s is not in the form <identity public key>@<host:port>",
+				trustedPeer)
+		}
+		identityKey, err := hex.DecodeString(parts[0])
+		if err != nil || len(identityKey) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("trusted peer %s has an invalid identity public key", trustedPeer)
+		}
+		address, err := normalizeAddress(parts[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "trusted peer %s has an invalid address", trustedPeer)
+		}
+		keysByAddress[address] = identityKey
+	}
+	return &TrustedPeers{keysByAddress: keysByAddress}, nil
+}
+
+// keyByAddress returns the identity key the peer at the given address must
+// authenticate with, or nil if it is not a trusted peer
+func (tp *TrustedPeers) keyByAddress(address string) ed25519.PublicKey {
+	normalizedAddress, err := normalizeAddress(address)
+	if err != nil {
+		return nil
+	}
+	return tp.keysByAddress[normalizedAddress]
+}
+
+// IsTrustedAddress returns whether the given address belongs to a trusted peer
+func (tp *TrustedPeers) IsTrustedAddress(address string) bool {
+	return tp.keyByAddress(address) != nil
+}
+
+func (tp *TrustedPeers) isTrustedKey(identityKey ed25519.PublicKey) bool {
+	for _, trustedKey := range tp.keysByAddress {
+		if trustedKey.Equal(identityKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeAddress(address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/kaspikr/kaspid/app/appmessage"
 	"github.com/kaspikr/kaspid/infrastructure/config"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/encryption"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/id"
 	routerpkg "github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server"
@@ -42,7 +43,17 @@ func NewNetAdapter(cfg *config.Config) (*NetAdapter, error) {
 	if err != nil {
 		return nil, err
 	}
-	p2pServer, err := grpcserver.NewP2PServer(cfg.Listeners)
+	identityKey, err := encryption.LoadOrCreateIdentityKey(cfg.P2PIdentityKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	trustedPeers, err := encryption.ParseTrustedPeers(cfg.TrustedPeers)
+	if err != nil {
+		return nil, err
+	}
+	credentials := encryption.NewCredentials(identityKey, cfg.RequireP2PEncryption, trustedPeers)
+	log.Infof("P2P identity public key: %x", []byte(credentials.IdentityPublicKey()))
+	p2pServer, err := grpcserver.NewP2PServer(cfg.Listeners, credentials, p2pDialer(cfg))
 	if err != nil {
 		return nil, err
 	}
@@ -167,6 +178,12 @@ func (na *NetAdapter) SetRPCRouterInitializer(routerInitializer RouterInitialize
 	na.rpcRouterInitializer = routerInitializer
 }
 
+// P2PTrafficStats returns the messages and bytes sent and received over
+// all P2P connections since the NetAdapter was created, per message type
+func (na *NetAdapter) P2PTrafficStats() *server.TrafficStats {
+	return na.p2pServer.TrafficStats()
+}
+
 // ID returns this netAdapter's ID in the network
 func (na *NetAdapter) ID() *id.ID {
 	return na.id
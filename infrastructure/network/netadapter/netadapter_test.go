@@ -1,15 +1,20 @@
 package netadapter
 
 import (
+	"encoding/binary"
 	"fmt"
+	"io"
+	"net"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/btcsuite/go-socks/socks"
 	"github.com/kaspikr/kaspid/app/appmessage"
 
 	"github.com/kaspikr/kaspid/infrastructure/config"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server"
 )
 
 // routerInitializerForTest returns new RouterInitializer which simply sets
@@ -153,6 +158,33 @@ func TestNetAdapter(t *testing.T) {
 		t.Fatalf("TestNetAdapter: expected '%d' message number but got %d", nonce, number)
 	}
 
+	// Ensure the received ping was counted both in the connection and in the adapter totals
+	connectionB := adapterB.P2PConnections()[0]
+	for _, trafficStats := range []*server.TrafficStats{connectionB.TrafficStats(), adapterB.P2PTrafficStats()} {
+		byMessageType := trafficStats.ByMessageType()
+		if len(byMessageType) != 1 || byMessageType[0].Command != appmessage.CmdPing ||
+			byMessageType[0].MessagesReceived != 1 || byMessageType[0].BytesReceived == 0 {
+			t.Fatalf("TestNetAdapter: expected a single received ping, got %+v", byMessageType)
+		}
+		if total := trafficStats.Total(); total != byMessageType[0].MessageTraffic {
+			t.Fatalf("TestNetAdapter: expected total %+v, got %+v", byMessageType[0].MessageTraffic, total)
+		}
+	}
+
+	// Ensure the route the ping was queued in recorded it in its high-water mark
+	foundRouteB := false
+	for _, routeStats := range connectionB.RouteStats() {
+		if routeStats.Name == "B - incoming" {
+			foundRouteB = true
+			if routeStats.Length != 0 || routeStats.HighWaterMark != 1 {
+				t.Fatalf("TestNetAdapter: expected an empty route with a high-water mark of 1, got %+v", routeStats)
+			}
+		}
+	}
+	if !foundRouteB {
+		t.Fatalf("TestNetAdapter: route B is missing from %+v", connectionB.RouteStats())
+	}
+
 	err = adapterA.Stop()
 	if err != nil {
 		t.Fatalf("TestNetAdapter: stopping adapter failed: %+v", err)
@@ -164,3 +196,134 @@ func TestNetAdapter(t *testing.T) {
 		t.Fatalf("TestNetAdapter: error expected at attempt to stop adapter second time, but got nothing")
 	}
 }
+
+// startSOCKS5ProxyForTest starts a minimal SOCKS5 proxy that forwards every
+// CONNECT request to target, and reports the requested addresses to requests
+func startSOCKS5ProxyForTest(t *testing.T, target string) (address string, requests <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	requestsChan := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5ForTest(conn, target, requestsChan)
+		}
+	}()
+	return listener.Addr().String(), requestsChan
+}
+
+func serveSOCKS5ForTest(conn net.Conn, target string, requests chan<- string) {
+	defer conn.Close()
+
+	// Greeting: version, method count and methods. Reply with "no authentication".
+	greeting := make([]byte, 2)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, greeting[1])); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return
+	}
+
+	// Request: version, CONNECT, reserved, and a domain name address type.
+	request := make([]byte, 5)
+	if _, err := io.ReadFull(conn, request); err != nil || request[3] != 3 {
+		return
+	}
+	hostAndPort := make([]byte, int(request[4])+2)
+	if _, err := io.ReadFull(conn, hostAndPort); err != nil {
+		return
+	}
+	host := string(hostAndPort[:request[4]])
+	port := binary.BigEndian.Uint16(hostAndPort[request[4]:])
+	requests <- fmt.Sprintf("%s:%d", host, port)
+
+	targetConn, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer targetConn.Close()
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+
+	go io.Copy(targetConn, conn)
+	io.Copy(conn, targetConn)
+}
+
+func TestNetAdapterOnionConnection(t *testing.T) {
+	const onionAddress = "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion:16111"
+
+	addressA := "127.0.0.1:3003"
+	addressB := "127.0.0.1:3004"
+	proxyAddress, proxyRequests := startSOCKS5ProxyForTest(t, addressB)
+
+	cfgA, cfgB := config.DefaultConfig(), config.DefaultConfig()
+	cfgA.Listeners = []string{addressA}
+	cfgB.Listeners = []string{addressB}
+
+	adapterA, err := NewNetAdapter(cfgA)
+	if err != nil {
+		t.Fatalf("TestNetAdapterOnionConnection: NetAdapter instantiation failed: %+v", err)
+	}
+	adapterA.SetP2PRouterInitializer(func(router *router.Router, connection *NetConnection) {})
+	adapterA.SetRPCRouterInitializer(func(router *router.Router, connection *NetConnection) {})
+	err = adapterA.Start()
+	if err != nil {
+		t.Fatalf("TestNetAdapterOnionConnection: Start() failed: %+v", err)
+	}
+	defer adapterA.Stop()
+
+	adapterB, err := NewNetAdapter(cfgB)
+	if err != nil {
+		t.Fatalf("TestNetAdapterOnionConnection: NetAdapter instantiation failed: %+v", err)
+	}
+	adapterB.SetP2PRouterInitializer(func(router *router.Router, connection *NetConnection) {})
+	adapterB.SetRPCRouterInitializer(func(router *router.Router, connection *NetConnection) {})
+	err = adapterB.Start()
+	if err != nil {
+		t.Fatalf("TestNetAdapterOnionConnection: Start() failed: %+v", err)
+	}
+	defer adapterB.Stop()
+
+	// Without a Tor proxy onion addresses are unreachable
+	err = adapterA.P2PConnect(onionAddress)
+	if err == nil {
+		t.Fatalf("TestNetAdapterOnionConnection: connected to %s without a Tor proxy", onionAddress)
+	}
+
+	cfgA.OnionDial = (&socks.Proxy{Addr: proxyAddress}).DialTimeout
+	err = adapterA.P2PConnect(onionAddress)
+	if err != nil {
+		t.Fatalf("TestNetAdapterOnionConnection: connection to %s failed: %+v", onionAddress, err)
+	}
+
+	select {
+	case request := <-proxyRequests:
+		if request != onionAddress {
+			t.Fatalf("TestNetAdapterOnionConnection: expected the proxy to be asked for %s, got %s",
+				onionAddress, request)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TestNetAdapterOnionConnection: the connection did not go through the proxy")
+	}
+
+	connections := adapterA.P2PConnections()
+	if len(connections) != 1 {
+		t.Fatalf("TestNetAdapterOnionConnection: expected 1 connection, got %d", len(connections))
+	}
+	netAddress := connections[0].NetAddress()
+	if netAddress.Network != appmessage.AddressNetworkTorV3 || netAddress.String() != onionAddress {
+		t.Fatalf("TestNetAdapterOnionConnection: expected the connection address to be %s, got %s",
+			onionAddress, netAddress)
+	}
+}
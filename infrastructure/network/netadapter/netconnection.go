@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/kaspikr/kaspid/app/appmessage"
 	routerpkg "github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
+	"github.com/kaspikr/kaspid/util/mstime"
 	"github.com/pkg/errors"
 	"sync/atomic"
 
@@ -75,9 +76,39 @@ func (c *NetConnection) IsOutbound() bool {
 	return c.connection.IsOutbound()
 }
 
-// NetAddress returns the NetAddress associated with this connection
+// NetAddress returns the NetAddress associated with this connection,
+// timestamped with the current time
 func (c *NetConnection) NetAddress() *appmessage.NetAddress {
-	return appmessage.NewNetAddress(c.connection.Address())
+	netAddress := *c.connection.Address()
+	netAddress.Timestamp = mstime.Now()
+	return &netAddress
+}
+
+// IsEncrypted returns whether the connection is encrypted
+func (c *NetConnection) IsEncrypted() bool {
+	return c.connection.IsEncrypted()
+}
+
+// IsTrusted returns whether the remote peer authenticated with the identity key of a trusted peer
+func (c *NetConnection) IsTrusted() bool {
+	return c.connection.IsTrusted()
+}
+
+// RemoteIdentityKey returns the static identity key the remote peer authenticated
+// with, or nil if the connection is not encrypted
+func (c *NetConnection) RemoteIdentityKey() []byte {
+	return c.connection.RemoteIdentityKey()
+}
+
+// TrafficStats returns the messages and bytes sent and received over
+// this connection, per message type
+func (c *NetConnection) TrafficStats() *server.TrafficStats {
+	return c.connection.TrafficStats()
+}
+
+// RouteStats returns the current and maximum queue lengths of the routes of this connection
+func (c *NetConnection) RouteStats() []routerpkg.RouteStats {
+	return c.router.RouteStats()
 }
 
 func (c *NetConnection) setOnDisconnectedHandler(onDisconnectedHandler server.OnDisconnectedHandler) {
@@ -35,6 +35,18 @@ type Route struct {
 	closed    bool
 	closeLock sync.Mutex
 	capacity  int
+
+	// highWaterMark is the maximum number of messages that were
+	// queued in the route at once. It's protected by closeLock.
+	highWaterMark int
+}
+
+// RouteStats describes the queue of a route
+type RouteStats struct {
+	Name          string
+	Length        int
+	Capacity      int
+	HighWaterMark int
 }
 
 // NewRoute create a new Route
@@ -62,10 +74,28 @@ func (r *Route) Enqueue(message appmessage.Message) error {
 	if len(r.channel) == r.capacity {
 		return errors.Wrapf(ErrRouteCapacityReached, "route '%s' reached capacity of %d", r.name, r.capacity)
 	}
+	// The length is taken before sending, since the message may be
+	// dequeued before we get to look at the channel again
+	if length := len(r.channel) + 1; length > r.highWaterMark {
+		r.highWaterMark = length
+	}
 	r.channel <- message
 	return nil
 }
 
+// Stats returns the current and the maximum number of messages queued in the route
+func (r *Route) Stats() RouteStats {
+	r.closeLock.Lock()
+	defer r.closeLock.Unlock()
+
+	return RouteStats{
+		Name:          r.name,
+		Length:        len(r.channel),
+		Capacity:      r.capacity,
+		HighWaterMark: r.highWaterMark,
+	}
+}
+
 // MaybeEnqueue enqueues a message to the route, but doesn't throw an error
 // if it's closed or its capacity has been reached.
 func (r *Route) MaybeEnqueue(message appmessage.Message) error {
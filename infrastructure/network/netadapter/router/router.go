@@ -2,6 +2,7 @@ package router
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/kaspikr/kaspid/app/appmessage"
@@ -107,6 +108,26 @@ func (r *Router) Close() {
 	r.outgoingRoute.Close()
 }
 
+// RouteStats returns the stats of the outgoing route, followed
+// by the stats of the incoming routes, sorted by name
+func (r *Router) RouteStats() []RouteStats {
+	r.incomingRoutesLock.RLock()
+	incomingRoutes := make(map[*Route]struct{})
+	for _, route := range r.incomingRoutes {
+		incomingRoutes[route] = struct{}{}
+	}
+	r.incomingRoutesLock.RUnlock()
+
+	incomingRouteStats := make([]RouteStats, 0, len(incomingRoutes))
+	for route := range incomingRoutes {
+		incomingRouteStats = append(incomingRouteStats, route.Stats())
+	}
+	sort.Slice(incomingRouteStats, func(i, j int) bool {
+		return incomingRouteStats[i].Name < incomingRouteStats[j].Name
+	})
+	return append([]RouteStats{r.outgoingRoute.Stats()}, incomingRouteStats...)
+}
+
 func (r *Router) incomingRoute(messageType appmessage.MessageCommand) (*Route, bool) {
 	r.incomingRoutesLock.RLock()
 	defer r.incomingRoutesLock.RUnlock()
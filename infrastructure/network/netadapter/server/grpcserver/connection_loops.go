@@ -12,6 +12,7 @@ import (
 
 	routerpkg "github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server/grpcserver/protowire"
 )
@@ -71,6 +72,7 @@ func (c *gRPCConnection) sendLoop() error {
 		if err != nil {
 			return err
 		}
+		c.trafficStats.RecordSent(message.Command(), proto.Size(messageProto))
 	}
 	return nil
 }
@@ -93,6 +95,8 @@ func (c *gRPCConnection) receiveLoop() error {
 			return err
 		}
 
+		c.trafficStats.RecordReceived(message.Command(), proto.Size(protoMessage))
+
 		messageNumber++
 		message.SetMessageNumber(messageNumber)
 		message.SetReceivedAt(time.Now())
@@ -1,14 +1,16 @@
 package grpcserver
 
 import (
-	"net"
 	"sync"
 	"sync/atomic"
 
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/encryption"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server/grpcserver/protowire"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server"
@@ -17,10 +19,12 @@ import (
 
 type gRPCConnection struct {
 	server                   *gRPCServer
-	address                  *net.TCPAddr
+	address                  *appmessage.NetAddress
 	stream                   grpcStream
 	router                   *router.Router
 	lowLevelClientConnection *grpc.ClientConn
+	authInfo                 *encryption.AuthInfo
+	trafficStats             *server.TrafficStats
 
 	// streamLock protects concurrent access to stream.
 	// Note that it's an RWMutex. Despite what the name
@@ -41,20 +45,32 @@ type grpcStream interface {
 	Recv() (*protowire.KaspidMessage, error)
 }
 
-func newConnection(server *gRPCServer, address *net.TCPAddr, stream grpcStream,
+func newConnection(grpcServer *gRPCServer, address *appmessage.NetAddress, stream grpcStream,
 	lowLevelClientConnection *grpc.ClientConn) *gRPCConnection {
 	connection := &gRPCConnection{
-		server:                   server,
+		server:                   grpcServer,
 		address:                  address,
 		stream:                   stream,
 		stopChan:                 make(chan struct{}),
 		isConnected:              1,
 		lowLevelClientConnection: lowLevelClientConnection,
+		authInfo:                 &encryption.AuthInfo{},
+		trafficStats:             server.NewTrafficStats(grpcServer.trafficStats),
 	}
 
 	return connection
 }
 
+// encryptionAuthInfo returns the encryption.AuthInfo of the given peer,
+// or an empty one if its connection was not made through encryption.Credentials
+func encryptionAuthInfo(peerInfo *peer.Peer) *encryption.AuthInfo {
+	authInfo, ok := peerInfo.AuthInfo.(*encryption.AuthInfo)
+	if !ok {
+		return &encryption.AuthInfo{}
+	}
+	return authInfo
+}
+
 func (c *gRPCConnection) Start(router *router.Router) {
 	if c.onDisconnectedHandler == nil {
 		panic(errors.New("onDisconnectedHandler is nil"))
@@ -123,10 +139,39 @@ func (c *gRPCConnection) Disconnect() {
 	}
 }
 
-func (c *gRPCConnection) Address() *net.TCPAddr {
+func (c *gRPCConnection) Address() *appmessage.NetAddress {
 	return c.address
 }
 
+// IsEncrypted returns whether the connection is encrypted
+//
+// This is part of the Connection interface
+func (c *gRPCConnection) IsEncrypted() bool {
+	return c.authInfo.IsEncrypted()
+}
+
+// IsTrusted returns whether the remote peer authenticated as a trusted peer
+//
+// This is part of the Connection interface
+func (c *gRPCConnection) IsTrusted() bool {
+	return c.authInfo.IsTrusted
+}
+
+// RemoteIdentityKey returns the static identity key the remote peer authenticated
+// with, or nil if the connection is not encrypted
+//
+// This is part of the Connection interface
+func (c *gRPCConnection) RemoteIdentityKey() []byte {
+	return c.authInfo.RemoteIdentityKey
+}
+
+// TrafficStats returns the traffic of this connection
+//
+// This is part of the Connection interface
+func (c *gRPCConnection) TrafficStats() *server.TrafficStats {
+	return c.trafficStats
+}
+
 func (c *gRPCConnection) receive() (*protowire.KaspidMessage, error) {
 	// We use RLock here and in send() because they can work
 	// in parallel. closeSend(), however, must not have either
@@ -3,6 +3,7 @@ package grpcserver
 import (
 	"context"
 	"fmt"
+	"github.com/kaspikr/kaspid/app/appmessage"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server"
 	"github.com/kaspikr/kaspid/util/panics"
 	"github.com/pkg/errors"
@@ -18,6 +19,7 @@ type gRPCServer struct {
 	listeningAddresses []string
 	server             *grpc.Server
 	name               string
+	trafficStats       *server.TrafficStats
 
 	maxInboundConnections      int
 	inboundConnectionCount     int
@@ -25,12 +27,17 @@ type gRPCServer struct {
 }
 
 // newGRPCServer creates a gRPC server
-func newGRPCServer(listeningAddresses []string, maxMessageSize int, maxInboundConnections int, name string) *gRPCServer {
+func newGRPCServer(listeningAddresses []string, maxMessageSize int, maxInboundConnections int, name string,
+	extraServerOptions ...grpc.ServerOption) *gRPCServer {
+
 	log.Debugf("Created new %s GRPC server with maxMessageSize %d and maxInboundConnections %d", name, maxMessageSize, maxInboundConnections)
+	serverOptions := append([]grpc.ServerOption{grpc.MaxRecvMsgSize(maxMessageSize), grpc.MaxSendMsgSize(maxMessageSize)},
+		extraServerOptions...)
 	return &gRPCServer{
-		server:                     grpc.NewServer(grpc.MaxRecvMsgSize(maxMessageSize), grpc.MaxSendMsgSize(maxMessageSize)),
+		server:                     grpc.NewServer(serverOptions...),
 		listeningAddresses:         listeningAddresses,
 		name:                       name,
+		trafficStats:               server.NewTrafficStats(nil),
 		maxInboundConnections:      maxInboundConnections,
 		inboundConnectionCount:     0,
 		inboundConnectionCountLock: &sync.Mutex{},
@@ -93,6 +100,11 @@ func (s *gRPCServer) SetOnConnectedHandler(onConnectedHandler server.OnConnected
 	s.onConnectedHandler = onConnectedHandler
 }
 
+// TrafficStats returns the traffic of all the connections of this server together
+func (s *gRPCServer) TrafficStats() *server.TrafficStats {
+	return s.trafficStats
+}
+
 func (s *gRPCServer) handleInboundConnection(ctx context.Context, stream grpcStream) error {
 	connectionCount, err := s.incrementInboundConnectionCountAndLimitIfRequired()
 	if err != nil {
@@ -109,7 +121,8 @@ func (s *gRPCServer) handleInboundConnection(ctx context.Context, stream grpcStr
 		return errors.Errorf("non-tcp connections are not supported")
 	}
 
-	connection := newConnection(s, tcpAddress, stream, nil)
+	connection := newConnection(s, appmessage.NewNetAddress(tcpAddress), stream, nil)
+	connection.authInfo = encryptionAuthInfo(peerInfo)
 
 	err = s.onConnectedHandler(connection)
 	if err != nil {
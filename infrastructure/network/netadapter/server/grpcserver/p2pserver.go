@@ -2,6 +2,8 @@ package grpcserver
 
 import (
 	"context"
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/encryption"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server/grpcserver/protowire"
 	"github.com/kaspikr/kaspid/util/panics"
@@ -10,26 +12,44 @@ import (
 	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/peer"
 	"net"
+	"strconv"
 	"time"
 )
 
 type p2pServer struct {
 	protowire.UnimplementedP2PServer
 	gRPCServer
+	credentials *encryption.Credentials
+	dialer      Dialer
 }
 
+// Dialer is a function that opens the underlying network connection of an outbound P2P connection
+type Dialer func(ctx context.Context, address string) (net.Conn, error)
+
 const p2pMaxMessageSize = 1024 * 1024 * 1024 // 1GB
 
+const (
+	defaultDialTimeout = 1 * time.Second
+
+	// overlayDialTimeout is the dial timeout for addresses in overlay networks, such as Tor,
+	// where establishing a connection takes several round trips through the overlay network
+	overlayDialTimeout = 30 * time.Second
+)
+
 // p2pMaxInboundConnections is the max amount of inbound connections for the P2P server.
 // Note that inbound connections are not limited by the gRPC server. (A value of 0 means
 // unlimited inbound connections.) The P2P limiting logic is more applicative, and as such
 // is handled in the ConnectionManager instead.
 const p2pMaxInboundConnections = 0
 
-// NewP2PServer creates a new P2PServer
-func NewP2PServer(listeningAddresses []string) (server.P2PServer, error) {
-	gRPCServer := newGRPCServer(listeningAddresses, p2pMaxMessageSize, p2pMaxInboundConnections, "P2P")
-	p2pServer := &p2pServer{gRPCServer: *gRPCServer}
+// NewP2PServer creates a new P2PServer that opportunistically encrypts its connections with the given credentials,
+// and dials outbound connections with the given dialer
+func NewP2PServer(listeningAddresses []string, credentials *encryption.Credentials,
+	dialer Dialer) (server.P2PServer, error) {
+
+	gRPCServer := newGRPCServer(listeningAddresses, p2pMaxMessageSize, p2pMaxInboundConnections, "P2P",
+		grpc.Creds(credentials))
+	p2pServer := &p2pServer{gRPCServer: *gRPCServer, credentials: credentials, dialer: dialer}
 	protowire.RegisterP2PServer(gRPCServer.server, p2pServer)
 	return p2pServer, nil
 }
@@ -45,11 +65,22 @@ func (p *p2pServer) MessageStream(stream protowire.P2P_MessageStreamServer) erro
 func (p *p2pServer) Connect(address string) (server.Connection, error) {
 	log.Debugf("%s Dialing to %s", p.name, address)
 
-	const dialTimeout = 1 * time.Second
+	dialTimeout := defaultDialTimeout
+	if host, _, err := net.SplitHostPort(address); err == nil && appmessage.IsOverlayHost(host) {
+		dialTimeout = overlayDialTimeout
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
 	defer cancel()
 
-	gRPCClientConnection, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	gRPCClientConnection, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(p.credentials),
+		grpc.WithContextDialer(p.dialer), grpc.WithBlock(), grpc.FailOnNonTempDialError(true))
+	if err != nil && isEncryptionNotSupportedError(err) && p.credentials.AllowsPlaintextTo(address) {
+		log.Debugf("%s %s does not support encryption. Falling back to plaintext", p.name, address)
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		defer cancel()
+		gRPCClientConnection, err = grpc.DialContext(ctx, address, grpc.WithInsecure(),
+			grpc.WithContextDialer(p.dialer), grpc.WithBlock())
+	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "%s error connecting to %s", p.name, address)
 	}
@@ -65,19 +96,57 @@ func (p *p2pServer) Connect(address string) (server.Connection, error) {
 	if !ok {
 		return nil, errors.Errorf("%s error getting stream peer info from context for %s", p.name, address)
 	}
-	tcpAddress, ok := peerInfo.Addr.(*net.TCPAddr)
-	if !ok {
-		return nil, errors.Errorf("non-tcp addresses are not supported")
+	netAddress, err := outboundNetAddress(address, peerInfo.Addr)
+	if err != nil {
+		return nil, err
 	}
 
-	connection := newConnection(&p.gRPCServer, tcpAddress, stream, gRPCClientConnection)
+	connection := newConnection(&p.gRPCServer, netAddress, stream, gRPCClientConnection)
+	connection.authInfo = encryptionAuthInfo(peerInfo)
 
 	err = p.onConnectedHandler(connection)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("%s Connected to %s", p.name, address)
+	log.Infof("%s Connected to %s (encrypted: %t)", p.name, address, connection.IsEncrypted())
 
 	return connection, nil
 }
+
+// outboundNetAddress returns the NetAddress of an outbound connection that was dialed to the given
+// address. Connections that go through a proxy have the address of the proxy as their remote address,
+// so the dialed address is preferred unless its host is a name that was resolved while dialing.
+func outboundNetAddress(address string, remoteAddress net.Addr) (*appmessage.NetAddress, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid port in address %s", address)
+	}
+	if appmessage.IsOverlayHost(host) {
+		return appmessage.NewNetAddressOverlayHost(host, uint16(port))
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return appmessage.NewNetAddressIPPort(ip, uint16(port)), nil
+	}
+
+	tcpAddress, ok := remoteAddress.(*net.TCPAddr)
+	if !ok {
+		return nil, errors.Errorf("non-tcp addresses are not supported")
+	}
+	return appmessage.NewNetAddress(tcpAddress), nil
+}
+
+// isEncryptionNotSupportedError returns whether the given dial error was caused by
+// the remote peer not supporting encryption
+func isEncryptionNotSupportedError(err error) bool {
+	// gRPC wraps handshake errors in connection errors which don't support unwrapping
+	var connectionError interface{ Origin() error }
+	if errors.As(err, &connectionError) {
+		err = connectionError.Origin()
+	}
+	return errors.Is(err, encryption.ErrEncryptionNotSupported)
+}
@@ -103,18 +103,28 @@ func (x *NetAddress) toAppMessage() (*appmessage.NetAddress, error) {
 	if x.Port > math.MaxUint16 {
 		return nil, errors.Errorf("port number is larger than %d", math.MaxUint16)
 	}
+	if x.Network > math.MaxUint8 {
+		return nil, errors.Errorf("network is larger than %d", math.MaxUint8)
+	}
 	return &appmessage.NetAddress{
-		Timestamp: mstime.UnixMilliseconds(x.Timestamp),
-		IP:        x.Ip,
-		Port:      uint16(x.Port),
+		Timestamp:      mstime.UnixMilliseconds(x.Timestamp),
+		Network:        appmessage.AddressNetwork(x.Network),
+		IP:             x.Ip,
+		OverlayAddress: x.OverlayAddress,
+		Port:           uint16(x.Port),
 	}, nil
 }
 
+// appMessageNetAddressToProto converts the given address to its protobuf form. Peers of protocol
+// versions before 6 don't know the network and overlay address fields, and would decode an overlay
+// address as an address with an empty IP, so the flows don't send them overlay addresses.
 func appMessageNetAddressToProto(address *appmessage.NetAddress) *NetAddress {
 	return &NetAddress{
-		Timestamp: address.Timestamp.UnixMilliseconds(),
-		Ip:        address.IP,
-		Port:      uint32(address.Port),
+		Timestamp:      address.Timestamp.UnixMilliseconds(),
+		Ip:             address.IP,
+		Port:           uint32(address.Port),
+		Network:        uint32(address.Network),
+		OverlayAddress: address.OverlayAddress,
 	}
 }
 
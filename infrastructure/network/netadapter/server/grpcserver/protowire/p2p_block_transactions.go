@@ -0,0 +1,51 @@
+package protowire
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *KaspidMessage_BlockTransactions) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "KaspidMessage_BlockTransactions is nil")
+	}
+	return x.BlockTransactions.toAppMessage()
+}
+
+func (x *BlockTransactionsMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "BlockTransactionsMessage is nil")
+	}
+	hash, err := x.Hash.toDomain()
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]*appmessage.MsgTx, len(x.Transactions))
+	for i, protoTx := range x.Transactions {
+		msgTx, err := protoTx.toAppMessage()
+		if err != nil {
+			return nil, err
+		}
+		transactions[i] = msgTx.(*appmessage.MsgTx)
+	}
+
+	return &appmessage.MsgBlockTransactions{
+		Hash:         hash,
+		Transactions: transactions,
+	}, nil
+}
+
+func (x *KaspidMessage_BlockTransactions) fromAppMessage(msgBlockTransactions *appmessage.MsgBlockTransactions) error {
+	protoTransactions := make([]*TransactionMessage, len(msgBlockTransactions.Transactions))
+	for i, tx := range msgBlockTransactions.Transactions {
+		protoTx := new(TransactionMessage)
+		protoTx.fromAppMessage(tx)
+		protoTransactions[i] = protoTx
+	}
+	x.BlockTransactions = &BlockTransactionsMessage{
+		Hash:         domainHashToProto(msgBlockTransactions.Hash),
+		Transactions: protoTransactions,
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package protowire
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *KaspidMessage_CompactBlock) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "KaspidMessage_CompactBlock is nil")
+	}
+	return x.CompactBlock.toAppMessage()
+}
+
+func (x *CompactBlockMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "CompactBlockMessage is nil")
+	}
+	if len(x.ShortIds) > appmessage.MaxShortIDsPerCompactBlock {
+		return nil, errors.Errorf("too many short IDs for message "+
+			"[count %d, max %d]", len(x.ShortIds), appmessage.MaxShortIDsPerCompactBlock)
+	}
+	header, err := x.Header.toAppMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	prefilledTransactions := make([]*appmessage.PrefilledTransaction, len(x.PrefilledTransactions))
+	for i, protoPrefilledTransaction := range x.PrefilledTransactions {
+		prefilledTransaction, err := protoPrefilledTransaction.toAppMessage()
+		if err != nil {
+			return nil, err
+		}
+		prefilledTransactions[i] = prefilledTransaction
+	}
+
+	return &appmessage.MsgCompactBlock{
+		Header:                *header,
+		ShortIDNonce:          x.ShortIdNonce,
+		ShortIDs:              x.ShortIds,
+		PrefilledTransactions: prefilledTransactions,
+	}, nil
+}
+
+func (x *KaspidMessage_CompactBlock) fromAppMessage(msgCompactBlock *appmessage.MsgCompactBlock) error {
+	if len(msgCompactBlock.ShortIDs) > appmessage.MaxShortIDsPerCompactBlock {
+		return errors.Errorf("too many short IDs for message "+
+			"[count %d, max %d]", len(msgCompactBlock.ShortIDs), appmessage.MaxShortIDsPerCompactBlock)
+	}
+	protoHeader := new(BlockHeader)
+	err := protoHeader.fromAppMessage(&msgCompactBlock.Header)
+	if err != nil {
+		return err
+	}
+
+	protoPrefilledTransactions := make([]*PrefilledTransaction, len(msgCompactBlock.PrefilledTransactions))
+	for i, prefilledTransaction := range msgCompactBlock.PrefilledTransactions {
+		protoPrefilledTransactions[i] = new(PrefilledTransaction)
+		protoPrefilledTransactions[i].fromAppMessage(prefilledTransaction)
+	}
+
+	x.CompactBlock = &CompactBlockMessage{
+		Header:                protoHeader,
+		ShortIdNonce:          msgCompactBlock.ShortIDNonce,
+		ShortIds:              msgCompactBlock.ShortIDs,
+		PrefilledTransactions: protoPrefilledTransactions,
+	}
+	return nil
+}
+
+func (x *PrefilledTransaction) toAppMessage() (*appmessage.PrefilledTransaction, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "PrefilledTransaction is nil")
+	}
+	msgTx, err := x.Transaction.toAppMessage()
+	if err != nil {
+		return nil, err
+	}
+	return &appmessage.PrefilledTransaction{
+		Index: x.Index,
+		Tx:    msgTx.(*appmessage.MsgTx),
+	}, nil
+}
+
+func (x *PrefilledTransaction) fromAppMessage(prefilledTransaction *appmessage.PrefilledTransaction) {
+	protoTransaction := new(TransactionMessage)
+	protoTransaction.fromAppMessage(prefilledTransaction.Tx)
+	*x = PrefilledTransaction{
+		Index:       prefilledTransaction.Index,
+		Transaction: protoTransaction,
+	}
+}
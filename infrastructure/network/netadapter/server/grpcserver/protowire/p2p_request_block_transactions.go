@@ -0,0 +1,45 @@
+package protowire
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *KaspidMessage_RequestBlockTransactions) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "KaspidMessage_RequestBlockTransactions is nil")
+	}
+	return x.RequestBlockTransactions.toAppMessage()
+}
+
+func (x *RequestBlockTransactionsMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "RequestBlockTransactionsMessage is nil")
+	}
+	if len(x.Indexes) > appmessage.MaxShortIDsPerCompactBlock {
+		return nil, errors.Errorf("too many indexes for message "+
+			"[count %d, max %d]", len(x.Indexes), appmessage.MaxShortIDsPerCompactBlock)
+	}
+	hash, err := x.Hash.toDomain()
+	if err != nil {
+		return nil, err
+	}
+	return &appmessage.MsgRequestBlockTransactions{
+		Hash:    hash,
+		Indexes: x.Indexes,
+	}, nil
+}
+
+func (x *KaspidMessage_RequestBlockTransactions) fromAppMessage(
+	msgRequestBlockTransactions *appmessage.MsgRequestBlockTransactions) error {
+
+	if len(msgRequestBlockTransactions.Indexes) > appmessage.MaxShortIDsPerCompactBlock {
+		return errors.Errorf("too many indexes for message "+
+			"[count %d, max %d]", len(msgRequestBlockTransactions.Indexes), appmessage.MaxShortIDsPerCompactBlock)
+	}
+	x.RequestBlockTransactions = &RequestBlockTransactionsMessage{
+		Hash:    domainHashToProto(msgRequestBlockTransactions.Hash),
+		Indexes: msgRequestBlockTransactions.Indexes,
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package protowire
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *KaspidMessage_RequestCompactBlock) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "KaspidMessage_RequestCompactBlock is nil")
+	}
+	return x.RequestCompactBlock.toAppMessage()
+}
+
+func (x *RequestCompactBlockMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "RequestCompactBlockMessage is nil")
+	}
+	hash, err := x.Hash.toDomain()
+	if err != nil {
+		return nil, err
+	}
+	return &appmessage.MsgRequestCompactBlock{Hash: hash}, nil
+}
+
+func (x *KaspidMessage_RequestCompactBlock) fromAppMessage(msgRequestCompactBlock *appmessage.MsgRequestCompactBlock) error {
+	x.RequestCompactBlock = &RequestCompactBlockMessage{
+		Hash: domainHashToProto(msgRequestCompactBlock.Hash),
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package protowire
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *KaspidMessage_RequestTransactionReconciliation) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "KaspidMessage_RequestTransactionReconciliation is nil")
+	}
+	return x.RequestTransactionReconciliation.toAppMessage()
+}
+
+func (x *RequestTransactionReconciliationMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "RequestTransactionReconciliationMessage is nil")
+	}
+	return &appmessage.MsgRequestTransactionReconciliation{
+		SetSize: x.SetSize,
+	}, nil
+}
+
+func (x *KaspidMessage_RequestTransactionReconciliation) fromAppMessage(
+	msgRequestTransactionReconciliation *appmessage.MsgRequestTransactionReconciliation) error {
+
+	x.RequestTransactionReconciliation = &RequestTransactionReconciliationMessage{
+		SetSize: msgRequestTransactionReconciliation.SetSize,
+	}
+	return nil
+}
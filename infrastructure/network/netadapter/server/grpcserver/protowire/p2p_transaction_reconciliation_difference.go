@@ -0,0 +1,41 @@
+package protowire
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *KaspidMessage_TransactionReconciliationDifference) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "KaspidMessage_TransactionReconciliationDifference is nil")
+	}
+	return x.TransactionReconciliationDifference.toAppMessage()
+}
+
+func (x *TransactionReconciliationDifferenceMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "TransactionReconciliationDifferenceMessage is nil")
+	}
+	if len(x.ShortIds) > appmessage.MaxInvPerTxInvMsg {
+		return nil, errors.Errorf("too many short IDs for message "+
+			"[count %d, max %d]", len(x.ShortIds), appmessage.MaxInvPerTxInvMsg)
+	}
+	return &appmessage.MsgTransactionReconciliationDifference{
+		Succeeded: x.Succeeded,
+		ShortIDs:  x.ShortIds,
+	}, nil
+}
+
+func (x *KaspidMessage_TransactionReconciliationDifference) fromAppMessage(
+	msgTransactionReconciliationDifference *appmessage.MsgTransactionReconciliationDifference) error {
+
+	if len(msgTransactionReconciliationDifference.ShortIDs) > appmessage.MaxInvPerTxInvMsg {
+		return errors.Errorf("too many short IDs for message "+
+			"[count %d, max %d]", len(msgTransactionReconciliationDifference.ShortIDs), appmessage.MaxInvPerTxInvMsg)
+	}
+	x.TransactionReconciliationDifference = &TransactionReconciliationDifferenceMessage{
+		Succeeded: msgTransactionReconciliationDifference.Succeeded,
+		ShortIds:  msgTransactionReconciliationDifference.ShortIDs,
+	}
+	return nil
+}
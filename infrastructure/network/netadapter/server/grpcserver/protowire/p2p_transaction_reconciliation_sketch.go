@@ -0,0 +1,59 @@
+package protowire
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *KaspidMessage_TransactionReconciliationSketch) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "KaspidMessage_TransactionReconciliationSketch is nil")
+	}
+	return x.TransactionReconciliationSketch.toAppMessage()
+}
+
+func (x *TransactionReconciliationSketchMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "TransactionReconciliationSketchMessage is nil")
+	}
+	if len(x.Cells) > appmessage.MaxTransactionReconciliationSketchCells {
+		return nil, errors.Errorf("too many cells for message "+
+			"[count %d, max %d]", len(x.Cells), appmessage.MaxTransactionReconciliationSketchCells)
+	}
+	cells := make([]*appmessage.TransactionReconciliationSketchCell, len(x.Cells))
+	for i, cell := range x.Cells {
+		if cell == nil {
+			return nil, errors.Wrapf(errorNil, "TransactionReconciliationSketchCell is nil")
+		}
+		cells[i] = &appmessage.TransactionReconciliationSketchCell{
+			Count:    cell.Count,
+			KeySum:   cell.KeySum,
+			CheckSum: cell.CheckSum,
+		}
+	}
+	return &appmessage.MsgTransactionReconciliationSketch{
+		Cells: cells,
+	}, nil
+}
+
+func (x *KaspidMessage_TransactionReconciliationSketch) fromAppMessage(
+	msgTransactionReconciliationSketch *appmessage.MsgTransactionReconciliationSketch) error {
+
+	if len(msgTransactionReconciliationSketch.Cells) > appmessage.MaxTransactionReconciliationSketchCells {
+		return errors.Errorf("too many cells for message "+
+			"[count %d, max %d]", len(msgTransactionReconciliationSketch.Cells),
+			appmessage.MaxTransactionReconciliationSketchCells)
+	}
+	cells := make([]*TransactionReconciliationSketchCell, len(msgTransactionReconciliationSketch.Cells))
+	for i, cell := range msgTransactionReconciliationSketch.Cells {
+		cells[i] = &TransactionReconciliationSketchCell{
+			Count:    cell.Count,
+			KeySum:   cell.KeySum,
+			CheckSum: cell.CheckSum,
+		}
+	}
+	x.TransactionReconciliationSketch = &TransactionReconciliationSketchMessage{
+		Cells: cells,
+	}
+	return nil
+}
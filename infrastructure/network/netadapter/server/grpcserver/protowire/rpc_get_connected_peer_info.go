@@ -37,6 +37,14 @@ func (x *KaspidMessage_GetConnectedPeerInfoResponse) fromAppMessage(message *app
 			AdvertisedProtocolVersion: info.AdvertisedProtocolVersion,
 			TimeConnected:             info.TimeConnected,
 			IsIbdPeer:                 info.IsIBDPeer,
+			MinPingDuration:           info.MinPingDuration,
+			AveragePingDuration:       info.AveragePingDuration,
+			MessagesSent:              info.MessagesSent,
+			BytesSent:                 info.BytesSent,
+			MessagesReceived:          info.MessagesReceived,
+			BytesReceived:             info.BytesReceived,
+			MessageTraffic:            messageTrafficInfosFromAppMessage(info.MessageTraffic),
+			RouteQueues:               routeQueueInfosFromAppMessage(info.RouteQueues),
 		}
 	}
 	x.GetConnectedPeerInfoResponse = &GetConnectedPeerInfoResponseMessage{
@@ -88,5 +96,67 @@ func (x *GetConnectedPeerInfoMessage) toAppMessage() (*appmessage.GetConnectedPe
 		AdvertisedProtocolVersion: x.AdvertisedProtocolVersion,
 		TimeConnected:             x.TimeOffset,
 		IsIBDPeer:                 x.IsIbdPeer,
+		MinPingDuration:           x.MinPingDuration,
+		AveragePingDuration:       x.AveragePingDuration,
+		MessagesSent:              x.MessagesSent,
+		BytesSent:                 x.BytesSent,
+		MessagesReceived:          x.MessagesReceived,
+		BytesReceived:             x.BytesReceived,
+		MessageTraffic:            messageTrafficInfosToAppMessage(x.MessageTraffic),
+		RouteQueues:               routeQueueInfosToAppMessage(x.RouteQueues),
 	}, nil
 }
+
+func messageTrafficInfosFromAppMessage(infos []*appmessage.MessageTrafficInfo) []*MessageTrafficInfo {
+	protoInfos := make([]*MessageTrafficInfo, len(infos))
+	for i, info := range infos {
+		protoInfos[i] = &MessageTrafficInfo{
+			Command:          info.Command,
+			MessagesSent:     info.MessagesSent,
+			BytesSent:        info.BytesSent,
+			MessagesReceived: info.MessagesReceived,
+			BytesReceived:    info.BytesReceived,
+		}
+	}
+	return protoInfos
+}
+
+func messageTrafficInfosToAppMessage(protoInfos []*MessageTrafficInfo) []*appmessage.MessageTrafficInfo {
+	infos := make([]*appmessage.MessageTrafficInfo, len(protoInfos))
+	for i, protoInfo := range protoInfos {
+		infos[i] = &appmessage.MessageTrafficInfo{
+			Command:          protoInfo.Command,
+			MessagesSent:     protoInfo.MessagesSent,
+			BytesSent:        protoInfo.BytesSent,
+			MessagesReceived: protoInfo.MessagesReceived,
+			BytesReceived:    protoInfo.BytesReceived,
+		}
+	}
+	return infos
+}
+
+func routeQueueInfosFromAppMessage(infos []*appmessage.RouteQueueInfo) []*RouteQueueInfo {
+	protoInfos := make([]*RouteQueueInfo, len(infos))
+	for i, info := range infos {
+		protoInfos[i] = &RouteQueueInfo{
+			Name:          info.Name,
+			Length:        info.Length,
+			Capacity:      info.Capacity,
+			HighWaterMark: info.HighWaterMark,
+		}
+	}
+	return protoInfos
+}
+
+func routeQueueInfosToAppMessage(protoInfos []*RouteQueueInfo) []*appmessage.RouteQueueInfo {
+	infos := make([]*appmessage.RouteQueueInfo, len(protoInfos))
+	for i, protoInfo := range protoInfos {
+		infos[i] = &appmessage.RouteQueueInfo{
+			Name:          protoInfo.Name,
+			Length:        protoInfo.Length,
+			Capacity:      protoInfo.Capacity,
+			HighWaterMark: protoInfo.HighWaterMark,
+		}
+	}
+	return infos
+}
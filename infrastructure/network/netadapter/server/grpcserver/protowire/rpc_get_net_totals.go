@@ -0,0 +1,60 @@
+package protowire
+
+import (
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *KaspidMessage_GetNetTotalsRequest) toAppMessage() (appmessage.Message, error) {
+	return &appmessage.GetNetTotalsRequestMessage{}, nil
+}
+
+func (x *KaspidMessage_GetNetTotalsRequest) fromAppMessage(_ *appmessage.GetNetTotalsRequestMessage) error {
+	x.GetNetTotalsRequest = &GetNetTotalsRequestMessage{}
+	return nil
+}
+
+func (x *KaspidMessage_GetNetTotalsResponse) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "KaspidMessage_GetNetTotalsResponse is nil")
+	}
+	return x.GetNetTotalsResponse.toAppMessage()
+}
+
+func (x *KaspidMessage_GetNetTotalsResponse) fromAppMessage(message *appmessage.GetNetTotalsResponseMessage) error {
+	var err *RPCError
+	if message.Error != nil {
+		err = &RPCError{Message: message.Error.Message}
+	}
+	x.GetNetTotalsResponse = &GetNetTotalsResponseMessage{
+		MessagesSent:     message.MessagesSent,
+		BytesSent:        message.BytesSent,
+		MessagesReceived: message.MessagesReceived,
+		BytesReceived:    message.BytesReceived,
+		MessageTraffic:   messageTrafficInfosFromAppMessage(message.MessageTraffic),
+
+		Error: err,
+	}
+	return nil
+}
+
+func (x *GetNetTotalsResponseMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "GetNetTotalsResponseMessage is nil")
+	}
+	rpcErr, err := x.Error.toAppMessage()
+	// Error is an optional field
+	if err != nil && !errors.Is(err, errorNil) {
+		return nil, err
+	}
+
+	return &appmessage.GetNetTotalsResponseMessage{
+		MessagesSent:     x.MessagesSent,
+		BytesSent:        x.BytesSent,
+		MessagesReceived: x.MessagesReceived,
+		BytesReceived:    x.BytesReceived,
+		MessageTraffic:   messageTrafficInfosToAppMessage(x.MessageTraffic),
+
+		Error: rpcErr,
+	}, nil
+}
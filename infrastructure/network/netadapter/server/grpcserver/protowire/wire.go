@@ -359,6 +359,55 @@ func toP2PPayload(message appmessage.Message) (isKaspidMessage_Payload, error) {
 			return nil, err
 		}
 		return payload, nil
+	case *appmessage.MsgRequestCompactBlock:
+		payload := new(KaspidMessage_RequestCompactBlock)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case *appmessage.MsgCompactBlock:
+		payload := new(KaspidMessage_CompactBlock)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case *appmessage.MsgRequestBlockTransactions:
+		payload := new(KaspidMessage_RequestBlockTransactions)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case *appmessage.MsgBlockTransactions:
+		payload := new(KaspidMessage_BlockTransactions)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case *appmessage.MsgRequestTransactionReconciliation:
+		payload := new(KaspidMessage_RequestTransactionReconciliation)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case *appmessage.MsgTransactionReconciliationSketch:
+		payload := new(KaspidMessage_TransactionReconciliationSketch)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case *appmessage.MsgTransactionReconciliationDifference:
+		payload := new(KaspidMessage_TransactionReconciliationDifference)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
 	default:
 		return nil, nil
 	}
@@ -968,6 +1017,20 @@ func toRPCPayload(message appmessage.Message) (isKaspidMessage_Payload, error) {
 			return nil, err
 		}
 		return payload, nil
+	case *appmessage.GetNetTotalsRequestMessage:
+		payload := new(KaspidMessage_GetNetTotalsRequest)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case *appmessage.GetNetTotalsResponseMessage:
+		payload := new(KaspidMessage_GetNetTotalsResponse)
+		err := payload.fromAppMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
 	default:
 		return nil, nil
 	}
@@ -2,8 +2,8 @@ package server
 
 import (
 	"fmt"
-	"net"
 
+	"github.com/kaspikr/kaspid/app/appmessage"
 	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/router"
 )
 
@@ -31,6 +31,7 @@ type Server interface {
 type P2PServer interface {
 	Server
 	Connect(address string) (Connection, error)
+	TrafficStats() *TrafficStats
 }
 
 // Connection represents a server connection.
@@ -42,5 +43,9 @@ type Connection interface {
 	IsOutbound() bool
 	SetOnDisconnectedHandler(onDisconnectedHandler OnDisconnectedHandler)
 	SetOnInvalidMessageHandler(onInvalidMessageHandler OnInvalidMessageHandler)
-	Address() *net.TCPAddr
+	Address() *appmessage.NetAddress
+	IsEncrypted() bool
+	IsTrusted() bool
+	RemoteIdentityKey() []byte
+	TrafficStats() *TrafficStats
 }
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+)
+
+// MessageTraffic counts the messages of some type sent and
+// received over a connection, and their serialized size
+type MessageTraffic struct {
+	MessagesSent     uint64
+	BytesSent        uint64
+	MessagesReceived uint64
+	BytesReceived    uint64
+}
+
+func (t *MessageTraffic) add(other *MessageTraffic) {
+	t.MessagesSent += other.MessagesSent
+	t.BytesSent += other.BytesSent
+	t.MessagesReceived += other.MessagesReceived
+	t.BytesReceived += other.BytesReceived
+}
+
+// MessageTypeTraffic is the MessageTraffic of a single message type
+type MessageTypeTraffic struct {
+	Command appmessage.MessageCommand
+	MessageTraffic
+}
+
+// TrafficStats counts the traffic of a connection per message type.
+// Traffic recorded in TrafficStats is recorded in its parent as well,
+// if it has one, so that the parent sums up the traffic of many connections.
+type TrafficStats struct {
+	parent *TrafficStats
+
+	lock          sync.Mutex
+	byMessageType map[appmessage.MessageCommand]*MessageTraffic
+}
+
+// NewTrafficStats returns a new TrafficStats that records its traffic
+// in the given parent as well. parent may be nil.
+func NewTrafficStats(parent *TrafficStats) *TrafficStats {
+	return &TrafficStats{
+		parent:        parent,
+		byMessageType: make(map[appmessage.MessageCommand]*MessageTraffic),
+	}
+}
+
+// RecordSent records that a message with the given command and serialized size was sent
+func (s *TrafficStats) RecordSent(command appmessage.MessageCommand, size int) {
+	s.record(command, &MessageTraffic{MessagesSent: 1, BytesSent: uint64(size)})
+}
+
+// RecordReceived records that a message with the given command and serialized size was received
+func (s *TrafficStats) RecordReceived(command appmessage.MessageCommand, size int) {
+	s.record(command, &MessageTraffic{MessagesReceived: 1, BytesReceived: uint64(size)})
+}
+
+func (s *TrafficStats) record(command appmessage.MessageCommand, traffic *MessageTraffic) {
+	for stats := s; stats != nil; stats = stats.parent {
+		stats.lock.Lock()
+		messageTypeTraffic, ok := stats.byMessageType[command]
+		if !ok {
+			messageTypeTraffic = &MessageTraffic{}
+			stats.byMessageType[command] = messageTypeTraffic
+		}
+		messageTypeTraffic.add(traffic)
+		stats.lock.Unlock()
+	}
+}
+
+// Total returns the traffic of all message types together
+func (s *TrafficStats) Total() MessageTraffic {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	total := MessageTraffic{}
+	for _, traffic := range s.byMessageType {
+		total.add(traffic)
+	}
+	return total
+}
+
+// ByMessageType returns the traffic of every message type
+// that was sent or received, sorted by command
+func (s *TrafficStats) ByMessageType() []*MessageTypeTraffic {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	byMessageType := make([]*MessageTypeTraffic, 0, len(s.byMessageType))
+	for command, traffic := range s.byMessageType {
+		byMessageType = append(byMessageType, &MessageTypeTraffic{Command: command, MessageTraffic: *traffic})
+	}
+	sort.Slice(byMessageType, func(i, j int) bool {
+		return byMessageType[i].Command < byMessageType[j].Command
+	})
+	return byMessageType
+}
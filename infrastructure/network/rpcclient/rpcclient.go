@@ -26,7 +26,8 @@ type RPCClient struct {
 	isReconnecting       uint32
 	lastDisconnectedTime time.Time
 
-	timeout time.Duration
+	timeout              time.Duration
+	onReconnectedHandler func()
 }
 
 // NewRPCClient сreates a new RPC client with a default call timeout value
@@ -118,6 +119,9 @@ func (c *RPCClient) Reconnect() error {
 		if time.Since(c.lastDisconnectedTime) > retryDelay {
 			err := c.connect()
 			if err == nil {
+				if c.onReconnectedHandler != nil {
+					c.onReconnectedHandler()
+				}
 				return nil
 			}
 			log.Warnf("Could not automatically reconnect to %s: %s", c.rpcAddress, err)
@@ -155,6 +159,12 @@ func (c *RPCClient) SetTimeout(timeout time.Duration) {
 	c.timeout = timeout
 }
 
+// SetOnReconnectedHandler sets a handler that is called after the client reconnects.
+// Notification registrations don't survive a reconnection, so it's where they are renewed.
+func (c *RPCClient) SetOnReconnectedHandler(onReconnectedHandler func()) {
+	c.onReconnectedHandler = onReconnectedHandler
+}
+
 // Close closes the RPC client
 func (c *RPCClient) Close() error {
 	swapped := atomic.CompareAndSwapUint32(&c.isClosed, 0, 1)
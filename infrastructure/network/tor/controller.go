@@ -0,0 +1,205 @@
+package tor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	controlDialTimeout = 10 * time.Second
+	replyOK            = 250
+
+	safeCookieServerToControllerKey = "Tor safe cookie authentication server-to-controller hash"
+	safeCookieControllerToServerKey = "Tor safe cookie authentication controller-to-server hash"
+	safeCookieNonceSize             = 32
+)
+
+// controller is a client of the Tor control protocol, as specified in
+// https://spec.torproject.org/control-spec
+type controller struct {
+	conn *textproto.Conn
+}
+
+func dialController(address string) (*controller, error) {
+	conn, err := net.DialTimeout("tcp", address, controlDialTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not connect to the Tor control port at %s", address)
+	}
+	return &controller{conn: textproto.NewConn(conn)}, nil
+}
+
+func (c *controller) close() error {
+	return c.conn.Close()
+}
+
+// command sends the given command, and returns the lines of its reply
+// if it succeeded
+func (c *controller) command(command string) ([]string, error) {
+	commandName := strings.SplitN(command, " ", 2)[0]
+
+	err := c.conn.PrintfLine("%s", command)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not send %s to Tor", commandName)
+	}
+	_, message, err := c.conn.ReadResponse(replyOK)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Tor replied to %s with an error", commandName)
+	}
+	return strings.Split(message, "\n"), nil
+}
+
+// authenticate authenticates with the given password if one is given, and
+// otherwise by the strongest method Tor supports among no authentication,
+// safe cookie and cookie authentication
+func (c *controller) authenticate(password string) error {
+	lines, err := c.command("PROTOCOLINFO 1")
+	if err != nil {
+		return err
+	}
+
+	methods := make(map[string]bool)
+	var cookieFile string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "AUTH ") {
+			continue
+		}
+		fields := parseReplyFields(strings.TrimPrefix(line, "AUTH "))
+		for _, method := range strings.Split(fields["METHODS"], ",") {
+			methods[method] = true
+		}
+		cookieFile = fields["COOKIEFILE"]
+	}
+
+	switch {
+	case password != "":
+		if !methods["HASHEDPASSWORD"] {
+			return errors.New("Tor does not accept password authentication")
+		}
+		_, err = c.command("AUTHENTICATE " + quoteString(password))
+	case methods["NULL"]:
+		_, err = c.command("AUTHENTICATE")
+	case methods["SAFECOOKIE"] && cookieFile != "":
+		err = c.authenticateSafeCookie(cookieFile)
+	case methods["COOKIE"] && cookieFile != "":
+		var cookie []byte
+		cookie, err = os.ReadFile(cookieFile)
+		if err != nil {
+			return errors.Wrap(err, "could not read the Tor authentication cookie")
+		}
+		_, err = c.command("AUTHENTICATE " + hex.EncodeToString(cookie))
+	default:
+		return errors.New("Tor requires a password to authenticate -- use --torpassword")
+	}
+	return err
+}
+
+func (c *controller) authenticateSafeCookie(cookieFile string) error {
+	cookie, err := os.ReadFile(cookieFile)
+	if err != nil {
+		return errors.Wrap(err, "could not read the Tor authentication cookie")
+	}
+	clientNonce := make([]byte, safeCookieNonceSize)
+	_, err = rand.Read(clientNonce)
+	if err != nil {
+		return err
+	}
+
+	lines, err := c.command("AUTHCHALLENGE SAFECOOKIE " + hex.EncodeToString(clientNonce))
+	if err != nil {
+		return err
+	}
+	fields := parseReplyFields(strings.TrimPrefix(lines[0], "AUTHCHALLENGE "))
+	serverHash, err := hex.DecodeString(fields["SERVERHASH"])
+	if err != nil {
+		return errors.Wrap(err, "malformed SERVERHASH in the Tor authentication challenge")
+	}
+	serverNonce, err := hex.DecodeString(fields["SERVERNONCE"])
+	if err != nil {
+		return errors.Wrap(err, "malformed SERVERNONCE in the Tor authentication challenge")
+	}
+
+	message := make([]byte, 0, len(cookie)+len(clientNonce)+len(serverNonce))
+	message = append(message, cookie...)
+	message = append(message, clientNonce...)
+	message = append(message, serverNonce...)
+	if !hmac.Equal(serverHash, safeCookieHash(safeCookieServerToControllerKey, message)) {
+		return errors.New("Tor failed to prove that it knows the authentication cookie")
+	}
+
+	clientHash := safeCookieHash(safeCookieControllerToServerKey, message)
+	_, err = c.command("AUTHENTICATE " + hex.EncodeToString(clientHash))
+	return err
+}
+
+func safeCookieHash(key string, message []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// parseReplyFields parses the space separated KEY=VALUE fields of a reply
+// line, where values may be quoted strings
+func parseReplyFields(line string) map[string]string {
+	fields := make(map[string]string)
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " ")
+		equalsIndex := strings.IndexByte(line, '=')
+		if equalsIndex < 0 {
+			break
+		}
+		key := line[:equalsIndex]
+		line = line[equalsIndex+1:]
+
+		if strings.HasPrefix(line, "\"") {
+			value, rest, ok := unquoteString(line)
+			if !ok {
+				break
+			}
+			fields[key] = value
+			line = rest
+			continue
+		}
+		valueEnd := strings.IndexByte(line, ' ')
+		if valueEnd < 0 {
+			valueEnd = len(line)
+		}
+		fields[key] = line[:valueEnd]
+		line = line[valueEnd:]
+	}
+	return fields
+}
+
+// unquoteString unquotes the quoted string at the start of s, and returns
+// the rest of s after it
+func unquoteString(s string) (value string, rest string, ok bool) {
+	var builder strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i < len(s) {
+				builder.WriteByte(s[i])
+			}
+		case '"':
+			return builder.String(), s[i+1:], true
+		default:
+			builder.WriteByte(s[i])
+		}
+	}
+	return "", "", false
+}
+
+var quotedStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func quoteString(s string) string {
+	return `"` + quotedStringEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,7 @@
+package tor
+
+import (
+	"github.com/kaspikr/kaspid/infrastructure/logger"
+)
+
+var log = logger.RegisterSubSystem("TORC")
@@ -0,0 +1,126 @@
+package tor
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+const newOnionServiceKey = "NEW:ED25519-V3"
+
+// OnionService is a Tor onion service that forwards inbound connections to a
+// local P2P listener. Tor removes the service once its control connection is
+// closed, so the service exists exactly as long as the OnionService is open.
+type OnionService struct {
+	controller *controller
+	serviceID  string
+	netAddress *appmessage.NetAddress
+}
+
+// NewOnionService creates an onion service through the Tor control port at
+// controlAddress, which forwards connections to the given virtual port of the
+// service to target. The private key of the service is kept in privateKeyFile,
+// so that the service keeps its onion address across restarts.
+func NewOnionService(controlAddress, password, privateKeyFile string, virtualPort uint16,
+	target string) (*OnionService, error) {
+
+	controller, err := dialController(controlAddress)
+	if err != nil {
+		return nil, err
+	}
+	onionService, err := newOnionService(controller, password, privateKeyFile, virtualPort, target)
+	if err != nil {
+		controller.close()
+		return nil, err
+	}
+	return onionService, nil
+}
+
+func newOnionService(controller *controller, password, privateKeyFile string, virtualPort uint16,
+	target string) (*OnionService, error) {
+
+	err := controller.authenticate(password)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := loadPrivateKey(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	keySpec := privateKey
+	if keySpec == "" {
+		keySpec = newOnionServiceKey
+	}
+
+	lines, err := controller.command("ADD_ONION " + keySpec + " Port=" +
+		strconv.Itoa(int(virtualPort)) + "," + target)
+	if err != nil {
+		return nil, err
+	}
+	var serviceID string
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "ServiceID="):
+			serviceID = strings.TrimPrefix(line, "ServiceID=")
+		case strings.HasPrefix(line, "PrivateKey=") && privateKey == "":
+			err := savePrivateKey(privateKeyFile, strings.TrimPrefix(line, "PrivateKey="))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if serviceID == "" {
+		return nil, errors.New("Tor did not reply with the ID of the new onion service")
+	}
+
+	netAddress, err := appmessage.NewNetAddressOverlayHost(serviceID+".onion", virtualPort)
+	if err != nil {
+		return nil, errors.Wrap(err, "Tor replied with an invalid onion service ID")
+	}
+	log.Infof("Created onion service %s, which forwards to %s", netAddress, target)
+
+	return &OnionService{
+		controller: controller,
+		serviceID:  serviceID,
+		netAddress: netAddress,
+	}, nil
+}
+
+// NetAddress returns the address of the onion service
+func (s *OnionService) NetAddress() *appmessage.NetAddress {
+	return s.netAddress
+}
+
+// Close removes the onion service
+func (s *OnionService) Close() error {
+	_, err := s.controller.command("DEL_ONION " + s.serviceID)
+	if err != nil {
+		log.Warnf("Could not remove onion service %s: %s", s.netAddress, err)
+	}
+	return s.controller.close()
+}
+
+// loadPrivateKey returns the private key in the given file in the KeyType:KeyBlob
+// format of ADD_ONION, or an empty string if the file doesn't exist
+func loadPrivateKey(privateKeyFile string) (string, error) {
+	privateKey, err := os.ReadFile(privateKeyFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "could not read the onion service private key")
+	}
+	return strings.TrimSpace(string(privateKey)), nil
+}
+
+func savePrivateKey(privateKeyFile string, privateKey string) error {
+	err := os.WriteFile(privateKeyFile, []byte(privateKey+"\n"), 0600)
+	if err != nil {
+		return errors.Wrap(err, "could not save the onion service private key")
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package tor
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kaspikr/kaspid/app/appmessage"
+)
+
+const (
+	testServiceID  = "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad"
+	testPrivateKey = "ED25519-V3:dGVzdCBwcml2YXRlIGtleQ=="
+)
+
+// fakeControlPort is a minimal stand-in for the Tor control port
+type fakeControlPort struct {
+	listener    net.Listener
+	authMethods string
+	cookieFile  string
+	password    string
+	commands    chan string
+}
+
+func newFakeControlPort(t *testing.T, authMethods, cookieFile, password string) *fakeControlPort {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	fake := &fakeControlPort{
+		listener:    listener,
+		authMethods: authMethods,
+		cookieFile:  cookieFile,
+		password:    password,
+		commands:    make(chan string, 100),
+	}
+	t.Cleanup(func() { listener.Close() })
+	go fake.serve()
+	return fake
+}
+
+func (f *fakeControlPort) address() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeControlPort) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeControlPort) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		f.commands <- line
+
+		reply := f.reply(line, &authenticated)
+		_, err = conn.Write([]byte(reply))
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeControlPort) reply(line string, authenticated *bool) string {
+	fields := strings.SplitN(line, " ", 2)
+	switch fields[0] {
+	case "PROTOCOLINFO":
+		auth := "250-AUTH METHODS=" + f.authMethods
+		if f.cookieFile != "" {
+			auth += fmt.Sprintf(" COOKIEFILE=%s", quoteString(f.cookieFile))
+		}
+		return "250-PROTOCOLINFO 1\r\n" + auth + "\r\n250-VERSION Tor=\"0.4.8.9\"\r\n250 OK\r\n"
+	case "AUTHENTICATE":
+		if f.authenticate(fields) {
+			*authenticated = true
+			return "250 OK\r\n"
+		}
+		return "515 Authentication failed\r\n"
+	case "ADD_ONION":
+		if !*authenticated {
+			return "514 Authentication required\r\n"
+		}
+		reply := "250-ServiceID=" + testServiceID + "\r\n"
+		if strings.HasPrefix(fields[1], newOnionServiceKey) {
+			reply += "250-PrivateKey=" + testPrivateKey + "\r\n"
+		}
+		return reply + "250 OK\r\n"
+	case "DEL_ONION":
+		return "250 OK\r\n"
+	}
+	return "510 Unrecognized command\r\n"
+}
+
+func (f *fakeControlPort) authenticate(fields []string) bool {
+	switch f.authMethods {
+	case "NULL":
+		return len(fields) == 1
+	case "HASHEDPASSWORD":
+		return len(fields) == 2 && fields[1] == quoteString(f.password)
+	case "COOKIE":
+		cookie, err := os.ReadFile(f.cookieFile)
+		if err != nil {
+			return false
+		}
+		return len(fields) == 2 && fields[1] == hex.EncodeToString(cookie)
+	}
+	return false
+}
+
+func TestOnionService(t *testing.T) {
+	dir := t.TempDir()
+	cookieFile := filepath.Join(dir, "control_auth_cookie")
+	err := os.WriteFile(cookieFile, []byte("0123456789abcdef0123456789abcdef"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	tests := []struct {
+		name        string
+		authMethods string
+		password    string
+	}{
+		{name: "no authentication", authMethods: "NULL"},
+		{name: "password authentication", authMethods: "HASHEDPASSWORD", password: `pass "word"\`},
+		{name: "cookie authentication", authMethods: "COOKIE"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFakeControlPort(t, test.authMethods, cookieFile, test.password)
+			privateKeyFile := filepath.Join(t.TempDir(), "onion_v3_private_key")
+
+			onionService, err := NewOnionService(fake.address(), test.password, privateKeyFile, 16111, "127.0.0.1:16111")
+			if err != nil {
+				t.Fatalf("NewOnionService: %s", err)
+			}
+			defer onionService.Close()
+
+			netAddress := onionService.NetAddress()
+			if netAddress.Network != appmessage.AddressNetworkTorV3 {
+				t.Fatalf("unexpected network %s", netAddress.Network)
+			}
+			if netAddress.String() != testServiceID+".onion:16111" {
+				t.Fatalf("unexpected address %s", netAddress)
+			}
+		})
+	}
+}
+
+func TestOnionServicePrivateKey(t *testing.T) {
+	fake := newFakeControlPort(t, "NULL", "", "")
+	privateKeyFile := filepath.Join(t.TempDir(), "onion_v3_private_key")
+
+	addOnionCommand := func() string {
+		for command := range fake.commands {
+			if strings.HasPrefix(command, "ADD_ONION") {
+				return command
+			}
+		}
+		return ""
+	}
+
+	// The first service gets a new key, which is saved
+	onionService, err := NewOnionService(fake.address(), "", privateKeyFile, 16111, "127.0.0.1:16111")
+	if err != nil {
+		t.Fatalf("NewOnionService: %s", err)
+	}
+	onionService.Close()
+	expectedCommand := "ADD_ONION NEW:ED25519-V3 Port=16111,127.0.0.1:16111"
+	if command := addOnionCommand(); command != expectedCommand {
+		t.Fatalf("expected %q, got %q", expectedCommand, command)
+	}
+	privateKey, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	if strings.TrimSpace(string(privateKey)) != testPrivateKey {
+		t.Fatalf("unexpected saved private key %q", privateKey)
+	}
+
+	// Later services reuse the saved key
+	onionService, err = NewOnionService(fake.address(), "", privateKeyFile, 16111, "127.0.0.1:16111")
+	if err != nil {
+		t.Fatalf("NewOnionService: %s", err)
+	}
+	onionService.Close()
+	expectedCommand = "ADD_ONION " + testPrivateKey + " Port=16111,127.0.0.1:16111"
+	if command := addOnionCommand(); command != expectedCommand {
+		t.Fatalf("expected %q, got %q", expectedCommand, command)
+	}
+}
+
+func TestOnionServiceAuthenticationFailure(t *testing.T) {
+	fake := newFakeControlPort(t, "HASHEDPASSWORD", "", "correct")
+	privateKeyFile := filepath.Join(t.TempDir(), "onion_v3_private_key")
+
+	_, err := NewOnionService(fake.address(), "incorrect", privateKeyFile, 16111, "127.0.0.1:16111")
+	if err == nil {
+		t.Fatalf("NewOnionService unexpectedly succeeded with a wrong password")
+	}
+	_, err = NewOnionService(fake.address(), "", privateKeyFile, 16111, "127.0.0.1:16111")
+	if err == nil {
+		t.Fatalf("NewOnionService unexpectedly succeeded without a password")
+	}
+}
+
+func TestParseReplyFields(t *testing.T) {
+	fields := parseReplyFields(`METHODS=COOKIE,SAFECOOKIE COOKIEFILE="/var/lib/tor/control \"auth\" cookie"`)
+	if fields["METHODS"] != "COOKIE,SAFECOOKIE" {
+		t.Fatalf("unexpected METHODS %q", fields["METHODS"])
+	}
+	if fields["COOKIEFILE"] != `/var/lib/tor/control "auth" cookie` {
+		t.Fatalf("unexpected COOKIEFILE %q", fields["COOKIEFILE"])
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"time"
+
+	"github.com/kaspikr/go-secp256k1"
+	"github.com/kaspikr/kaspid/app/appmessage"
+	"github.com/kaspikr/kaspid/app/protocol/flowcontext"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/consensushashing"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/subnetworks"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/txscript"
+	utxopkg "github.com/kaspikr/kaspid/domain/consensus/utils/utxo"
+	"github.com/kaspikr/kaspid/infrastructure/network/netadapter/server/grpcserver/protowire"
+	"github.com/kaspikr/kaspid/stability-tests/common/mine"
+	"github.com/kaspikr/kaspid/stability-tests/common/rpc"
+	"github.com/kaspikr/kaspid/util"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	compactBlockRelayTransactionCount = 100
+	compactBlockRelayTransactionFee   = 10_000
+)
+
+// checkCompactBlockRelay fills the mempools of both nodes with transactions, mines a block that
+// contains them on the SYNCER, and checks through the P2P traffic counters of the SYNCEE that it
+// received the block in its compact form, without requesting any of its transactions or falling
+// back to the full block, since its mempool already holds all of them.
+func checkCompactBlockRelay(syncerClient, syncedClient *rpc.Client) error {
+	log.Info("Checking compact block relay")
+
+	keyPair, err := secp256k1.GenerateSchnorrKeyPair()
+	if err != nil {
+		return err
+	}
+	publicKey, err := keyPair.SchnorrPublicKey()
+	if err != nil {
+		return err
+	}
+	publicKeySerialized, err := publicKey.Serialize()
+	if err != nil {
+		return err
+	}
+	address, err := util.NewAddressPublicKey(publicKeySerialized[:], activeConfig().NetParams().Prefix)
+	if err != nil {
+		return err
+	}
+
+	// Mine enough blocks to have compactBlockRelayTransactionCount mature coinbase outputs. The reward of
+	// a block is paid by the coinbase of the block that merges it, so one more block is needed for it.
+	fundingBlockCount := compactBlockRelayTransactionCount + activeConfig().NetParams().BlockCoinbaseMaturity + 2
+	log.Infof("Mining %d funding blocks", fundingBlockCount)
+	for i := uint64(0); i < fundingBlockCount; i++ {
+		_, err := mineBlockAndWaitForSyncee(syncerClient, syncedClient, address)
+		if err != nil {
+			return err
+		}
+	}
+
+	transactions, err := buildCompactBlockRelayTransactions(syncerClient, address, keyPair)
+	if err != nil {
+		return err
+	}
+	log.Infof("Submitting %d transactions to the SYNCER", len(transactions))
+	for i, transaction := range transactions {
+		// The SYNCER announces the transactions it accepts in batches, once a transaction is accepted
+		// after the propagation interval passed, so the last one is what makes it announce all of them
+		if i == len(transactions)-1 {
+			time.Sleep(flowcontext.TransactionIDPropagationInterval)
+		}
+		_, err := syncerClient.SubmitTransaction(appmessage.DomainTransactionToRPCTransaction(transaction),
+			consensushashing.TransactionID(transaction).String(), false)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = waitForMempoolSize(syncedClient, len(transactions))
+	if err != nil {
+		return err
+	}
+
+	trafficBefore, err := p2pReceivedTraffic(syncedClient)
+	if err != nil {
+		return err
+	}
+	block, err := mineBlockAndWaitForSyncee(syncerClient, syncedClient, address)
+	if err != nil {
+		return err
+	}
+	if len(block.Transactions) < len(transactions) {
+		return errors.Errorf("expected the relayed block to contain at least %d transactions but got %d",
+			len(transactions), len(block.Transactions))
+	}
+	trafficAfter, err := p2pReceivedTraffic(syncedClient)
+	if err != nil {
+		return err
+	}
+	received := func(command appmessage.MessageCommand) *appmessage.MessageTrafficInfo {
+		before, after := trafficBefore[command.String()], trafficAfter[command.String()]
+		return &appmessage.MessageTrafficInfo{
+			Command:          command.String(),
+			MessagesReceived: after.MessagesReceived - before.MessagesReceived,
+			BytesReceived:    after.BytesReceived - before.BytesReceived,
+		}
+	}
+
+	compactBlocks := received(appmessage.CmdCompactBlock)
+	blockTransactions := received(appmessage.CmdBlockTransactions)
+	fullBlocks := received(appmessage.CmdBlock)
+	if compactBlocks.MessagesReceived != 1 {
+		return errors.Errorf("expected the SYNCEE to receive 1 compact block but it received %d",
+			compactBlocks.MessagesReceived)
+	}
+	if blockTransactions.MessagesReceived != 0 {
+		return errors.Errorf("the SYNCEE requested missing transactions of the block %d times, "+
+			"although its mempool holds all of them", blockTransactions.MessagesReceived)
+	}
+	if fullBlocks.MessagesReceived != 0 {
+		return errors.Errorf("the SYNCEE fell back to receiving %d full blocks, although its mempool holds "+
+			"all the transactions of the block", fullBlocks.MessagesReceived)
+	}
+
+	// The full block was never sent, so its size is the size it would have had on the wire
+	fullBlockSize, err := messageWireSize(appmessage.DomainBlockToMsgBlock(block))
+	if err != nil {
+		return err
+	}
+	if compactBlocks.BytesReceived >= uint64(fullBlockSize) {
+		return errors.Errorf("the SYNCEE received a compact block of %d bytes, which is not smaller than "+
+			"the full block of %d bytes", compactBlocks.BytesReceived, fullBlockSize)
+	}
+	log.Infof("The SYNCEE received a block with %d transactions as a compact block of %d bytes instead of "+
+		"%d bytes (%.1f%% saved)", len(block.Transactions), compactBlocks.BytesReceived, fullBlockSize,
+		100*float64(uint64(fullBlockSize)-compactBlocks.BytesReceived)/float64(fullBlockSize))
+
+	return nil
+}
+
+// p2pReceivedTraffic returns the P2P traffic the node received so far, by message command.
+// Commands the node received nothing of are missing, and map to zero traffic.
+func p2pReceivedTraffic(client *rpc.Client) (map[string]appmessage.MessageTrafficInfo, error) {
+	netTotals, err := client.GetNetTotals()
+	if err != nil {
+		return nil, err
+	}
+	trafficByCommand := make(map[string]appmessage.MessageTrafficInfo, len(netTotals.MessageTraffic))
+	for _, traffic := range netTotals.MessageTraffic {
+		trafficByCommand[traffic.Command] = *traffic
+	}
+	return trafficByCommand, nil
+}
+
+func mineBlockAndWaitForSyncee(syncerClient, syncedClient *rpc.Client,
+	address util.Address) (*externalapi.DomainBlock, error) {
+
+	template, err := syncerClient.GetBlockTemplate(address.String(), "")
+	if err != nil {
+		return nil, err
+	}
+	block, err := appmessage.RPCBlockToDomainBlock(template.Block)
+	if err != nil {
+		return nil, err
+	}
+	if !activeConfig().NetParams().SkipProofOfWork {
+		mine.SolveBlock(block)
+	}
+	rejectReason, err := syncerClient.SubmitBlockAlsoIfNonDAA(block)
+	if err != nil {
+		return nil, err
+	}
+	if rejectReason != appmessage.RejectReasonNone {
+		return nil, errors.Errorf("mined block rejected: %s", rejectReason)
+	}
+
+	const timeout = 10 * time.Second
+	select {
+	case <-syncedClient.OnBlockAdded:
+	case <-time.After(timeout):
+		return nil, errors.Errorf("SYNCEE did not receive block %s after %s", consensushashing.BlockHash(block), timeout)
+	}
+	return block, nil
+}
+
+func buildCompactBlockRelayTransactions(syncerClient *rpc.Client, address util.Address,
+	keyPair *secp256k1.SchnorrKeyPair) ([]*externalapi.DomainTransaction, error) {
+
+	scriptPublicKey, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, err
+	}
+	dagInfo, err := syncerClient.GetBlockDAGInfo()
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := syncerClient.GetUTXOsByAddresses([]string{address.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]*externalapi.DomainTransaction, 0, compactBlockRelayTransactionCount)
+	for _, entry := range utxos.Entries {
+		if len(transactions) == compactBlockRelayTransactionCount {
+			break
+		}
+		if entry.UTXOEntry.BlockDAAScore+activeConfig().NetParams().BlockCoinbaseMaturity >= dagInfo.VirtualDAAScore ||
+			entry.UTXOEntry.Amount <= compactBlockRelayTransactionFee {
+			continue
+		}
+
+		outpoint, err := appmessage.RPCOutpointToDomainOutpoint(entry.Outpoint)
+		if err != nil {
+			return nil, err
+		}
+		transaction := &externalapi.DomainTransaction{
+			Version: constants.MaxTransactionVersion,
+			Inputs: []*externalapi.DomainTransactionInput{{
+				PreviousOutpoint: *outpoint,
+				UTXOEntry: utxopkg.NewUTXOEntry(entry.UTXOEntry.Amount, scriptPublicKey,
+					entry.UTXOEntry.IsCoinbase, entry.UTXOEntry.BlockDAAScore),
+				SigOpCount: 1,
+			}},
+			Outputs: []*externalapi.DomainTransactionOutput{{
+				Value:           entry.UTXOEntry.Amount - compactBlockRelayTransactionFee,
+				ScriptPublicKey: scriptPublicKey,
+			}},
+			SubnetworkID: subnetworks.SubnetworkIDNative,
+			Payload:      []byte{},
+		}
+		signatureScript, err := txscript.SignatureScript(transaction, 0, consensushashing.SigHashAll, keyPair,
+			&consensushashing.SighashReusedValues{})
+		if err != nil {
+			return nil, err
+		}
+		transaction.Inputs[0].SignatureScript = signatureScript
+		transactions = append(transactions, transaction)
+	}
+
+	if len(transactions) < compactBlockRelayTransactionCount {
+		return nil, errors.Errorf("expected %d mature UTXOs but found only %d",
+			compactBlockRelayTransactionCount, len(transactions))
+	}
+	return transactions, nil
+}
+
+func waitForMempoolSize(client *rpc.Client, expectedSize int) error {
+	const timeout = 30 * time.Second
+	start := time.Now()
+	for {
+		response, err := client.GetMempoolEntries(false, false)
+		if err != nil {
+			return err
+		}
+		if len(response.Entries) >= expectedSize {
+			return nil
+		}
+		if time.Since(start) > timeout {
+			return errors.Errorf("SYNCEE mempool has %d transactions after %s while expecting %d",
+				len(response.Entries), timeout, expectedSize)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func messageWireSize(message appmessage.Message) (int, error) {
+	protoMessage, err := protowire.FromAppMessage(message)
+	if err != nil {
+		return 0, err
+	}
+	return proto.Size(protoMessage), nil
+}
@@ -62,5 +62,10 @@ func main() {
 		panic(errors.Wrap(err, "error in checkResolveVirtual"))
 	}
 
+	err = checkCompactBlockRelay(syncerClient, syncedClient)
+	if err != nil {
+		panic(errors.Wrap(err, "error in checkCompactBlockRelay"))
+	}
+
 	atomic.StoreUint64(&shutdown, 1)
 }
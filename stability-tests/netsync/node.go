@@ -39,6 +39,7 @@ func startNode(name string, rpcAddress, listen, connect, profilePort, dataDir st
 		"--profile", profilePort,
 		"--loglevel", "debug",
 		"--allow-submit-block-when-not-synced",
+		"--utxoindex",
 	}
 	if connect != "" {
 		args = append(args, "--connect", connect)
@@ -41,6 +41,7 @@ func setConfig(t *testing.T, harness *appHarness, protocolVersion uint32) {
 	harness.config.RPCListeners = []string{harness.rpcAddress}
 	harness.config.UTXOIndex = harness.utxoIndex
 	harness.config.AllowSubmitBlockWhenNotSynced = true
+	harness.config.TxReconciliation = harness.txReconciliation
 	if protocolVersion != 0 {
 		harness.config.ProtocolVersion = protocolVersion
 	}
@@ -25,6 +25,7 @@ type appHarness struct {
 	database                database.Database
 	utxoIndex               bool
 	overrideDAGParams       *dagconfig.Params
+	txReconciliation        bool
 }
 
 type harnessParams struct {
@@ -35,6 +36,7 @@ type harnessParams struct {
 	utxoIndex               bool
 	overrideDAGParams       *dagconfig.Params
 	protocolVersion         uint32
+	txReconciliation        bool
 }
 
 // setupHarness creates a single appHarness with given parameters
@@ -46,6 +48,7 @@ func setupHarness(t *testing.T, params *harnessParams) (harness *appHarness, tea
 		miningAddressPrivateKey: params.miningAddressPrivateKey,
 		utxoIndex:               params.utxoIndex,
 		overrideDAGParams:       params.overrideDAGParams,
+		txReconciliation:        params.txReconciliation,
 	}
 
 	setConfig(t, harness, params.protocolVersion)
@@ -24,6 +24,41 @@ func TestTxRelay(t *testing.T) {
 	payer, mediator, payee, teardown := standardSetup(t)
 	defer teardown()
 
+	testTxRelay(t, payer, mediator, payee)
+}
+
+func TestTxRelayWithReconciliation(t *testing.T) {
+	harnesses, teardown := setupHarnesses(t, []*harnessParams{
+		{
+			p2pAddress:              p2pAddress1,
+			rpcAddress:              rpcAddress1,
+			miningAddress:           miningAddress1,
+			miningAddressPrivateKey: miningAddress1PrivateKey,
+			txReconciliation:        true,
+		},
+		{
+			p2pAddress:              p2pAddress2,
+			rpcAddress:              rpcAddress2,
+			miningAddress:           miningAddress2,
+			miningAddressPrivateKey: miningAddress2PrivateKey,
+			txReconciliation:        true,
+		},
+		{
+			p2pAddress:              p2pAddress3,
+			rpcAddress:              rpcAddress3,
+			miningAddress:           miningAddress3,
+			miningAddressPrivateKey: miningAddress3PrivateKey,
+			txReconciliation:        true,
+		},
+	})
+	defer teardown()
+
+	// Since the payer and the mediator are each only connected to their peers as
+	// inbound connections, the transaction can only be announced by reconciliation
+	testTxRelay(t, harnesses[0], harnesses[1], harnesses[2])
+}
+
+func testTxRelay(t *testing.T, payer, mediator, payee *appHarness) {
 	// Connect nodes in chain: payer <--> mediator <--> payee
 	// So that payee doesn't directly get transactions from payer
 	connect(t, payer, mediator)
@@ -1,4 +1,4 @@
-package utils
+package util
 
 import "testing"
 
@@ -88,3 +88,24 @@ func TestValidateAmountFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestFormatKas(t *testing.T) {
+	testVectors := map[uint64]string{
+		0:                    "0.00000000",
+		1:                    "0.00000001",
+		150_000_000:          "1.50000000",
+		18446744073709551615: "184467440737.09551615",
+	}
+	for amount, expected := range testVectors {
+		formatted := FormatKas(amount)
+		if formatted != expected {
+			t.Errorf("Expected %d to be formatted as %s. Got: %s", amount, expected, formatted)
+		}
+		parsed, err := KasToSompi(formatted)
+		if err != nil {
+			t.Errorf("KasToSompi(%s): %s", formatted, err)
+		} else if parsed != amount {
+			t.Errorf("Expected %s to convert back to %d. Got: %d", formatted, amount, parsed)
+		}
+	}
+}
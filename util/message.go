@@ -0,0 +1,48 @@
+package util
+
+import (
+	"github.com/kaspikr/go-secp256k1"
+	"github.com/kaspikr/kaspid/domain/consensus/model/externalapi"
+	"github.com/kaspikr/kaspid/domain/consensus/utils/hashes"
+	"github.com/pkg/errors"
+)
+
+// MessageHash returns the hash a message is signed on. The hash is domain separated,
+// so a signature of a message is never valid as a signature of a transaction.
+func MessageHash(message string) *externalapi.DomainHash {
+	hashWriter := hashes.NewPersonalMessageSigningHashWriter()
+	hashWriter.InfallibleWrite([]byte(message))
+	return hashWriter.Finalize()
+}
+
+// VerifyMessage returns whether signature is a valid signature of message by the key behind address.
+// The signature is expected to be a Schnorr signature for a public key address, and an ECDSA signature
+// for an ECDSA public key address. Messages can't be signed by script hash addresses.
+func VerifyMessage(address Address, message string, signature []byte) (bool, error) {
+	hash := secp256k1.Hash(*MessageHash(message).ByteArray())
+
+	switch address := address.(type) {
+	case *AddressPublicKey:
+		publicKey, err := secp256k1.DeserializeSchnorrPubKey(address.ScriptAddress())
+		if err != nil {
+			return false, err
+		}
+		schnorrSignature, err := secp256k1.DeserializeSchnorrSignatureFromSlice(signature)
+		if err != nil {
+			return false, err
+		}
+		return publicKey.SchnorrVerify(&hash, schnorrSignature), nil
+	case *AddressPublicKeyECDSA:
+		publicKey, err := secp256k1.DeserializeECDSAPubKey(address.ScriptAddress())
+		if err != nil {
+			return false, err
+		}
+		ecdsaSignature, err := secp256k1.DeserializeECDSASignatureFromSlice(signature)
+		if err != nil {
+			return false, err
+		}
+		return publicKey.ECDSAVerify(&hash, ecdsaSignature), nil
+	default:
+		return false, errors.Errorf("messages can be signed only by public key addresses, while %s is not one", address)
+	}
+}
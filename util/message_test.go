@@ -0,0 +1,87 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/kaspikr/go-secp256k1"
+	"github.com/kaspikr/kaspid/util"
+)
+
+func TestVerifyMessage(t *testing.T) {
+	const message = "Withdrawal to a self-hosted wallet"
+	hash := secp256k1.Hash(*util.MessageHash(message).ByteArray())
+
+	schnorrKeyPair, err := secp256k1.GenerateSchnorrKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateSchnorrKeyPair: %+v", err)
+	}
+	schnorrPublicKey, err := schnorrKeyPair.SchnorrPublicKey()
+	if err != nil {
+		t.Fatalf("SchnorrPublicKey: %+v", err)
+	}
+	serializedSchnorrPublicKey, err := schnorrPublicKey.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %+v", err)
+	}
+	schnorrAddress, err := util.NewAddressPublicKey(serializedSchnorrPublicKey[:], util.Bech32PrefixKaspi)
+	if err != nil {
+		t.Fatalf("NewAddressPublicKey: %+v", err)
+	}
+	schnorrSignature, err := schnorrKeyPair.SchnorrSign(&hash)
+	if err != nil {
+		t.Fatalf("SchnorrSign: %+v", err)
+	}
+
+	ecdsaPrivateKey, err := secp256k1.GenerateECDSAPrivateKey()
+	if err != nil {
+		t.Fatalf("GenerateECDSAPrivateKey: %+v", err)
+	}
+	ecdsaPublicKey, err := ecdsaPrivateKey.ECDSAPublicKey()
+	if err != nil {
+		t.Fatalf("ECDSAPublicKey: %+v", err)
+	}
+	serializedECDSAPublicKey, err := ecdsaPublicKey.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %+v", err)
+	}
+	ecdsaAddress, err := util.NewAddressPublicKeyECDSA(serializedECDSAPublicKey[:], util.Bech32PrefixKaspi)
+	if err != nil {
+		t.Fatalf("NewAddressPublicKeyECDSA: %+v", err)
+	}
+	ecdsaSignature, err := ecdsaPrivateKey.ECDSASign(&hash)
+	if err != nil {
+		t.Fatalf("ECDSASign: %+v", err)
+	}
+
+	tests := []struct {
+		name          string
+		address       util.Address
+		message       string
+		signature     []byte
+		expectedValid bool
+	}{
+		{"schnorr", schnorrAddress, message, schnorrSignature.Serialize()[:], true},
+		{"schnorr, other message", schnorrAddress, message + ".", schnorrSignature.Serialize()[:], false},
+		{"ecdsa", ecdsaAddress, message, ecdsaSignature.Serialize()[:], true},
+		{"ecdsa, other message", ecdsaAddress, message + ".", ecdsaSignature.Serialize()[:], false},
+		{"ecdsa signature for a schnorr address", schnorrAddress, message, ecdsaSignature.Serialize()[:], false},
+	}
+	for _, test := range tests {
+		isValid, err := util.VerifyMessage(test.address, test.message, test.signature)
+		if err != nil {
+			t.Fatalf("%s: VerifyMessage: %+v", test.name, err)
+		}
+		if isValid != test.expectedValid {
+			t.Fatalf("%s: expected the signature to be valid: %t, but got: %t", test.name, test.expectedValid, isValid)
+		}
+	}
+
+	scriptHashAddress, err := util.NewAddressScriptHash([]byte{1, 2, 3}, util.Bech32PrefixKaspi)
+	if err != nil {
+		t.Fatalf("NewAddressScriptHash: %+v", err)
+	}
+	_, err = util.VerifyMessage(scriptHashAddress, message, schnorrSignature.Serialize()[:])
+	if err == nil {
+		t.Fatalf("VerifyMessage unexpectedly verified a signature of a script hash address")
+	}
+}
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaspikr/kaspid/domain/consensus/utils/constants"
+	"github.com/pkg/errors"
+)
+
+// Parameters of a payment URI
+const (
+	paymentURIAmountParameter  = "amount"
+	paymentURILabelParameter   = "label"
+	paymentURIMessageParameter = "message"
+	paymentURIExpiresParameter = "expires"
+
+	// paymentURIRequiredParameterPrefix is the prefix of parameters that a payment URI
+	// must not be paid if they aren't understood, as in BIP 21
+	paymentURIRequiredParameterPrefix = "req-"
+)
+
+// PaymentURI is a request to pay to an address, in the style of BIP 21. The scheme of the
+// URI is the prefix of the address, and its parameters are optional:
+//
+//	kaspi:qr...?amount=1.5&label=Shop&message=Order%2042&expires=1700000000
+//
+// where amount is in KAS, and expires is the unix time in seconds after which it shouldn't be paid.
+type PaymentURI struct {
+	Address Address
+	// Amount is in sompi, and is 0 if the URI doesn't specify an amount
+	Amount  uint64
+	Label   string
+	Message string
+	// Expires is zero if the URI doesn't expire
+	Expires time.Time
+}
+
+// ParsePaymentURI parses a payment URI of the network with the given prefix. A plain
+// address is a payment URI with no parameters.
+func ParsePaymentURI(uri string, expectedPrefix Bech32Prefix) (*PaymentURI, error) {
+	addressString, query := uri, ""
+	if questionMarkIndex := strings.Index(uri, "?"); questionMarkIndex != -1 {
+		addressString, query = uri[:questionMarkIndex], uri[questionMarkIndex+1:]
+	}
+
+	address, err := DecodeAddress(addressString, expectedPrefix)
+	if err != nil {
+		return nil, err
+	}
+	paymentURI := &PaymentURI{Address: address}
+
+	parameters, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid parameters in payment URI %s", uri)
+	}
+	for name, values := range parameters {
+		if len(values) != 1 {
+			return nil, errors.Errorf("the parameter %s appears more than once in payment URI %s", name, uri)
+		}
+		value := values[0]
+
+		switch name {
+		case paymentURIAmountParameter:
+			paymentURI.Amount, err = KasToSompi(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid amount in payment URI %s", uri)
+			}
+			if paymentURI.Amount > constants.MaxSompi {
+				return nil, errors.Errorf("the amount %s in payment URI %s is higher than the maximum amount of KAS",
+					value, uri)
+			}
+		case paymentURILabelParameter:
+			paymentURI.Label = value
+		case paymentURIMessageParameter:
+			paymentURI.Message = value
+		case paymentURIExpiresParameter:
+			expires, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || expires <= 0 {
+				return nil, errors.Errorf("invalid expiry time %s in payment URI %s", value, uri)
+			}
+			paymentURI.Expires = time.Unix(expires, 0)
+		default:
+			if strings.HasPrefix(name, paymentURIRequiredParameterPrefix) {
+				return nil, errors.Errorf("the payment URI %s has the required parameter %s, which is not supported",
+					uri, name)
+			}
+		}
+	}
+	return paymentURI, nil
+}
+
+// String returns the text of the payment URI
+func (p *PaymentURI) String() string {
+	var parameters []string
+	if p.Amount != 0 {
+		// Trailing zeros are left out, to keep the URI short
+		amount := strings.TrimSuffix(strings.TrimRight(FormatKas(p.Amount), "0"), ".")
+		parameters = append(parameters, paymentURIAmountParameter+"="+amount)
+	}
+	if p.Label != "" {
+		parameters = append(parameters, paymentURILabelParameter+"="+escapePaymentURIParameter(p.Label))
+	}
+	if p.Message != "" {
+		parameters = append(parameters, paymentURIMessageParameter+"="+escapePaymentURIParameter(p.Message))
+	}
+	if !p.Expires.IsZero() {
+		parameters = append(parameters, paymentURIExpiresParameter+"="+strconv.FormatInt(p.Expires.Unix(), 10))
+	}
+
+	if len(parameters) == 0 {
+		return p.Address.String()
+	}
+	return p.Address.String() + "?" + strings.Join(parameters, "&")
+}
+
+// IsExpired returns whether the payment URI has expired at the given time
+func (p *PaymentURI) IsExpired(now time.Time) bool {
+	return !p.Expires.IsZero() && !now.Before(p.Expires)
+}
+
+// escapePaymentURIParameter escapes a parameter value, encoding spaces
+// as %20 rather than +, which not all URI parsers decode as a space
+func escapePaymentURIParameter(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
@@ -0,0 +1,98 @@
+package util_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaspikr/kaspid/util"
+)
+
+func TestPaymentURI(t *testing.T) {
+	address, err := util.NewAddressPublicKey(make([]byte, util.PublicKeySize), util.Bech32PrefixKaspi)
+	if err != nil {
+		t.Fatalf("NewAddressPublicKey: %+v", err)
+	}
+
+	tests := []struct {
+		name     string
+		uri      *util.PaymentURI
+		expected string
+	}{
+		{
+			name:     "address only",
+			uri:      &util.PaymentURI{Address: address},
+			expected: address.String(),
+		},
+		{
+			name:     "fractional amount",
+			uri:      &util.PaymentURI{Address: address, Amount: 150_000_000},
+			expected: address.String() + "?amount=1.5",
+		},
+		{
+			name:     "whole amount",
+			uri:      &util.PaymentURI{Address: address, Amount: 2_000_000_000},
+			expected: address.String() + "?amount=20",
+		},
+		{
+			name: "all parameters",
+			uri: &util.PaymentURI{
+				Address: address,
+				Amount:  1,
+				Label:   "Coffee & Co",
+				Message: "Order #42",
+				Expires: time.Unix(1700000000, 0),
+			},
+			expected: address.String() + "?amount=0.00000001&label=Coffee%20%26%20Co&message=Order%20%2342&expires=1700000000",
+		},
+	}
+	for _, test := range tests {
+		formatted := test.uri.String()
+		if formatted != test.expected {
+			t.Fatalf("%s: expected %s, but got %s", test.name, test.expected, formatted)
+		}
+
+		parsed, err := util.ParsePaymentURI(formatted, util.Bech32PrefixKaspi)
+		if err != nil {
+			t.Fatalf("%s: ParsePaymentURI: %+v", test.name, err)
+		}
+		if parsed.Address.String() != test.uri.Address.String() || parsed.Amount != test.uri.Amount ||
+			parsed.Label != test.uri.Label || parsed.Message != test.uri.Message || !parsed.Expires.Equal(test.uri.Expires) {
+
+			t.Fatalf("%s: the parsed payment URI %+v is different from %+v", test.name, parsed, test.uri)
+		}
+	}
+
+	parsed, err := util.ParsePaymentURI(address.String()+"?label=Shop&somethingNew=1", util.Bech32PrefixKaspi)
+	if err != nil {
+		t.Fatalf("ParsePaymentURI: %+v", err)
+	}
+	if parsed.Label != "Shop" {
+		t.Fatalf("expected the label Shop, but got %s", parsed.Label)
+	}
+	if parsed.IsExpired(time.Now()) {
+		t.Fatalf("a payment URI with no expiry is not expected to expire")
+	}
+
+	invalidURIs := []string{
+		address.String() + "?amount=1.123456789",
+		address.String() + "?amount=-1",
+		address.String() + "?amount=.5",
+		address.String() + "?amount=1&amount=2",
+		address.String() + "?amount=180000000001",
+		address.String() + "?expires=soon",
+		address.String() + "?req-signature=abc",
+		strings.Replace(address.String(), "kaspi:", "kaspitest:", 1) + "?amount=1",
+	}
+	for _, uri := range invalidURIs {
+		_, err := util.ParsePaymentURI(uri, util.Bech32PrefixKaspi)
+		if err == nil {
+			t.Fatalf("ParsePaymentURI unexpectedly accepted %s", uri)
+		}
+	}
+
+	expiring := &util.PaymentURI{Address: address, Expires: time.Unix(1700000000, 0)}
+	if !expiring.IsExpired(time.Unix(1700000000, 0)) || expiring.IsExpired(time.Unix(1699999999, 0)) {
+		t.Fatalf("IsExpired returned an unexpected result")
+	}
+}